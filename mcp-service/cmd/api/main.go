@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/vishalk17/mcp-service-restaurant/internal/config"
-	"github.com/vishalk17/mcp-service-restaurant/internal/database"
+	"github.com/vishalk17/mcp-service-restaurant/internal/faultinject"
 	"github.com/vishalk17/mcp-service-restaurant/internal/handlers"
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
 	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
 	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/publicmenu"
+	"github.com/vishalk17/mcp-service-restaurant/internal/publicurl"
+	"github.com/vishalk17/mcp-service-restaurant/internal/reports"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/ui"
 )
 
 func main() {
@@ -30,12 +39,36 @@ func main() {
 	log.Printf("   OAuth Server: %s", cfg.Server.OAuthServerURL)
 	log.Printf("   Default Admin: %s", cfg.Server.DefaultAdminEmail)
 
-	// Connect to database
-	db, err := database.Connect(cfg.Database)
+	// Absolute links in responses (already validated above)
+	links, err := publicurl.New(cfg.Server.PublicBaseURL, cfg.Server.OAuthServerURL, cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	if cfg.Server.PublicBaseURL != "" {
+		log.Printf("   Public URL: %s", cfg.Server.PublicBaseURL)
+	}
+
+	if err := handlers.CheckToolExamples(); err != nil {
+		log.Fatal("Invalid tool definitions:", err)
+	}
+	if _, err := reports.All(); err != nil {
+		log.Fatal("Invalid report templates:", err)
+	}
+
+	// Connect to database; NewDB applies the embedded migrations and enum
+	// constraints, the same as for the MCP servers, and fails if they fail
+	db, err := storage.NewDB(cfg.Database, cfg.DatabasePool, cfg.DatabaseRetry)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
+	log.Println("✅ Database connected successfully")
+
+	if cfg.SeedSampleData {
+		if err := db.SeedSampleData(context.Background()); err != nil {
+			log.Printf("Failed to seed sample data: %v", err)
+		}
+	}
 
 	// Initialize OAuth components
 	oauthStorage := oauth.NewStorage(db.DB)
@@ -45,6 +78,14 @@ func main() {
 		nil, // Use default public paths
 	)
 
+	// Restaurant POS systems push orders to /mcp with per-restaurant API
+	// tokens, each rate limited on its own
+	tokenLimit := middleware.NewRateLimiter("restaurant_token", middleware.RestaurantTokenRateLimit, oauth.RestaurantTokenKey)
+	authMiddleware.EnableRestaurantTokens(oauthStorage, tokenLimit)
+
+	// Expired token rows are purged hourly; table stats are published as metrics
+	oauth.StartTokenCleanup(oauthStorage, time.Hour)
+
 	log.Println("✅ OAuth server initialized")
 
 	// Create main router
@@ -68,17 +109,68 @@ func main() {
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":        "healthy",
+			"database_pool": cfg.DatabasePool.Status(db.DB),
+		})
+	})
+
+	// Metrics (protected by OAuth middleware). Business signals are computed
+	// from the database on scrape.
+	metrics.RegisterBusinessCollector(db.DB, metrics.BusinessOptions{
+		OrderSLA:              cfg.Metrics.OrderSLA,
+		MaxStaleness:          cfg.Metrics.MaxStaleness,
+		PerRestaurantLabelMax: cfg.Metrics.PerRestaurantLabelMax,
 	})
+	mux.Handle("/metrics", metrics.Handler())
 
-	// Restaurant API endpoints (protected by OAuth middleware)
-	restaurantHandler := handlers.NewRestaurantHandler(db.DB)
+	// Restaurant API endpoints (protected by OAuth middleware). REST, public
+	// menus and MCP share db, and with it the in-flight reads every write
+	// through any of them invalidates.
+	restaurantHandler := handlers.NewRestaurantHandlerWithStore(db.DB, db, links)
 	mux.HandleFunc("/api/restaurants", restaurantHandler.ListRestaurants)
+	mux.HandleFunc("POST /api/restaurants", restaurantHandler.CreateRestaurant)
 	mux.HandleFunc("/api/restaurants/get", restaurantHandler.GetRestaurant)
 	mux.HandleFunc("/api/restaurants/menu", restaurantHandler.GetMenu)
+	mux.HandleFunc("GET /api/restaurants/{id}/stats", restaurantHandler.GetStats)
+	mux.HandleFunc("/api/orders", restaurantHandler.ListOrders)
+	mux.HandleFunc("POST /api/orders", restaurantHandler.CreateOrder)
+	mux.HandleFunc("GET /api/orders/export", restaurantHandler.ExportOrders)
+
+	// Public menu pages for embedding in restaurant websites (no auth,
+	// rate limited per client, only for restaurants that opt in)
+	publicMenu, err := publicmenu.NewHandler(db)
+	if err != nil {
+		log.Fatal("Failed to load public menu template:", err)
+	}
+	publicLimit := middleware.NewRateLimiter("public_menu", middleware.PublicRateLimit, links.ClientIP)
+	mux.Handle("GET /public/restaurants/{id}/menu", publicLimit.Middleware(publicMenu))
+
+	// Read-only web UI (session cookie auth; login and callback are public)
+	uiHandler, err := ui.NewHandler()
+	if err != nil {
+		log.Fatal("Failed to load web UI:", err)
+	}
+	mux.HandleFunc("/ui/login", oauthServer.HandleUILogin)
+	mux.HandleFunc("/ui/callback", oauthServer.HandleUICallback)
+	mux.HandleFunc("/ui/logout", oauthServer.HandleUILogout)
+	mux.Handle("/ui", uiHandler)
+	mux.Handle("/ui/", uiHandler)
 
 	// MCP JSON-RPC endpoint (protected by OAuth middleware)
-	mcpHandler := handlers.NewMCPHandler(db.DB)
+	// faultinject is a no-op outside builds with the faultinject tag
+	mcpHandler := handlers.NewMCPHandlerWithStore(db.DB, faultinject.WrapStore(db))
+	if faultinject.Enabled {
+		log.Println("   ⚠️  Fault injection build: inject_fault is available to admins")
+	}
+	shadow, err := handlers.ShadowModeFromEnv()
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	mcpHandler.SetShadowMode(shadow)
+	if shadow.Enabled() {
+		log.Printf("   Shadow mode: %s (recorded, not run)", shadow)
+	}
 	mux.HandleFunc("/mcp", mcpHandler.HandleMCP)
 
 	log.Println("✅ OAuth routes registered")
@@ -92,12 +184,24 @@ func main() {
 	log.Println("")
 	log.Println("📍 API Endpoints:")
 	log.Printf("   List Restaurants: %s/api/restaurants", cfg.Server.OAuthServerURL)
+	log.Printf("   Create Restaurant: POST %s/api/restaurants", cfg.Server.OAuthServerURL)
 	log.Printf("   Get Restaurant: %s/api/restaurants/get?id={id}", cfg.Server.OAuthServerURL)
 	log.Printf("   Get Menu: %s/api/restaurants/menu?restaurant_id={id}", cfg.Server.OAuthServerURL)
+	log.Printf("   Restaurant Stats: %s/api/restaurants/{id}/stats", cfg.Server.OAuthServerURL)
+	log.Printf("   List Orders: %s/api/orders?status={status}", cfg.Server.OAuthServerURL)
+	log.Printf("   Create Order: POST %s/api/orders", cfg.Server.OAuthServerURL)
+	log.Printf("   Export Orders: %s/api/orders/export?restaurant_id={id}&from={date}&to={date}", cfg.Server.OAuthServerURL)
+	log.Printf("   Public Menu: %s/public/restaurants/{id}/menu", cfg.Server.OAuthServerURL)
+	log.Printf("   Web UI: %s/ui", cfg.Server.OAuthServerURL)
 	log.Println("")
 
-	// Apply middleware (Logging -> CORS -> Auth)
-	handler := middleware.LoggingMiddleware(middleware.CORSMiddleware(authMiddleware.Middleware(mux)))
+	cors, err := middleware.NewCORSPolicy(cfg.Server.AllowedOrigins)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	// Apply middleware (Logging -> Fault injection -> CORS -> Body limit -> Auth)
+	handler := middleware.LoggingMiddleware(faultinject.Middleware(cors.Middleware(middleware.LimitBodyMiddleware(authMiddleware.Middleware(mux)))))
 
 	// Start server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
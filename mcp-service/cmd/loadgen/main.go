@@ -0,0 +1,70 @@
+// Command loadgen opens concurrent MCP sessions against a server, runs a
+// weighted mix of tool calls and reports throughput, latency percentiles
+// and error rates. With -p95-budget it exits 1 when the p95 latency of all
+// calls is over budget or any call failed, for use in CI.
+//
+//	go run ./cmd/loadgen -url http://localhost:8080/mcp -sessions 50 -duration 1m
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/loadgen"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/mcp", "MCP endpoint")
+	token := flag.String("token", os.Getenv("MCP_TOKEN"), "bearer token, if the endpoint needs one (default $MCP_TOKEN)")
+	sessions := flag.Int("sessions", 10, "concurrent sessions")
+	duration := flag.Duration("duration", 30*time.Second, "how long to run")
+	calls := flag.Int("calls", 0, "stop after this many calls in total instead of after -duration")
+	mix := flag.String("mix", "get_menu=60,list_orders=20,create_order=20", "tool=weight pairs; tools are get_menu, list_orders and create_order")
+	restaurant := flag.Int("restaurant", 1, "restaurant whose menu is read and where orders are placed")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each call")
+	seed := flag.Uint64("seed", 1, "seed of the tool and order choices")
+	budget := flag.Duration("p95-budget", 0, "fail when p95 latency is over this, e.g. 200ms")
+	flag.Parse()
+
+	m, err := loadgen.ParseMix(*mix)
+	if err != nil {
+		log.Fatalf("loadgen: %v", err)
+	}
+	cfg := loadgen.Config{
+		URL:          *url,
+		Token:        *token,
+		Sessions:     *sessions,
+		Duration:     *duration,
+		Calls:        *calls,
+		Mix:          m,
+		RestaurantID: *restaurant,
+		Timeout:      *timeout,
+		Seed:         *seed,
+	}
+	if *calls > 0 {
+		cfg.Duration = 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := loadgen.Run(ctx, cfg)
+	if err != nil {
+		log.Fatalf("loadgen: %v", err)
+	}
+	if err := report.Write(os.Stdout); err != nil {
+		log.Fatalf("loadgen: %v", err)
+	}
+
+	if *budget > 0 {
+		if p95 := report.Total.Percentile(95); p95 > *budget {
+			log.Fatalf("loadgen: p95 latency %s is over the %s budget", p95, *budget)
+		}
+		if report.Total.Errors > 0 {
+			log.Fatalf("loadgen: %d of %d calls failed", report.Total.Errors, report.Total.Calls)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !faultinject
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+)
+
+// TestSameCatalogAsRemote checks the stdio server lists the tools, schemas
+// and examples of /mcp, as cmd/remote-mcp's tests do for the SSE server
+func TestSameCatalogAsRemote(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	mcptest.SameCatalog(t, converse)
+}
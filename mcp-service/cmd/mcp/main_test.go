@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/handlers"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// setMaxMessageBytes lowers the message limit for the rest of the test
+func setMaxMessageBytes(tb testing.TB, n int64) {
+	old := middleware.MaxMessageBytes
+	middleware.MaxMessageBytes = n
+	tb.Cleanup(func() { middleware.MaxMessageBytes = old })
+}
+
+func TestReadLineDiscardsOversizedMessages(t *testing.T) {
+	setMaxMessageBytes(t, 64)
+	s := &MCPServer{reader: bufio.NewReaderSize(strings.NewReader(strings.Repeat("x", 1000)+"\n"+`{"jsonrpc":"2.0"}`+"\n"), 16)}
+
+	if _, err := s.readLine(); err != errLineTooLong {
+		t.Fatalf("oversized line: err = %v, want errLineTooLong", err)
+	}
+	if line, err := s.readLine(); err != nil || line != `{"jsonrpc":"2.0"}` {
+		t.Errorf("line after the oversized one = %q, %v", line, err)
+	}
+	if _, err := s.readLine(); err != io.EOF {
+		t.Errorf("end of input: err = %v, want io.EOF", err)
+	}
+}
+
+// FuzzStdin feeds arbitrary bytes, binary and oversized lines included, to
+// the stdio server as its stdin. Every line it reads must be within the
+// limit, and reading must go on to the end of the input.
+func FuzzStdin(f *testing.F) {
+	setMaxMessageBytes(f, 256)
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}` + "\n"))
+	f.Add([]byte("\x00\xff\xfe\x81binary\r\n\n"))
+	f.Add([]byte(strings.Repeat("{", 1000) + "\n" + `{"jsonrpc":"2.0","id":"7","method":"tools/list"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_order","arguments":{"order_id":1e309}}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := &MCPServer{reader: bufio.NewReaderSize(bytes.NewReader(data), 16)}
+		for {
+			line, err := s.readLine()
+			if err == errLineTooLong {
+				continue
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Fatalf("readLine: %v", err)
+			}
+			if int64(len(line)) > middleware.MaxMessageBytes || strings.Contains(line, "\n") {
+				t.Fatalf("readLine returned %d bytes: %q", len(line), line)
+			}
+		}
+	})
+}
+
+// converse runs the stdio server over messages, one per line, until the
+// end of its input and returns the lines it wrote
+func converse(t *testing.T, messages ...string) []string {
+	h := handlers.NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	h.SetAnonymousRole(models.RoleAdmin)
+	var out bytes.Buffer
+	NewMCPServer(h.Server(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out).Run()
+	if out.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestConformance(t *testing.T) {
+	log.SetOutput(io.Discard) // the server logs every request
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	mcptest.Conformance(t, converse)
+	t.Run("stateful", func(t *testing.T) {
+		mcptest.Stateful(t, converse)
+	})
+}
+
+// TestToolListChangedIsSent registers a tool while a client is connected
+// and checks the client is told on stdout, after initialize said it would be
+func TestToolListChangedIsSent(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	h := handlers.NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		NewMCPServer(h.Server(), inR, outW).Run()
+		outW.Close()
+		close(done)
+	}()
+	out := bufio.NewScanner(outR)
+	out.Buffer(nil, 1<<20)
+	next := func() string {
+		t.Helper()
+		if !out.Scan() {
+			t.Fatalf("no message on stdout: %v", out.Err())
+		}
+		return out.Text()
+	}
+
+	fmt.Fprintln(inW, mcptest.Initialize)
+	if line := next(); !strings.Contains(line, `"tools":{"listChanged":true}`) {
+		t.Errorf("initialize = %s, want tools with listChanged", line)
+	}
+	fmt.Fprintln(inW, mcptest.Initialized)
+	// A ping answered means notifications/initialized was handled
+	fmt.Fprintln(inW, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	next()
+
+	mcptest.AddTool(h.Server(), "echo_later")
+	if line := next(); line != `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` {
+		t.Errorf("after registering a tool got %s, want list_changed", line)
+	}
+	inW.Close()
+	io.Copy(io.Discard, outR)
+	<-done
+}
+
+// guardStdout swaps os.Stdout for a pipe until the test ends and panics if
+// anything was written to it: JSON-RPC messages only go out through the
+// server's messageWriter, and a stray print would corrupt the stream.
+func guardStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	stray := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		stray <- data
+	}()
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		w.Close()
+		if data := <-stray; len(data) > 0 {
+			panic(fmt.Sprintf("%s wrote to os.Stdout outside the message writer: %q", t.Name(), data))
+		}
+	})
+}
+
+func TestConcurrentResponsesAreWholeLines(t *testing.T) {
+	guardStdout(t)
+	var out bytes.Buffer
+	s := &MCPServer{out: newMessageWriter(&out)}
+	send := func(message string) error {
+		return s.send(mcp.ErrorResponse(jsonrpc.NullID, mcp.CodeInternalError, message))
+	}
+
+	const senders, each = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				if err := send(fmt.Sprintf("%d: %s", i, strings.Repeat("x", 100*j))); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := s.out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("late"); err != errWriterClosed {
+		t.Errorf("send after Close: err = %v, want errWriterClosed", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != senders*each {
+		t.Fatalf("%d lines, want %d", len(lines), senders*each)
+	}
+	for _, line := range lines {
+		var resp mcp.Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil || resp.Error == nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+	}
+}
+
+func TestMessageWriterEndsEveryMessageWithANewline(t *testing.T) {
+	var out bytes.Buffer
+	w := newMessageWriter(&out)
+	for _, msg := range []string{`{"a":1}`, "{\"b\":2}\n", ""} {
+		if err := w.send([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"a\":1}\n{\"b\":2}\n\n"; out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// outboxSize is how many messages may wait for the writer before senders
+// block, so a client that stops reading slows the server down instead of
+// growing its memory
+const outboxSize = 64
+
+var errWriterClosed = errors.New("stdout writer is closed")
+
+// messageWriter owns stdout. Responses and notifications are handed to it
+// already marshaled and a single goroutine writes them one after the other,
+// each as one newline-terminated line, so concurrent senders can never
+// interleave their bytes in the stream.
+type messageWriter struct {
+	queue chan []byte
+	done  chan struct{} // closed when the goroutine has stopped
+	err   error         // first write error; read only after done is closed
+
+	mu     sync.RWMutex // held for reading while sending, for writing by Close
+	closed bool
+}
+
+// newMessageWriter starts the goroutine writing messages to out
+func newMessageWriter(out io.Writer) *messageWriter {
+	w := &messageWriter{queue: make(chan []byte, outboxSize), done: make(chan struct{})}
+	go w.run(out)
+	return w
+}
+
+func (w *messageWriter) run(out io.Writer) {
+	defer close(w.done)
+	for msg := range w.queue {
+		if len(msg) == 0 || msg[len(msg)-1] != '\n' {
+			msg = append(msg, '\n')
+		}
+		if _, err := out.Write(msg); err != nil {
+			w.err = err
+			return
+		}
+	}
+}
+
+// send queues msg, waiting while the queue is full. It fails once the
+// writer is closed or a write has failed.
+func (w *messageWriter) send(msg []byte) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return errWriterClosed
+	}
+	select {
+	case w.queue <- msg:
+		return nil
+	case <-w.done:
+		return w.err
+	}
+}
+
+// Close writes the messages still queued and stops the goroutine,
+// returning the first write error
+func (w *messageWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.err
+}
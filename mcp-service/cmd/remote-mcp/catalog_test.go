@@ -0,0 +1,16 @@
+//go:build !faultinject
+
+package main
+
+import (
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+)
+
+// TestSameCatalogAsStdio checks this server lists the tools, schemas and
+// examples of /mcp, as cmd/mcp's tests do for the stdio server
+func TestSameCatalogAsStdio(t *testing.T) {
+	quiet(t)
+	mcptest.SameCatalog(t, post(newTestServer(mcptest.Store(t))))
+}
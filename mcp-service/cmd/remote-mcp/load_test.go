@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/loadgen"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+)
+
+var loadBudget = flag.Duration("load-p95-budget", 100*time.Millisecond, "p95 latency TestLoadBudget allows")
+
+// TestLoadBudget runs the default loadgen mix against an in-process server
+// with a seeded store. It catches requests that serialize behind each
+// other or grow with the number of orders, not database latency.
+func TestLoadBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	log.SetOutput(io.Discard) // the server logs every request
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	ctx := context.Background()
+	store := memory.New()
+	r := models.Restaurant{Name: "Load Test Kitchen", Address: "Pune"}
+	if err := store.CreateRestaurant(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 40; i++ {
+		m := models.MenuItem{RestaurantID: r.ID, Name: fmt.Sprintf("Dish %d", i+1), Price: models.Rupees(float64(80 + 10*i)), Category: "Main Course", Available: true}
+		if err := store.CreateMenuItem(ctx, &m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(newTestServer(store).handleSSE))
+	defer server.Close()
+
+	report, err := loadgen.Run(ctx, loadgen.Config{URL: server.URL, Sessions: 16, Calls: 800, RestaurantID: r.ID, Client: server.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	report.Write(&out)
+	t.Log("\n" + out.String())
+
+	if report.Total.Calls != 800 || report.Total.Errors != 0 {
+		t.Errorf("%d calls with %d errors, want 800 without errors", report.Total.Calls, report.Total.Errors)
+	}
+	if p95 := report.Total.Percentile(95); p95 > *loadBudget {
+		t.Errorf("p95 latency %s, budget %s", p95, *loadBudget)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/handlers"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+)
+
+// newTestServer serves store the way main serves Postgres
+func newTestServer(store storage.Store) *MCPServer {
+	h := handlers.NewMCPHandlerWithStore(nil, store)
+	h.SetAnonymousRole(models.RoleOwner)
+	return NewMCPServer(h.Server())
+}
+
+// events returns the data of the SSE events of a response body
+func events(t *testing.T, body io.Reader) []string {
+	t.Helper()
+	var data []string
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			data = append(data, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// post sends each message as a POST of its own
+func post(s *MCPServer) mcptest.Conversation {
+	return func(t *testing.T, messages ...string) []string {
+		var responses []string
+		for _, msg := range messages {
+			r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(msg))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.handleSSE(w, r)
+			responses = append(responses, events(t, w.Body)...)
+		}
+		return responses
+	}
+}
+
+// stream sends all messages as the body of one streaming GET
+func stream(s *MCPServer) mcptest.Conversation {
+	return func(t *testing.T, messages ...string) []string {
+		w := httptest.NewRecorder()
+		s.handleSSE(w, httptest.NewRequest(http.MethodGet, "/mcp", strings.NewReader(strings.Join(messages, "\n")+"\n")))
+		return events(t, w.Body)
+	}
+}
+
+func quiet(t *testing.T) {
+	log.SetOutput(io.Discard) // the server logs every request
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+func TestConformance(t *testing.T) {
+	quiet(t)
+	t.Run("POST", func(t *testing.T) {
+		mcptest.Conformance(t, func(t *testing.T, messages ...string) []string {
+			return post(newTestServer(mcptest.Store(t)))(t, messages...)
+		})
+	})
+	t.Run("GET stream", func(t *testing.T) {
+		converse := func(t *testing.T, messages ...string) []string {
+			return stream(newTestServer(mcptest.Store(t)))(t, messages...)
+		}
+		mcptest.Conformance(t, converse)
+		t.Run("stateful", func(t *testing.T) {
+			mcptest.Stateful(t, converse)
+		})
+	})
+}
+
+// pipeWriter is a ResponseWriter whose body is read while the handler runs
+type pipeWriter struct {
+	*io.PipeWriter
+	header http.Header
+}
+
+func (w pipeWriter) Header() http.Header { return w.header }
+func (w pipeWriter) WriteHeader(int)     {}
+
+// TestToolListChangedIsStreamed registers a tool while a streaming GET is
+// open and checks its client gets the notification as an event, while a
+// POST, which can't be told, isn't promised one
+func TestToolListChangedIsStreamed(t *testing.T) {
+	quiet(t)
+	s := newTestServer(mcptest.Store(t))
+
+	if lines := post(s)(t, mcptest.Initialize); len(lines) != 1 || !strings.Contains(lines[0], `"tools":{"listChanged":false}`) {
+		t.Errorf("initialize over POST = %q, want tools without listChanged", lines)
+	}
+
+	body, in := io.Pipe()
+	out, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		s.handleSSE(pipeWriter{w, http.Header{}}, httptest.NewRequest(http.MethodGet, "/mcp", body))
+		w.Close()
+		close(done)
+	}()
+	events := bufio.NewScanner(out)
+	events.Buffer(nil, 1<<20)
+	next := func() string {
+		t.Helper()
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				return data
+			}
+		}
+		t.Fatalf("stream ended: %v", events.Err())
+		return ""
+	}
+
+	fmt.Fprintln(in, mcptest.Initialize)
+	if event := next(); !strings.Contains(event, `"tools":{"listChanged":true}`) {
+		t.Errorf("initialize over a stream = %s, want tools with listChanged", event)
+	}
+	fmt.Fprintln(in, mcptest.Initialized)
+	// A ping answered means notifications/initialized was handled
+	fmt.Fprintln(in, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	next()
+
+	// The event is written as the tool is registered, so read it meanwhile
+	go mcptest.AddTool(s.server, "echo_later")
+	if event := next(); event != `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` {
+		t.Errorf("after registering a tool got %s, want list_changed", event)
+	}
+	in.Close()
+	io.Copy(io.Discard, out)
+	<-done
+}
+
+// callTool runs one tools/call over POST and returns its result
+func callTool(t *testing.T, s *MCPServer, tool, args string) mcp.CallToolResult {
+	t.Helper()
+	return mcptest.Converse(t, post(s), mcptest.Call(tool, args))[0].ToolResult(t)
+}
+
+func TestCreateOrderIgnoresCallerPrices(t *testing.T) {
+	quiet(t)
+	ctx := context.Background()
+	store := memory.New()
+	r := models.Restaurant{Name: "Test Kitchen", Address: "Pune"}
+	other := models.Restaurant{Name: "Other Kitchen", Address: "Mumbai"}
+	for _, restaurant := range []*models.Restaurant{&r, &other} {
+		if err := store.CreateRestaurant(ctx, restaurant); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := models.MenuItem{RestaurantID: r.ID, Name: "Masala Dosa", Price: models.Rupees(90), Available: true}
+	foreign := models.MenuItem{RestaurantID: other.ID, Name: "Vada Pav", Price: models.Rupees(30), Available: true}
+	for _, item := range []*models.MenuItem{&m, &foreign} {
+		if err := store.CreateMenuItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newTestServer(store)
+
+	items := func(id, price int) string {
+		return fmt.Sprintf(`{"restaurant_id":%d,"customer_name":"Asha Rao","items":[{"menu_item_id":%d,"quantity":2,"price":%d}]}`, r.ID, id, price)
+	}
+	if result := callTool(t, s, "create_order", items(m.ID, 1)); result.IsError {
+		t.Fatalf("create_order: %s", mcptest.Text(result))
+	}
+	o, err := store.GetOrderByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.OrderItems[0].Price != m.Price {
+		t.Errorf("item priced at %v, want the menu's %v", o.OrderItems[0].Price, m.Price)
+	}
+
+	for name, itemID := range map[string]int{"another restaurant's item": foreign.ID, "a deleted item": 999} {
+		result := callTool(t, s, "create_order", items(itemID, 1))
+		if !result.IsError || !strings.Contains(mcptest.Text(result), "menu item") {
+			t.Errorf("create_order with %s = %+v, want an error naming the item", name, result)
+		}
+	}
+}
+
+func TestCreateOrderRejectsGuessedItemFields(t *testing.T) {
+	quiet(t)
+	resp := mcptest.Converse(t, post(newTestServer(mcptest.Store(t))), mcptest.Call("create_order", `{"restaurant_id":1,"customer_name":"Asha Rao","items":[{"itemId":1,"qty":2}]}`))[0]
+	if resp.Error == nil || resp.Error.Code != mcp.CodeInvalidParams || !strings.Contains(resp.Error.Message, "items[0].menu_item_id") {
+		t.Errorf("create_order with guessed item fields = %s %+v, want invalid params naming them", resp.Result, resp.Error)
+	}
+}
+
+func TestQuickOrderRefusesUnknownNames(t *testing.T) {
+	quiet(t)
+	store := mcptest.Store(t)
+	s := newTestServer(store)
+
+	result := callTool(t, s, "quick_order", `{"restaurant_id":1,"customer_name":"Table 4","items":[{"name":"thali","quantity":1},{"name":"main","quantity":1}]}`)
+	if text := mcptest.Text(result); !result.IsError || !strings.Contains(text, "Order not placed") || !strings.Contains(text, "Vegan Thali") {
+		t.Errorf("quick_order with an unknown name = %+v, want the order refused with the resolved dish listed", result)
+	}
+	if orders, total, err := store.GetOrders(context.Background(), models.OrderFilter{}, models.Page{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("orders after a refused quick_order = %v, %d, %v; want only the seeded one", orders, total, err)
+	}
+}
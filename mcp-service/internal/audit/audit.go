@@ -0,0 +1,75 @@
+// Package audit records who changed what. Every handler of a mutating MCP
+// tool or REST endpoint calls Record once its change has succeeded, adding
+// an entry to the audit log with the caller, the entity changed and the
+// arguments of the call. Calls refused by the restaurant access policy are
+// recorded too, with RecordOutcome.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// writeTimeout bounds the write of an entry, which outlives the request
+const writeTimeout = 5 * time.Second
+
+// Recorder stores audit log entries. Every storage.Store is one.
+type Recorder interface {
+	RecordAudit(ctx context.Context, e *models.AuditEntry) error
+}
+
+// Record adds an entry for a successful call of tool that changed the
+// entity of entityType with entityID, 0 when it changed several. args are
+// the call's arguments: a tool's argument map or a decoded request body.
+// A failure to write the entry is logged, not returned, since the change
+// it records has already been made.
+func Record(ctx context.Context, r Recorder, tool, entityType string, entityID int, args interface{}) {
+	RecordOutcome(ctx, r, tool, "", entityType, entityID, args)
+}
+
+// RecordOutcome is Record for a call that made no change, with outcome
+// saying why, e.g. models.AuditDenied
+func RecordOutcome(ctx context.Context, r Recorder, tool, outcome, entityType string, entityID int, args interface{}) {
+	e := &models.AuditEntry{Actor: Actor(ctx), Tool: tool, EntityType: entityType, Arguments: arguments(args), Outcome: outcome}
+	if entityID != 0 {
+		e.EntityID = &entityID
+	}
+	// Written even when the client has gone away right after the change
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+	if err := r.RecordAudit(ctx, e); err != nil {
+		log.Printf("Warning: audit log entry for %s of %s %d by %s not written: %v", tool, entityType, entityID, e.Actor, err)
+	}
+}
+
+// Actor names who a request's changes are made by: the email of the OAuth
+// user when there is one, else the actor the context names for storage
+func Actor(ctx context.Context) string {
+	if email, _ := oauth.GetUserFromContext(ctx)["email"].(string); email != "" {
+		return email
+	}
+	return storage.Actor(ctx)
+}
+
+// arguments converts args to a JSON object, recording what it can't
+// convert as an error instead
+func arguments(args interface{}) map[string]interface{} {
+	if m, ok := args.(map[string]interface{}); ok && m != nil {
+		return m
+	}
+	m := map[string]interface{}{}
+	data, err := json.Marshal(args)
+	if err == nil && string(data) != "null" {
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return map[string]interface{}{"error": "arguments not recorded: " + err.Error()}
+	}
+	return m
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+)
+
+func TestRecord(t *testing.T) {
+	store := memory.New()
+	ctx := context.WithValue(context.Background(), oauth.UserContextKey, map[string]interface{}{"email": "priya@example.com"})
+	ctx, cancel := context.WithCancel(ctx)
+	cancel() // the client went away after the change
+
+	Record(ctx, store, "POST /api/orders", models.AuditOrder, 7, struct {
+		CustomerName string `json:"customer_name"`
+	}{"Asha Rao"})
+	Record(storage.WithActor(context.Background(), "token:Front counter"), store, "merge_orders", models.AuditOrder, 0, map[string]interface{}{"order_ids": []interface{}{1.0, 2.0}})
+
+	entries, total, err := store.GetAuditLog(context.Background(), models.AuditQuery{EntityType: models.AuditOrder}, models.Page{Limit: 10})
+	if err != nil || total != 2 {
+		t.Fatalf("%d entries, %v; want 2", total, err)
+	}
+	merged, created := entries[0], entries[1]
+	if created.Actor != "priya@example.com" || *created.EntityID != 7 || created.Arguments["customer_name"] != "Asha Rao" {
+		t.Errorf("entry of the REST call: %+v", created)
+	}
+	if merged.Actor != "token:Front counter" || merged.EntityID != nil {
+		t.Errorf("entry of the merge: %+v", merged)
+	}
+}
+
+func TestRecordLogsAFailedWrite(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	store := memory.New()
+	store.Err = errors.New("connection refused")
+	Record(context.Background(), store, "delete_order", models.AuditOrder, 3, map[string]interface{}{"id": 3.0})
+	if !strings.Contains(out.String(), "delete_order of order 3 by mcp not written: connection refused") {
+		t.Errorf("log: %q", out.String())
+	}
+}
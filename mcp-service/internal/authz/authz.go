@@ -0,0 +1,115 @@
+// Package authz decides whether a user may see a restaurant. A user scoped
+// to some restaurants who asks for another one gets the same answer as for
+// a restaurant that doesn't exist, so IDs can't be enumerated, while admins
+// see every restaurant. Denials are still told apart from true misses in the
+// metrics and in the failure log.
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// Outcomes of a restaurant access check
+const (
+	Allowed  = "allowed"
+	NotFound = "not_found" // the restaurant doesn't exist
+	Denied   = "denied"    // it exists but is outside the user's scope
+)
+
+// Scope is the set of restaurants a user works for. The zero Scope is
+// unscoped and includes every restaurant.
+type Scope struct {
+	restaurants map[int]bool // nil when unscoped
+}
+
+// Unscoped includes every restaurant
+func Unscoped() Scope {
+	return Scope{}
+}
+
+// Restaurants includes only the given restaurants, or none without IDs
+func Restaurants(ids ...int) Scope {
+	s := Scope{restaurants: make(map[int]bool, len(ids))}
+	for _, id := range ids {
+		s.restaurants[id] = true
+	}
+	return s
+}
+
+// Includes reports whether restaurantID is in the scope
+func (s Scope) Includes(restaurantID int) bool {
+	return s.restaurants == nil || s.restaurants[restaurantID]
+}
+
+// ScopeSource returns the scope of the user a request is authenticated as
+type ScopeSource func(ctx context.Context) (Scope, error)
+
+// UnscopedSource gives every user the Unscoped scope. It is the source until
+// restaurants are assigned to users.
+func UnscopedSource(ctx context.Context) (Scope, error) {
+	return Unscoped(), nil
+}
+
+// CheckRestaurant applies the access policy to a lookup of restaurantID by
+// a user with role and scope, where exists says whether the restaurant is
+// in the database
+func CheckRestaurant(role string, scope Scope, restaurantID int, exists bool) string {
+	switch {
+	case !exists:
+		return NotFound
+	case role == models.RoleAdmin || scope.Includes(restaurantID):
+		return Allowed
+	default:
+		return Denied
+	}
+}
+
+// RestaurantNotFound is the error text for both NotFound and Denied, so the
+// two can't be told apart by the caller
+func RestaurantNotFound(restaurantID int) string {
+	return fmt.Sprintf("Restaurant %d not found", restaurantID)
+}
+
+// Record counts a NotFound or Denied outcome of tool, logs denials and
+// marks ctx (see WithOutcome) so the failure log can tell them apart
+func Record(ctx context.Context, tool, user, outcome string, restaurantID int) {
+	switch outcome {
+	case Denied:
+		log.Printf("authz: denied %s access to restaurant %d via %s", user, restaurantID, tool)
+		metrics.AddCounterWithLabels("authz_denied_total", "Restaurant lookups refused because the restaurant is outside the user's scope",
+			map[string]string{"tool": tool}, 1)
+	case NotFound:
+		metrics.AddCounterWithLabels("restaurant_not_found_total", "Restaurant lookups for an ID that doesn't exist",
+			map[string]string{"tool": tool}, 1)
+	default:
+		return
+	}
+	if o, ok := ctx.Value(outcomeKey{}).(*atomic.Value); ok {
+		o.Store(outcome)
+	}
+}
+
+type outcomeKey struct{}
+
+// WithOutcome returns a context in which Record notes the outcome of the
+// checks made while serving one request, for Outcome to read
+func WithOutcome(ctx context.Context) context.Context {
+	return context.WithValue(ctx, outcomeKey{}, &atomic.Value{})
+}
+
+// Outcome returns the last outcome recorded in a context from WithOutcome,
+// or "" if none was
+func Outcome(ctx context.Context) string {
+	o, ok := ctx.Value(outcomeKey{}).(*atomic.Value)
+	if !ok {
+		return ""
+	}
+	outcome, _ := o.Load().(string)
+	return outcome
+}
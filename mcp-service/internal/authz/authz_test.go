@@ -0,0 +1,74 @@
+package authz
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+func TestCheckRestaurant(t *testing.T) {
+	scoped := Restaurants(1, 2)
+	for _, tc := range []struct {
+		name   string
+		role   string
+		scope  Scope
+		id     int
+		exists bool
+		want   string
+	}{
+		{"staff in scope", models.RoleStaff, scoped, 1, true, Allowed},
+		{"staff outside scope", models.RoleStaff, scoped, 3, true, Denied},
+		{"owner outside scope", models.RoleOwner, scoped, 3, true, Denied},
+		{"admin outside scope", models.RoleAdmin, scoped, 3, true, Allowed},
+		{"unscoped staff", models.RoleStaff, Unscoped(), 3, true, Allowed},
+		{"missing restaurant", models.RoleStaff, scoped, 9, false, NotFound},
+		{"missing restaurant for admin", models.RoleAdmin, scoped, 9, false, NotFound},
+		{"no restaurants", models.RoleStaff, Restaurants(), 1, true, Denied},
+	} {
+		if got := CheckRestaurant(tc.role, tc.scope, tc.id, tc.exists); got != tc.want {
+			t.Errorf("%s: CheckRestaurant = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+// scrape returns the /metrics lines of a metric
+func scrape(name string) []string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	var lines []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, name+"{") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestRecordCountsDenialsApartFromMisses(t *testing.T) {
+	ctx := WithOutcome(context.Background())
+	Record(ctx, "test_tool", "staff@example.com", Denied, 3)
+	if got := Outcome(ctx); got != Denied {
+		t.Errorf("Outcome after a denial = %q, want %q", got, Denied)
+	}
+	Record(ctx, "test_tool", "staff@example.com", NotFound, 9)
+	Record(ctx, "test_tool", "staff@example.com", NotFound, 10)
+
+	if got := scrape("authz_denied_total"); len(got) != 1 || got[0] != `authz_denied_total{tool="test_tool"} 1` {
+		t.Errorf("authz_denied_total = %q, want one denial", got)
+	}
+	if got := scrape("restaurant_not_found_total"); len(got) != 1 || got[0] != `restaurant_not_found_total{tool="test_tool"} 2` {
+		t.Errorf("restaurant_not_found_total = %q, want two misses", got)
+	}
+
+	Record(ctx, "test_tool", "staff@example.com", Allowed, 1)
+	if got := Outcome(ctx); got != NotFound {
+		t.Errorf("Outcome after an allowed check = %q, want the last failure %q", got, NotFound)
+	}
+	if got := Outcome(context.Background()); got != "" {
+		t.Errorf("Outcome without WithOutcome = %q, want none", got)
+	}
+}
@@ -0,0 +1,62 @@
+// Package bulk defines the result envelope shared by every tool that acts on
+// many items in one call, so clients parse partial failures the same way
+// regardless of the tool.
+package bulk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result reports the outcome of a bulk tool call. It is sent both as
+// structuredContent and, via Summary, as the text content.
+type Result struct {
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Items     []ItemResult `json:"items"`
+}
+
+// ItemResult is the outcome for one input item. Index is the item's position
+// in the request; ID is the affected row, zero when the item failed before
+// one existed.
+type ItemResult struct {
+	Index int    `json:"index"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewResult returns an empty result for total input items
+func NewResult(total int) *Result {
+	return &Result{Total: total, Items: []ItemResult{}}
+}
+
+// Add records the outcome of the item at index
+func (r *Result) Add(index, id int, err error) {
+	item := ItemResult{Index: index, ID: id}
+	if err != nil {
+		item.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// HasFailures reports whether any item failed
+func (r *Result) HasFailures() bool {
+	return r.Failed > 0
+}
+
+// Summary renders the result as readable text: one headline followed by a
+// line per failed item
+func (r *Result) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d succeeded, %d failed", r.Succeeded, r.Total, r.Failed)
+	for _, item := range r.Items {
+		if item.Error != "" {
+			fmt.Fprintf(&b, "\n- item %d: %s", item.Index, item.Error)
+		}
+	}
+	return b.String()
+}
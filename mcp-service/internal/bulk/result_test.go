@@ -0,0 +1,36 @@
+package bulk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestResultEnvelope(t *testing.T) {
+	r := NewResult(3)
+	r.Add(0, 11, nil)
+	r.Add(1, 0, errors.New("price must be greater than 0"))
+	r.Add(2, 12, nil)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"total":3,"succeeded":2,"failed":1,"items":[{"index":0,"id":11},{"index":1,"error":"price must be greater than 0"},{"index":2,"id":12}]}`
+	if string(data) != want {
+		t.Errorf("JSON = %s\nwant   %s", data, want)
+	}
+	if !r.HasFailures() {
+		t.Error("HasFailures = false")
+	}
+	if got, want := r.Summary(), "2 of 3 succeeded, 1 failed\n- item 1: price must be greater than 0"; got != want {
+		t.Errorf("Summary = %q, want %q", got, want)
+	}
+}
+
+func TestEmptyResultListsNoItems(t *testing.T) {
+	data, _ := json.Marshal(NewResult(0))
+	if want := `{"total":0,"succeeded":0,"failed":0,"items":[]}`; string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
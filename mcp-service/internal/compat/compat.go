@@ -0,0 +1,207 @@
+// Package compat accepts deprecated tool names and argument forms that
+// prompts in the wild still use, translates them to the current form and
+// reports a warning for each, so a shim can be deleted once its usage
+// counter stays at zero.
+package compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+// WarningPrefix starts every deprecation line appended to tool result text
+const WarningPrefix = "Deprecation warning: "
+
+// Warning describes one deprecated form found in a tool call
+type Warning struct {
+	Form    string `json:"form"` // stable identifier, also the metric label
+	Message string `json:"message"`
+}
+
+// Text renders the warning as the standard line appended to tool results
+func (w Warning) Text() string {
+	return WarningPrefix + w.Message
+}
+
+// RenamedTools maps old tool names to their replacements. The get_ names
+// come from the SSE server's catalog before it shared the one of /mcp.
+var RenamedTools = map[string]string{
+	"get_restaurants":   "list_restaurants",
+	"get_orders":        "list_orders",
+	"get_pricing_rules": "list_pricing_rules",
+}
+
+// argShim translates one deprecated argument form
+type argShim struct {
+	tools     []string
+	arg       string
+	renameTo  string // the current name of arg, if it was renamed
+	form      string
+	message   string
+	translate func(v interface{}) (interface{}, bool, error) // false if v isn't the deprecated form, an error if it is but can't be translated
+}
+
+var argShims = []argShim{
+	{
+		tools:     []string{"create_menu_item", "update_menu_item"},
+		arg:       "is_available",
+		form:      "is_available_string",
+		message:   `is_available as a string ("true"/"false") is deprecated, pass a boolean`,
+		translate: stringToBool,
+	},
+	{
+		// Argument names follow the JSON of internal/models, where the
+		// field has always been available
+		tools:     []string{"create_menu_item", "update_menu_item"},
+		arg:       "is_available",
+		renameTo:  "available",
+		form:      "is_available_arg",
+		message:   "is_available is deprecated, pass available as in the menu item JSON",
+		translate: keep,
+	},
+	{
+		tools:     []string{"create_order"},
+		arg:       "items",
+		form:      "items_json_string",
+		message:   "items as a JSON-encoded string is deprecated, pass an array",
+		translate: jsonStringToArray,
+	},
+	{
+		// Prices have come from the menu since create_order stopped trusting
+		// the caller; the items schema no longer has the field
+		tools:     []string{"create_order"},
+		arg:       "items",
+		form:      "items_price",
+		message:   "price in create_order items is ignored and deprecated, prices come from the menu",
+		translate: dropItemPrices,
+	},
+	// The SSE server named the ID of the record a tool acts on after its
+	// type; /mcp has always called it id
+	idShim("restaurant_id", "get_restaurant", "update_restaurant", "delete_restaurant", "restore_restaurant"),
+	idShim("order_id", "get_order", "update_order", "cancel_order", "delete_order"),
+	idShim("menu_item_id", "update_menu_item", "delete_menu_item"),
+	idShim("pricing_rule_id", "update_pricing_rule", "delete_pricing_rule"),
+}
+
+// idShim renames arg to id for tools
+func idShim(arg string, tools ...string) argShim {
+	return argShim{
+		tools:     tools,
+		arg:       arg,
+		renameTo:  "id",
+		form:      arg + "_arg",
+		message:   arg + " is deprecated for this tool, pass id",
+		translate: keep,
+	}
+}
+
+// Apply rewrites a tool call in place to its current form and returns the
+// tool name to dispatch and one warning per deprecated form it contained.
+// A deprecated form that can't be translated is a *toolschema.ValidationError.
+func Apply(tool string, args map[string]interface{}) (string, []Warning, error) {
+	var warnings []Warning
+	if newName, ok := RenamedTools[tool]; ok {
+		warnings = append(warnings, record(Warning{
+			Form:    "tool_" + tool,
+			Message: fmt.Sprintf("tool %s is deprecated, use %s", tool, newName),
+		}))
+		tool = newName
+	}
+
+	for _, shim := range argShims {
+		if !contains(shim.tools, tool) {
+			continue
+		}
+		v, ok := args[shim.arg]
+		if !ok {
+			continue
+		}
+		translated, ok, err := shim.translate(v)
+		if err != nil {
+			return tool, warnings, &toolschema.ValidationError{Violations: []string{shim.arg + " " + err.Error()}}
+		}
+		if !ok {
+			continue
+		}
+		if shim.renameTo == "" {
+			args[shim.arg] = translated
+		} else {
+			// The current name wins when a call passes both
+			delete(args, shim.arg)
+			if _, ok := args[shim.renameTo]; !ok {
+				args[shim.renameTo] = translated
+			}
+		}
+		warnings = append(warnings, record(Warning{Form: shim.form, Message: shim.message}))
+	}
+	return tool, warnings, nil
+}
+
+func record(w Warning) Warning {
+	metrics.AddCounterWithLabels("mcp_deprecated_usage_total", "Tool calls using a deprecated tool name or argument form",
+		map[string]string{"form": w.Form}, 1)
+	return w
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func keep(v interface{}) (interface{}, bool, error) {
+	return v, true, nil
+}
+
+// stringToBool takes the spellings strconv.ParseBool does, such as "true",
+// "False" or "1"; any other string is refused rather than read as false
+func stringToBool(v interface{}) (interface{}, bool, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, true, fmt.Errorf("must be a boolean, got %q", s)
+	}
+	return b, true, nil
+}
+
+func jsonStringToArray(v interface{}) (interface{}, bool, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false, nil
+	}
+	var items []interface{}
+	if err := json.Unmarshal([]byte(s), &items); err != nil {
+		// Leave it for the tool to reject as an invalid items array
+		return nil, false, nil
+	}
+	return items, true, nil
+}
+
+// dropItemPrices removes price from each item object, reporting whether any
+// item had one
+func dropItemPrices(v interface{}) (interface{}, bool, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	dropped := false
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if _, has := m["price"]; has {
+				delete(m, "price")
+				dropped = true
+			}
+		}
+	}
+	return items, dropped, nil
+}
@@ -0,0 +1,60 @@
+package compat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+func TestApplyRenamesIsAvailable(t *testing.T) {
+	args := map[string]interface{}{"id": 3.0, "is_available": "false"}
+	tool, warnings, err := Apply("update_menu_item", args)
+	if err != nil || tool != "update_menu_item" || len(warnings) != 2 {
+		t.Fatalf("Apply = %s, %+v, %v; want two warnings", tool, warnings, err)
+	}
+	if _, ok := args["is_available"]; ok || args["available"] != false {
+		t.Errorf("args = %v, want available false in place of is_available", args)
+	}
+
+	args = map[string]interface{}{"is_available": false, "available": true}
+	Apply("create_menu_item", args)
+	if _, ok := args["is_available"]; ok || args["available"] != true {
+		t.Errorf("args = %v, want available kept when both are given", args)
+	}
+}
+
+func TestApplyReadsIsAvailableStringsAsParseBool(t *testing.T) {
+	for value, want := range map[string]bool{"true": true, "False": false, "1": true, "0": false, "t": true} {
+		args := map[string]interface{}{"is_available": value}
+		if _, _, err := Apply("create_menu_item", args); err != nil || args["available"] != want {
+			t.Errorf("is_available %q: args %v, %v; want available %v", value, args, err, want)
+		}
+	}
+
+	for _, value := range []string{"yes", "", "flase"} {
+		_, _, err := Apply("update_menu_item", map[string]interface{}{"id": 3.0, "is_available": value})
+		var invalid *toolschema.ValidationError
+		if !errors.As(err, &invalid) || !strings.Contains(err.Error(), "is_available must be a boolean") {
+			t.Errorf("is_available %q: %v, want a validation error", value, err)
+		}
+	}
+}
+
+func TestApplyTranslatesSSEToolNames(t *testing.T) {
+	args := map[string]interface{}{"restaurant_id": 1.0}
+	tool, warnings, _ := Apply("get_orders", args)
+	if tool != "list_orders" || len(warnings) != 1 || args["restaurant_id"] != 1.0 {
+		t.Errorf("Apply(get_orders) = %s, %+v, args %v; want list_orders with restaurant_id kept", tool, warnings, args)
+	}
+
+	args = map[string]interface{}{"order_id": 7.0, "status": "ready"}
+	tool, warnings, _ = Apply("update_order", args)
+	if tool != "update_order" || len(warnings) != 1 || warnings[0].Form != "order_id_arg" {
+		t.Fatalf("Apply(update_order) = %s, %+v; want one order_id_arg warning", tool, warnings)
+	}
+	if _, ok := args["order_id"]; ok || args["id"] != 7.0 {
+		t.Errorf("args = %v, want id 7 in place of order_id", args)
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/publicurl"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
 )
 
 // OAuthConfig holds OAuth provider configuration
@@ -27,6 +32,9 @@ type ServerConfig struct {
 	Host              string
 	Port              string
 	OAuthServerURL    string
+	PublicBaseURL     string   // base for absolute links in responses; empty to derive it
+	TrustedProxies    []string // IPs or CIDRs whose X-Forwarded-Host is believed
+	AllowedOrigins    []string // browser origins allowed besides the known MCP clients, with credentials
 	JWTSecret         string
 	AccessTokenLife   int64 // in seconds
 	RefreshTokenLife  int64 // in seconds
@@ -34,11 +42,22 @@ type ServerConfig struct {
 	DefaultAdminName  string
 }
 
+// MetricsConfig holds settings for the business signals on /metrics
+type MetricsConfig struct {
+	OrderSLA              time.Duration // open orders older than this are reported as breaching
+	MaxStaleness          time.Duration // how long a business metrics snapshot is reused across scrapes
+	PerRestaurantLabelMax int           // per-restaurant series only while restaurant count is below this
+}
+
 // Config holds all application configuration
 type Config struct {
-	Database     string
-	OAuth        *OAuthConfig
-	Server       *ServerConfig
+	Database       string
+	DatabasePool   storage.PoolConfig
+	DatabaseRetry  storage.ConnectRetry
+	SeedSampleData bool // SEED_SAMPLE_DATA, off unless set
+	OAuth          *OAuthConfig
+	Server         *ServerConfig
+	Metrics        *MetricsConfig
 }
 
 // Load loads configuration from environment variables
@@ -53,12 +72,26 @@ func Load() (*Config, error) {
 	if config.Database == "" {
 		config.Database = "host=localhost port=5432 user=postgres password=postgres dbname=mcp_restaurant sslmode=disable"
 	}
+	pool, err := storage.PoolConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	config.DatabasePool = pool
+	if config.DatabaseRetry, err = storage.ConnectRetryFromEnv(); err != nil {
+		return nil, err
+	}
+	if config.SeedSampleData, err = storage.SeedFromEnv(false); err != nil {
+		return nil, err
+	}
 
 	// Server configuration
 	config.Server = &ServerConfig{
 		Host:              os.Getenv("HOST"),
 		Port:              os.Getenv("PORT"),
 		OAuthServerURL:    os.Getenv("OAUTH_SERVER_URL"),
+		PublicBaseURL:     os.Getenv("PUBLIC_BASE_URL"),
+		TrustedProxies:    publicurl.SplitList(os.Getenv("TRUSTED_PROXIES")),
+		AllowedOrigins:    publicurl.SplitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
 		JWTSecret:         os.Getenv("JWT_SECRET"),
 		DefaultAdminEmail: os.Getenv("DEFAULT_ADMIN_EMAIL"),
 		DefaultAdminName:  os.Getenv("DEFAULT_ADMIN_NAME"),
@@ -110,6 +143,13 @@ func Load() (*Config, error) {
 		config.Server.RefreshTokenLife = lifetime
 	}
 
+	// Metrics configuration
+	metricsConfig, err := loadMetricsConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.Metrics = metricsConfig
+
 	// OAuth configuration
 	oauthConfig, err := loadOAuthConfig(config.Server.OAuthServerURL)
 	if err != nil {
@@ -120,6 +160,39 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// loadMetricsConfig loads business metrics settings from environment
+func loadMetricsConfig() (*MetricsConfig, error) {
+	config := &MetricsConfig{
+		OrderSLA:              45 * time.Minute,
+		MaxStaleness:          30 * time.Second,
+		PerRestaurantLabelMax: 50,
+	}
+
+	if v := os.Getenv("ORDER_SLA_MINUTES"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			return nil, fmt.Errorf("invalid ORDER_SLA_MINUTES: %q", v)
+		}
+		config.OrderSLA = time.Duration(minutes) * time.Minute
+	}
+	if v := os.Getenv("METRICS_MAX_STALENESS_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid METRICS_MAX_STALENESS_SECONDS: %q", v)
+		}
+		config.MaxStaleness = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("METRICS_PER_RESTAURANT_LABEL_MAX"); v != "" {
+		max, err := strconv.Atoi(v)
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("invalid METRICS_PER_RESTAURANT_LABEL_MAX: %q", v)
+		}
+		config.PerRestaurantLabelMax = max
+	}
+
+	return config, nil
+}
+
 // loadOAuthConfig loads OAuth provider configuration from environment
 func loadOAuthConfig(serverURL string) (*OAuthConfig, error) {
 	provider := os.Getenv("OAUTH_PROVIDER")
@@ -189,5 +262,11 @@ func (c *Config) Validate() error {
 	if c.OAuth.ClientID == "" || c.OAuth.ClientSecret == "" {
 		return errors.New("OAuth client credentials are required")
 	}
+	if _, err := publicurl.New(c.Server.PublicBaseURL, c.Server.OAuthServerURL, c.Server.TrustedProxies); err != nil {
+		return err
+	}
+	if _, err := middleware.NewCORSPolicy(c.Server.AllowedOrigins); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,18 @@
+//go:build !faultinject
+
+package faultinject
+
+import (
+	"net/http"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Enabled reports whether this build has the faultinject tag
+const Enabled = false
+
+// WrapStore returns s
+func WrapStore(s storage.Store) storage.Store { return s }
+
+// Middleware returns next
+func Middleware(next http.Handler) http.Handler { return next }
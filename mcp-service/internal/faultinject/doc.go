@@ -0,0 +1,11 @@
+// Package faultinject makes the storage layer and the HTTP server fail on
+// demand, to check tool timeouts, retries and health probes under failure.
+//
+// It is compiled in only with the faultinject build tag:
+//
+//	go test -tags faultinject ./...
+//
+// Without the tag WrapStore and Middleware return what they are given and
+// the inject_fault tool doesn't exist, so production builds can wire them in
+// unconditionally at no cost.
+package faultinject
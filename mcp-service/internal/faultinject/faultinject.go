@@ -0,0 +1,176 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Enabled reports whether this build has the faultinject tag
+const Enabled = true
+
+// Fault kinds
+const (
+	KindDelay = "delay" // the call first waits DelayMS, or until its context ends
+	KindError = "error" // the call fails with ErrInjected
+)
+
+// Targets besides a single storage.Store method
+const (
+	TargetStorage = "storage" // every storage method
+	TargetHTTP    = "http"    // every request through Middleware gets a 503
+)
+
+// MaxSeconds caps how long one fault stays active, so a forgotten fault
+// can't outlive the test run by much
+const MaxSeconds = 600
+
+// ErrInjected is the error of a storage call failed by a fault
+var ErrInjected = errors.New("injected fault")
+
+// Fault makes calls to Target misbehave until Until
+type Fault struct {
+	Target  string    `json:"target"`
+	Kind    string    `json:"kind"`
+	DelayMS int       `json:"delay_ms,omitempty"` // for KindDelay
+	Until   time.Time `json:"until"`
+}
+
+// storageMethods are the targetable storage.Store methods
+var storageMethods = func() []string {
+	t := reflect.TypeFor[storage.Store]()
+	names := make([]string, t.NumMethod())
+	for i := range names {
+		names[i] = t.Method(i).Name
+	}
+	return names
+}()
+
+// faults are the active faults, at most one per target. They are process
+// wide, like the storage and HTTP stack they break.
+var faults struct {
+	mu     sync.Mutex
+	active map[string]Fault
+}
+
+// Args reads the inject_fault tool arguments into a fault starting at now
+func Args(args map[string]interface{}, now time.Time) (Fault, error) {
+	f := Fault{Kind: KindError}
+	f.Target, _ = args["target"].(string)
+	if kind, _ := args["kind"].(string); kind != "" {
+		f.Kind = kind
+	}
+	seconds, _ := args["seconds"].(float64)
+	delay, _ := args["delay_ms"].(float64)
+	f.DelayMS = int(delay)
+
+	switch {
+	case f.Target != TargetStorage && f.Target != TargetHTTP && !slices.Contains(storageMethods, f.Target):
+		return f, fmt.Errorf("invalid target %q: must be %s, %s or a storage method such as GetRestaurantByID", f.Target, TargetStorage, TargetHTTP)
+	case f.Kind != KindDelay && f.Kind != KindError:
+		return f, fmt.Errorf("invalid kind %q: must be %s or %s", f.Kind, KindDelay, KindError)
+	case f.Target == TargetHTTP && f.Kind != KindError:
+		return f, fmt.Errorf("the %s target only takes kind %s, a 503", TargetHTTP, KindError)
+	case seconds <= 0 || seconds > MaxSeconds || seconds != math.Trunc(seconds):
+		return f, fmt.Errorf("seconds must be a whole number from 1 to %d", MaxSeconds)
+	case f.Kind == KindDelay && f.DelayMS <= 0:
+		return f, fmt.Errorf("delay_ms must be positive for a %s fault", KindDelay)
+	}
+	if f.Kind != KindDelay {
+		f.DelayMS = 0
+	}
+	f.Until = now.Add(time.Duration(seconds) * time.Second)
+	return f, nil
+}
+
+// Inject activates f, replacing any fault on the same target
+func Inject(f Fault) {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	if faults.active == nil {
+		faults.active = map[string]Fault{}
+	}
+	faults.active[f.Target] = f
+}
+
+// Clear ends every fault
+func Clear() {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	faults.active = nil
+}
+
+// Active returns the faults still active at now, by target
+func Active(now time.Time) []Fault {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	var active []Fault
+	for target, f := range faults.active {
+		if !now.Before(f.Until) {
+			delete(faults.active, target)
+			continue
+		}
+		active = append(active, f)
+	}
+	slices.SortFunc(active, func(a, b Fault) int { return strings.Compare(a.Target, b.Target) })
+	return active
+}
+
+// find returns the active fault for target, preferring one on the exact
+// target over a catch-all one
+func find(targets ...string) (Fault, bool) {
+	now := time.Now()
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	for _, target := range targets {
+		if f, ok := faults.active[target]; ok && now.Before(f.Until) {
+			return f, true
+		}
+	}
+	return Fault{}, false
+}
+
+// hit applies the active fault, if any, to a call of the storage method
+func hit(ctx context.Context, method string) error {
+	f, ok := find(method, TargetStorage)
+	if !ok {
+		return nil
+	}
+	if f.Kind == KindError {
+		return fmt.Errorf("%s: %w", method, ErrInjected)
+	}
+	timer := time.NewTimer(time.Duration(f.DelayMS) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Middleware answers every request with a 503 while an http fault is
+// active, with Retry-After set to when it ends
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f, ok := find(TargetHTTP); ok {
+			retry := int(math.Ceil(time.Until(f.Until).Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retry, 1)))
+			http.Error(w, "Service unavailable (injected fault)", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,108 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+)
+
+func inject(t *testing.T, args map[string]interface{}) {
+	t.Helper()
+	f, err := Args(args, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Inject(f)
+	t.Cleanup(Clear)
+}
+
+func TestArgs(t *testing.T) {
+	now := time.Date(2026, 5, 18, 12, 0, 0, 0, time.UTC)
+	f, err := Args(map[string]interface{}{"target": "GetRestaurantByID", "kind": "delay", "delay_ms": 250.0, "seconds": 30.0}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Fault{Target: "GetRestaurantByID", Kind: KindDelay, DelayMS: 250, Until: now.Add(30 * time.Second)}); f != want {
+		t.Errorf("Args = %+v, want %+v", f, want)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"target": "DropDatabase", "seconds": 10.0},
+		{"target": "storage", "kind": "panic", "seconds": 10.0},
+		{"target": "http", "kind": "delay", "delay_ms": 10.0, "seconds": 10.0},
+		{"target": "storage", "seconds": 0.0},
+		{"target": "storage", "seconds": float64(MaxSeconds + 1)},
+		{"target": "storage", "kind": "delay", "seconds": 10.0},
+	} {
+		if _, err := Args(args, now); err == nil {
+			t.Errorf("Args(%v) accepted", args)
+		}
+	}
+}
+
+func TestStoreFaults(t *testing.T) {
+	ctx := context.Background()
+	s := WrapStore(memory.New())
+	r := models.Restaurant{Name: "Test Kitchen"}
+	if err := s.CreateRestaurant(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	inject(t, map[string]interface{}{"target": "GetRestaurantByID", "seconds": 60.0})
+	if _, err := s.GetRestaurantByID(ctx, r.ID); !errors.Is(err, ErrInjected) {
+		t.Errorf("GetRestaurantByID under an error fault = %v, want ErrInjected", err)
+	}
+	if _, _, err := s.GetAllRestaurants(ctx, models.Page{Limit: 10}, ""); err != nil {
+		t.Errorf("GetAllRestaurants without a fault = %v", err)
+	}
+
+	inject(t, map[string]interface{}{"target": "storage", "kind": "delay", "delay_ms": 5000.0, "seconds": 60.0})
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := s.GetAllRestaurants(timeout, models.Page{Limit: 10}, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetAllRestaurants under a delay fault = %v, want the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("delayed call took %s, want it cut short by the context", elapsed)
+	}
+	if _, err := s.GetRestaurantByID(ctx, r.ID); !errors.Is(err, ErrInjected) {
+		t.Errorf("the exact target's fault = %v, want it to win over the storage one", err)
+	}
+
+	Clear()
+	if _, err := s.GetRestaurantByID(ctx, r.ID); err != nil {
+		t.Errorf("after Clear = %v", err)
+	}
+}
+
+func TestMiddlewareFault(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w
+	}
+
+	if w := serve(); w.Code != http.StatusOK {
+		t.Fatalf("without a fault: %d", w.Code)
+	}
+	inject(t, map[string]interface{}{"target": "http", "seconds": 30.0})
+	if w := serve(); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("under an http fault: %d, Retry-After %q; want 503 after 30", w.Code, w.Header().Get("Retry-After"))
+	}
+	if active := Active(time.Now().Add(time.Minute)); len(active) != 0 {
+		t.Errorf("faults active after they ended: %+v", active)
+	}
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("after the fault ended: %d", w.Code)
+	}
+}
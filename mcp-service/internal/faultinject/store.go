@@ -0,0 +1,452 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"context"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// store runs every method of the wrapped storage.Store through the active
+// faults first. It doesn't embed the Store, so a method added to the
+// interface fails the build here until it is wrapped too.
+type store struct {
+	next storage.Store
+}
+
+var _ storage.Store = store{}
+
+// WrapStore returns s with the injected faults applied to its methods
+func WrapStore(s storage.Store) storage.Store {
+	return store{s}
+}
+
+func (s store) EnsureConnected(ctx context.Context) error {
+	if err := hit(ctx, "EnsureConnected"); err != nil {
+		return err
+	}
+	return s.next.EnsureConnected(ctx)
+}
+
+func (s store) GetAllRestaurants(ctx context.Context, page models.Page, sort string) ([]models.Restaurant, int, error) {
+	if err := hit(ctx, "GetAllRestaurants"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetAllRestaurants(ctx, page, sort)
+}
+
+func (s store) SearchRestaurants(ctx context.Context, query, cuisine string, limit int) ([]models.Restaurant, error) {
+	if err := hit(ctx, "SearchRestaurants"); err != nil {
+		return nil, err
+	}
+	return s.next.SearchRestaurants(ctx, query, cuisine, limit)
+}
+
+func (s store) GetRestaurantByID(ctx context.Context, id int) (*models.Restaurant, error) {
+	if err := hit(ctx, "GetRestaurantByID"); err != nil {
+		return nil, err
+	}
+	return s.next.GetRestaurantByID(ctx, id)
+}
+
+func (s store) CreateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := hit(ctx, "CreateRestaurant"); err != nil {
+		return err
+	}
+	return s.next.CreateRestaurant(ctx, r)
+}
+
+func (s store) UpdateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := hit(ctx, "UpdateRestaurant"); err != nil {
+		return err
+	}
+	return s.next.UpdateRestaurant(ctx, r)
+}
+
+func (s store) PatchRestaurant(ctx context.Context, id int, patch models.RestaurantPatch) (*models.Restaurant, error) {
+	if err := hit(ctx, "PatchRestaurant"); err != nil {
+		return nil, err
+	}
+	return s.next.PatchRestaurant(ctx, id, patch)
+}
+
+func (s store) DeleteRestaurant(ctx context.Context, id int, opts models.RestaurantDeleteOptions) (models.RestaurantDeletion, error) {
+	if err := hit(ctx, "DeleteRestaurant"); err != nil {
+		return models.RestaurantDeletion{}, err
+	}
+	return s.next.DeleteRestaurant(ctx, id, opts)
+}
+
+func (s store) RestoreRestaurant(ctx context.Context, id int) (*models.Restaurant, error) {
+	if err := hit(ctx, "RestoreRestaurant"); err != nil {
+		return nil, err
+	}
+	return s.next.RestoreRestaurant(ctx, id)
+}
+
+func (s store) GetMenuByRestaurantID(ctx context.Context, restaurantID int, includeUnavailable bool) ([]models.MenuItem, error) {
+	if err := hit(ctx, "GetMenuByRestaurantID"); err != nil {
+		return nil, err
+	}
+	return s.next.GetMenuByRestaurantID(ctx, restaurantID, includeUnavailable)
+}
+
+func (s store) GetMenuAsOf(ctx context.Context, restaurantID int, asOf string, includeUnavailable bool) ([]models.MenuItem, error) {
+	if err := hit(ctx, "GetMenuAsOf"); err != nil {
+		return nil, err
+	}
+	return s.next.GetMenuAsOf(ctx, restaurantID, asOf, includeUnavailable)
+}
+
+func (s store) SearchMenuItems(ctx context.Context, q models.MenuSearch) ([]models.MenuItem, error) {
+	if err := hit(ctx, "SearchMenuItems"); err != nil {
+		return nil, err
+	}
+	return s.next.SearchMenuItems(ctx, q)
+}
+
+func (s store) GetMenuItemByID(ctx context.Context, id int) (*models.MenuItem, error) {
+	if err := hit(ctx, "GetMenuItemByID"); err != nil {
+		return nil, err
+	}
+	return s.next.GetMenuItemByID(ctx, id)
+}
+
+func (s store) GetMenuItemsByIDs(ctx context.Context, ids []int) (map[int]models.MenuItem, error) {
+	if err := hit(ctx, "GetMenuItemsByIDs"); err != nil {
+		return nil, err
+	}
+	return s.next.GetMenuItemsByIDs(ctx, ids)
+}
+
+func (s store) CreateMenuItem(ctx context.Context, m *models.MenuItem) error {
+	if err := hit(ctx, "CreateMenuItem"); err != nil {
+		return err
+	}
+	return s.next.CreateMenuItem(ctx, m)
+}
+
+func (s store) CreateMenuItems(ctx context.Context, items []*models.MenuItem) error {
+	if err := hit(ctx, "CreateMenuItems"); err != nil {
+		return err
+	}
+	return s.next.CreateMenuItems(ctx, items)
+}
+
+func (s store) UpdateMenuItem(ctx context.Context, m *models.MenuItem, changedBy string) error {
+	if err := hit(ctx, "UpdateMenuItem"); err != nil {
+		return err
+	}
+	return s.next.UpdateMenuItem(ctx, m, changedBy)
+}
+
+func (s store) SetStock(ctx context.Context, menuItemID int, quantity *int) (*models.MenuItem, error) {
+	if err := hit(ctx, "SetStock"); err != nil {
+		return nil, err
+	}
+	return s.next.SetStock(ctx, menuItemID, quantity)
+}
+
+func (s store) DeleteMenuItem(ctx context.Context, id int) error {
+	if err := hit(ctx, "DeleteMenuItem"); err != nil {
+		return err
+	}
+	return s.next.DeleteMenuItem(ctx, id)
+}
+
+func (s store) GetPriceHistory(ctx context.Context, q models.PriceHistoryQuery, page models.Page) ([]models.PriceChange, int, error) {
+	if err := hit(ctx, "GetPriceHistory"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetPriceHistory(ctx, q, page)
+}
+
+func (s store) LastPriceChanges(ctx context.Context, restaurantID int) (map[int]time.Time, error) {
+	if err := hit(ctx, "LastPriceChanges"); err != nil {
+		return nil, err
+	}
+	return s.next.LastPriceChanges(ctx, restaurantID)
+}
+
+func (s store) GetAllOrders(ctx context.Context, page models.Page) ([]models.Order, int, error) {
+	if err := hit(ctx, "GetAllOrders"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetAllOrders(ctx, page)
+}
+
+func (s store) GetOrdersByRestaurantID(ctx context.Context, restaurantID int, page models.Page) ([]models.Order, int, error) {
+	if err := hit(ctx, "GetOrdersByRestaurantID"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetOrdersByRestaurantID(ctx, restaurantID, page)
+}
+
+func (s store) GetOrdersByStatus(ctx context.Context, statuses []string, page models.Page) ([]models.Order, int, error) {
+	if err := hit(ctx, "GetOrdersByStatus"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetOrdersByStatus(ctx, statuses, page)
+}
+
+func (s store) GetOrders(ctx context.Context, f models.OrderFilter, page models.Page) ([]models.Order, int, error) {
+	if err := hit(ctx, "GetOrders"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetOrders(ctx, f, page)
+}
+
+func (s store) GetOrdersByCustomerPhone(ctx context.Context, phone string, page models.Page) ([]models.Order, models.CustomerSummary, error) {
+	if err := hit(ctx, "GetOrdersByCustomerPhone"); err != nil {
+		return nil, models.CustomerSummary{}, err
+	}
+	return s.next.GetOrdersByCustomerPhone(ctx, phone, page)
+}
+
+func (s store) GetSalesReport(ctx context.Context, q models.SalesQuery) (*models.SalesReport, error) {
+	if err := hit(ctx, "GetSalesReport"); err != nil {
+		return nil, err
+	}
+	return s.next.GetSalesReport(ctx, q)
+}
+
+func (s store) GetTopSellingItems(ctx context.Context, q models.TopItemsQuery) ([]models.ItemSales, error) {
+	if err := hit(ctx, "GetTopSellingItems"); err != nil {
+		return nil, err
+	}
+	return s.next.GetTopSellingItems(ctx, q)
+}
+
+func (s store) GetOrderDigest(ctx context.Context, q models.DigestQuery) (*models.OrderDigest, error) {
+	if err := hit(ctx, "GetOrderDigest"); err != nil {
+		return nil, err
+	}
+	return s.next.GetOrderDigest(ctx, q)
+}
+
+func (s store) GetRestaurantStats(ctx context.Context, restaurantID int) (*models.RestaurantStats, error) {
+	if err := hit(ctx, "GetRestaurantStats"); err != nil {
+		return nil, err
+	}
+	return s.next.GetRestaurantStats(ctx, restaurantID)
+}
+
+func (s store) GetOrderByID(ctx context.Context, id int) (*models.Order, error) {
+	if err := hit(ctx, "GetOrderByID"); err != nil {
+		return nil, err
+	}
+	return s.next.GetOrderByID(ctx, id)
+}
+
+func (s store) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]models.OrderItem, error) {
+	if err := hit(ctx, "GetOrderItemsByOrderID"); err != nil {
+		return nil, err
+	}
+	return s.next.GetOrderItemsByOrderID(ctx, orderID)
+}
+
+func (s store) CreateOrder(ctx context.Context, o *models.Order) error {
+	if err := hit(ctx, "CreateOrder"); err != nil {
+		return err
+	}
+	return s.next.CreateOrder(ctx, o)
+}
+
+func (s store) UpdateOrder(ctx context.Context, o *models.Order) error {
+	if err := hit(ctx, "UpdateOrder"); err != nil {
+		return err
+	}
+	return s.next.UpdateOrder(ctx, o)
+}
+
+func (s store) UpdateOrderItems(ctx context.Context, orderID int, items []models.OrderItem) (*models.Order, error) {
+	if err := hit(ctx, "UpdateOrderItems"); err != nil {
+		return nil, err
+	}
+	return s.next.UpdateOrderItems(ctx, orderID, items)
+}
+
+func (s store) SetOrderTip(ctx context.Context, orderID int, tip models.Money) (*models.Order, error) {
+	if err := hit(ctx, "SetOrderTip"); err != nil {
+		return nil, err
+	}
+	return s.next.SetOrderTip(ctx, orderID, tip)
+}
+
+func (s store) SplitOrder(ctx context.Context, orderID int, moves []models.OrderItem) (*models.Order, *models.Order, error) {
+	if err := hit(ctx, "SplitOrder"); err != nil {
+		return nil, nil, err
+	}
+	return s.next.SplitOrder(ctx, orderID, moves)
+}
+
+func (s store) MergeOrders(ctx context.Context, orderIDs []int) (*models.Order, error) {
+	if err := hit(ctx, "MergeOrders"); err != nil {
+		return nil, err
+	}
+	return s.next.MergeOrders(ctx, orderIDs)
+}
+
+func (s store) AssignDelivery(ctx context.Context, orderID int, riderName, riderPhone string) (*models.Order, error) {
+	if err := hit(ctx, "AssignDelivery"); err != nil {
+		return nil, err
+	}
+	return s.next.AssignDelivery(ctx, orderID, riderName, riderPhone)
+}
+
+func (s store) CancelOrder(ctx context.Context, orderID int, reason, note, cancelledBy string) (*models.Order, error) {
+	if err := hit(ctx, "CancelOrder"); err != nil {
+		return nil, err
+	}
+	return s.next.CancelOrder(ctx, orderID, reason, note, cancelledBy)
+}
+
+func (s store) DeleteOrder(ctx context.Context, id int) error {
+	if err := hit(ctx, "DeleteOrder"); err != nil {
+		return err
+	}
+	return s.next.DeleteOrder(ctx, id)
+}
+
+func (s store) RecordOrderFeedback(ctx context.Context, f *models.OrderFeedback) error {
+	if err := hit(ctx, "RecordOrderFeedback"); err != nil {
+		return err
+	}
+	return s.next.RecordOrderFeedback(ctx, f)
+}
+
+func (s store) GetOrderFeedback(ctx context.Context, orderID int) (*models.OrderFeedback, error) {
+	if err := hit(ctx, "GetOrderFeedback"); err != nil {
+		return nil, err
+	}
+	return s.next.GetOrderFeedback(ctx, orderID)
+}
+
+func (s store) GetOrderHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error) {
+	if err := hit(ctx, "GetOrderHistory"); err != nil {
+		return nil, err
+	}
+	return s.next.GetOrderHistory(ctx, orderID)
+}
+
+func (s store) ExportOrders(ctx context.Context, restaurantID int, from, to time.Time, row func(models.OrderExportRow) error) error {
+	if err := hit(ctx, "ExportOrders"); err != nil {
+		return err
+	}
+	return s.next.ExportOrders(ctx, restaurantID, from, to, row)
+}
+
+func (s store) ProposeChange(ctx context.Context, c *models.ChangeRequest) error {
+	if err := hit(ctx, "ProposeChange"); err != nil {
+		return err
+	}
+	return s.next.ProposeChange(ctx, c)
+}
+
+func (s store) ListChangeRequests(ctx context.Context, status string, page models.Page) ([]models.ChangeRequest, int, error) {
+	if err := hit(ctx, "ListChangeRequests"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.ListChangeRequests(ctx, status, page)
+}
+
+func (s store) ReviewChangeRequest(ctx context.Context, id int, approve bool, reviewer string) (*models.ChangeRequest, error) {
+	if err := hit(ctx, "ReviewChangeRequest"); err != nil {
+		return nil, err
+	}
+	return s.next.ReviewChangeRequest(ctx, id, approve, reviewer)
+}
+
+func (s store) RecordShadowedAction(ctx context.Context, a *models.ShadowedAction) error {
+	if err := hit(ctx, "RecordShadowedAction"); err != nil {
+		return err
+	}
+	return s.next.RecordShadowedAction(ctx, a)
+}
+
+func (s store) ListShadowedActions(ctx context.Context, status string, page models.Page) ([]models.ShadowedAction, int, error) {
+	if err := hit(ctx, "ListShadowedActions"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.ListShadowedActions(ctx, status, page)
+}
+
+func (s store) ClaimShadowedAction(ctx context.Context, id int, replayer string) (*models.ShadowedAction, error) {
+	if err := hit(ctx, "ClaimShadowedAction"); err != nil {
+		return nil, err
+	}
+	return s.next.ClaimShadowedAction(ctx, id, replayer)
+}
+
+func (s store) FinishShadowedAction(ctx context.Context, id int, failed bool, result string) error {
+	if err := hit(ctx, "FinishShadowedAction"); err != nil {
+		return err
+	}
+	return s.next.FinishShadowedAction(ctx, id, failed, result)
+}
+
+func (s store) ArchiveOrders(ctx context.Context, before time.Time) (int, error) {
+	if err := hit(ctx, "ArchiveOrders"); err != nil {
+		return 0, err
+	}
+	return s.next.ArchiveOrders(ctx, before)
+}
+
+func (s store) GetArchivedOrder(ctx context.Context, id int) (*models.Order, error) {
+	if err := hit(ctx, "GetArchivedOrder"); err != nil {
+		return nil, err
+	}
+	return s.next.GetArchivedOrder(ctx, id)
+}
+
+func (s store) RecordAudit(ctx context.Context, e *models.AuditEntry) error {
+	if err := hit(ctx, "RecordAudit"); err != nil {
+		return err
+	}
+	return s.next.RecordAudit(ctx, e)
+}
+
+func (s store) GetAuditLog(ctx context.Context, q models.AuditQuery, page models.Page) ([]models.AuditEntry, int, error) {
+	if err := hit(ctx, "GetAuditLog"); err != nil {
+		return nil, 0, err
+	}
+	return s.next.GetAuditLog(ctx, q, page)
+}
+
+func (s store) CreatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := hit(ctx, "CreatePricingRule"); err != nil {
+		return err
+	}
+	return s.next.CreatePricingRule(ctx, r)
+}
+
+func (s store) ListPricingRules(ctx context.Context, restaurantID int) ([]models.PricingRule, error) {
+	if err := hit(ctx, "ListPricingRules"); err != nil {
+		return nil, err
+	}
+	return s.next.ListPricingRules(ctx, restaurantID)
+}
+
+func (s store) GetPricingRule(ctx context.Context, id int) (*models.PricingRule, error) {
+	if err := hit(ctx, "GetPricingRule"); err != nil {
+		return nil, err
+	}
+	return s.next.GetPricingRule(ctx, id)
+}
+
+func (s store) UpdatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := hit(ctx, "UpdatePricingRule"); err != nil {
+		return err
+	}
+	return s.next.UpdatePricingRule(ctx, r)
+}
+
+func (s store) DeletePricingRule(ctx context.Context, id int) error {
+	if err := hit(ctx, "DeletePricingRule"); err != nil {
+		return err
+	}
+	return s.next.DeletePricingRule(ctx, id)
+}
@@ -0,0 +1,17 @@
+//go:build !faultinject
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// TestToolsListGolden keeps mcptest.CatalogGolden, the catalog the stdio
+// and SSE servers are compared with, in step with /mcp. It is the catalog
+// of the default build; faultinject builds list fault tools as well.
+func TestToolsListGolden(t *testing.T) {
+	storagetest.AssertGolden(t, "tools_list", mcptest.ToolsList(t, conversePOST))
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// TestPriceUpdateIsReadBackThroughRESTAndMCP changes a price and reads the
+// menu straight back through both views, wired as cmd/api wires them: one
+// store shared by the REST and MCP handlers. REST has no endpoint that
+// writes menu items, so the change goes through the MCP tool.
+func TestPriceUpdateIsReadBackThroughRESTAndMCP(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	if _, err := db.Exec(`INSERT INTO user_profiles (user_id, email, name, role) VALUES ('owner-1', 'owner@example.com', 'Asha Rao', 'owner')`); err != nil {
+		t.Fatal(err)
+	}
+	rest := NewRestaurantHandlerWithStore(db.DB, db, nil)
+	h := NewMCPHandlerWithStore(db.DB, db)
+	owner := context.WithValue(t.Context(), oauth.UserContextKey, map[string]interface{}{"sub": "owner-1", "email": "owner@example.com"})
+	callTool := func(name, args string) string {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":`+args+`}}`)).WithContext(owner)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, req)
+		return w.Body.String()
+	}
+	restMenu := func() string {
+		w := httptest.NewRecorder()
+		rest.GetMenu(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/restaurants/menu?restaurant_id=%d", r.Restaurant.ID), nil))
+		return w.Body.String()
+	}
+	getMenu := fmt.Sprintf(`{"restaurant_id":%d}`, r.Restaurant.ID)
+	if body := callTool("get_menu", getMenu); !strings.Contains(body, r.Menu[0].Name) {
+		t.Fatalf("get_menu before the update: %s", body)
+	}
+
+	if body := callTool("update_menu_item", fmt.Sprintf(`{"id":%d,"price":437.5}`, r.Menu[0].ID)); strings.Contains(body, `"isError":true`) {
+		t.Fatalf("update_menu_item: %s", body)
+	}
+	if body := callTool("get_menu", getMenu); !strings.Contains(body, "437.5") {
+		t.Errorf("get_menu right after the update: %s, want the new price 437.5", body)
+	}
+	if body := restMenu(); !strings.Contains(body, "437.5") {
+		t.Errorf("GET /api/restaurants/menu right after the update: %s, want the new price 437.5", body)
+	}
+}
@@ -1,42 +1,95 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"time"
 
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/compat"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
 	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolerrors"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
 )
 
+// MCPHandler holds the tool catalog shared by every MCP entry point: /mcp
+// here, and the stdio and SSE servers of cmd/mcp and cmd/remote-mcp, which
+// only add their transport around Server.
 type MCPHandler struct {
-	db *sql.DB
+	db     *sql.DB        // Postgres, for user roles, run_report and tokens; nil without it
+	store  storage.Store  // everything else
+	tokens *oauth.Storage // restaurant API tokens
+
+	failures *toolerrors.Ring // recent failed tool calls, for get_recent_errors
+	scopes   authz.ScopeSource
+	shadow   ShadowMode // destructive tools recorded instead of run
+
+	anonymousRole string // see SetAnonymousRole
+	server        *mcp.Server
 }
 
 func NewMCPHandler(db *sql.DB) *MCPHandler {
-	return &MCPHandler{db: db}
+	return NewMCPHandlerWithStore(db, storage.Wrap(db))
 }
 
-// MCP JSON-RPC types
-type MCPRequest struct {
-	JSONRPC string          `json:"jsonrpc"`
-	Method  string          `json:"method"`
-	Params  json.RawMessage `json:"params,omitempty"`
-	ID      interface{}     `json:"id"`
+// NewMCPHandlerWithStore uses store for the tools, e.g. a memory.Store in
+// tests or SQLite for the stdio server. db may be nil when there is no
+// Postgres; the tools that need it then fail.
+func NewMCPHandlerWithStore(db *sql.DB, store storage.Store) *MCPHandler {
+	h := &MCPHandler{db: db, store: store, tokens: oauth.NewStorage(db), failures: toolerrors.NewRing(toolerrors.BufferSize), scopes: authz.UnscopedSource}
+	h.server = mcp.NewServer("restaurant-mcp-server", "1.0.0")
+	handlers := h.toolHandlers()
+	for _, tool := range toolDefinitions() {
+		name, _ := tool["name"].(string)
+		h.server.RegisterTool(tool, toolExamples[name], handlers[name])
+	}
+	h.server.Use(h.intercept)
+	h.server.Truncate = func(content []mcp.Content) []mcp.Content {
+		return mw.TruncateContent(content, mw.MaxResponseBytes)
+	}
+	h.server.SetResources(restaurantResources{h})
+	h.registerPrompts()
+	return h
 }
 
-type MCPResponse struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Result  interface{} `json:"result,omitempty"`
-	Error   *MCPError   `json:"error,omitempty"`
-	ID      interface{} `json:"id"`
+// Server is the MCP server of the tool catalog, for transports other than
+// HandleMCP to pass requests to
+func (h *MCPHandler) Server() *mcp.Server {
+	return h.server
 }
 
-type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+// SetAnonymousRole sets the role of requests without an authenticated user,
+// which only the unauthenticated transports see: models.RoleAdmin for the
+// stdio server, run by the operator, or models.RoleOwner for the SSE
+// server. Such requests have no role by default.
+func (h *MCPHandler) SetAnonymousRole(role string) {
+	h.anonymousRole = role
 }
 
+// SetScopeSource decides which restaurants each user may look up; see
+// package authz. Every user is unscoped by default.
+func (h *MCPHandler) SetScopeSource(scopes authz.ScopeSource) {
+	h.scopes = scopes
+}
+
+// MCP JSON-RPC types, shared with the other transports
+type (
+	MCPRequest  = mcp.Request
+	MCPResponse = mcp.Response
+	MCPError    = mcp.Error
+)
+
 // HandleMCP handles MCP JSON-RPC requests
 func (h *MCPHandler) HandleMCP(w http.ResponseWriter, r *http.Request) {
 	if mw.IsDebug() {
@@ -48,8 +101,20 @@ func (h *MCPHandler) HandleMCP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !mw.IsJSONContentType(r) {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
 	var req MCPRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if mw.IsBodyTooLarge(err) {
+			// sendError sets the header too, but after WriteHeader it's too late
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			h.sendError(w, jsonrpc.NullID, -32600, "Request too large")
+			return
+		}
 		h.sendError(w, req.ID, -32700, "Parse error")
 		return
 	}
@@ -58,248 +123,525 @@ func (h *MCPHandler) HandleMCP(w http.ResponseWriter, r *http.Request) {
 		log.Printf("MCP method: %s", req.Method)
 	}
 
-	var response MCPResponse
-
-	switch req.Method {
-	case "initialize":
-		response = h.handleInitialize(req.ID)
-	case "notifications/initialized":
-		if mw.IsDebug() { log.Println("Client initialized notification") }
+	// /mcp keeps no session, so requests after initialize carry the
+	// negotiated version in the Mcp-Protocol-Version header
+	sess := mcp.RequestSession(r.Header.Get("Mcp-Session-Id"), r.Header.Get(mcp.ProtocolVersionHeader))
+	response, ok := h.server.HandleRequest(r.Context(), sess, req)
+	if !ok {
 		w.WriteHeader(http.StatusOK)
 		return // No response for notifications
-	case "tools/list":
-		response = h.handleToolsList(req.ID)
-	case "tools/call":
-		response = h.handleToolsCall(req)
-	default:
-		response = MCPResponse{
-			JSONRPC: "2.0",
-			Error: &MCPError{
-				Code:    -32601,
-				Message: "Method not found: " + req.Method,
-			},
-			ID: req.ID,
-		}
 	}
 
+	mw.SetWriteDeadline(w)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *MCPHandler) handleInitialize(id interface{}) MCPResponse {
-	if mw.IsDebug() {
-		log.Printf("Initialize request")
-	}
-
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
-			},
-			"serverInfo": map[string]interface{}{
-				"name":    "restaurant-mcp-server",
-				"version": "1.0.0",
-			},
-		},
-		ID: id,
-	}
+// toolExamples holds at least one sample invocation per tool; each must
+// validate against the tool's own input schema
+var toolExamples = map[string][]toolschema.Example{
+	"list_restaurants":        {{}, {"sort": "name", "limit": 10}},
+	"search_restaurants":      {{"query": "Banjara Hills"}, {"cuisine": "South Indian", "limit": 5}},
+	"get_restaurant":          {{"id": 1}},
+	"create_restaurant":       {{"name": "Spice Garden", "address": "MG Road, Bengaluru", "phone_number": "+91-80-12345678", "email": "orders@spicegarden.in", "cuisine_type": "South Indian"}, {"name": "Chai Point", "address": "Park Street, Kolkata", "tax_rate": 0.18, "service_charge_percent": 7.5}},
+	"update_restaurant":       {{"id": 1, "phone_number": "+91-11-87654321", "notification_preferences": map[string]interface{}{"notify_on_low_stock": false}}, {"id": 1, "public_menu_enabled": true}, {"id": 1, "auto_confirm_orders": true}, {"id": 1, "tax_rate": 0.18}, {"id": 1, "service_charge_percent": 10}, {"id": 1, "address": "JM Road, Pune", "expected_version": 4}},
+	"delete_restaurant":       {{"id": 4}, {"id": 4, "hard_delete": true}, {"id": 4, "hard_delete": true, "force": true}},
+	"restore_restaurant":      {{"id": 4}},
+	"get_menu":                {{"restaurant_id": 1}, {"restaurant_id": 1, "as_of": "2026-12-01"}, {"restaurant_id": 1, "include_stats": true}, {"restaurant_id": 1, "include_unavailable": true}, {"restaurant_id": 1, "include_promotions": true}, {"restaurant_id": 1, "sort": "recently_changed"}},
+	"search_menu_items":       {{"restaurant_id": 1, "dietary_type": "vegan", "max_price": 200}, {"restaurant_id": 1, "query": "paneer", "spice_level": "mild"}},
+	"bulk_create_menu_items":  {{"restaurant_id": 1, "items": []interface{}{map[string]interface{}{"name": "Idli Sambar", "price": 70, "category": "Breakfast"}, map[string]interface{}{"name": "Medu Vada", "price": 60, "category": "Breakfast", "spice_level": "mild"}, map[string]interface{}{"name": "Chicken 65", "price": 240, "category": "Starter", "dietary_type": "non_vegetarian", "spice_level": "hot"}}}},
+	"create_menu_item":        {{"restaurant_id": 1, "name": "Chole Bhature", "description": "Spiced chickpeas with fried bread", "price": 180, "category": "Main Course", "dietary_type": "vegetarian", "spice_level": "medium"}, {"restaurant_id": 1, "name": "Mango Lassi", "price": 120, "category": "Beverage", "effective_from": "2026-04-01", "effective_to": "2026-06-30"}},
+	"update_menu_item":        {{"id": 3, "price": 260}, {"id": 3, "category": "Desserts", "expected_version": 2}, {"id": 3, "available": false}},
+	"set_stock":               {{"menu_item_id": 3, "quantity": 20}, {"menu_item_id": 3, "untracked": true}},
+	"delete_menu_item":        {{"id": 3}},
+	"get_price_history":       {{"menu_item_id": 3}, {"restaurant_id": 1, "from": "2026-01-01", "to": "2026-03-31"}},
+	"list_orders":             {{}, {"limit": 20, "offset": 40}},
+	"get_order":               {{"id": 1}, {"id": 1, "include_feedback": true}, {"id": 1, "include_archived": true}},
+	"get_customer_orders":     {{"customer_phone": "98200 12345"}, {"customer_phone": "+91-9820012345", "limit": 5}},
+	"get_sales_report":        {{"restaurant_id": 1, "from": "2024-05-01", "to": "2024-05-31"}},
+	"export_orders":           {{"restaurant_id": 1, "from": "2026-03-01", "to": "2026-03-31"}},
+	"archive_orders":          {{"before": "2026-01-01"}},
+	"summarize_orders":        {{"restaurant_id": 1, "date": "2024-05-18"}, {"restaurant_id": 1}},
+	"get_top_selling_items":   {{"restaurant_id": 1}, {"restaurant_id": 1, "since": "2024-05-01", "category": "Main Course", "limit": 5}},
+	"get_restaurant_stats":    {{"restaurant_id": 1}},
+	"create_order":            {{"restaurant_id": 1, "customer_name": "Asha Rao", "items": []interface{}{map[string]interface{}{"menu_item_id": 1, "quantity": 2}, map[string]interface{}{"menu_item_id": 4, "quantity": 1}}}, {"restaurant_id": 1, "customer_name": "Ravi Kumar", "items": []interface{}{map[string]interface{}{"menu_item_id": 2, "quantity": 1}}, "tip": 40, "idempotency_key": "3f6c2a9e-5b1d-4e8a-9c07-2d4b6e8f1a35"}, {"restaurant_id": 1, "customer_name": "Meera Iyer", "customer_phone": "+91-98450-12345", "order_type": "delivery", "payment_method": "upi", "billing_address": "12 Residency Road, Bengaluru", "items": []interface{}{map[string]interface{}{"menu_item_id": 3, "quantity": 1, "notes": "no onion"}}}},
+	"quick_order":             {{"restaurant_id": 1, "customer_name": "Table 4", "items": []interface{}{map[string]interface{}{"name": "masala dosa", "quantity": 2}, map[string]interface{}{"name": "filter coffee", "quantity": 2, "notes": "strong"}}}},
+	"update_order":            {{"id": 1, "status": "confirmed"}, {"id": 1, "tip": 50}, {"id": 1, "payment_status": "completed"}},
+	"assign_delivery":         {{"order_id": 1, "delivery_person_name": "Ravi Kumar", "delivery_person_phone": "+91-99000-11223"}},
+	"add_order_item":          {{"order_id": 1, "menu_item_id": 4}, {"order_id": 1, "menu_item_id": 2, "quantity": 2, "notes": "less spicy"}},
+	"remove_order_item":       {{"order_id": 1, "menu_item_id": 1, "quantity": 1}, {"order_id": 1, "menu_item_id": 4}},
+	"split_order":             {{"order_id": 1, "items": []interface{}{map[string]interface{}{"menu_item_id": 1, "quantity": 1}}}},
+	"merge_orders":            {{"order_ids": []interface{}{1, 2}}},
+	"get_order_history":       {{"order_id": 1}},
+	"cancel_order":            {{"id": 1, "reason": "out_of_stock", "cancelled_by": "Priya (front desk)"}, {"id": 2, "reason": "other", "note": "Duplicate order placed by phone"}},
+	"delete_order":            {{"id": 1}},
+	"run_report":              {{"report": "revenue_by_category", "params": map[string]interface{}{"from": "2026-01-01", "to": "2026-01-31"}}, {"report": "orders_by_hour", "params": map[string]interface{}{"from": "2026-01-01", "to": "2026-01-07", "restaurant_id": 2}}},
+	"get_recent_errors":       {{}, {"limit": 5, "tool": "create_order"}},
+	"get_audit_log":           {{"entity_type": "order", "entity_id": 1}, {"from": "2026-03-01", "to": "2026-03-07", "limit": 20}},
+	"record_order_feedback":   {{"order_id": 1, "score": 4, "comment": "Food arrived warm, rider was late", "contacted": true}},
+	"propose_change":          {{"entity": "menu_item", "entity_id": 3, "changes": map[string]interface{}{"price": 280}, "note": "Paneer cost went up"}, {"entity": "menu_item", "entity_id": 5, "changes": map[string]interface{}{"available": false}}},
+	"list_change_requests":    {{"status": "pending"}, {}},
+	"review_change_request":   {{"id": 1, "decision": "approve"}, {"id": 2, "decision": "reject"}},
+	"create_restaurant_token": {{"restaurant_id": 1, "name": "Front counter POS"}, {"restaurant_id": 1, "name": "Zomato sync", "expires_in_days": 90}},
+	"list_restaurant_tokens":  {{"restaurant_id": 1}},
+	"revoke_restaurant_token": {{"id": 1}},
+	"create_pricing_rule":     {{"restaurant_id": 1, "name": "Happy hour", "category": "Beverage", "percent_off": 20, "days_of_week": []interface{}{"mon", "tue", "wed", "thu", "fri"}, "start_time": "16:00", "end_time": "18:00"}, {"restaurant_id": 1, "name": "Late night", "percent_off": 10, "days_of_week": []interface{}{"fri", "sat"}, "start_time": "22:00", "end_time": "02:00"}},
+	"list_pricing_rules":      {{"restaurant_id": 1}},
+	"update_pricing_rule":     {{"id": 1, "active": false}, {"id": 1, "percent_off": 25, "end_time": "19:00"}},
+	"delete_pricing_rule":     {{"id": 1}},
+	"list_shadowed_actions":   {{"status": "pending"}, {}},
+	"replay_shadowed_action":  {{"id": 1}},
 }
 
-func (h *MCPHandler) handleToolsList(id interface{}) MCPResponse {
+// Order types and payment methods offered by create_order and quick_order
+var (
+	orderTypes     = []string{models.OrderTypeDineIn, models.OrderTypeTakeaway, models.OrderTypeDelivery}
+	paymentMethods = []string{"cash", "card", "upi", "digital_wallet"}
+)
+
+// toolDefinitions returns every tool of the catalog
+func toolDefinitions() []map[string]interface{} {
 	tools := []map[string]interface{}{
-		{"name": "list_restaurants", "description": "List all restaurants", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}},
+		{"name": "list_restaurants", "description": "List restaurants a page at a time. The result says how many restaurants there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer", "description": "Maximum number of restaurants to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of restaurants to skip (default 0)"}, "sort": map[string]interface{}{"type": "string", "description": "Sort order (default id)", "enum": models.RestaurantSorts}}}},
+		{"name": "search_restaurants", "description": "Find restaurants by part of their name or address, e.g. a neighbourhood, and by cuisine, ordered by name. Use this instead of paging through list_restaurants", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"query": map[string]interface{}{"type": "string", "description": "Optional. Text to look for in the name or address, case-insensitive"}, "cuisine": map[string]interface{}{"type": "string", "description": "Optional. Only restaurants of exactly this cuisine type, ignoring case"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of restaurants to return (default 20, at most 200)"}}}},
 		{"name": "get_restaurant", "description": "Get restaurant by ID", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
-		{"name": "create_restaurant", "description": "Create a new restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}, "address": map[string]interface{}{"type": "string"}, "phone_number": map[string]interface{}{"type": "string"}, "cuisine_type": map[string]interface{}{"type": "string"}}, "required": []string{"name", "address"}}},
-		{"name": "update_restaurant", "description": "Update restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "address": map[string]interface{}{"type": "string"}, "phone_number": map[string]interface{}{"type": "string"}, "cuisine_type": map[string]interface{}{"type": "string"}}, "required": []string{"id"}}},
-		{"name": "delete_restaurant", "description": "Delete restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
-		{"name": "get_menu", "description": "Get menu for restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}}, "required": []string{"restaurant_id"}}},
-		{"name": "create_menu_item", "description": "Add menu item", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "description": map[string]interface{}{"type": "string"}, "price": map[string]interface{}{"type": "number"}, "category": map[string]interface{}{"type": "string"}, "dietary_type": map[string]interface{}{"type": "string"}, "spice_level": map[string]interface{}{"type": "string"}}, "required": []string{"restaurant_id", "name", "price"}}},
-		{"name": "update_menu_item", "description": "Update menu item", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "description": map[string]interface{}{"type": "string"}, "price": map[string]interface{}{"type": "number"}, "category": map[string]interface{}{"type": "string"}}, "required": []string{"id"}}},
+		{"name": "create_restaurant", "description": "Create a new restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}, "address": map[string]interface{}{"type": "string"}, "phone_number": map[string]interface{}{"type": "string"}, "email": map[string]interface{}{"type": "string"}, "notification_preferences": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"notify_on_order": map[string]interface{}{"type": "boolean"}, "notify_on_low_stock": map[string]interface{}{"type": "boolean"}}}, "cuisine_type": map[string]interface{}{"type": "string"}, "tax_rate": map[string]interface{}{"type": "number", "description": "GST on orders as a fraction, such as 0.05 for 5%; without it the server default applies"}, "service_charge_percent": map[string]interface{}{"type": "number", "description": "Service charge on dine-in orders as a percent of the item total less discount, 0 to 100; none without it"}}, "required": []string{"name", "address"}}},
+		{"name": "update_restaurant", "description": "Update restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "address": map[string]interface{}{"type": "string"}, "phone_number": map[string]interface{}{"type": "string"}, "email": map[string]interface{}{"type": "string"}, "notification_preferences": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"notify_on_order": map[string]interface{}{"type": "boolean"}, "notify_on_low_stock": map[string]interface{}{"type": "boolean"}}}, "cuisine_type": map[string]interface{}{"type": "string"}, "public_menu_enabled": map[string]interface{}{"type": "boolean", "description": "Whether the menu is published without login at /public/restaurants/{id}/menu, for embedding in the restaurant's website"}, "auto_confirm_orders": map[string]interface{}{"type": "boolean", "description": "Whether new orders are created confirmed, skipping the manual confirm step"}, "tax_rate": map[string]interface{}{"type": "number", "description": "GST on orders as a fraction, such as 0.05 for 5%; without it the server default applies"}, "service_charge_percent": map[string]interface{}{"type": "number", "description": "Service charge on dine-in orders as a percent of the item total less discount, 0 to 100"}, "expected_version": map[string]interface{}{"type": "integer", "description": "The version the restaurant had when you read it; the update is refused if it has changed since"}}, "required": []string{"id"}}},
+		{"name": "delete_restaurant", "description": "Delete a restaurant. By default it is only deactivated: hidden from listings and search and closed to new orders, with its menu and order history kept for accounting, and restore_restaurant brings it back. hard_delete removes it for good, refused while it has menu items or orders unless force is true; the result says exactly what was removed", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "hard_delete": map[string]interface{}{"type": "boolean", "description": "Remove the restaurant instead of deactivating it (default false)"}, "force": map[string]interface{}{"type": "boolean", "description": "With hard_delete, also delete the restaurant's menu items and all of its orders, open ones included (default false)"}}, "required": []string{"id"}}},
+		{"name": "restore_restaurant", "description": "Reactivate a restaurant deleted without hard_delete, so it is listed and takes orders again", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
+		{"name": "get_menu", "description": "Get menu for restaurant. Items whose stock is tracked have a stock_quantity of portions left", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}, "as_of": map[string]interface{}{"type": "string", "description": "YYYY-MM-DD; defaults to today in the restaurant's timezone"}, "include_stats": map[string]interface{}{"type": "boolean", "description": "Also return when each item's price last changed"}, "include_unavailable": map[string]interface{}{"type": "boolean", "description": "Also return items switched off with available=false, e.g. to turn one back on (default false)"}, "include_promotions": map[string]interface{}{"type": "boolean", "description": "Also return each item's effective_price now after pricing rules, and a current_promotions note; the result becomes an object with items and current_promotions"}, "sort": map[string]interface{}{"type": "string", "enum": models.MenuSorts, "description": "category (default) or recently_changed to list the items updated last first, e.g. to review recent price changes"}}, "required": []string{"restaurant_id"}}},
+		{"name": "search_menu_items", "description": "Search a restaurant's current menu by name or description and filter by category, dietary type, spice level and price range, e.g. vegan dishes under 200 rupees. Only available items are searched", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}, "query": map[string]interface{}{"type": "string", "description": "Optional. Text to look for in the item name or description, case-insensitive"}, "category": map[string]interface{}{"type": "string", "description": "Optional. Only items of this menu category, e.g. Main Course"}, "dietary_type": map[string]interface{}{"type": "string", "enum": models.DietaryTypes, "description": "Optional. Only items of this dietary type"}, "spice_level": map[string]interface{}{"type": "string", "enum": models.SpiceLevels, "description": "Optional. Only items of this spice level"}, "min_price": map[string]interface{}{"type": "number", "description": "Optional. Lowest price in rupees, included"}, "max_price": map[string]interface{}{"type": "number", "description": "Optional. Highest price in rupees, included"}}, "required": []string{"restaurant_id"}}},
+		bulkCreateMenuItemsDefinition(),
+		{"name": "create_menu_item", "description": "Add menu item", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "description": map[string]interface{}{"type": "string"}, "price": map[string]interface{}{"type": "number"}, "category": map[string]interface{}{"type": "string"}, "dietary_type": map[string]interface{}{"type": "string"}, "spice_level": map[string]interface{}{"type": "string"}, "available": map[string]interface{}{"type": "boolean", "description": "Whether the item can be ordered (default true)"}, "effective_from": map[string]interface{}{"type": "string"}, "effective_to": map[string]interface{}{"type": "string"}}, "required": []string{"restaurant_id", "name", "price"}}},
+		{"name": "update_menu_item", "description": "Update menu item. Changing the price needs the owner or admin role; staff use propose_change instead", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "name": map[string]interface{}{"type": "string"}, "description": map[string]interface{}{"type": "string"}, "price": map[string]interface{}{"type": "number"}, "category": map[string]interface{}{"type": "string"}, "dietary_type": map[string]interface{}{"type": "string", "enum": models.DietaryTypes}, "spice_level": map[string]interface{}{"type": "string", "enum": models.SpiceLevels}, "available": map[string]interface{}{"type": "boolean", "description": "Whether the item can be ordered"}, "effective_from": map[string]interface{}{"type": "string"}, "effective_to": map[string]interface{}{"type": "string"}, "changed_by": map[string]interface{}{"type": "string", "description": "Recorded in the price history; defaults to your account email"}, "expected_version": map[string]interface{}{"type": "integer", "description": "The version the menu item had when you read it; the update is refused if it has changed since"}}, "required": []string{"id"}}},
+		{"name": "get_price_history", "description": "Get the price changes of one menu item or of every item of a restaurant, newest first, optionally between two dates. The result says how many changes there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"menu_item_id": map[string]interface{}{"type": "integer", "description": "Pass this or restaurant_id"}, "restaurant_id": map[string]interface{}{"type": "integer", "description": "Pass this or menu_item_id"}, "from": map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive, in the restaurant's timezone"}, "to": map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive, in the restaurant's timezone"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of changes to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of changes to skip (default 0)"}}}},
+		{"name": "set_stock", "description": "Set how many portions of a menu item are left. Orders take from the stock and are refused beyond it, the item becomes unavailable when it runs out and available again when restocked, and cancelling an order puts its portions back. Untracked items never run out", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"menu_item_id": map[string]interface{}{"type": "integer"}, "quantity": map[string]interface{}{"type": "integer", "description": "Portions in stock, 0 or more"}, "untracked": map[string]interface{}{"type": "boolean", "description": "Stop tracking the item's stock instead of setting a quantity"}}, "required": []string{"menu_item_id"}}},
 		{"name": "delete_menu_item", "description": "Delete menu item", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
-		{"name": "list_orders", "description": "List all orders", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}},
-		{"name": "get_order", "description": "Get order by ID", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
-		{"name": "create_order", "description": "Create new order", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}, "customer_name": map[string]interface{}{"type": "string"}, "items": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"menu_item_id": map[string]interface{}{"type": "number"}, "quantity": map[string]interface{}{"type": "number"}}}}}, "required": []string{"restaurant_id", "customer_name", "items"}}},
-		{"name": "update_order", "description": "Update order status", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "status": map[string]interface{}{"type": "string"}}, "required": []string{"id", "status"}}},
+		{"name": "list_orders", "description": "List orders, newest first, a page at a time, optionally only one restaurant's, some statuses or a date range. The result says how many orders there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "Optional. Only orders of this restaurant"}, "status": map[string]interface{}{"type": "string", "description": "Optional. A status or comma-separated statuses, e.g. pending,preparing"}, "created_from": map[string]interface{}{"type": "string", "description": "Optional. Only orders created at or after this date (YYYY-MM-DD, UTC) or RFC 3339 time"}, "created_to": map[string]interface{}{"type": "string", "description": "Optional. Only orders created up to this date (YYYY-MM-DD, UTC, the whole day included) or RFC 3339 time"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of orders to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of orders to skip (default 0)"}}}},
+		{"name": "get_order", "description": "Get order by ID", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "include_feedback": map[string]interface{}{"type": "boolean", "description": "Also return the satisfaction feedback recorded for the order, if any"}, "include_archived": map[string]interface{}{"type": "boolean", "description": "Also look the order up in the archive when it is no longer live; see archive_orders. Archived orders are returned without feedback"}}, "required": []string{"id"}}},
+		{"name": "get_customer_orders", "description": "Get a customer's orders across all restaurants by phone number, newest first, with a summary of their total orders and spend. Spaces, dashes and a +91 prefix in the number are ignored", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"customer_phone": map[string]interface{}{"type": "string", "description": "The customer's phone number"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of orders to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of orders to skip (default 0)"}}, "required": []string{"customer_phone"}}},
+		{"name": "get_sales_report", "description": "Get a restaurant's sales per day between two dates: order count, gross total, tax, discounts and net revenue, excluding cancelled orders, which are counted by cancellation reason. Days follow the restaurant's timezone", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}, "from": map[string]interface{}{"type": "string", "description": "First day of the report (YYYY-MM-DD)"}, "to": map[string]interface{}{"type": "string", "description": "Last day of the report (YYYY-MM-DD), included"}}, "required": []string{"restaurant_id", "from", "to"}}},
+		exportOrdersDefinition(),
+		archiveOrdersDefinition(),
+		{"name": "summarize_orders", "description": "Summarize one day of a restaurant's orders in a few sentences ready to relay as is: orders per status, revenue against the day before, the busiest hour, unusually large orders, items now unavailable and refunds. Days follow the restaurant's timezone", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}, "date": map[string]interface{}{"type": "string", "description": "Optional. The day to summarize (YYYY-MM-DD); today when omitted"}}, "required": []string{"restaurant_id"}}},
+		{"name": "get_top_selling_items", "description": "Get a restaurant's best selling menu items ranked by units sold, with revenue, excluding cancelled orders. Optionally only one category and orders since a date", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}, "since": map[string]interface{}{"type": "string", "description": "Optional. Only orders created on or after this date (YYYY-MM-DD, UTC) or RFC 3339 time; omit for all orders"}, "category": map[string]interface{}{"type": "string", "description": "Optional. Only items of this menu category, e.g. Main Course"}, "limit": map[string]interface{}{"type": "integer", "description": "Number of items to list (default 10, at most 100)"}}, "required": []string{"restaurant_id"}}},
+		{"name": "get_restaurant_stats", "description": "Get a restaurant's summary statistics: total, cancelled and pending (awaiting confirmation) orders, revenue and average order value (cancelled orders excluded), number of available menu items and when the last order was placed. Use this instead of summing get_orders", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}}, "required": []string{"restaurant_id"}}},
+		{"name": "create_order", "description": "Create new order", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "number"}, "customer_name": map[string]interface{}{"type": "string"}, "customer_phone": map[string]interface{}{"type": "string"}, "order_type": map[string]interface{}{"type": "string", "enum": orderTypes, "description": "Default dine_in; only dine-in orders pay the restaurant's service charge"}, "payment_method": map[string]interface{}{"type": "string", "enum": paymentMethods, "description": "Default cash"}, "billing_address": map[string]interface{}{"type": "string"}, "items": map[string]interface{}{"type": "array", "description": "Order items. Items must be on this restaurant's menu; prices come from the menu", "items": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"menu_item_id": map[string]interface{}{"type": "integer", "description": "ID of the menu item"}, "quantity": map[string]interface{}{"type": "integer", "description": "Number of portions"}, "notes": map[string]interface{}{"type": "string", "description": "Kitchen instructions"}}, "required": []string{"menu_item_id", "quantity"}}}, "discount": map[string]interface{}{"type": "number", "description": "Amount off the item total (optional); no more than the item total is taken off"}, "discount_percent": map[string]interface{}{"type": "number", "description": "Percent off the item total, 0 to 100 (optional); use instead of discount"}, "tip": map[string]interface{}{"type": "number", "description": "Tip added to the final amount (optional); untaxed unless the server is configured to tax it"}, "idempotency_key": map[string]interface{}{"type": "string", "description": "A unique string such as a UUID (optional). Retrying create_order with the same key returns the order already created instead of placing a duplicate"}}, "required": []string{"restaurant_id", "customer_name", "items"}}},
+		{"name": "quick_order", "description": "Create an order from dish names instead of menu item IDs. Names are matched against the restaurant's current menu (exact, then case-insensitive, then partial); unknown or ambiguous names return the candidate dishes instead of placing the order", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer"}, "customer_name": map[string]interface{}{"type": "string", "description": "Name of the customer or table"}, "customer_phone": map[string]interface{}{"type": "string"}, "order_type": map[string]interface{}{"type": "string", "enum": orderTypes, "description": "Default dine_in"}, "payment_method": map[string]interface{}{"type": "string", "enum": paymentMethods, "description": "Default cash"}, "items": map[string]interface{}{"type": "array", "description": "Dishes to order", "items": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string", "description": "Dish name as it appears on the menu, or part of it"}, "quantity": map[string]interface{}{"type": "integer", "description": "Number of portions"}, "notes": map[string]interface{}{"type": "string", "description": "Kitchen instructions"}}, "required": []string{"name", "quantity"}}}}, "required": []string{"restaurant_id", "customer_name", "items"}}},
+		{"name": "update_order", "description": "Update order status or payment status, or set the tip of an order that isn't paid or cancelled", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "status": map[string]interface{}{"type": "string"}, "payment_status": map[string]interface{}{"type": "string", "enum": models.PaymentStatuses}, "tip": map[string]interface{}{"type": "number", "description": "Tip to set on the order, replacing any earlier tip; the totals are recomputed"}}, "required": []string{"id"}}},
+		{"name": "assign_delivery", "description": "Assign a rider to a ready delivery order and mark it out_for_delivery", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer"}, "delivery_person_name": map[string]interface{}{"type": "string", "description": "Name of the rider"}, "delivery_person_phone": map[string]interface{}{"type": "string", "description": "Phone number of the rider"}}, "required": []string{"order_id", "delivery_person_name", "delivery_person_phone"}}},
+		{"name": "add_order_item", "description": "Add a menu item to a pending or confirmed order. The price comes from the menu and the order totals are recomputed", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer"}, "menu_item_id": map[string]interface{}{"type": "integer", "description": "Must be on the order's restaurant's menu"}, "quantity": map[string]interface{}{"type": "integer", "description": "How many to add (default 1)"}, "notes": map[string]interface{}{"type": "string", "description": "Kitchen notes for the item"}}, "required": []string{"order_id", "menu_item_id"}}},
+		{"name": "remove_order_item", "description": "Remove a menu item, or some of its quantity, from a pending or confirmed order and recompute the totals. An order can't be left without items; cancel it instead", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer"}, "menu_item_id": map[string]interface{}{"type": "integer"}, "quantity": map[string]interface{}{"type": "integer", "description": "How many to remove; omit to remove the item entirely"}}, "required": []string{"order_id", "menu_item_id"}}},
+		{"name": "split_order", "description": "Split a dine-in bill: move some items of a pending or confirmed, unpaid order to a new order for the same customer. Items keep the prices they were ordered at and both orders' totals are recomputed", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer", "description": "The order to move items off; it must keep at least one"}, "items": map[string]interface{}{"type": "array", "description": "What to move to the new order", "items": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"menu_item_id": map[string]interface{}{"type": "integer"}, "quantity": map[string]interface{}{"type": "integer", "description": "How many of the item to move"}}, "required": []string{"menu_item_id", "quantity"}}}}, "required": []string{"order_id", "items"}}},
+		{"name": "merge_orders", "description": "Merge the pending or confirmed, unpaid dine-in orders of one restaurant, such as a group's tables, into a new order holding all their items. The new order keeps the earliest created_at and lists its origins in merged_from; the origins are cancelled with reason merged", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_ids": map[string]interface{}{"type": "array", "description": "At least two order IDs", "items": map[string]interface{}{"type": "integer"}}}, "required": []string{"order_ids"}}},
+		{"name": "get_order_history", "description": "Get the status timeline of an order: each status change with when and by whom, in the restaurant's timezone", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "number"}}, "required": []string{"order_id"}}},
+		{"name": "cancel_order", "description": "Cancel an order with a reason. Orders that are out for delivery or delivered cannot be cancelled; a completed payment is marked refunded", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}, "reason": map[string]interface{}{"type": "string", "enum": models.CancelReasons}, "note": map[string]interface{}{"type": "string", "description": "Required when reason is other"}, "cancelled_by": map[string]interface{}{"type": "string"}}, "required": []string{"id", "reason"}}},
+		{"name": "record_order_feedback", "description": "Record the customer's satisfaction with a delivered order, for staff use and separate from public reviews. Only delivered orders, and only once per order", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer", "description": "ID of the delivered order"}, "score": map[string]interface{}{"type": "integer", "description": "Satisfaction from 1 (very unhappy) to 5 (very happy)"}, "comment": map[string]interface{}{"type": "string", "description": "What the customer said"}, "contacted": map[string]interface{}{"type": "boolean", "description": "Whether staff contacted the customer about it (default false)"}, "recorded_by": map[string]interface{}{"type": "string", "description": "Who is recording the feedback; defaults to your account email"}}, "required": []string{"order_id", "score"}}},
+		{"name": "propose_change", "description": "Propose a price or availability change to a menu item for an owner or admin to approve. Staff use this instead of update_menu_item for prices; nothing changes until the request is approved", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"entity": map[string]interface{}{"type": "string", "enum": []string{models.ChangeEntityMenuItem}}, "entity_id": map[string]interface{}{"type": "integer", "description": "ID of the menu item"}, "changes": map[string]interface{}{"type": "object", "description": "The fields to change", "properties": map[string]interface{}{"price": map[string]interface{}{"type": "number"}, "available": map[string]interface{}{"type": "boolean"}}, "additionalProperties": false}, "note": map[string]interface{}{"type": "string", "description": "Why the change is needed, for the reviewer"}}, "required": []string{"entity", "entity_id", "changes"}}},
+		{"name": "list_change_requests", "description": "List proposed changes, newest first, a page at a time. The result says how many there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"status": map[string]interface{}{"type": "string", "enum": []string{models.ChangeStatusPending, models.ChangeStatusApproved, models.ChangeStatusRejected}, "description": "Only requests with this status; all when omitted"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of requests to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of requests to skip (default 0)"}}}},
+		{"name": "review_change_request", "description": "Owner or admin only. Approve or reject a pending change request. Approving applies the change to the menu item, recording price changes in the price history", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "integer", "description": "ID of the change request"}, "decision": map[string]interface{}{"type": "string", "enum": []string{"approve", "reject"}}}, "required": []string{"id", "decision"}}},
 		{"name": "delete_order", "description": "Delete order", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}}, "required": []string{"id"}}},
+		{"name": "create_restaurant_token", "description": "Admin only. Create an API token a restaurant's POS uses to push orders without logging in. The secret is returned once and only its hash is kept; the token may only call create_order for this restaurant", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant the token acts for"}, "name": map[string]interface{}{"type": "string", "description": "What the token is for, e.g. the POS terminal; orders and audit logs are attributed to it"}, "expires_in_days": map[string]interface{}{"type": "integer", "description": "Optional. Days until the token stops working; it never expires when omitted"}}, "required": []string{"restaurant_id", "name"}}},
+		{"name": "list_restaurant_tokens", "description": "Admin only. List a restaurant's API tokens, newest first, with when they were last used, expire or were revoked. Secrets are never shown", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}}, "required": []string{"restaurant_id"}}},
+		{"name": "revoke_restaurant_token", "description": "Admin only. Revoke a restaurant API token; requests made with it are refused from then on", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "integer", "description": "ID of the token, from list_restaurant_tokens"}}, "required": []string{"id"}}},
+		createPricingRuleDefinition(),
+		{"name": "list_pricing_rules", "description": "List a restaurant's pricing rules, inactive ones included", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"}}, "required": []string{"restaurant_id"}}},
+		updatePricingRuleDefinition(),
+		{"name": "delete_pricing_rule", "description": "Owner or admin only. Delete a pricing rule. Orders it discounted keep their prices", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "integer", "description": "ID of the pricing rule, from list_pricing_rules"}}, "required": []string{"id"}}},
+		{"name": "list_shadowed_actions", "description": "Admin only. List the destructive tool calls shadow mode (SHADOW_MODE) recorded instead of running, newest first, with what each would have done. The result says which tools shadow mode covers and how many actions there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"status": map[string]interface{}{"type": "string", "enum": []string{models.ShadowStatusPending, models.ShadowStatusReplaying, models.ShadowStatusReplayed, models.ShadowStatusFailed}, "description": "Only actions with this status; all when omitted"}, "limit": map[string]interface{}{"type": "integer", "description": "Maximum number of actions to return (default 50, at most 200)"}, "offset": map[string]interface{}{"type": "integer", "description": "Number of actions to skip (default 0)"}}}},
+		{"name": "replay_shadowed_action", "description": "Admin only. Run a call recorded by shadow mode for real, as you. A pending or failed action can be replayed; each runs at most once successfully", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "integer", "description": "ID of the shadowed action, from list_shadowed_actions"}}, "required": []string{"id"}}},
+		runReportDefinition(),
+		getAuditLogDefinition(),
+		{"name": "get_recent_errors", "description": "Admin only. Get the most recent failed tool calls on this server instance, newest first, with request id, session, tool, the error the client saw and the arguments with personal data redacted", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer", "description": "Maximum number of failures to return (default 20)"}, "tool": map[string]interface{}{"type": "string", "description": "Only failures of this tool"}}}},
 	}
+	return append(tools, faultToolDefinitions()...)
+}
 
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"tools": tools,
-		},
-		ID: id,
+// CheckToolExamples reports tools whose examples are missing or have drifted
+// from their input schema
+func CheckToolExamples() error {
+	for _, tool := range toolDefinitions() {
+		name, _ := tool["name"].(string)
+		if err := toolschema.CheckExamples(name, tool["inputSchema"], toolExamples[name]); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (h *MCPHandler) handleToolsCall(req MCPRequest) MCPResponse {
-	var params struct {
-		Name      string                 `json:"name"`
-		Arguments map[string]interface{} `json:"arguments"`
-	}
+// intercept runs around every tool call: it applies the timeout, actor and
+// compatibility shims, refuses what a restaurant token may not do, records
+// shadowed calls instead of running them, and keeps failures for
+// get_recent_errors
+func (h *MCPHandler) intercept(next mcp.CallFunc) mcp.CallFunc {
+	return func(ctx context.Context, id jsonrpc.RequestID, name string, args map[string]interface{}) MCPResponse {
+		start := time.Now()
+		if mw.IsDebug() {
+			log.Printf("Tool call %s: %s with args: %v", mcp.RequestID(ctx), name, args)
+		}
 
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return h.errorResponse(req.ID, -32602, "Invalid params")
+		// The request context ends when the client disconnects; the timeout
+		// covers a client that waits on a hung database forever
+		ctx, cancel := context.WithTimeout(ctx, mw.ToolCallTimeout)
+		defer cancel()
+		ctx = authz.WithOutcome(ctx)
+		ctx = storage.WithActor(ctx, requestUser(ctx))
+
+		var warnings []compat.Warning
+		var deprecatedErr error
+		name, warnings, deprecatedErr = compat.Apply(name, args)
+		var resp MCPResponse
+		if deprecatedErr != nil {
+			resp = mcp.InvalidArgs(id, deprecatedErr)
+		} else if denial := restaurantTokenDenial(ctx, name, args); denial != "" {
+			resp = h.toolError(id, denial)
+		} else if err := h.store.EnsureConnected(ctx); err != nil {
+			// A Postgres restart shouldn't require restarting the server; the
+			// model gets a clear error while the database is still down
+			resp = h.unavailableError(id, err)
+		} else if h.shadow.Covers(name) {
+			resp = withWarnings(h.shadowToolCall(ctx, id, name, args), warnings)
+		} else {
+			resp = withWarnings(next(ctx, id, name, args), warnings)
+		}
+		h.recordFailure(ctx, id, mcp.SessionID(ctx), name, args, resp, start)
+		return resp
 	}
+}
 
-	if mw.IsDebug() {
-		log.Printf("Tool call: %s with args: %v", params.Name, params.Arguments)
-	}
-
-	switch params.Name {
-	case "list_restaurants":
-		return h.toolListRestaurants(req.ID)
-	case "get_restaurant":
-		return h.toolGetRestaurant(req.ID, params.Arguments)
-	case "create_restaurant":
-		return h.toolCreateRestaurant(req.ID, params.Arguments)
-	case "update_restaurant":
-		return h.toolUpdateRestaurant(req.ID, params.Arguments)
-	case "delete_restaurant":
-		return h.toolDeleteRestaurant(req.ID, params.Arguments)
-	case "get_menu":
-		return h.toolGetMenu(req.ID, params.Arguments)
-	case "create_menu_item":
-		return h.toolCreateMenuItem(req.ID, params.Arguments)
-	case "update_menu_item":
-		return h.toolUpdateMenuItem(req.ID, params.Arguments)
-	case "delete_menu_item":
-		return h.toolDeleteMenuItem(req.ID, params.Arguments)
-	case "list_orders":
-		return h.toolListOrders(req.ID)
-	case "get_order":
-		return h.toolGetOrder(req.ID, params.Arguments)
-	case "create_order":
-		return h.toolCreateOrder(req.ID, params.Arguments)
-	case "update_order":
-		return h.toolUpdateOrder(req.ID, params.Arguments)
-	case "delete_order":
-		return h.toolDeleteOrder(req.ID, params.Arguments)
+// withWarnings appends deprecation warnings to a tool result, as a text line
+// for the model and a warnings array in structuredContent for clients. The
+// array is added to the structuredContent the tool returned, if any.
+func withWarnings(resp MCPResponse, warnings []compat.Warning) MCPResponse {
+	result, ok := resp.Result.(*mcp.CallToolResult)
+	if !ok || len(warnings) == 0 {
+		return resp
+	}
+	for _, w := range warnings {
+		result.Content = append(result.Content, mcp.TextContent(w.Text()))
+	}
+	switch sc := result.StructuredContent.(type) {
+	case nil:
+		result.StructuredContent = map[string]interface{}{"warnings": warnings}
+	case map[string]interface{}:
+		sc["warnings"] = warnings
 	default:
-		return h.errorResponse(req.ID, -32601, "Unknown tool: "+params.Name)
+		fields := structuredObject(sc)
+		fields["warnings"] = warnings
+		result.StructuredContent = fields
+	}
+	return resp
+}
+
+// structuredObject returns the fields of a typed structuredContent such as
+// a *models.Restaurant as a map, or a map holding it under "result" when it
+// isn't a JSON object
+func structuredObject(sc interface{}) map[string]interface{} {
+	var fields map[string]interface{}
+	if data, err := json.Marshal(sc); err == nil && json.Unmarshal(data, &fields) == nil && fields != nil {
+		return fields
+	}
+	return map[string]interface{}{"result": sc}
+}
+
+// toolHandlers maps each tool of toolDefinitions to its handler
+func (h *MCPHandler) toolHandlers() map[string]mcp.ToolHandler {
+	handlers := map[string]mcp.ToolHandler{
+		"list_restaurants":        h.toolListRestaurants,
+		"search_restaurants":      h.toolSearchRestaurants,
+		"get_restaurant":          h.toolGetRestaurant,
+		"create_restaurant":       h.toolCreateRestaurant,
+		"update_restaurant":       h.toolUpdateRestaurant,
+		"delete_restaurant":       h.toolDeleteRestaurant,
+		"restore_restaurant":      h.toolRestoreRestaurant,
+		"get_menu":                h.toolGetMenu,
+		"search_menu_items":       h.toolSearchMenuItems,
+		"create_menu_item":        h.toolCreateMenuItem,
+		"bulk_create_menu_items":  h.toolBulkCreateMenuItems,
+		"update_menu_item":        h.toolUpdateMenuItem,
+		"set_stock":               h.toolSetStock,
+		"delete_menu_item":        h.toolDeleteMenuItem,
+		"get_price_history":       h.toolGetPriceHistory,
+		"list_orders":             h.toolListOrders,
+		"get_order":               h.toolGetOrder,
+		"get_customer_orders":     h.toolGetCustomerOrders,
+		"export_orders":           h.toolExportOrders,
+		"archive_orders":          h.toolArchiveOrders,
+		"get_sales_report":        h.toolGetSalesReport,
+		"summarize_orders":        h.toolSummarizeOrders,
+		"get_top_selling_items":   h.toolGetTopSellingItems,
+		"get_restaurant_stats":    h.toolGetRestaurantStats,
+		"create_order":            h.toolCreateOrder,
+		"quick_order":             h.toolQuickOrder,
+		"update_order":            h.toolUpdateOrder,
+		"assign_delivery":         h.toolAssignDelivery,
+		"add_order_item":          h.toolAddOrderItem,
+		"remove_order_item":       h.toolRemoveOrderItem,
+		"split_order":             h.toolSplitOrder,
+		"merge_orders":            h.toolMergeOrders,
+		"get_order_history":       h.toolGetOrderHistory,
+		"cancel_order":            h.toolCancelOrder,
+		"delete_order":            h.toolDeleteOrder,
+		"record_order_feedback":   h.toolRecordOrderFeedback,
+		"propose_change":          h.toolProposeChange,
+		"list_change_requests":    h.toolListChangeRequests,
+		"review_change_request":   h.toolReviewChangeRequest,
+		"create_restaurant_token": h.toolCreateRestaurantToken,
+		"list_restaurant_tokens":  h.toolListRestaurantTokens,
+		"revoke_restaurant_token": h.toolRevokeRestaurantToken,
+		"create_pricing_rule":     h.toolCreatePricingRule,
+		"list_pricing_rules":      h.toolListPricingRules,
+		"update_pricing_rule":     h.toolUpdatePricingRule,
+		"delete_pricing_rule":     h.toolDeletePricingRule,
+		"list_shadowed_actions":   h.toolListShadowedActions,
+		"replay_shadowed_action":  h.toolReplayShadowedAction,
+		"run_report":              h.toolRunReport,
+		"get_recent_errors":       h.toolGetRecentErrors,
+		"get_audit_log":           h.toolGetAuditLog,
 	}
+	maps.Copy(handlers, h.faultToolHandlers())
+	return handlers
 }
 
-func (h *MCPHandler) toolListRestaurants(id interface{}) MCPResponse {
-	rows, err := h.db.Query(`
-		SELECT id, name, address, phone_number, cuisine_type 
-		FROM restaurants 
-		ORDER BY name
-	`)
+func (h *MCPHandler) toolListRestaurants(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
 	if err != nil {
-		log.Printf("Error listing restaurants: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	sort, _ := args["sort"].(string)
+	if err := models.ValidateRestaurantSort(sort); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
 	}
-	defer rows.Close()
 
-	restaurants := []Restaurant{}
-	for rows.Next() {
-		var r Restaurant
-		if err := rows.Scan(&r.ID, &r.Name, &r.Address, &r.PhoneNumber, &r.CuisineType); err != nil {
-			continue
-		}
-		restaurants = append(restaurants, r)
+	restaurants, total, err := h.store.GetAllRestaurants(ctx, page, sort)
+	if err != nil {
+		return h.databaseError(id, "listing restaurants", err)
 	}
 
-	data, _ := json.MarshalIndent(restaurants, "", "  ")
-	
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		ID: id,
+	data := resultJSON(restaurants)
+	return h.successResponseText(id, page.Summary("restaurants", len(restaurants), total)+"\n"+data)
+}
+
+func (h *MCPHandler) toolSearchRestaurants(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	query, cuisine, limit, err := models.RestaurantSearchArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
 	}
+
+	restaurants, err := h.store.SearchRestaurants(ctx, query, cuisine, limit)
+	if err != nil {
+		return h.databaseError(id, "searching restaurants", err)
+	}
+	return h.successResponseText(id, fmt.Sprintf("%d matching restaurants\n%s", len(restaurants), resultJSON(restaurants)))
 }
 
-func (h *MCPHandler) toolGetRestaurant(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolGetRestaurant(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing or invalid id")
 	}
 
-	var restaurant Restaurant
-	err := h.db.QueryRow(`
-		SELECT id, name, address, phone_number, cuisine_type 
-		FROM restaurants 
-		WHERE id = $1
-	`, int(restaurantID)).Scan(&restaurant.ID, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &restaurant.CuisineType)
-
-	if err == sql.ErrNoRows {
-		return h.errorResponse(id, -32602, "Restaurant not found")
+	restaurant, err := h.store.GetRestaurantByID(ctx, int(restaurantID))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return h.databaseError(id, "getting restaurant", err)
 	}
-	if err != nil {
-		log.Printf("Error getting restaurant: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+	if outcome, err := h.checkRestaurant(ctx, "get_restaurant", int(restaurantID), err == nil, args); err != nil {
+		return h.databaseError(id, "checking restaurant access", err)
+	} else if outcome != authz.Allowed {
+		return h.toolError(id, authz.RestaurantNotFound(int(restaurantID)))
 	}
 
-	data, _ := json.MarshalIndent(restaurant, "", "  ")
-	
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		ID: id,
-	}
+	data := resultJSON(restaurant)
+	return h.successResponseText(id, data)
 }
 
-func (h *MCPHandler) toolGetMenu(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolGetMenu(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, ok := args["restaurant_id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing or invalid restaurant_id")
 	}
+	if resp, ok := h.allowRestaurant(ctx, id, "get_menu", int(restaurantID), args); !ok {
+		return resp
+	}
+
+	asOf, _ := args["as_of"].(string)
+	if asOf != "" {
+		if _, err := time.Parse(models.DateLayout, asOf); err != nil {
+			return h.errorResponse(id, -32602, "Invalid as_of, expected YYYY-MM-DD")
+		}
+	}
+
+	sort, _ := args["sort"].(string)
+	if err := models.ValidateMenuSort(sort); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
 
-	rows, err := h.db.Query(`
-		SELECT id, restaurant_id, name, description, price, category, dietary_type, spice_level, available
-		FROM menu_items 
-		WHERE restaurant_id = $1 AND available = true
-		ORDER BY category, name
-	`, int(restaurantID))
+	includeUnavailable, _ := args["include_unavailable"].(bool)
+	menuItems, err := h.store.GetMenuAsOf(ctx, int(restaurantID), asOf, includeUnavailable)
 	if err != nil {
-		log.Printf("Error getting menu: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "getting menu", err)
 	}
-	defer rows.Close()
+	models.SortMenu(menuItems, sort)
 
-	menuItems := []MenuItem{}
-	for rows.Next() {
-		var m MenuItem
-		if err := rows.Scan(&m.ID, &m.RestaurantID, &m.Name, &m.Description, &m.Price, &m.Category, &m.DietaryType, &m.SpiceLevel, &m.Available); err != nil {
-			continue
+	if includeStats, _ := args["include_stats"].(bool); includeStats {
+		last, err := h.store.LastPriceChanges(ctx, int(restaurantID))
+		if err != nil {
+			return h.databaseError(id, "getting price changes", err)
+		}
+		for i := range menuItems {
+			if at, ok := last[menuItems[i].ID]; ok {
+				menuItems[i].LastPriceChangeAt = &at
+			}
 		}
-		menuItems = append(menuItems, m)
 	}
 
-	data, _ := json.MarshalIndent(menuItems, "", "  ")
-	
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		ID: id,
+	if includePromotions, _ := args["include_promotions"].(bool); includePromotions {
+		priced, notes, err := storage.PriceMenu(ctx, h.store, int(restaurantID), menuItems)
+		if err != nil {
+			return h.databaseError(id, "getting pricing rules", err)
+		}
+		return h.successResponseText(id, resultJSON(map[string]interface{}{"items": priced, "current_promotions": notes}))
+	}
+
+	data := resultJSON(menuItems)
+	return h.successResponseText(id, data)
+}
+
+func (h *MCPHandler) toolSearchMenuItems(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	q, err := models.MenuSearchArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if resp, ok := h.allowRestaurant(ctx, id, "search_menu_items", q.RestaurantID, args); !ok {
+		return resp
+	}
+
+	items, err := h.store.SearchMenuItems(ctx, q)
+	if err != nil {
+		return h.databaseError(id, "searching menu items", err)
+	}
+	return h.successResponseText(id, fmt.Sprintf("%d matching menu items\n%s", len(items), resultJSON(items)))
+}
+
+func (h *MCPHandler) toolGetPriceHistory(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	q, err := models.PriceHistoryArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	changes, total, err := h.store.GetPriceHistory(ctx, q, page)
+	if err != nil {
+		return h.databaseError(id, "getting price history", err)
+	}
+
+	data := resultJSON(changes)
+	return h.successResponseText(id, page.Summary("price changes", len(changes), total)+"\n"+data)
+}
+
+func (h *MCPHandler) toolGetCustomerOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	phone, _ := args["customer_phone"].(string)
+	if models.NormalizePhone(phone) == "" {
+		return h.errorResponse(id, -32602, "Missing or invalid customer_phone")
+	}
+
+	orders, summary, err := h.store.GetOrdersByCustomerPhone(ctx, phone, page)
+	if err != nil {
+		return h.databaseError(id, "getting customer orders", err)
+	}
+
+	data := resultJSON(orders)
+	return h.successResponseText(id, summary.Line(phone)+"\n"+page.Summary("orders", len(orders), summary.Orders)+"\n"+data)
+}
+
+func (h *MCPHandler) toolGetSalesReport(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	q, err := models.SalesQueryArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if resp, ok := h.allowRestaurant(ctx, id, "get_sales_report", q.RestaurantID, args); !ok {
+		return resp
+	}
+
+	report, err := h.store.GetSalesReport(ctx, q)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "getting sales report", err)
+	}
+	return h.successResponseBlocks(id, report.Blocks()...)
+}
+
+func (h *MCPHandler) toolGetTopSellingItems(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	q, err := models.TopItemsArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if resp, ok := h.allowRestaurant(ctx, id, "get_top_selling_items", q.RestaurantID, args); !ok {
+		return resp
+	}
+
+	items, err := h.store.GetTopSellingItems(ctx, q)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "getting top selling items", err)
+	}
+	return h.successResponseText(id, models.TopItemsText(q, items))
+}
+
+// toolSummarizeOrders answers with a short narrative of a day's orders the
+// model can relay as is, with the numbers in structuredContent
+func (h *MCPHandler) toolSummarizeOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	q, err := models.DigestQueryArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if resp, ok := h.allowRestaurant(ctx, id, "summarize_orders", q.RestaurantID, args); !ok {
+		return resp
+	}
+
+	digest, err := h.store.GetOrderDigest(ctx, q)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "summarizing orders", err)
+	}
+	resp := h.successResponse(id, digest.Text())
+	resp.Result.(*mcp.CallToolResult).StructuredContent = digest
+	return resp
+}
+
+func (h *MCPHandler) toolGetRestaurantStats(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, ok := args["restaurant_id"].(float64)
+	if !ok {
+		return h.errorResponse(id, -32602, "Missing restaurant_id")
+	}
+	if resp, ok := h.allowRestaurant(ctx, id, "get_restaurant_stats", int(restaurantID), args); !ok {
+		return resp
+	}
+
+	stats, err := h.store.GetRestaurantStats(ctx, int(restaurantID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "getting restaurant stats", err)
 	}
+	return h.successResponseText(id, resultJSON(stats))
 }
 
-func (h *MCPHandler) sendError(w http.ResponseWriter, id interface{}, code int, message string) {
+func (h *MCPHandler) sendError(w http.ResponseWriter, id jsonrpc.RequestID, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(MCPResponse{
 		JSONRPC: "2.0",
@@ -311,7 +653,7 @@ func (h *MCPHandler) sendError(w http.ResponseWriter, id interface{}, code int,
 	})
 }
 
-func (h *MCPHandler) errorResponse(id interface{}, code int, message string) MCPResponse {
+func (h *MCPHandler) errorResponse(id jsonrpc.RequestID, code int, message string) MCPResponse {
 	return MCPResponse{
 		JSONRPC: "2.0",
 		Error: &MCPError{
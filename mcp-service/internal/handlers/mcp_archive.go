@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+func archiveOrdersDefinition() map[string]interface{} {
+	return map[string]interface{}{"name": "archive_orders", "description": "Admin only. Move the delivered and cancelled orders created before a date, with their items, status history and feedback, out of the live tables into the archive. Archived orders no longer appear in order lists, reports or exports; get_order with include_archived still finds them", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"before": map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, exclusive, in UTC; must not be after today"},
+	}, "required": []string{"before"}}}
+}
+
+func (h *MCPHandler) toolArchiveOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	s, _ := args["before"].(string)
+	before, err := time.Parse(models.DateLayout, s)
+	if err != nil {
+		return h.errorResponse(id, -32602, "before must be a date as YYYY-MM-DD")
+	}
+	if before.After(time.Now()) {
+		return h.errorResponse(id, -32602, "before must not be after today")
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "archive_orders requires the admin role")
+	}
+
+	n, err := h.store.ArchiveOrders(ctx, before)
+	if errors.Is(err, storage.ErrArchiveUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "archiving orders", err)
+	}
+	if n > 0 {
+		h.audit(ctx, "archive_orders", models.AuditOrder, 0, args)
+	}
+	return h.successResponse(id, fmt.Sprintf("Archived %d orders created before %s", n, before.Format(models.DateLayout)))
+}
+
+// getArchivedOrder is get_order for an order no longer in the live tables
+func (h *MCPHandler) getArchivedOrder(ctx context.Context, id jsonrpc.RequestID, orderID int) MCPResponse {
+	o, err := h.store.GetArchivedOrder(ctx, orderID)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrArchiveUnavailable) {
+		return h.toolError(id, "Order not found")
+	}
+	if err != nil {
+		return h.databaseError(id, "getting archived order", err)
+	}
+	return h.successResponseText(id, resultJSON(Order{
+		ID: o.ID, RestaurantID: o.RestaurantID, CustomerName: o.CustomerName, Status: o.Status, TotalAmount: o.FinalAmount,
+		PaymentStatus: o.PaymentStatus, CancellationReason: o.CancellationReason, CancellationNote: o.CancellationNote,
+		ArchivedAt: o.ArchivedAt,
+	}))
+}
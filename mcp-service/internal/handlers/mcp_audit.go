@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/audit"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// audit records a successful call of a mutating tool in the audit log; see
+// package audit. A failure to record it is only logged.
+func (h *MCPHandler) audit(ctx context.Context, tool, entityType string, entityID int, args map[string]interface{}) {
+	audit.Record(ctx, h.store, tool, entityType, entityID, args)
+}
+
+func getAuditLogDefinition() map[string]interface{} {
+	return map[string]interface{}{"name": "get_audit_log", "description": "Admin only. Get who changed what: the successful calls of mutating tools and REST endpoints, newest first, with the caller, the entity changed and the arguments. Calls refused because the restaurant is outside the caller's scope are listed too, with outcome denied, and so are calls shadow mode recorded instead of running, with outcome shadowed and their effect. The result says how many entries there are in total", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"entity_type": map[string]interface{}{"type": "string", "enum": models.AuditEntityTypes, "description": "Only changes to this kind of entity"},
+		"entity_id":   map[string]interface{}{"type": "integer", "description": "Only changes to this entity; needs entity_type"},
+		"from":        map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive, in UTC"},
+		"to":          map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive, in UTC"},
+		"limit":       map[string]interface{}{"type": "integer", "description": "Maximum number of entries to return (default 50, at most 200)"},
+		"offset":      map[string]interface{}{"type": "integer", "description": "Number of entries to skip (default 0)"},
+	}}}
+}
+
+func (h *MCPHandler) toolGetAuditLog(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	q, err := models.AuditQueryArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "get_audit_log requires the admin role")
+	}
+
+	entries, total, err := h.store.GetAuditLog(ctx, q, page)
+	if errors.Is(err, storage.ErrAuditLogUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "getting audit log", err)
+	}
+	return h.successResponseText(id, page.Summary("audit log entries", len(entries), total)+"\n"+resultJSON(entries))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/audit"
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// checkRestaurant applies the restaurant access policy to a lookup by tool
+// of restaurantID, which exists or not, and records a miss or denial. A
+// denial is also written to the audit log with the call's args. Any outcome
+// but authz.Allowed must be answered with authz.RestaurantNotFound.
+func (h *MCPHandler) checkRestaurant(ctx context.Context, tool string, restaurantID int, exists bool, args map[string]interface{}) (string, error) {
+	scope, err := h.scopes(ctx)
+	if err != nil {
+		return "", err
+	}
+	var role string
+	if exists && !scope.Includes(restaurantID) {
+		// Only an admin gets past the scope, so the role is needed just here
+		if role, err = h.userRole(ctx); err != nil {
+			return "", err
+		}
+	}
+	outcome := authz.CheckRestaurant(role, scope, restaurantID, exists)
+	authz.Record(ctx, tool, requestUser(ctx), outcome, restaurantID)
+	if outcome == authz.Denied {
+		audit.RecordOutcome(ctx, h.store, tool, models.AuditDenied, models.AuditRestaurant, restaurantID, args)
+	}
+	return outcome, nil
+}
+
+// allowRestaurant looks up restaurantID for a call of tool and applies
+// checkRestaurant, for tools that only take a restaurant ID. When the call
+// may not go on, ok is false and resp is its answer.
+func (h *MCPHandler) allowRestaurant(ctx context.Context, id jsonrpc.RequestID, tool string, restaurantID int, args map[string]interface{}) (resp MCPResponse, ok bool) {
+	_, err := h.store.GetRestaurantByID(ctx, restaurantID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return h.databaseError(id, "getting restaurant", err), false
+	}
+	if outcome, err := h.checkRestaurant(ctx, tool, restaurantID, err == nil, args); err != nil {
+		return h.databaseError(id, "checking restaurant access", err), false
+	} else if outcome != authz.Allowed {
+		return h.toolError(id, authz.RestaurantNotFound(restaurantID)), false
+	}
+	return MCPResponse{}, true
+}
+
+// requestUser names the authenticated user of the request by email, by
+// token name for a restaurant API token, or else by subject
+func requestUser(ctx context.Context) string {
+	if _, name, ok := oauth.RestaurantTokenFromContext(ctx); ok {
+		return "token:" + name
+	}
+	user := oauth.GetUserFromContext(ctx)
+	if email, _ := user["email"].(string); email != "" {
+		return email
+	}
+	sub, _ := user["sub"].(string)
+	return sub
+}
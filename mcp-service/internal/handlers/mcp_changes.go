@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Staff propose price and availability changes with propose_change; owners
+// and admins make them directly or approve the proposals with
+// review_change_request.
+
+// canApproveChanges reports whether the user of the request is an owner or
+// admin
+func (h *MCPHandler) canApproveChanges(ctx context.Context) (bool, error) {
+	role, err := h.userRole(ctx)
+	return models.CanApproveChanges(role), err
+}
+
+// proposeInstead is the error staff get from a direct price change
+func proposeInstead(menuItemID int) string {
+	return fmt.Sprintf("changing a price needs the owner or admin role. Use propose_change with entity %q, entity_id %d and changes {\"price\": ...} to ask an owner to approve it",
+		models.ChangeEntityMenuItem, menuItemID)
+}
+
+func (h *MCPHandler) toolProposeChange(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	change, err := models.ChangeRequestArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	change.ProposedBy, _ = oauth.GetUserFromContext(ctx)["email"].(string)
+
+	err = h.store.ProposeChange(ctx, &change)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrChangeRequestsUnavailable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "proposing change", err)
+	}
+	h.audit(ctx, "propose_change", models.AuditChangeRequest, change.ID, args)
+	return h.successResponse(id, fmt.Sprintf("Change request %d created for %s %d; it applies once an owner or admin approves it with review_change_request",
+		change.ID, change.Entity, change.EntityID))
+}
+
+func (h *MCPHandler) toolListChangeRequests(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	status, _ := args["status"].(string)
+	if err := models.ValidateChangeStatus(status); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	requests, total, err := h.store.ListChangeRequests(ctx, status, page)
+	if errors.Is(err, storage.ErrChangeRequestsUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "listing change requests", err)
+	}
+	return h.successResponseText(id, page.Summary("change requests", len(requests), total)+"\n"+resultJSON(requests))
+}
+
+func (h *MCPHandler) toolReviewChangeRequest(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	requestID, ok := args["id"].(float64)
+	if !ok || requestID != float64(int(requestID)) {
+		return h.errorResponse(id, -32602, "Missing or invalid id")
+	}
+	decision, _ := args["decision"].(string)
+	if decision != "approve" && decision != "reject" {
+		return h.errorResponse(id, -32602, "decision must be approve or reject")
+	}
+
+	approver, err := h.canApproveChanges(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !approver {
+		return h.toolError(id, "review_change_request requires the owner or admin role")
+	}
+	reviewer, _ := oauth.GetUserFromContext(ctx)["email"].(string)
+
+	change, err := h.store.ReviewChangeRequest(ctx, int(requestID), decision == "approve", reviewer)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrChangeReviewed),
+		errors.Is(err, storage.ErrChangeRequestsUnavailable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "reviewing change request", err)
+	}
+	h.audit(ctx, "review_change_request", models.AuditChangeRequest, change.ID, args)
+	return h.successResponse(id, fmt.Sprintf("Change request %d %s", change.ID, change.Status))
+}
@@ -1,241 +1,586 @@
 package handlers
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/bulk"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
 )
 
 // Restaurant CRUD
-func (h *MCPHandler) toolCreateRestaurant(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolCreateRestaurant(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	name, _ := args["name"].(string)
 	address, _ := args["address"].(string)
 	phone, _ := args["phone_number"].(string)
+	email, _ := args["email"].(string)
 	cuisine, _ := args["cuisine_type"].(string)
 	
 	if cuisine == "" {
 		cuisine = "Indian"
 	}
+	if err := models.ValidateEmail(email); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	restaurant := &models.Restaurant{
+		Name:                    name,
+		Address:                 address,
+		PhoneNumber:             phone,
+		Email:                   email,
+		CuisineType:             cuisine,
+		NotificationPreferences: models.DefaultNotificationPreferences(),
+	}
+	flags, err := models.ParseNotificationPreferences(args["notification_preferences"])
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	restaurant.NotificationPreferences.Apply(flags)
+	if restaurant.TaxRate, err = models.TaxRateArg(args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if restaurant.ServiceChargePercent, err = models.ServiceChargePercentArg(args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
 	
-	var newID int
-	err := h.db.QueryRow(`
-		INSERT INTO restaurants (name, address, phone_number, cuisine_type)
-		VALUES ($1, $2, $3, $4) RETURNING id
-	`, name, address, phone, cuisine).Scan(&newID)
+	var duplicate *storage.DuplicateRestaurantError
+	err = h.store.CreateRestaurant(ctx, restaurant)
+	switch {
+	case errors.As(err, &duplicate):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "creating restaurant", err)
+	}
+	h.audit(ctx, "create_restaurant", models.AuditRestaurant, restaurant.ID, args)
 	
+	return h.successResponse(id, fmt.Sprintf("Restaurant created with ID %d", restaurant.ID))
+}
+
+func (h *MCPHandler) toolUpdateRestaurant(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, ok := args["id"].(float64)
+	if !ok {
+		return h.errorResponse(id, -32602, "Missing id")
+	}
+	
+	var patch models.RestaurantPatch
+	patch.Name, _ = args["name"].(string)
+	patch.Address, _ = args["address"].(string)
+	patch.PhoneNumber, _ = args["phone_number"].(string)
+	patch.Email, _ = args["email"].(string)
+	patch.CuisineType, _ = args["cuisine_type"].(string)
+	
+	if err := models.ValidateEmail(patch.Email); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	prefs, err := models.ParseNotificationPreferences(args["notification_preferences"])
 	if err != nil {
-		log.Printf("Error creating restaurant: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
 	}
+	patch.NotificationPreferences = prefs
+	if v, ok := args["public_menu_enabled"].(bool); ok {
+		patch.PublicMenuEnabled = &v
+	}
+	if v, ok := args["auto_confirm_orders"].(bool); ok {
+		patch.AutoConfirmOrders = &v
+	}
+	if patch.TaxRate, err = models.TaxRateArg(args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if patch.ServiceChargePercent, err = models.ServiceChargePercentArg(args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if patch.ExpectedVersion, err = models.ExpectedVersionArg(args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	
+	restaurant, err := h.store.PatchRestaurant(ctx, int(restaurantID), patch)
+	var conflict *storage.VersionConflictError
+	var duplicate *storage.DuplicateRestaurantError
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.As(err, &conflict), errors.As(err, &duplicate):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "updating restaurant", err)
+	}
+	h.audit(ctx, "update_restaurant", models.AuditRestaurant, restaurant.ID, args)
 	
-	return h.successResponse(id, fmt.Sprintf("Restaurant created with ID %d", newID))
+	return h.successResponse(id, fmt.Sprintf("Restaurant %d updated to version %d", int(restaurantID), restaurant.Version))
 }
 
-func (h *MCPHandler) toolUpdateRestaurant(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolDeleteRestaurant(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	name, _ := args["name"].(string)
-	address, _ := args["address"].(string)
-	phone, _ := args["phone_number"].(string)
-	cuisine, _ := args["cuisine_type"].(string)
-	
-	_, err := h.db.Exec(`
-		UPDATE restaurants 
-		SET name = COALESCE(NULLIF($1, ''), name),
-		    address = COALESCE(NULLIF($2, ''), address),
-		    phone_number = COALESCE(NULLIF($3, ''), phone_number),
-		    cuisine_type = COALESCE(NULLIF($4, ''), cuisine_type)
-		WHERE id = $5
-	`, name, address, phone, cuisine, int(restaurantID))
+	var opts models.RestaurantDeleteOptions
+	opts.Hard, _ = args["hard_delete"].(bool)
+	opts.Force, _ = args["force"].(bool)
+	if opts.Force && !opts.Hard {
+		return h.errorResponse(id, -32602, "force only applies with hard_delete: true")
+	}
 	
-	if err != nil {
-		log.Printf("Error updating restaurant: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+	var inUse *storage.RestaurantInUseError
+	deleted, err := h.store.DeleteRestaurant(ctx, int(restaurantID), opts)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.As(err, &inUse):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "deleting restaurant", err)
 	}
+	h.audit(ctx, "delete_restaurant", models.AuditRestaurant, int(restaurantID), args)
 	
-	return h.successResponse(id, fmt.Sprintf("Restaurant %d updated", int(restaurantID)))
+	message := deleted.String()
+	if deleted.Deactivated {
+		message += ". restore_restaurant brings it back"
+	}
+	resp := h.successResponse(id, message)
+	resp.Result.(*mcp.CallToolResult).StructuredContent = deleted
+	return resp
 }
 
-func (h *MCPHandler) toolDeleteRestaurant(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolRestoreRestaurant(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	_, err := h.db.Exec("DELETE FROM restaurants WHERE id = $1", int(restaurantID))
+	r, err := h.store.RestoreRestaurant(ctx, int(restaurantID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
 	if err != nil {
-		log.Printf("Error deleting restaurant: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "restoring restaurant", err)
 	}
+	h.audit(ctx, "restore_restaurant", models.AuditRestaurant, r.ID, args)
 	
-	return h.successResponse(id, fmt.Sprintf("Restaurant %d deleted", int(restaurantID)))
+	resp := h.successResponse(id, fmt.Sprintf("Restaurant %d %q restored: it is listed and takes orders again", r.ID, r.Name))
+	resp.Result.(*mcp.CallToolResult).StructuredContent = r
+	return resp
 }
 
 // Menu Item CRUD
-func (h *MCPHandler) toolCreateMenuItem(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolCreateMenuItem(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, _ := args["restaurant_id"].(float64)
 	name, _ := args["name"].(string)
-	description, _ := args["description"].(string)
-	price, _ := args["price"].(float64)
-	category, _ := args["category"].(string)
-	dietary, _ := args["dietary_type"].(string)
-	spice, _ := args["spice_level"].(string)
-	
-	if category == "" {
-		category = "Main Course"
+	price, ok := args["price"].(float64)
+	if name == "" || !ok {
+		return h.errorResponse(id, -32602, "Missing name or price")
 	}
-	if dietary == "" {
-		dietary = "vegetarian"
+	m := &models.MenuItem{RestaurantID: int(restaurantID), Name: name, Price: models.Rupees(price), Category: "Main Course", DietaryType: models.DietaryVegetarian, SpiceLevel: models.SpiceMedium, Available: true}
+	m.Description, _ = args["description"].(string)
+	m.EffectiveFrom, _ = args["effective_from"].(string)
+	m.EffectiveTo, _ = args["effective_to"].(string)
+	for key, field := range map[string]*string{"category": &m.Category, "dietary_type": &m.DietaryType, "spice_level": &m.SpiceLevel} {
+		if v, _ := args[key].(string); v != "" {
+			*field = v
+		}
 	}
-	if spice == "" {
-		spice = "medium"
+	if available, ok := args["available"].(bool); ok {
+		m.Available = available
+	}
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
 	}
 	
-	var newID int
-	err := h.db.QueryRow(`
-		INSERT INTO menu_items (restaurant_id, name, description, price, category, dietary_type, spice_level, available)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, true) RETURNING id
-	`, int(restaurantID), name, description, price, category, dietary, spice).Scan(&newID)
-	
+	err := h.store.CreateMenuItem(ctx, m)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
 	if err != nil {
-		log.Printf("Error creating menu item: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "creating menu item", err)
 	}
+	h.audit(ctx, "create_menu_item", models.AuditMenuItem, m.ID, args)
 	
-	return h.successResponse(id, fmt.Sprintf("Menu item created with ID %d", newID))
+	return h.successResponse(id, fmt.Sprintf("Menu item created with ID %d", m.ID))
+}
+
+// maxBulkMenuItems caps the items of one bulk_create_menu_items call
+const maxBulkMenuItems = 200
+
+func bulkCreateMenuItemsDefinition() map[string]interface{} {
+	item := map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"name":           map[string]interface{}{"type": "string"},
+		"description":    map[string]interface{}{"type": "string"},
+		"price":          map[string]interface{}{"type": "number", "description": "More than 0"},
+		"category":       map[string]interface{}{"type": "string", "description": "Default Main Course"},
+		"dietary_type":   map[string]interface{}{"type": "string", "enum": models.DietaryTypes, "description": "Default vegetarian"},
+		"spice_level":    map[string]interface{}{"type": "string", "enum": models.SpiceLevels, "description": "Default medium"},
+		"available":      map[string]interface{}{"type": "boolean", "description": "Default true"},
+		"effective_from": map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive"},
+		"effective_to":   map[string]interface{}{"type": "string", "description": "YYYY-MM-DD, inclusive"},
+	}, "required": []string{"name", "price"}}
+	return map[string]interface{}{"name": "bulk_create_menu_items", "description": fmt.Sprintf("Add up to %d menu items to a restaurant in one call, e.g. to seed a new menu. All or nothing: if any item is invalid, none are created and the error names the index of the first invalid item", maxBulkMenuItems), "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"},
+		"items":         map[string]interface{}{"type": "array", "items": item, "description": "The menu items, in menu order"},
+	}, "required": []string{"restaurant_id", "items"}}}
+}
+
+// toolBulkCreateMenuItems creates many menu items in one transaction
+func (h *MCPHandler) toolBulkCreateMenuItems(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, _ := args["restaurant_id"].(float64)
+	raw, _ := args["items"].([]interface{})
+	if len(raw) == 0 || len(raw) > maxBulkMenuItems {
+		return h.errorResponse(id, -32602, fmt.Sprintf("items must hold 1 to %d menu items", maxBulkMenuItems))
+	}
+
+	items := make([]*models.MenuItem, len(raw))
+	for i, v := range raw {
+		fields, _ := v.(map[string]interface{})
+		m := &models.MenuItem{RestaurantID: int(restaurantID), Category: "Main Course", DietaryType: models.DietaryVegetarian, SpiceLevel: models.SpiceMedium, Available: true}
+		m.Name, _ = fields["name"].(string)
+		m.Description, _ = fields["description"].(string)
+		if price, ok := fields["price"].(float64); ok {
+			m.Price = models.Rupees(price)
+		}
+		m.EffectiveFrom, _ = fields["effective_from"].(string)
+		m.EffectiveTo, _ = fields["effective_to"].(string)
+		for key, field := range map[string]*string{"category": &m.Category, "dietary_type": &m.DietaryType, "spice_level": &m.SpiceLevel} {
+			if v, _ := fields[key].(string); v != "" {
+				*field = v
+			}
+		}
+		if available, ok := fields["available"].(bool); ok {
+			m.Available = available
+		}
+		if err := m.Validate(); err != nil {
+			return h.errorResponse(id, -32602, fmt.Sprintf("No menu items created: item %d: %v", i, err))
+		}
+		items[i] = m
+	}
+
+	err := h.store.CreateMenuItems(ctx, items)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, "No menu items created: "+err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "creating menu items", err)
+	}
+	h.audit(ctx, "bulk_create_menu_items", models.AuditMenuItem, 0, args)
+
+	result := bulk.NewResult(len(items))
+	for i, m := range items {
+		result.Add(i, m.ID, nil)
+	}
+	resp := h.successResponseText(id, result.Summary()+"\n"+resultJSON(items))
+	resp.Result.(*mcp.CallToolResult).StructuredContent = result
+	return resp
 }
 
-func (h *MCPHandler) toolUpdateMenuItem(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolUpdateMenuItem(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	menuItemID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	name, _ := args["name"].(string)
-	description, _ := args["description"].(string)
 	price, _ := args["price"].(float64)
-	category, _ := args["category"].(string)
-	
-	_, err := h.db.Exec(`
-		UPDATE menu_items 
-		SET name = COALESCE(NULLIF($1, ''), name),
-		    description = COALESCE(NULLIF($2, ''), description),
-		    price = CASE WHEN $3 > 0 THEN $3 ELSE price END,
-		    category = COALESCE(NULLIF($4, ''), category)
-		WHERE id = $5
-	`, name, description, price, category, int(menuItemID))
+	if price > 0 {
+		approver, err := h.canApproveChanges(ctx)
+		if err != nil {
+			return h.databaseError(id, "checking user role", err)
+		}
+		if !approver {
+			return h.toolError(id, proposeInstead(int(menuItemID)))
+		}
+	}
+	changedBy, _ := args["changed_by"].(string)
+	if changedBy == "" {
+		changedBy, _ = oauth.GetUserFromContext(ctx)["email"].(string)
+	}
+	expectedVersion, err := models.ExpectedVersionArg(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	m, err := h.store.GetMenuItemByID(ctx, int(menuItemID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, fmt.Sprintf("Menu item %d not found", int(menuItemID)))
+	}
+	if err != nil {
+		return h.databaseError(id, "getting menu item", err)
+	}
+	// Without expected_version the store still refuses the update if the
+	// item changes between this read and the write
+	if expectedVersion != nil {
+		if *expectedVersion != m.Version {
+			return h.toolError(id, (&storage.VersionConflictError{Entity: "menu item", ID: int(menuItemID), Expected: *expectedVersion}).Error())
+		}
+	}
+	if price > 0 {
+		m.Price = models.Rupees(price)
+	}
+	for key, field := range map[string]*string{"name": &m.Name, "description": &m.Description, "category": &m.Category, "dietary_type": &m.DietaryType, "spice_level": &m.SpiceLevel} {
+		if v, _ := args[key].(string); v != "" {
+			*field = v
+		}
+	}
+	if available, ok := args["available"].(bool); ok {
+		m.Available = available
+	}
+	// Effective dates are only touched when passed; an empty string clears one.
+	// The from <= to check runs against the merged values.
+	if effectiveFrom, ok := args["effective_from"].(string); ok {
+		m.EffectiveFrom = effectiveFrom
+	}
+	if effectiveTo, ok := args["effective_to"].(string); ok {
+		m.EffectiveTo = effectiveTo
+	}
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
 	
+	err = h.store.UpdateMenuItem(ctx, m, changedBy)
+	var conflict *storage.VersionConflictError
+	if errors.As(err, &conflict) || errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
 	if err != nil {
-		log.Printf("Error updating menu item: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "updating menu item", err)
 	}
+	h.audit(ctx, "update_menu_item", models.AuditMenuItem, int(menuItemID), args)
 	
-	return h.successResponse(id, fmt.Sprintf("Menu item %d updated", int(menuItemID)))
+	return h.successResponse(id, fmt.Sprintf("Menu item %d updated to version %d", int(menuItemID), m.Version))
 }
 
-func (h *MCPHandler) toolDeleteMenuItem(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolSetStock(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	menuItemID, _ := args["menu_item_id"].(float64)
+	quantity, err := models.StockArg(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	m, err := h.store.SetStock(ctx, int(menuItemID), quantity)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "setting stock", err)
+	}
+	h.audit(ctx, "set_stock", models.AuditMenuItem, m.ID, args)
+
+	msg := fmt.Sprintf("Stock of %s (menu item %d) is no longer tracked", m.Name, m.ID)
+	if m.StockQuantity != nil {
+		msg = fmt.Sprintf("%s (menu item %d) has %d in stock", m.Name, m.ID, *m.StockQuantity)
+	}
+	if !m.Available {
+		msg += "; it is unavailable"
+	}
+	resp := h.successResponse(id, msg)
+	resp.Result.(*mcp.CallToolResult).StructuredContent = m
+	return resp
+}
+
+func (h *MCPHandler) toolDeleteMenuItem(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	menuItemID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	_, err := h.db.Exec("DELETE FROM menu_items WHERE id = $1", int(menuItemID))
+	err := h.store.DeleteMenuItem(ctx, int(menuItemID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
 	if err != nil {
-		log.Printf("Error deleting menu item: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "deleting menu item", err)
 	}
+	h.audit(ctx, "delete_menu_item", models.AuditMenuItem, int(menuItemID), args)
 	
 	return h.successResponse(id, fmt.Sprintf("Menu item %d deleted", int(menuItemID)))
 }
 
 // Order CRUD
 type Order struct {
-	ID           int     `json:"id"`
-	RestaurantID int     `json:"restaurant_id"`
-	CustomerName string  `json:"customer_name"`
-	Status       string  `json:"status"`
-	TotalAmount  float64 `json:"total_amount"`
-}
-
-func (h *MCPHandler) toolListOrders(id interface{}) MCPResponse {
-	rows, err := h.db.Query(`
-		SELECT id, restaurant_id, customer_name, status, final_amount
-		FROM orders 
-		ORDER BY created_at DESC
-	`)
+	ID           int          `json:"id"`
+	RestaurantID int          `json:"restaurant_id"`
+	CustomerName string       `json:"customer_name"`
+	Status       string       `json:"status"`
+	TotalAmount  models.Money `json:"total_amount"`
+
+	// only from get_order and cancel_order
+	PaymentStatus      string `json:"payment_status,omitempty"`
+	CancellationReason string `json:"cancellation_reason,omitempty"`
+	CancellationNote   string `json:"cancellation_note,omitempty"`
+
+	Feedback   *models.OrderFeedback `json:"feedback,omitempty"`    // only with include_feedback
+	ArchivedAt *time.Time            `json:"archived_at,omitempty"` // only for archived orders, with include_archived
+}
+
+// orderSummary is the Order of o, whose total is its final amount
+func orderSummary(o models.Order) Order {
+	return Order{ID: o.ID, RestaurantID: o.RestaurantID, CustomerName: o.CustomerName, Status: o.Status, TotalAmount: o.FinalAmount}
+}
+
+func (h *MCPHandler) toolListOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
 	if err != nil {
-		log.Printf("Error listing orders: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
 	}
-	defer rows.Close()
-	
-	orders := []Order{}
-	for rows.Next() {
-		var o Order
-		if err := rows.Scan(&o.ID, &o.RestaurantID, &o.CustomerName, &o.Status, &o.TotalAmount); err != nil {
-			continue
+	var filter models.OrderFilter
+	if v := args["restaurant_id"]; v != nil {
+		restaurantID, ok := v.(float64)
+		if !ok || restaurantID <= 0 || restaurantID != float64(int(restaurantID)) {
+			return h.errorResponse(id, -32602, "restaurant_id must be a positive integer")
 		}
-		orders = append(orders, o)
+		if resp, ok := h.allowRestaurant(ctx, id, "list_orders", int(restaurantID), args); !ok {
+			return resp
+		}
+		filter.RestaurantID = int(restaurantID)
+	}
+	status, _ := args["status"].(string)
+	if filter.Statuses, err = models.ParseOrderStatuses(status); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	from, _ := args["created_from"].(string)
+	to, _ := args["created_to"].(string)
+	if err := filter.SetCreatedRange("created_from", from, "created_to", to); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	
+	// A missing restaurant comes back as a not-found error
+	found, total, err := h.store.GetOrders(ctx, filter, page)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "listing orders", err)
+	}
+	orders := make([]Order, len(found))
+	for i, o := range found {
+		orders[i] = orderSummary(o)
 	}
 	
-	data, _ := json.MarshalIndent(orders, "", "  ")
-	return h.successResponseText(id, string(data))
+	data := resultJSON(orders)
+	return h.successResponseText(id, page.Summary("orders", len(orders), total)+"\n"+data)
 }
 
-func (h *MCPHandler) toolGetOrder(id interface{}, args map[string]interface{}) MCPResponse {
+func (h *MCPHandler) toolGetOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	orderID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	var order Order
-	err := h.db.QueryRow(`
-		SELECT id, restaurant_id, customer_name, status, final_amount
-		FROM orders WHERE id = $1
-	`, int(orderID)).Scan(&order.ID, &order.RestaurantID, &order.CustomerName, &order.Status, &order.TotalAmount)
-	
-	if err == sql.ErrNoRows {
-		return h.errorResponse(id, -32602, "Order not found")
+	o, err := h.store.GetOrderByID(ctx, int(orderID))
+	if errors.Is(err, storage.ErrNotFound) {
+		if includeArchived, _ := args["include_archived"].(bool); includeArchived {
+			return h.getArchivedOrder(ctx, id, int(orderID))
+		}
+		return h.toolError(id, "Order not found")
 	}
 	if err != nil {
-		log.Printf("Error getting order: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "getting order", err)
+	}
+	order := orderSummary(*o)
+	order.PaymentStatus, order.CancellationReason, order.CancellationNote = o.PaymentStatus, o.CancellationReason, o.CancellationNote
+	if includeFeedback, _ := args["include_feedback"].(bool); includeFeedback {
+		if order.Feedback, err = h.store.GetOrderFeedback(ctx, order.ID); err != nil {
+			return h.databaseError(id, "getting order feedback", err)
+		}
 	}
 	
-	data, _ := json.MarshalIndent(order, "", "  ")
-	return h.successResponseText(id, string(data))
+	data := resultJSON(order)
+	return h.successResponseText(id, data)
+}
+
+// toolGetOrderHistory returns the status timeline of an order, with times
+// in its restaurant's timezone
+func (h *MCPHandler) toolGetOrderHistory(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, ok := args["order_id"].(float64)
+	if !ok {
+		return h.errorResponse(id, -32602, "Missing order_id")
+	}
+
+	changes, err := h.store.GetOrderHistory(ctx, int(orderID))
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrHistoryUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "getting order history", err)
+	}
+	var tz string
+	if order, err := h.store.GetOrderByID(ctx, int(orderID)); err == nil {
+		if restaurant, err := h.store.GetRestaurantByID(ctx, order.RestaurantID); err == nil {
+			tz = restaurant.Timezone
+		}
+	}
+
+	return h.successResponseText(id, models.OrderTimelineText(int(orderID), changes, tz)+"\n"+resultJSON(changes))
 }
 
-func (h *MCPHandler) toolCreateOrder(id interface{}, args map[string]interface{}) MCPResponse {
+// toolRecordOrderFeedback stores a satisfaction score for a delivered order,
+// recorded by default under the caller's account email
+func (h *MCPHandler) toolRecordOrderFeedback(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	feedback, err := models.OrderFeedbackArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if feedback.RecordedBy == "" {
+		feedback.RecordedBy, _ = oauth.GetUserFromContext(ctx)["email"].(string)
+	}
+
+	err = h.store.RecordOrderFeedback(ctx, &feedback)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrOrderNotDelivered),
+		errors.Is(err, storage.ErrFeedbackRecorded), errors.Is(err, storage.ErrFeedbackUnavailable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "recording order feedback", err)
+	}
+	h.audit(ctx, "record_order_feedback", models.AuditOrder, feedback.OrderID, args)
+	return h.successResponse(id, fmt.Sprintf("Feedback (score %d) recorded for order %d", feedback.Score, feedback.OrderID))
+}
+
+func (h *MCPHandler) toolCreateOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	restaurantID, _ := args["restaurant_id"].(float64)
 	customerName, _ := args["customer_name"].(string)
+	customerPhone, _ := args["customer_phone"].(string)
+	billingAddress, _ := args["billing_address"].(string)
 	items, _ := args["items"].([]interface{})
-	
-	// Start transaction
-	tx, err := h.db.Begin()
+	orderType, _ := args["order_type"].(string)
+	if orderType == "" {
+		orderType = models.OrderTypeDineIn
+	}
+	paymentMethod, _ := args["payment_method"].(string)
+	if paymentMethod == "" {
+		paymentMethod = "cash"
+	}
+	discount, discountPercent, err := models.DiscountArgs(args)
 	if err != nil {
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
 	}
-	defer tx.Rollback()
-	
-	// Create order
-	var orderID int
-	err = tx.QueryRow(`
-		INSERT INTO orders (restaurant_id, customer_name, status, total_amount, final_amount)
-		VALUES ($1, $2, 'pending', 0, 0) RETURNING id
-	`, int(restaurantID), customerName).Scan(&orderID)
-	
+	tip, _, err := models.TipArg(args)
 	if err != nil {
-		log.Printf("Error creating order: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	idempotencyKey, err := models.IdempotencyKeyArg(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	order := &models.Order{
+		RestaurantID:    int(restaurantID),
+		CustomerName:    customerName,
+		CustomerPhone:   customerPhone,
+		OrderType:       orderType,
+		Status:          models.OrderStatusPending,
+		Discount:        discount,
+		DiscountPercent: discountPercent,
+		Tip:             tip,
+		PaymentStatus:   "pending",
+		PaymentMethod:   paymentMethod,
+		BillingAddress:  billingAddress,
+		OrderItems:      []models.OrderItem{},
+		IdempotencyKey:  idempotencyKey,
 	}
-	
-	// Add order items
-	var totalAmount float64
 	for _, item := range items {
 		itemMap, ok := item.(map[string]interface{})
 		if !ok {
@@ -243,89 +588,433 @@ func (h *MCPHandler) toolCreateOrder(id interface{}, args map[string]interface{}
 		}
 		menuItemID, _ := itemMap["menu_item_id"].(float64)
 		quantity, _ := itemMap["quantity"].(float64)
-		
-		// Get price
-		var price float64
-		tx.QueryRow("SELECT price FROM menu_items WHERE id = $1", int(menuItemID)).Scan(&price)
-		
-		// Insert order item
-		tx.Exec(`
-			INSERT INTO order_items (order_id, menu_item_id, quantity, price)
-			VALUES ($1, $2, $3, $4)
-		`, orderID, int(menuItemID), int(quantity), price)
-		
-		totalAmount += price * quantity
+		notes, _ := itemMap["notes"].(string)
+		// Prices, including any pricing rule running now, come from the menu
+		order.OrderItems = append(order.OrderItems, models.OrderItem{MenuItemID: int(menuItemID), Quantity: int(quantity), Notes: notes})
 	}
-	
-	// Update order total
-	tx.Exec("UPDATE orders SET total_amount = $1, final_amount = $1 WHERE id = $2", totalAmount, orderID)
-	
-	if err := tx.Commit(); err != nil {
-		return h.errorResponse(id, -32603, "Database error")
+
+	var foreign *storage.ForeignItemsError
+	var short *storage.InsufficientStockError
+	err = h.store.CreateOrder(ctx, order)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrRestaurantInactive), errors.As(err, &foreign), errors.As(err, &short):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "creating order", err)
 	}
-	
-	return h.successResponse(id, fmt.Sprintf("Order created with ID %d, total: $%.2f", orderID, totalAmount))
+	h.audit(ctx, "create_order", models.AuditOrder, order.ID, args)
+
+	msg := fmt.Sprintf("Order created with ID %d, total: %s", order.ID, order.TotalAmount)
+	if order.Discount > 0 {
+		msg += fmt.Sprintf(", discount: %s", order.Discount)
+	}
+	if order.Status == models.OrderStatusConfirmed {
+		msg += " (confirmed automatically)"
+	}
+	return h.successResponse(id, msg+"\n"+order.Receipt())
 }
 
-func (h *MCPHandler) toolUpdateOrder(id interface{}, args map[string]interface{}) MCPResponse {
+// toolQuickOrder resolves dish names to menu items with
+// models.MatchMenuItems and places the order through toolCreateOrder, so it
+// gets the same validation and totals. Unknown or ambiguous names fail the
+// call with the candidates instead of guessing.
+func (h *MCPHandler) toolQuickOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, _ := args["restaurant_id"].(float64)
+	items, _ := args["items"].([]interface{})
+	if len(items) == 0 {
+		return h.errorResponse(id, -32602, "items must name at least one dish")
+	}
+
+	menu, err := h.store.GetMenuByRestaurantID(ctx, int(restaurantID), false)
+	if err != nil {
+		return h.databaseError(id, "getting menu", err)
+	}
+
+	var resolved []interface{}
+	var decisions, problems []string
+	for i, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		name, _ := itemMap["name"].(string)
+		quantity, _ := itemMap["quantity"].(float64)
+		if name == "" || quantity <= 0 {
+			return h.errorResponse(id, -32602, fmt.Sprintf("items[%d] needs a name and a positive quantity", i))
+		}
+
+		matches, how := models.MatchMenuItems(menu, name)
+		switch len(matches) {
+		case 0:
+			problems = append(problems, fmt.Sprintf("%q: not on the menu", name))
+			continue
+		case 1:
+		default:
+			candidates := make([]string, len(matches))
+			for j, m := range matches {
+				candidates[j] = fmt.Sprintf("%s (id %d, %s)", m.Name, m.ID, m.Price)
+			}
+			problems = append(problems, fmt.Sprintf("%q is ambiguous, did you mean: %s", name, strings.Join(candidates, "; ")))
+			continue
+		}
+
+		match := matches[0]
+		decisions = append(decisions, fmt.Sprintf("%q -> %s (id %d, %s match) x%d", name, match.Name, match.ID, how, int(quantity)))
+		resolvedItem := map[string]interface{}{"menu_item_id": float64(match.ID), "quantity": quantity}
+		if notes, ok := itemMap["notes"].(string); ok {
+			resolvedItem["notes"] = notes
+		}
+		resolved = append(resolved, resolvedItem)
+	}
+	if len(problems) > 0 {
+		text := "Order not placed, some items could not be resolved:\n- " + strings.Join(problems, "\n- ")
+		if len(decisions) > 0 {
+			text += "\n\nResolved:\n- " + strings.Join(decisions, "\n- ")
+		}
+		return h.toolError(id, text)
+	}
+
+	orderArgs := maps.Clone(args)
+	orderArgs["items"] = resolved
+	resp := h.toolCreateOrder(ctx, id, orderArgs)
+	if result, ok := resp.Result.(*mcp.CallToolResult); ok && !result.IsError {
+		echo := mcp.TextContent("Matched items:\n- " + strings.Join(decisions, "\n- "))
+		result.Content = append([]mcp.Content{echo}, result.Content...)
+	}
+	return resp
+}
+
+// toolUpdateOrder moves an order along OrderStatusTransitions, refusing
+// illegal moves such as delivered back to pending, and sets its tip and
+// payment status
+func (h *MCPHandler) toolUpdateOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	orderID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
 	status, _ := args["status"].(string)
-	
-	_, err := h.db.Exec("UPDATE orders SET status = $1 WHERE id = $2", status, int(orderID))
+	if status == models.OrderStatusCancelled {
+		return h.errorResponse(id, -32602, "Use the cancel_order tool to cancel an order so a reason is recorded")
+	}
+	paymentStatus, _ := args["payment_status"].(string)
+	if paymentStatus != "" && !contains(models.PaymentStatuses, paymentStatus) {
+		return h.errorResponse(id, -32602, fmt.Sprintf("Invalid payment_status %q: expected one of %s", paymentStatus, strings.Join(models.PaymentStatuses, ", ")))
+	}
+	tip, hasTip, err := models.TipArg(args)
 	if err != nil {
-		log.Printf("Error updating order: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if status == "" && paymentStatus == "" && !hasTip {
+		return h.errorResponse(id, -32602, "Nothing to update: give a status, payment_status or tip")
+	}
+	
+	var msgs []string
+	if hasTip {
+		order, err := h.store.SetOrderTip(ctx, int(orderID), tip)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return h.toolError(id, fmt.Sprintf("Order %d not found", int(orderID)))
+		case errors.Is(err, storage.ErrOrderNotTippable):
+			return h.toolError(id, err.Error())
+		case err != nil:
+			return h.databaseError(id, "setting order tip", err)
+		}
+		h.audit(ctx, "update_order", models.AuditOrder, int(orderID), args)
+		msgs = append(msgs, fmt.Sprintf("Order %d tip set to %s\n%s", int(orderID), tip, order.Receipt()))
+		if status == "" && paymentStatus == "" {
+			return h.successResponse(id, strings.Join(msgs, "\n"))
+		}
 	}
 	
-	return h.successResponse(id, fmt.Sprintf("Order %d status updated to %s", int(orderID), status))
+	order, err := h.store.GetOrderByID(ctx, int(orderID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, fmt.Sprintf("Order %d not found", int(orderID)))
+	}
+	if err != nil {
+		return h.databaseError(id, "getting order", err)
+	}
+	if status != "" {
+		if err := order.CheckTransition(status); err != nil {
+			return h.toolError(id, err.Error())
+		}
+		// The store checks the transition again against the locked row
+		order.Status = status
+	}
+	if paymentStatus != "" {
+		order.PaymentStatus = paymentStatus
+	}
+	var transition *models.TransitionError
+	err = h.store.UpdateOrder(ctx, order)
+	switch {
+	case errors.As(err, &transition):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "updating order", err)
+	}
+	if !hasTip {
+		h.audit(ctx, "update_order", models.AuditOrder, int(orderID), args)
+	}
+
+	if status != "" {
+		msgs = append(msgs, fmt.Sprintf("Order %d status updated to %s", int(orderID), status))
+	}
+	if paymentStatus != "" {
+		msgs = append(msgs, fmt.Sprintf("Order %d payment status set to %s", int(orderID), paymentStatus))
+	}
+	return h.successResponse(id, strings.Join(msgs, "\n"))
+}
+
+// toolAssignDelivery hands a ready delivery order to a rider
+func (h *MCPHandler) toolAssignDelivery(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, _ := args["order_id"].(float64)
+	riderName, _ := args["delivery_person_name"].(string)
+	riderPhone, _ := args["delivery_person_phone"].(string)
+	if riderName == "" || riderPhone == "" {
+		return h.errorResponse(id, -32602, "Missing required fields: delivery_person_name and delivery_person_phone")
+	}
+
+	order, err := h.store.AssignDelivery(ctx, int(orderID), riderName, riderPhone)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return h.toolError(id, fmt.Sprintf("Order %d not found", int(orderID)))
+	case errors.Is(err, storage.ErrNotDeliverable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "assigning delivery", err)
+	}
+	h.audit(ctx, "assign_delivery", models.AuditOrder, order.ID, args)
+	return h.successResponse(id, fmt.Sprintf("Order %d is out for delivery with %s (%s)", order.ID, riderName, riderPhone))
 }
 
-func (h *MCPHandler) toolDeleteOrder(id interface{}, args map[string]interface{}) MCPResponse {
+// toolCancelOrder cancels an order with a reason code, refusing orders past
+// preparing and marking completed payments refunded, and returns the order
+func (h *MCPHandler) toolCancelOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, ok := args["id"].(float64)
+	if !ok {
+		return h.errorResponse(id, -32602, "Missing id")
+	}
+	reason, _ := args["reason"].(string)
+	note, _ := args["note"].(string)
+	if err := models.ValidateCancelReason(reason, note); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	cancelledBy, _ := args["cancelled_by"].(string)
+	if cancelledBy == "" {
+		cancelledBy = "staff"
+	}
+
+	order, err := h.store.CancelOrder(ctx, int(orderID), reason, note, cancelledBy)
+	var transition *models.TransitionError
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return h.toolError(id, fmt.Sprintf("Order %d not found", int(orderID)))
+	case errors.As(err, &transition):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "cancelling order", err)
+	}
+	h.audit(ctx, "cancel_order", models.AuditOrder, int(orderID), args)
+
+	msg := fmt.Sprintf("Order %d cancelled (%s)", int(orderID), reason)
+	if order.PaymentStatus == models.PaymentStatusRefunded {
+		msg += "; payment marked refunded"
+	}
+	return h.successResponseText(id, msg+"\n"+resultJSON(order))
+}
+
+// toolAddOrderItem adds a menu item to a pending or confirmed order
+func (h *MCPHandler) toolAddOrderItem(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, _ := args["order_id"].(float64)
+	menuItemID, _ := args["menu_item_id"].(float64)
+	quantity := 1.0
+	if q, ok := args["quantity"].(float64); ok {
+		quantity = q
+	}
+	if quantity < 1 {
+		return h.errorResponse(id, -32602, "quantity must be at least 1")
+	}
+	notes, _ := args["notes"].(string)
+
+	return h.editOrderItems(ctx, id, "add_order_item", args, int(orderID), func(o *models.Order) error {
+		o.AddItem(int(menuItemID), int(quantity), notes)
+		return nil
+	})
+}
+
+// toolRemoveOrderItem takes a menu item, or some of its quantity, off a
+// pending or confirmed order
+func (h *MCPHandler) toolRemoveOrderItem(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, _ := args["order_id"].(float64)
+	menuItemID, _ := args["menu_item_id"].(float64)
+	quantity, ok := args["quantity"].(float64)
+	if ok && quantity < 1 {
+		return h.errorResponse(id, -32602, "quantity must be at least 1; omit it to remove the item entirely")
+	}
+
+	return h.editOrderItems(ctx, id, "remove_order_item", args, int(orderID), func(o *models.Order) error {
+		return o.RemoveItem(int(menuItemID), int(quantity))
+	})
+}
+
+// editOrderItems applies edit to the items of an order and saves them,
+// repriced from the menu, replying with the updated order. The change is
+// audited as a call of tool with args.
+func (h *MCPHandler) editOrderItems(ctx context.Context, id jsonrpc.RequestID, tool string, args map[string]interface{}, orderID int, edit func(*models.Order) error) MCPResponse {
+	order, err := h.store.GetOrderByID(ctx, orderID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, fmt.Sprintf("Order %d not found", orderID))
+	}
+	if err != nil {
+		return h.databaseError(id, "getting order", err)
+	}
+	if !order.ItemsEditable() {
+		return h.toolError(id, fmt.Sprintf("order %d is %s; %v", orderID, order.Status, storage.ErrOrderNotEditable))
+	}
+	if err := edit(order); err != nil {
+		return h.toolError(id, err.Error())
+	}
+
+	var foreign *storage.ForeignItemsError
+	var short *storage.InsufficientStockError
+	order, err = h.store.UpdateOrderItems(ctx, orderID, order.OrderItems)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrOrderNotEditable), errors.As(err, &foreign), errors.As(err, &short):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "updating order items", err)
+	}
+	h.audit(ctx, tool, models.AuditOrder, orderID, args)
+
+	msg := fmt.Sprintf("Order %d updated, total: %s", order.ID, order.FinalAmount)
+	return h.successResponseText(id, msg+"\n"+resultJSON(order))
+}
+
+// toolSplitOrder moves items of an order onto a new order, as for
+// splitting a table's bill
+func (h *MCPHandler) toolSplitOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	orderID, _ := args["order_id"].(float64)
+	items, _ := args["items"].([]interface{})
+	var moves []models.OrderItem
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		menuItemID, _ := itemMap["menu_item_id"].(float64)
+		quantity, _ := itemMap["quantity"].(float64)
+		moves = append(moves, models.OrderItem{MenuItemID: int(menuItemID), Quantity: int(quantity)})
+	}
+
+	var combine *models.CombineError
+	source, split, err := h.store.SplitOrder(ctx, int(orderID), moves)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.As(err, &combine):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "splitting order", err)
+	}
+	h.audit(ctx, "split_order", models.AuditOrder, source.ID, args)
+
+	msg := fmt.Sprintf("Order %d split: items moved to new order %d (total %s), order %d now totals %s",
+		source.ID, split.ID, split.FinalAmount, source.ID, source.FinalAmount)
+	return h.successResponseText(id, msg+"\n"+resultJSON([]*models.Order{source, split}))
+}
+
+// toolMergeOrders combines orders, such as a group's tables, into one
+func (h *MCPHandler) toolMergeOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	ids, _ := args["order_ids"].([]interface{})
+	var orderIDs []int
+	for _, v := range ids {
+		orderID, _ := v.(float64)
+		orderIDs = append(orderIDs, int(orderID))
+	}
+
+	var combine *models.CombineError
+	merged, err := h.store.MergeOrders(ctx, orderIDs)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.As(err, &combine):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "merging orders", err)
+	}
+	h.audit(ctx, "merge_orders", models.AuditOrder, merged.ID, args)
+
+	msg := fmt.Sprintf("Merged %s into new order %d, total: %s", merged.MergedFromText(), merged.ID, merged.FinalAmount)
+	return h.successResponseText(id, msg+"\n"+resultJSON(merged))
+}
+
+func (h *MCPHandler) toolDeleteOrder(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
 	orderID, ok := args["id"].(float64)
 	if !ok {
 		return h.errorResponse(id, -32602, "Missing id")
 	}
 	
-	_, err := h.db.Exec("DELETE FROM orders WHERE id = $1", int(orderID))
+	err := h.store.DeleteOrder(ctx, int(orderID))
 	if err != nil {
-		log.Printf("Error deleting order: %v", err)
-		return h.errorResponse(id, -32603, "Database error")
+		return h.databaseError(id, "deleting order", err)
 	}
+	h.audit(ctx, "delete_order", models.AuditOrder, int(orderID), args)
 	
 	return h.successResponse(id, fmt.Sprintf("Order %d deleted", int(orderID)))
 }
 
 // Helper functions
-func (h *MCPHandler) successResponse(id interface{}, message string) MCPResponse {
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": message,
-				},
-			},
-		},
-		ID: id,
-	}
-}
-
-func (h *MCPHandler) successResponseText(id interface{}, text string) MCPResponse {
-	return MCPResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": text,
-				},
-			},
-		},
-		ID: id,
+// toolError reports a tool that ran but failed, such as a missing row or an
+// unavailable database, as an isError result the model can read and act on.
+// errorResponse's protocol errors are only for malformed requests, like a
+// missing or mistyped argument, which clients treat as hard failures.
+func (h *MCPHandler) toolError(id jsonrpc.RequestID, message string) MCPResponse {
+	return MCPResponse{JSONRPC: "2.0", Result: mcp.ErrorResult(message), ID: id}
+}
+
+// databaseError logs a failed query and returns a toolError. The driver
+// error stays in the log since it can expose SQL and schema details; a
+// feature the store's backend lacks is said as such.
+func (h *MCPHandler) databaseError(id jsonrpc.RequestID, action string, err error) MCPResponse {
+	if errors.Is(err, storage.ErrUnsupported) {
+		return h.toolError(id, err.Error())
 	}
+	log.Printf("Error %s: %v", action, err)
+	return h.toolError(id, fmt.Sprintf("database error while %s, please try again later", action))
+}
+
+// unavailableError answers a tool call made while the database health
+// check fails, keeping the driver error in the log as databaseError does
+func (h *MCPHandler) unavailableError(id jsonrpc.RequestID, err error) MCPResponse {
+	var unavailable *storage.ErrUnavailable
+	if !errors.As(err, &unavailable) {
+		return h.databaseError(id, "checking the database connection", err)
+	}
+	log.Printf("Database health check failed: %v", err)
+	return h.toolError(id, fmt.Sprintf("database temporarily unavailable, retried %d times, please try again later", unavailable.Attempts))
+}
+
+func (h *MCPHandler) successResponse(id jsonrpc.RequestID, message string) MCPResponse {
+	return MCPResponse{JSONRPC: "2.0", Result: mcp.TextResult(message), ID: id}
+}
+
+// prettyJSONLimit is the largest tool result that is indented for
+// readability; bigger results stay compact, since indenting them costs
+// another copy of the whole payload and the model reads either form
+const prettyJSONLimit = 32 << 10 // 32 KiB
+
+// resultJSON marshals a tool result, indenting it only when it is small
+func resultJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(data) > prettyJSONLimit {
+		return string(data)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+func (h *MCPHandler) successResponseText(id jsonrpc.RequestID, text string) MCPResponse {
+	return MCPResponse{JSONRPC: "2.0", Result: mcp.TextResult(text), ID: id}
+}
+
+// successResponseBlocks is successResponseText for a result of several text
+// blocks
+func (h *MCPHandler) successResponseBlocks(id jsonrpc.RequestID, texts ...string) MCPResponse {
+	return MCPResponse{JSONRPC: "2.0", Result: mcp.BlocksResult(texts...), ID: id}
 }
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolerrors"
+)
+
+// defaultRecentErrors is how many failures get_recent_errors returns
+// without a limit
+const defaultRecentErrors = 20
+
+// recordFailure keeps resp in the failure ring if it is a JSON-RPC error or
+// an isError result
+func (h *MCPHandler) recordFailure(ctx context.Context, id jsonrpc.RequestID, session, tool string, args map[string]interface{}, resp MCPResponse, start time.Time) {
+	entry := toolerrors.Entry{
+		Time:       start,
+		DurationMs: time.Since(start).Milliseconds(),
+		RequestID:  id.String(),
+		ServerID:   mcp.RequestID(ctx),
+		Session:    session,
+		Tool:       tool,
+		Arguments:  args,
+	}
+	if resp.Error != nil {
+		entry.Code, entry.Error = resp.Error.Code, resp.Error.Message
+	} else if result, ok := resp.Result.(*mcp.CallToolResult); ok && result.IsError {
+		entry.Error = result.ErrorText()
+	} else {
+		return
+	}
+	entry.User = requestUser(ctx)
+	entry.Outcome = authz.Outcome(ctx)
+	h.failures.Add(entry)
+}
+
+func (h *MCPHandler) toolGetRecentErrors(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	limit := defaultRecentErrors
+	if v, ok := args["limit"]; ok {
+		n, ok := v.(float64)
+		if !ok || n != float64(int(n)) || n <= 0 {
+			return h.errorResponse(id, -32602, "limit must be a positive integer")
+		}
+		limit = int(n)
+	}
+	tool, _ := args["tool"].(string)
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "get_recent_errors requires the admin role")
+	}
+
+	var entries []toolerrors.Entry
+	for _, e := range h.failures.Recent(toolerrors.BufferSize) {
+		if len(entries) == limit {
+			break
+		}
+		if tool == "" || e.Tool == tool {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return h.successResponse(id, "No failed tool calls since this server started")
+	}
+
+	data := resultJSON(entries)
+	return h.successResponseText(id, fmt.Sprintf("%d most recent failed tool calls on this instance (it keeps the last %d)\n%s",
+		len(entries), toolerrors.BufferSize, data))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// errExportCapped stops an export_orders export at mw.ExportMaxRows
+var errExportCapped = errors.New("export row cap reached")
+
+func exportOrdersDefinition() map[string]interface{} {
+	return map[string]interface{}{"name": "export_orders", "description": fmt.Sprintf("Export a restaurant's orders between two dates as CSV for accounting, one row per line item with the order's totals repeated. Days follow the restaurant's timezone. At most %d rows are returned, with a warning when the export is cut short; GET /api/orders/export streams every row", mw.ExportMaxRows), "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"restaurant_id": map[string]interface{}{"type": "integer", "description": "The restaurant ID"},
+		"from":          map[string]interface{}{"type": "string", "description": "First day to export (YYYY-MM-DD)"},
+		"to":            map[string]interface{}{"type": "string", "description": "Last day to export (YYYY-MM-DD), included"},
+	}, "required": []string{"restaurant_id", "from", "to"}}}
+}
+
+func (h *MCPHandler) toolExportOrders(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	q, err := models.OrderExportArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	// The CSV holds customers' names and phones, so the restaurant scope
+	// applies as it does to get_restaurant
+	restaurant, err := h.store.GetRestaurantByID(ctx, q.RestaurantID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return h.databaseError(id, "getting restaurant", err)
+	}
+	if outcome, err := h.checkRestaurant(ctx, "export_orders", q.RestaurantID, err == nil, args); err != nil {
+		return h.databaseError(id, "checking restaurant access", err)
+	} else if outcome != authz.Allowed {
+		return h.toolError(id, authz.RestaurantNotFound(q.RestaurantID))
+	}
+
+	var buf bytes.Buffer
+	out := models.NewOrderCSV(&buf, restaurant.Timezone)
+	from, to := q.Range(restaurant.Timezone)
+	err = h.store.ExportOrders(ctx, restaurant.ID, from, to, func(r models.OrderExportRow) error {
+		if out.Rows == mw.ExportMaxRows {
+			return errExportCapped
+		}
+		return out.Write(r)
+	})
+	capped := errors.Is(err, errExportCapped)
+	if err == nil || capped {
+		err = out.Flush()
+	}
+	if err != nil {
+		return h.databaseError(id, "exporting orders", err)
+	}
+
+	summary := fmt.Sprintf("%d line items of the orders of restaurant %d from %s to %s, as CSV:", out.Rows, restaurant.ID, q.From, q.To)
+	if capped {
+		summary = fmt.Sprintf("Warning: the export is cut short at %d line items, the most one call returns. Export fewer days at a time, or use GET /api/orders/export for the whole range.\n", mw.ExportMaxRows) + summary
+	}
+	return h.successResponseText(id, summary+"\n"+buf.String())
+}
@@ -0,0 +1,65 @@
+//go:build faultinject
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/faultinject"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+// The inject_fault tool only exists in builds with the faultinject tag; see
+// package faultinject
+
+func init() {
+	toolExamples["inject_fault"] = []toolschema.Example{
+		{"target": "GetRestaurantByID", "kind": "delay", "delay_ms": 5000, "seconds": 60},
+		{"target": "storage", "kind": "error", "seconds": 30},
+		{"target": "http", "seconds": 10},
+		{"clear": true},
+	}
+}
+
+func faultToolDefinitions() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"name": "inject_fault", "description": "Admin only, test builds only. Make a storage method (or all of them) wait or fail, or every HTTP request get a 503, for a number of seconds, to check timeouts, retries and health probes. Returns the active faults", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "A storage method such as GetRestaurantByID, \"storage\" for every storage method, or \"http\" for 503 responses"}, "kind": map[string]interface{}{"type": "string", "enum": []string{faultinject.KindDelay, faultinject.KindError}, "description": "delay waits delay_ms first, error fails the call (default error; http only takes error)"}, "delay_ms": map[string]interface{}{"type": "integer", "minimum": 1, "description": "How long a delay fault waits, bounded by the tool call timeout"}, "seconds": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": faultinject.MaxSeconds, "description": "How long the fault stays active"}, "clear": map[string]interface{}{"type": "boolean", "description": "End every active fault instead"}}}},
+	}
+}
+
+func (h *MCPHandler) faultToolHandlers() map[string]mcp.ToolHandler {
+	return map[string]mcp.ToolHandler{"inject_fault": h.toolInjectFault}
+}
+
+func (h *MCPHandler) toolInjectFault(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	clearAll, _ := args["clear"].(bool)
+	var fault faultinject.Fault
+	if !clearAll {
+		var err error
+		if fault, err = faultinject.Args(args, time.Now()); err != nil {
+			return h.errorResponse(id, -32602, err.Error())
+		}
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "inject_fault requires the admin role")
+	}
+	message := "All faults cleared"
+	if clearAll {
+		faultinject.Clear()
+	} else {
+		faultinject.Inject(fault)
+		message = fmt.Sprintf("Injected %s fault on %s until %s", fault.Kind, fault.Target, fault.Until.Format(time.RFC3339))
+	}
+	log.Printf("faultinject: %s by %s", message, requestUser(ctx))
+	return h.successResponseText(id, message+"\n"+resultJSON(faultinject.Active(time.Now())))
+}
@@ -0,0 +1,9 @@
+//go:build !faultinject
+
+package handlers
+
+import "github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+
+func faultToolDefinitions() []map[string]interface{} { return nil }
+
+func (h *MCPHandler) faultToolHandlers() map[string]mcp.ToolHandler { return nil }
@@ -0,0 +1,53 @@
+//go:build faultinject
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/faultinject"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+)
+
+// TestToolCallsFailFastUnderStorageFaults checks a hung storage call is cut
+// off by the tool call timeout and a failing one becomes a tool error,
+// neither leaking the injected error to the client
+func TestToolCallsFailFastUnderStorageFaults(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, faultinject.WrapStore(mcptest.Store(t)))
+	defer func(timeout time.Duration) { mw.ToolCallTimeout = timeout }(mw.ToolCallTimeout)
+	mw.ToolCallTimeout = 50 * time.Millisecond
+	t.Cleanup(faultinject.Clear)
+	call := func() string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_restaurants","arguments":{"query":"Pune"}}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		return w.Body.String()
+	}
+
+	for _, fault := range []faultinject.Fault{
+		{Target: "SearchRestaurants", Kind: faultinject.KindDelay, DelayMS: 10000},
+		{Target: faultinject.TargetStorage, Kind: faultinject.KindError},
+	} {
+		fault.Until = time.Now().Add(time.Minute)
+		faultinject.Inject(fault)
+		start := time.Now()
+		body := call()
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s fault: the call took %s, want it bounded by the tool timeout", fault.Kind, elapsed)
+		}
+		if !strings.Contains(body, `"isError":true`) || strings.Contains(body, "injected") {
+			t.Errorf("%s fault: %s", fault.Kind, body)
+		}
+		faultinject.Clear()
+	}
+
+	if body := call(); strings.Contains(body, `"isError":true`) {
+		t.Errorf("after clearing the faults: %s", body)
+	}
+}
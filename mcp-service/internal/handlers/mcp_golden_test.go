@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// TestToolResultsGolden calls every tool with its first example and compares
+// the JSON-RPC response with testdata/tools/<tool>.golden, to catch changes
+// to the result wire format. Tools still on raw SQL get an unreachable
+// database, so their golden files hold the isError result.
+func TestToolResultsGolden(t *testing.T) {
+	db, err := sql.Open("postgres", "host=/nonexistent sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tool := range toolDefinitions() {
+		name, _ := tool["name"].(string)
+		t.Run(name, func(t *testing.T) {
+			h := NewMCPHandlerWithStore(db, mcptest.Store(t))
+			h.server.NewRequestID = func() string { return "golden" }
+			params, _ := json.Marshal(map[string]interface{}{"name": name, "arguments": toolExamples[name][0]})
+			body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":` + string(params) + `}`
+			r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.HandleMCP(w, r)
+
+			storagetest.AssertGolden(t, "tools/"+name, json.RawMessage(w.Body.Bytes()))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+)
+
+// TestMCPOversizedBodyIsJSON streams a body past the message limit, with no
+// Content-Length for the middleware to refuse it by
+func TestMCPOversizedBodyIsJSON(t *testing.T) {
+	defer func(limit int64) { mw.MaxMessageBytes = limit }(mw.MaxMessageBytes)
+	mw.MaxMessageBytes = 64
+	h := NewMCPHandler(nil)
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"pad":"`+strings.Repeat("x", 100)+`"}}`))
+	r.ContentLength = -1
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mw.LimitBodyMiddleware(http.HandlerFunc(h.HandleMCP)).ServeHTTP(w, r)
+	// Result has the headers as sent, not as changed after WriteHeader
+	contentType := w.Result().Header.Get("Content-Type")
+	if w.Code != http.StatusRequestEntityTooLarge || contentType != "application/json" || !strings.Contains(w.Body.String(), `"code":-32600`) {
+		t.Errorf("status %d, Content-Type %q, body %s; want a 413 JSON-RPC error", w.Code, contentType, w.Body.String())
+	}
+}
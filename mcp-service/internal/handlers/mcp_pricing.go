@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Pricing rules are time-boxed promotions such as a happy hour. Like direct
+// price changes, managing them needs the owner or admin role.
+
+// pricingRuleProperties are the rule fields create_pricing_rule and
+// update_pricing_rule share
+func pricingRuleProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         map[string]interface{}{"type": "string", "description": "Shown to customers with the discounted price, e.g. Happy hour"},
+		"category":     map[string]interface{}{"type": "string", "description": "Optional. Only items of this menu category, e.g. Beverage"},
+		"menu_item_id": map[string]interface{}{"type": "integer", "description": "Optional. Only this menu item"},
+		"percent_off":  map[string]interface{}{"type": "number", "description": "Discount in percent, more than 0 and at most 100"},
+		"days_of_week": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": models.Weekdays}, "description": "Days the rule runs on; every day when empty or omitted"},
+		"start_time":   map[string]interface{}{"type": "string", "description": "HH:MM in the restaurant's timezone, inclusive"},
+		"end_time":     map[string]interface{}{"type": "string", "description": "HH:MM in the restaurant's timezone, exclusive. Before start_time for a window past midnight, which counts for the day it starts"},
+		"active":       map[string]interface{}{"type": "boolean", "description": "Whether the rule applies (default true)"},
+	}
+}
+
+func createPricingRuleDefinition() map[string]interface{} {
+	properties := pricingRuleProperties()
+	properties["restaurant_id"] = map[string]interface{}{"type": "integer", "description": "The restaurant ID"}
+	return map[string]interface{}{"name": "create_pricing_rule", "description": "Owner or admin only. Create a time-boxed promotion such as a happy hour: percent_off off matching items between start_time and end_time on the given days. Orders get the single best rule per item; rules don't stack", "inputSchema": map[string]interface{}{"type": "object", "properties": properties, "required": []string{"restaurant_id", "name", "percent_off", "start_time", "end_time"}}}
+}
+
+func updatePricingRuleDefinition() map[string]interface{} {
+	properties := pricingRuleProperties()
+	properties["id"] = map[string]interface{}{"type": "integer", "description": "ID of the pricing rule"}
+	return map[string]interface{}{"name": "update_pricing_rule", "description": "Owner or admin only. Change the fields given of a pricing rule, e.g. active=false to pause it", "inputSchema": map[string]interface{}{"type": "object", "properties": properties, "required": []string{"id"}}}
+}
+
+// pricingRuleError answers a failed pricing rule store call
+func (h *MCPHandler) pricingRuleError(id jsonrpc.RequestID, action string, err error) MCPResponse {
+	if errors.Is(err, storage.ErrPricingRulesUnavailable) || errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, err.Error())
+	}
+	return h.databaseError(id, action, err)
+}
+
+func (h *MCPHandler) toolCreatePricingRule(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	rule, err := models.PricingRuleArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	approver, err := h.canApproveChanges(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !approver {
+		return h.toolError(id, "create_pricing_rule requires the owner or admin role")
+	}
+	if err := h.store.CreatePricingRule(ctx, &rule); err != nil {
+		return h.pricingRuleError(id, "creating pricing rule", err)
+	}
+	h.audit(ctx, "create_pricing_rule", models.AuditPricingRule, rule.ID, args)
+	return h.successResponseText(id, fmt.Sprintf("Pricing rule %d created. %s\n%s", rule.ID, rule.Describe(), resultJSON(rule)))
+}
+
+func (h *MCPHandler) toolListPricingRules(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, _ := args["restaurant_id"].(float64)
+
+	rules, err := h.store.ListPricingRules(ctx, int(restaurantID))
+	if err != nil {
+		return h.pricingRuleError(id, "listing pricing rules", err)
+	}
+	if len(rules) == 0 {
+		return h.successResponse(id, fmt.Sprintf("Restaurant %d has no pricing rules", int(restaurantID)))
+	}
+	return h.successResponseText(id, fmt.Sprintf("%d pricing rules\n%s", len(rules), resultJSON(rules)))
+}
+
+func (h *MCPHandler) toolUpdatePricingRule(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	ruleID, _ := args["id"].(float64)
+
+	approver, err := h.canApproveChanges(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !approver {
+		return h.toolError(id, "update_pricing_rule requires the owner or admin role")
+	}
+	rule, err := h.store.GetPricingRule(ctx, int(ruleID))
+	if err != nil {
+		return h.pricingRuleError(id, "getting pricing rule", err)
+	}
+	if err := models.ApplyPricingRuleArgs(rule, args); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	if err := h.store.UpdatePricingRule(ctx, rule); err != nil {
+		return h.pricingRuleError(id, "updating pricing rule", err)
+	}
+	h.audit(ctx, "update_pricing_rule", models.AuditPricingRule, rule.ID, args)
+	return h.successResponseText(id, fmt.Sprintf("Pricing rule %d updated. %s\n%s", rule.ID, rule.Describe(), resultJSON(rule)))
+}
+
+func (h *MCPHandler) toolDeletePricingRule(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	ruleID, _ := args["id"].(float64)
+
+	approver, err := h.canApproveChanges(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !approver {
+		return h.toolError(id, "delete_pricing_rule requires the owner or admin role")
+	}
+	if err := h.store.DeletePricingRule(ctx, int(ruleID)); err != nil {
+		return h.pricingRuleError(id, "deleting pricing rule", err)
+	}
+	h.audit(ctx, "delete_pricing_rule", models.AuditPricingRule, int(ruleID), args)
+	return h.successResponse(id, fmt.Sprintf("Pricing rule %d deleted", int(ruleID)))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// restaurantIDArgument is the argument of every prompt, naming the
+// restaurant whose menu it embeds
+var restaurantIDArgument = mcp.PromptArgument{Name: "restaurant_id", Description: "ID of the restaurant, as listed by list_restaurants", Required: true}
+
+// registerPrompts adds the prompts clients such as Claude Desktop offer as
+// slash commands. Each embeds the menu as it is when the prompt is fetched.
+func (h *MCPHandler) registerPrompts() {
+	h.server.RegisterPrompt(mcp.Prompt{
+		Name:        "take_order",
+		Description: "Take a customer's order at a restaurant: its menu today, and how to collect items, quantities and spice preferences before placing the order",
+		Arguments:   []mcp.PromptArgument{restaurantIDArgument},
+	}, h.promptTakeOrder)
+	h.server.RegisterPrompt(mcp.Prompt{
+		Name:        "menu_review",
+		Description: "Review a restaurant's whole menu and suggest better prices and descriptions",
+		Arguments:   []mcp.PromptArgument{restaurantIDArgument},
+	}, h.promptMenuReview)
+}
+
+func (h *MCPHandler) promptTakeOrder(ctx context.Context, args map[string]string) (*mcp.PromptResult, error) {
+	r, menu, err := h.promptMenu(ctx, "take_order", args, false)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are taking an order for %s (restaurant %d). The menu available today:\n\n", r.Name, r.ID)
+	writeMenu(&b, menu)
+	b.WriteString(`
+Ask the customer what they would like. For each dish:
+- match it to one item of the menu above, and ask again if it matches none or several;
+- confirm the quantity;
+- ask how spicy they want it, and note any request such as "no onion" for the item.
+Also get the customer's name, and whether the order is dine-in, takeaway or delivery.
+Read the order back with each item's price and the total, and only when the customer agrees place it with create_order, using the menu_item_id of each item and its notes.`)
+	return mcp.UserPrompt("Take an order at "+r.Name, b.String()), nil
+}
+
+func (h *MCPHandler) promptMenuReview(ctx context.Context, args map[string]string) (*mcp.PromptResult, error) {
+	r, menu, err := h.promptMenu(ctx, "menu_review", args, true)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review the menu of %s (restaurant %d), a %s restaurant at %s. Every item, including those switched off:\n\n", r.Name, r.ID, r.CuisineType, r.Address)
+	writeMenu(&b, menu)
+	b.WriteString(`
+Suggest improvements, item by item:
+- prices out of line with the rest of their category or with similar dishes;
+- missing, vague or unappetising descriptions, with a better one of a sentence or two;
+- dietary types or spice levels that look wrong for the dish.
+Give the reason for each suggestion. Don't change anything yourself: the owner decides, and applies what they accept with update_menu_item or propose_change.`)
+	return mcp.UserPrompt("Review the menu of "+r.Name, b.String()), nil
+}
+
+// promptMenu returns the restaurant of a prompt's restaurant_id and its
+// menu, read now, with the items switched off if includeUnavailable
+func (h *MCPHandler) promptMenu(ctx context.Context, prompt string, args map[string]string, includeUnavailable bool) (*models.Restaurant, []models.MenuItem, error) {
+	restaurantID, err := strconv.Atoi(strings.TrimSpace(args["restaurant_id"]))
+	if err != nil || restaurantID <= 0 {
+		return nil, nil, fmt.Errorf("%w: restaurant_id must be a positive integer", mcp.ErrInvalidPromptArgument)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mw.ToolCallTimeout)
+	defer cancel()
+	r, err := h.visibleRestaurant(ctx, prompt, restaurantID)
+	if err != nil {
+		log.Printf("Error getting restaurant for prompt %s: %v", prompt, err)
+		return nil, nil, err
+	} else if r == nil {
+		return nil, nil, fmt.Errorf("%w: restaurant %d not found", mcp.ErrInvalidPromptArgument, restaurantID)
+	}
+	menu, err := h.store.GetMenuAsOf(ctx, restaurantID, "", includeUnavailable)
+	if err != nil {
+		log.Printf("Error getting menu for prompt %s: %v", prompt, err)
+		return nil, nil, err
+	}
+	return r, menu, nil
+}
+
+// writeMenu lists menu items a line each, as a prompt embeds them
+func writeMenu(b *strings.Builder, menu []models.MenuItem) {
+	if len(menu) == 0 {
+		b.WriteString("(no items)\n")
+		return
+	}
+	for _, item := range menu {
+		fmt.Fprintf(b, "- menu_item_id %d: %s, %s (%s, %s, %s)", item.ID, item.Name, item.Price, item.Category, item.DietaryType, item.SpiceLevel)
+		if item.Description != "" {
+			fmt.Fprintf(b, ": %s", item.Description)
+		}
+		if !item.Available {
+			b.WriteString(" [switched off]")
+		} else if item.StockQuantity != nil {
+			fmt.Fprintf(b, " [%d left]", *item.StockQuantity)
+		}
+		b.WriteString("\n")
+	}
+}
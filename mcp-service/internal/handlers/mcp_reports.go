@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/reports"
+)
+
+// runReportDefinition describes run_report, listing the available reports
+// and their params so the model can pick one without another round trip
+func runReportDefinition() map[string]interface{} {
+	all, _ := reports.All()
+	usage := make([]string, len(all))
+	for i, r := range all {
+		usage[i] = r.Usage()
+	}
+	description := fmt.Sprintf("Admin only. Run a named read-only report and get a markdown table plus CSV (at most %d rows). Reports: %s",
+		reports.MaxRows, strings.Join(usage, "; "))
+	return map[string]interface{}{"name": "run_report", "description": description, "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"report": map[string]interface{}{"type": "string", "enum": reports.Names()}, "params": map[string]interface{}{"type": "object", "description": "Report parameters by name"}}, "required": []string{"report"}}}
+}
+
+func (h *MCPHandler) toolRunReport(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	name, _ := args["report"].(string)
+	report, err := reports.Get(name)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	params := map[string]interface{}{}
+	if raw, ok := args["params"]; ok && raw != nil {
+		if params, ok = raw.(map[string]interface{}); !ok {
+			return h.errorResponse(id, -32602, "params must be an object")
+		}
+	}
+	if _, err := report.Bind(params); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "run_report requires the admin role")
+	}
+	if h.db == nil {
+		return h.toolError(id, needsPostgres("run_report"))
+	}
+
+	result, err := report.Run(ctx, h.db, params)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "57014" { // query_canceled
+		return h.toolError(id, fmt.Sprintf("report %s took longer than %s; narrow the date range", name, reports.StatementTimeout))
+	}
+	if err != nil {
+		return h.databaseError(id, "running report "+name, err)
+	}
+
+	summary := fmt.Sprintf("Report %s: %d rows.", name, len(result.Rows))
+	var caveats string
+	if result.Truncated {
+		summary = fmt.Sprintf("Report %s: first %d rows shown.", name, reports.MaxRows)
+		caveats = fmt.Sprintf("The report stopped at %d rows; narrow the params to see the rest.", reports.MaxRows)
+	}
+	return h.successResponseBlocks(id, summary, result.Markdown(), "CSV:\n```csv\n"+result.CSV()+"```", caveats)
+}
+
+// isAdmin reports whether the authenticated user of the request has the
+// admin role
+func (h *MCPHandler) isAdmin(ctx context.Context) (bool, error) {
+	role, err := h.userRole(ctx)
+	return role == models.RoleAdmin, err
+}
+
+// needsPostgres is the error of a tool that only runs against Postgres,
+// called on a server without it
+func needsPostgres(tool string) string {
+	return tool + " needs the Postgres database, which this server doesn't use"
+}
+
+// userRole returns the role of the authenticated user of the request, or ""
+// without an active profile. Roles live in user_profiles, not in the token,
+// so a demotion applies immediately. Requests without a user get the
+// anonymous role.
+func (h *MCPHandler) userRole(ctx context.Context) (string, error) {
+	sub, _ := oauth.GetUserFromContext(ctx)["sub"].(string)
+	if sub == "" {
+		return h.anonymousRole, nil
+	}
+	if h.db == nil {
+		return "", nil
+	}
+	var role string
+	err := h.db.QueryRowContext(ctx, `SELECT COALESCE(role, '') FROM user_profiles WHERE user_id = $1 AND status = 'active'`, sub).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// restaurantResources serves each restaurant as restaurant://{id}, the JSON
+// of get_restaurant, and its menu as restaurant://{id}/menu, the JSON of
+// get_menu with no options, so a client can attach them as context without
+// a tool call
+type restaurantResources struct {
+	h *MCPHandler
+}
+
+const restaurantScheme = "restaurant://"
+
+// restaurantURI is the resource URI of a restaurant, or of its menu
+func restaurantURI(restaurantID int, menu bool) string {
+	uri := restaurantScheme + strconv.Itoa(restaurantID)
+	if menu {
+		uri += "/menu"
+	}
+	return uri
+}
+
+// parseRestaurantURI is the inverse of restaurantURI. Only the URIs it
+// returns parse, so restaurant://03 is not restaurant://3.
+func parseRestaurantURI(uri string) (restaurantID int, menu bool, ok bool) {
+	rest, ok := strings.CutPrefix(uri, restaurantScheme)
+	if !ok {
+		return 0, false, false
+	}
+	rest, menu = strings.CutSuffix(rest, "/menu")
+	restaurantID, err := strconv.Atoi(rest)
+	if err != nil || restaurantID <= 0 || restaurantURI(restaurantID, menu) != uri {
+		return 0, false, false
+	}
+	return restaurantID, menu, true
+}
+
+func restaurantResourceEntries(r models.Restaurant) []mcp.Resource {
+	return []mcp.Resource{
+		{URI: restaurantURI(r.ID, false), Name: r.Name, Description: fmt.Sprintf("%s restaurant at %s", r.CuisineType, r.Address), MimeType: "application/json"},
+		{URI: restaurantURI(r.ID, true), Name: r.Name + " menu", Description: "Menu items available today, by category", MimeType: "application/json"},
+	}
+}
+
+// List pages through the restaurants as list_restaurants does, the cursor
+// being the offset of the next page. Restaurant API tokens, which may only
+// call create_order, see no resources.
+func (rr restaurantResources) List(ctx context.Context, cursor string) ([]mcp.Resource, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, mw.ToolCallTimeout)
+	defer cancel()
+
+	if _, _, ok := oauth.RestaurantTokenFromContext(ctx); ok {
+		return []mcp.Resource{}, "", nil
+	}
+
+	offset := 0
+	if cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(cursor); err != nil || offset < 0 {
+			return nil, "", mcp.ErrInvalidCursor
+		}
+	}
+	page := models.Page{Limit: models.DefaultPageSize, Offset: offset}
+	restaurants, total, err := rr.h.store.GetAllRestaurants(ctx, page, "")
+	if err != nil {
+		log.Printf("Error listing resources: %v", err)
+		return nil, "", err
+	}
+	resources := []mcp.Resource{}
+	for _, r := range restaurants {
+		resources = append(resources, restaurantResourceEntries(r)...)
+	}
+	next := ""
+	if end := offset + len(restaurants); len(restaurants) > 0 && end < total {
+		next = strconv.Itoa(end)
+	}
+	return resources, next, nil
+}
+
+// Read returns a restaurant or its menu, behind the same access policy as
+// get_restaurant: a restaurant the user may not see doesn't exist for them
+func (rr restaurantResources) Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	ctx, cancel := context.WithTimeout(ctx, mw.ToolCallTimeout)
+	defer cancel()
+
+	restaurantID, menu, ok := parseRestaurantURI(uri)
+	if !ok {
+		return nil, mcp.ErrResourceNotFound
+	}
+	restaurant, err := rr.h.visibleRestaurant(ctx, "resources/read", restaurantID)
+	if err != nil {
+		log.Printf("Error reading %s: %v", uri, err)
+		return nil, err
+	} else if restaurant == nil {
+		return nil, mcp.ErrResourceNotFound
+	}
+
+	var text string
+	if menu {
+		items, err := rr.h.store.GetMenuAsOf(ctx, restaurantID, "", false)
+		if err != nil {
+			log.Printf("Error reading %s: %v", uri, err)
+			return nil, err
+		}
+		text = resultJSON(items)
+	} else {
+		text = resultJSON(restaurant)
+	}
+	return []mcp.ResourceContents{{URI: uri, MimeType: "application/json", Text: text}}, nil
+}
+
+// visibleRestaurant looks up a restaurant for a resource or prompt, by way
+// of via, behind the access policy of get_restaurant. It is nil when the
+// restaurant doesn't exist or the user may not see it, which restaurant API
+// tokens, limited to create_order, never do.
+func (h *MCPHandler) visibleRestaurant(ctx context.Context, via string, restaurantID int) (*models.Restaurant, error) {
+	if _, _, ok := oauth.RestaurantTokenFromContext(ctx); ok {
+		authz.Record(ctx, via, requestUser(ctx), authz.Denied, restaurantID)
+		return nil, nil
+	}
+	restaurant, err := h.store.GetRestaurantByID(ctx, restaurantID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	outcome, err := h.checkRestaurant(ctx, via, restaurantID, err == nil, nil)
+	if err != nil || outcome != authz.Allowed {
+		return nil, err
+	}
+	return restaurant, nil
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/audit"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/publicurl"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Shadow mode is a soft launch for agents: calls of the destructive tools it
+// covers are validated and recorded with what they would have done, but not
+// run. An admin lists them with list_shadowed_actions and runs the ones that
+// look right with replay_shadowed_action.
+
+// shadowableTools are the tools shadow mode can cover
+var shadowableTools = []string{
+	"update_restaurant", "delete_restaurant",
+	"update_menu_item", "delete_menu_item",
+	"update_order", "cancel_order", "delete_order",
+	"update_pricing_rule", "delete_pricing_rule",
+}
+
+// ShadowMode is the set of tools whose calls are recorded instead of run.
+// The zero value covers none.
+type ShadowMode struct {
+	tools map[string]bool
+}
+
+// ParseShadowMode reads a SHADOW_MODE value: empty or off for none, all for
+// every destructive tool, or a comma-separated list of tool names
+func ParseShadowMode(v string) (ShadowMode, error) {
+	mode := ShadowMode{tools: map[string]bool{}}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "off", "false", "0":
+		return mode, nil
+	case "all", "on", "true", "1":
+		for _, tool := range shadowableTools {
+			mode.tools[tool] = true
+		}
+		return mode, nil
+	}
+	for _, tool := range publicurl.SplitList(v) {
+		if !contains(shadowableTools, tool) {
+			return ShadowMode{}, fmt.Errorf("invalid SHADOW_MODE: %q is not a destructive tool; use all or some of %s", tool, strings.Join(shadowableTools, ", "))
+		}
+		mode.tools[tool] = true
+	}
+	return mode, nil
+}
+
+// ShadowModeFromEnv reads SHADOW_MODE
+func ShadowModeFromEnv() (ShadowMode, error) {
+	return ParseShadowMode(os.Getenv("SHADOW_MODE"))
+}
+
+// Covers reports whether calls of tool are shadowed
+func (m ShadowMode) Covers(tool string) bool {
+	return m.tools[tool]
+}
+
+// Enabled reports whether any tool is shadowed
+func (m ShadowMode) Enabled() bool {
+	return len(m.tools) > 0
+}
+
+func (m ShadowMode) String() string {
+	if !m.Enabled() {
+		return "off"
+	}
+	tools := make([]string, 0, len(m.tools))
+	for tool := range m.tools {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return strings.Join(tools, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SetShadowMode decides which destructive tools are recorded instead of
+// run. Shadow mode is off by default.
+func (h *MCPHandler) SetShadowMode(mode ShadowMode) {
+	h.shadow = mode
+}
+
+// shadowRefusal is a reason a shadowed call would have failed, answered as
+// a tool error like the real call would
+type shadowRefusal string
+
+func (r shadowRefusal) Error() string { return string(r) }
+
+// shadowToolCall validates a call of a shadowed tool, works out what it
+// would do and records it instead of running it
+func (h *MCPHandler) shadowToolCall(ctx context.Context, id jsonrpc.RequestID, tool string, args map[string]interface{}) MCPResponse {
+	if err := h.server.ValidateArgs(tool, args); err != nil {
+		return mcp.InvalidArgs(id, err)
+	}
+	targetID, ok := args["id"].(float64)
+	if !ok || targetID != float64(int(targetID)) || targetID <= 0 {
+		return h.errorResponse(id, -32602, "Missing or invalid id")
+	}
+
+	var refusal shadowRefusal
+	effect, err := h.shadowEffect(ctx, tool, int(targetID), args)
+	switch {
+	case errors.As(err, &refusal), errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrPricingRulesUnavailable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "checking shadowed call", err)
+	}
+
+	action := models.ShadowedAction{Tool: tool, Arguments: args, Effect: effect, RequestedBy: requestUser(ctx)}
+	err = h.store.RecordShadowedAction(ctx, &action)
+	if errors.Is(err, storage.ErrShadowedActionsUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "recording shadowed action", err)
+	}
+	log.Printf("shadow: %s by %s recorded as shadowed action %d, not run: would %s", tool, action.RequestedBy, action.ID, effect)
+	audited := map[string]interface{}{"effect": effect, "shadowed_action_id": action.ID}
+	for k, v := range args {
+		audited[k] = v
+	}
+	audit.RecordOutcome(ctx, h.store, tool, models.AuditShadowed, shadowEntityType(tool), int(targetID), audited)
+
+	resp := h.successResponse(id, fmt.Sprintf("Recorded, pending shadow mode: %s was validated but NOT executed. It would %s. An admin can run it with replay_shadowed_action id %d",
+		tool, effect, action.ID))
+	resp.Result.(*mcp.CallToolResult).StructuredContent = map[string]interface{}{"shadowed": true, "shadowed_action_id": action.ID, "effect": effect}
+	return resp
+}
+
+// shadowEntityType is the audit log entity type of the target of a
+// shadowable tool
+func shadowEntityType(tool string) string {
+	switch tool {
+	case "update_restaurant", "delete_restaurant":
+		return models.AuditRestaurant
+	case "update_menu_item", "delete_menu_item":
+		return models.AuditMenuItem
+	case "update_pricing_rule", "delete_pricing_rule":
+		return models.AuditPricingRule
+	}
+	return models.AuditOrder
+}
+
+// shadowEffect checks a shadowed call the way the tool would and describes
+// what it would do, e.g. "delete menu item 3 \"Paneer Tikka\" of restaurant 1"
+func (h *MCPHandler) shadowEffect(ctx context.Context, tool string, id int, args map[string]interface{}) (string, error) {
+	switch tool {
+	case "update_restaurant", "delete_restaurant":
+		r, err := h.store.GetRestaurantByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if tool == "delete_restaurant" {
+			if hard, _ := args["hard_delete"].(bool); !hard {
+				return fmt.Sprintf("deactivate restaurant %d %q", r.ID, r.Name), nil
+			}
+			if force, _ := args["force"].(bool); force {
+				return fmt.Sprintf("delete restaurant %d %q with all of its menu items and orders", r.ID, r.Name), nil
+			}
+			return fmt.Sprintf("delete restaurant %d %q", r.ID, r.Name), nil
+		}
+		if err := checkShadowVersion(args, "restaurant", r.ID, r.Version); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("set %s on restaurant %d %q", shadowChanges(args), r.ID, r.Name), nil
+
+	case "update_menu_item", "delete_menu_item":
+		if price, _ := args["price"].(float64); tool == "update_menu_item" && price > 0 {
+			approver, err := h.canApproveChanges(ctx)
+			if err != nil {
+				return "", err
+			}
+			if !approver {
+				return "", shadowRefusal(proposeInstead(id))
+			}
+		}
+		m, err := h.store.GetMenuItemByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if tool == "delete_menu_item" {
+			return fmt.Sprintf("delete menu item %d %q of restaurant %d", m.ID, m.Name, m.RestaurantID), nil
+		}
+		if err := checkShadowVersion(args, "menu item", m.ID, m.Version); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("set %s on menu item %d %q (price %s) of restaurant %d", shadowChanges(args), m.ID, m.Name, m.Price, m.RestaurantID), nil
+
+	case "update_order", "cancel_order", "delete_order":
+		o, err := h.store.GetOrderByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		summary := fmt.Sprintf("order %d (%s, total %s)", o.ID, o.Status, o.FinalAmount)
+		switch tool {
+		case "update_order":
+			status, _ := args["status"].(string)
+			tip, hasTip, err := models.TipArg(args)
+			if err != nil {
+				return "", shadowRefusal(err.Error())
+			}
+			if hasTip {
+				if !o.Tippable() {
+					return "", shadowRefusal(fmt.Sprintf("Order %d is %s with payment %s; %s", o.ID, o.Status, o.PaymentStatus, storage.ErrOrderNotTippable))
+				}
+				if status == "" {
+					return fmt.Sprintf("set tip %s on %s", tip, summary), nil
+				}
+				summary = fmt.Sprintf("%s with tip %s", summary, tip)
+			}
+			if status == models.OrderStatusCancelled {
+				return "", shadowRefusal("Use the cancel_order tool to cancel an order so a reason is recorded")
+			}
+			if err := o.CheckTransition(status); err != nil {
+				return "", shadowRefusal(err.Error())
+			}
+			return fmt.Sprintf("move %s to %s", summary, status), nil
+		case "cancel_order":
+			reason, _ := args["reason"].(string)
+			note, _ := args["note"].(string)
+			if err := models.ValidateCancelReason(reason, note); err != nil {
+				return "", shadowRefusal(err.Error())
+			}
+			if !models.CanTransition(o.Status, models.OrderStatusCancelled) {
+				return "", shadowRefusal(fmt.Sprintf("Order %d is %s and can no longer be cancelled", o.ID, o.Status))
+			}
+			return fmt.Sprintf("cancel %s for %s", summary, reason), nil
+		}
+		return "delete " + summary, nil
+
+	case "update_pricing_rule", "delete_pricing_rule":
+		approver, err := h.canApproveChanges(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !approver {
+			return "", shadowRefusal(tool + " requires the owner or admin role")
+		}
+		rule, err := h.store.GetPricingRule(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if tool == "delete_pricing_rule" {
+			return fmt.Sprintf("delete pricing rule %d (%s)", rule.ID, rule.Describe()), nil
+		}
+		before := rule.Describe()
+		if err := models.ApplyPricingRuleArgs(rule, args); err != nil {
+			return "", shadowRefusal(err.Error())
+		}
+		return fmt.Sprintf("change pricing rule %d from %s to %s", rule.ID, before, rule.Describe()), nil
+	}
+	return "", shadowRefusal(tool + " can't be shadowed")
+}
+
+// checkShadowVersion refuses an update whose expected_version is already
+// stale, as the update itself would be
+func checkShadowVersion(args map[string]interface{}, entity string, id, version int) error {
+	expected, err := models.ExpectedVersionArg(args)
+	if err != nil {
+		return shadowRefusal(err.Error())
+	}
+	if expected != nil && *expected != version {
+		return shadowRefusal((&storage.VersionConflictError{Entity: entity, ID: id, Expected: *expected}).Error())
+	}
+	return nil
+}
+
+// shadowChanges lists the fields an update call sets, e.g. "price = 260,
+// available = false"
+func shadowChanges(args map[string]interface{}) string {
+	fields := make([]string, 0, len(args))
+	for k, v := range args {
+		if k != "id" && k != "expected_version" {
+			fields = append(fields, fmt.Sprintf("%s = %v", k, v))
+		}
+	}
+	if len(fields) == 0 {
+		return "nothing"
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ", ")
+}
+
+func (h *MCPHandler) toolListShadowedActions(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	page, err := models.PageArgs(args)
+	if err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+	status, _ := args["status"].(string)
+	if err := models.ValidateShadowStatus(status); err != nil {
+		return h.errorResponse(id, -32602, err.Error())
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "list_shadowed_actions requires the admin role")
+	}
+	actions, total, err := h.store.ListShadowedActions(ctx, status, page)
+	if errors.Is(err, storage.ErrShadowedActionsUnavailable) {
+		return h.toolError(id, err.Error())
+	}
+	if err != nil {
+		return h.databaseError(id, "listing shadowed actions", err)
+	}
+	return h.successResponseText(id, fmt.Sprintf("Shadow mode: %s\n%s\n%s", h.shadow, page.Summary("shadowed actions", len(actions), total), resultJSON(actions)))
+}
+
+// toolReplayShadowedAction runs a recorded call for real, as the admin
+// replaying it. Shadow mode doesn't apply to the replay.
+func (h *MCPHandler) toolReplayShadowedAction(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	actionID, _ := args["id"].(float64)
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "replay_shadowed_action requires the admin role")
+	}
+	replayer := requestUser(ctx)
+	action, err := h.store.ClaimShadowedAction(ctx, int(actionID), replayer)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrShadowReplayed),
+		errors.Is(err, storage.ErrShadowedActionsUnavailable):
+		return h.toolError(id, err.Error())
+	case err != nil:
+		return h.databaseError(id, "claiming shadowed action", err)
+	}
+
+	resp := h.server.Dispatch(ctx, id, action.Tool, action.Arguments)
+	failed, text := replayOutcome(resp)
+	if err := h.store.FinishShadowedAction(ctx, action.ID, failed, text); err != nil {
+		log.Printf("Error recording replay of shadowed action %d: %v", action.ID, err)
+	}
+	log.Printf("shadow: shadowed action %d (%s by %s) replayed by %s, failed=%v: %s", action.ID, action.Tool, action.RequestedBy, replayer, failed, text)
+
+	if failed {
+		return h.toolError(id, fmt.Sprintf("replaying shadowed action %d (%s) failed, it can be tried again: %s", action.ID, action.Tool, text))
+	}
+	return h.successResponse(id, fmt.Sprintf("Replayed shadowed action %d (%s requested by %s): %s", action.ID, action.Tool, action.RequestedBy, text))
+}
+
+// replayOutcome reads whether a replayed call failed and what it answered
+func replayOutcome(resp MCPResponse) (bool, string) {
+	if resp.Error != nil {
+		return true, resp.Error.Message
+	}
+	result, _ := resp.Result.(*mcp.CallToolResult)
+	if result == nil || len(result.Content) == 0 {
+		return false, ""
+	}
+	return result.IsError, result.Content[0].Text
+}
@@ -0,0 +1,786 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp/mcptest"
+	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolerrors"
+)
+
+func TestToolExamplesMatchTheirSchemas(t *testing.T) {
+	if err := CheckToolExamples(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMCPEchoesRequestIDs(t *testing.T) {
+	h := NewMCPHandler(nil)
+	for _, id := range []string{`"7"`, `7`, `9007199254740993`, `"req-abc"`, `null`} {
+		body := `{"jsonrpc":"2.0","id":` + id + `,"method":"initialize","params":{}}`
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body)))
+		var resp struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("id %s: %v in %s", id, err, w.Body)
+		}
+		if string(resp.ID) != id {
+			t.Errorf("initialize with id %s answered with id %s", id, resp.ID)
+		}
+	}
+}
+
+func TestMCPInitializeNegotiatesVersion(t *testing.T) {
+	h := NewMCPHandler(nil)
+	for requested, want := range map[string]string{
+		mcp.ProtocolVersion20241105: mcp.ProtocolVersion20241105,
+		mcp.ProtocolVersion20250326: mcp.ProtocolVersion20250326,
+		"1999-01-01":                mcp.LatestProtocolVersion(),
+	} {
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"` + requested + `"}}`
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body)))
+		var resp struct {
+			Result struct {
+				ProtocolVersion string `json:"protocolVersion"`
+			} `json:"result"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Result.ProtocolVersion != want {
+			t.Errorf("initialize with %s got version %q, want %s", requested, resp.Result.ProtocolVersion, want)
+		}
+	}
+}
+
+// conversePOST sends each message as a request of its own to POST /mcp
+func conversePOST(t *testing.T, messages ...string) []string {
+	h := NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	var responses []string
+	for _, msg := range messages {
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(msg)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", msg, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != "" {
+			responses = append(responses, body)
+		}
+	}
+	return responses
+}
+
+// TestMCPConformance runs the protocol suite every transport passes over
+// POST /mcp, one request per message
+func TestMCPConformance(t *testing.T) {
+	mcptest.Conformance(t, conversePOST)
+}
+
+// menu returns n menu items of about 25 KB each, as a large listing holds
+func menu(n int) []map[string]interface{} {
+	items := make([]map[string]interface{}, n)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": i + 1, "name": "Masala Dosa", "price": 90, "description": strings.Repeat("Crisp rice crepe ", 1500)}
+	}
+	return items
+}
+
+func TestResultJSONIndentsOnlySmallResults(t *testing.T) {
+	if got := resultJSON(map[string]int{"id": 1}); got != "{\n  \"id\": 1\n}" {
+		t.Errorf("small result = %q, want it indented", got)
+	}
+	if got := resultJSON(menu(2)); strings.Contains(got, "\n") {
+		t.Errorf("result over prettyJSONLimit is indented")
+	}
+}
+
+// BenchmarkResultJSON compares resultJSON with always indenting on a result
+// of about 5 MB
+func BenchmarkResultJSON(b *testing.B) {
+	items := menu(200)
+	b.Run("MarshalIndent", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, _ := json.MarshalIndent(items, "", "  ")
+			_ = string(data)
+		}
+	})
+	b.Run("resultJSON", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = resultJSON(items)
+		}
+	})
+}
+
+// BenchmarkHandleMCPLargeListing lists 200 restaurants of about 25 KB each,
+// a 5 MB result, through /mcp
+func BenchmarkHandleMCPLargeListing(b *testing.B) {
+	store := memory.New()
+	for i := 0; i < models.MaxPageSize; i++ {
+		r := &models.Restaurant{Name: fmt.Sprintf("Kitchen %d", i), Address: strings.Repeat("MG Road, Pune ", 1800)}
+		if err := store.CreateRestaurant(context.Background(), r); err != nil {
+			b.Fatal(err)
+		}
+	}
+	h := NewMCPHandlerWithStore(nil, store)
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_restaurants","arguments":{"limit":200}}}`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"isError":true`) {
+			b.Fatalf("status %d: %.200s", w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestScopedRestaurantDenialLooksLikeNotFound checks a staff user asking for
+// a restaurant outside their scope gets the not-found answer, while the
+// failure log records the denial
+func TestScopedRestaurantDenialLooksLikeNotFound(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, memory.New())
+	h.SetScopeSource(func(ctx context.Context) (authz.Scope, error) { return authz.Restaurants(1), nil })
+
+	check := func(restaurantID int, exists bool) (string, MCPResponse, toolerrors.Entry) {
+		ctx := authz.WithOutcome(context.Background())
+		outcome, err := h.checkRestaurant(ctx, "get_restaurant", restaurantID, exists, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := h.toolError(jsonrpc.NullID, authz.RestaurantNotFound(restaurantID))
+		h.recordFailure(ctx, jsonrpc.NullID, "", "get_restaurant", nil, resp, time.Now())
+		return outcome, resp, h.failures.Recent(1)[0]
+	}
+
+	if outcome, err := h.checkRestaurant(context.Background(), "get_restaurant", 1, true, nil); err != nil || outcome != authz.Allowed {
+		t.Errorf("restaurant in scope: %s, %v; want allowed", outcome, err)
+	}
+
+	denied, deniedResp, deniedEntry := check(2, true)
+	missing, missingResp, missingEntry := check(2, false)
+	if denied != authz.Denied || missing != authz.NotFound {
+		t.Fatalf("outcomes %s and %s, want denied and not_found", denied, missing)
+	}
+	deniedJSON, _ := json.Marshal(deniedResp)
+	missingJSON, _ := json.Marshal(missingResp)
+	if string(deniedJSON) != string(missingJSON) {
+		t.Errorf("denied answer %s differs from not-found answer %s", deniedJSON, missingJSON)
+	}
+	if deniedEntry.Outcome != authz.Denied || missingEntry.Outcome != authz.NotFound {
+		t.Errorf("failure log outcomes %q and %q, want denied and not_found", deniedEntry.Outcome, missingEntry.Outcome)
+	}
+}
+
+// TestRestaurantKeyedToolsApplyTheScope calls every tool that reads one
+// restaurant's data about a restaurant outside the caller's scope: each
+// answers not found and writes a denied entry to the audit log
+func TestRestaurantKeyedToolsApplyTheScope(t *testing.T) {
+	store := mcptest.Store(t)
+	other := models.Restaurant{Name: "Other Kitchen", Address: "MG Road, Pune", NotificationPreferences: models.DefaultNotificationPreferences()}
+	if err := store.CreateRestaurant(context.Background(), &other); err != nil {
+		t.Fatal(err)
+	}
+	h := NewMCPHandlerWithStore(nil, store)
+	h.SetScopeSource(func(ctx context.Context) (authz.Scope, error) { return authz.Restaurants(1), nil })
+	call := func(tool, args string) string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tool+`","arguments":`+args+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		return w.Body.String()
+	}
+
+	if body := call("get_menu", `{"restaurant_id":1}`); strings.Contains(body, `"isError":true`) {
+		t.Fatalf("get_menu of a restaurant in scope: %s", body)
+	}
+	for tool, args := range map[string]string{
+		"get_menu":              `{"restaurant_id":%d}`,
+		"search_menu_items":     `{"restaurant_id":%d,"query":"dosa"}`,
+		"list_orders":           `{"restaurant_id":%d}`,
+		"get_sales_report":      `{"restaurant_id":%d,"from":"2026-01-01","to":"2026-01-31"}`,
+		"get_top_selling_items": `{"restaurant_id":%d}`,
+		"summarize_orders":      `{"restaurant_id":%d}`,
+		"get_restaurant_stats":  `{"restaurant_id":%d}`,
+	} {
+		denied, missing := call(tool, fmt.Sprintf(args, other.ID)), call(tool, fmt.Sprintf(args, 99))
+		if !strings.Contains(denied, fmt.Sprintf("Restaurant %d not found", other.ID)) || !strings.Contains(denied, `"isError":true`) {
+			t.Errorf("%s of a restaurant outside the scope: %s", tool, denied)
+		}
+		if !strings.Contains(missing, "Restaurant 99 not found") {
+			t.Errorf("%s of a missing restaurant: %s", tool, missing)
+		}
+	}
+
+	entries, total, err := store.GetAuditLog(context.Background(), models.AuditQuery{}, models.Page{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	denials := map[string]bool{}
+	for _, e := range entries {
+		if e.Outcome != models.AuditDenied || e.EntityType != models.AuditRestaurant || e.EntityID == nil || *e.EntityID != other.ID {
+			t.Errorf("audit entry %+v, want only denials of restaurant %d", e, other.ID)
+		}
+		denials[e.Tool] = true
+	}
+	if total != 7 || len(denials) != 7 {
+		t.Errorf("%d audit entries for tools %v, want one denial per tool", total, denials)
+	}
+}
+
+// tokenFinder knows one restaurant API token
+type tokenFinder struct {
+	secret string
+	token  models.RestaurantToken
+}
+
+func (f tokenFinder) FindRestaurantToken(ctx context.Context, secret string) (*models.RestaurantToken, error) {
+	if secret != f.secret {
+		return nil, nil
+	}
+	return &f.token, nil
+}
+
+// TestRestaurantTokenOnlyCreatesOrdersForItsRestaurant checks the POS token
+// path through the auth middleware: a token may only call create_order for
+// its own restaurant, on /mcp, within its own rate limit
+func TestRestaurantTokenOnlyCreatesOrdersForItsRestaurant(t *testing.T) {
+	db, err := sql.Open("postgres", "host=/nonexistent sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := NewMCPHandlerWithStore(db, mcptest.Store(t))
+	auth := oauth.NewAuthMiddleware(nil, nil)
+	auth.EnableRestaurantTokens(tokenFinder{secret: "rt_secret", token: models.RestaurantToken{ID: 7, RestaurantID: 1, Name: "Front counter POS"}},
+		mw.NewRateLimiter("restaurant_token", 3, oauth.RestaurantTokenKey))
+	handler := auth.Middleware(http.HandlerFunc(h.HandleMCP))
+
+	call := func(path, authorization, tool string, args map[string]interface{}) *httptest.ResponseRecorder {
+		params, _ := json.Marshal(map[string]interface{}{"name": tool, "arguments": args})
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":`+string(params)+`}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	order := func(restaurantID int) map[string]interface{} {
+		return map[string]interface{}{"restaurant_id": restaurantID, "customer_name": "Walk-in", "items": []interface{}{map[string]interface{}{"menu_item_id": 1, "quantity": 1}}}
+	}
+
+	if w := call("/mcp", "Token rt_wrong", "create_order", order(1)); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: status %d, want 401", w.Code)
+	}
+	if w := call("/api/orders", "Token rt_secret", "create_order", order(1)); w.Code != http.StatusUnauthorized {
+		t.Errorf("token off /mcp: status %d, want 401", w.Code)
+	}
+	if w := call("/mcp", "Token rt_secret", "get_restaurant", map[string]interface{}{"id": 1}); !strings.Contains(w.Body.String(), "may only call create_order") {
+		t.Errorf("get_restaurant with a token: %s", w.Body.String())
+	}
+	if w := call("/mcp", "Token rt_secret", "create_order", order(2)); !strings.Contains(w.Body.String(), "Restaurant 2 not found") {
+		t.Errorf("order for another restaurant: %s", w.Body.String())
+	}
+	if w := call("/mcp", "Token rt_secret", "create_order", order(1)); !strings.Contains(w.Body.String(), "Order created") {
+		t.Errorf("order for its restaurant: %s", w.Body.String())
+	}
+	if w := call("/mcp", "Token rt_secret", "create_order", order(1)); w.Code != http.StatusTooManyRequests {
+		t.Errorf("fourth request in a minute: status %d, want 429", w.Code)
+	}
+
+	if e := h.failures.Recent(1)[0]; e.User != "token:Front counter POS" {
+		t.Errorf("failure attributed to %q, want the token name", e.User)
+	}
+}
+
+// TestRestaurantTokenSeesNoResources checks a token, which may only call
+// create_order, can't read even its own restaurant's menu as a resource
+func TestRestaurantTokenSeesNoResources(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	auth := oauth.NewAuthMiddleware(nil, nil)
+	auth.EnableRestaurantTokens(tokenFinder{secret: "rt_secret", token: models.RestaurantToken{ID: 7, RestaurantID: 1, Name: "Front counter POS"}},
+		mw.NewRateLimiter("restaurant_token", 10, oauth.RestaurantTokenKey))
+	handler := auth.Middleware(http.HandlerFunc(h.HandleMCP))
+
+	for msg, want := range map[string]string{
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`:                                        `"resources":[]`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"restaurant://1/menu"}}`: `"code":-32002`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(msg))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Token rt_secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%s with a token = %s, want %s", msg, w.Body.String(), want)
+		}
+	}
+}
+
+// TestCreateOrderIdempotencyKeyHeader retries POST /api/orders with one
+// Idempotency-Key, concurrently as a client timing out would
+func TestCreateOrderIdempotencyKeyHeader(t *testing.T) {
+	h := &RestaurantHandler{store: mcptest.Store(t)}
+	post := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(`{"restaurant_id":1,"customer_name":"Walk-in","items":[{"menu_item_id":4,"quantity":2}]}`))
+		r.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		h.CreateOrder(w, r)
+		return w
+	}
+
+	ids := make([]int, 5)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := post("pos-42")
+			var o models.Order
+			if err := json.Unmarshal(w.Body.Bytes(), &o); err != nil || w.Code != http.StatusCreated {
+				t.Errorf("status %d: %s", w.Code, w.Body.String())
+			}
+			ids[i] = o.ID
+		}()
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Errorf("retries with one key made orders %v, want one", ids)
+			break
+		}
+	}
+
+	if w := post("pos-43"); !strings.Contains(w.Body.String(), `"total_amount":80`) || strings.Contains(w.Body.String(), fmt.Sprintf(`"id":%d,`, ids[0])) {
+		t.Errorf("another key: %s", w.Body.String())
+	}
+	if w := post("bad\x01key"); w.Code != http.StatusBadRequest {
+		t.Errorf("unprintable key: status %d, want 400", w.Code)
+	}
+}
+
+// TestDuplicateRestaurantIsRefused creates the golden store's restaurant
+// again, with create_restaurant and POST /api/restaurants
+func TestDuplicateRestaurantIsRefused(t *testing.T) {
+	store := mcptest.Store(t)
+	h := NewMCPHandlerWithStore(nil, store)
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_restaurant","arguments":{"name":"test kitchen","address":"FC Road, Pune"}}}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleMCP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "a restaurant with this name and address already exists, id=1") {
+		t.Errorf("create_restaurant: %s", body)
+	}
+
+	rest := &RestaurantHandler{store: store}
+	w = httptest.NewRecorder()
+	rest.CreateRestaurant(w, httptest.NewRequest(http.MethodPost, "/api/restaurants", strings.NewReader(`{"name":"Test Kitchen","address":"fc road, pune"}`)))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "id=1") {
+		t.Errorf("POST /api/restaurants: status %d, %s; want 409", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	rest.CreateRestaurant(w, httptest.NewRequest(http.MethodPost, "/api/restaurants", strings.NewReader(`{"name":"Test Kitchen","address":"Baner, Pune"}`)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("another address: status %d, %s; want 201", w.Code, w.Body.String())
+	}
+}
+
+// unhealthyStore is a store whose database stopped answering pings
+type unhealthyStore struct {
+	*memory.Store
+}
+
+func (unhealthyStore) EnsureConnected(ctx context.Context) error {
+	return &storage.ErrUnavailable{Attempts: 3, Err: errors.New("connection refused")}
+}
+
+// TestToolCallChecksDatabaseHealth runs a tool whose store could answer it
+// while the database is down: the health check's error is what comes back
+func TestToolCallChecksDatabaseHealth(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, unhealthyStore{mcptest.Store(t)})
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_restaurant","arguments":{"id":1}}}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleMCP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "database temporarily unavailable, retried 3 times") || strings.Contains(body, "connection refused") {
+		t.Errorf("get_restaurant with the database down: %s", body)
+	}
+}
+
+func TestParseShadowMode(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		covers  []string
+		skips   []string
+		invalid bool
+	}{
+		{value: "", skips: []string{"delete_restaurant"}},
+		{value: "off", skips: []string{"delete_restaurant"}},
+		{value: "all", covers: []string{"delete_restaurant", "cancel_order", "update_pricing_rule"}, skips: []string{"create_order"}},
+		{value: "delete_menu_item, cancel_order", covers: []string{"delete_menu_item", "cancel_order"}, skips: []string{"delete_restaurant"}},
+		{value: "create_order", invalid: true},
+	} {
+		mode, err := ParseShadowMode(tc.value)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("%q: no error", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+			continue
+		}
+		for _, tool := range tc.covers {
+			if !mode.Covers(tool) {
+				t.Errorf("%q doesn't cover %s", tc.value, tool)
+			}
+		}
+		for _, tool := range tc.skips {
+			if mode.Covers(tool) {
+				t.Errorf("%q covers %s", tc.value, tool)
+			}
+		}
+	}
+}
+
+// TestShadowModeRecordsDestructiveCallsWithoutRunningThem checks a shadowed
+// delete is validated and recorded with its effect, in the shadowed actions
+// and the audit log, while the restaurant stays, and a call that would fail
+// is refused without a record
+func TestShadowModeRecordsDestructiveCallsWithoutRunningThem(t *testing.T) {
+	store := mcptest.Store(t)
+	h := NewMCPHandlerWithStore(nil, store)
+	mode, err := ParseShadowMode("delete_restaurant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetShadowMode(mode)
+
+	call := func(args string) string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_restaurant","arguments":`+args+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		return w.Body.String()
+	}
+
+	if body := call(`{"id":1}`); !strings.Contains(body, "Recorded, pending shadow mode") || !strings.Contains(body, "NOT executed") {
+		t.Fatalf("shadowed delete: %s", body)
+	}
+	if _, err := store.GetRestaurantByID(context.Background(), 1); err != nil {
+		t.Errorf("restaurant gone after a shadowed delete: %v", err)
+	}
+	if body := call(`{"id":999}`); !strings.Contains(body, `"isError":true`) {
+		t.Errorf("shadowed delete of a missing restaurant: %s", body)
+	}
+
+	actions, total, err := store.ListShadowedActions(context.Background(), "", models.Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || actions[0].Status != models.ShadowStatusPending || !strings.HasPrefix(actions[0].Effect, "deactivate restaurant 1 ") {
+		t.Errorf("shadowed actions %+v, want one pending deactivation of restaurant 1", actions)
+	}
+
+	entries, total, err := store.GetAuditLog(context.Background(), models.AuditQuery{}, models.Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || entries[0].Outcome != models.AuditShadowed || entries[0].Tool != "delete_restaurant" ||
+		entries[0].EntityType != models.AuditRestaurant || entries[0].EntityID == nil || *entries[0].EntityID != 1 ||
+		entries[0].Arguments["effect"] != actions[0].Effect {
+		t.Errorf("audit log %+v, want the shadowed delete of restaurant 1 with its effect", entries)
+	}
+}
+
+// TestShadowedUpdateOrderChecksTheTransition checks an illegal status move
+// is refused, naming the statuses the order can move to, before it is
+// recorded
+func TestShadowedUpdateOrderChecksTheTransition(t *testing.T) {
+	store := mcptest.Store(t)
+	if err := store.UpdateOrder(context.Background(), &models.Order{ID: 1, Status: models.OrderStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewMCPHandlerWithStore(nil, store)
+	mode, err := ParseShadowMode("update_order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetShadowMode(mode)
+
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"update_order","arguments":{"id":1,"status":"delivered"}}}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleMCP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "allowed next statuses: confirmed, cancelled") {
+		t.Errorf("pending to delivered: %s", body)
+	}
+}
+
+// TestHardDeleteRestaurantNeedsForceWhileInUse checks a restaurant with
+// menu items and orders is only hard deleted with force, and the reply says
+// what went
+func TestHardDeleteRestaurantNeedsForceWhileInUse(t *testing.T) {
+	store := mcptest.Store(t)
+	h := NewMCPHandlerWithStore(nil, store)
+	call := func(args string) string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_restaurant","arguments":`+args+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		return w.Body.String()
+	}
+
+	if body := call(`{"id":1,"hard_delete":true}`); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "pass force") {
+		t.Fatalf("hard delete without force: %s", body)
+	}
+	if body := call(`{"id":1,"force":true}`); !strings.Contains(body, "-32602") {
+		t.Errorf("force without hard_delete: %s", body)
+	}
+	if r, err := store.GetRestaurantByID(context.Background(), 1); err != nil || !r.Active {
+		t.Fatalf("restaurant after refused deletes: %+v, %v", r, err)
+	}
+	if body := call(`{"id":1,"hard_delete":true,"force":true}`); !strings.Contains(body, "Restaurant 1 deleted with ") || !strings.Contains(body, `"orders_deleted"`) {
+		t.Errorf("delete with force: %s", body)
+	}
+	if _, err := store.GetRestaurantByID(context.Background(), 1); err == nil {
+		t.Error("restaurant still there after a forced delete")
+	}
+}
+
+// TestBulkCreateMenuItemsIsAllOrNothing checks an invalid item rejects the
+// whole batch with an error naming its index
+func TestBulkCreateMenuItemsIsAllOrNothing(t *testing.T) {
+	store := mcptest.Store(t)
+	h := NewMCPHandlerWithStore(nil, store)
+	call := func(items string) string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"bulk_create_menu_items","arguments":{"restaurant_id":1,"items":`+items+`}}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		return w.Body.String()
+	}
+	menuSize := func() int {
+		menu, err := store.GetMenuByRestaurantID(context.Background(), 1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(menu)
+	}
+	before := menuSize()
+
+	for _, items := range []string{
+		`[{"name":"Idli Sambar","price":70},{"name":"Medu Vada","price":60,"spice_level":"volcanic"}]`,
+		`[{"name":"Idli Sambar","price":70},{"name":"Medu Vada","price":0}]`,
+	} {
+		if body := call(items); !strings.Contains(body, "item 1") && !strings.Contains(body, "items[1]") {
+			t.Errorf("%s: %s, want item 1 named", items, body)
+		}
+	}
+	if n := menuSize(); n != before {
+		t.Errorf("menu has %d items after rejected batches, want %d", n, before)
+	}
+
+	if body := call(`[{"name":"Idli Sambar","price":70},{"name":"Medu Vada","price":60}]`); !strings.Contains(body, "2 of 2 succeeded") {
+		t.Errorf("valid batch: %s", body)
+	}
+	if n := menuSize(); n != before+2 {
+		t.Errorf("menu has %d items, want %d", n, before+2)
+	}
+}
+
+// TestToolCallEchoesMeta checks the client's _meta comes back on the result
+// with the ID the server logged the call under
+// TestDeprecatedArgumentKeepsStructuredContent calls tools that return
+// structuredContent with a deprecated argument: the warnings are added to
+// it instead of replacing it
+func TestDeprecatedArgumentKeepsStructuredContent(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	mode, err := ParseShadowMode("update_menu_item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetShadowMode(mode)
+	call := func(tool, args string) map[string]interface{} {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tool+`","arguments":`+args+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		var resp struct {
+			Result struct {
+				StructuredContent map[string]interface{} `json:"structuredContent"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Result.StructuredContent
+	}
+
+	// restore_restaurant returns a *models.Restaurant
+	if sc := call("restore_restaurant", `{"restaurant_id":1}`); sc["id"] != float64(1) || sc["name"] == nil || sc["warnings"] == nil {
+		t.Errorf("restore_restaurant structuredContent %v, want the restaurant and warnings", sc)
+	}
+	// A shadowed call returns a map of its effect
+	if sc := call("update_menu_item", `{"menu_item_id":1,"category":"Desserts"}`); sc["shadowed"] != true || sc["effect"] == nil || sc["warnings"] == nil {
+		t.Errorf("shadowed update_menu_item structuredContent %v, want the shadowed effect and warnings", sc)
+	}
+}
+
+func TestToolCallEchoesMeta(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	h.server.NewRequestID = func() string { return "req-1" }
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_restaurants","arguments":{},"_meta":{"progressToken":"p-1"}}}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleMCP(w, r)
+
+	var resp struct {
+		Result mcp.CallToolResult `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if meta := resp.Result.Meta; meta["progressToken"] != "p-1" || meta["serverRequestId"] != "req-1" {
+		t.Errorf("_meta = %v, want the progressToken echoed with serverRequestId req-1", meta)
+	}
+}
+
+// TestSingleBlockMetaJoinsReportBlocks checks a multi-block report comes as
+// one text block when the client asks for it in _meta
+func TestSingleBlockMetaJoinsReportBlocks(t *testing.T) {
+	h := NewMCPHandlerWithStore(nil, mcptest.Store(t))
+	call := func(meta string) mcp.CallToolResult {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_sales_report","arguments":{"restaurant_id":1,"from":"2024-05-01","to":"2024-05-31"}`+meta+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		var resp struct {
+			Result mcp.CallToolResult `json:"result"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Result
+	}
+
+	if blocks := call("").Content; len(blocks) < 2 {
+		t.Fatalf("report blocks %+v, want a summary and caveats", blocks)
+	}
+	joined := call(`,"_meta":{"singleBlock":true}`).Content
+	if len(joined) != 1 || !strings.HasPrefix(joined[0].Text, "Sales for restaurant 1") || !strings.Contains(joined[0].Text, "\n\nCancelled orders are excluded") {
+		t.Errorf("singleBlock report %+v", joined)
+	}
+}
+
+// TestRESTAndMCPShareEntityJSON checks the REST API and the MCP tools encode
+// the same restaurant and menu with the same fields and values, as both use
+// the models types
+func TestRESTAndMCPShareEntityJSON(t *testing.T) {
+	store := mcptest.Store(t)
+	rest := &RestaurantHandler{store: store}
+	h := NewMCPHandlerWithStore(nil, store)
+	tool := func(name, args string) string {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":`+args+`}}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleMCP(w, r)
+		var resp struct {
+			Result mcp.CallToolResult `json:"result"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Result.IsError || len(resp.Result.Content) != 1 {
+			t.Fatalf("%s: %s", name, w.Body)
+		}
+		return resp.Result.Content[0].Text
+	}
+	get := func(handle http.HandlerFunc, target string) string {
+		w := httptest.NewRecorder()
+		handle(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", target, w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+	// canonical re-encodes a JSON document with sorted keys and no
+	// indentation, so only field names and values are compared
+	canonical := func(doc string) string {
+		var v interface{}
+		if err := json.Unmarshal([]byte(doc), &v); err != nil {
+			t.Fatalf("%v: %s", err, doc)
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	for _, tc := range []struct {
+		entity    string
+		rest, mcp string
+	}{
+		{"restaurant", get(rest.GetRestaurant, "/api/restaurants/get?id=1"), tool("get_restaurant", `{"id":1}`)},
+		{"menu", get(rest.GetMenu, "/api/restaurants/menu?restaurant_id=1"), tool("get_menu", `{"restaurant_id":1}`)},
+	} {
+		if got, want := canonical(tc.rest), canonical(tc.mcp); got != want {
+			t.Errorf("%s JSON differs\nREST: %s\nMCP:  %s", tc.entity, got, want)
+		}
+	}
+}
+
+func TestExportOrdersCSV(t *testing.T) {
+	store := mcptest.Store(t)
+	o := models.Order{RestaurantID: 1, CustomerName: `Rao, Ravi "RK"`, Status: models.OrderStatusPending,
+		OrderItems: []models.OrderItem{{MenuItemID: 2, Quantity: 1, Notes: "no ghee"}, {MenuItemID: 4, Quantity: 2}}}
+	if err := store.CreateOrder(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	today := models.LocalTime("", time.Now()).Format(models.DateLayout)
+
+	rest := &RestaurantHandler{store: store}
+	w := httptest.NewRecorder()
+	rest.ExportOrders(w, httptest.NewRequest(http.MethodGet, "/api/orders/export?restaurant_id=1&from="+today+"&to="+today, nil))
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" || len(lines) != 4 {
+		t.Fatalf("GET /api/orders/export: %d %s", w.Code, w.Body)
+	}
+	if !strings.Contains(lines[2], `,"Rao, Ravi ""RK""",`) || !strings.HasSuffix(lines[2], ",2,Vegan Thali,1,180.00,180.00,no ghee") {
+		t.Errorf("first line item of the second order: %s", lines[2])
+	}
+	w = httptest.NewRecorder()
+	rest.ExportOrders(w, httptest.NewRequest(http.MethodGet, "/api/orders/export?restaurant_id=1&from="+today, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("without to: status %d, want 400", w.Code)
+	}
+
+	defer func(max int) { mw.ExportMaxRows = max }(mw.ExportMaxRows)
+	mw.ExportMaxRows = 2
+	h := NewMCPHandlerWithStore(nil, store)
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"export_orders","arguments":{"restaurant_id":1,"from":"`+today+`","to":"`+today+`"}}}`))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h.HandleMCP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, "Warning: the export is cut short at 2 line items") || strings.Contains(body, "Filter Coffee") {
+		t.Errorf("export_orders over the cap: %s", body)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/authz"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// A restaurant's POS pushes orders with a restaurant API token instead of an
+// OAuth login. Admins manage the tokens with the tools below; a request made
+// with one may only create orders for its own restaurant.
+
+// restaurantTokenTool is the only tool a restaurant API token may call
+const restaurantTokenTool = "create_order"
+
+// restaurantTokenDenial returns why a call of tool by a restaurant API token
+// is refused, or "" when it isn't or the request uses another credential
+func restaurantTokenDenial(ctx context.Context, tool string, args map[string]interface{}) string {
+	restaurantID, _, ok := oauth.RestaurantTokenFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if tool != restaurantTokenTool {
+		return fmt.Sprintf("restaurant API tokens may only call %s", restaurantTokenTool)
+	}
+	if requested, _ := args["restaurant_id"].(float64); int(requested) != restaurantID {
+		authz.Record(ctx, tool, requestUser(ctx), authz.Denied, int(requested))
+		return authz.RestaurantNotFound(int(requested))
+	}
+	return ""
+}
+
+func (h *MCPHandler) toolCreateRestaurantToken(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, _ := args["restaurant_id"].(float64)
+	name, _ := args["name"].(string)
+	var expiresAt *time.Time
+	if v, ok := args["expires_in_days"]; ok {
+		days, _ := v.(float64)
+		if days <= 0 {
+			return h.errorResponse(id, -32602, "expires_in_days must be a positive integer")
+		}
+		t := time.Now().AddDate(0, 0, int(days))
+		expiresAt = &t
+	}
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "create_restaurant_token requires the admin role")
+	}
+	if h.db == nil {
+		return h.toolError(id, needsPostgres("create_restaurant_token"))
+	}
+	if _, err := h.store.GetRestaurantByID(ctx, int(restaurantID)); errors.Is(err, storage.ErrNotFound) {
+		return h.toolError(id, authz.RestaurantNotFound(int(restaurantID)))
+	} else if err != nil {
+		return h.databaseError(id, "getting restaurant", err)
+	}
+
+	token, secret, err := h.tokens.CreateRestaurantToken(ctx, int(restaurantID), name, requestUser(ctx), expiresAt)
+	if err != nil {
+		return h.databaseError(id, "creating restaurant token", err)
+	}
+	h.audit(ctx, "create_restaurant_token", models.AuditRestaurantToken, token.ID, args)
+	return h.successResponseText(id, fmt.Sprintf("Created token %d %q for restaurant %d. Give the POS this secret now, it is not shown again; it sends it as \"Authorization: Token <secret>\" to /mcp and may only call %s\n%s\n%s",
+		token.ID, token.Name, token.RestaurantID, restaurantTokenTool, secret, resultJSON(token)))
+}
+
+func (h *MCPHandler) toolListRestaurantTokens(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	restaurantID, _ := args["restaurant_id"].(float64)
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "list_restaurant_tokens requires the admin role")
+	}
+	if h.db == nil {
+		return h.toolError(id, needsPostgres("list_restaurant_tokens"))
+	}
+	tokens, err := h.tokens.ListRestaurantTokens(ctx, int(restaurantID))
+	if err != nil {
+		return h.databaseError(id, "listing restaurant tokens", err)
+	}
+	if len(tokens) == 0 {
+		return h.successResponse(id, fmt.Sprintf("Restaurant %d has no API tokens", int(restaurantID)))
+	}
+	return h.successResponseText(id, fmt.Sprintf("%d tokens\n%s", len(tokens), resultJSON(tokens)))
+}
+
+func (h *MCPHandler) toolRevokeRestaurantToken(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) MCPResponse {
+	tokenID, _ := args["id"].(float64)
+
+	admin, err := h.isAdmin(ctx)
+	if err != nil {
+		return h.databaseError(id, "checking user role", err)
+	}
+	if !admin {
+		return h.toolError(id, "revoke_restaurant_token requires the admin role")
+	}
+	if h.db == nil {
+		return h.toolError(id, needsPostgres("revoke_restaurant_token"))
+	}
+	token, err := h.tokens.RevokeRestaurantToken(ctx, int(tokenID))
+	if err != nil {
+		return h.databaseError(id, "revoking restaurant token", err)
+	}
+	if token == nil {
+		return h.toolError(id, fmt.Sprintf("Restaurant token %d not found", int(tokenID)))
+	}
+	h.audit(ctx, "revoke_restaurant_token", models.AuditRestaurantToken, token.ID, args)
+	return h.successResponse(id, fmt.Sprintf("Token %d %q of restaurant %d revoked", token.ID, token.Name, token.RestaurantID))
+}
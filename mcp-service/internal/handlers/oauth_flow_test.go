@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/config"
+	"github.com/vishalk17/mcp-service-restaurant/internal/oauth"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+func TestMain(m *testing.M) { storagetest.Main(m) }
+
+// fakeIdP is an upstream identity provider that signs in one user for any
+// authorization code
+func fakeIdP(t *testing.T, email string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "idp-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"idp-access","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer idp-access" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"sub": "idp-42", "email": email, "name": "Asha Rao"})
+	})
+	idp := httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// postMCP posts a JSON-RPC body to /mcp with an optional bearer token
+func postMCP(t *testing.T, client *http.Client, base, bearer, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, base+"/mcp", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// TestOAuthFlowThenMCP walks a client through registration, authorization,
+// token exchange, /mcp calls, refresh and revocation, as cmd/api wires them
+func TestOAuthFlowThenMCP(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithName("Test Kitchen").Build(t, db)
+	const email = "owner@example.com"
+	if _, err := db.Exec(`INSERT INTO user_profiles (user_id, email, name) VALUES ('user-1', $1, 'Asha Rao')`, email); err != nil {
+		t.Fatal(err)
+	}
+	idp := fakeIdP(t, email)
+
+	mux := http.NewServeMux()
+	app := httptest.NewServer(mux)
+	defer app.Close()
+	cfg := &config.Config{
+		OAuth: &config.OAuthConfig{
+			Provider: "google", ClientID: "upstream-client", ClientSecret: "upstream-secret",
+			AuthURL: idp.URL + "/authorize", TokenURL: idp.URL + "/token", UserInfoURL: idp.URL + "/userinfo",
+			RedirectURI: app.URL + "/oauth/callback",
+		},
+		Server: &config.ServerConfig{OAuthServerURL: app.URL, JWTSecret: "test-secret", AccessTokenLife: 3600, RefreshTokenLife: 86400},
+	}
+	server := oauth.NewServerWithProvider(cfg, oauth.NewStorage(db.DB), oauth.NewProviderWithClient(cfg.OAuth, idp.Client()))
+	mux.HandleFunc("/oauth/authorize", server.HandleAuthorize)
+	mux.HandleFunc("/oauth/callback", server.HandleCallback)
+	mux.HandleFunc("/oauth/token", server.HandleToken)
+	mux.HandleFunc("/oauth/register", server.HandleRegister)
+	mux.HandleFunc("/oauth/revoke", server.HandleRevoke)
+	mux.Handle("/mcp", oauth.NewAuthMiddleware(server.GetTokenManager(), nil).Middleware(http.HandlerFunc(NewMCPHandler(db.DB).HandleMCP)))
+
+	client := app.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	redirectTo := func(resp *http.Response) *url.URL {
+		t.Helper()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("status %d, want a redirect", resp.StatusCode)
+		}
+		u, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	// Dynamic client registration
+	resp, err := client.Post(app.URL+"/oauth/register", "application/json",
+		strings.NewReader(`{"client_name":"Test Client","redirect_uris":["http://localhost:6274/callback"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registered struct {
+		ClientID     string   `json:"client_id"`
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	json.NewDecoder(resp.Body).Decode(&registered)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || registered.ClientID == "" || len(registered.RedirectURIs) != 1 {
+		t.Fatalf("register: status %d, %+v", resp.StatusCode, registered)
+	}
+
+	// Authorize sends the browser to the IdP, which calls back with a code.
+	// Clients send PKCE parameters; the server doesn't verify them yet, but
+	// they must not break the flow.
+	resp, err = client.Get(app.URL + "/oauth/authorize?" + url.Values{
+		"client_id": {registered.ClientID}, "redirect_uri": {"http://localhost:6274/callback"},
+		"response_type": {"code"}, "state": {"client-state"},
+		"code_challenge": {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGlSstw-cM"}, "code_challenge_method": {"S256"},
+	}.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	toIdP := redirectTo(resp)
+	if !strings.HasPrefix(toIdP.String(), idp.URL+"/authorize") || toIdP.Query().Get("state") == "" {
+		t.Fatalf("authorize redirected to %s, want the IdP", toIdP)
+	}
+	resp, err = client.Get(app.URL + "/oauth/callback?" + url.Values{"code": {"idp-code"}, "state": {toIdP.Query().Get("state")}}.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back := redirectTo(resp)
+	if back.Host != "localhost:6274" || back.Query().Get("state") != "client-state" || back.Query().Get("code") == "" {
+		t.Fatalf("callback redirected to %s, want the client with a code and its state", back)
+	}
+
+	token := func(form url.Values) (access, refresh string) {
+		t.Helper()
+		resp, err := client.PostForm(app.URL+"/oauth/token", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var tokens struct {
+			AccessToken  string `json:"access_token"`
+			TokenType    string `json:"token_type"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		json.NewDecoder(resp.Body).Decode(&tokens)
+		if resp.StatusCode != http.StatusOK || tokens.TokenType != "Bearer" || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+			t.Fatalf("token %s: status %d, %+v", form.Get("grant_type"), resp.StatusCode, tokens)
+		}
+		return tokens.AccessToken, tokens.RefreshToken
+	}
+	access, refresh := token(url.Values{
+		"grant_type": {"authorization_code"}, "code": {back.Query().Get("code")}, "client_id": {registered.ClientID},
+		"redirect_uri": {"http://localhost:6274/callback"}, "code_verifier": {"dBjftJeZ4CVP-mJ92K1d8ZQLj6lEpv1ic5n0BHU4sbk"},
+	})
+
+	list := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	get := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_restaurant","arguments":{"restaurant_id":` + strconv.Itoa(r.Restaurant.ID) + `}}}`
+	if code, body := postMCP(t, client, app.URL, "", list); code != http.StatusUnauthorized {
+		t.Fatalf("tools/list without a token: status %d: %s", code, body)
+	}
+	if code, body := postMCP(t, client, app.URL, access, list); code != http.StatusOK || !strings.Contains(body, `"name":"get_restaurant"`) {
+		t.Fatalf("tools/list: status %d: %.300s", code, body)
+	}
+	if code, body := postMCP(t, client, app.URL, access, get); code != http.StatusOK || !strings.Contains(body, "Test Kitchen") || strings.Contains(body, `"isError":true`) {
+		t.Fatalf("get_restaurant: status %d: %.300s", code, body)
+	}
+
+	// A refresh rotates the refresh token
+	access2, _ := token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refresh}})
+	resp, err = client.PostForm(app.URL+"/oauth/token", url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refresh}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("reusing a refresh token: status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if code, body := postMCP(t, client, app.URL, access2, get); code != http.StatusOK || !strings.Contains(body, "Test Kitchen") {
+		t.Fatalf("get_restaurant with the refreshed token: status %d: %.300s", code, body)
+	}
+
+	// A revoked access token is refused by /mcp
+	resp, err = client.PostForm(app.URL+"/oauth/revoke", url.Values{"token": {access2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: status %d", resp.StatusCode)
+	}
+	if code, body := postMCP(t, client, app.URL, access2, list); code != http.StatusUnauthorized || !strings.Contains(body, "Invalid or expired token") {
+		t.Errorf("tools/list with a revoked token: status %d: %s", code, body)
+	}
+}
@@ -3,62 +3,147 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/vishalk17/mcp-service-restaurant/internal/audit"
 	mw "github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/publicurl"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
 )
 
-type Restaurant struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Address     string `json:"address"`
-	PhoneNumber string `json:"phone_number"`
-	CuisineType string `json:"cuisine_type"`
-}
-
-type MenuItem struct {
-	ID           int     `json:"id"`
-	RestaurantID int     `json:"restaurant_id"`
-	Name         string  `json:"name"`
-	Description  string  `json:"description"`
-	Price        float64 `json:"price"`
-	Category     string  `json:"category"`
-	DietaryType  string  `json:"dietary_type"`
-	SpiceLevel   string  `json:"spice_level"`
-	Available    bool    `json:"available"`
+type RestaurantHandler struct {
+	db    *sql.DB
+	store storage.Store
+	links *publicurl.Builder
 }
 
-type RestaurantHandler struct {
-	db *sql.DB
+func NewRestaurantHandler(db *sql.DB, links *publicurl.Builder) *RestaurantHandler {
+	return NewRestaurantHandlerWithStore(db, storage.Wrap(db), links)
 }
 
-func NewRestaurantHandler(db *sql.DB) *RestaurantHandler {
-	return &RestaurantHandler{db: db}
+// NewRestaurantHandlerWithStore reads through store. Passing the store the
+// MCP handler uses shares in-flight reads between the two, so a change made
+// through MCP is never hidden from REST by a read that began before it.
+func NewRestaurantHandlerWithStore(db *sql.DB, store storage.Store, links *publicurl.Builder) *RestaurantHandler {
+	return &RestaurantHandler{db: db, store: store, links: links}
 }
 
-// ListRestaurants handles GET /api/restaurants
+// ListRestaurants handles GET /api/restaurants, paged with limit and offset
+// and ordered by sort (id, name, created_at or cuisine_type). With q or
+// cuisine it searches instead; see searchRestaurants.
 func (h *RestaurantHandler) ListRestaurants(w http.ResponseWriter, r *http.Request) {
 	if mw.IsDebug() { log.Printf("ListRestaurants called from %s", r.RemoteAddr) }
-	rows, err := h.db.Query(`
-		SELECT id, name, address, phone_number, cuisine_type 
-		FROM restaurants 
-		ORDER BY name
-	`)
+	if r.URL.Query().Get("q") != "" || r.URL.Query().Get("cuisine") != "" {
+		h.searchRestaurants(w, r)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sort := r.URL.Query().Get("sort")
+	if err := models.ValidateRestaurantSort(sort); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	restaurants, total, err := h.store.GetAllRestaurants(r.Context(), page, sort)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	restaurants := []Restaurant{}
-	for rows.Next() {
-		var r Restaurant
-		if err := rows.Scan(&r.ID, &r.Name, &r.Address, &r.PhoneNumber, &r.CuisineType); err != nil {
-			continue
+	h.setPageHeaders(w, r, "/api/restaurants", page, len(restaurants), total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restaurants)
+}
+
+// createRestaurantRequest is the body of POST /api/restaurants
+type createRestaurantRequest struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	PhoneNumber string `json:"phone_number"`
+	Email       string `json:"email"`
+	CuisineType string `json:"cuisine_type"`
+}
+
+// CreateRestaurant handles POST /api/restaurants. A restaurant with the
+// name and address of another one, ignoring case, is refused with 409.
+func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Request) {
+	if mw.IsDebug() { log.Printf("CreateRestaurant called from %s", r.RemoteAddr) }
+	var req createRestaurantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.Address == "" {
+		http.Error(w, "name and address are required", http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateEmail(req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.CuisineType == "" {
+		req.CuisineType = "Indian"
+	}
+
+	restaurant := &models.Restaurant{
+		Name:                    req.Name,
+		Address:                 req.Address,
+		PhoneNumber:             req.PhoneNumber,
+		Email:                   req.Email,
+		CuisineType:             req.CuisineType,
+		NotificationPreferences: models.DefaultNotificationPreferences(),
+	}
+	var duplicate *storage.DuplicateRestaurantError
+	err := h.store.CreateRestaurant(r.Context(), restaurant)
+	if errors.As(err, &duplicate) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit.Record(r.Context(), h.store, "POST /api/restaurants", models.AuditRestaurant, restaurant.ID, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(restaurant)
+}
+
+// searchRestaurants answers GET /api/restaurants?q=&cuisine= with up to
+// limit (default 20) restaurants whose name or address contains q and whose
+// cuisine type is cuisine, ordered by name
+func (h *RestaurantHandler) searchRestaurants(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var limit int
+	if raw := query.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
 		}
-		restaurants = append(restaurants, r)
+		limit = v
+	}
+	limit, err := models.RestaurantSearchLimit(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	restaurants, err := h.store.SearchRestaurants(r.Context(), query.Get("q"), query.Get("cuisine"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -80,14 +165,8 @@ func (h *RestaurantHandler) GetRestaurant(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var restaurant Restaurant
-	err = h.db.QueryRow(`
-		SELECT id, name, address, phone_number, cuisine_type 
-		FROM restaurants 
-		WHERE id = $1
-	`, id).Scan(&restaurant.ID, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &restaurant.CuisineType)
-
-	if err == sql.ErrNoRows {
+	restaurant, err := h.store.GetRestaurantByID(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
 		http.Error(w, "Restaurant not found", http.StatusNotFound)
 		return
 	}
@@ -100,6 +179,29 @@ func (h *RestaurantHandler) GetRestaurant(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(restaurant)
 }
 
+// GetStats handles GET /api/restaurants/{id}/stats
+func (h *RestaurantHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if mw.IsDebug() { log.Printf("GetStats called from %s", r.RemoteAddr) }
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.store.GetRestaurantStats(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Restaurant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 // GetMenu handles GET /api/restaurants/{id}/menu
 func (h *RestaurantHandler) GetMenu(w http.ResponseWriter, r *http.Request) {
 	if mw.IsDebug() { log.Printf("GetMenu called from %s", r.RemoteAddr) }
@@ -115,27 +217,229 @@ func (h *RestaurantHandler) GetMenu(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.db.Query(`
-		SELECT id, restaurant_id, name, description, price, category, dietary_type, spice_level, available
-		FROM menu_items 
-		WHERE restaurant_id = $1 AND available = true
-		ORDER BY category, name
-	`, restaurantID)
+	asOf := r.URL.Query().Get("as_of")
+	if asOf != "" {
+		if _, err := time.Parse(models.DateLayout, asOf); err != nil {
+			http.Error(w, "Invalid as_of, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var includeUnavailable bool
+	if v := r.URL.Query().Get("include_unavailable"); v != "" {
+		if includeUnavailable, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid include_unavailable, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	menuItems, err := h.store.GetMenuAsOf(r.Context(), restaurantID, asOf, includeUnavailable)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	menuItems := []MenuItem{}
-	for rows.Next() {
-		var m MenuItem
-		if err := rows.Scan(&m.ID, &m.RestaurantID, &m.Name, &m.Description, &m.Price, &m.Category, &m.DietaryType, &m.SpiceLevel, &m.Available); err != nil {
-			continue
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(menuItems)
+}
+
+// ListOrders handles GET /api/orders, optionally filtered by status (one or
+// a comma-separated list), restaurant_id and a from/to creation date range
+// and paged with limit and offset
+func (h *RestaurantHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	if mw.IsDebug() { log.Printf("ListOrders called from %s", r.RemoteAddr) }
+	var filter models.OrderFilter
+	var err error
+	if filter.Statuses, err = models.ParseOrderStatuses(r.URL.Query().Get("status")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if idStr := r.URL.Query().Get("restaurant_id"); idStr != "" {
+		if filter.RestaurantID, err = strconv.Atoi(idStr); err != nil {
+			http.Error(w, "Invalid restaurant_id", http.StatusBadRequest)
+			return
 		}
-		menuItems = append(menuItems, m)
+	}
+	if err := filter.SetCreatedRange("from", r.URL.Query().Get("from"), "to", r.URL.Query().Get("to")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := pageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	found, total, err := h.store.GetOrders(r.Context(), filter, page)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Restaurant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	orders := make([]Order, 0, len(found))
+	for _, o := range found {
+		orders = append(orders, Order{ID: o.ID, RestaurantID: o.RestaurantID, CustomerName: o.CustomerName, Status: o.Status, TotalAmount: o.FinalAmount})
 	}
 
+	h.setPageHeaders(w, r, "/api/orders", page, len(orders), total)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(menuItems)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// ExportOrders handles GET /api/orders/export?restaurant_id=&from=&to=,
+// streaming the restaurant's orders between the dates (YYYY-MM-DD,
+// inclusive, in its timezone) as CSV with one row per line item.
+func (h *RestaurantHandler) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	if mw.IsDebug() { log.Printf("ExportOrders called from %s", r.RemoteAddr) }
+	query := r.URL.Query()
+	q := models.OrderExportQuery{From: query.Get("from"), To: query.Get("to")}
+	q.RestaurantID, _ = strconv.Atoi(query.Get("restaurant_id"))
+	if err := q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	restaurant, err := h.store.GetRestaurantByID(r.Context(), q.RestaurantID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Restaurant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders-%d-%s-to-%s.csv"`, restaurant.ID, q.From, q.To))
+	from, to := q.Range(restaurant.Timezone)
+	out := models.NewOrderCSV(w, restaurant.Timezone)
+	err = h.store.ExportOrders(r.Context(), restaurant.ID, from, to, out.Write)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		log.Printf("Error exporting orders of restaurant %d: %v", restaurant.ID, err)
+		if out.Rows == 0 {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Rows may already be sent with a 200; dropping the connection keeps
+		// a partial file from passing for the whole export
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// createOrderRequest is the body of POST /api/orders
+type createOrderRequest struct {
+	RestaurantID  int    `json:"restaurant_id"`
+	CustomerName  string `json:"customer_name"`
+	CustomerPhone string `json:"customer_phone"`
+	OrderType     string `json:"order_type"`
+	Items         []struct {
+		MenuItemID int    `json:"menu_item_id"`
+		Quantity   int    `json:"quantity"`
+		Notes      string `json:"notes"`
+	} `json:"items"`
+}
+
+// CreateOrder handles POST /api/orders, placing a pending order priced from
+// the restaurant's menu. A request repeated with the same Idempotency-Key
+// header gets the order the first one created instead of a duplicate.
+func (h *RestaurantHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	if mw.IsDebug() { log.Printf("CreateOrder called from %s", r.RemoteAddr) }
+	key := r.Header.Get("Idempotency-Key")
+	if err := models.ValidateIdempotencyKey(key); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req createOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.RestaurantID <= 0 || req.CustomerName == "" || len(req.Items) == 0 {
+		http.Error(w, "restaurant_id, customer_name and items are required", http.StatusBadRequest)
+		return
+	}
+	switch req.OrderType {
+	case "", models.OrderTypeDineIn, models.OrderTypeTakeaway, models.OrderTypeDelivery:
+	default:
+		http.Error(w, "Invalid order_type: must be dine_in, takeaway or delivery", http.StatusBadRequest)
+		return
+	}
+
+	order := &models.Order{
+		RestaurantID:   req.RestaurantID,
+		CustomerName:   req.CustomerName,
+		CustomerPhone:  req.CustomerPhone,
+		OrderType:      req.OrderType,
+		Status:         models.OrderStatusPending,
+		PaymentStatus:  models.PaymentStatusPending,
+		IdempotencyKey: key,
+	}
+	for _, item := range req.Items {
+		if item.MenuItemID <= 0 || item.Quantity <= 0 {
+			http.Error(w, "Each item needs a menu_item_id and a positive quantity", http.StatusBadRequest)
+			return
+		}
+		order.OrderItems = append(order.OrderItems, models.OrderItem{MenuItemID: item.MenuItemID, Quantity: item.Quantity, Notes: item.Notes})
+	}
+
+	var foreign *storage.ForeignItemsError
+	var short *storage.InsufficientStockError
+	err := h.store.CreateOrder(r.Context(), order)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrRestaurantInactive), errors.As(err, &foreign):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.As(err, &short):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit.Record(r.Context(), h.store, "POST /api/orders", models.AuditOrder, order.ID, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// setPageHeaders reports the total and the next page of a list response in
+// headers, so the body can stay a plain array
+func (h *RestaurantHandler) setPageHeaders(w http.ResponseWriter, r *http.Request, path string, page models.Page, shown, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next := page.Offset + shown; shown > 0 && next < total {
+		query := r.URL.Query()
+		query.Set("limit", strconv.Itoa(page.Limit))
+		query.Set("offset", strconv.Itoa(next))
+		if link := h.links.URL(r, path, query); link != "" {
+			w.Header().Set("Link", "<"+link+`>; rel="next"`)
+		}
+	}
+}
+
+// pageParams reads the optional limit and offset query parameters
+func pageParams(r *http.Request) (models.Page, error) {
+	var values [2]int
+	for i, name := range []string{"limit", "offset"} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return models.Page{}, fmt.Errorf("invalid %s", name)
+		}
+		values[i] = v
+	}
+	return models.NewPage(values[0], values[1])
 }
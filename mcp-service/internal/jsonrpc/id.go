@@ -0,0 +1,68 @@
+// Package jsonrpc holds JSON-RPC 2.0 types shared by the MCP transports.
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// RequestID is a JSON-RPC request id. It keeps the exact JSON the client
+// sent (a string, an integer or null) and writes it back unchanged, so "7"
+// stays a string, 9007199254740993 keeps its precision and 7 is never
+// rendered as 7.000000. The zero value is a null id, which is also what a
+// request without an id (a notification) decodes to.
+type RequestID struct {
+	raw json.RawMessage
+}
+
+// NullID is the id used in responses to requests whose id could not be read
+var NullID = RequestID{}
+
+// IsNull reports whether the id is null or was absent
+func (id RequestID) IsNull() bool {
+	return len(id.raw) == 0 || bytes.Equal(id.raw, []byte("null"))
+}
+
+// MarshalJSON writes the id exactly as it was received
+func (id RequestID) MarshalJSON() ([]byte, error) {
+	if len(id.raw) == 0 {
+		return []byte("null"), nil
+	}
+	return id.raw, nil
+}
+
+// UnmarshalJSON accepts a string, an integer or null. Fractional numbers,
+// objects and arrays are rejected as the spec allows only those three.
+func (id *RequestID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		id.raw = nil
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid request id: %v", err)
+		}
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid request id %s: must be a string, integer or null", data)
+		}
+		// Integers of any size are kept verbatim; only fractions are refused
+		if bytes.ContainsAny(data, ".eE") {
+			return fmt.Errorf("invalid request id %s: must be an integer", data)
+		}
+	}
+	id.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// String returns the id as it appears on the wire, for logging
+func (id RequestID) String() string {
+	if len(id.raw) == 0 {
+		return "null"
+	}
+	return string(id.raw)
+}
@@ -0,0 +1,48 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	for _, id := range []string{`"7"`, `7`, `-3`, `9007199254740993`, `123456789012345678901234567890`, `""`, `null`} {
+		var req struct {
+			ID RequestID `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(`{"id":`+id+`}`), &req); err != nil {
+			t.Errorf("decoding id %s: %v", id, err)
+			continue
+		}
+		if got, _ := json.Marshal(req.ID); string(got) != id {
+			t.Errorf("id %s encodes as %s", id, got)
+		}
+		if req.ID.String() != id {
+			t.Errorf("id %s String() = %s", id, req.ID.String())
+		}
+	}
+}
+
+func TestRequestIDMissingIsNull(t *testing.T) {
+	var req struct {
+		ID RequestID `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(`{"method":"notifications/initialized"}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if !req.ID.IsNull() {
+		t.Error("a missing id isn't null")
+	}
+	if got, _ := json.Marshal(req.ID); string(got) != "null" {
+		t.Errorf("missing id encodes as %s", got)
+	}
+}
+
+func TestRequestIDRejectsOtherTypes(t *testing.T) {
+	for _, id := range []string{`7.5`, `7.0`, `1e3`, `true`, `{}`, `[1]`} {
+		var got RequestID
+		if err := json.Unmarshal([]byte(id), &got); err == nil {
+			t.Errorf("id %s was accepted as %s", id, got)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Package loadgen drives concurrent MCP sessions against a server with a
+// weighted mix of tool calls and reports throughput, latency percentiles
+// and error rates. cmd/loadgen runs it against a deployment; the remote MCP
+// server's tests run it against a seeded in-process instance to hold p95
+// latency to a budget.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+)
+
+// Weighted is a tool and its share of the calls in a Mix
+type Weighted struct {
+	Tool   string
+	Weight int
+}
+
+// Mix is the tools a session calls, picked at random by weight
+type Mix []Weighted
+
+// DefaultMix is mostly menu reads with some order listing and creation,
+// roughly what a front desk assistant does
+func DefaultMix() Mix {
+	return Mix{{"get_menu", 60}, {"list_orders", 20}, {"create_order", 20}}
+}
+
+// ParseMix reads a mix written as tool=weight pairs separated by commas,
+// e.g. get_menu=60,list_orders=20,create_order=20
+func ParseMix(s string) (Mix, error) {
+	var mix Mix
+	for _, part := range strings.Split(s, ",") {
+		tool, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || tool == "" {
+			return nil, fmt.Errorf("mix entry %q is not tool=weight", part)
+		}
+		if _, known := argBuilders[tool]; !known {
+			return nil, fmt.Errorf("mix entry %q: loadgen can't call %s; it calls %s", part, tool, strings.Join(Tools(), ", "))
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("mix entry %q: weight must be a whole number, 0 or more", part)
+		}
+		mix = append(mix, Weighted{tool, w})
+	}
+	if mix.total() == 0 {
+		return nil, errors.New("mix has no weight")
+	}
+	return mix, nil
+}
+
+func (m Mix) total() int {
+	total := 0
+	for _, w := range m {
+		total += w.Weight
+	}
+	return total
+}
+
+func (m Mix) pick(r *rand.Rand) string {
+	n := r.IntN(m.total())
+	for _, w := range m {
+		if n < w.Weight {
+			return w.Tool
+		}
+		n -= w.Weight
+	}
+	return m[len(m)-1].Tool
+}
+
+func (m Mix) has(tool string) bool {
+	for _, w := range m {
+		if w.Tool == tool && w.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is one load run. Sessions run concurrently until Duration has
+// passed or, when Calls is set, Calls tool calls have been made in total.
+type Config struct {
+	URL          string // the MCP endpoint, e.g. http://localhost:8080/mcp
+	Token        string // sent as a bearer token when set
+	Sessions     int
+	Duration     time.Duration
+	Calls        int
+	Mix          Mix
+	RestaurantID int // the menu read and the restaurant orders are placed at
+	Timeout      time.Duration
+	Client       *http.Client // http.DefaultClient when nil
+	Seed         uint64       // of the tool and order choices, for repeatable runs
+}
+
+// argBuilders make the arguments of each tool loadgen can call
+var argBuilders = map[string]func(cfg *Config, menu []int, r *rand.Rand) map[string]interface{}{
+	"get_menu": func(cfg *Config, _ []int, _ *rand.Rand) map[string]interface{} {
+		return map[string]interface{}{"restaurant_id": cfg.RestaurantID}
+	},
+	"list_orders": func(cfg *Config, _ []int, _ *rand.Rand) map[string]interface{} {
+		return map[string]interface{}{"restaurant_id": cfg.RestaurantID, "limit": 20}
+	},
+	"create_order": syntheticOrder,
+}
+
+// Tools are the tools a Mix can name
+func Tools() []string {
+	return []string{"create_order", "get_menu", "list_orders"}
+}
+
+// syntheticOrder is a cash dine-in order of one to three menu items
+func syntheticOrder(cfg *Config, menu []int, r *rand.Rand) map[string]interface{} {
+	n := 1 + r.IntN(3)
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = map[string]interface{}{"menu_item_id": menu[r.IntN(len(menu))], "quantity": 1 + r.IntN(3)}
+	}
+	return map[string]interface{}{
+		"restaurant_id":  cfg.RestaurantID,
+		"customer_name":  fmt.Sprintf("Load Test %04d", r.IntN(10000)),
+		"customer_phone": fmt.Sprintf("+91-90000-%05d", r.IntN(100000)),
+		"items":          items,
+		"order_type":     "dine_in",
+		"payment_method": "cash",
+	}
+}
+
+// Run makes the calls of cfg and reports on them. It fails only when the
+// run can't start: the server doesn't initialize, or create_order is in
+// the mix and the restaurant's menu has no available items. Failed calls
+// during the run are counted in the report.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.Sessions < 1 {
+		return nil, errors.New("sessions must be at least 1")
+	}
+	if cfg.Duration <= 0 && cfg.Calls <= 0 {
+		return nil, errors.New("a duration or a number of calls is required")
+	}
+	if len(cfg.Mix) == 0 {
+		cfg.Mix = DefaultMix()
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	c := &client{cfg: &cfg}
+	if err := c.initialize(ctx); err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	var menu []int
+	if cfg.Mix.has("create_order") {
+		var err error
+		if menu, err = c.availableMenu(ctx); err != nil {
+			return nil, fmt.Errorf("read the menu of restaurant %d: %w", cfg.RestaurantID, err)
+		}
+		if len(menu) == 0 {
+			return nil, fmt.Errorf("restaurant %d has no available menu items to order", cfg.RestaurantID)
+		}
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	report := newReport()
+	var calls atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < cfg.Sessions; i++ {
+		wg.Add(1)
+		go func(session int) {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(cfg.Seed, uint64(session)))
+			sess := &client{cfg: &cfg}
+			if err := sess.initialize(ctx); err != nil {
+				if ctx.Err() == nil {
+					report.record("initialize", 0, err)
+				}
+				return
+			}
+			for ctx.Err() == nil {
+				if cfg.Calls > 0 && calls.Add(1) > int64(cfg.Calls) {
+					return
+				}
+				tool := cfg.Mix.pick(r)
+				args := argBuilders[tool](&cfg, menu, r)
+				began := time.Now()
+				_, err := sess.callTool(ctx, tool, args)
+				elapsed := time.Since(began)
+				if err != nil && ctx.Err() != nil {
+					return // cut off by the end of the run, not a failure
+				}
+				report.record(tool, elapsed, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// client speaks JSON-RPC over HTTP POST to one MCP endpoint. Responses may
+// be plain JSON or a server-sent event, as from cmd/remote-mcp.
+type client struct {
+	cfg             *Config
+	nextID          int
+	protocolVersion string
+}
+
+// ToolError is a tools/call that came back as a JSON-RPC error or an
+// isError result
+type ToolError struct {
+	Tool    string
+	Message string
+}
+
+func (e *ToolError) Error() string {
+	return e.Tool + ": " + e.Message
+}
+
+func (c *client) initialize(ctx context.Context) error {
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	params := map[string]interface{}{
+		"protocolVersion": mcp.LatestProtocolVersion(),
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "loadgen", "version": "1.0.0"},
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.protocolVersion = result.ProtocolVersion
+	return nil
+}
+
+func (c *client) callTool(ctx context.Context, tool string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	var result mcp.CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]interface{}{"name": tool, "arguments": args}, &result); err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return nil, &ToolError{Tool: tool, Message: rpcErr.Message}
+		}
+		return nil, err
+	}
+	if result.IsError {
+		text := ""
+		if len(result.Content) > 0 {
+			text = result.Content[0].Text
+		}
+		return &result, &ToolError{Tool: tool, Message: text}
+	}
+	return &result, nil
+}
+
+// availableMenu is the IDs of the restaurant's available menu items
+func (c *client) availableMenu(ctx context.Context) ([]int, error) {
+	result, err := c.callTool(ctx, "get_menu", map[string]interface{}{"restaurant_id": c.cfg.RestaurantID})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Content) == 0 {
+		return nil, errors.New("get_menu returned no content")
+	}
+	var items []struct {
+		ID        int  `json:"id"`
+		Available bool `json:"available"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &items); err != nil {
+		return nil, fmt.Errorf("get_menu result is not a list of menu items: %w", err)
+	}
+	var ids []int
+	for _, item := range items {
+		if item.Available {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids, nil
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+func (c *client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	c.nextID++
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.protocolVersion != "" {
+		req.Header.Set(mcp.ProtocolVersionHeader, c.protocolVersion)
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		if data, err = eventData(data); err != nil {
+			return err
+		}
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// eventData is the data of the first server-sent event in body
+func eventData(body []byte) ([]byte, error) {
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			return bytes.TrimSpace(data), nil
+		}
+	}
+	return nil, errors.New("event stream response has no data")
+}
@@ -0,0 +1,127 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("get_menu=3, create_order=1,list_orders=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	picked := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		picked[mix.pick(r)]++
+	}
+	if picked["list_orders"] != 0 || picked["get_menu"] < 2700 || picked["get_menu"] > 3300 {
+		t.Errorf("picked %v from 3:1:0", picked)
+	}
+
+	for _, bad := range []string{"", "get_menu", "get_menu=-1", "get_menu=0", "drop_tables=5", "get_menu=lots"} {
+		if _, err := ParseMix(bad); err == nil {
+			t.Errorf("ParseMix(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var s Stats
+	if s.Percentile(95) != 0 {
+		t.Error("percentile of no calls")
+	}
+	for i := 100; i >= 1; i-- {
+		s.add(time.Duration(i)*time.Millisecond, nil)
+	}
+	for p, want := range map[float64]time.Duration{50: 50, 95: 95, 99: 99, 100: 100, 0.1: 1} {
+		if got := s.Percentile(p); got != want*time.Millisecond {
+			t.Errorf("p%v = %s, want %dms", p, got, want)
+		}
+	}
+}
+
+// fakeServer answers like cmd/remote-mcp, with server-sent events, and
+// fails every create_order naming an item other than 7
+func fakeServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Name      string                 `json:"name"`
+				Arguments map[string]interface{} `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		var result interface{}
+		switch {
+		case req.Method == "initialize":
+			result = map[string]string{"protocolVersion": "2025-06-18"}
+		case req.Params.Name == "get_menu":
+			result = textResult(`[{"id":7,"available":true},{"id":8,"available":false}]`, false)
+		case req.Params.Name == "create_order":
+			items, _ := json.Marshal(req.Params.Arguments["items"])
+			result = textResult("Order created", strings.Contains(string(items), `"menu_item_id":8`))
+		default:
+			result = textResult("[]", false)
+		}
+		data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}))
+}
+
+func textResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{"content": []interface{}{map[string]string{"type": "text", "text": text}}, "isError": isError}
+}
+
+func TestRunReportsEveryCall(t *testing.T) {
+	server := fakeServer(t)
+	defer server.Close()
+
+	report, err := Run(context.Background(), Config{URL: server.URL, Sessions: 4, Calls: 200, RestaurantID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.Calls != 200 || report.Total.Errors != 0 {
+		t.Errorf("%d calls, %d errors (%s); want 200 without errors, ordering only the available item", report.Total.Calls, report.Total.Errors, report.Total.FirstError)
+	}
+	for _, tool := range Tools() {
+		if report.Tools[tool] == nil || report.Tools[tool].Calls == 0 {
+			t.Errorf("no %s calls in the default mix", tool)
+		}
+	}
+	var out strings.Builder
+	if err := report.Write(&out); err != nil || !strings.Contains(out.String(), "create_order") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestRunNeedsSomethingToOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID int `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": textResult("[]", false)})
+	}))
+	defer server.Close()
+
+	_, err := Run(context.Background(), Config{URL: server.URL, Sessions: 1, Calls: 1, RestaurantID: 3})
+	if err == nil || !strings.Contains(err.Error(), "no available menu items") {
+		t.Errorf("Run against an empty menu: %v", err)
+	}
+	if _, err := Run(context.Background(), Config{URL: server.URL, Sessions: 1, Calls: 1, Mix: Mix{{"list_orders", 1}}}); err != nil {
+		t.Errorf("Run without create_order: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is what a run measured, overall and per tool
+type Report struct {
+	Elapsed time.Duration
+	Total   Stats
+	Tools   map[string]*Stats
+
+	mu sync.Mutex
+}
+
+// Stats are the calls of one tool, or of all of them. Latencies are of
+// every call, failed or not.
+type Stats struct {
+	Calls      int
+	Errors     int             // calls that failed in any way
+	ToolErrors int             // of Errors, the JSON-RPC errors and isError results
+	FirstError string          // for a hint at what went wrong
+	latencies  []time.Duration // sorted by Percentile
+	sorted     bool
+}
+
+func newReport() *Report {
+	return &Report{Tools: map[string]*Stats{}}
+}
+
+func (r *Report) record(tool string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.Tools[tool]
+	if s == nil {
+		s = &Stats{}
+		r.Tools[tool] = s
+	}
+	s.add(latency, err)
+	r.Total.add(latency, err)
+}
+
+func (s *Stats) add(latency time.Duration, err error) {
+	s.Calls++
+	s.latencies = append(s.latencies, latency)
+	s.sorted = false
+	if err == nil {
+		return
+	}
+	s.Errors++
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		s.ToolErrors++
+	}
+	if s.FirstError == "" {
+		s.FirstError = err.Error()
+	}
+}
+
+// Percentile is the latency that p percent of calls finished within, by
+// the nearest-rank method; zero when there were no calls
+func (s *Stats) Percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	if !s.sorted {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		s.sorted = true
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(s.latencies))))
+	rank = max(1, min(rank, len(s.latencies)))
+	return s.latencies[rank-1]
+}
+
+// ErrorRate is the fraction of calls that failed
+func (s *Stats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Calls)
+}
+
+// Throughput is calls per second over the run
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Total.Calls) / r.Elapsed.Seconds()
+}
+
+// Write prints the report as a table, one row per tool and a total
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "%d calls in %s, %.1f calls/s, %.2f%% errors\n\n", r.Total.Calls, r.Elapsed.Round(time.Millisecond), r.Throughput(), 100*r.Total.ErrorRate())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "tool\tcalls\terrors\tp50\tp90\tp95\tp99\tmax\t")
+	tools := make([]string, 0, len(r.Tools))
+	for tool := range r.Tools {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	row := func(name string, s *Stats) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t\n", name, s.Calls, s.Errors,
+			ms(s.Percentile(50)), ms(s.Percentile(90)), ms(s.Percentile(95)), ms(s.Percentile(99)), ms(s.Percentile(100)))
+	}
+	for _, tool := range tools {
+		row(tool, r.Tools[tool])
+	}
+	row("all", &r.Total)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.Total.Errors > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, tool := range tools {
+		if s := r.Tools[tool]; s.FirstError != "" {
+			if _, err := fmt.Fprintf(w, "%s: first error: %s\n", tool, s.FirstError); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
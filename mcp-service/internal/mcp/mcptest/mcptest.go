@@ -0,0 +1,414 @@
+// Package mcptest checks that an MCP transport speaks the protocol the same
+// way as the others, whatever carries its messages.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/compat"
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/memory"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// Conversation sends messages, in order, to a server over one connection
+// of its transport and returns the responses it wrote, in order. For a
+// transport without connections, such as HTTP POST, each message is a
+// request of its own.
+type Conversation func(t *testing.T, messages ...string) []string
+
+// Store returns a memory store holding restaurant 1, "Test Kitchen", with
+// five menu items and one order, the IDs the tool examples refer to
+func Store(t testing.TB) *memory.Store {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	r := models.Restaurant{Name: "Test Kitchen", Address: "FC Road, Pune", CuisineType: "Indian", NotificationPreferences: models.DefaultNotificationPreferences()}
+	if err := store.CreateRestaurant(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []models.MenuItem{
+		{Name: "Masala Dosa", Price: models.Rupees(90), Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		{Name: "Vegan Thali", Price: models.Rupees(180), Category: "Main Course", DietaryType: "vegan", SpiceLevel: "medium"},
+		{Name: "Paneer Tikka", Price: models.Rupees(250), Category: "Starter", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		{Name: "Filter Coffee", Price: models.Rupees(40), Category: "Beverage", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		{Name: "Chicken Chettinad", Price: models.Rupees(320), Category: "Main Course", DietaryType: "non-vegetarian", SpiceLevel: "hot"},
+	} {
+		m.RestaurantID, m.Available = r.ID, true
+		if err := store.CreateMenuItem(ctx, &m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", CustomerPhone: "+91-9820012345", Status: models.OrderStatusPending, OrderItems: []models.OrderItem{{MenuItemID: 1, Quantity: 2}}}
+	if err := store.CreateOrder(ctx, &o); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// Initialize is the initialize request, with id 0, that starts every
+// conversation of the suite
+const Initialize = `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"mcptest","version":"1.0"}}}`
+
+// Initialized is the notification a client sends once initialize has been
+// answered, after which the server may send it notifications
+const Initialized = `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+
+// Response is a decoded JSON-RPC response
+type Response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *mcp.Error      `json:"error"`
+}
+
+// ToolResult decodes the result of a tools/call
+func (r Response) ToolResult(t testing.TB) mcp.CallToolResult {
+	t.Helper()
+	var result mcp.CallToolResult
+	if r.Error != nil || json.Unmarshal(r.Result, &result) != nil {
+		t.Fatalf("response %s %+v is not a tool result", r.Result, r.Error)
+	}
+	return result
+}
+
+// Text joins the text of the content blocks of a tool result
+func Text(result mcp.CallToolResult) string {
+	texts := make([]string, len(result.Content))
+	for i, c := range result.Content {
+		texts[i] = c.Text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Converse initializes the server and sends messages after that, returning
+// the decoded responses to messages
+func Converse(t *testing.T, converse Conversation, messages ...string) []Response {
+	t.Helper()
+	lines := converse(t, append([]string{Initialize, Initialized}, messages...)...)
+	if len(lines) == 0 {
+		t.Fatal("no response to initialize")
+	}
+	responses := make([]Response, len(lines)-1)
+	for i, line := range lines[1:] {
+		if err := json.Unmarshal([]byte(line), &responses[i]); err != nil {
+			t.Fatalf("response %q: %v", line, err)
+		}
+	}
+	return responses
+}
+
+// Call is a tools/call request with id 1
+func Call(tool, args string) string {
+	return `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `","arguments":` + args + `}}`
+}
+
+// one returns the single response of a conversation
+func one(t *testing.T, responses []Response) Response {
+	t.Helper()
+	if len(responses) != 1 {
+		t.Fatalf("%d responses, want 1: %+v", len(responses), responses)
+	}
+	return responses[0]
+}
+
+// wantError fails unless resp is the JSON-RPC error code
+func wantError(t *testing.T, resp Response, code int) {
+	t.Helper()
+	if resp.Error == nil || resp.Error.Code != code {
+		t.Errorf("response %s %+v, want error %d", resp.Result, resp.Error, code)
+	}
+}
+
+// Conformance runs the protocol checks every transport must pass against
+// a server whose store is Store
+func Conformance(t *testing.T, converse Conversation) {
+	t.Run("initialize", func(t *testing.T) {
+		for requested, want := range map[string]string{
+			mcp.ProtocolVersion20241105: mcp.ProtocolVersion20241105,
+			"1999-01-01":                mcp.LatestProtocolVersion(),
+		} {
+			lines := converse(t, `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"`+requested+`"}}`)
+			var resp struct {
+				Result struct {
+					ProtocolVersion string                 `json:"protocolVersion"`
+					Capabilities    map[string]interface{} `json:"capabilities"`
+					ServerInfo      struct{ Name string }  `json:"serverInfo"`
+				} `json:"result"`
+			}
+			if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &resp) != nil {
+				t.Fatalf("initialize with %s answered %q", requested, lines)
+			}
+			if resp.Result.ProtocolVersion != want || resp.Result.Capabilities["tools"] == nil || resp.Result.ServerInfo.Name == "" {
+				t.Errorf("initialize with %s = %+v, want version %s with tools", requested, resp.Result, want)
+			}
+			if resources, _ := resp.Result.Capabilities["resources"].(map[string]interface{}); resources == nil || resources["subscribe"] != false {
+				t.Errorf("initialize with %s advertised resources %v, want them without subscribe", requested, resp.Result.Capabilities["resources"])
+			}
+			if resp.Result.Capabilities["prompts"] == nil {
+				t.Errorf("initialize with %s didn't advertise prompts", requested)
+			}
+		}
+	})
+
+	t.Run("echoes request ids", func(t *testing.T) {
+		ids := []string{`"7"`, `7`, `9007199254740993`, `"req-abc"`}
+		messages := make([]string, len(ids))
+		for i, id := range ids {
+			messages[i] = `{"jsonrpc":"2.0","id":` + id + `,"method":"ping"}`
+		}
+		responses := Converse(t, converse, messages...)
+		if len(responses) != len(ids) {
+			t.Fatalf("%d responses to %d pings", len(responses), len(ids))
+		}
+		for i, resp := range responses {
+			if string(resp.ID) != ids[i] || string(resp.Result) != "{}" {
+				t.Errorf("ping with id %s answered %s with id %s", ids[i], resp.Result, resp.ID)
+			}
+		}
+	})
+
+	t.Run("notifications get no response", func(t *testing.T) {
+		responses := Converse(t, converse, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+		if resp := one(t, responses); string(resp.ID) != "2" {
+			t.Errorf("answered %s, want only the ping", resp.ID)
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		wantError(t, one(t, Converse(t, converse, `{"jsonrpc":"2.0","id":1,"method":"completion/complete"}`)), mcp.CodeMethodNotFound)
+	})
+
+	t.Run("resources", func(t *testing.T) {
+		resp := one(t, Converse(t, converse, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`))
+		var list struct {
+			Resources []mcp.Resource `json:"resources"`
+		}
+		if err := json.Unmarshal(resp.Result, &list); err != nil {
+			t.Fatalf("resources/list = %s %+v", resp.Result, resp.Error)
+		}
+		var uris []string
+		for _, r := range list.Resources {
+			uris = append(uris, r.URI)
+		}
+		if strings.Join(uris, " ") != "restaurant://1 restaurant://1/menu" {
+			t.Errorf("resources/list = %v, want the restaurant and its menu", uris)
+		}
+
+		responses := Converse(t, converse,
+			`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"restaurant://1"}}`,
+			`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"restaurant://1/menu"}}`,
+			`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"restaurant://99"}}`,
+			`{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"file:///etc/passwd"}}`)
+		if len(responses) != 4 {
+			t.Fatalf("%d responses to 4 reads", len(responses))
+		}
+		for i, want := range []string{"Test Kitchen", "Masala Dosa"} {
+			var read struct {
+				Contents []mcp.ResourceContents `json:"contents"`
+			}
+			if err := json.Unmarshal(responses[i].Result, &read); err != nil || len(read.Contents) != 1 {
+				t.Fatalf("resources/read = %s %+v", responses[i].Result, responses[i].Error)
+			}
+			if c := read.Contents[0]; c.URI != uris[i] || c.MimeType != "application/json" || !strings.Contains(c.Text, want) {
+				t.Errorf("resources/read %s = %+v, want its JSON", uris[i], c)
+			}
+		}
+		for _, resp := range responses[2:] {
+			wantError(t, resp, mcp.CodeResourceNotFound)
+		}
+	})
+
+	t.Run("prompts", func(t *testing.T) {
+		resp := one(t, Converse(t, converse, `{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`))
+		var list struct {
+			Prompts []mcp.Prompt `json:"prompts"`
+		}
+		if err := json.Unmarshal(resp.Result, &list); err != nil || len(list.Prompts) < 2 {
+			t.Fatalf("prompts/list = %s %+v", resp.Result, resp.Error)
+		}
+
+		get := func(id int, name, args string) string {
+			return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"prompts/get","params":{"name":%q,"arguments":%s}}`, id, name, args)
+		}
+		responses := Converse(t, converse,
+			get(1, "take_order", `{"restaurant_id":"1"}`),
+			get(2, "menu_review", `{"restaurant_id":"1"}`),
+			get(3, "take_order", `{}`),
+			get(4, "take_order", `{"restaurant_id":"one"}`),
+			get(5, "take_order", `{"restaurant_id":"99"}`),
+			get(6, "take_order", `{"restaurant_id":1}`),
+			get(7, "no_such_prompt", `{}`))
+		if len(responses) != 7 {
+			t.Fatalf("%d responses to 7 prompts/get", len(responses))
+		}
+		for _, resp := range responses[:2] {
+			var result mcp.PromptResult
+			if err := json.Unmarshal(resp.Result, &result); err != nil || len(result.Messages) == 0 {
+				t.Fatalf("prompts/get = %s %+v", resp.Result, resp.Error)
+			}
+			if text := result.Messages[0].Content.Text; !strings.Contains(text, "Test Kitchen") || !strings.Contains(text, "Masala Dosa") {
+				t.Errorf("prompt %q doesn't embed the menu", text)
+			}
+		}
+		for _, resp := range responses[2:] {
+			wantError(t, resp, mcp.CodeInvalidParams)
+		}
+	})
+
+	t.Run("tools/list", func(t *testing.T) {
+		resp := one(t, Converse(t, converse, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+		var list struct {
+			Tools []struct {
+				Name        string                 `json:"name"`
+				Description string                 `json:"description"`
+				InputSchema map[string]interface{} `json:"inputSchema"`
+			} `json:"tools"`
+		}
+		if err := json.Unmarshal(resp.Result, &list); err != nil || len(list.Tools) == 0 {
+			t.Fatalf("tools/list = %s %+v", resp.Result, resp.Error)
+		}
+		found := false
+		for _, tool := range list.Tools {
+			if tool.Name == "" || tool.Description == "" || tool.InputSchema["type"] != "object" {
+				t.Errorf("tool %+v needs a name, description and object input schema", tool)
+			}
+			found = found || tool.Name == "get_restaurant"
+		}
+		if !found {
+			t.Error("get_restaurant isn't listed")
+		}
+	})
+
+	t.Run("tools/call", func(t *testing.T) {
+		result := one(t, Converse(t, converse, Call("get_restaurant", `{"id":1}`))).ToolResult(t)
+		if result.IsError || !strings.Contains(Text(result), "Test Kitchen") {
+			t.Errorf("get_restaurant = %+v", result)
+		}
+		if id, _ := result.Meta[mcp.MetaServerRequestID].(string); id == "" {
+			t.Errorf("result _meta %v has no %s", result.Meta, mcp.MetaServerRequestID)
+		}
+	})
+
+	t.Run("tool failure is an isError result", func(t *testing.T) {
+		result := one(t, Converse(t, converse, Call("get_restaurant", `{"id":999}`))).ToolResult(t)
+		if !result.IsError {
+			t.Errorf("get_restaurant of a missing restaurant = %+v, want isError", result)
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		wantError(t, one(t, Converse(t, converse, Call("no_such_tool", `{}`))), mcp.CodeMethodNotFound)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		wantError(t, one(t, Converse(t, converse, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":"get_restaurant"}`)), mcp.CodeInvalidParams)
+	})
+
+	t.Run("arguments against the schema", func(t *testing.T) {
+		resp := one(t, Converse(t, converse, Call("get_restaurant", `{"id":"one","color":"red"}`)))
+		wantError(t, resp, mcp.CodeInvalidParams)
+		data, _ := resp.Error.Data.(map[string]interface{})
+		if violations, _ := data["violations"].([]interface{}); len(violations) != 2 {
+			t.Errorf("error data = %v, want both violations listed", resp.Error.Data)
+		}
+	})
+
+	t.Run("deprecated tool name", func(t *testing.T) {
+		result := one(t, Converse(t, converse, Call("get_restaurants", `{}`))).ToolResult(t)
+		if result.IsError || !strings.Contains(Text(result), compat.WarningPrefix) || len(result.Content) < 2 {
+			t.Errorf("get_restaurants = %+v, want the listing and a deprecation warning", result)
+		}
+	})
+
+	t.Run("singleBlock", func(t *testing.T) {
+		call := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_restaurants","arguments":{},"_meta":{"singleBlock":true}}}`
+		result := one(t, Converse(t, converse, call)).ToolResult(t)
+		if len(result.Content) != 1 || !mcp.SingleBlock(result.Meta) {
+			t.Errorf("result = %+v, want one block and singleBlock echoed", result)
+		}
+	})
+}
+
+// Stateful checks a transport that keeps a session across messages, such
+// as stdio: tools are only available once the session is initialized
+func Stateful(t *testing.T, converse Conversation) {
+	lines := converse(t, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, Initialize, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if len(lines) != 3 {
+		t.Fatalf("%d responses, want 3: %q", len(lines), lines)
+	}
+	var before, after Response
+	json.Unmarshal([]byte(lines[0]), &before)
+	json.Unmarshal([]byte(lines[2]), &after)
+	wantError(t, before, mcp.CodeNotInitialized)
+	if after.Error != nil {
+		t.Errorf("tools/list after initialize: %+v", after.Error)
+	}
+}
+
+// ToolsList returns the tools/list result of a transport, after an
+// initialize so stateful transports answer it too
+func ToolsList(t *testing.T, converse Conversation) json.RawMessage {
+	t.Helper()
+	responses := Converse(t, converse, Initialize, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses to initialize and tools/list, want 2", len(responses))
+	}
+	resp := responses[1]
+	if resp.Error != nil {
+		t.Fatalf("tools/list: %+v", resp.Error)
+	}
+	return resp.Result
+}
+
+// CatalogGolden is the tools/list result of POST /mcp, relative to the
+// module root. The handlers tests keep it up to date (-update-golden).
+const CatalogGolden = "internal/handlers/testdata/tools_list.golden"
+
+// SameCatalog fails unless a transport lists exactly the tools, schemas
+// and examples of CatalogGolden, so no entry point can drift from the
+// others
+func SameCatalog(t *testing.T, converse Conversation) {
+	t.Helper()
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			t.Fatal("no go.mod above the test's directory")
+		}
+		root = parent
+	}
+	want, err := os.ReadFile(filepath.Join(root, CatalogGolden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := storagetest.NormalizeJSON(t, ToolsList(t, converse)); string(got) != string(want) {
+		t.Errorf("tools/list differs from %s; run the handlers tests with -update-golden if the catalog changed on purpose\n--- got ---\n%s", CatalogGolden, got)
+	}
+}
+
+// AddTool registers a tool named name on server, which echoes its text
+// argument, as a server adding a tool while serving would
+func AddTool(server *mcp.Server, name string) {
+	server.RegisterTool(map[string]interface{}{
+		"name":        name,
+		"description": "Echo the text",
+		"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}},
+	}, nil, func(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) mcp.Response {
+		text, _ := args["text"].(string)
+		return mcp.ResultResponse(id, mcp.TextResult(text))
+	})
+}
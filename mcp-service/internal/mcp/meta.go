@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+)
+
+// CallToolParams are the params of a tools/call request
+type CallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      map[string]interface{} `json:"_meta,omitempty"` // e.g. progressToken, tracing or attribution fields
+}
+
+// _meta keys read or written by this server
+const (
+	MetaProgressToken   = "progressToken"
+	MetaServerRequestID = "serverRequestId" // the ID the server logged the call under
+	MetaSingleBlock     = "singleBlock"     // true asks for one text block; see SingleBlock
+)
+
+// NewRequestID returns a random ID for the server to log a tool call under
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type callKey struct{}
+
+// call is the request metadata of the tool call a context belongs to
+type call struct {
+	id   string
+	meta map[string]interface{}
+}
+
+// WithCall returns ctx carrying the server's ID for a tool call and the
+// _meta its client sent, for the handlers it is dispatched to
+func WithCall(ctx context.Context, requestID string, meta map[string]interface{}) context.Context {
+	return context.WithValue(ctx, callKey{}, call{id: requestID, meta: meta})
+}
+
+// RequestID returns the server's ID for the tool call of ctx, or ""
+func RequestID(ctx context.Context) string {
+	c, _ := ctx.Value(callKey{}).(call)
+	return c.id
+}
+
+// RequestMeta returns the _meta the client sent with the tool call of ctx,
+// nil if there was none
+func RequestMeta(ctx context.Context) map[string]interface{} {
+	c, _ := ctx.Value(callKey{}).(call)
+	return c.meta
+}
+
+// ProgressToken returns the token the client asked progress notifications
+// for the tool call of ctx to carry, if any
+func ProgressToken(ctx context.Context) (interface{}, bool) {
+	token, ok := RequestMeta(ctx)[MetaProgressToken]
+	return token, ok && token != nil
+}
+
+// SingleBlock reports whether the client asked, with "singleBlock": true in
+// its _meta, for a result's content blocks joined into one
+func SingleBlock(meta map[string]interface{}) bool {
+	single, _ := meta[MetaSingleBlock].(bool)
+	return single
+}
+
+// ResponseMeta is the _meta of the result of the tool call of ctx: the
+// client's _meta echoed back, plus the server's request ID
+func ResponseMeta(ctx context.Context) map[string]interface{} {
+	meta := maps.Clone(RequestMeta(ctx))
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	if id := RequestID(ctx); id != "" {
+		meta[MetaServerRequestID] = id
+	}
+	return meta
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCallToolParamsKeepMeta(t *testing.T) {
+	var params CallToolParams
+	err := json.Unmarshal([]byte(`{"name":"get_menu","arguments":{"restaurant_id":1},"_meta":{"progressToken":"p-1","traceparent":"00-abc-def-01"}}`), &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"progressToken": "p-1", "traceparent": "00-abc-def-01"}
+	if !reflect.DeepEqual(params.Meta, want) {
+		t.Errorf("Meta = %v, want %v", params.Meta, want)
+	}
+
+	data, err := json.Marshal(CallToolParams{Name: "get_menu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"name":"get_menu"}` {
+		t.Errorf("params without _meta = %s", data)
+	}
+}
+
+func TestResponseMetaEchoesTheRequest(t *testing.T) {
+	ctx := WithCall(context.Background(), "abc123", map[string]interface{}{MetaProgressToken: 7.0, "traceparent": "00-abc-def-01"})
+	if token, ok := ProgressToken(ctx); !ok || token != 7.0 {
+		t.Errorf("ProgressToken = %v, %v; want 7", token, ok)
+	}
+
+	result := TextResult("ok")
+	result.Meta = ResponseMeta(ctx)
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"content":[{"type":"text","text":"ok"}],"_meta":{"progressToken":7,"serverRequestId":"abc123","traceparent":"00-abc-def-01"}}`; string(data) != want {
+		t.Errorf("result = %s, want %s", data, want)
+	}
+	if _, ok := RequestMeta(ctx)[MetaServerRequestID]; ok {
+		t.Error("ResponseMeta modified the request _meta")
+	}
+
+	data, err = json.Marshal(TextResult("ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"content":[{"type":"text","text":"ok"}]}` {
+		t.Errorf("result without _meta = %s", data)
+	}
+	if _, ok := ProgressToken(context.Background()); ok {
+		t.Error("ProgressToken outside a tool call")
+	}
+}
@@ -0,0 +1,11 @@
+package mcp
+
+// Notification is a JSON-RPC notification the server sends a client
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// MethodToolsListChanged tells a client to fetch tools/list again
+const MethodToolsListChanged = "notifications/tools/list_changed"
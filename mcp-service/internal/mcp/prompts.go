@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+// Prompt is a prompts/list entry: a message template a client offers its
+// user, e.g. as a slash command
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument is an argument a prompt is filled in with. Values arrive
+// as strings.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a filled-in prompt
+type PromptMessage struct {
+	Role    string  `json:"role"` // "user" or "assistant"
+	Content Content `json:"content"`
+}
+
+// PromptResult is the result of a prompts/get request
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// UserPrompt is a prompt result of a single user message holding text
+func UserPrompt(description, text string) *PromptResult {
+	return &PromptResult{Description: description, Messages: []PromptMessage{{Role: "user", Content: TextContent(text)}}}
+}
+
+// ErrInvalidPromptArgument is wrapped by a PromptHandler's error for an
+// argument the client got wrong, which becomes an invalid params error
+// carrying the error's text. Any other error is an internal error, which
+// the handler should log itself.
+var ErrInvalidPromptArgument = errors.New("invalid prompt argument")
+
+// PromptHandler fills in a prompt. args holds every required argument and
+// no undeclared one.
+type PromptHandler func(ctx context.Context, args map[string]string) (*PromptResult, error)
+
+type prompt struct {
+	definition Prompt
+	handler    PromptHandler
+}
+
+// RegisterPrompt adds a prompt, served by prompts/list and prompts/get and
+// advertised at initialize from the first one. Registering a name twice
+// panics.
+func (s *Server) RegisterPrompt(definition Prompt, handler PromptHandler) {
+	if definition.Name == "" || handler == nil {
+		panic(fmt.Sprintf("mcp: prompt %q registered without a name or handler", definition.Name))
+	}
+	if s.prompts == nil {
+		s.prompts = map[string]*prompt{}
+	}
+	if _, ok := s.prompts[definition.Name]; ok {
+		panic("mcp: prompt " + definition.Name + " registered twice")
+	}
+	s.prompts[definition.Name] = &prompt{definition: definition, handler: handler}
+	s.promptOrder = append(s.promptOrder, definition.Name)
+}
+
+// Prompts returns the prompts/list entries in registration order
+func (s *Server) Prompts() []Prompt {
+	prompts := make([]Prompt, len(s.promptOrder))
+	for i, name := range s.promptOrder {
+		prompts[i] = s.prompts[name].definition
+	}
+	return prompts
+}
+
+// getPrompt answers prompts/get, checking the arguments against the
+// prompt's before its handler runs
+func (s *Server) getPrompt(ctx context.Context, req Request) Response {
+	var params struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return ErrorResponse(req.ID, CodeInvalidParams, "Invalid params: arguments must be strings")
+	}
+	p, ok := s.prompts[params.Name]
+	if !ok {
+		return ErrorResponse(req.ID, CodeInvalidParams, "Unknown prompt: "+params.Name)
+	}
+	if err := checkPromptArgs(p.definition, params.Arguments); err != nil {
+		return InvalidArgs(req.ID, err)
+	}
+	if params.Arguments == nil {
+		params.Arguments = map[string]string{}
+	}
+	result, err := p.handler(ctx, params.Arguments)
+	if errors.Is(err, ErrInvalidPromptArgument) {
+		return ErrorResponse(req.ID, CodeInvalidParams, err.Error())
+	} else if err != nil {
+		return ErrorResponse(req.ID, CodeInternalError, "Internal error")
+	}
+	return ResultResponse(req.ID, result)
+}
+
+// checkPromptArgs reports every missing required argument and every one
+// the prompt doesn't declare, as validation does for tool arguments
+func checkPromptArgs(definition Prompt, args map[string]string) error {
+	var violations []string
+	declared := map[string]bool{}
+	for _, arg := range definition.Arguments {
+		declared[arg.Name] = true
+		if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+			violations = append(violations, arg.Name+" is required")
+		}
+	}
+	var unknown []string
+	for name := range args {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		violations = append(violations, name+" is not an argument of "+definition.Name)
+	}
+	if len(violations) > 0 {
+		return &toolschema.ValidationError{Violations: violations}
+	}
+	return nil
+}
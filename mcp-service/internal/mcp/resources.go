@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+)
+
+// CodeResourceNotFound is the error for resources/read of a URI the server
+// doesn't have. The protocol reuses the code of CodeNotInitialized.
+const CodeResourceNotFound = -32002
+
+// Resource is a resources/list entry
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the text of a resource in a resources/read result
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Errors a Resources implementation returns for the client's mistakes;
+// any other error is an internal error, which it should log itself
+var (
+	ErrResourceNotFound = errors.New("resource not found")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+// Resources serves the resources capability: data a client attaches as
+// context without a tool call
+type Resources interface {
+	// List returns a page of resources from cursor, "" for the first, and
+	// the cursor of the next page or "" after the last
+	List(ctx context.Context, cursor string) ([]Resource, string, error)
+	// Read returns the contents of uri, or ErrResourceNotFound
+	Read(ctx context.Context, uri string) ([]ResourceContents, error)
+}
+
+// SetResources enables resources/list and resources/read, which are unknown
+// methods until then
+func (s *Server) SetResources(r Resources) {
+	s.resources = r
+}
+
+// listResources answers resources/list
+func (s *Server) listResources(ctx context.Context, req Request) Response {
+	var params struct {
+		Cursor string `json:"cursor"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return ErrorResponse(req.ID, CodeInvalidParams, "Invalid params")
+		}
+	}
+	resources, next, err := s.resources.List(ctx, params.Cursor)
+	if err != nil {
+		return resourceError(req.ID, err, params.Cursor)
+	}
+	result := map[string]interface{}{"resources": resources}
+	if next != "" {
+		result["nextCursor"] = next
+	}
+	return ResultResponse(req.ID, result)
+}
+
+// readResource answers resources/read
+func (s *Server) readResource(ctx context.Context, req Request) Response {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return ErrorResponse(req.ID, CodeInvalidParams, "Invalid params: uri is required")
+	}
+	contents, err := s.resources.Read(ctx, params.URI)
+	if err != nil {
+		return resourceError(req.ID, err, params.URI)
+	}
+	return ResultResponse(req.ID, map[string]interface{}{"contents": contents})
+}
+
+// resourceError is the JSON-RPC error for a failed List or Read of what,
+// a cursor or URI
+func resourceError(id jsonrpc.RequestID, err error, what string) Response {
+	switch {
+	case errors.Is(err, ErrResourceNotFound):
+		resp := ErrorResponse(id, CodeResourceNotFound, "Resource not found: "+what)
+		resp.Error.Data = map[string]interface{}{"uri": what}
+		return resp
+	case errors.Is(err, ErrInvalidCursor):
+		return ErrorResponse(id, CodeInvalidParams, "Invalid cursor: "+what)
+	default:
+		return ErrorResponse(id, CodeInternalError, "Internal error")
+	}
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import "strings"
+
+// CallToolResult is the result of a tools/call request
+type CallToolResult struct {
+	Content           []Content              `json:"content"`
+	StructuredContent interface{}            `json:"structuredContent,omitempty"` // e.g. *bulk.Result for bulk tools
+	IsError           bool                   `json:"isError,omitempty"`
+	Meta              map[string]interface{} `json:"_meta,omitempty"` // see ResponseMeta
+}
+
+// Content is one block of a tool result. Only text blocks are produced;
+// long reports come as several, e.g. a summary, a table and caveats.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// TextContent is a text content block
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// TextResult is a successful tool result holding text
+func TextResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{TextContent(text)}}
+}
+
+// TextBlocks returns a text content block for each non-empty text
+func TextBlocks(texts ...string) []Content {
+	blocks := make([]Content, 0, len(texts))
+	for _, text := range texts {
+		if text != "" {
+			blocks = append(blocks, TextContent(text))
+		}
+	}
+	return blocks
+}
+
+// BlocksResult is a successful tool result of several text blocks, for
+// clients that render them one at a time
+func BlocksResult(texts ...string) *CallToolResult {
+	return &CallToolResult{Content: TextBlocks(texts...)}
+}
+
+// JoinContent collapses the text blocks of r into one, separated by blank
+// lines, for clients that only show the first block
+func (r *CallToolResult) JoinContent() {
+	if len(r.Content) < 2 {
+		return
+	}
+	texts := make([]string, len(r.Content))
+	for i, c := range r.Content {
+		texts[i] = strings.TrimRight(c.Text, "\n")
+	}
+	r.Content = []Content{TextContent(strings.Join(texts, "\n\n"))}
+}
+
+// ErrorResult reports a tool that ran but failed, as an isError result
+// whose text is msg prefixed with "Error: "
+func ErrorResult(msg string) *CallToolResult {
+	return &CallToolResult{Content: []Content{TextContent("Error: " + msg)}, IsError: true}
+}
+
+// ErrorText returns the text of an isError result, or "" for a result that
+// isn't one
+func (r *CallToolResult) ErrorText() string {
+	if r == nil || !r.IsError || len(r.Content) == 0 {
+		return ""
+	}
+	return r.Content[0].Text
+}
@@ -0,0 +1,18 @@
+package mcp
+
+import "testing"
+
+func TestJoinContentForSingleBlockClients(t *testing.T) {
+	r := BlocksResult("Report: 2 rows.\n", "", "| a |\n| 1 |\n")
+	if len(r.Content) != 2 {
+		t.Fatalf("blocks %+v, want the empty one dropped", r.Content)
+	}
+	r.JoinContent()
+	if len(r.Content) != 1 || r.Content[0].Text != "Report: 2 rows.\n\n| a |\n| 1 |" {
+		t.Errorf("joined %+v", r.Content)
+	}
+
+	if !SingleBlock(map[string]interface{}{MetaSingleBlock: true}) || SingleBlock(map[string]interface{}{MetaSingleBlock: "yes"}) || SingleBlock(nil) {
+		t.Error("SingleBlock only honours a true singleBlock")
+	}
+}
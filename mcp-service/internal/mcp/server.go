@@ -0,0 +1,378 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+// Request is a JSON-RPC request, or a notification when it has no id
+type Request struct {
+	JSONRPC string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  json.RawMessage   `json:"params,omitempty"`
+	ID      jsonrpc.RequestID `json:"id"`
+}
+
+// Response is a JSON-RPC response holding either a result or an error
+type Response struct {
+	JSONRPC string            `json:"jsonrpc"`
+	Result  interface{}       `json:"result,omitempty"`
+	Error   *Error            `json:"error,omitempty"`
+	ID      jsonrpc.RequestID `json:"id"`
+}
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// JSON-RPC error codes used by the server
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeNotInitialized = -32002
+)
+
+// ErrorResponse is the response to request id failing with a JSON-RPC error
+func ErrorResponse(id jsonrpc.RequestID, code int, message string) Response {
+	return Response{JSONRPC: "2.0", Error: &Error{Code: code, Message: message}, ID: id}
+}
+
+// ResultResponse is the successful response to request id
+func ResultResponse(id jsonrpc.RequestID, result interface{}) Response {
+	return Response{JSONRPC: "2.0", Result: result, ID: id}
+}
+
+// ToolHandler runs one call of a tool with its arguments. Failures the
+// model can act on are isError results; JSON-RPC errors are for malformed
+// calls.
+type ToolHandler func(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) Response
+
+// CallFunc dispatches a tool call by name
+type CallFunc func(ctx context.Context, id jsonrpc.RequestID, name string, args map[string]interface{}) Response
+
+// Middleware wraps the dispatch of every tools/call, e.g. to authorize it or
+// record its failures
+type Middleware func(next CallFunc) CallFunc
+
+type tool struct {
+	definition map[string]interface{}
+	schema     toolschema.Schema // inputSchema, parsed once
+	examples   []toolschema.Example
+	handler    ToolHandler
+}
+
+// Server answers MCP requests for a catalog of tools. It knows nothing of
+// how messages arrive: the stdio, SSE and HTTP transports decode requests,
+// pass them to HandleRequest and write back the response.
+type Server struct {
+	name, version string
+
+	mu         sync.RWMutex // guards tools, order and listeners, as tools may be added while serving
+	tools      map[string]*tool
+	order      []string          // tool names in registration order, for tools/list
+	listeners  map[*Session]bool // sessions told when the tool list changes
+	middleware []Middleware
+	resources  Resources // nil without the resources capability
+
+	prompts     map[string]*prompt
+	promptOrder []string
+
+	// NewRequestID returns the ID each tool call is logged under, echoed in
+	// its result _meta
+	NewRequestID func() string
+
+	// Truncate, when set, limits the content of every tool result before it
+	// is returned, whichever tool or middleware produced it
+	Truncate func([]Content) []Content
+}
+
+// NewServer returns a server without tools, introducing itself at initialize
+// as name and version
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, tools: map[string]*tool{}, listeners: map[*Session]bool{}, NewRequestID: NewRequestID}
+}
+
+// RegisterTool adds a tool. definition is its tools/list entry, with name,
+// description and inputSchema; examples are sample arguments, rendered into
+// the listing. Calls are validated against inputSchema before handler runs,
+// so it can rely on the types and enums of its arguments. Registering a name
+// twice panics. A tool added while serving is announced to the sessions that
+// can be told, see Session.Notify.
+func (s *Server) RegisterTool(definition map[string]interface{}, examples []toolschema.Example, handler ToolHandler) {
+	name, _ := definition["name"].(string)
+	if name == "" || handler == nil {
+		panic(fmt.Sprintf("mcp: tool %q registered without a name or handler", name))
+	}
+	schema, err := toolschema.Parse(definition["inputSchema"])
+	if err != nil {
+		panic(fmt.Sprintf("mcp: tool %s: %v", name, err))
+	}
+	s.mu.Lock()
+	if _, ok := s.tools[name]; ok {
+		s.mu.Unlock()
+		panic("mcp: tool " + name + " registered twice")
+	}
+	s.tools[name] = &tool{definition: definition, schema: schema, examples: examples, handler: handler}
+	s.order = append(s.order, name)
+	listeners := slices.Collect(maps.Keys(s.listeners))
+	s.mu.Unlock()
+
+	for _, sess := range listeners {
+		sess.Notify(Notification{JSONRPC: "2.0", Method: MethodToolsListChanged})
+	}
+}
+
+// tool returns the named tool, or nil
+func (s *Server) tool(name string) *tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tools[name]
+}
+
+// Use adds middleware around tool calls. The first added runs outermost.
+func (s *Server) Use(m Middleware) {
+	s.middleware = append(s.middleware, m)
+}
+
+// Tools returns the tools/list entries, each with its first example
+// rendered into the description and all of them in _meta for clients that
+// read it
+func (s *Server) Tools() []map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tools := make([]map[string]interface{}, len(s.order))
+	for i, name := range s.order {
+		t := s.tools[name]
+		entry := maps.Clone(t.definition)
+		description, _ := entry["description"].(string)
+		entry["description"] = toolschema.RenderDescription(description, t.examples)
+		entry["_meta"] = map[string]interface{}{"examples": t.examples}
+		tools[i] = entry
+	}
+	return tools
+}
+
+// CheckExamples reports tools whose examples are missing or have drifted
+// from their input schema
+func (s *Server) CheckExamples() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range s.order {
+		t := s.tools[name]
+		if err := toolschema.CheckExamples(name, t.definition["inputSchema"], t.examples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dispatch validates the arguments of a call and runs the named tool,
+// without the middleware, as the innermost step of every tools/call and for
+// a tool that calls another on behalf of a call already through it
+func (s *Server) Dispatch(ctx context.Context, id jsonrpc.RequestID, name string, args map[string]interface{}) Response {
+	t := s.tool(name)
+	if t == nil {
+		return ErrorResponse(id, CodeMethodNotFound, "Unknown tool: "+name)
+	}
+	if err := t.schema.Validate(args); err != nil {
+		return InvalidArgs(id, err)
+	}
+	return t.handler(ctx, id, args)
+}
+
+// ValidateArgs checks a call's arguments as Dispatch does, for a caller
+// that records the call instead of running it. An unknown tool is an error.
+func (s *Server) ValidateArgs(name string, args map[string]interface{}) error {
+	t := s.tool(name)
+	if t == nil {
+		return fmt.Errorf("unknown tool %s", name)
+	}
+	return t.schema.Validate(args)
+}
+
+// InvalidArgs is the -32602 error for arguments that failed validation,
+// listing every violation in the message and as data.violations
+func InvalidArgs(id jsonrpc.RequestID, err error) Response {
+	resp := ErrorResponse(id, CodeInvalidParams, err.Error())
+	var invalid *toolschema.ValidationError
+	if errors.As(err, &invalid) {
+		resp.Error.Data = map[string]interface{}{"violations": invalid.Violations}
+	}
+	return resp
+}
+
+// Session is the state a transport keeps for one client connection
+type Session struct {
+	// ID names the session in logs, e.g. from the Mcp-Session-Id header
+	ID string
+	// ProtocolVersion is the version negotiated at initialize, or for a
+	// sessionless request the one it says it runs under
+	ProtocolVersion string
+	// Notify pushes a notification to the client, for a transport that can
+	// send one between responses; set it before initialize. Sessions without
+	// it aren't told when the tool list changes, and initialize says so.
+	Notify func(Notification)
+
+	stateful    bool
+	initialized bool
+}
+
+// NewSession starts a connection that carries many requests, such as stdio
+// or a streaming HTTP GET. Tools can't be listed or called on it before
+// initialize.
+func NewSession() *Session {
+	return &Session{stateful: true}
+}
+
+// RequestSession is the session of a single HTTP request. The protocol
+// version comes from its ProtocolVersionHeader, since initialize ran on
+// another request.
+func RequestSession(id, versionHeader string) *Session {
+	return &Session{ID: id, ProtocolVersion: RequestProtocolVersion(versionHeader)}
+}
+
+type sessionKey struct{}
+
+// SessionID returns the ID of the session a tool call of ctx arrived on
+func SessionID(ctx context.Context) string {
+	sess, _ := ctx.Value(sessionKey{}).(*Session)
+	if sess == nil {
+		return ""
+	}
+	return sess.ID
+}
+
+// HandleRequest answers one request arriving on sess. It returns false for
+// a notification, which gets no response.
+func (s *Server) HandleRequest(ctx context.Context, sess *Session, req Request) (Response, bool) {
+	if strings.HasPrefix(req.Method, "notifications/") {
+		if req.Method == "notifications/initialized" && sess.initialized && sess.Notify != nil {
+			s.mu.Lock()
+			s.listeners[sess] = true
+			s.mu.Unlock()
+		}
+		return Response{}, false
+	}
+	switch req.Method {
+	case "initialize":
+		return s.initialize(sess, req), true
+	case "ping":
+		return ResultResponse(req.ID, map[string]interface{}{}), true
+	case "tools/list", "tools/call", "resources/list", "resources/read", "prompts/list", "prompts/get":
+		if !s.offers(req.Method) {
+			break
+		}
+		if sess.stateful && !sess.initialized {
+			return ErrorResponse(req.ID, CodeNotInitialized, "Server not initialized"), true
+		}
+		ctx = context.WithValue(ctx, sessionKey{}, sess)
+		switch req.Method {
+		case "tools/list":
+			return ResultResponse(req.ID, map[string]interface{}{"tools": s.Tools()}), true
+		case "tools/call":
+			return s.callTool(ctx, req), true
+		case "resources/list":
+			return s.listResources(ctx, req), true
+		case "resources/read":
+			return s.readResource(ctx, req), true
+		case "prompts/list":
+			return ResultResponse(req.ID, map[string]interface{}{"prompts": s.Prompts()}), true
+		default:
+			return s.getPrompt(ctx, req), true
+		}
+	}
+	return ErrorResponse(req.ID, CodeMethodNotFound, "Method not found: "+req.Method), true
+}
+
+// offers reports whether the server has the capability of method; those
+// it lacks are unknown methods
+func (s *Server) offers(method string) bool {
+	switch {
+	case strings.HasPrefix(method, "resources/"):
+		return s.resources != nil
+	case strings.HasPrefix(method, "prompts/"):
+		return len(s.prompts) > 0
+	}
+	return true
+}
+
+// EndSession stops notifying sess, whose connection has closed. Transports
+// that set Session.Notify must call it before the connection goes away.
+func (s *Server) EndSession(sess *Session) {
+	s.mu.Lock()
+	delete(s.listeners, sess)
+	s.mu.Unlock()
+}
+
+// initialize negotiates the protocol version of sess
+func (s *Server) initialize(sess *Session, req Request) Response {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return ErrorResponse(req.ID, CodeInvalidParams, "Invalid params")
+		}
+	}
+	sess.ProtocolVersion = NegotiateProtocolVersion(params.ProtocolVersion)
+	sess.initialized = true
+
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{"listChanged": sess.Notify != nil},
+	}
+	if s.offers("resources/") {
+		capabilities["resources"] = map[string]interface{}{"subscribe": false, "listChanged": false}
+	}
+	if s.offers("prompts/") {
+		capabilities["prompts"] = map[string]interface{}{"listChanged": false}
+	}
+	return ResultResponse(req.ID, map[string]interface{}{
+		"protocolVersion": sess.ProtocolVersion,
+		"capabilities":    capabilities,
+		"serverInfo": map[string]interface{}{
+			"name":    s.name,
+			"version": s.version,
+		},
+	})
+}
+
+// callTool runs a tools/call through the middleware, truncates its result
+// and sets the result's _meta
+func (s *Server) callTool(ctx context.Context, req Request) Response {
+	var params CallToolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return ErrorResponse(req.ID, CodeInvalidParams, "Invalid params")
+	}
+	ctx = WithCall(ctx, s.NewRequestID(), params.Meta)
+
+	call := CallFunc(s.Dispatch)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		call = s.middleware[i](call)
+	}
+	resp := call(ctx, req.ID, params.Name, params.Arguments)
+	if result, ok := resp.Result.(*CallToolResult); ok {
+		if s.Truncate != nil {
+			result.Content = s.Truncate(result.Content)
+		}
+		if SingleBlock(params.Meta) {
+			result.JoinContent()
+		}
+		result.Meta = ResponseMeta(ctx)
+	}
+	return resp
+}
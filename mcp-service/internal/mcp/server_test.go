@@ -0,0 +1,244 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/jsonrpc"
+	"github.com/vishalk17/mcp-service-restaurant/internal/toolschema"
+)
+
+func echoServer() *Server {
+	s := NewServer("test", "0.1")
+	s.RegisterTool(map[string]interface{}{
+		"name":        "echo",
+		"description": "Echo the text",
+		"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}},
+	}, []toolschema.Example{{"text": "hi"}}, func(ctx context.Context, id jsonrpc.RequestID, args map[string]interface{}) Response {
+		text, _ := args["text"].(string)
+		return ResultResponse(id, TextResult(text))
+	})
+	return s
+}
+
+func request(method, params string) Request {
+	req := Request{JSONRPC: "2.0", Method: method}
+	json.Unmarshal([]byte("1"), &req.ID)
+	if params != "" {
+		req.Params = json.RawMessage(params)
+	}
+	return req
+}
+
+func TestRegisterToolRefusesDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering echo twice didn't panic")
+		}
+	}()
+	s := echoServer()
+	s.RegisterTool(map[string]interface{}{"name": "echo"}, nil, s.tools["echo"].handler)
+}
+
+func TestStatefulSessionNeedsInitialize(t *testing.T) {
+	s, sess := echoServer(), NewSession()
+	if resp, _ := s.HandleRequest(context.Background(), sess, request("tools/list", "")); resp.Error == nil || resp.Error.Code != CodeNotInitialized {
+		t.Fatalf("tools/list before initialize = %+v", resp)
+	}
+	if _, ok := s.HandleRequest(context.Background(), sess, request("notifications/initialized", "")); ok {
+		t.Error("notification got a response")
+	}
+	s.HandleRequest(context.Background(), sess, request("initialize", `{"protocolVersion":"2024-11-05"}`))
+	if resp, _ := s.HandleRequest(context.Background(), sess, request("tools/list", "")); resp.Error != nil {
+		t.Errorf("tools/list after initialize = %+v", resp.Error)
+	}
+	if sess.ProtocolVersion != ProtocolVersion20241105 {
+		t.Errorf("negotiated %q", sess.ProtocolVersion)
+	}
+
+	// A request of its own needs no initialize
+	if resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("tools/list", "")); resp.Error != nil {
+		t.Errorf("sessionless tools/list = %+v", resp.Error)
+	}
+}
+
+func TestToolsListRendersExamples(t *testing.T) {
+	tools := echoServer().Tools()
+	if len(tools) != 1 || !strings.Contains(tools[0]["description"].(string), `"text":"hi"`) {
+		t.Fatalf("tools = %v, want echo with its example in the description", tools)
+	}
+	if meta, _ := tools[0]["_meta"].(map[string]interface{}); meta["examples"] == nil {
+		t.Errorf("_meta = %v, want the examples", tools[0]["_meta"])
+	}
+}
+
+func TestMiddlewareWrapsCallsInOrder(t *testing.T) {
+	s := echoServer()
+	s.NewRequestID = func() string { return "req-1" }
+	var order []string
+	for _, name := range []string{"outer", "inner"} {
+		s.Use(func(next CallFunc) CallFunc {
+			return func(ctx context.Context, id jsonrpc.RequestID, tool string, args map[string]interface{}) Response {
+				order = append(order, name)
+				return next(ctx, id, tool, args)
+			}
+		})
+	}
+
+	resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("tools/call", `{"name":"echo","arguments":{"text":"hello"},"_meta":{"progressToken":5}}`))
+	result, ok := resp.Result.(*CallToolResult)
+	if !ok || result.Content[0].Text != "hello" {
+		t.Fatalf("tools/call = %+v", resp)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("middleware ran %v, want outer first", order)
+	}
+	if result.Meta[MetaServerRequestID] != "req-1" || result.Meta[MetaProgressToken] == nil {
+		t.Errorf("_meta = %v, want the request ID and the client's _meta", result.Meta)
+	}
+
+	if resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("tools/call", `{"name":"nope"}`)); resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Errorf("unknown tool = %+v", resp)
+	}
+}
+
+func TestTruncateAppliesToEveryToolResult(t *testing.T) {
+	s := echoServer()
+	s.Truncate = func(content []Content) []Content {
+		content[0].Text = content[0].Text[:3]
+		return content
+	}
+
+	resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("tools/call", `{"name":"echo","arguments":{"text":"hello"}}`))
+	result, ok := resp.Result.(*CallToolResult)
+	if !ok || result.Content[0].Text != "hel" {
+		t.Fatalf("tools/call = %+v, want the text truncated", resp)
+	}
+}
+
+// pages serves two resources a page at a time. Reading page://2 fails.
+type pages struct{}
+
+func (pages) List(ctx context.Context, cursor string) ([]Resource, string, error) {
+	switch cursor {
+	case "":
+		return []Resource{{URI: "page://1", Name: "one"}}, "2", nil
+	case "2":
+		return []Resource{{URI: "page://2", Name: "two"}}, "", nil
+	}
+	return nil, "", ErrInvalidCursor
+}
+
+func (pages) Read(ctx context.Context, uri string) ([]ResourceContents, error) {
+	switch uri {
+	case "page://1":
+		return []ResourceContents{{URI: uri, MimeType: "text/plain", Text: "one"}}, nil
+	case "page://2":
+		return nil, errors.New("disk on fire")
+	}
+	return nil, ErrResourceNotFound
+}
+
+func TestResources(t *testing.T) {
+	s, sess := echoServer(), RequestSession("", "")
+	call := func(method, params string) Response {
+		resp, _ := s.HandleRequest(context.Background(), sess, request(method, params))
+		return resp
+	}
+	if resp := call("resources/list", ""); resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Fatalf("resources/list without resources = %+v", resp)
+	}
+	if caps := call("initialize", "").Result.(map[string]interface{})["capabilities"].(map[string]interface{}); caps["resources"] != nil {
+		t.Errorf("capabilities %v, want no resources", caps)
+	}
+
+	s.SetResources(pages{})
+	if caps := call("initialize", "").Result.(map[string]interface{})["capabilities"].(map[string]interface{}); caps["resources"] == nil {
+		t.Errorf("capabilities %v, want resources", caps)
+	}
+	first := call("resources/list", "").Result.(map[string]interface{})
+	last := call("resources/list", `{"cursor":"2"}`).Result.(map[string]interface{})
+	if first["nextCursor"] != "2" || last["nextCursor"] != nil || last["resources"].([]Resource)[0].URI != "page://2" {
+		t.Errorf("pages %v then %v", first, last)
+	}
+	if resp := call("resources/read", `{"uri":"page://1"}`); resp.Error != nil || resp.Result.(map[string]interface{})["contents"].([]ResourceContents)[0].Text != "one" {
+		t.Errorf("resources/read page://1 = %+v", resp)
+	}
+	for params, code := range map[string]int{
+		`{"cursor":"x"}`:     CodeInvalidParams,
+		`{"uri":"page://9"}`: CodeResourceNotFound,
+		`{"uri":"page://2"}`: CodeInternalError,
+		`{}`:                 CodeInvalidParams,
+	} {
+		method := "resources/read"
+		if strings.Contains(params, "cursor") {
+			method = "resources/list"
+		}
+		if resp := call(method, params); resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("%s %s = %+v, want error %d", method, params, resp, code)
+		}
+	}
+}
+
+func TestGetPromptChecksArguments(t *testing.T) {
+	s := echoServer()
+	if resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("prompts/list", "")); resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Fatalf("prompts/list without prompts = %+v", resp)
+	}
+	s.RegisterPrompt(Prompt{Name: "greet", Arguments: []PromptArgument{{Name: "who", Required: true}}}, func(ctx context.Context, args map[string]string) (*PromptResult, error) {
+		if args["who"] == "nobody" {
+			return nil, fmt.Errorf("%w: who must be somebody", ErrInvalidPromptArgument)
+		}
+		return UserPrompt("", "Say hello to "+args["who"]), nil
+	})
+
+	get := func(args string) Response {
+		resp, _ := s.HandleRequest(context.Background(), RequestSession("", ""), request("prompts/get", `{"name":"greet","arguments":`+args+`}`))
+		return resp
+	}
+	if resp := get(`{"who":"Asha"}`); resp.Error != nil || resp.Result.(*PromptResult).Messages[0].Content.Text != "Say hello to Asha" {
+		t.Errorf("greet Asha = %+v", resp)
+	}
+	resp := get(`{"whom":"Asha"}`)
+	if resp.Error == nil || resp.Error.Message != "who is required; whom is not an argument of greet" {
+		t.Errorf("greet with a misspelt argument = %+v, want both violations", resp.Error)
+	}
+	if resp := get(`{"who":"nobody"}`); resp.Error == nil || resp.Error.Code != CodeInvalidParams || !strings.Contains(resp.Error.Message, "somebody") {
+		t.Errorf("greet nobody = %+v, want the handler's invalid argument", resp.Error)
+	}
+}
+
+func TestRegisterToolNotifiesInitializedSessions(t *testing.T) {
+	s := echoServer()
+	var notified []string
+	sess := NewSession()
+	sess.Notify = func(n Notification) { notified = append(notified, n.Method) }
+	register := func(name string) {
+		s.RegisterTool(map[string]interface{}{"name": name, "inputSchema": map[string]interface{}{"type": "object"}}, nil, s.tools["echo"].handler)
+	}
+
+	resp, _ := s.HandleRequest(context.Background(), sess, request("initialize", ""))
+	if tools := resp.Result.(map[string]interface{})["capabilities"].(map[string]interface{})["tools"]; tools.(map[string]interface{})["listChanged"] != true {
+		t.Errorf("tools capability %v, want listChanged", tools)
+	}
+	register("before_initialized")
+	s.HandleRequest(context.Background(), sess, request("notifications/initialized", ""))
+	register("after_initialized")
+	if strings.Join(notified, ",") != MethodToolsListChanged {
+		t.Fatalf("notified %v, want one list_changed after notifications/initialized", notified)
+	}
+	s.EndSession(sess)
+	register("after_end")
+	if len(notified) != 1 {
+		t.Errorf("notified %v after EndSession", notified)
+	}
+
+	resp, _ = s.HandleRequest(context.Background(), RequestSession("", ""), request("initialize", ""))
+	if tools := resp.Result.(map[string]interface{})["capabilities"].(map[string]interface{})["tools"]; tools.(map[string]interface{})["listChanged"] != false {
+		t.Errorf("tools capability of a session that can't be told %v, want listChanged false", tools)
+	}
+}
@@ -0,0 +1,56 @@
+// Package mcp holds the MCP server shared by the transports: the stdio
+// server, the SSE server and the /mcp endpoint of the API. Each decodes
+// requests its own way and passes them to Server.HandleRequest.
+package mcp
+
+// Protocol revisions this server implements
+const (
+	ProtocolVersion20241105 = "2024-11-05"
+	ProtocolVersion20250326 = "2025-03-26"
+)
+
+// SupportedProtocolVersions lists the implemented revisions, oldest first
+var SupportedProtocolVersions = []string{
+	ProtocolVersion20241105,
+	ProtocolVersion20250326,
+}
+
+// ProtocolVersionHeader carries the negotiated version on HTTP requests made
+// after initialize, for transports that keep no session between requests
+const ProtocolVersionHeader = "Mcp-Protocol-Version"
+
+// LatestProtocolVersion is the newest revision this server implements
+func LatestProtocolVersion() string {
+	return SupportedProtocolVersions[len(SupportedProtocolVersions)-1]
+}
+
+// IsSupportedProtocolVersion reports whether version is implemented here
+func IsSupportedProtocolVersion(version string) bool {
+	for _, v := range SupportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateProtocolVersion picks the version to answer an initialize request
+// with. As the spec requires, a supported requested version is echoed back;
+// anything else gets the latest supported version and the client decides
+// whether it can continue.
+func NegotiateProtocolVersion(requested string) string {
+	if IsSupportedProtocolVersion(requested) {
+		return requested
+	}
+	return LatestProtocolVersion()
+}
+
+// RequestProtocolVersion returns the version a sessionless HTTP request runs
+// under, read from ProtocolVersionHeader. Clients that don't send the header
+// predate it, so they get the oldest supported version.
+func RequestProtocolVersion(header string) string {
+	if IsSupportedProtocolVersion(header) {
+		return header
+	}
+	return SupportedProtocolVersions[0]
+}
@@ -0,0 +1,29 @@
+package mcp
+
+import "testing"
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	for requested, want := range map[string]string{
+		ProtocolVersion20241105: ProtocolVersion20241105,
+		ProtocolVersion20250326: ProtocolVersion20250326,
+		"2024-06-01":            LatestProtocolVersion(),
+		"2099-01-01":            LatestProtocolVersion(),
+		"":                      LatestProtocolVersion(),
+	} {
+		if got := NegotiateProtocolVersion(requested); got != want {
+			t.Errorf("NegotiateProtocolVersion(%q) = %s, want %s", requested, got, want)
+		}
+	}
+}
+
+func TestRequestProtocolVersion(t *testing.T) {
+	for header, want := range map[string]string{
+		ProtocolVersion20250326: ProtocolVersion20250326,
+		"":                      ProtocolVersion20241105,
+		"2099-01-01":            ProtocolVersion20241105,
+	} {
+		if got := RequestProtocolVersion(header); got != want {
+			t.Errorf("RequestProtocolVersion(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
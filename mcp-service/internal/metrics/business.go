@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// businessQueryTimeout bounds the snapshot queries so a slow database
+// cannot hang a scrape
+const businessQueryTimeout = 5 * time.Second
+
+// BusinessOptions tunes the business signal collector
+type BusinessOptions struct {
+	OrderSLA     time.Duration // open orders older than this count as breaching
+	MaxStaleness time.Duration // a snapshot younger than this is served as is
+	// Per-restaurant series are exported only while there are fewer
+	// restaurants than this; above it only totals are exported
+	PerRestaurantLabelMax int
+}
+
+// businessCollector computes alerting signals from the orders and menu
+// tables. Scrapes within MaxStaleness of the last snapshot reuse it, so
+// several Prometheus replicas don't multiply the query load.
+type businessCollector struct {
+	db   *sql.DB
+	opts BusinessOptions
+
+	mu       sync.Mutex
+	snapshot []Sample
+	takenAt  time.Time
+}
+
+// RegisterBusinessCollector registers the business signal collector on /metrics
+func RegisterBusinessCollector(db *sql.DB, opts BusinessOptions) {
+	c := &businessCollector{db: db, opts: opts}
+	RegisterCollector(c.collect)
+}
+
+func (c *businessCollector) collect() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.snapshot == nil || time.Since(c.takenAt) > c.opts.MaxStaleness {
+		samples, err := c.query()
+		if err != nil {
+			// Keep serving the previous snapshot; its age shows it is stale
+			log.Printf("Error collecting business metrics: %v", err)
+		} else {
+			c.snapshot, c.takenAt = samples, time.Now()
+		}
+	}
+
+	out := append([]Sample{}, c.snapshot...)
+	if !c.takenAt.IsZero() {
+		out = append(out, Sample{
+			Name:  "business_metrics_snapshot_age_seconds",
+			Help:  "Age of the business metrics snapshot served on this scrape",
+			Type:  TypeGauge,
+			Value: time.Since(c.takenAt).Seconds(),
+		})
+	}
+	return out
+}
+
+func (c *businessCollector) query() ([]Sample, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessQueryTimeout)
+	defer cancel()
+
+	var restaurants, emptyMenus int
+	err := c.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE NOT EXISTS (
+				SELECT 1 FROM menu_items m WHERE m.restaurant_id = r.id AND m.available
+			))
+		FROM restaurants r
+	`).Scan(&restaurants, &emptyMenus)
+	if err != nil {
+		return nil, err
+	}
+	perRestaurant := restaurants < c.opts.PerRestaurantLabelMax
+
+	samples := []Sample{{
+		Name:  "restaurants_empty_menu",
+		Help:  "Restaurants without any available menu item",
+		Type:  TypeGauge,
+		Value: float64(emptyMenus),
+	}}
+
+	breaching, err := c.countByRestaurant(ctx, perRestaurant, `
+		SELECT restaurant_id, COUNT(*) FROM orders
+		WHERE status NOT IN ('delivered', 'cancelled')
+			AND created_at < NOW() - make_interval(secs => $1)
+		GROUP BY restaurant_id
+	`, c.opts.OrderSLA.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range breaching {
+		s.Name, s.Type = "orders_open_over_sla", TypeGauge
+		s.Help = "Open orders older than the order SLA (" + c.opts.OrderSLA.String() + ")"
+		samples = append(samples, s)
+	}
+
+	// Derived from the current rows rather than counted at the source, so
+	// deleting a failed order looks like a counter reset to Prometheus
+	failed, err := c.countByRestaurant(ctx, perRestaurant, `
+		SELECT restaurant_id, COUNT(*) FROM orders
+		WHERE payment_status = 'failed'
+		GROUP BY restaurant_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range failed {
+		s.Name, s.Type = "orders_payment_failed_total", TypeCounter
+		s.Help = "Orders whose payment failed"
+		samples = append(samples, s)
+	}
+
+	// Reasons come from a fixed enum, so the label is bounded
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT COALESCE(cancellation_reason, 'unknown'), COUNT(*) FROM orders
+		WHERE status = 'cancelled'
+		GROUP BY 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reason string
+		var count float64
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		samples = append(samples, Sample{
+			Name:   "orders_cancelled_total",
+			Help:   "Cancelled orders by cancellation reason",
+			Type:   TypeCounter,
+			Labels: map[string]string{"reason": reason},
+			Value:  count,
+		})
+	}
+	return samples, rows.Err()
+}
+
+// countByRestaurant runs a (restaurant_id, count) query and returns one
+// sample per restaurant, or a single total when perRestaurant is false.
+// Name, Help and Type are left for the caller.
+func (c *businessCollector) countByRestaurant(ctx context.Context, perRestaurant bool, query string, args ...interface{}) ([]Sample, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []Sample
+	var total float64
+	for rows.Next() {
+		var restaurantID sql.NullInt64
+		var count float64
+		if err := rows.Scan(&restaurantID, &count); err != nil {
+			return nil, err
+		}
+		total += count
+		if perRestaurant {
+			samples = append(samples, Sample{
+				Labels: map[string]string{"restaurant_id": strconv.FormatInt(restaurantID.Int64, 10)},
+				Value:  count,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !perRestaurant || len(samples) == 0 {
+		// An unlabelled zero keeps the series present for absent() alerts
+		samples = []Sample{{Value: total}}
+	}
+	return samples, nil
+}
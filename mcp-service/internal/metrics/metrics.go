@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types as written on the TYPE line
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Sample is one series of a metric. Collectors return samples on every
+// scrape; SetGauge and the counter helpers keep theirs in memory.
+type Sample struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector produces samples at scrape time, for values that are cheaper to
+// compute on demand than to keep up to date
+type Collector func() []Sample
+
+var (
+	mu         sync.RWMutex
+	series     = map[string]*Sample{} // keyed by name and rendered labels
+	collectors []Collector
+)
+
+// SetGauge records the current value of a gauge, registering it on first use
+func SetGauge(name, help string, value float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	lookup(name, help, TypeGauge, nil).Value = value
+}
+
+// AddCounter increases a monotonic counter by delta, registering it on first use
+func AddCounter(name, help string, delta float64) {
+	AddCounterWithLabels(name, help, nil, delta)
+}
+
+// IncCounter increases a counter by one
+func IncCounter(name, help string) {
+	AddCounterWithLabels(name, help, nil, 1)
+}
+
+// AddCounterWithLabels increases one labelled series of a counter. Label
+// values must come from a small fixed set to keep cardinality bounded.
+func AddCounterWithLabels(name, help string, labels map[string]string, delta float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	lookup(name, help, TypeCounter, labels).Value += delta
+}
+
+// RegisterCollector adds a collector that is run on every scrape
+func RegisterCollector(c Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors = append(collectors, c)
+}
+
+// lookup returns the stored series for name and labels, creating it if needed.
+// mu must be held.
+func lookup(name, help, typ string, labels map[string]string) *Sample {
+	key := name + renderLabels(labels)
+	s, ok := series[key]
+	if !ok {
+		s = &Sample{Name: name, Help: help, Type: typ, Labels: labels}
+		series[key] = s
+	}
+	return s
+}
+
+// renderLabels formats labels as {a="1",b="2"} with sorted keys
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + strconv.Quote(labels[k])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Handler serves all gauges, counters and collected samples in the
+// Prometheus text exposition format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.RLock()
+		samples := make([]Sample, 0, len(series))
+		for _, s := range series {
+			samples = append(samples, *s)
+		}
+		cs := collectors
+		mu.RUnlock()
+
+		// Collectors may hit the database, so they run outside the lock
+		for _, c := range cs {
+			samples = append(samples, c()...)
+		}
+
+		sort.SliceStable(samples, func(i, j int) bool {
+			if samples[i].Name != samples[j].Name {
+				return samples[i].Name < samples[j].Name
+			}
+			return renderLabels(samples[i].Labels) < renderLabels(samples[j].Labels)
+		})
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for i, s := range samples {
+			if i == 0 || samples[i-1].Name != s.Name {
+				fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.Name, s.Help, s.Name, s.Type)
+			}
+			fmt.Fprintf(w, "%s%s %s\n", s.Name, renderLabels(s.Labels), strconv.FormatFloat(s.Value, 'g', -1, 64))
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the originating client address, preferring the first
+// X-Forwarded-For hop set by the load balancer. The header is client
+// controlled when the service is reached directly, so only use the result
+// for logging and forensics, never for access decisions.
+func ClientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		if i := strings.IndexByte(fwd, ','); i >= 0 {
+			fwd = fwd[:i]
+		}
+		if ip := strings.TrimSpace(fwd); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,19 +1,100 @@
 package middleware
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
 
-// CORSMiddleware adds CORS headers to responses
-func CORSMiddleware(next http.Handler) http.Handler {
+// DefaultCORSOrigins are the browser-based MCP clients allowed without any
+// configuration. They get no credentialed access; they send Bearer tokens.
+var DefaultCORSOrigins = []string{
+	"https://claude.ai",
+	"https://chatgpt.com",
+	"https://chat.openai.com",
+}
+
+// CORSPolicy decides which browser origins may call the service
+type CORSPolicy struct {
+	allowed      map[string]bool
+	credentialed map[string]bool // explicitly configured origins, which may send cookies
+}
+
+// NewCORSPolicy allows DefaultCORSOrigins plus the given origins. Only the
+// given origins may make credentialed requests. Wildcards and "null" are
+// rejected: a reflected "*" with credentials would let any site in.
+func NewCORSPolicy(origins []string) (*CORSPolicy, error) {
+	p := &CORSPolicy{allowed: map[string]bool{}, credentialed: map[string]bool{}}
+	for _, o := range DefaultCORSOrigins {
+		p.allowed[o] = true
+	}
+	for _, o := range origins {
+		origin, err := normalizeOrigin(o)
+		if err != nil {
+			return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS: %v", err)
+		}
+		p.allowed[origin] = true
+		p.credentialed[origin] = true
+	}
+	return p, nil
+}
+
+// CORSPolicyFromEnv reads CORS_ALLOWED_ORIGINS (comma separated), for the
+// binaries that don't use internal/config
+func CORSPolicyFromEnv() (*CORSPolicy, error) {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return NewCORSPolicy(origins)
+}
+
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("invalid origin %q: want scheme://host[:port]", raw)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// Middleware adds CORS headers for allowed origins and answers preflight
+// requests. Requests without an Origin header aren't from a browser page and
+// pass through untouched; disallowed origins get no CORS headers, so the
+// browser withholds the response, and their preflights are refused.
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow all origins for OAuth (ChatGPT, Claude, etc.)
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		// Handle preflight requests
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		normalized, err := normalizeOrigin(origin)
+		if err != nil || !p.allowed[normalized] {
+			if r.Method == http.MethodOptions {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if p.credentialed[normalized] {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, Mcp-Protocol-Version, X-Total-Count, Link")
+
 		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Mcp-Protocol-Version, Mcp-Session-Id")
+			w.Header().Set("Access-Control-Max-Age", "3600")
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
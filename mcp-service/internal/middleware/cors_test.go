@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	p, err := NewCORSPolicy([]string{"https://Dashboard.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		name, method, origin string
+		wantStatus           int
+		wantAllowOrigin      string
+		wantCredentials      bool
+	}{
+		{"no origin", http.MethodPost, "", http.StatusOK, "", false},
+		{"default client", http.MethodPost, "https://claude.ai", http.StatusOK, "https://claude.ai", false},
+		{"default client preflight", http.MethodOptions, "https://claude.ai", http.StatusNoContent, "https://claude.ai", false},
+		{"configured origin", http.MethodPost, "https://dashboard.example.com", http.StatusOK, "https://dashboard.example.com", true},
+		{"configured origin preflight", http.MethodOptions, "https://dashboard.example.com", http.StatusNoContent, "https://dashboard.example.com", true},
+		{"disallowed origin", http.MethodPost, "https://evil.example", http.StatusOK, "", false},
+		{"disallowed preflight", http.MethodOptions, "https://evil.example", http.StatusForbidden, "", false},
+		{"null origin", http.MethodPost, "null", http.StatusOK, "", false},
+		{"null preflight", http.MethodOptions, "null", http.StatusForbidden, "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/mcp", nil)
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantAllowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tc.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tc.wantCredentials)
+			}
+			if tc.origin != "" && w.Header().Get("Vary") != "Origin" {
+				t.Errorf("Vary = %q, want Origin", w.Header().Get("Vary"))
+			}
+			if tc.wantStatus == http.StatusNoContent {
+				allowed := w.Header().Get("Access-Control-Allow-Headers")
+				for _, header := range []string{"Authorization", "Mcp-Session-Id"} {
+					if !strings.Contains(allowed, header) {
+						t.Errorf("preflight allows headers %q, missing %s", allowed, header)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewCORSPolicyRejectsWildcardAndNull(t *testing.T) {
+	for _, origin := range []string{"*", "null", "https://example.com/app", "ftp://example.com"} {
+		if _, err := NewCORSPolicy([]string{origin}); err == nil {
+			t.Errorf("NewCORSPolicy(%q) succeeded", origin)
+		}
+	}
+}
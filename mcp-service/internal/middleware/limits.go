@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+)
+
+// MaxMessageBytes caps the size of a single inbound JSON-RPC message
+// (HTTP body, SSE stream line or stdio line). Override with MCP_MAX_MESSAGE_BYTES.
+var MaxMessageBytes int64 = 1 << 20 // 1 MiB
+
+// MaxResponseBytes caps the text returned by a single tool call; anything
+// beyond it is truncated. Override with MCP_MAX_RESPONSE_BYTES.
+var MaxResponseBytes = 512 << 10 // 512 KiB
+
+// ToolCallTimeout bounds the database work of a single tool call so a hung
+// Postgres connection fails the call instead of stalling it forever.
+// Override with MCP_TOOL_TIMEOUT_SECONDS.
+var ToolCallTimeout = 30 * time.Second
+
+// PublicRateLimit is how many requests a minute one client may make to the
+// unauthenticated /public endpoints. Override with PUBLIC_RATE_LIMIT_PER_MINUTE.
+var PublicRateLimit = 60
+
+// RestaurantTokenRateLimit is how many requests a minute one restaurant API
+// token may make. Override with RESTAURANT_TOKEN_RATE_LIMIT_PER_MINUTE.
+var RestaurantTokenRateLimit = 120
+
+// ExportMaxRows caps the CSV rows the export_orders tool returns; the REST
+// export streams every row. Override with EXPORT_MAX_ROWS.
+var ExportMaxRows = 2000
+
+// WriteTimeout bounds every write to a client so a stalled reader cannot
+// pin a handler goroutine and its buffered response forever.
+const WriteTimeout = 15 * time.Second
+
+func init() {
+	if v, err := strconv.ParseInt(os.Getenv("MCP_MAX_MESSAGE_BYTES"), 10, 64); err == nil && v > 0 {
+		MaxMessageBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("MCP_MAX_RESPONSE_BYTES")); err == nil && v > 0 {
+		MaxResponseBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("MCP_TOOL_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		ToolCallTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("PUBLIC_RATE_LIMIT_PER_MINUTE")); err == nil && v > 0 {
+		PublicRateLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RESTAURANT_TOKEN_RATE_LIMIT_PER_MINUTE")); err == nil && v > 0 {
+		RestaurantTokenRateLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("EXPORT_MAX_ROWS")); err == nil && v > 0 {
+		ExportMaxRows = v
+	}
+}
+
+// LimitBodyMiddleware rejects request bodies larger than MaxMessageBytes
+func LimitBodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > MaxMessageBytes {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, MaxMessageBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsBodyTooLarge reports whether err came from a body exceeding the limit
+func IsBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// IsJSONContentType reports whether a request declares a JSON body. Requests
+// without a Content-Type are accepted since several MCP clients omit it.
+func IsJSONContentType(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(ct), "application/json")
+}
+
+// SetWriteDeadline arms a per-write deadline on the connection behind w.
+// Writers that don't support deadlines (e.g. httptest recorders) are ignored.
+func SetWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(WriteTimeout))
+}
+
+// TruncateText shortens s to at most max bytes without splitting a UTF-8
+// sequence, appending a note so the model knows the output is incomplete.
+func TruncateText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n\n[truncated: response exceeded " + strconv.Itoa(max) + " bytes, narrow the request to see the rest]"
+}
+
+// TruncateContent applies max to the text of all of a tool result's blocks
+// together: the block that crosses it is cut with TruncateText and the
+// blocks after it are dropped
+func TruncateContent(content []mcp.Content, max int) []mcp.Content {
+	left := max
+	for i := range content {
+		if len(content[i].Text) > left {
+			content[i].Text = TruncateText(content[i].Text, left)
+			return content[:i+1]
+		}
+		left -= len(content[i].Text)
+	}
+	return content
+}
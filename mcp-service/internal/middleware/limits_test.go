@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/mcp"
+)
+
+func TestLimitBodyMiddleware(t *testing.T) {
+	old := MaxMessageBytes
+	MaxMessageBytes = 16
+	t.Cleanup(func() { MaxMessageBytes = old })
+	h := LimitBodyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if IsBodyTooLarge(err) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		name     string
+		body     io.Reader
+		wantCode int
+	}{
+		{"small", strings.NewReader(`{"id":1}`), http.StatusOK},
+		{"declared too large", strings.NewReader(strings.Repeat("x", 17)), http.StatusRequestEntityTooLarge},
+		// A reader of unknown length is sent without a Content-Length
+		{"streamed too large", io.MultiReader(strings.NewReader(strings.Repeat("\xff", 17))), http.StatusRequestEntityTooLarge},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", tc.body)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.wantCode {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.wantCode)
+		}
+	}
+}
+
+func TestIsJSONContentType(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"":                                true,
+		"application/json":                true,
+		"Application/JSON; charset=utf-8": true,
+		"application/octet-stream":        false,
+		"text/plain":                      false,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if ct != "" {
+			r.Header.Set("Content-Type", ct)
+		}
+		if got := IsJSONContentType(r); got != want {
+			t.Errorf("IsJSONContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+// FuzzTruncateText checks that tool output of any size and bytes stays
+// within the limit, plus the note, without splitting a UTF-8 sequence
+func FuzzTruncateText(f *testing.F) {
+	f.Add("short", 64)
+	f.Add(strings.Repeat("₹350.00 ", 100), 100)
+	f.Add("\xff\xfe\x00binary", 3)
+
+	f.Fuzz(func(t *testing.T, s string, max int) {
+		if max <= 0 || max > 1<<16 {
+			t.Skip()
+		}
+		got := TruncateText(s, max)
+		if i := strings.Index(got, "\n\n[truncated: "); i >= 0 && len(s) > max {
+			got = got[:i]
+		}
+		if len(got) > max {
+			t.Errorf("%d bytes kept, want at most %d", len(got), max)
+		}
+		if utf8.ValidString(s) && !utf8.ValidString(got) {
+			t.Errorf("truncation split a UTF-8 sequence: %q", got)
+		}
+	})
+}
+
+func TestTruncateContentCountsAcrossBlocks(t *testing.T) {
+	content := mcp.TextBlocks("summary", strings.Repeat("x", 20), "caveats")
+	got := TruncateContent(content, 15)
+	if len(got) != 2 || got[0].Text != "summary" || !strings.HasPrefix(got[1].Text, "xxxxxxxx\n\n[truncated") {
+		t.Errorf("truncated to %+v, want the summary and 8 bytes of the table", got)
+	}
+	if got := TruncateContent(mcp.TextBlocks("a", "b"), 2); len(got) != 2 || got[1].Text != "b" {
+		t.Errorf("content within the limit changed: %+v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+)
+
+// RateLimiter gives every client a token bucket holding perMinute requests
+// that refills evenly over a minute, so short bursts pass and sustained
+// scraping is slowed down
+type RateLimiter struct {
+	name      string
+	perMinute float64
+	key       func(*http.Request) string
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// NewRateLimiter limits each client, as identified by key, to perMinute
+// requests a minute. name labels the rejections in metrics.
+func NewRateLimiter(name string, perMinute int, key func(*http.Request) string) *RateLimiter {
+	return &RateLimiter{
+		name:      name,
+		perMinute: float64(perMinute),
+		key:       key,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it
+// reports how long until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.perMinute, seen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.perMinute, b.tokens+now.Sub(b.seen).Minutes()*l.perMinute)
+	b.seen = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.perMinute * float64(time.Minute))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets clients idle for a minute, whose buckets are full again
+// anyway, so the map doesn't grow with every address ever seen
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Middleware answers 429 with a Retry-After header once a client is over
+// the limit
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(l.key(r)); !ok {
+			metrics.AddCounterWithLabels("http_rate_limited_total", "Requests rejected by a rate limit",
+				map[string]string{"limit": l.name}, 1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
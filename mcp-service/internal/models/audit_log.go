@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Entity types of audit log entries
+const (
+	AuditRestaurant      = "restaurant"
+	AuditMenuItem        = "menu_item"
+	AuditOrder           = "order"
+	AuditPricingRule     = "pricing_rule"
+	AuditChangeRequest   = "change_request"
+	AuditRestaurantToken = "restaurant_token"
+)
+
+// AuditEntityTypes lists every audited entity type
+var AuditEntityTypes = []string{AuditRestaurant, AuditMenuItem, AuditOrder, AuditPricingRule, AuditChangeRequest, AuditRestaurantToken}
+
+// Outcomes of audited calls that made no change
+const (
+	AuditDenied   = "denied"   // the restaurant is outside the caller's scope
+	AuditShadowed = "shadowed" // recorded by shadow mode instead of run
+)
+
+// AuditEntry records one successful call of a mutating tool or endpoint:
+// who made it, what it changed and the arguments it was given. An entry
+// with an Outcome records a call that was refused or shadowed instead.
+type AuditEntry struct {
+	ID         int                    `json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Actor      string                 `json:"actor"`
+	Tool       string                 `json:"tool"` // MCP tool name, or the REST method and path
+	EntityType string                 `json:"entity_type"`
+	EntityID   *int                   `json:"entity_id,omitempty"` // nil when the call changed several
+	Arguments  map[string]interface{} `json:"arguments"`
+	Outcome    string                 `json:"outcome,omitempty"` // empty for a change that was made, else e.g. AuditDenied
+}
+
+// AuditQuery selects audit log entries, optionally of one entity type or
+// entity and between two dates (YYYY-MM-DD, inclusive, in UTC)
+type AuditQuery struct {
+	EntityType string
+	EntityID   int
+	From       string
+	To         string
+}
+
+// AuditQueryArgs reads the get_audit_log tool arguments
+func AuditQueryArgs(args map[string]interface{}) (AuditQuery, error) {
+	var q AuditQuery
+	q.EntityType, _ = args["entity_type"].(string)
+	if v, ok := args["entity_id"].(float64); ok {
+		q.EntityID = int(v)
+	}
+	q.From, _ = args["from"].(string)
+	q.To, _ = args["to"].(string)
+	return q, q.Validate()
+}
+
+// Validate checks the entity type, that an entity ID comes with one, and
+// that the dates parse in order
+func (q AuditQuery) Validate() error {
+	if q.EntityType != "" && !slices.Contains(AuditEntityTypes, q.EntityType) {
+		return fmt.Errorf("invalid entity_type %q: must be one of %s", q.EntityType, strings.Join(AuditEntityTypes, ", "))
+	}
+	if q.EntityID != 0 && q.EntityType == "" {
+		return fmt.Errorf("entity_id needs an entity_type")
+	}
+	return validateDateRange(q.From, q.To)
+}
+
+// Matches reports whether e is selected by q
+func (q AuditQuery) Matches(e AuditEntry) bool {
+	if q.EntityType != "" && e.EntityType != q.EntityType {
+		return false
+	}
+	if q.EntityID != 0 && (e.EntityID == nil || *e.EntityID != q.EntityID) {
+		return false
+	}
+	day := e.CreatedAt.UTC().Format(DateLayout)
+	return (q.From == "" || day >= q.From) && (q.To == "" || day <= q.To)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CanApproveChanges reports whether role may change prices and availability
+// directly and review the changes staff propose
+func CanApproveChanges(role string) bool {
+	return role == RoleAdmin || role == RoleOwner
+}
+
+// Change request statuses
+const (
+	ChangeStatusPending  = "pending"
+	ChangeStatusApproved = "approved"
+	ChangeStatusRejected = "rejected"
+)
+
+// ChangeEntityMenuItem is the only entity changes can be proposed for so far
+const ChangeEntityMenuItem = "menu_item"
+
+// MenuItemChange is the diff a change request proposes for a menu item.
+// Nil fields are left as they are.
+type MenuItemChange struct {
+	Price     *Money `json:"price,omitempty"`
+	Available *bool  `json:"available,omitempty"`
+}
+
+// Validate checks that something changes and the new price is positive
+func (c MenuItemChange) Validate() error {
+	if c.Price == nil && c.Available == nil {
+		return fmt.Errorf("changes must set price or available")
+	}
+	if c.Price != nil && *c.Price <= 0 {
+		return fmt.Errorf("price must be greater than 0")
+	}
+	return nil
+}
+
+// ApplyTo sets the changed fields on m
+func (c MenuItemChange) ApplyTo(m *MenuItem) {
+	if c.Price != nil {
+		m.Price = *c.Price
+	}
+	if c.Available != nil {
+		m.Available = *c.Available
+	}
+}
+
+// ChangeRequest is a change to a menu item that staff proposed and an owner
+// or admin approves or rejects. Approving applies Changes.
+type ChangeRequest struct {
+	ID         int            `json:"id"`
+	Entity     string         `json:"entity"`
+	EntityID   int            `json:"entity_id"`
+	Changes    MenuItemChange `json:"changes"`
+	Note       string         `json:"note,omitempty"`
+	Status     string         `json:"status"`
+	ProposedBy string         `json:"proposed_by"`
+	ReviewedBy string         `json:"reviewed_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ReviewedAt *time.Time     `json:"reviewed_at,omitempty"`
+}
+
+// Validate checks the entity and the proposed changes
+func (c ChangeRequest) Validate() error {
+	if c.Entity != ChangeEntityMenuItem {
+		return fmt.Errorf("entity must be %q", ChangeEntityMenuItem)
+	}
+	if c.EntityID <= 0 {
+		return fmt.Errorf("entity_id must be a positive integer")
+	}
+	return c.Changes.Validate()
+}
+
+// ChangeRequestArgs reads the propose_change tool arguments. changes is an
+// object with only the fields MenuItemChange knows.
+func ChangeRequestArgs(args map[string]interface{}) (ChangeRequest, error) {
+	c := ChangeRequest{Status: ChangeStatusPending}
+	c.Entity, _ = args["entity"].(string)
+	if v, ok := args["entity_id"].(float64); ok && v == math.Trunc(v) {
+		c.EntityID = int(v)
+	}
+	c.Note, _ = args["note"].(string)
+	changes, ok := args["changes"].(map[string]interface{})
+	if !ok {
+		return c, fmt.Errorf("changes must be an object, e.g. {\"price\": 199}")
+	}
+	for field, value := range changes {
+		switch field {
+		case "price":
+			v, ok := value.(float64)
+			if !ok || math.IsInf(v, 0) || math.IsNaN(v) {
+				return c, fmt.Errorf("changes.price must be a number")
+			}
+			price := Rupees(v)
+			c.Changes.Price = &price
+		case "available":
+			available, ok := value.(bool)
+			if !ok {
+				return c, fmt.Errorf("changes.available must be true or false")
+			}
+			c.Changes.Available = &available
+		default:
+			return c, fmt.Errorf("changes.%s can't be proposed; only price and available", field)
+		}
+	}
+	return c, c.Validate()
+}
+
+// ValidateChangeStatus checks a list_change_requests status filter; empty
+// means all
+func ValidateChangeStatus(status string) error {
+	switch status {
+	case "", ChangeStatusPending, ChangeStatusApproved, ChangeStatusRejected:
+		return nil
+	}
+	return fmt.Errorf("invalid status %q: expected pending, approved or rejected", status)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// indianPrefix matches a 10-digit number written with its country code or
+// trunk prefix
+var indianPrefix = regexp.MustCompile(`^(91|0)(\d{10})$`)
+
+// NormalizePhone reduces a phone number to its digits without the +91 or 0
+// prefix, so "98200 12345" and "+91-9820012345" compare equal. Storage
+// applies the same rule in SQL; keep the two in step.
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	return indianPrefix.ReplaceAllString(digits, "$2")
+}
+
+// CustomerSummary totals every order placed with one phone number
+type CustomerSummary struct {
+	Orders int   `json:"orders"`
+	Spend  Money `json:"spend"` // final amounts, excluding cancelled orders
+}
+
+// Line renders the summary for a tool result
+func (s CustomerSummary) Line(phone string) string {
+	return fmt.Sprintf("Customer %s: %d orders, total spend %s (excluding cancelled orders)", phone, s.Orders, s.Spend)
+}
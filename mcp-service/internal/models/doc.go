@@ -0,0 +1,6 @@
+// Package models defines the restaurant domain types and their rules. The
+// JSON tags here are the canonical field names: REST responses and MCP tool
+// results encode these types as they are rather than copies of them, and
+// tool arguments use the same names, as in available or phone_number. Older
+// argument names are accepted and reported by package compat.
+package models
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// How a menu item name was matched by MatchMenuItems, from strictest to loosest
+const (
+	MatchExact           = "exact"
+	MatchCaseInsensitive = "case_insensitive"
+	MatchSubstring       = "substring"
+	MatchAllWords        = "all_words"
+)
+
+// MatchMenuItems resolves a free-form dish name against a menu. Each tier is
+// tried in turn (exact, case-insensitive, substring, all words present in any
+// order) and the first tier with any hit wins, so "Butter Chicken" never
+// becomes ambiguous with "Butter Chicken Biryani". It returns the matches and
+// the tier that produced them; more than one match means the name is ambiguous.
+func MatchMenuItems(menu []MenuItem, name string) ([]MenuItem, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ""
+	}
+	query := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	words := strings.Fields(query)
+
+	tiers := []struct {
+		how   string
+		match func(item MenuItem) bool
+	}{
+		{MatchExact, func(m MenuItem) bool { return m.Name == name }},
+		{MatchCaseInsensitive, func(m MenuItem) bool { return strings.EqualFold(m.Name, name) }},
+		{MatchSubstring, func(m MenuItem) bool { return strings.Contains(strings.ToLower(m.Name), query) }},
+		{MatchAllWords, func(m MenuItem) bool {
+			itemName := strings.ToLower(m.Name)
+			for _, w := range words {
+				if !strings.Contains(itemName, w) {
+					return false
+				}
+			}
+			return true
+		}},
+	}
+
+	for _, tier := range tiers {
+		var matches []MenuItem
+		for _, item := range menu {
+			if tier.match(item) {
+				matches = append(matches, item)
+			}
+		}
+		if len(matches) > 0 {
+			return matches, tier.how
+		}
+	}
+	return nil, ""
+}
+
+// MenuSearch selects items of a restaurant's current menu. Empty and zero
+// fields don't filter.
+type MenuSearch struct {
+	RestaurantID int
+	Query        string // case-insensitive substring of the name or description
+	Category     string // matched case-insensitively
+	DietaryType  string // see DietaryTypes
+	SpiceLevel   string // see SpiceLevels
+	MinPrice     Money
+	MaxPrice     Money
+}
+
+// MenuSearchArgs reads the search_menu_items tool arguments
+func MenuSearchArgs(args map[string]interface{}) (MenuSearch, error) {
+	var q MenuSearch
+	if v, ok := args["restaurant_id"].(float64); ok {
+		q.RestaurantID = int(v)
+	}
+	for name, field := range map[string]*string{"query": &q.Query, "category": &q.Category, "dietary_type": &q.DietaryType, "spice_level": &q.SpiceLevel} {
+		v, _ := args[name].(string)
+		*field = strings.TrimSpace(v)
+	}
+	prices := []struct {
+		name  string
+		field *Money
+	}{{"min_price", &q.MinPrice}, {"max_price", &q.MaxPrice}}
+	for _, p := range prices {
+		if raw, ok := args[p.name]; ok {
+			v, ok := raw.(float64)
+			if !ok || math.IsInf(v, 0) {
+				return q, fmt.Errorf("%s must be a number", p.name)
+			}
+			*p.field = Rupees(v)
+		}
+	}
+	return q, q.Validate()
+}
+
+// Validate checks the restaurant, the dietary type and spice level values
+// and that the price range is in order
+func (q MenuSearch) Validate() error {
+	if q.RestaurantID <= 0 {
+		return fmt.Errorf("restaurant_id must be a positive integer")
+	}
+	if q.DietaryType != "" && !slices.Contains(DietaryTypes, q.DietaryType) {
+		return fmt.Errorf("invalid dietary_type %q: expected one of %s", q.DietaryType, strings.Join(DietaryTypes, ", "))
+	}
+	if q.SpiceLevel != "" && !slices.Contains(SpiceLevels, q.SpiceLevel) {
+		return fmt.Errorf("invalid spice_level %q: expected one of %s", q.SpiceLevel, strings.Join(SpiceLevels, ", "))
+	}
+	if q.MinPrice < 0 || q.MaxPrice < 0 {
+		return fmt.Errorf("min_price and max_price must not be negative")
+	}
+	if q.MaxPrice > 0 && q.MinPrice > q.MaxPrice {
+		return fmt.Errorf("min_price (%s) must not be above max_price (%s)", q.MinPrice.Decimal(), q.MaxPrice.Decimal())
+	}
+	return nil
+}
+
+// Matches reports whether m passes every filter of q other than the
+// restaurant
+func (q MenuSearch) Matches(m MenuItem) bool {
+	if q.Query != "" {
+		query := strings.ToLower(q.Query)
+		if !strings.Contains(strings.ToLower(m.Name), query) && !strings.Contains(strings.ToLower(m.Description), query) {
+			return false
+		}
+	}
+	return (q.Category == "" || strings.EqualFold(m.Category, q.Category)) &&
+		(q.DietaryType == "" || m.DietaryType == q.DietaryType) &&
+		(q.SpiceLevel == "" || m.SpiceLevel == q.SpiceLevel) &&
+		m.Price >= q.MinPrice && (q.MaxPrice == 0 || m.Price <= q.MaxPrice)
+}
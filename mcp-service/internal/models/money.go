@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of rupees held as whole paise, so prices and order
+// totals add up exactly. It is a JSON number and a DECIMAL column with two
+// decimals, and prints as ₹350.00.
+type Money int64
+
+// Rupees converts a rupee amount, such as a tool argument, to Money,
+// rounding to the nearest paisa
+func Rupees(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// ParseMoney reads a decimal rupee amount such as "350.5" or "-12.25"
+func ParseMoney(s string) (Money, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return Rupees(f), nil
+}
+
+// Rupees returns m as a number of rupees, for arithmetic that isn't money
+// such as averages and percentages
+func (m Money) Rupees() float64 {
+	return float64(m) / 100
+}
+
+// Decimal formats m with exactly two decimals and no currency sign, as in
+// 350.00
+func (m Money) Decimal() string {
+	sign := ""
+	paise := int64(m)
+	if paise < 0 {
+		sign, paise = "-", -paise
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, paise/100, paise%100)
+}
+
+// String formats m for tool text, as in ₹350.00
+func (m Money) String() string {
+	if m < 0 {
+		return "-₹" + (-m).Decimal()
+	}
+	return "₹" + m.Decimal()
+}
+
+// Times is the amount of quantity units at m each
+func (m Money) Times(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// MulRate is m multiplied by rate, such as a tax rate of 0.05, rounded to
+// the nearest paisa with halves away from zero
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Percent is percent of m, rounded as by MulRate
+func (m Money) Percent(percent float64) Money {
+	return Money(math.Round(float64(m) * percent / 100))
+}
+
+// MarshalJSON writes m as a number with two decimals
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON reads a number of rupees
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a DECIMAL column; NULL is zero
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case float64:
+		*m = Rupees(v)
+	case int64:
+		*m = Money(v * 100)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value writes m as a DECIMAL literal
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyFormats(t *testing.T) {
+	tests := []struct {
+		m               Money
+		decimal, string string
+	}{
+		{Rupees(350), "350.00", "₹350.00"},
+		{Rupees(0.5), "0.50", "₹0.50"},
+		{Rupees(104.9895), "104.99", "₹104.99"},
+		{0, "0.00", "₹0.00"},
+		{Rupees(-12.05), "-12.05", "-₹12.05"},
+	}
+	for _, tc := range tests {
+		if got := tc.m.Decimal(); got != tc.decimal {
+			t.Errorf("Decimal(%d) = %s, want %s", tc.m, got, tc.decimal)
+		}
+		if got := tc.m.String(); got != tc.string {
+			t.Errorf("String(%d) = %s, want %s", tc.m, got, tc.string)
+		}
+	}
+}
+
+func TestMoneyJSONHasTwoDecimals(t *testing.T) {
+	item := OrderItem{Quantity: 3, Price: Rupees(33.33), Subtotal: Rupees(99.99)}
+	data, err := json.Marshal(struct {
+		Price    Money `json:"price"`
+		Subtotal Money `json:"subtotal"`
+		Whole    Money `json:"whole"`
+	}{item.Price, item.Subtotal, Rupees(180)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":33.33,"subtotal":99.99,"whole":180.00}` {
+		t.Errorf("JSON %s", data)
+	}
+
+	var back struct {
+		Price Money `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(`{"price":104.98950000000001}`), &back); err != nil || back.Price != Rupees(104.99) {
+		t.Errorf("unmarshal = %v, %v; want ₹104.99", back.Price, err)
+	}
+	if err := json.Unmarshal([]byte(`{"price":"12"}`), &back); err == nil {
+		t.Error("unmarshalled a string")
+	}
+}
+
+func TestMoneyScansDecimalColumns(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Money
+	}{
+		{[]byte("350.00"), Rupees(350)},
+		{"12.5", Rupees(12.5)},
+		{99.99, Rupees(99.99)},
+		{int64(7), Rupees(7)},
+	}
+	for _, tc := range tests {
+		var m Money
+		if err := m.Scan(tc.src); err != nil || m != tc.want {
+			t.Errorf("Scan(%v) = %v, %v; want %v", tc.src, m, err, tc.want)
+		}
+	}
+	var m Money = 5
+	if err := m.Scan(nil); err != nil || m != 0 {
+		t.Errorf("Scan(nil) = %v, %v; want 0", m, err)
+	}
+	if err := m.Scan([]byte("abc")); err == nil {
+		t.Error("scanned abc")
+	}
+	if v, err := Rupees(33.3).Value(); err != nil || v != "33.30" {
+		t.Errorf("Value = %v, %v; want 33.30", v, err)
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "testing"
+
+func TestShouldNotifyFollowsPreferences(t *testing.T) {
+	r := Restaurant{Email: "orders@spicegarden.in", NotificationPreferences: DefaultNotificationPreferences()}
+	r.NotificationPreferences.NotifyOnLowStock = false
+	if !r.ShouldNotify(NotifyOrder) || r.ShouldNotify(NotifyLowStock) || r.ShouldNotify("refund") {
+		t.Errorf("with low stock turned off: order %v, low stock %v, unknown %v; want only order",
+			r.ShouldNotify(NotifyOrder), r.ShouldNotify(NotifyLowStock), r.ShouldNotify("refund"))
+	}
+	r.Email = ""
+	if r.ShouldNotify(NotifyOrder) {
+		t.Error("a restaurant without an email is notified")
+	}
+}
@@ -12,7 +12,7 @@ type User struct {
 	Provider       *string    `json:"provider,omitempty"` // Nullable
 	ProviderUserID *string    `json:"provider_user_id,omitempty"` // Nullable
 	Status         string     `json:"status"`            // active, inactive, suspended
-	Role           string     `json:"role"`              // admin, user
+	Role           string     `json:"role"`              // admin, owner, user (staff)
 	CreatedAt      time.Time  `json:"created_at"`
 	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
 	UpdatedAt      time.Time  `json:"updated_at"`
@@ -41,15 +41,32 @@ type OAuthClient struct {
 
 // OAuthToken represents token metadata for revocation
 type OAuthToken struct {
-	ID        int       `json:"id"`
-	TokenID   string    `json:"token_id"`
-	ClientID  string    `json:"client_id"`
-	UserID    string    `json:"user_id"`
-	TokenType string    `json:"token_type"` // access_token, refresh_token
-	Scope     string    `json:"scope"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	Active    bool      `json:"active"`
+	ID          int       `json:"id"`
+	TokenID     string    `json:"token_id"` // SHA-256 hex of the token_id claim once stored
+	ClientID    string    `json:"client_id"`
+	UserID      string    `json:"user_id"`
+	TokenType   string    `json:"token_type"` // access_token, refresh_token
+	Scope       string    `json:"scope"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	Active      bool      `json:"active"`
+	CreatedByIP string    `json:"created_by_ip,omitempty"` // For forensics only
+}
+
+// RestaurantTokenScope is the only scope a restaurant API token carries
+const RestaurantTokenScope = "orders:write"
+
+// RestaurantToken is an API token a restaurant's POS uses instead of an
+// OAuth login. Only the SHA-256 of the secret is stored.
+type RestaurantToken struct {
+	ID           int        `json:"id"`
+	RestaurantID int        `json:"restaurant_id"`
+	Name         string     `json:"name"`
+	CreatedBy    string     `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
 }
 
 // TokenResponse represents OAuth token response
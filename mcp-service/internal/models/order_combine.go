@@ -0,0 +1,161 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CancelReasonMerged is the cancellation reason of the orders MergeOrders
+// combines. It is set by the server and is not one of CancelReasons.
+const CancelReasonMerged = "merged"
+
+// CombineError explains why orders can't be split or merged as asked
+type CombineError struct {
+	Reason string
+}
+
+func (e *CombineError) Error() string {
+	return e.Reason
+}
+
+func combineErrorf(format string, args ...interface{}) error {
+	return &CombineError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// checkCombinable reports whether the order's items may still be moved
+// between bills: only before the kitchen starts and before it is paid
+func (o *Order) checkCombinable() error {
+	if !o.ItemsEditable() {
+		return combineErrorf("order %d is %s; only pending or confirmed orders can be split or merged", o.ID, o.Status)
+	}
+	if o.PaymentStatus == PaymentStatusCompleted || o.PaymentStatus == PaymentStatusRefunded {
+		return combineErrorf("order %d is already paid and can't be split or merged", o.ID)
+	}
+	return nil
+}
+
+// Split takes the given quantities of menu items off the order and returns
+// a new order for the same restaurant and customer holding them, at the
+// prices they were ordered at. The order must keep at least one item.
+// Totals are left for the caller to compute with the restaurant's charges;
+// any tip stays on o.
+func (o *Order) Split(moves []OrderItem) (Order, error) {
+	if err := o.checkCombinable(); err != nil {
+		return Order{}, err
+	}
+	if len(moves) == 0 {
+		return Order{}, combineErrorf("give at least one item to move off order %d", o.ID)
+	}
+
+	source := o.ID
+	split := Order{
+		RestaurantID:   o.RestaurantID,
+		CustomerName:   o.CustomerName,
+		CustomerPhone:  o.CustomerPhone,
+		OrderType:      o.OrderType,
+		Status:         o.Status,
+		PaymentStatus:  PaymentStatusPending,
+		PaymentMethod:  o.PaymentMethod,
+		BillingAddress: o.BillingAddress,
+		SplitFrom:      &source,
+	}
+	items := slices.Clone(o.OrderItems)
+	for _, move := range moves {
+		ordered := 0
+		for _, item := range items {
+			if item.MenuItemID == move.MenuItemID {
+				ordered += item.Quantity
+			}
+		}
+		if move.Quantity <= 0 || move.Quantity > ordered {
+			return Order{}, combineErrorf("order %d has %d of menu item %d; can't move %d", o.ID, ordered, move.MenuItemID, move.Quantity)
+		}
+		left := move.Quantity
+		for i := range items {
+			item := &items[i]
+			if item.MenuItemID != move.MenuItemID || item.Quantity == 0 || left == 0 {
+				continue
+			}
+			taken := min(item.Quantity, left)
+			item.Quantity -= taken
+			left -= taken
+			moved := *item
+			moved.ID, moved.OrderID, moved.Quantity = 0, 0, taken
+			split.OrderItems = append(split.OrderItems, moved)
+		}
+	}
+
+	items = slices.DeleteFunc(items, func(item OrderItem) bool { return item.Quantity == 0 })
+	if len(items) == 0 {
+		return Order{}, combineErrorf("moving every item would leave order %d empty", o.ID)
+	}
+	o.OrderItems = items
+	return split, nil
+}
+
+// MergeOrders combines dine-in orders of one restaurant, such as the tables
+// of a group, into a new order holding all their items at the prices they
+// were ordered at, their discounts and their tips. It takes the customer
+// and created time of the earliest order and lists its origins in
+// MergedFrom. It is pending if any origin still is. Totals are left for
+// the caller.
+func MergeOrders(orders []Order) (Order, error) {
+	if len(orders) < 2 {
+		return Order{}, combineErrorf("give at least two orders to merge")
+	}
+	orders = slices.Clone(orders)
+	slices.SortFunc(orders, func(a, b Order) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+
+	first := orders[0]
+	merged := Order{
+		RestaurantID:  first.RestaurantID,
+		CustomerName:  first.CustomerName,
+		CustomerPhone: first.CustomerPhone,
+		OrderType:     OrderTypeDineIn,
+		Status:        OrderStatusConfirmed,
+		PaymentStatus: PaymentStatusPending,
+		PaymentMethod: first.PaymentMethod,
+		CreatedAt:     first.CreatedAt,
+	}
+	for _, o := range orders {
+		if slices.Contains(merged.MergedFrom, o.ID) {
+			return Order{}, combineErrorf("order %d is listed twice", o.ID)
+		}
+		if err := o.checkCombinable(); err != nil {
+			return Order{}, err
+		}
+		if o.RestaurantID != first.RestaurantID {
+			return Order{}, combineErrorf("orders %d and %d are for different restaurants", first.ID, o.ID)
+		}
+		if o.OrderType != OrderTypeDineIn {
+			return Order{}, combineErrorf("order %d is a %s order; only dine-in orders can be merged", o.ID, o.OrderType)
+		}
+		if o.Status == OrderStatusPending {
+			merged.Status = OrderStatusPending
+		}
+		merged.Discount += o.Discount
+		merged.Tip += o.Tip
+		merged.MergedFrom = append(merged.MergedFrom, o.ID)
+		for _, item := range o.OrderItems {
+			item.ID, item.OrderID = 0, 0
+			merged.OrderItems = append(merged.OrderItems, item)
+		}
+	}
+	slices.Sort(merged.MergedFrom)
+	return merged, nil
+}
+
+// MergedFromText lists the origins of a merged order, as in "orders 3, 5"
+func (o *Order) MergedFromText() string {
+	ids := make([]string, len(o.MergedFrom))
+	for i, id := range o.MergedFrom {
+		ids[i] = fmt.Sprint(id)
+	}
+	return "orders " + strings.Join(ids, ", ")
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrderSplitMovesQuantitiesAtTheirPrices(t *testing.T) {
+	o := Order{ID: 3, RestaurantID: 1, CustomerName: "Asha Rao", OrderType: OrderTypeDineIn, Status: OrderStatusConfirmed, PaymentStatus: PaymentStatusPending,
+		OrderItems: []OrderItem{{ID: 10, MenuItemID: 1, Quantity: 2, Price: Rupees(90)}, {ID: 11, MenuItemID: 2, Quantity: 1, Price: Rupees(40)}}}
+
+	split, err := o.Split([]OrderItem{{MenuItemID: 1, Quantity: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if split.SplitFrom == nil || *split.SplitFrom != 3 || split.Status != OrderStatusConfirmed {
+		t.Errorf("split order %+v, want a confirmed order split from 3", split)
+	}
+	if len(split.OrderItems) != 1 || split.OrderItems[0].Quantity != 1 || split.OrderItems[0].Price != Rupees(90) || split.OrderItems[0].ID != 0 {
+		t.Errorf("split items %+v, want one new item at 90", split.OrderItems)
+	}
+	if len(o.OrderItems) != 2 || o.OrderItems[0].Quantity != 1 {
+		t.Errorf("source items %+v, want one of item 1 left", o.OrderItems)
+	}
+
+	var combine *CombineError
+	if _, err := o.Split([]OrderItem{{MenuItemID: 1, Quantity: 1}, {MenuItemID: 2, Quantity: 1}}); !errors.As(err, &combine) {
+		t.Errorf("moving every item = %v, want a CombineError", err)
+	}
+	if _, err := o.Split([]OrderItem{{MenuItemID: 2, Quantity: 5}}); !errors.As(err, &combine) {
+		t.Errorf("moving more than was ordered = %v, want a CombineError", err)
+	}
+	o.PaymentStatus = PaymentStatusCompleted
+	if _, err := o.Split([]OrderItem{{MenuItemID: 2, Quantity: 1}}); !errors.As(err, &combine) {
+		t.Errorf("splitting a paid order = %v, want a CombineError", err)
+	}
+}
+
+func TestMergeOrdersKeepsTheEarliestOrder(t *testing.T) {
+	now := time.Date(2026, 3, 14, 20, 0, 0, 0, time.UTC)
+	orders := []Order{
+		{ID: 5, RestaurantID: 1, CustomerName: "Ravi", OrderType: OrderTypeDineIn, Status: OrderStatusConfirmed, Discount: Rupees(10), CreatedAt: now,
+			OrderItems: []OrderItem{{ID: 20, MenuItemID: 2, Quantity: 1, Price: Rupees(40)}}},
+		{ID: 3, RestaurantID: 1, CustomerName: "Asha Rao", OrderType: OrderTypeDineIn, Status: OrderStatusPending, Discount: Rupees(5), CreatedAt: now.Add(-time.Hour),
+			OrderItems: []OrderItem{{ID: 10, MenuItemID: 1, Quantity: 2, Price: Rupees(90)}}},
+	}
+
+	merged, err := MergeOrders(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.CustomerName != "Asha Rao" || !merged.CreatedAt.Equal(now.Add(-time.Hour)) || merged.Status != OrderStatusPending || merged.Discount != Rupees(15) {
+		t.Errorf("merged order %+v, want Asha Rao's pending order with both discounts", merged)
+	}
+	if len(merged.OrderItems) != 2 || merged.MergedFromText() != "orders 3, 5" {
+		t.Errorf("merged %d items from %s, want 2 from orders 3, 5", len(merged.OrderItems), merged.MergedFromText())
+	}
+
+	var combine *CombineError
+	if _, err := MergeOrders(orders[:1]); !errors.As(err, &combine) {
+		t.Errorf("merging one order = %v, want a CombineError", err)
+	}
+	if _, err := MergeOrders([]Order{orders[0], orders[0]}); !errors.As(err, &combine) {
+		t.Errorf("merging an order with itself = %v, want a CombineError", err)
+	}
+	orders[1].OrderType = OrderTypeDelivery
+	if _, err := MergeOrders(orders); !errors.As(err, &combine) {
+		t.Errorf("merging a delivery order = %v, want a CombineError", err)
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Limits of an order digest, which is meant to be relayed verbatim
+const (
+	MaxDigestLength  = 1000 // characters of Text
+	LargeOrderFactor = 2    // an order is large from this many times the day's average
+	MaxLargeOrders   = 3
+	MaxSoldOutItems  = 5
+)
+
+// DigestQuery selects one day of a restaurant's orders to summarize. An
+// empty Date is today in the restaurant's timezone.
+type DigestQuery struct {
+	RestaurantID int
+	Date         string // YYYY-MM-DD
+}
+
+// DigestQueryArgs reads the summarize_orders tool arguments
+func DigestQueryArgs(args map[string]interface{}) (DigestQuery, error) {
+	var q DigestQuery
+	if v, ok := args["restaurant_id"].(float64); ok {
+		q.RestaurantID = int(v)
+	}
+	q.Date, _ = args["date"].(string)
+	return q, q.Validate()
+}
+
+// Validate checks the restaurant and, if given, the date
+func (q DigestQuery) Validate() error {
+	if q.RestaurantID <= 0 {
+		return fmt.Errorf("restaurant_id must be a positive integer")
+	}
+	if q.Date != "" {
+		if _, err := time.Parse(DateLayout, q.Date); err != nil {
+			return fmt.Errorf("invalid date %q: expected YYYY-MM-DD", q.Date)
+		}
+	}
+	return nil
+}
+
+// Day returns the date to summarize and the day before it, resolving an
+// empty Date to the date at now in loc
+func (q DigestQuery) Day(loc *time.Location, now time.Time) (day, previous string) {
+	d, err := time.Parse(DateLayout, q.Date)
+	if err != nil {
+		n := now.In(loc)
+		d = time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return d.Format(DateLayout), d.AddDate(0, 0, -1).Format(DateLayout)
+}
+
+// DigestOrder is a notable order of a digest
+type DigestOrder struct {
+	ID           int    `json:"id"`
+	CustomerName string `json:"customer_name"`
+	FinalAmount  Money  `json:"final_amount"`
+}
+
+// OrderDigest is the numbers behind a narrative summary of one day of a
+// restaurant's orders. Revenue leaves out cancelled orders.
+type OrderDigest struct {
+	RestaurantID      int            `json:"restaurant_id"`
+	RestaurantName    string         `json:"restaurant_name"`
+	Timezone          string         `json:"timezone"`
+	Date              string         `json:"date"`
+	Orders            int            `json:"orders"`
+	ByStatus          map[string]int `json:"by_status"`
+	Revenue           Money          `json:"revenue"`
+	PreviousRevenue   Money          `json:"previous_revenue"` // of the day before
+	BusiestHour       int            `json:"busiest_hour"`     // 0-23 local time; -1 without orders
+	BusiestHourOrders int            `json:"busiest_hour_orders"`
+	LargeOrders       []DigestOrder  `json:"large_orders"` // at most MaxLargeOrders, largest first
+	SoldOut           []string       `json:"sold_out"`     // items ordered that day and now unavailable
+	Refunds           int            `json:"refunds"`
+	RefundedAmount    Money          `json:"refunded_amount"`
+}
+
+// NewOrderDigest returns an empty digest of r's orders on date
+func NewOrderDigest(r Restaurant, date string) *OrderDigest {
+	return &OrderDigest{RestaurantID: r.ID, RestaurantName: r.Name, Timezone: r.Timezone, Date: date,
+		ByStatus: map[string]int{}, BusiestHour: -1, LargeOrders: []DigestOrder{}, SoldOut: []string{}}
+}
+
+// IsLarge reports whether an order of amount stands out against the day's
+// average order value
+func IsLarge(amount, average Money) bool {
+	return average > 0 && float64(amount) >= LargeOrderFactor*float64(average)
+}
+
+// Text renders the digest as a few sentences with the numbers formatted,
+// at most MaxDigestLength characters
+func (d OrderDigest) Text() string {
+	var sentences []string
+	day := d.Date
+	if t, err := time.Parse(DateLayout, d.Date); err == nil {
+		day = t.Format("Mon 2 Jan 2006")
+	}
+	if d.Orders == 0 {
+		sentences = append(sentences, fmt.Sprintf("%s had no orders on %s (%s).", d.RestaurantName, day, d.Timezone))
+		if d.PreviousRevenue > 0 {
+			sentences = append(sentences, fmt.Sprintf("The day before brought in %s.", d.PreviousRevenue))
+		}
+		return strings.Join(sentences, " ")
+	}
+
+	// Statuses in lifecycle order, then any unknown ones
+	order := slices.Clone(OrderStatuses)
+	for _, status := range slices.Sorted(maps.Keys(d.ByStatus)) {
+		if !slices.Contains(order, status) {
+			order = append(order, status)
+		}
+	}
+	var statuses []string
+	for _, status := range order {
+		if n := d.ByStatus[status]; n > 0 {
+			if status == "" {
+				status = "unknown"
+			}
+			statuses = append(statuses, fmt.Sprintf("%d %s", n, strings.ReplaceAll(status, "_", " ")))
+		}
+	}
+	sentences = append(sentences, fmt.Sprintf("%s took %s on %s (%s): %s.", d.RestaurantName, plural(d.Orders, "order"), day, d.Timezone, strings.Join(statuses, ", ")))
+
+	revenue := fmt.Sprintf("Revenue was %s", d.Revenue)
+	switch {
+	case d.PreviousRevenue == 0:
+		revenue += ", with none the day before."
+	case d.Revenue >= d.PreviousRevenue:
+		revenue += fmt.Sprintf(", up %.0f%% on the day before (%s).", percentChange(d.Revenue, d.PreviousRevenue), d.PreviousRevenue)
+	default:
+		revenue += fmt.Sprintf(", down %.0f%% on the day before (%s).", -percentChange(d.Revenue, d.PreviousRevenue), d.PreviousRevenue)
+	}
+	sentences = append(sentences, revenue)
+
+	if d.BusiestHour >= 0 {
+		sentences = append(sentences, fmt.Sprintf("The busiest hour was %02d:00-%02d:00 with %s.", d.BusiestHour, (d.BusiestHour+1)%24, plural(d.BusiestHourOrders, "order")))
+	}
+	if len(d.LargeOrders) > 0 {
+		large := make([]string, len(d.LargeOrders))
+		for i, o := range d.LargeOrders {
+			large[i] = fmt.Sprintf("#%d for %s (%s)", o.ID, o.CustomerName, o.FinalAmount)
+		}
+		sentences = append(sentences, "Large orders: "+strings.Join(large, ", ")+".")
+	}
+	if len(d.SoldOut) > 0 {
+		soldOut := d.SoldOut
+		more := ""
+		if len(soldOut) > MaxSoldOutItems {
+			soldOut, more = soldOut[:MaxSoldOutItems], fmt.Sprintf(" and %d more", len(soldOut)-MaxSoldOutItems)
+		}
+		sentences = append(sentences, "Now unavailable after selling: "+strings.Join(soldOut, ", ")+more+".")
+	}
+	if d.Refunds > 0 {
+		sentences = append(sentences, fmt.Sprintf("%s totalling %s.", plural(d.Refunds, "refund"), d.RefundedAmount))
+	}
+
+	// Drop whole sentences from the end rather than cutting one short
+	text := strings.Join(sentences, " ")
+	for len(sentences) > 1 && len([]rune(text)) > MaxDigestLength {
+		sentences = sentences[:len(sentences)-1]
+		text = strings.Join(sentences, " ")
+	}
+	return text
+}
+
+func percentChange(now, before Money) float64 {
+	return math.Round(float64(now-before) / float64(before) * 100)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestQueryDayDefaultsToTodayInTheRestaurantsTimezone(t *testing.T) {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 8, 20, 0, 0, 0, time.UTC) // 01:30 on the 9th in IST
+	if day, previous := (DigestQuery{RestaurantID: 1}).Day(ist, now); day != "2026-03-09" || previous != "2026-03-08" {
+		t.Errorf("today = %s after %s, want 2026-03-09 after 2026-03-08", day, previous)
+	}
+	if day, previous := (DigestQuery{RestaurantID: 1, Date: "2026-03-01"}).Day(ist, now); day != "2026-03-01" || previous != "2026-02-28" {
+		t.Errorf("given date = %s after %s, want 2026-03-01 after 2026-02-28", day, previous)
+	}
+}
+
+func TestOrderDigestText(t *testing.T) {
+	d := OrderDigest{RestaurantName: "Test Kitchen", Timezone: "Asia/Kolkata", Date: "2026-03-09",
+		Orders: 5, ByStatus: map[string]int{OrderStatusDelivered: 3, OrderStatusPending: 1, OrderStatusCancelled: 1},
+		Revenue: Rupees(1120), PreviousRevenue: Rupees(1000), BusiestHour: 13, BusiestHourOrders: 3,
+		LargeOrders: []DigestOrder{{ID: 5, CustomerName: "Asha Rao", FinalAmount: Rupees(600)}},
+		SoldOut:     []string{"Masala Dosa"}, Refunds: 1, RefundedAmount: Rupees(90)}
+
+	want := "Test Kitchen took 5 orders on Mon 9 Mar 2026 (Asia/Kolkata): 1 pending, 3 delivered, 1 cancelled. " +
+		"Revenue was ₹1120.00, up 12% on the day before (₹1000.00). The busiest hour was 13:00-14:00 with 3 orders. " +
+		"Large orders: #5 for Asha Rao (₹600.00). Now unavailable after selling: Masala Dosa. 1 refund totalling ₹90.00."
+	if got := d.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+
+	d.SoldOut = nil
+	for i := 0; i < 200; i++ {
+		d.SoldOut = append(d.SoldOut, strings.Repeat("Dish ", 40))
+	}
+	d.Refunds = 0
+	if got := d.Text(); len([]rune(got)) > MaxDigestLength || !strings.HasSuffix(got, "(₹600.00).") {
+		t.Errorf("long digest is %d characters ending %q", len([]rune(got)), got[len(got)-20:])
+	}
+
+	empty := OrderDigest{RestaurantName: "Test Kitchen", Timezone: "Asia/Kolkata", Date: "2026-03-09", PreviousRevenue: Rupees(1000)}
+	if got, want := empty.Text(), "Test Kitchen had no orders on Mon 9 Mar 2026 (Asia/Kolkata). The day before brought in ₹1000.00."; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// OrderExportQuery selects the orders a restaurant took between two dates
+// (YYYY-MM-DD, inclusive) of its own calendar, for export to accounting.
+// The range is bounded like a sales report's.
+type OrderExportQuery struct {
+	RestaurantID int
+	From         string
+	To           string
+}
+
+// OrderExportArgs reads the export_orders tool arguments
+func OrderExportArgs(args map[string]interface{}) (OrderExportQuery, error) {
+	q, err := SalesQueryArgs(args)
+	return OrderExportQuery(q), err
+}
+
+// Validate checks the restaurant and the dates as SalesQuery.Validate does
+func (q OrderExportQuery) Validate() error {
+	return SalesQuery(q).Validate()
+}
+
+// Range returns the instants the dates start and end at in the restaurant
+// timezone tz: from is the start of From, to the start of the day after To
+func (q OrderExportQuery) Range(tz string) (from, to time.Time) {
+	loc := restaurantLocation(tz)
+	fromDay, _ := time.ParseInLocation(DateLayout, q.From, loc)
+	toDay, _ := time.ParseInLocation(DateLayout, q.To, loc)
+	return fromDay, toDay.AddDate(0, 0, 1)
+}
+
+// OrderExportRow is one line item of an exported order, with the fields of
+// its order. An order without items is a single row whose Item is zero.
+type OrderExportRow struct {
+	Order    Order // without its OrderItems
+	Item     OrderItem
+	ItemName string
+}
+
+// OrderCSVHeader names the columns of OrderCSV, order columns first
+var OrderCSVHeader = []string{
+	"order_id", "created_at", "order_type", "status", "payment_status", "payment_method",
+	"customer_name", "customer_phone", "total_amount", "tax_amount", "discount", "service_charge", "tip", "final_amount",
+	"menu_item_id", "item_name", "quantity", "price", "subtotal", "notes",
+}
+
+// OrderCSV writes exported order rows as CSV, one line item per record,
+// with times in the restaurant's timezone. encoding/csv quotes customer
+// names and notes holding commas, quotes or line breaks.
+type OrderCSV struct {
+	w      *csv.Writer
+	loc    *time.Location
+	header bool
+	Rows   int // rows written, not counting the header
+}
+
+// NewOrderCSV writes to w in the restaurant timezone tz
+func NewOrderCSV(w io.Writer, tz string) *OrderCSV {
+	return &OrderCSV{w: csv.NewWriter(w), loc: restaurantLocation(tz)}
+}
+
+// Write adds r, after the header for the first row
+func (c *OrderCSV) Write(r OrderExportRow) error {
+	if !c.header {
+		if err := c.w.Write(OrderCSVHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	o := r.Order
+	record := []string{
+		strconv.Itoa(o.ID), o.CreatedAt.In(c.loc).Format(time.RFC3339), o.OrderType, o.Status, o.PaymentStatus, o.PaymentMethod,
+		o.CustomerName, o.CustomerPhone, o.TotalAmount.Decimal(), o.TaxAmount.Decimal(), o.Discount.Decimal(),
+		o.ServiceCharge.Decimal(), o.Tip.Decimal(), o.FinalAmount.Decimal(),
+		"", "", "", "", "", "",
+	}
+	if item := r.Item; item.MenuItemID != 0 {
+		copy(record[14:], []string{strconv.Itoa(item.MenuItemID), r.ItemName, strconv.Itoa(item.Quantity),
+			item.Price.Decimal(), item.Subtotal.Decimal(), item.Notes})
+	}
+	c.Rows++
+	return c.w.Write(record)
+}
+
+// Flush writes out what is buffered, the header too when there were no
+// rows, and returns any error writing failed with
+func (c *OrderCSV) Flush() error {
+	if !c.header {
+		c.w.Write(OrderCSVHeader)
+		c.header = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
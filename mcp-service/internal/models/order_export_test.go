@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderCSVQuotesNamesAndNotes(t *testing.T) {
+	var b strings.Builder
+	c := NewOrderCSV(&b, "Asia/Kolkata")
+	o := Order{ID: 12, CreatedAt: time.Date(2026, 3, 8, 20, 0, 0, 0, time.UTC), OrderType: OrderTypeDelivery, Status: OrderStatusDelivered,
+		PaymentStatus: PaymentStatusPending, CustomerName: `Rao, Asha "Ashu"`, TotalAmount: Rupees(350), FinalAmount: Rupees(367.5)}
+	rows := []OrderExportRow{
+		{Order: o, Item: OrderItem{MenuItemID: 3, Quantity: 2, Price: Rupees(175), Subtotal: Rupees(350), Notes: "less spicy,\nno onion"}, ItemName: "Masala Dosa"},
+		{Order: Order{ID: 13, CreatedAt: o.CreatedAt}},
+	}
+	for _, r := range rows {
+		if err := c.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("reading back %q: %v", b.String(), err)
+	}
+	if len(records) != 3 || c.Rows != 2 || strings.Join(records[0], ",") != strings.Join(OrderCSVHeader, ",") {
+		t.Fatalf("records %q, %d rows; want the header and 2 rows", records, c.Rows)
+	}
+	item := records[1]
+	if item[1] != "2026-03-09T01:30:00+05:30" || item[6] != `Rao, Asha "Ashu"` || item[13] != "367.50" || item[15] != "Masala Dosa" || item[19] != "less spicy,\nno onion" {
+		t.Errorf("item row %q", item)
+	}
+	if empty := records[2]; empty[0] != "13" || empty[14] != "" || empty[16] != "" {
+		t.Errorf("row of an order without items %q", empty)
+	}
+}
+
+func TestOrderCSVWritesAHeaderWithoutRows(t *testing.T) {
+	var b strings.Builder
+	if err := NewOrderCSV(&b, "").Flush(); err != nil || b.String() != strings.Join(OrderCSVHeader, ",")+"\n" {
+		t.Errorf("empty export %q, %v", b.String(), err)
+	}
+}
+
+func TestOrderExportQueryRangeCoversWholeLocalDays(t *testing.T) {
+	from, to := OrderExportQuery{RestaurantID: 1, From: "2026-03-01", To: "2026-03-31"}.Range("Asia/Kolkata")
+	if got := from.UTC().Format(time.RFC3339); got != "2026-02-28T18:30:00Z" {
+		t.Errorf("from = %s", got)
+	}
+	if got := to.UTC().Format(time.RFC3339); got != "2026-03-31T18:30:00Z" {
+		t.Errorf("to = %s", got)
+	}
+}
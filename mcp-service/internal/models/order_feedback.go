@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Satisfaction score bounds for order feedback
+const (
+	MinFeedbackScore = 1
+	MaxFeedbackScore = 5
+)
+
+// OrderFeedback is the satisfaction staff recorded for a delivered order,
+// separate from public reviews
+type OrderFeedback struct {
+	OrderID    int       `json:"order_id"`
+	Score      int       `json:"score"`
+	Comment    string    `json:"comment,omitempty"`
+	Contacted  bool      `json:"contacted"` // the customer was contacted about it
+	RecordedBy string    `json:"recorded_by"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Validate checks the score range
+func (f OrderFeedback) Validate() error {
+	if f.Score < MinFeedbackScore || f.Score > MaxFeedbackScore {
+		return fmt.Errorf("score must be a whole number from %d to %d", MinFeedbackScore, MaxFeedbackScore)
+	}
+	return nil
+}
+
+// OrderFeedbackArgs reads the record_order_feedback tool arguments
+func OrderFeedbackArgs(args map[string]interface{}) (OrderFeedback, error) {
+	var f OrderFeedback
+	orderID, ok := args["order_id"].(float64)
+	if !ok || orderID != float64(int(orderID)) {
+		return f, fmt.Errorf("missing or invalid order_id")
+	}
+	f.OrderID = int(orderID)
+	if score, ok := args["score"].(float64); ok && score == float64(int(score)) {
+		f.Score = int(score)
+	}
+	f.Comment, _ = args["comment"].(string)
+	f.Contacted, _ = args["contacted"].(bool)
+	f.RecordedBy, _ = args["recorded_by"].(string)
+	return f, f.Validate()
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderStatusChange is one step of an order's status timeline. FromStatus
+// is empty for the status the order was created in. Note explains steps
+// that are more than a status change, such as a split or merge, and may
+// come with the status unchanged.
+type OrderStatusChange struct {
+	OrderID    int       `json:"order_id"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+	ChangedBy  string    `json:"changed_by"`
+	Note       string    `json:"note,omitempty"`
+}
+
+// OrderTimelineText renders an order's status changes, oldest first, one
+// line each, with times in the restaurant's timezone tz
+func OrderTimelineText(orderID int, changes []OrderStatusChange, tz string) string {
+	if len(changes) == 0 {
+		return fmt.Sprintf("No status history recorded for order %d", orderID)
+	}
+	lines := []string{fmt.Sprintf("Status history of order %d (times in %s):", orderID, LocalTime(tz, time.Now()).Location())}
+	for _, c := range changes {
+		step := "created " + c.ToStatus
+		switch {
+		case c.FromStatus == c.ToStatus:
+			step = "still " + c.ToStatus
+		case c.FromStatus != "":
+			step = c.FromStatus + " -> " + c.ToStatus
+		}
+		if c.Note != "" {
+			step += " (" + c.Note + ")"
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s by %s", LocalTime(tz, c.ChangedAt).Format("2006-01-02 15:04:05"), step, c.ChangedBy))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// List page sizes
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Page selects a slice of a list result
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// NewPage validates a requested page. A zero limit means DefaultPageSize
+// and limits above MaxPageSize are capped rather than rejected.
+func NewPage(limit, offset int) (Page, error) {
+	if limit < 0 {
+		return Page{}, fmt.Errorf("limit must not be negative")
+	}
+	if offset < 0 {
+		return Page{}, fmt.Errorf("offset must not be negative")
+	}
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	return Page{Limit: limit, Offset: offset}, nil
+}
+
+// PageArgs reads the optional limit and offset tool arguments
+func PageArgs(args map[string]interface{}) (Page, error) {
+	var values [2]int
+	for i, name := range []string{"limit", "offset"} {
+		raw, ok := args[name]
+		if !ok || raw == nil {
+			continue
+		}
+		v, ok := raw.(float64)
+		if !ok || v != math.Trunc(v) {
+			return Page{}, fmt.Errorf("%s must be an integer", name)
+		}
+		values[i] = int(v)
+	}
+	return NewPage(values[0], values[1])
+}
+
+// Summary tells the reader of a list result which slice they got and how
+// to fetch the next one
+func (p Page) Summary(noun string, shown, total int) string {
+	switch {
+	case total == 0:
+		return fmt.Sprintf("No %s found.", noun)
+	case shown == 0:
+		return fmt.Sprintf("No %s at offset %d; there are %d in total.", noun, p.Offset, total)
+	}
+	summary := fmt.Sprintf("Showing %s %d-%d of %d.", noun, p.Offset+1, p.Offset+shown, total)
+	if next := p.Offset + shown; next < total {
+		summary += fmt.Sprintf(" Pass offset=%d for the next page.", next)
+	}
+	return summary
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PriceChange is one row of a menu item's price history
+type PriceChange struct {
+	ID           int       `json:"id"`
+	ItemID       int       `json:"item_id"`
+	ItemName     string    `json:"item_name"`
+	RestaurantID int       `json:"restaurant_id"`
+	OldPrice     Money     `json:"old_price"`
+	NewPrice     Money     `json:"new_price"`
+	ChangedBy    string    `json:"changed_by"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// PriceHistoryQuery selects price changes of one item or of every item of a
+// restaurant, optionally between two dates (YYYY-MM-DD, inclusive)
+type PriceHistoryQuery struct {
+	ItemID       int
+	RestaurantID int
+	From         string
+	To           string
+}
+
+// PriceHistoryArgs reads the get_price_history tool arguments
+func PriceHistoryArgs(args map[string]interface{}) (PriceHistoryQuery, error) {
+	var q PriceHistoryQuery
+	if v, ok := args["menu_item_id"].(float64); ok {
+		q.ItemID = int(v)
+	}
+	if v, ok := args["restaurant_id"].(float64); ok {
+		q.RestaurantID = int(v)
+	}
+	q.From, _ = args["from"].(string)
+	q.To, _ = args["to"].(string)
+	return q, q.Validate()
+}
+
+// Validate checks that exactly one of ItemID and RestaurantID is set and
+// that the dates parse in order
+func (q PriceHistoryQuery) Validate() error {
+	if (q.ItemID > 0) == (q.RestaurantID > 0) {
+		return fmt.Errorf("pass either menu_item_id or restaurant_id")
+	}
+	return validateDateRange(q.From, q.To)
+}
+
+// validateDateRange checks that the dates, either of which may be empty,
+// parse and that from is not after to
+func validateDateRange(from, to string) error {
+	var fromDate, toDate time.Time
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse(DateLayout, from); err != nil {
+			return fmt.Errorf("invalid from %q: expected YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(DateLayout, to); err != nil {
+			return fmt.Errorf("invalid to %q: expected YYYY-MM-DD", to)
+		}
+	}
+	if from != "" && to != "" && fromDate.After(toDate) {
+		return fmt.Errorf("from (%s) must not be after to (%s)", from, to)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Weekdays are the days_of_week values of a pricing rule, in time.Weekday
+// order
+var Weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ClockLayout is the format of pricing rule start and end times
+const ClockLayout = "15:04"
+
+// PricingRule takes PercentOff off the price of matching menu items while
+// the restaurant's local time is between StartTime and EndTime on one of
+// DaysOfWeek, e.g. 20% off beverages from 16:00 to 18:00. A window whose end
+// is before its start runs past midnight and belongs to the day it starts
+// on. A rule with neither Category nor MenuItemID covers the whole menu.
+type PricingRule struct {
+	ID           int       `json:"id"`
+	RestaurantID int       `json:"restaurant_id"`
+	Name         string    `json:"name"`
+	Category     string    `json:"category,omitempty"`     // matched ignoring case
+	MenuItemID   int       `json:"menu_item_id,omitempty"` // 0 for any item
+	PercentOff   float64   `json:"percent_off"`
+	DaysOfWeek   []string  `json:"days_of_week"` // see Weekdays; empty means every day
+	StartTime    string    `json:"start_time"`   // HH:MM, inclusive
+	EndTime      string    `json:"end_time"`     // HH:MM, exclusive
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Validate checks the discount, the days and the time window
+func (r PricingRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.RestaurantID <= 0 {
+		return fmt.Errorf("restaurant_id must be a positive integer")
+	}
+	if r.MenuItemID < 0 {
+		return fmt.Errorf("menu_item_id must be a positive integer")
+	}
+	if !(r.PercentOff > 0 && r.PercentOff <= 100) {
+		return fmt.Errorf("percent_off must be greater than 0 and at most 100")
+	}
+	for _, d := range r.DaysOfWeek {
+		if weekday(d) < 0 {
+			return fmt.Errorf("invalid day %q in days_of_week: expected one of %s", d, strings.Join(Weekdays, ", "))
+		}
+	}
+	start, err := time.Parse(ClockLayout, r.StartTime)
+	if err != nil {
+		return fmt.Errorf("invalid start_time %q: expected HH:MM", r.StartTime)
+	}
+	end, err := time.Parse(ClockLayout, r.EndTime)
+	if err != nil {
+		return fmt.Errorf("invalid end_time %q: expected HH:MM", r.EndTime)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("start_time and end_time must differ")
+	}
+	return nil
+}
+
+func weekday(day string) time.Weekday {
+	for i, d := range Weekdays {
+		if d == day {
+			return time.Weekday(i)
+		}
+	}
+	return -1
+}
+
+// minuteOfDay parses an HH:MM clock time, which Validate has checked
+func minuteOfDay(clock string) int {
+	t, _ := time.Parse(ClockLayout, clock)
+	return t.Hour()*60 + t.Minute()
+}
+
+// ActiveAt reports whether the rule's window covers local, a time in the
+// restaurant's timezone. Inactive rules never apply.
+func (r PricingRule) ActiveAt(local time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	now := local.Hour()*60 + local.Minute()
+	start, end := minuteOfDay(r.StartTime), minuteOfDay(r.EndTime)
+	day := local.Weekday()
+	if end < start {
+		// Past midnight the window still belongs to the day before
+		if now < end {
+			day = (day + 6) % 7
+		} else if now < start {
+			return false
+		}
+	} else if now < start || now >= end {
+		return false
+	}
+	return r.onDay(day)
+}
+
+func (r PricingRule) onDay(day time.Weekday) bool {
+	if len(r.DaysOfWeek) == 0 {
+		return true
+	}
+	for _, d := range r.DaysOfWeek {
+		if weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the rule covers the menu item
+func (r PricingRule) Matches(m MenuItem) bool {
+	if r.RestaurantID != m.RestaurantID {
+		return false
+	}
+	if r.MenuItemID != 0 && r.MenuItemID != m.ID {
+		return false
+	}
+	return r.Category == "" || strings.EqualFold(r.Category, m.Category)
+}
+
+// Apply returns price with the discount taken off, rounded to paise
+func (r PricingRule) Apply(price Money) Money {
+	return price.Percent(100 - r.PercentOff)
+}
+
+// BestPricingRule returns the rule giving the biggest discount on m at
+// local, or nil when none applies. Rules don't stack; on a tie the oldest
+// rule wins.
+func BestPricingRule(rules []PricingRule, m MenuItem, local time.Time) *PricingRule {
+	var best *PricingRule
+	for i := range rules {
+		r := &rules[i]
+		if !r.Matches(m) || !r.ActiveAt(local) {
+			continue
+		}
+		if best == nil || r.PercentOff > best.PercentOff || (r.PercentOff == best.PercentOff && r.ID < best.ID) {
+			best = r
+		}
+	}
+	return best
+}
+
+// LocalTime returns t in the restaurant timezone tz, falling back to India
+// time for an empty or unknown zone like the SQL menu queries do
+func LocalTime(tz string, t time.Time) time.Time {
+	return t.In(restaurantLocation(tz))
+}
+
+// restaurantLocation loads the restaurant timezone tz with LocalTime's
+// fallback
+func restaurantLocation(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if tz == "" || err != nil {
+		loc, err = time.LoadLocation("Asia/Kolkata")
+		if err != nil {
+			loc = time.FixedZone("IST", 5*60*60+30*60)
+		}
+	}
+	return loc
+}
+
+// PricedMenuItem is a menu item with the price it sells for right now
+type PricedMenuItem struct {
+	MenuItem
+	EffectivePrice Money  `json:"effective_price"`
+	PricingRuleID  int    `json:"pricing_rule_id,omitempty"`
+	Promotion      string `json:"promotion,omitempty"` // name of the rule applied
+}
+
+// PriceMenu prices items with rules at local and describes the promotions
+// running, for get_menu's current_promotions note
+func PriceMenu(items []MenuItem, rules []PricingRule, local time.Time) ([]PricedMenuItem, []string) {
+	priced := make([]PricedMenuItem, len(items))
+	running := map[int]bool{}
+	for i, m := range items {
+		priced[i] = PricedMenuItem{MenuItem: m, EffectivePrice: m.Price}
+		if r := BestPricingRule(rules, m, local); r != nil {
+			priced[i].EffectivePrice = r.Apply(m.Price)
+			priced[i].PricingRuleID, priced[i].Promotion = r.ID, r.Name
+			running[r.ID] = true
+		}
+	}
+	notes := []string{}
+	for _, r := range rules {
+		if running[r.ID] {
+			notes = append(notes, r.Describe())
+		}
+	}
+	return priced, notes
+}
+
+// Describe summarises the rule, e.g. "Happy hour: 20% off Beverage,
+// 16:00-18:00 on mon, tue"
+func (r PricingRule) Describe() string {
+	target := "the whole menu"
+	switch {
+	case r.MenuItemID != 0:
+		target = fmt.Sprintf("menu item %d", r.MenuItemID)
+	case r.Category != "":
+		target = r.Category
+	}
+	days := "every day"
+	if len(r.DaysOfWeek) > 0 {
+		days = "on " + strings.Join(r.DaysOfWeek, ", ")
+	}
+	return fmt.Sprintf("%s: %g%% off %s, %s-%s %s", r.Name, r.PercentOff, target, r.StartTime, r.EndTime, days)
+}
+
+// PricingRuleArgs reads the create_pricing_rule tool arguments
+func PricingRuleArgs(args map[string]interface{}) (PricingRule, error) {
+	r := PricingRule{Active: true}
+	if v, ok := args["restaurant_id"].(float64); ok && v == math.Trunc(v) {
+		r.RestaurantID = int(v)
+	}
+	if err := r.applyArgs(args); err != nil {
+		return r, err
+	}
+	return r, r.Validate()
+}
+
+// ApplyPricingRuleArgs sets the update_pricing_rule arguments present in
+// args on r
+func ApplyPricingRuleArgs(r *PricingRule, args map[string]interface{}) error {
+	if err := r.applyArgs(args); err != nil {
+		return err
+	}
+	return r.Validate()
+}
+
+func (r *PricingRule) applyArgs(args map[string]interface{}) error {
+	if v, ok := args["name"].(string); ok {
+		r.Name = v
+	}
+	if v, ok := args["category"].(string); ok {
+		r.Category = v
+	}
+	if v, ok := args["menu_item_id"]; ok {
+		id, ok := v.(float64)
+		if !ok || id != math.Trunc(id) {
+			return fmt.Errorf("menu_item_id must be an integer")
+		}
+		r.MenuItemID = int(id)
+	}
+	if v, ok := args["percent_off"]; ok {
+		if r.PercentOff, ok = v.(float64); !ok {
+			return fmt.Errorf("percent_off must be a number")
+		}
+	}
+	if v, ok := args["days_of_week"]; ok {
+		days, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("days_of_week must be an array of days, e.g. [\"sat\", \"sun\"]")
+		}
+		r.DaysOfWeek = []string{}
+		for _, d := range days {
+			day, _ := d.(string)
+			r.DaysOfWeek = append(r.DaysOfWeek, strings.ToLower(day))
+		}
+	}
+	if v, ok := args["start_time"].(string); ok {
+		r.StartTime = v
+	}
+	if v, ok := args["end_time"].(string); ok {
+		r.EndTime = v
+	}
+	if v, ok := args["active"].(bool); ok {
+		r.Active = v
+	}
+	return nil
+}
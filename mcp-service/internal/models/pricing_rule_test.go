@@ -0,0 +1,113 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPricingRuleActiveAtAroundMidnight(t *testing.T) {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2026-10-16 is a Friday
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 10, day, hour, min, 0, 0, ist) }
+	lateNight := PricingRule{Name: "Late night", PercentOff: 10, DaysOfWeek: []string{"fri"}, StartTime: "22:00", EndTime: "02:00", Active: true}
+	happyHour := PricingRule{Name: "Happy hour", PercentOff: 20, StartTime: "16:00", EndTime: "18:00", Active: true}
+	untilMidnight := PricingRule{Name: "Last orders", PercentOff: 15, DaysOfWeek: []string{"fri"}, StartTime: "23:00", EndTime: "00:00", Active: true}
+
+	tests := []struct {
+		name  string
+		rule  PricingRule
+		local time.Time
+		want  bool
+	}{
+		{"friday before the window", lateNight, at(16, 21, 59), false},
+		{"friday start is inclusive", lateNight, at(16, 22, 0), true},
+		{"friday just before midnight", lateNight, at(16, 23, 59), true},
+		{"saturday midnight belongs to friday", lateNight, at(17, 0, 0), true},
+		{"saturday early hours", lateNight, at(17, 1, 30), true},
+		{"end is exclusive", lateNight, at(17, 2, 0), false},
+		{"saturday night is not friday's window", lateNight, at(17, 22, 30), false},
+		{"friday early hours belong to thursday", lateNight, at(16, 1, 0), false},
+		{"daily window", happyHour, at(18, 16, 30), true},
+		{"daily window end", happyHour, at(18, 18, 0), false},
+		{"window ending at midnight", untilMidnight, at(16, 23, 30), true},
+		{"window ending at midnight is over", untilMidnight, at(17, 0, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.ActiveAt(tt.local); got != tt.want {
+			t.Errorf("%s: %s ActiveAt(%s) = %v, want %v", tt.name, tt.rule.Describe(), tt.local.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	paused := happyHour
+	paused.Active = false
+	if paused.ActiveAt(at(16, 17, 0)) {
+		t.Error("inactive rule applied")
+	}
+}
+
+func TestLocalTimeUsesRestaurantTimezone(t *testing.T) {
+	// 20:00 UTC on a Friday is already Saturday 01:30 in India
+	utc := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	rule := PricingRule{Name: "Late night", PercentOff: 10, DaysOfWeek: []string{"fri"}, StartTime: "22:00", EndTime: "02:00", Active: true}
+	if !rule.ActiveAt(LocalTime("Asia/Kolkata", utc)) {
+		t.Error("rule not running at 01:30 Saturday in Kolkata")
+	}
+	if !rule.ActiveAt(LocalTime("", utc)) {
+		t.Error("an empty timezone should fall back to India time")
+	}
+	if rule.ActiveAt(LocalTime("Europe/London", utc)) {
+		t.Error("rule running at 21:00 Friday in London")
+	}
+}
+
+func TestBestPricingRuleWins(t *testing.T) {
+	local := time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC)
+	lassi := MenuItem{ID: 7, RestaurantID: 1, Category: "Beverage", Price: Rupees(120)}
+	rules := []PricingRule{
+		{ID: 1, RestaurantID: 1, Name: "Menu wide", PercentOff: 10, StartTime: "12:00", EndTime: "23:00", Active: true},
+		{ID: 2, RestaurantID: 1, Name: "Happy hour", Category: "beverage", PercentOff: 20, StartTime: "16:00", EndTime: "18:00", Active: true},
+		{ID: 3, RestaurantID: 1, Name: "Same discount", MenuItemID: 7, PercentOff: 20, StartTime: "16:00", EndTime: "18:00", Active: true},
+		{ID: 4, RestaurantID: 1, Name: "Desserts", Category: "Dessert", PercentOff: 50, StartTime: "16:00", EndTime: "18:00", Active: true},
+		{ID: 5, RestaurantID: 2, Name: "Other restaurant", PercentOff: 90, StartTime: "16:00", EndTime: "18:00", Active: true},
+	}
+
+	best := BestPricingRule(rules, lassi, local)
+	if best == nil || best.ID != 2 {
+		t.Fatalf("best rule = %+v, want rule 2", best)
+	}
+	if got := best.Apply(lassi.Price); got != Rupees(96) {
+		t.Errorf("price = %v, want 96", got)
+	}
+
+	priced, notes := PriceMenu([]MenuItem{lassi, {ID: 8, RestaurantID: 1, Category: "Main Course", Price: Rupees(250)}}, rules, local)
+	if priced[0].EffectivePrice != Rupees(96) || priced[0].PricingRuleID != 2 || priced[1].EffectivePrice != Rupees(225) || priced[1].PricingRuleID != 1 {
+		t.Errorf("priced menu = %+v", priced)
+	}
+	if len(notes) != 2 {
+		t.Errorf("current promotions = %q, want rules 1 and 2", notes)
+	}
+}
+
+func TestPricingRuleValidate(t *testing.T) {
+	valid := PricingRule{RestaurantID: 1, Name: "Happy hour", PercentOff: 20, StartTime: "16:00", EndTime: "18:00"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid rule: %v", err)
+	}
+	for name, change := range map[string]func(*PricingRule){
+		"no discount":    func(r *PricingRule) { r.PercentOff = 0 },
+		"over 100%":      func(r *PricingRule) { r.PercentOff = 120 },
+		"unknown day":    func(r *PricingRule) { r.DaysOfWeek = []string{"friday"} },
+		"bad start time": func(r *PricingRule) { r.StartTime = "4pm" },
+		"empty window":   func(r *PricingRule) { r.EndTime = r.StartTime },
+		"no name":        func(r *PricingRule) { r.Name = " " },
+	} {
+		r := valid
+		change(&r)
+		if err := r.Validate(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
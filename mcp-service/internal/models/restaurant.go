@@ -0,0 +1,989 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Restaurant represents a restaurant outlet
+type Restaurant struct {
+	ID                      int                     `json:"id"`
+	Name                    string                  `json:"name"`
+	Address                 string                  `json:"address"`
+	PhoneNumber             string                  `json:"phone_number"`
+	Email                   string                  `json:"email"` // Not unique: outlets of a chain may share one
+	CuisineType             string                  `json:"cuisine_type"`
+	Timezone                string                  `json:"timezone"` // IANA name; decides which menu items are effective "today"
+	NotificationPreferences NotificationPreferences `json:"notification_preferences"`
+	PublicMenuEnabled       bool                    `json:"public_menu_enabled"`    // menu is served at /public/restaurants/{id}/menu
+	AutoConfirmOrders       bool                    `json:"auto_confirm_orders"`    // new orders are created confirmed
+	TaxRate                 *float64                `json:"tax_rate"`               // GST on orders as a fraction; nil charges DefaultTaxRate
+	ServiceChargePercent    *float64                `json:"service_charge_percent"` // added to dine-in orders; nil charges none
+	Active                  bool                    `json:"active"`                 // false once deleted without hard_delete; see RestaurantDeleteOptions
+	Version                 int                     `json:"version"`                // raised by every update; see VersionConflictError in storage
+	CreatedAt               time.Time               `json:"created_at"`
+	UpdatedAt               time.Time               `json:"updated_at"`
+}
+
+// Restaurant list sort orders
+const (
+	RestaurantSortID          = "id"
+	RestaurantSortName        = "name"
+	RestaurantSortCreatedAt   = "created_at"
+	RestaurantSortCuisineType = "cuisine_type"
+)
+
+// RestaurantSorts lists the accepted restaurant sort orders
+var RestaurantSorts = []string{RestaurantSortID, RestaurantSortName, RestaurantSortCreatedAt, RestaurantSortCuisineType}
+
+// ValidateRestaurantSort accepts one of RestaurantSorts, or empty for by ID
+func ValidateRestaurantSort(sort string) error {
+	if sort == "" {
+		return nil
+	}
+	for _, s := range RestaurantSorts {
+		if s == sort {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sort %q: must be one of %s", sort, strings.Join(RestaurantSorts, ", "))
+}
+
+// DefaultRestaurantSearchLimit caps restaurant search results when no
+// limit is given; larger limits are capped at MaxPageSize
+const DefaultRestaurantSearchLimit = 20
+
+// RestaurantSearchLimit turns a requested search limit into the one used,
+// rejecting negative values
+func RestaurantSearchLimit(limit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, fmt.Errorf("limit must not be negative")
+	case limit == 0:
+		return DefaultRestaurantSearchLimit, nil
+	case limit > MaxPageSize:
+		return MaxPageSize, nil
+	}
+	return limit, nil
+}
+
+// RestaurantSearchArgs reads the search_restaurants tool arguments. The
+// limit is validated and defaulted as by RestaurantSearchLimit.
+func RestaurantSearchArgs(args map[string]interface{}) (query, cuisine string, limit int, err error) {
+	query, _ = args["query"].(string)
+	cuisine, _ = args["cuisine"].(string)
+	if raw, ok := args["limit"]; ok && raw != nil {
+		v, ok := raw.(float64)
+		if !ok || v != float64(int(v)) {
+			return "", "", 0, fmt.Errorf("limit must be an integer")
+		}
+		limit = int(v)
+	}
+	limit, err = RestaurantSearchLimit(limit)
+	return strings.TrimSpace(query), strings.TrimSpace(cuisine), limit, err
+}
+
+// MatchesRestaurantSearch reports whether r has query in its name or address,
+// case-insensitively, and cuisine as its cuisine type, ignoring case. Empty
+// query and cuisine match every restaurant.
+func MatchesRestaurantSearch(r Restaurant, query, cuisine string) bool {
+	query = strings.ToLower(query)
+	return (query == "" || strings.Contains(strings.ToLower(r.Name), query) || strings.Contains(strings.ToLower(r.Address), query)) &&
+		(cuisine == "" || strings.EqualFold(r.CuisineType, cuisine))
+}
+
+// RestaurantPatch is a partial restaurant update. Empty strings keep the
+// stored value, and only the notification flags present are changed.
+type RestaurantPatch struct {
+	Name                    string
+	Address                 string
+	PhoneNumber             string
+	Email                   string
+	CuisineType             string
+	NotificationPreferences map[string]bool
+	PublicMenuEnabled       *bool    // nil keeps the current setting
+	AutoConfirmOrders       *bool    // nil keeps the current setting
+	TaxRate                 *float64 // nil keeps the current rate
+	ServiceChargePercent    *float64 // nil keeps the current percent
+	ExpectedVersion         *int     // when set, the patch only applies to this version
+}
+
+// RestaurantDeleteOptions choose how a restaurant is deleted. By default it
+// is only deactivated: hidden from listings and search and closed to new
+// orders, with its menu and order history kept.
+type RestaurantDeleteOptions struct {
+	Hard  bool // remove the row instead
+	Force bool // with Hard, also remove its menu items and orders
+}
+
+// RestaurantDeletion is what deleting a restaurant removed along with it
+type RestaurantDeletion struct {
+	RestaurantID int  `json:"restaurant_id"`
+	Deactivated  bool `json:"deactivated"` // soft deleted, nothing removed
+	MenuItems    int  `json:"menu_items_deleted"`
+	Orders       int  `json:"orders_deleted"`
+	OpenOrders   int  `json:"open_orders_deleted"` // of Orders, those not yet delivered or cancelled
+}
+
+// String says what was removed, e.g. "Restaurant 4 deleted with 12 menu
+// items and 3 orders (1 still open)"
+func (d RestaurantDeletion) String() string {
+	if d.Deactivated {
+		return fmt.Sprintf("Restaurant %d deactivated: it no longer appears in listings or search or takes orders, and its menu and order history are kept", d.RestaurantID)
+	}
+	if d.MenuItems == 0 && d.Orders == 0 {
+		return fmt.Sprintf("Restaurant %d deleted", d.RestaurantID)
+	}
+	orders := plural(d.Orders, "order")
+	if d.OpenOrders > 0 {
+		orders += fmt.Sprintf(" (%d still open)", d.OpenOrders)
+	}
+	return fmt.Sprintf("Restaurant %d deleted with %s and %s", d.RestaurantID, plural(d.MenuItems, "menu item"), orders)
+}
+
+// Notification events a restaurant can opt in or out of
+const (
+	NotifyOrder    = "order"
+	NotifyLowStock = "low_stock"
+)
+
+// NotificationPreferences is stored as JSONB on the restaurants table
+type NotificationPreferences struct {
+	NotifyOnOrder    bool `json:"notify_on_order"`
+	NotifyOnLowStock bool `json:"notify_on_low_stock"`
+}
+
+// DefaultNotificationPreferences matches the column default in the schema
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{NotifyOnOrder: true, NotifyOnLowStock: true}
+}
+
+// Value implements driver.Valuer. It returns a string rather than []byte so
+// lib/pq sends JSON text instead of bytea.
+func (p NotificationPreferences) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (p *NotificationPreferences) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = DefaultNotificationPreferences()
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into NotificationPreferences", src)
+	}
+}
+
+// ParseNotificationPreferences validates a notification_preferences tool
+// argument and returns the flags it sets. A missing argument yields no flags.
+func ParseNotificationPreferences(raw interface{}) (map[string]bool, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	flags, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("notification_preferences must be an object")
+	}
+	parsed := make(map[string]bool, len(flags))
+	for key, value := range flags {
+		if key != "notify_on_order" && key != "notify_on_low_stock" {
+			return nil, fmt.Errorf("unknown notification preference: %s", key)
+		}
+		enabled, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("notification_preferences.%s must be a boolean", key)
+		}
+		parsed[key] = enabled
+	}
+	return parsed, nil
+}
+
+// Apply overlays flags parsed by ParseNotificationPreferences
+func (p *NotificationPreferences) Apply(flags map[string]bool) {
+	if enabled, ok := flags["notify_on_order"]; ok {
+		p.NotifyOnOrder = enabled
+	}
+	if enabled, ok := flags["notify_on_low_stock"]; ok {
+		p.NotifyOnLowStock = enabled
+	}
+}
+
+// ShouldNotify reports whether a restaurant may be emailed about event,
+// NotifyOrder or NotifyLowStock. Restaurants without an email are never
+// notified. Nothing sends notifications yet; a sender must check this
+// before each one.
+func (r *Restaurant) ShouldNotify(event string) bool {
+	if r.Email == "" {
+		return false
+	}
+	switch event {
+	case NotifyOrder:
+		return r.NotificationPreferences.NotifyOnOrder
+	case NotifyLowStock:
+		return r.NotificationPreferences.NotifyOnLowStock
+	default:
+		return false
+	}
+}
+
+// ValidateEmail checks that email is a bare address (no display name).
+// An empty email is valid since the field is optional.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return fmt.Errorf("invalid email address: %q", email)
+	}
+	return nil
+}
+
+// MenuItem represents a dish on a restaurant's menu
+type MenuItem struct {
+	ID            int       `json:"id"`
+	RestaurantID  int       `json:"restaurant_id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Price         Money     `json:"price"`
+	Category      string    `json:"category"`
+	DietaryType   string    `json:"dietary_type"`             // see DietaryTypes
+	SpiceLevel    string    `json:"spice_level"`              // see SpiceLevels
+	Available     bool      `json:"available"`                // Manual override
+	EffectiveFrom string    `json:"effective_from,omitempty"` // YYYY-MM-DD; scheduled start, inclusive
+	EffectiveTo   string    `json:"effective_to,omitempty"`   // YYYY-MM-DD; scheduled end, inclusive
+	Version       int       `json:"version"`                  // raised by every update
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"` // last change of any field
+
+	// Portions left; nil when stock isn't tracked. See SetStock.
+	StockQuantity *int `json:"stock_quantity,omitempty"`
+
+	// Set only when a menu is requested with include_stats
+	LastPriceChangeAt *time.Time `json:"last_price_change_at,omitempty"`
+}
+
+// Menu sort orders. Menus come sorted by category and name.
+const (
+	MenuSortCategory        = "category"
+	MenuSortRecentlyChanged = "recently_changed" // most recently updated first
+)
+
+// MenuSorts lists the accepted menu sort orders
+var MenuSorts = []string{MenuSortCategory, MenuSortRecentlyChanged}
+
+// ValidateMenuSort accepts one of MenuSorts, or empty for by category
+func ValidateMenuSort(sort string) error {
+	if sort != "" && !slices.Contains(MenuSorts, sort) {
+		return fmt.Errorf("invalid sort %q: must be one of %s", sort, strings.Join(MenuSorts, ", "))
+	}
+	return nil
+}
+
+// SortMenu reorders items, as read by category and name, by a sort that
+// passed ValidateMenuSort
+func SortMenu(items []MenuItem, sort string) {
+	if sort == MenuSortRecentlyChanged {
+		slices.SortStableFunc(items, func(a, b MenuItem) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	}
+}
+
+// SetStock sets the portions left of the item, nil to stop tracking them.
+// Running out makes the item unavailable, and restocking an item that had
+// run out makes it available again. It reports whether Available changed.
+func (m *MenuItem) SetStock(quantity *int) bool {
+	was := m.Available
+	switch {
+	case quantity == nil:
+	case *quantity == 0:
+		m.Available = false
+	case m.StockQuantity != nil && *m.StockQuantity == 0:
+		m.Available = true
+	}
+	m.StockQuantity = quantity
+	return m.Available != was
+}
+
+// StockArg reads the arguments of set_stock: a quantity of at least 0, or
+// untracked to stop tracking the stock, returned as nil
+func StockArg(args map[string]interface{}) (*int, error) {
+	untracked, _ := args["untracked"].(bool)
+	v, ok := args["quantity"]
+	switch {
+	case untracked && ok:
+		return nil, fmt.Errorf("give either quantity or untracked, not both")
+	case untracked:
+		return nil, nil
+	case !ok:
+		return nil, fmt.Errorf("quantity is required, or untracked: true to stop tracking the stock")
+	}
+	f, isNumber := v.(float64)
+	if !isNumber || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return nil, fmt.Errorf("quantity must be a whole number of at least 0")
+	}
+	quantity := int(f)
+	return &quantity, nil
+}
+
+// StockTaken sums, by menu item, the portions taking the items in taken
+// from stock needs after putting back the ones in released that came from
+// stock. Negative sums are stock to put back.
+func StockTaken(released, taken []OrderItem) map[int]int {
+	need := map[int]int{}
+	for _, item := range released {
+		if item.FromStock {
+			need[item.MenuItemID] -= item.Quantity
+		}
+	}
+	for _, item := range taken {
+		need[item.MenuItemID] += item.Quantity
+	}
+	return need
+}
+
+// Dietary types of a menu item. Empty means not specified.
+const (
+	DietaryVegetarian    = "vegetarian"
+	DietaryNonVegetarian = "non_vegetarian"
+	DietaryVegan         = "vegan"
+	DietaryJainFriendly  = "jain_friendly"
+)
+
+// DietaryTypes lists every valid dietary type
+var DietaryTypes = []string{DietaryVegetarian, DietaryNonVegetarian, DietaryVegan, DietaryJainFriendly}
+
+// Spice levels of a menu item, mildest first. Empty means not specified.
+const (
+	SpiceMild     = "mild"
+	SpiceMedium   = "medium"
+	SpiceHot      = "hot"
+	SpiceExtraHot = "extra_hot"
+)
+
+// SpiceLevels lists every valid spice level
+var SpiceLevels = []string{SpiceMild, SpiceMedium, SpiceHot, SpiceExtraHot}
+
+// DateLayout is the format of menu effective dates and the as_of argument
+const DateLayout = "2006-01-02"
+
+// ValidateEffectiveDates checks that both dates parse and from <= to.
+// Either may be empty, meaning open-ended.
+func ValidateEffectiveDates(from, to string) error {
+	var fromDate, toDate time.Time
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse(DateLayout, from); err != nil {
+			return fmt.Errorf("invalid effective_from %q: expected YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(DateLayout, to); err != nil {
+			return fmt.Errorf("invalid effective_to %q: expected YYYY-MM-DD", to)
+		}
+	}
+	if from != "" && to != "" && fromDate.After(toDate) {
+		return fmt.Errorf("effective_from %s is after effective_to %s", from, to)
+	}
+	return nil
+}
+
+// Validate checks what a new menu item needs: a name, a positive price,
+// known dietary type and spice level if given, and valid effective dates
+func (m *MenuItem) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if m.Price <= 0 {
+		return fmt.Errorf("price must be greater than 0")
+	}
+	if m.DietaryType != "" && !slices.Contains(DietaryTypes, m.DietaryType) {
+		return fmt.Errorf("invalid dietary_type %q: expected one of %s", m.DietaryType, strings.Join(DietaryTypes, ", "))
+	}
+	if m.SpiceLevel != "" && !slices.Contains(SpiceLevels, m.SpiceLevel) {
+		return fmt.Errorf("invalid spice_level %q: expected one of %s", m.SpiceLevel, strings.Join(SpiceLevels, ", "))
+	}
+	return ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo)
+}
+
+// Order represents a customer order with billing details
+type Order struct {
+	ID                  int         `json:"id"`
+	RestaurantID        int         `json:"restaurant_id"`
+	CustomerName        string      `json:"customer_name"`
+	CustomerPhone       string      `json:"customer_phone"`
+	OrderType           string      `json:"order_type"` // dine_in, takeaway, delivery
+	Status              string      `json:"status"`     // see OrderStatus* constants
+	TotalAmount         Money       `json:"total_amount"`
+	TaxAmount           Money       `json:"tax_amount"`
+	Discount            Money       `json:"discount"`
+	DiscountPercent     *float64    `json:"discount_percent,omitempty"` // set when Discount is a percentage of TotalAmount
+	ServiceCharge       Money       `json:"service_charge,omitempty"`   // see Restaurant.ServiceChargePercent
+	Tip                 Money       `json:"tip,omitempty"`
+	FinalAmount         Money       `json:"final_amount"`
+	PaymentStatus       string      `json:"payment_status"` // see PaymentStatuses
+	PaymentMethod       string      `json:"payment_method"` // cash, card, upi, digital_wallet
+	BillingAddress      string      `json:"billing_address"`
+	DeliveryPersonName  string      `json:"delivery_person_name,omitempty"`
+	DeliveryPersonPhone string      `json:"delivery_person_phone,omitempty"`
+	DispatchedAt        *time.Time  `json:"dispatched_at,omitempty"`
+	DeliveredAt         *time.Time  `json:"delivered_at,omitempty"`        // Set automatically when status becomes delivered
+	CancellationReason  string      `json:"cancellation_reason,omitempty"` // see CancelReason* constants
+	CancellationNote    string      `json:"cancellation_note,omitempty"`
+	CancelledBy         string      `json:"cancelled_by,omitempty"`
+	CancelledAt         *time.Time  `json:"cancelled_at,omitempty"`
+	SplitFrom           *int        `json:"split_from,omitempty"`  // the order this one was split off; see Order.Split
+	MergedFrom          []int       `json:"merged_from,omitempty"` // the orders combined into this one; see MergeOrders
+	ArchivedAt          *time.Time  `json:"archived_at,omitempty"` // set on orders read from the archive; see ArchiveOrders
+	CreatedAt           time.Time   `json:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at"`
+	OrderItems          []OrderItem `json:"order_items"`
+
+	Feedback *OrderFeedback `json:"feedback,omitempty"` // only with include_feedback
+
+	// IdempotencyKey, when set on a new order, makes CreateOrder return the
+	// restaurant's order already created with the key instead of a duplicate
+	IdempotencyKey string `json:"-"`
+}
+
+// Order types
+const (
+	OrderTypeDineIn   = "dine_in"
+	OrderTypeTakeaway = "takeaway"
+	OrderTypeDelivery = "delivery"
+)
+
+// Order statuses
+const (
+	OrderStatusPending        = "pending"
+	OrderStatusConfirmed      = "confirmed"
+	OrderStatusPreparing      = "preparing"
+	OrderStatusReady          = "ready"
+	OrderStatusOutForDelivery = "out_for_delivery"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+)
+
+// OrderStatuses lists every order status in lifecycle order. Legacy
+// spellings such as cooking are mapped to these by storage/enumcheck.
+var OrderStatuses = []string{
+	OrderStatusPending,
+	OrderStatusConfirmed,
+	OrderStatusPreparing,
+	OrderStatusReady,
+	OrderStatusOutForDelivery,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+}
+
+// OrderStatusTransitions lists the statuses an order may move to from each
+// status. Once an order is ready the food is made, so it can't be cancelled.
+var OrderStatusTransitions = map[string][]string{
+	OrderStatusPending:        {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:      {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing:      {OrderStatusReady, OrderStatusCancelled},
+	OrderStatusReady:          {OrderStatusOutForDelivery, OrderStatusDelivered},
+	OrderStatusOutForDelivery: {OrderStatusDelivered},
+	OrderStatusDelivered:      {},
+	OrderStatusCancelled:      {},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range OrderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when an order can't move to a status from
+// the one it is in
+type TransitionError struct {
+	OrderID int
+	From    string
+	To      string
+}
+
+func (e *TransitionError) Error() string {
+	next := OrderStatusTransitions[e.From]
+	allowed := "none"
+	if len(next) > 0 {
+		allowed = strings.Join(next, ", ")
+	}
+	return fmt.Sprintf("order %d is %s and can't move to %s; allowed next statuses: %s", e.OrderID, e.From, e.To, allowed)
+}
+
+// CheckTransition reports whether the order may move to status, returning
+// a *TransitionError if not. Staying in its current status is allowed.
+func (o *Order) CheckTransition(status string) error {
+	if status == o.Status || CanTransition(o.Status, status) {
+		return nil
+	}
+	return &TransitionError{OrderID: o.ID, From: o.Status, To: status}
+}
+
+// ParseOrderStatuses splits a comma-separated list of statuses and checks
+// each against OrderStatuses. An empty list yields no statuses.
+func ParseOrderStatuses(list string) ([]string, error) {
+	var statuses []string
+	for _, status := range strings.Split(list, ",") {
+		status = strings.TrimSpace(status)
+		if status == "" {
+			continue
+		}
+		valid := false
+		for _, s := range OrderStatuses {
+			valid = valid || s == status
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid status %q: must be one of %s", status, strings.Join(OrderStatuses, ", "))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// OrderFilter narrows an order listing. Zero fields match every order.
+type OrderFilter struct {
+	RestaurantID  int
+	Statuses      []string  // any of these
+	CreatedFrom   time.Time // created at or after
+	CreatedBefore time.Time // created strictly before
+}
+
+// SetCreatedRange parses the bounds of an order's creation time into f.
+// Each is an RFC 3339 timestamp or a YYYY-MM-DD date, taken as UTC, and may
+// be empty for an open end. A date as the upper bound covers that whole day.
+// fromName and toName are the argument names to report in errors.
+func (f *OrderFilter) SetCreatedRange(fromName, from, toName, to string) error {
+	var err error
+	if f.CreatedFrom, _, err = parseTimeBound(fromName, from); err != nil {
+		return err
+	}
+	var dateOnly bool
+	if f.CreatedBefore, dateOnly, err = parseTimeBound(toName, to); err != nil {
+		return err
+	}
+	if !f.CreatedBefore.IsZero() {
+		if dateOnly {
+			f.CreatedBefore = f.CreatedBefore.AddDate(0, 0, 1)
+		} else {
+			// created_at keeps microseconds, so this makes the bound inclusive
+			f.CreatedBefore = f.CreatedBefore.Truncate(time.Microsecond).Add(time.Microsecond)
+		}
+	}
+	if !f.CreatedFrom.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedFrom.Before(f.CreatedBefore) {
+		return fmt.Errorf("%s (%s) must not be after %s (%s)", fromName, from, toName, to)
+	}
+	return nil
+}
+
+func parseTimeBound(name, value string) (t time.Time, dateOnly bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err = time.Parse(DateLayout, value); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid %s %q: expected a date (YYYY-MM-DD) or a time such as 2024-05-01T18:30:00+05:30", name, value)
+}
+
+// Payment statuses
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusCompleted = "completed"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+)
+
+// PaymentStatuses lists every payment status
+var PaymentStatuses = []string{
+	PaymentStatusPending,
+	PaymentStatusCompleted,
+	PaymentStatusFailed,
+	PaymentStatusRefunded,
+}
+
+// Cancellation reasons accepted by cancel_order
+const (
+	CancelReasonCustomerRequest = "customer_request"
+	CancelReasonOutOfStock      = "out_of_stock"
+	CancelReasonKitchenClosed   = "kitchen_closed"
+	CancelReasonPaymentFailed   = "payment_failed"
+	CancelReasonOther           = "other"
+)
+
+// CancelReasons lists every valid cancellation reason
+var CancelReasons = []string{
+	CancelReasonCustomerRequest,
+	CancelReasonOutOfStock,
+	CancelReasonKitchenClosed,
+	CancelReasonPaymentFailed,
+	CancelReasonOther,
+}
+
+// ValidateCancelReason checks reason against CancelReasons. The "other"
+// reason needs a free-text note explaining it.
+func ValidateCancelReason(reason, note string) error {
+	for _, r := range CancelReasons {
+		if r == reason {
+			if reason == CancelReasonOther && strings.TrimSpace(note) == "" {
+				return fmt.Errorf("cancellation reason %q requires a note", reason)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid cancellation reason %q: must be one of %s", reason, strings.Join(CancelReasons, ", "))
+}
+
+// OrderItem represents a single line of an order
+type OrderItem struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	MenuItemID int       `json:"menu_item_id"`
+	MenuItem   *MenuItem `json:"menu_item,omitempty"`
+	Quantity   int       `json:"quantity"`
+	Price      Money     `json:"price"`
+	Notes      string    `json:"notes"`
+	Subtotal   Money     `json:"subtotal"`
+
+	// The pricing rule that discounted Price when the order was placed
+	PricingRuleID *int `json:"pricing_rule_id,omitempty"`
+
+	// Quantity was taken from the menu item's stock, to put back when the
+	// order is cancelled
+	FromStock bool `json:"-"`
+}
+
+// DefaultTaxRate is the GST added to the item total of the orders of
+// restaurants without a tax_rate of their own. Override with
+// DEFAULT_TAX_RATE, a fraction such as 0.05.
+var DefaultTaxRate = 0.05
+
+// TaxServiceChargeAndTip adds an order's service charge and tip to the
+// amount GST is charged on, which is only the item total unless
+// TAX_SERVICE_CHARGE_AND_TIP is true
+var TaxServiceChargeAndTip = false
+
+func init() {
+	if v, err := strconv.ParseFloat(os.Getenv("DEFAULT_TAX_RATE"), 64); err == nil && ValidateTaxRate(&v) == nil {
+		DefaultTaxRate = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("TAX_SERVICE_CHARGE_AND_TIP")); err == nil {
+		TaxServiceChargeAndTip = v
+	}
+}
+
+// ValidateTaxRate checks that rate is a fraction, 0.05 for 5%, and not a
+// percentage. A nil rate is valid since restaurants may use the default.
+func ValidateTaxRate(rate *float64) error {
+	if rate != nil && (*rate < 0 || *rate >= 1) {
+		return fmt.Errorf("invalid tax_rate %v: give a fraction from 0 up to 1, such as 0.05 for 5%%", *rate)
+	}
+	return nil
+}
+
+// TaxRateArg reads the optional tax_rate tool argument, nil if absent
+func TaxRateArg(args map[string]interface{}) (*float64, error) {
+	v, ok := args["tax_rate"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	rate, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("tax_rate must be a number, such as 0.05 for 5%%")
+	}
+	if err := ValidateTaxRate(&rate); err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// OrderTaxRate is the rate the restaurant's orders are taxed at: its own
+// tax_rate, or DefaultTaxRate
+func (r Restaurant) OrderTaxRate() float64 {
+	if r.TaxRate != nil {
+		return *r.TaxRate
+	}
+	return DefaultTaxRate
+}
+
+// ExpectedVersionArg reads the optional expected_version tool argument, the
+// version of a restaurant or menu item an update was based on; nil if absent
+func ExpectedVersionArg(args map[string]interface{}) (*int, error) {
+	v, ok := args["expected_version"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	version, ok := v.(float64)
+	if !ok || version < 1 || version != math.Trunc(version) {
+		return nil, fmt.Errorf("expected_version must be the version number read with the record, such as 3")
+	}
+	n := int(version)
+	return &n, nil
+}
+
+// ValidateServiceChargePercent checks that percent is from 0 to 100. A nil
+// percent is valid and charges none.
+func ValidateServiceChargePercent(percent *float64) error {
+	if percent != nil && (*percent < 0 || *percent > 100) {
+		return fmt.Errorf("invalid service_charge_percent %v: give a percent from 0 to 100, such as 10", *percent)
+	}
+	return nil
+}
+
+// ServiceChargePercentArg reads the optional service_charge_percent tool
+// argument, nil if absent
+func ServiceChargePercentArg(args map[string]interface{}) (*float64, error) {
+	v, ok := args["service_charge_percent"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	percent, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("service_charge_percent must be a number, such as 10 for 10%%")
+	}
+	if err := ValidateServiceChargePercent(&percent); err != nil {
+		return nil, err
+	}
+	return &percent, nil
+}
+
+// Charges are what a restaurant adds to the item total of its orders
+type Charges struct {
+	TaxRate                float64 // GST as a fraction
+	ServiceChargePercent   float64 // of the item total less discount, on dine-in orders
+	TaxServiceChargeAndTip bool    // GST is charged on the service charge and tip too
+}
+
+// OrderCharges are the charges on the restaurant's orders
+func (r Restaurant) OrderCharges() Charges {
+	c := Charges{TaxRate: r.OrderTaxRate(), TaxServiceChargeAndTip: TaxServiceChargeAndTip}
+	if r.ServiceChargePercent != nil {
+		c.ServiceChargePercent = *r.ServiceChargePercent
+	}
+	return c
+}
+
+// TipArg reads the optional tip tool argument, an amount of at least 0
+func TipArg(args map[string]interface{}) (tip Money, ok bool, err error) {
+	v, ok := args["tip"]
+	if !ok {
+		return 0, false, nil
+	}
+	amount, isNumber := v.(float64)
+	if !isNumber || amount < 0 || math.IsInf(amount, 0) {
+		return 0, true, fmt.Errorf("tip must be an amount of at least 0")
+	}
+	return Rupees(amount), true, nil
+}
+
+// MaxIdempotencyKeyLength is the longest idempotency key accepted
+const MaxIdempotencyKeyLength = 255
+
+// ValidateIdempotencyKey checks a client's idempotency key: up to
+// MaxIdempotencyKeyLength printable ASCII characters. "" means none.
+func ValidateIdempotencyKey(key string) error {
+	if len(key) > MaxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key is longer than %d characters", MaxIdempotencyKeyLength)
+	}
+	for _, c := range key {
+		if c < ' ' || c > '~' {
+			return fmt.Errorf("idempotency key must be printable ASCII, such as a UUID")
+		}
+	}
+	return nil
+}
+
+// IdempotencyKeyArg reads the optional idempotency_key tool argument, ""
+// if absent
+func IdempotencyKeyArg(args map[string]interface{}) (string, error) {
+	v, ok := args["idempotency_key"]
+	if !ok || v == nil {
+		return "", nil
+	}
+	key, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("idempotency_key must be a string, such as a UUID")
+	}
+	if err := ValidateIdempotencyKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Tippable reports whether a tip may still be set on the order: not once
+// it is cancelled or its payment has completed, since the tip would change
+// a settled bill
+func (o *Order) Tippable() bool {
+	return o.Status != OrderStatusCancelled && o.PaymentStatus != PaymentStatusCompleted && o.PaymentStatus != PaymentStatusRefunded
+}
+
+// AutoConfirm confirms a new pending order for a restaurant that skips the
+// manual confirm step. Orders created in any other status are left alone.
+func (o *Order) AutoConfirm(r Restaurant) {
+	if r.AutoConfirmOrders && (o.Status == "" || o.Status == OrderStatusPending) {
+		o.Status = OrderStatusConfirmed
+	}
+}
+
+// ItemsEditable reports whether the order's items may still be changed,
+// which is only until the kitchen starts on it
+func (o *Order) ItemsEditable() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusConfirmed
+}
+
+// AddItem adds quantity of a menu item to the order, onto its existing line
+// for the item when the notes match. The price is left for storage to set
+// from the menu.
+func (o *Order) AddItem(menuItemID, quantity int, notes string) {
+	for i := range o.OrderItems {
+		if item := &o.OrderItems[i]; item.MenuItemID == menuItemID && item.Notes == notes {
+			item.Quantity += quantity
+			return
+		}
+	}
+	o.OrderItems = append(o.OrderItems, OrderItem{MenuItemID: menuItemID, Quantity: quantity, Notes: notes})
+}
+
+// RemoveItem takes quantity of a menu item off the order, dropping lines
+// that reach zero. A quantity of 0, or more than was ordered, removes the
+// item entirely. An order can't be left without items; cancel it instead.
+func (o *Order) RemoveItem(menuItemID, quantity int) error {
+	ordered := 0
+	for _, item := range o.OrderItems {
+		if item.MenuItemID == menuItemID {
+			ordered += item.Quantity
+		}
+	}
+	if ordered == 0 {
+		return fmt.Errorf("menu item %d is not on order %d", menuItemID, o.ID)
+	}
+	if quantity <= 0 || quantity > ordered {
+		quantity = ordered
+	}
+
+	kept := o.OrderItems[:0:0]
+	for _, item := range o.OrderItems {
+		if item.MenuItemID == menuItemID && quantity > 0 {
+			taken := min(item.Quantity, quantity)
+			item.Quantity -= taken
+			quantity -= taken
+		}
+		if item.Quantity > 0 {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == 0 {
+		return fmt.Errorf("removing menu item %d would leave order %d empty; cancel the order instead", menuItemID, o.ID)
+	}
+	o.OrderItems = kept
+	return nil
+}
+
+// ComputeTotals sets each item's subtotal and the order's total, discount,
+// service charge, tax and final amounts from the item prices, the discount,
+// the tip and c, the restaurant's OrderCharges. Every way of placing or
+// editing an order totals it here, so the discount never exceeds the item
+// total and the final amount is never negative.
+//
+// The service charge is only on dine-in orders. GST is on the item total,
+// plus the service charge and tip with TaxServiceChargeAndTip.
+func (o *Order) ComputeTotals(c Charges) {
+	o.TotalAmount = 0
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.Subtotal = item.Price.Times(item.Quantity)
+		o.TotalAmount += item.Subtotal
+	}
+	o.Discount = DiscountAmount(o.TotalAmount, o.Discount, o.DiscountPercent)
+	o.ServiceCharge = 0
+	if o.OrderType == OrderTypeDineIn || o.OrderType == "" { // "" is the column default, dine-in
+		o.ServiceCharge = (o.TotalAmount - o.Discount).Percent(c.ServiceChargePercent)
+	}
+	taxed := o.TotalAmount
+	if c.TaxServiceChargeAndTip {
+		taxed += o.ServiceCharge + o.Tip
+	}
+	o.TaxAmount = taxed.MulRate(c.TaxRate)
+	o.FinalAmount = max(0, o.TotalAmount+o.TaxAmount-o.Discount) + o.ServiceCharge + o.Tip
+}
+
+// Receipt lists the amounts that make up the order's final amount, one per
+// line, leaving out a zero discount, service charge and tip
+func (o *Order) Receipt() string {
+	var b strings.Builder
+	line := func(label string, amount Money) {
+		fmt.Fprintf(&b, "%-15s %12s\n", label, amount)
+	}
+	line("Items", o.TotalAmount)
+	if o.Discount > 0 {
+		line("Discount", -o.Discount)
+	}
+	if o.ServiceCharge > 0 {
+		line("Service charge", o.ServiceCharge)
+	}
+	line("GST", o.TaxAmount)
+	if o.Tip > 0 {
+		line("Tip", o.Tip)
+	}
+	line("Total", o.FinalAmount)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// DiscountAmount is the discount on an item total of subtotal: percent of
+// it, rounded to the paisa, when percent is set and amount otherwise. It is
+// clamped to between zero and subtotal.
+func DiscountAmount(subtotal, amount Money, percent *float64) Money {
+	if percent != nil {
+		amount = subtotal.Percent(*percent)
+	}
+	return min(max(amount, 0), subtotal)
+}
+
+// DiscountArgs reads the optional discount and discount_percent tool
+// arguments, which are mutually exclusive. The percent is nil when absent.
+func DiscountArgs(args map[string]interface{}) (Money, *float64, error) {
+	amount, hasAmount := args["discount"]
+	percent, hasPercent := args["discount_percent"]
+	if hasAmount && hasPercent {
+		return 0, nil, fmt.Errorf("give either discount or discount_percent, not both")
+	}
+	if hasPercent {
+		p, ok := percent.(float64)
+		if !ok || p < 0 || p > 100 {
+			return 0, nil, fmt.Errorf("discount_percent must be a number from 0 to 100")
+		}
+		return 0, &p, nil
+	}
+	if hasAmount {
+		a, ok := amount.(float64)
+		if !ok || a < 0 || math.IsInf(a, 0) {
+			return 0, nil, fmt.Errorf("discount must be an amount of at least 0")
+		}
+		return Rupees(a), nil, nil
+	}
+	return 0, nil, nil
+}
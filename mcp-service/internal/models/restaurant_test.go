@@ -0,0 +1,314 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{OrderStatusPending, OrderStatusConfirmed, true},
+		{OrderStatusConfirmed, OrderStatusPreparing, true},
+		{OrderStatusPreparing, OrderStatusReady, true},
+		{OrderStatusReady, OrderStatusDelivered, true},
+		{OrderStatusPreparing, OrderStatusCancelled, true},
+		{OrderStatusPending, OrderStatusPending, true},
+		{OrderStatusReady, OrderStatusCancelled, false},
+		{OrderStatusDelivered, OrderStatusPending, false},
+		{OrderStatusCancelled, OrderStatusPreparing, false},
+		{OrderStatusPending, OrderStatusReady, false},
+	}
+	for _, tc := range tests {
+		o := Order{ID: 7, Status: tc.from}
+		if err := o.CheckTransition(tc.to); (err == nil) != tc.ok {
+			t.Errorf("%s -> %s: %v, want ok %v", tc.from, tc.to, err, tc.ok)
+		}
+	}
+
+	o := Order{ID: 7, Status: OrderStatusPreparing}
+	var terr *TransitionError
+	if err := o.CheckTransition(OrderStatusPending); !errors.As(err, &terr) ||
+		err.Error() != "order 7 is preparing and can't move to pending; allowed next statuses: ready, cancelled" {
+		t.Errorf("error %v", err)
+	}
+	o.Status = OrderStatusDelivered
+	if err := o.CheckTransition(OrderStatusPending); err == nil || err.Error() != "order 7 is delivered and can't move to pending; allowed next statuses: none" {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestOrderAddAndRemoveItems(t *testing.T) {
+	o := Order{ID: 3, OrderItems: []OrderItem{{MenuItemID: 1, Quantity: 2}}}
+	o.AddItem(1, 1, "")
+	o.AddItem(1, 1, "no onion")
+	o.AddItem(2, 1, "")
+	if len(o.OrderItems) != 3 || o.OrderItems[0].Quantity != 3 {
+		t.Fatalf("items after adding %+v", o.OrderItems)
+	}
+
+	if err := o.RemoveItem(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.OrderItems) != 2 || o.OrderItems[0].Notes != "no onion" || o.OrderItems[0].Quantity != 1 {
+		t.Errorf("items after removing 3 of item 1 %+v", o.OrderItems)
+	}
+	if err := o.RemoveItem(9, 0); err == nil {
+		t.Error("removed an item not on the order")
+	}
+	if err := o.RemoveItem(1, 0); err != nil || len(o.OrderItems) != 1 {
+		t.Errorf("removing item 1 entirely: %v, %+v", err, o.OrderItems)
+	}
+	if err := o.RemoveItem(2, 0); err == nil || len(o.OrderItems) != 1 {
+		t.Errorf("removing the last item: %v, %+v", err, o.OrderItems)
+	}
+}
+
+func TestTaxRateArgTakesFractionsOnly(t *testing.T) {
+	if rate, err := TaxRateArg(map[string]interface{}{}); rate != nil || err != nil {
+		t.Errorf("no tax_rate = %v, %v; want nil", rate, err)
+	}
+	if rate, err := TaxRateArg(map[string]interface{}{"tax_rate": 0.18}); err != nil || *rate != 0.18 {
+		t.Errorf("tax_rate 0.18 = %v, %v", rate, err)
+	}
+	for _, bad := range []interface{}{18.0, -0.05, 1.0, "5%"} {
+		if _, err := TaxRateArg(map[string]interface{}{"tax_rate": bad}); err == nil {
+			t.Errorf("tax_rate %v accepted", bad)
+		}
+	}
+}
+
+func TestComputeTotalsUsesTheRestaurantsTaxRate(t *testing.T) {
+	o := Order{Discount: Rupees(10), OrderItems: []OrderItem{{Quantity: 2, Price: Rupees(100)}}}
+	o.ComputeTotals(Restaurant{}.OrderCharges())
+	if o.TaxAmount != Rupees(200*DefaultTaxRate) || o.FinalAmount != Rupees(200)+o.TaxAmount-Rupees(10) {
+		t.Errorf("default rate: tax %v, final %v", o.TaxAmount, o.FinalAmount)
+	}
+	rate := 0.18
+	o.ComputeTotals(Restaurant{TaxRate: &rate}.OrderCharges())
+	if o.TaxAmount != Rupees(36) || o.FinalAmount != Rupees(226) {
+		t.Errorf("18%%: tax %v, final %v; want 36 and 226", o.TaxAmount, o.FinalAmount)
+	}
+}
+
+func TestComputeTotalsRoundsToThePaisa(t *testing.T) {
+	o := Order{OrderItems: []OrderItem{{Quantity: 3, Price: Rupees(33.33)}}}
+	o.ComputeTotals(Charges{TaxRate: 0.05})
+	if o.TotalAmount != Rupees(99.99) || o.TaxAmount != Rupees(5) || o.FinalAmount != Rupees(104.99) {
+		t.Errorf("3 x 33.33 at 5%%: total %v, tax %v, final %v; want ₹99.99, ₹5.00 and ₹104.99", o.TotalAmount, o.TaxAmount, o.FinalAmount)
+	}
+	if o.OrderItems[0].Subtotal != o.TotalAmount {
+		t.Errorf("item subtotal %v, want %v", o.OrderItems[0].Subtotal, o.TotalAmount)
+	}
+}
+
+func TestDiscountAmount(t *testing.T) {
+	pct := func(p float64) *float64 { return &p }
+	tests := []struct {
+		name     string
+		subtotal float64
+		amount   float64
+		percent  *float64
+		want     float64
+	}{
+		{"amount", 500, 50, nil, 50},
+		{"no discount", 500, 0, nil, 0},
+		{"amount over the subtotal", 500, 10000, nil, 500},
+		{"negative amount", 500, -20, nil, 0},
+		{"0%", 500, 0, pct(0), 0},
+		{"100%", 500, 0, pct(100), 500},
+		{"10%", 500, 0, pct(10), 50},
+		{"rounds to 2 decimals", 333.33, 0, pct(12.5), 41.67},
+		{"rounds half up", 0.1, 0, pct(5), 0.01},
+		{"percent wins over a stale amount", 200, 75, pct(10), 20},
+	}
+	for _, tc := range tests {
+		if got := DiscountAmount(Rupees(tc.subtotal), Rupees(tc.amount), tc.percent); got != Rupees(tc.want) {
+			t.Errorf("%s: DiscountAmount(%v, %v) = %v, want %v", tc.name, tc.subtotal, tc.amount, got, tc.want)
+		}
+	}
+}
+
+func TestComputeTotalsNeverGoesBelowZero(t *testing.T) {
+	o := Order{Discount: Rupees(10000), OrderItems: []OrderItem{{Quantity: 1, Price: Rupees(500)}}}
+	o.ComputeTotals(Charges{TaxRate: 0})
+	if o.Discount != Rupees(500) || o.FinalAmount != 0 {
+		t.Errorf("discount %v, final %v; want 500 and 0", o.Discount, o.FinalAmount)
+	}
+
+	percent := 20.0
+	o = Order{DiscountPercent: &percent, OrderItems: []OrderItem{{Quantity: 2, Price: Rupees(100)}}}
+	o.ComputeTotals(Charges{TaxRate: 0.05})
+	if o.Discount != Rupees(40) || o.FinalAmount != Rupees(170) {
+		t.Errorf("20%%: discount %v, final %v; want 40 and 170", o.Discount, o.FinalAmount)
+	}
+	o.OrderItems[0].Quantity = 1
+	o.ComputeTotals(Charges{TaxRate: 0.05})
+	if o.Discount != Rupees(20) {
+		t.Errorf("20%% after removing an item: discount %v, want 20", o.Discount)
+	}
+}
+
+func TestDiscountArgs(t *testing.T) {
+	if amount, percent, err := DiscountArgs(map[string]interface{}{"discount": 25.0}); err != nil || amount != Rupees(25) || percent != nil {
+		t.Errorf("discount 25 = %v, %v, %v", amount, percent, err)
+	}
+	if amount, percent, err := DiscountArgs(map[string]interface{}{"discount_percent": 15.0}); err != nil || amount != 0 || *percent != 15 {
+		t.Errorf("discount_percent 15 = %v, %v, %v", amount, percent, err)
+	}
+	for _, bad := range []map[string]interface{}{
+		{"discount": 10.0, "discount_percent": 10.0},
+		{"discount": -5.0},
+		{"discount_percent": 101.0},
+		{"discount_percent": -1.0},
+		{"discount_percent": "10%"},
+	} {
+		if _, _, err := DiscountArgs(bad); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestServiceChargeIsOnDineInOnly(t *testing.T) {
+	c := Charges{TaxRate: 0.05, ServiceChargePercent: 10}
+	o := Order{OrderType: OrderTypeDineIn, Discount: Rupees(100), OrderItems: []OrderItem{{Quantity: 2, Price: Rupees(250)}}}
+	o.ComputeTotals(c)
+	// 10% of 500 less the 100 discount, and GST on the 500 of items only
+	if o.ServiceCharge != Rupees(40) || o.TaxAmount != Rupees(25) || o.FinalAmount != Rupees(465) {
+		t.Errorf("dine-in: service %v, tax %v, final %v; want 40, 25 and 465", o.ServiceCharge, o.TaxAmount, o.FinalAmount)
+	}
+	o.OrderType = OrderTypeDelivery
+	o.ComputeTotals(c)
+	if o.ServiceCharge != 0 || o.FinalAmount != Rupees(425) {
+		t.Errorf("delivery: service %v, final %v; want 0 and 425", o.ServiceCharge, o.FinalAmount)
+	}
+}
+
+func TestTipIsTaxedOnlyWhenConfigured(t *testing.T) {
+	o := Order{OrderType: OrderTypeDineIn, Tip: Rupees(50), OrderItems: []OrderItem{{Quantity: 1, Price: Rupees(1000)}}}
+	o.ComputeTotals(Charges{TaxRate: 0.05, ServiceChargePercent: 10})
+	if o.TaxAmount != Rupees(50) || o.FinalAmount != Rupees(1200) {
+		t.Errorf("untaxed: tax %v, final %v; want 50 and 1200", o.TaxAmount, o.FinalAmount)
+	}
+	o.ComputeTotals(Charges{TaxRate: 0.05, ServiceChargePercent: 10, TaxServiceChargeAndTip: true})
+	if o.TaxAmount != Rupees(57.5) || o.FinalAmount != Rupees(1207.5) {
+		t.Errorf("taxed: tax %v, final %v; want 57.50 and 1207.50", o.TaxAmount, o.FinalAmount)
+	}
+
+	o.Discount = Rupees(5000)
+	o.ComputeTotals(Charges{})
+	if o.FinalAmount != Rupees(50) {
+		t.Errorf("fully discounted: final %v, want the 50 tip", o.FinalAmount)
+	}
+}
+
+func TestTipArgAndTippable(t *testing.T) {
+	if tip, ok, err := TipArg(map[string]interface{}{"tip": 20.5}); tip != Rupees(20.5) || !ok || err != nil {
+		t.Errorf("tip 20.5 = %v, %v, %v", tip, ok, err)
+	}
+	if _, ok, _ := TipArg(map[string]interface{}{}); ok {
+		t.Error("no tip reported as given")
+	}
+	for _, bad := range []interface{}{-1.0, "20", nil} {
+		if _, _, err := TipArg(map[string]interface{}{"tip": bad}); err == nil {
+			t.Errorf("tip %v accepted", bad)
+		}
+	}
+
+	for _, o := range []Order{
+		{Status: OrderStatusCancelled, PaymentStatus: PaymentStatusPending},
+		{Status: OrderStatusDelivered, PaymentStatus: PaymentStatusCompleted},
+		{Status: OrderStatusDelivered, PaymentStatus: PaymentStatusRefunded},
+	} {
+		if o.Tippable() {
+			t.Errorf("%s order with %s payment takes a tip", o.Status, o.PaymentStatus)
+		}
+	}
+	o := Order{Status: OrderStatusDelivered, PaymentStatus: PaymentStatusPending}
+	if !o.Tippable() {
+		t.Error("unpaid delivered order takes no tip")
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	o := Order{OrderType: OrderTypeDineIn, Tip: Rupees(30), OrderItems: []OrderItem{{Quantity: 2, Price: Rupees(100)}}}
+	o.ComputeTotals(Charges{TaxRate: 0.05, ServiceChargePercent: 10})
+	want := "" +
+		"Items                ₹200.00\n" +
+		"Service charge        ₹20.00\n" +
+		"GST                   ₹10.00\n" +
+		"Tip                   ₹30.00\n" +
+		"Total                ₹260.00"
+	if got := o.Receipt(); got != want {
+		t.Errorf("receipt:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExpectedVersionArg(t *testing.T) {
+	if v, err := ExpectedVersionArg(map[string]interface{}{}); v != nil || err != nil {
+		t.Errorf("no expected_version = %v, %v; want nil", v, err)
+	}
+	if v, err := ExpectedVersionArg(map[string]interface{}{"expected_version": 3.0}); err != nil || *v != 3 {
+		t.Errorf("expected_version 3 = %v, %v", v, err)
+	}
+	for _, bad := range []interface{}{0.0, 2.5, "3"} {
+		if _, err := ExpectedVersionArg(map[string]interface{}{"expected_version": bad}); err == nil {
+			t.Errorf("expected_version %v accepted", bad)
+		}
+	}
+}
+
+func TestIdempotencyKeyArg(t *testing.T) {
+	if key, err := IdempotencyKeyArg(map[string]interface{}{"idempotency_key": "3f6c2a9e-5b1d"}); err != nil || key != "3f6c2a9e-5b1d" {
+		t.Errorf("idempotency_key = %q, %v", key, err)
+	}
+	for _, bad := range []interface{}{42.0, "tab\there", "कुंजी", strings.Repeat("k", MaxIdempotencyKeyLength+1)} {
+		if _, err := IdempotencyKeyArg(map[string]interface{}{"idempotency_key": bad}); err == nil {
+			t.Errorf("idempotency_key %q accepted", bad)
+		}
+	}
+}
+
+func TestSortMenu(t *testing.T) {
+	now := time.Now()
+	items := []MenuItem{{ID: 1, UpdatedAt: now.Add(-time.Hour)}, {ID: 2, UpdatedAt: now}, {ID: 3, UpdatedAt: now.Add(-time.Hour)}}
+	SortMenu(items, MenuSortCategory)
+	if items[0].ID != 1 {
+		t.Errorf("sort by category reordered the menu: %+v", items)
+	}
+	SortMenu(items, MenuSortRecentlyChanged)
+	if ids := []int{items[0].ID, items[1].ID, items[2].ID}; ids[0] != 2 || ids[1] != 1 || ids[2] != 3 {
+		t.Errorf("recently changed order %v, want [2 1 3]", ids)
+	}
+	if err := ValidateMenuSort("price"); err == nil {
+		t.Error("sort by price accepted")
+	}
+}
+
+func TestSetStockFollowsAvailability(t *testing.T) {
+	zero, ten := 0, 10
+	m := MenuItem{Available: true}
+	if !m.SetStock(&zero) || m.Available {
+		t.Error("running out left the item available")
+	}
+	if !m.SetStock(&ten) || !m.Available {
+		t.Error("restocking left the item unavailable")
+	}
+	m.Available = false // switched off by hand
+	if m.SetStock(&zero); m.SetStock(nil) || m.Available || m.StockQuantity != nil {
+		t.Errorf("untracking changed availability: %+v", m)
+	}
+
+	for _, bad := range []map[string]interface{}{{}, {"quantity": -1.0}, {"quantity": 1.5}, {"quantity": "3"}, {"quantity": 3.0, "untracked": true}} {
+		if _, err := StockArg(bad); err == nil {
+			t.Errorf("StockArg(%v) succeeded", bad)
+		}
+	}
+	if q, err := StockArg(map[string]interface{}{"untracked": true}); q != nil || err != nil {
+		t.Errorf("StockArg(untracked) = %v, %v", q, err)
+	}
+}
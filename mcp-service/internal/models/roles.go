@@ -0,0 +1,9 @@
+package models
+
+// User roles, from user_profiles.role. Users without a profile role are
+// staff.
+const (
+	RoleAdmin = "admin"
+	RoleOwner = "owner"
+	RoleStaff = "user"
+)
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxSalesReportDays bounds the range of one sales report
+const MaxSalesReportDays = 366
+
+// SalesQuery selects the daily sales of a restaurant between two dates
+// (YYYY-MM-DD, inclusive) of the restaurant's own calendar
+type SalesQuery struct {
+	RestaurantID int
+	From         string
+	To           string
+}
+
+// SalesQueryArgs reads the get_sales_report tool arguments
+func SalesQueryArgs(args map[string]interface{}) (SalesQuery, error) {
+	var q SalesQuery
+	if v, ok := args["restaurant_id"].(float64); ok {
+		q.RestaurantID = int(v)
+	}
+	q.From, _ = args["from"].(string)
+	q.To, _ = args["to"].(string)
+	return q, q.Validate()
+}
+
+// Validate checks that the restaurant and both dates are given, parse, are
+// in order and span at most MaxSalesReportDays
+func (q SalesQuery) Validate() error {
+	if q.RestaurantID <= 0 {
+		return fmt.Errorf("restaurant_id must be a positive integer")
+	}
+	from, err := time.Parse(DateLayout, q.From)
+	if err != nil {
+		return fmt.Errorf("invalid from %q: expected YYYY-MM-DD", q.From)
+	}
+	to, err := time.Parse(DateLayout, q.To)
+	if err != nil {
+		return fmt.Errorf("invalid to %q: expected YYYY-MM-DD", q.To)
+	}
+	if from.After(to) {
+		return fmt.Errorf("from (%s) must not be after to (%s)", q.From, q.To)
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > MaxSalesReportDays {
+		return fmt.Errorf("from and to span %d days; at most %d are allowed", days, MaxSalesReportDays)
+	}
+	return nil
+}
+
+// SalesDay totals the orders placed on one day, cancelled orders excluded
+type SalesDay struct {
+	Date          string `json:"date"` // YYYY-MM-DD in the restaurant's timezone
+	Orders        int    `json:"orders"`
+	Gross         Money  `json:"gross"`
+	Tax           Money  `json:"tax"`
+	Discount      Money  `json:"discount"`
+	ServiceCharge Money  `json:"service_charge"`
+	Tips          Money  `json:"tips"`
+	Net           Money  `json:"net"` // includes the service charge and tips
+}
+
+// Add counts one order into d
+func (d *SalesDay) Add(o Order) {
+	d.Orders++
+	d.Gross += o.TotalAmount
+	d.Tax += o.TaxAmount
+	d.Discount += o.Discount
+	d.ServiceCharge += o.ServiceCharge
+	d.Tips += o.Tip
+	d.Net += o.FinalAmount
+}
+
+// SalesCancellations counts the cancelled orders of one cancellation reason
+// and the amount they would have brought in
+type SalesCancellations struct {
+	Reason string `json:"reason"` // "unknown" for orders cancelled without one
+	Orders int    `json:"orders"`
+	Amount Money  `json:"amount"`
+}
+
+// SalesReport is the daily sales of a restaurant. Days without orders are
+// left out. Cancelled orders count only in Cancelled, by reason.
+type SalesReport struct {
+	RestaurantID int                  `json:"restaurant_id"`
+	Timezone     string               `json:"timezone"`
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Days         []SalesDay           `json:"days"`
+	Cancelled    []SalesCancellations `json:"cancelled"`
+}
+
+// AddCancelled counts one cancelled order into the report's Cancelled,
+// which stays ordered by reason
+func (r *SalesReport) AddCancelled(o Order) {
+	reason := o.CancellationReason
+	if reason == "" {
+		reason = "unknown"
+	}
+	i, found := slices.BinarySearchFunc(r.Cancelled, reason, func(c SalesCancellations, reason string) int { return strings.Compare(c.Reason, reason) })
+	if !found {
+		r.Cancelled = slices.Insert(r.Cancelled, i, SalesCancellations{Reason: reason})
+	}
+	r.Cancelled[i].Orders++
+	r.Cancelled[i].Amount += o.FinalAmount
+}
+
+// Total adds up the report's days
+func (r SalesReport) Total() SalesDay {
+	total := SalesDay{Date: "total"}
+	for _, d := range r.Days {
+		total.Orders += d.Orders
+		total.Gross += d.Gross
+		total.Tax += d.Tax
+		total.Discount += d.Discount
+		total.ServiceCharge += d.ServiceCharge
+		total.Tips += d.Tips
+		total.Net += d.Net
+	}
+	return total
+}
+
+// Table renders the days of the report as a fixed-width text table with a
+// total row, or "" without orders
+func (r SalesReport) Table() string {
+	if len(r.Days) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	row := func(d SalesDay) {
+		fmt.Fprintf(&b, "%-10s %6d %12s %10s %10s %10s %10s %12s\n", d.Date, d.Orders, d.Gross.Decimal(), d.Tax.Decimal(), d.Discount.Decimal(),
+			d.ServiceCharge.Decimal(), d.Tips.Decimal(), d.Net.Decimal())
+	}
+	fmt.Fprintf(&b, "%-10s %6s %12s %10s %10s %10s %10s %12s\n", "date", "orders", "gross", "tax", "discount", "service", "tips", "net")
+	for _, d := range r.Days {
+		row(d)
+	}
+	row(r.Total())
+	return b.String()
+}
+
+// CancelledLine sums up the report's cancelled orders by reason, or "" when
+// none were cancelled
+func (r SalesReport) CancelledLine() string {
+	if len(r.Cancelled) == 0 {
+		return ""
+	}
+	var orders int
+	var amount Money
+	reasons := make([]string, len(r.Cancelled))
+	for i, c := range r.Cancelled {
+		orders += c.Orders
+		amount += c.Amount
+		reasons[i] = fmt.Sprintf("%s %d (%s)", c.Reason, c.Orders, c.Amount)
+	}
+	return fmt.Sprintf("Cancelled: %s worth %s; %s.", plural(orders, "order"), amount, strings.Join(reasons, ", "))
+}
+
+// Blocks renders the report as tool result blocks: a summary line, the
+// table, the cancellations and what the figures leave out
+func (r SalesReport) Blocks() []string {
+	summary := fmt.Sprintf("Sales for restaurant %d, %s to %s: no orders in this period.", r.RestaurantID, r.From, r.To)
+	if total := r.Total(); total.Orders > 0 {
+		summary = fmt.Sprintf("Sales for restaurant %d, %s to %s: %s over %s, net %s.",
+			r.RestaurantID, r.From, r.To, plural(total.Orders, "order"), plural(len(r.Days), "day"), total.Net)
+	}
+	caveats := fmt.Sprintf("Cancelled orders are excluded from the table and counted by reason instead. Net includes tips, which are passed on to staff. Days run midnight to midnight in %s; days without orders are left out.", r.Timezone)
+	return []string{summary, r.Table(), r.CancelledLine(), caveats}
+}
+
+// Limits of get_top_selling_items
+const (
+	DefaultTopItems = 10
+	MaxTopItems     = 100
+)
+
+// TopItemsQuery selects a restaurant's best selling menu items, optionally
+// only those of one category and orders placed since a time
+type TopItemsQuery struct {
+	RestaurantID int
+	Since        time.Time // zero means all orders
+	Category     string    // matched case-insensitively; empty means all
+	Limit        int
+}
+
+// TopItemsArgs reads the get_top_selling_items tool arguments
+func TopItemsArgs(args map[string]interface{}) (TopItemsQuery, error) {
+	q := TopItemsQuery{Limit: DefaultTopItems}
+	if v, ok := args["restaurant_id"].(float64); ok {
+		q.RestaurantID = int(v)
+	}
+	if v, ok := args["limit"].(float64); ok {
+		q.Limit = int(v)
+	}
+	q.Category, _ = args["category"].(string)
+	q.Category = strings.TrimSpace(q.Category)
+	since, _ := args["since"].(string)
+	var err error
+	if q.Since, _, err = parseTimeBound("since", since); err != nil {
+		return q, err
+	}
+	return q, q.Validate()
+}
+
+// Validate checks the restaurant and that Limit is within 1..MaxTopItems
+func (q TopItemsQuery) Validate() error {
+	if q.RestaurantID <= 0 {
+		return fmt.Errorf("restaurant_id must be a positive integer")
+	}
+	if q.Limit < 1 || q.Limit > MaxTopItems {
+		return fmt.Errorf("limit must be between 1 and %d", MaxTopItems)
+	}
+	return nil
+}
+
+// ItemSales is how much of one menu item was sold
+type ItemSales struct {
+	MenuItemID int    `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	Units      int    `json:"units"`
+	Revenue    Money  `json:"revenue"`
+}
+
+// TopItemsText renders a ranking of items as text, one line per item
+func TopItemsText(q TopItemsQuery, items []ItemSales) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Top selling items of restaurant %d", q.RestaurantID)
+	if q.Category != "" {
+		fmt.Fprintf(&b, " in %s", q.Category)
+	}
+	if !q.Since.IsZero() {
+		fmt.Fprintf(&b, " since %s", q.Since.Format(time.RFC3339))
+	}
+	b.WriteString(", cancelled orders excluded\n")
+	if len(items) == 0 {
+		b.WriteString("No items sold in this period\n")
+		return b.String()
+	}
+	for i, item := range items {
+		fmt.Fprintf(&b, "%d. %s (ID %d): %d sold, revenue %s\n", i+1, item.Name, item.MenuItemID, item.Units, item.Revenue)
+	}
+	return b.String()
+}
+
+// RestaurantStats summarises a restaurant's orders and menu. Revenue and
+// AverageOrderValue leave out cancelled orders.
+type RestaurantStats struct {
+	RestaurantID       int        `json:"restaurant_id"`
+	Orders             int        `json:"orders"`
+	CancelledOrders    int        `json:"cancelled_orders"`
+	PendingOrders      int        `json:"pending_orders_requiring_confirmation"` // always 0 with auto_confirm_orders
+	Revenue            Money      `json:"revenue"`
+	AverageOrderValue  Money      `json:"average_order_value"`
+	AvailableMenuItems int        `json:"available_menu_items"`
+	LastOrderAt        *time.Time `json:"last_order_at"` // nil without orders
+}
+
+// SetAverage fills in AverageOrderValue from Revenue over the orders that
+// weren't cancelled, or zero when there are none
+func (s *RestaurantStats) SetAverage() {
+	s.AverageOrderValue = 0
+	if n := s.Orders - s.CancelledOrders; n > 0 {
+		s.AverageOrderValue = Rupees(s.Revenue.Rupees() / float64(n))
+	}
+}
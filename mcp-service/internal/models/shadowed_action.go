@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Shadowed action statuses. A replay that fails can be tried again.
+const (
+	ShadowStatusPending   = "pending"
+	ShadowStatusReplaying = "replaying"
+	ShadowStatusReplayed  = "replayed"
+	ShadowStatusFailed    = "failed"
+)
+
+// ShadowedAction is a destructive tool call that shadow mode accepted and
+// validated but did not run. Effect says what it would have done; an admin
+// replays it to run it for real.
+type ShadowedAction struct {
+	ID           int                    `json:"id"`
+	Tool         string                 `json:"tool"`
+	Arguments    map[string]interface{} `json:"arguments"`
+	Effect       string                 `json:"effect"`
+	Status       string                 `json:"status"`
+	RequestedBy  string                 `json:"requested_by"`
+	CreatedAt    time.Time              `json:"created_at"`
+	ReplayedBy   string                 `json:"replayed_by,omitempty"`
+	ReplayedAt   *time.Time             `json:"replayed_at,omitempty"`
+	ReplayResult string                 `json:"replay_result,omitempty"` // what the tool answered when replayed
+}
+
+// ValidateShadowStatus checks a status filter; empty means any status
+func ValidateShadowStatus(status string) error {
+	switch status {
+	case "", ShadowStatusPending, ShadowStatusReplaying, ShadowStatusReplayed, ShadowStatusFailed:
+		return nil
+	}
+	return fmt.Errorf("status must be one of %s, %s, %s or %s", ShadowStatusPending, ShadowStatusReplaying, ShadowStatusReplayed, ShadowStatusFailed)
+}
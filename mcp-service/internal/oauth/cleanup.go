@@ -0,0 +1,51 @@
+package oauth
+
+import (
+	"log"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+)
+
+// vacuumThreshold is the number of deleted rows after which the cleanup job
+// runs VACUUM ANALYZE so the table and its indexes don't bloat
+const vacuumThreshold = 10000
+
+// StartTokenCleanup periodically deletes expired token metadata and
+// publishes oauth_tokens table metrics. It runs once immediately.
+func StartTokenCleanup(storage *Storage, interval time.Duration) {
+	go func() {
+		for {
+			runTokenCleanup(storage)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func runTokenCleanup(storage *Storage) {
+	deleted, err := storage.CleanupExpiredTokens()
+	if err != nil {
+		log.Printf("Token cleanup failed: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Cleaned up %d expired tokens", deleted)
+	}
+	metrics.SetGauge("oauth_tokens_cleanup_deleted", "Rows deleted by the last token cleanup run", float64(deleted))
+
+	if deleted >= vacuumThreshold {
+		if err := storage.VacuumTokens(); err != nil {
+			log.Printf("⚠️  Deleted %d tokens but VACUUM ANALYZE oauth_tokens failed (%v); run it manually to reclaim space", deleted, err)
+		}
+	}
+
+	stats, err := storage.GetTokenTableStats()
+	if err != nil {
+		log.Printf("Token stats failed: %v", err)
+		return
+	}
+	metrics.SetGauge("oauth_tokens_rows", "Rows in oauth_tokens", float64(stats.Rows))
+	metrics.SetGauge("oauth_tokens_legacy_rows", "oauth_tokens rows still keyed by an unhashed token id", float64(stats.LegacyRows))
+	metrics.SetGauge("oauth_tokens_size_bytes", "Total on-disk size of oauth_tokens including indexes", float64(stats.SizeBytes))
+	metrics.SetGauge("oauth_tokens_oldest_row_age_seconds", "Age of the oldest oauth_tokens row", stats.OldestAgeSecs)
+}
@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/vishalk17/mcp-service-restaurant/internal/models"
@@ -22,7 +21,7 @@ func NewClientRegistry(storage *Storage) *ClientRegistry {
 
 // RegisterClient registers a new OAuth client
 func (cr *ClientRegistry) RegisterClient(req map[string]interface{}) (*models.OAuthClient, error) {
-	now := time.Now()
+	now := clock()
 	client := &models.OAuthClient{
 		ClientID:                "mcp-" + uuid.New().String(),
 		ClientSecret:            generateSecureSecret(),
@@ -0,0 +1,8 @@
+package oauth
+
+import "time"
+
+// clock is the time source for every expiry decision in this package: auth
+// codes, issued and validated tokens and stored token lookups. Replacing it
+// lets expiry be exercised without waiting.
+var clock = time.Now
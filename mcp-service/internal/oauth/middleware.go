@@ -2,8 +2,13 @@ package oauth
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
 )
 
 // UserContextKey is the key for user context
@@ -15,8 +20,21 @@ const UserContextKey = contextKey("user")
 type AuthMiddleware struct {
 	tokenManager *TokenManager
 	publicPaths  []string
+
+	restaurantTokens RestaurantTokenFinder   // nil until EnableRestaurantTokens
+	restaurantLimit  *middleware.RateLimiter // optional, one bucket per token
+}
+
+// RestaurantTokenFinder looks up an active restaurant API token by secret,
+// returning nil when there is none
+type RestaurantTokenFinder interface {
+	FindRestaurantToken(ctx context.Context, secret string) (*models.RestaurantToken, error)
 }
 
+// restaurantTokenPath is the only path restaurant API tokens are accepted
+// on: they carry orders:write alone, and orders are only written over MCP
+const restaurantTokenPath = "/mcp"
+
 // NewAuthMiddleware creates a new auth middleware
 func NewAuthMiddleware(tokenManager *TokenManager, publicPaths []string) *AuthMiddleware {
 	if publicPaths == nil {
@@ -29,6 +47,9 @@ func NewAuthMiddleware(tokenManager *TokenManager, publicPaths []string) *AuthMi
 			"/oauth/callback",
 			"/oauth/register",
 			"/oauth/token",
+			"/ui/login",
+			"/ui/callback",
+			"/public/",
 		}
 	}
 	return &AuthMiddleware{
@@ -37,6 +58,14 @@ func NewAuthMiddleware(tokenManager *TokenManager, publicPaths []string) *AuthMi
 	}
 }
 
+// EnableRestaurantTokens accepts "Authorization: Token <secret>" for the
+// restaurant API tokens finder knows. limit, if not nil, is applied to
+// each token separately; key it with RestaurantTokenKey.
+func (am *AuthMiddleware) EnableRestaurantTokens(finder RestaurantTokenFinder, limit *middleware.RateLimiter) {
+	am.restaurantTokens = finder
+	am.restaurantLimit = limit
+}
+
 // Middleware wraps an HTTP handler with authentication
 func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -46,25 +75,31 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Get Authorization header
+		// Get Authorization header, falling back to the web UI session cookie
 		authHeader := r.Header.Get("Authorization")
+		var token string
 		if authHeader == "" {
-			am.unauthorized(w, "Missing Authorization header")
-			return
-		}
-
-		// Check Bearer token
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			am.unauthorized(w, "Invalid Authorization header format")
+			token = am.sessionToken(r)
+			if token == "" {
+				am.unauthorizedRequest(w, r, "Missing Authorization header")
+				return
+			}
+		} else if secret, ok := strings.CutPrefix(authHeader, "Token "); ok && am.restaurantTokens != nil {
+			am.serveRestaurantToken(w, r, next, secret)
 			return
+		} else {
+			// Check Bearer token
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				am.unauthorized(w, "Invalid Authorization header format")
+				return
+			}
+			token = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-
 		// Validate token
 		claims, err := am.tokenManager.ValidateToken(token)
 		if err != nil {
-			am.unauthorized(w, "Invalid or expired token")
+			am.unauthorizedRequest(w, r, "Invalid or expired token")
 			return
 		}
 
@@ -83,6 +118,61 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// serveRestaurantToken authenticates a request made with a restaurant API
+// token as a synthetic user scoped to the token's restaurant
+func (am *AuthMiddleware) serveRestaurantToken(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	if r.URL.Path != restaurantTokenPath {
+		am.unauthorized(w, "Restaurant tokens are only accepted on "+restaurantTokenPath)
+		return
+	}
+	token, err := am.restaurantTokens.FindRestaurantToken(r.Context(), secret)
+	if err != nil {
+		log.Printf("Restaurant token lookup failed: %v", err)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if token == nil {
+		am.unauthorized(w, "Invalid, expired or revoked token")
+		return
+	}
+
+	userCtx := map[string]interface{}{
+		"sub":           RestaurantTokenSubject(token.ID),
+		"name":          token.Name,
+		"client_id":     "restaurant-token",
+		"scope":         models.RestaurantTokenScope,
+		"restaurant_id": token.RestaurantID,
+		"token_name":    token.Name,
+	}
+	ctx := context.WithValue(r.Context(), UserContextKey, userCtx)
+	if am.restaurantLimit != nil {
+		next = am.restaurantLimit.Middleware(next)
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RestaurantTokenSubject is the sub of the synthetic user a restaurant API
+// token authenticates as
+func RestaurantTokenSubject(tokenID int) string {
+	return "restaurant-token:" + strconv.Itoa(tokenID)
+}
+
+// RestaurantTokenFromContext returns the restaurant and name of the API
+// token a request is authenticated with, and false for any other user
+func RestaurantTokenFromContext(ctx context.Context) (restaurantID int, name string, ok bool) {
+	user := GetUserFromContext(ctx)
+	restaurantID, ok = user["restaurant_id"].(int)
+	name, _ = user["token_name"].(string)
+	return restaurantID, name, ok
+}
+
+// RestaurantTokenKey keys a rate limiter by restaurant API token, so every
+// token gets its own bucket
+func RestaurantTokenKey(r *http.Request) string {
+	sub, _ := GetUserFromContext(r.Context())["sub"].(string)
+	return sub
+}
+
 // isPublicPath checks if the path is public
 func (am *AuthMiddleware) isPublicPath(path string) bool {
 	for _, publicPath := range am.publicPaths {
@@ -93,6 +183,30 @@ func (am *AuthMiddleware) isPublicPath(path string) bool {
 	return false
 }
 
+// sessionToken returns the access token from the web UI session cookie.
+// The UI is read-only, so the cookie is only honoured on safe methods; that
+// keeps cross-site form posts from riding on it.
+func (am *AuthMiddleware) sessionToken(r *http.Request) string {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return ""
+	}
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// unauthorizedRequest sends browsers on /ui to the login page and everyone
+// else an unauthorized response
+func (am *AuthMiddleware) unauthorizedRequest(w http.ResponseWriter, r *http.Request, message string) {
+	if r.URL.Path == "/ui" || strings.HasPrefix(r.URL.Path, "/ui/") {
+		http.Redirect(w, r, "/ui/login", http.StatusFound)
+		return
+	}
+	am.unauthorized(w, message)
+}
+
 // unauthorized sends an unauthorized response
 func (am *AuthMiddleware) unauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("WWW-Authenticate", "Bearer realm=\"MCP OAuth\"")
@@ -17,10 +17,17 @@ import (
 type Provider struct {
 	config *config.OAuthConfig
 	oauth2Config *oauth2.Config
+	httpClient *http.Client
 }
 
 // NewProvider creates a new OAuth provider
 func NewProvider(cfg *config.OAuthConfig) *Provider {
+	return NewProviderWithClient(cfg, http.DefaultClient)
+}
+
+// NewProviderWithClient makes every call to the provider (token exchange
+// and user info) through client, e.g. one pointed at a fake provider
+func NewProviderWithClient(cfg *config.OAuthConfig, client *http.Client) *Provider {
 	oauth2Cfg := &oauth2.Config{
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
@@ -35,6 +42,7 @@ func NewProvider(cfg *config.OAuthConfig) *Provider {
 	return &Provider{
 		config:       cfg,
 		oauth2Config: oauth2Cfg,
+		httpClient:   client,
 	}
 }
 
@@ -45,6 +53,7 @@ func (p *Provider) GetAuthorizationURL(state string) string {
 
 // ExchangeCodeForToken exchanges authorization code for access token
 func (p *Provider) ExchangeCodeForToken(ctx context.Context, code string) (*oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
 	token, err := p.oauth2Config.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
@@ -61,8 +70,7 @@ func (p *Provider) GetUserInfo(ctx context.Context, accessToken string) (*models
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
@@ -0,0 +1,93 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// RestaurantTokenPrefix starts every restaurant API token secret so a leaked
+// one is recognisable in logs and secret scanners
+const RestaurantTokenPrefix = "rt_"
+
+const restaurantTokenColumns = `id, restaurant_id, name, created_by, created_at, expires_at, last_used_at, revoked_at`
+
+func scanRestaurantToken(row interface{ Scan(...interface{}) error }) (*models.RestaurantToken, error) {
+	t := &models.RestaurantToken{}
+	err := row.Scan(&t.ID, &t.RestaurantID, &t.Name, &t.CreatedBy, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt)
+	return t, err
+}
+
+// CreateRestaurantToken issues an API token for restaurantID. The returned
+// secret is not stored and can't be recovered later.
+func (s *Storage) CreateRestaurantToken(ctx context.Context, restaurantID int, name, createdBy string, expiresAt *time.Time) (*models.RestaurantToken, string, error) {
+	secret := RestaurantTokenPrefix + generateSecureSecret()
+	t, err := scanRestaurantToken(s.db.QueryRowContext(ctx, `
+		INSERT INTO restaurant_api_tokens (restaurant_id, name, token_hash, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+restaurantTokenColumns,
+		restaurantID, name, HashTokenID(secret), createdBy, expiresAt))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create restaurant token: %w", err)
+	}
+	return t, secret, nil
+}
+
+// ListRestaurantTokens returns the tokens of restaurantID, revoked and
+// expired ones included, newest first
+func (s *Storage) ListRestaurantTokens(ctx context.Context, restaurantID int) ([]models.RestaurantToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+restaurantTokenColumns+`
+		FROM restaurant_api_tokens
+		WHERE restaurant_id = $1
+		ORDER BY created_at DESC, id DESC`, restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list restaurant tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.RestaurantToken{}
+	for rows.Next() {
+		t, err := scanRestaurantToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list restaurant tokens: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeRestaurantToken revokes token id and returns it, or nil if there is
+// no such token. Revoking twice keeps the first revocation time.
+func (s *Storage) RevokeRestaurantToken(ctx context.Context, id int) (*models.RestaurantToken, error) {
+	t, err := scanRestaurantToken(s.db.QueryRowContext(ctx, `
+		UPDATE restaurant_api_tokens SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING `+restaurantTokenColumns, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke restaurant token: %w", err)
+	}
+	return t, nil
+}
+
+// FindRestaurantToken returns the unrevoked, unexpired token with secret and
+// notes it as used, or nil if there is none
+func (s *Storage) FindRestaurantToken(ctx context.Context, secret string) (*models.RestaurantToken, error) {
+	t, err := scanRestaurantToken(s.db.QueryRowContext(ctx, `
+		UPDATE restaurant_api_tokens SET last_used_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		RETURNING `+restaurantTokenColumns, HashTokenID(secret), clock()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find restaurant token: %w", err)
+	}
+	return t, nil
+}
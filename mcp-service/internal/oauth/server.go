@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/vishalk17/mcp-service-restaurant/internal/config"
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
 	"github.com/vishalk17/mcp-service-restaurant/internal/models"
 )
 
@@ -39,7 +41,12 @@ type Server struct {
 
 // NewServer creates a new OAuth server
 func NewServer(cfg *config.Config, storage *Storage) *Server {
-	provider := NewProvider(cfg.OAuth)
+	return NewServerWithProvider(cfg, storage, NewProvider(cfg.OAuth))
+}
+
+// NewServerWithProvider uses provider for the upstream login instead of the
+// one described by cfg.OAuth, e.g. a fake identity provider in tests
+func NewServerWithProvider(cfg *config.Config, storage *Storage, provider *Provider) *Server {
 	tokenManager := NewTokenManager(
 		cfg.Server.JWTSecret,
 		cfg.Server.OAuthServerURL,
@@ -120,6 +127,7 @@ func (s *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	errorParam := r.URL.Query().Get("error")
 
 	if errorParam != "" {
+		rejectedLogin("provider_error")
 		http.Error(w, fmt.Sprintf("OAuth error: %s", errorParam), http.StatusBadRequest)
 		return
 	}
@@ -151,6 +159,7 @@ func (s *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	token, err := s.provider.ExchangeCodeForToken(r.Context(), code)
 	if err != nil {
 		log.Printf("Failed to exchange code: %v", err)
+		rejectedLogin("code_exchange_failed")
 		s.redirectWithError(w, r, redirectURI, "access_denied", "Failed to authenticate", originalState)
 		return
 	}
@@ -159,6 +168,7 @@ func (s *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	userInfo, err := s.provider.GetUserInfo(r.Context(), token.AccessToken)
 	if err != nil {
 		log.Printf("Failed to get user info: %v", err)
+		rejectedLogin("userinfo_failed")
 		s.redirectWithError(w, r, redirectURI, "access_denied", "Failed to get user info", originalState)
 		return
 	}
@@ -174,6 +184,7 @@ func (s *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	if user == nil {
 		// User not in database - reject
 		log.Printf("Unauthorized user attempt: %s", userInfo.Email)
+		rejectedLogin("not_allowed")
 		s.redirectWithError(w, r, redirectURI, "access_denied", "User not authorized", originalState)
 		return
 	}
@@ -195,7 +206,7 @@ func (s *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		RedirectURI: redirectURI,
 		Scope:       scope,
 		UserInfo:    userInfo,
-		ExpiresAt:   time.Now().Add(10 * time.Minute),
+		ExpiresAt:   clock().Add(10 * time.Minute),
 	}
 	s.authCodesMux.Unlock()
 
@@ -254,7 +265,7 @@ func (s *Server) handleAuthorizationCodeGrant(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if time.Now().After(authCode.ExpiresAt) {
+	if clock().After(authCode.ExpiresAt) {
 		s.jsonError(w, "invalid_grant", "Authorization code expired", http.StatusBadRequest)
 		return
 	}
@@ -272,7 +283,7 @@ func (s *Server) handleAuthorizationCodeGrant(w http.ResponseWriter, r *http.Req
 	}
 
 	// Create tokens
-	tokens, err := s.tokenManager.CreateTokens(user, clientID, authCode.Scope)
+	tokens, err := s.tokenManager.CreateTokens(user, clientID, authCode.Scope, middleware.ClientIP(r))
 	if err != nil {
 		log.Printf("Failed to create tokens: %v", err)
 		s.jsonError(w, "server_error", "Failed to create tokens", http.StatusInternalServerError)
@@ -293,7 +304,7 @@ func (s *Server) handleRefreshTokenGrant(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Refresh tokens
-	tokens, err := s.tokenManager.RefreshToken(refreshToken, s.storage)
+	tokens, err := s.tokenManager.RefreshToken(refreshToken, s.storage, middleware.ClientIP(r))
 	if err != nil {
 		log.Printf("Failed to refresh token: %v", err)
 		s.jsonError(w, "invalid_grant", "Invalid refresh token", http.StatusBadRequest)
@@ -418,6 +429,13 @@ func (s *Server) HandleJWKS(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(jwks)
 }
 
+// rejectedLogin counts a failed or refused login at the callback. reason is
+// one of a fixed set of values so the label stays low-cardinality.
+func rejectedLogin(reason string) {
+	metrics.AddCounterWithLabels("oauth_login_rejected_total", "Logins rejected at the OAuth callback",
+		map[string]string{"reason": reason}, 1)
+}
+
 // Helper functions
 
 func (s *Server) redirectWithError(w http.ResponseWriter, r *http.Request, redirectURI, errorCode, errorDesc, state string) {
@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/middleware"
+)
+
+// Session cookie auth for the built-in web UI. The browser logs in through
+// the same identity provider and email whitelist as MCP clients; the access
+// token is then kept in an HttpOnly cookie instead of an Authorization header.
+const (
+	// SessionCookieName holds the access token of a web UI session
+	SessionCookieName = "mcp_session"
+
+	// UIClientID is the client_id tokens issued to the web UI carry
+	UIClientID = "web-ui"
+
+	uiStateCookieName = "mcp_ui_state"
+	uiCallbackPath    = "/ui/callback"
+)
+
+// HandleUILogin starts a web UI login by redirecting to the identity provider
+func (s *Server) HandleUILogin(w http.ResponseWriter, r *http.Request) {
+	nonce := generateState()
+	stateData := map[string]string{
+		"client_id":    UIClientID,
+		"redirect_uri": s.config.Server.OAuthServerURL + uiCallbackPath,
+		"scope":        "openid profile email",
+		"state":        nonce,
+	}
+	stateJSON, _ := json.Marshal(stateData)
+	encodedState := base64.URLEncoding.EncodeToString(stateJSON)
+
+	// Bind the login to this browser so a callback can't be replayed into another one
+	http.SetCookie(w, &http.Cookie{
+		Name:     uiStateCookieName,
+		Value:    nonce,
+		Path:     uiCallbackPath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.provider.GetAuthorizationURL(encodedState), http.StatusFound)
+}
+
+// HandleUICallback receives the authorization code issued by HandleCallback
+// for the web UI, exchanges it for tokens and stores the access token in the
+// session cookie
+func (s *Server) HandleUICallback(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+
+	stateCookie, err := r.Cookie(uiStateCookieName)
+	if err != nil || state == "" || stateCookie.Value != state {
+		http.Error(w, "Invalid login state, please sign in again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: uiStateCookieName, Path: uiCallbackPath, MaxAge: -1})
+
+	s.authCodesMux.Lock()
+	authCode, exists := s.authCodes[code]
+	if exists {
+		delete(s.authCodes, code) // Single use
+	}
+	s.authCodesMux.Unlock()
+
+	if !exists || authCode.ClientID != UIClientID || clock().After(authCode.ExpiresAt) {
+		http.Error(w, "Invalid or expired authorization code", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.storage.FindUserByEmail(authCode.UserInfo.Email)
+	if err != nil || user == nil {
+		http.Error(w, "User not authorized", http.StatusForbidden)
+		return
+	}
+
+	tokens, err := s.tokenManager.CreateTokens(user, UIClientID, authCode.Scope, middleware.ClientIP(r))
+	if err != nil {
+		log.Printf("Failed to create UI session tokens: %v", err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		MaxAge:   int(tokens.ExpiresIn),
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/ui", http.StatusFound)
+}
+
+// HandleUILogout clears the web UI session cookie
+func (s *Server) HandleUILogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		s.tokenManager.RevokeToken(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookieName, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/ui/login", http.StatusFound)
+}
+
+// secureCookies reports whether cookies should be limited to HTTPS, which
+// is the case whenever the server is published over HTTPS
+func (s *Server) secureCookies() bool {
+	return strings.HasPrefix(s.config.Server.OAuthServerURL, "https://")
+}
@@ -1,22 +1,27 @@
 package oauth
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"time"
+	"sync/atomic"
 
 	"github.com/vishalk17/mcp-service-restaurant/internal/models"
 )
 
 // Storage handles OAuth database operations
 type Storage struct {
-	db *sql.DB
+	db             *sql.DB
+	legacyTokenIDs atomic.Bool // also match raw token ids written before hashing
 }
 
 // NewStorage creates a new OAuth storage
 func NewStorage(db *sql.DB) *Storage {
-	return &Storage{db: db}
+	s := &Storage{db: db}
+	s.legacyTokenIDs.Store(true)
+	return s
 }
 
 // ============================================
@@ -166,18 +171,36 @@ func (s *Storage) ValidateClientRedirectURI(clientID, redirectURI string) (bool,
 // Token Operations
 // ============================================
 
-// SaveTokenMetadata saves token metadata for revocation
+// HashTokenID returns the at-rest form of a JWT token_id claim. Token ids are
+// only ever compared for equality, so storing a SHA-256 digest is enough.
+func HashTokenID(tokenID string) string {
+	sum := sha256.Sum256([]byte(tokenID))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenIDMatch returns the WHERE clause and arguments that find a token row.
+// While rows written before hashing was introduced may still exist, the raw
+// id is matched as well; the cleanup job turns this off once they are gone.
+func (s *Storage) tokenIDMatch(tokenID string) (string, []interface{}) {
+	if s.legacyTokenIDs.Load() {
+		return "token_id IN ($1, $2)", []interface{}{HashTokenID(tokenID), tokenID}
+	}
+	return "token_id = $1", []interface{}{HashTokenID(tokenID)}
+}
+
+// SaveTokenMetadata saves token metadata for revocation. token.TokenID is the
+// raw claim; only its hash is persisted.
 func (s *Storage) SaveTokenMetadata(token *models.OAuthToken) error {
 	query := `
 		INSERT INTO oauth_tokens (
-			token_id, client_id, user_id, token_type, scope, expires_at, active
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			token_id, client_id, user_id, token_type, scope, expires_at, active, created_by_ip
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
 	`
 	
 	_, err := s.db.Exec(
 		query,
-		token.TokenID, token.ClientID, token.UserID, token.TokenType,
-		token.Scope, token.ExpiresAt, token.Active,
+		HashTokenID(token.TokenID), token.ClientID, token.UserID, token.TokenType,
+		token.Scope, token.ExpiresAt, token.Active, token.CreatedByIP,
 	)
 	
 	if err != nil {
@@ -187,20 +210,23 @@ func (s *Storage) SaveTokenMetadata(token *models.OAuthToken) error {
 	return nil
 }
 
-// GetTokenMetadata retrieves token metadata
+// GetTokenMetadata retrieves token metadata by raw token id
 func (s *Storage) GetTokenMetadata(tokenID string) (*models.OAuthToken, error) {
+	where, args := s.tokenIDMatch(tokenID)
 	query := `
 		SELECT id, token_id, client_id, user_id, token_type, scope,
-		       expires_at, created_at, active
+		       expires_at, created_at, active, COALESCE(created_by_ip, '')
 		FROM oauth_tokens
-		WHERE token_id = $1
+		WHERE ` + where + `
+		ORDER BY length(token_id) DESC
+		LIMIT 1
 	`
 	
 	token := &models.OAuthToken{}
-	err := s.db.QueryRow(query, tokenID).Scan(
+	err := s.db.QueryRow(query, args...).Scan(
 		&token.ID, &token.TokenID, &token.ClientID, &token.UserID,
 		&token.TokenType, &token.Scope, &token.ExpiresAt,
-		&token.CreatedAt, &token.Active,
+		&token.CreatedAt, &token.Active, &token.CreatedByIP,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -215,28 +241,66 @@ func (s *Storage) GetTokenMetadata(tokenID string) (*models.OAuthToken, error) {
 
 // RevokeToken marks a token as inactive
 func (s *Storage) RevokeToken(tokenID string) error {
-	query := `UPDATE oauth_tokens SET active = false WHERE token_id = $1`
-	_, err := s.db.Exec(query, tokenID)
+	where, args := s.tokenIDMatch(tokenID)
+	query := `UPDATE oauth_tokens SET active = false WHERE ` + where
+	_, err := s.db.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to revoke token: %w", err)
 	}
 	return nil
 }
 
-// CleanupExpiredTokens removes expired token metadata
-func (s *Storage) CleanupExpiredTokens() error {
+// CleanupExpiredTokens removes expired token metadata and returns the number
+// of rows deleted
+func (s *Storage) CleanupExpiredTokens() (int64, error) {
 	query := `DELETE FROM oauth_tokens WHERE expires_at < NOW()`
 	result, err := s.db.Exec(query)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup expired tokens: %w", err)
+		return 0, fmt.Errorf("failed to cleanup expired tokens: %w", err)
 	}
 	
 	rows, _ := result.RowsAffected()
-	if rows > 0 {
-		fmt.Printf("Cleaned up %d expired tokens\n", rows)
+	return rows, nil
+}
+
+// VacuumTokens reclaims space and refreshes planner statistics after a large
+// cleanup. VACUUM needs table ownership, so callers should treat failure as
+// advisory.
+func (s *Storage) VacuumTokens() error {
+	_, err := s.db.Exec(`VACUUM ANALYZE oauth_tokens`)
+	return err
+}
+
+// TokenTableStats describes the oauth_tokens table for monitoring
+type TokenTableStats struct {
+	Rows          int64
+	LegacyRows    int64 // rows still keyed by a raw (unhashed) token id
+	SizeBytes     int64
+	OldestAgeSecs float64
+}
+
+// GetTokenTableStats returns row counts, on-disk size and oldest row age
+func (s *Storage) GetTokenTableStats() (*TokenTableStats, error) {
+	query := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE length(token_id) <> 64),
+		       pg_total_relation_size('oauth_tokens'),
+		       COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)
+		FROM oauth_tokens
+	`
+	
+	stats := &TokenTableStats{}
+	err := s.db.QueryRow(query).Scan(&stats.Rows, &stats.LegacyRows, &stats.SizeBytes, &stats.OldestAgeSecs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token table stats: %w", err)
 	}
 	
-	return nil
+	// Once no raw ids remain the deprecation window is over
+	if stats.LegacyRows == 0 {
+		s.legacyTokenIDs.Store(false)
+	}
+	
+	return stats, nil
 }
 
 // IsTokenRevoked checks if a token has been revoked
@@ -250,7 +314,7 @@ func (s *Storage) IsTokenRevoked(tokenID string) (bool, error) {
 	}
 	
 	// Check if expired
-	if time.Now().After(token.ExpiresAt) {
+	if clock().After(token.ExpiresAt) {
 		return true, nil
 	}
 	
@@ -29,9 +29,10 @@ func NewTokenManager(jwtSecret string, issuer string, accessLife, refreshLife in
 	}
 }
 
-// CreateTokens creates access and refresh tokens for a user
-func (tm *TokenManager) CreateTokens(user *models.User, clientID, scope string) (*models.TokenResponse, error) {
-	now := time.Now()
+// CreateTokens creates access and refresh tokens for a user. clientIP is
+// recorded with the token metadata for forensics.
+func (tm *TokenManager) CreateTokens(user *models.User, clientID, scope, clientIP string) (*models.TokenResponse, error) {
+	now := clock()
 	accessTokenID := uuid.New().String()
 	refreshTokenID := uuid.New().String()
 
@@ -77,13 +78,14 @@ func (tm *TokenManager) CreateTokens(user *models.User, clientID, scope string)
 
 	// Save token metadata for revocation tracking
 	accessTokenMeta := &models.OAuthToken{
-		TokenID:   accessTokenID,
-		ClientID:  clientID,
-		UserID:    user.UserID,
-		TokenType: "access_token",
-		Scope:     scope,
-		ExpiresAt: time.Unix(accessClaims["exp"].(int64), 0),
-		Active:    true,
+		TokenID:     accessTokenID,
+		ClientID:    clientID,
+		UserID:      user.UserID,
+		TokenType:   "access_token",
+		Scope:       scope,
+		ExpiresAt:   time.Unix(accessClaims["exp"].(int64), 0),
+		Active:      true,
+		CreatedByIP: clientIP,
 	}
 	if err := tm.storage.SaveTokenMetadata(accessTokenMeta); err != nil {
 		// Log but don't fail - token is still valid
@@ -91,13 +93,14 @@ func (tm *TokenManager) CreateTokens(user *models.User, clientID, scope string)
 	}
 
 	refreshTokenMeta := &models.OAuthToken{
-		TokenID:   refreshTokenID,
-		ClientID:  clientID,
-		UserID:    user.UserID,
-		TokenType: "refresh_token",
-		Scope:     scope,
-		ExpiresAt: time.Unix(refreshClaims["exp"].(int64), 0),
-		Active:    true,
+		TokenID:     refreshTokenID,
+		ClientID:    clientID,
+		UserID:      user.UserID,
+		TokenType:   "refresh_token",
+		Scope:       scope,
+		ExpiresAt:   time.Unix(refreshClaims["exp"].(int64), 0),
+		Active:      true,
+		CreatedByIP: clientIP,
 	}
 	if err := tm.storage.SaveTokenMetadata(refreshTokenMeta); err != nil {
 		fmt.Printf("Warning: failed to save refresh token metadata: %v\n", err)
@@ -119,7 +122,7 @@ func (tm *TokenManager) ValidateToken(tokenString string) (jwt.MapClaims, error)
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return tm.jwtSecret, nil
-	})
+	}, jwt.WithTimeFunc(clock))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -136,7 +139,7 @@ func (tm *TokenManager) ValidateToken(tokenString string) (jwt.MapClaims, error)
 
 	// Check expiration
 	if exp, ok := claims["exp"].(float64); ok {
-		if time.Now().Unix() > int64(exp) {
+		if clock().Unix() > int64(exp) {
 			return nil, fmt.Errorf("token expired")
 		}
 	}
@@ -162,7 +165,7 @@ func (tm *TokenManager) ValidateToken(tokenString string) (jwt.MapClaims, error)
 }
 
 // RefreshToken creates new tokens from a refresh token
-func (tm *TokenManager) RefreshToken(refreshTokenString string, storage *Storage) (*models.TokenResponse, error) {
+func (tm *TokenManager) RefreshToken(refreshTokenString string, storage *Storage, clientIP string) (*models.TokenResponse, error) {
 	claims, err := tm.ValidateToken(refreshTokenString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
@@ -192,7 +195,7 @@ func (tm *TokenManager) RefreshToken(refreshTokenString string, storage *Storage
 	}
 
 	// Create new tokens
-	return tm.CreateTokens(user, clientID, scope)
+	return tm.CreateTokens(user, clientID, scope, clientIP)
 }
 
 // RevokeToken revokes a token
@@ -0,0 +1,230 @@
+// Package publicmenu serves a restaurant's current menu without
+// authentication, as an HTML page for embedding in the restaurant's own
+// website or as JSON. Only restaurants with public_menu_enabled are served;
+// every other ID gets the same 404 as one that doesn't exist.
+package publicmenu
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+//go:embed menu.html
+var files embed.FS
+
+// CacheControl lets browsers and CDNs reuse a menu for five minutes;
+// after that the ETag makes revalidation cheap
+const CacheControl = "public, max-age=300"
+
+// Menu is what both formats render: the available items effective today
+// in the restaurant's timezone, grouped by category
+type Menu struct {
+	Restaurant Restaurant `json:"restaurant"`
+	Categories []Category `json:"categories"`
+}
+
+// Restaurant is the public part of a restaurant's details
+type Restaurant struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	CuisineType string `json:"cuisine_type,omitempty"`
+}
+
+// Category is one menu section, in the order the menu lists them
+type Category struct {
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+// Item is one dish as guests see it
+type Item struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Price       models.Money `json:"price"`
+	DietaryType string       `json:"dietary_type,omitempty"`
+	SpiceLevel  string       `json:"spice_level,omitempty"`
+}
+
+// Handler serves GET /public/restaurants/{id}/menu
+type Handler struct {
+	store storage.Store
+	page  *template.Template
+}
+
+// NewHandler parses the embedded page template
+func NewHandler(store storage.Store) (*Handler, error) {
+	page, err := template.New("menu.html").Funcs(template.FuncMap{
+		"price":   formatPrice,
+		"dietary": dietaryLabel,
+		"spice":   spiceLabel,
+	}).ParseFS(files, "menu.html")
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{store: store, page: page}, nil
+}
+
+// ServeHTTP renders the menu as HTML, or as JSON with ?format=json
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "json" {
+		http.Error(w, "Invalid format, expected html or json", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "Menu not found", http.StatusNotFound)
+		return
+	}
+
+	menu, err := h.load(r, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Menu not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading public menu of restaurant %d: %v", id, err)
+		http.Error(w, "Menu unavailable, please try again later", http.StatusInternalServerError)
+		return
+	}
+
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json"
+		err = json.NewEncoder(&body).Encode(menu)
+		// Public data without credentials; any site may fetch it, unless
+		// the CORS policy already answered for a configured origin
+		if w.Header().Get("Access-Control-Allow-Origin") == "" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+	} else {
+		err = h.page.Execute(&body, menu)
+	}
+	if err != nil {
+		log.Printf("Error rendering public menu of restaurant %d: %v", id, err)
+		http.Error(w, "Menu unavailable, please try again later", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", CacheControl)
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	if r.Method != http.MethodHead {
+		w.Write(body.Bytes())
+	}
+}
+
+// load returns the menu of an active restaurant that publishes it. Any
+// other restaurant is reported as not found so IDs can't be probed.
+func (h *Handler) load(r *http.Request, id int) (*Menu, error) {
+	restaurant, err := h.store.GetRestaurantByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if !restaurant.PublicMenuEnabled || !restaurant.Active {
+		return nil, storage.ErrNotFound
+	}
+	items, err := h.store.GetMenuByRestaurantID(r.Context(), id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	menu := &Menu{
+		Restaurant: Restaurant{
+			ID:          restaurant.ID,
+			Name:        restaurant.Name,
+			Address:     restaurant.Address,
+			PhoneNumber: restaurant.PhoneNumber,
+			CuisineType: restaurant.CuisineType,
+		},
+		Categories: []Category{},
+	}
+	// Items come sorted by category, so each category is one run
+	for _, m := range items {
+		name := m.Category
+		if name == "" {
+			name = "Other"
+		}
+		if n := len(menu.Categories); n == 0 || menu.Categories[n-1].Name != name {
+			menu.Categories = append(menu.Categories, Category{Name: name})
+		}
+		c := &menu.Categories[len(menu.Categories)-1]
+		c.Items = append(c.Items, Item{
+			Name:        m.Name,
+			Description: m.Description,
+			Price:       m.Price,
+			DietaryType: m.DietaryType,
+			SpiceLevel:  m.SpiceLevel,
+		})
+	}
+	return menu, nil
+}
+
+// matchesETag reports whether an If-None-Match header lists etag
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPrice shows whole rupees without decimals: ₹180, ₹180.50
+func formatPrice(p models.Money) string {
+	if p%100 == 0 {
+		return "₹" + strconv.FormatInt(int64(p/100), 10)
+	}
+	return p.String()
+}
+
+func dietaryLabel(t string) string {
+	switch t {
+	case models.DietaryVegetarian:
+		return "Veg"
+	case models.DietaryNonVegetarian:
+		return "Non-veg"
+	case models.DietaryVegan:
+		return "Vegan"
+	case models.DietaryJainFriendly:
+		return "Jain"
+	}
+	return t
+}
+
+func spiceLabel(level string) string {
+	switch level {
+	case models.SpiceMild:
+		return "Mild"
+	case models.SpiceMedium:
+		return "Medium spicy"
+	case models.SpiceHot:
+		return "Hot"
+	case models.SpiceExtraHot:
+		return "Extra hot"
+	}
+	return level
+}
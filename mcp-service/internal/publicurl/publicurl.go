@@ -0,0 +1,180 @@
+// Package publicurl builds the absolute links that responses and tool output
+// embed, such as payment links, downloads and receipts. Links are always
+// based on a configured public URL, or on the forwarded host a trusted
+// proxy reports, never on the Host a request arrived with, so internal
+// cluster hostnames don't leak to clients.
+package publicurl
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Builder makes absolute URLs for paths served by this service
+type Builder struct {
+	base     *url.URL // PUBLIC_BASE_URL, nil when unset
+	fallback *url.URL // used when neither PUBLIC_BASE_URL nor a trusted proxy applies
+	trusted  []*net.IPNet
+}
+
+// Parse validates a base URL: it must be an absolute http or https URL
+// without query or fragment. A trailing slash is dropped.
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: must be an absolute http or https URL", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid URL %q: must not have a query or fragment", raw)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
+
+// New returns a Builder. publicBaseURL and fallbackURL may be empty;
+// trustedProxies are IP addresses or CIDR ranges whose X-Forwarded-Host
+// and X-Forwarded-Proto headers are believed.
+func New(publicBaseURL, fallbackURL string, trustedProxies []string) (*Builder, error) {
+	b := &Builder{}
+	if publicBaseURL != "" {
+		u, err := Parse(publicBaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("PUBLIC_BASE_URL: %v", err)
+		}
+		b.base = u
+	}
+	if fallbackURL != "" {
+		u, err := Parse(fallbackURL)
+		if err != nil {
+			return nil, err
+		}
+		b.fallback = u
+	}
+	for _, p := range trustedProxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: invalid address %q", p)
+		}
+		b.trusted = append(b.trusted, network)
+	}
+	return b, nil
+}
+
+// FromEnv returns a Builder configured by PUBLIC_BASE_URL and TRUSTED_PROXIES
+// (comma separated), for the binaries that don't use internal/config
+func FromEnv(fallbackURL string) (*Builder, error) {
+	return New(os.Getenv("PUBLIC_BASE_URL"), fallbackURL, SplitList(os.Getenv("TRUSTED_PROXIES")))
+}
+
+// SplitList splits a comma separated setting, dropping empty entries
+func SplitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Base returns the public base URL for a request, or "" when none is known.
+// r may be nil for transports without HTTP requests, such as stdio.
+func (b *Builder) Base(r *http.Request) string {
+	if u := b.baseFor(r); u != nil {
+		return u.String()
+	}
+	return ""
+}
+
+// URL returns the absolute URL of path (which should start with "/") with
+// the given query, or "" when no public base URL is known, in which case the
+// caller should leave the link out
+func (b *Builder) URL(r *http.Request, path string, query url.Values) string {
+	base := b.baseFor(r)
+	if base == nil {
+		return ""
+	}
+	u := *base
+	u.Path = base.Path + path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (b *Builder) baseFor(r *http.Request) *url.URL {
+	if b.base != nil {
+		return b.base
+	}
+	if r != nil && b.fromTrustedProxy(r) {
+		if host := firstValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+			scheme := firstValue(r.Header.Get("X-Forwarded-Proto"))
+			if scheme != "http" && scheme != "https" {
+				scheme = "https"
+			}
+			if u, err := Parse(scheme + "://" + host); err == nil {
+				return u
+			}
+		}
+	}
+	return b.fallback
+}
+
+func (b *Builder) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range b.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client behind a request: the first
+// X-Forwarded-For hop when the request came through a trusted proxy, else
+// the peer address. Unlike middleware.ClientIP it can't be spoofed by the
+// client, so it may key rate limits.
+func (b *Builder) ClientIP(r *http.Request) string {
+	if b.fromTrustedProxy(r) {
+		if ip := firstValue(r.Header.Get("X-Forwarded-For")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// firstValue returns the first entry of a comma separated header, which is
+// the one the outermost proxy set
+func firstValue(v string) string {
+	if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
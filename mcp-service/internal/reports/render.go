@@ -0,0 +1,37 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+// Markdown renders the result as a markdown table
+func (res *Result) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(escapeCells(res.Columns), " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(res.Columns)) + "\n")
+	for _, row := range res.Rows {
+		b.WriteString("| " + strings.Join(escapeCells(row), " | ") + " |\n")
+	}
+	return b.String()
+}
+
+// CSV renders the result as CSV with a header row
+func (res *Result) CSV() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(res.Columns)
+	w.WriteAll(res.Rows)
+	return buf.String()
+}
+
+// escapeCells keeps cell text from breaking the table layout
+func escapeCells(cells []string) []string {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		c = strings.ReplaceAll(c, "|", `\|`)
+		out[i] = strings.NewReplacer("\r\n", " ", "\n", " ").Replace(c)
+	}
+	return out
+}
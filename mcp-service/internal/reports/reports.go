@@ -0,0 +1,318 @@
+// Package reports runs named, parameterized, read-only SQL reports for
+// admins. Each report is one file in templates/: a header of "--" comment
+// lines declaring its description and parameters, followed by the query.
+//
+//	-- description: Revenue per menu category
+//	-- param: from date required First order date to include
+//	-- param: restaurant_id integer optional Only this restaurant
+//	SELECT ... WHERE created_at >= $1::date AND ($2::int IS NULL OR restaurant_id = $2)
+//
+// Parameters bind to $1, $2... in declaration order; an omitted optional
+// parameter binds NULL. Adding a report needs no Go code.
+package reports
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+//go:embed templates/*.sql
+var templates embed.FS
+
+// Limits applied to every report run
+const (
+	MaxRows          = 500
+	StatementTimeout = 5 * time.Second
+)
+
+// Parameter types a report may declare
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeDate    = "date"
+	TypeBoolean = "boolean"
+)
+
+// Param is one declared report parameter
+type Param struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// Report is a parsed report template
+type Report struct {
+	Name        string
+	Description string
+	Params      []Param
+	query       string
+}
+
+// Result is the output of a report run
+type Result struct {
+	Columns   []string
+	Rows      [][]string
+	Truncated bool // more than MaxRows rows matched
+}
+
+var (
+	loadOnce sync.Once
+	catalog  map[string]*Report
+	loadErr  error
+)
+
+// All returns every report sorted by name, or the first template error
+func All() ([]*Report, error) {
+	loadOnce.Do(func() { catalog, loadErr = load() })
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	all := make([]*Report, 0, len(catalog))
+	for _, r := range catalog {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all, nil
+}
+
+// Names returns the report names, sorted
+func Names() []string {
+	all, _ := All()
+	names := make([]string, len(all))
+	for i, r := range all {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// Get returns the named report
+func Get(name string) (*Report, error) {
+	if _, err := All(); err != nil {
+		return nil, err
+	}
+	r, ok := catalog[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown report %q: must be one of %s", name, strings.Join(Names(), ", "))
+	}
+	return r, nil
+}
+
+func load() (map[string]*Report, error) {
+	files, err := templates.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string]*Report{}
+	for _, f := range files {
+		data, err := templates.ReadFile("templates/" + f.Name())
+		if err != nil {
+			return nil, err
+		}
+		r, err := parse(strings.TrimSuffix(f.Name(), path.Ext(f.Name())), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("report %s: %v", f.Name(), err)
+		}
+		reports[r.Name] = r
+	}
+	return reports, nil
+}
+
+func parse(name, text string) (*Report, error) {
+	r := &Report{Name: name}
+	var query strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	header := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header && strings.HasPrefix(line, "--") {
+			key, value, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "--")), ":")
+			value = strings.TrimSpace(value)
+			switch key {
+			case "description":
+				r.Description = value
+			case "param":
+				p, err := parseParam(value)
+				if err != nil {
+					return nil, err
+				}
+				r.Params = append(r.Params, p)
+			}
+			continue
+		}
+		header = false
+		query.WriteString(line)
+		query.WriteByte('\n')
+	}
+	r.query = strings.TrimSpace(query.String())
+	switch {
+	case r.Description == "":
+		return nil, fmt.Errorf("missing description")
+	case r.query == "":
+		return nil, fmt.Errorf("missing query")
+	}
+	return r, nil
+}
+
+// parseParam reads "<name> <type> required|optional <description>"
+func parseParam(spec string) (Param, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 3 {
+		return Param{}, fmt.Errorf("invalid param %q: want <name> <type> required|optional <description>", spec)
+	}
+	p := Param{Name: fields[0], Type: fields[1], Description: strings.Join(fields[3:], " ")}
+	switch p.Type {
+	case TypeString, TypeInteger, TypeNumber, TypeDate, TypeBoolean:
+	default:
+		return Param{}, fmt.Errorf("param %s: unknown type %q", p.Name, p.Type)
+	}
+	switch fields[2] {
+	case "required":
+		p.Required = true
+	case "optional":
+	default:
+		return Param{}, fmt.Errorf("param %s: want required or optional, got %q", p.Name, fields[2])
+	}
+	return p, nil
+}
+
+// Usage describes the report and its parameters in one line
+func (r *Report) Usage() string {
+	if len(r.Params) == 0 {
+		return r.Name + ": " + r.Description
+	}
+	params := make([]string, len(r.Params))
+	for i, p := range r.Params {
+		opt := ""
+		if !p.Required {
+			opt = ", optional"
+		}
+		params[i] = fmt.Sprintf("%s (%s%s)", p.Name, p.Type, opt)
+	}
+	return fmt.Sprintf("%s: %s. Params: %s", r.Name, r.Description, strings.Join(params, ", "))
+}
+
+// Bind validates tool arguments against the declared parameters and returns
+// them in placeholder order
+func (r *Report) Bind(args map[string]interface{}) ([]interface{}, error) {
+	declared := map[string]bool{}
+	values := make([]interface{}, len(r.Params))
+	for i, p := range r.Params {
+		declared[p.Name] = true
+		raw, ok := args[p.Name]
+		if !ok || raw == nil {
+			if p.Required {
+				return nil, fmt.Errorf("missing required param %s", p.Name)
+			}
+			continue
+		}
+		v, err := p.convert(raw)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %v", p.Name, err)
+		}
+		values[i] = v
+	}
+	for name := range args {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown param %s for report %s", name, r.Name)
+		}
+	}
+	return values, nil
+}
+
+func (p Param) convert(raw interface{}) (interface{}, error) {
+	switch p.Type {
+	case TypeString:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	case TypeInteger:
+		if f, ok := raw.(float64); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+		return nil, fmt.Errorf("must be an integer")
+	case TypeNumber:
+		if f, ok := raw.(float64); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	case TypeDate:
+		if s, ok := raw.(string); ok {
+			if _, err := time.Parse(models.DateLayout, s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+	case TypeBoolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be true or false")
+	}
+	return nil, fmt.Errorf("unknown type %s", p.Type)
+}
+
+// Run executes the report in a read-only transaction bounded by
+// StatementTimeout, keeping at most MaxRows rows
+func (r *Report) Run(ctx context.Context, db *sql.DB, args map[string]interface{}) (*Result, error) {
+	values, err := r.Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	timeout := fmt.Sprintf("SET LOCAL statement_timeout = %d", StatementTimeout.Milliseconds())
+	if _, err := tx.ExecContext(ctx, timeout); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, r.query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Columns: columns, Rows: [][]string{}}
+	for rows.Next() {
+		if len(result.Rows) == MaxRows {
+			result.Truncated = true
+			break
+		}
+		cells := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range cells {
+			dest[i] = &cells[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(columns))
+		for i, c := range cells {
+			row[i] = c.String
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
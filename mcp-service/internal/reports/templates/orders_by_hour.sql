@@ -0,0 +1,14 @@
+-- description: Order count and revenue per hour of day, to see the rush hours
+-- param: from date required First order date to include (YYYY-MM-DD)
+-- param: to date required Last order date to include (YYYY-MM-DD)
+-- param: restaurant_id integer optional Only orders of this restaurant
+-- param: include_cancelled boolean optional Count cancelled orders too (default false)
+SELECT EXTRACT(HOUR FROM o.created_at)::int AS hour,
+       COUNT(*) AS orders,
+       SUM(o.final_amount) AS revenue
+FROM orders o
+WHERE o.created_at >= $1::date AND o.created_at < $2::date + 1
+  AND ($3::int IS NULL OR o.restaurant_id = $3)
+  AND (COALESCE($4::boolean, false) OR o.status <> 'cancelled')
+GROUP BY 1
+ORDER BY 1
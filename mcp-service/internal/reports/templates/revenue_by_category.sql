@@ -0,0 +1,15 @@
+-- description: Quantity sold and revenue per menu category, excluding cancelled orders
+-- param: from date required First order date to include (YYYY-MM-DD)
+-- param: to date required Last order date to include (YYYY-MM-DD)
+-- param: restaurant_id integer optional Only orders of this restaurant
+SELECT COALESCE(NULLIF(mi.category, ''), 'Uncategorized') AS category,
+       SUM(oi.quantity) AS quantity,
+       SUM(oi.subtotal) AS revenue
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+JOIN menu_items mi ON mi.id = oi.menu_item_id
+WHERE o.status <> 'cancelled'
+  AND o.created_at >= $1::date AND o.created_at < $2::date + 1
+  AND ($3::int IS NULL OR o.restaurant_id = $3)
+GROUP BY 1
+ORDER BY revenue DESC, category
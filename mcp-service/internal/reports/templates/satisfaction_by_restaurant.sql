@@ -0,0 +1,17 @@
+-- description: Delivered orders, revenue and average staff-recorded satisfaction (1-5) per restaurant
+-- param: from date required First order date to include (YYYY-MM-DD)
+-- param: to date required Last order date to include (YYYY-MM-DD)
+-- param: restaurant_id integer optional Only this restaurant
+SELECT r.name AS restaurant,
+       COUNT(o.id) AS delivered_orders,
+       COALESCE(SUM(o.final_amount), 0) AS revenue,
+       COUNT(f.order_id) AS feedback_count,
+       ROUND(AVG(f.score), 2) AS avg_satisfaction,
+       COUNT(*) FILTER (WHERE f.contacted) AS contacted
+FROM restaurants r
+JOIN orders o ON o.restaurant_id = r.id AND o.status = 'delivered'
+LEFT JOIN order_feedback f ON f.order_id = o.id
+WHERE o.created_at >= $1::date AND o.created_at < $2::date + 1
+  AND ($3::int IS NULL OR r.id = $3)
+GROUP BY r.id, r.name
+ORDER BY revenue DESC, restaurant
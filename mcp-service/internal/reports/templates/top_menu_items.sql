@@ -0,0 +1,20 @@
+-- description: Best selling menu items by quantity, excluding cancelled orders
+-- param: from date required First order date to include (YYYY-MM-DD)
+-- param: to date required Last order date to include (YYYY-MM-DD)
+-- param: restaurant_id integer optional Only orders of this restaurant
+-- param: top integer optional Number of items to list (default 10)
+SELECT mi.id AS menu_item_id,
+       mi.name,
+       r.name AS restaurant,
+       SUM(oi.quantity) AS quantity,
+       SUM(oi.subtotal) AS revenue
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+JOIN menu_items mi ON mi.id = oi.menu_item_id
+JOIN restaurants r ON r.id = mi.restaurant_id
+WHERE o.status <> 'cancelled'
+  AND o.created_at >= $1::date AND o.created_at < $2::date + 1
+  AND ($3::int IS NULL OR o.restaurant_id = $3)
+GROUP BY mi.id, mi.name, r.name
+ORDER BY quantity DESC, revenue DESC, mi.id
+LIMIT LEAST(COALESCE($4::int, 10), 500)
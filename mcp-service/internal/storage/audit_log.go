@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrAuditLogUnavailable is returned while the database predates the
+// audit_log migration
+var ErrAuditLogUnavailable = errors.New("the audit log is not available until the database is migrated")
+
+// RecordAudit stores an audit log entry, filling in ID and CreatedAt
+func (db *DB) RecordAudit(ctx context.Context, e *models.AuditEntry) error {
+	if !db.schema.hasTable("audit_log") {
+		return ErrAuditLogUnavailable
+	}
+	arguments, err := json.Marshal(e.Arguments)
+	if err != nil {
+		return err
+	}
+	columns, values := "actor, tool, entity_type, entity_id, arguments", "$1, $2, $3, $4, $5"
+	args := []interface{}{e.Actor, e.Tool, e.EntityType, e.EntityID, arguments}
+	if e.Outcome != "" {
+		// Without the column a refused call would read as a change made
+		if !db.schema.hasColumn("audit_log", "outcome") {
+			return ErrAuditLogUnavailable
+		}
+		columns, values, args = columns+", outcome", values+", $6", append(args, e.Outcome)
+	}
+	return db.QueryRowContext(ctx,
+		`INSERT INTO audit_log (`+columns+`) VALUES (`+values+`) RETURNING id, created_at`, args...,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+// GetAuditLog returns one page of the audit log entries selected by q,
+// newest first, and the total count
+func (db *DB) GetAuditLog(ctx context.Context, q models.AuditQuery, page models.Page) ([]models.AuditEntry, int, error) {
+	if err := q.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if !db.schema.hasTable("audit_log") {
+		return nil, 0, ErrAuditLogUnavailable
+	}
+	const where = `
+		FROM audit_log
+		WHERE ($1 = '' OR entity_type = $1) AND ($2 = 0 OR entity_id = $2)
+			AND ($3 = '' OR (created_at AT TIME ZONE 'UTC')::date >= NULLIF($3, '')::date)
+			AND ($4 = '' OR (created_at AT TIME ZONE 'UTC')::date <= NULLIF($4, '')::date)`
+	args := []interface{}{q.EntityType, q.EntityID, q.From, q.To}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.QueryContext(ctx, db.schema.read(
+		`SELECT id, created_at, actor, tool, entity_type, entity_id, arguments, outcome`+where+`
+		ORDER BY created_at DESC, id DESC LIMIT $5 OFFSET $6`, "audit_log"),
+		append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var entityID sql.NullInt64
+		var arguments []byte
+		var outcome sql.NullString
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Actor, &e.Tool, &e.EntityType, &entityID, &arguments, &outcome); err != nil {
+			return nil, 0, err
+		}
+		e.Outcome = outcome.String
+		if entityID.Valid {
+			id := int(entityID.Int64)
+			e.EntityID = &id
+		}
+		if err := json.Unmarshal(arguments, &e.Arguments); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrChangeRequestsUnavailable is returned while the database predates the
+// change_requests migration
+var ErrChangeRequestsUnavailable = errors.New("change requests are not available until the database is migrated")
+
+// ErrChangeReviewed is wrapped by ReviewChangeRequest when the request was
+// already approved or rejected
+var ErrChangeReviewed = errors.New("change request was already reviewed")
+
+const changeRequestColumns = `id, entity, entity_id, changes, note, status, proposed_by, COALESCE(reviewed_by, ''), created_at, reviewed_at`
+
+func scanChangeRequest(row interface{ Scan(...interface{}) error }, c *models.ChangeRequest) error {
+	var changes []byte
+	var reviewedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.Entity, &c.EntityID, &changes, &c.Note, &c.Status, &c.ProposedBy, &c.ReviewedBy, &c.CreatedAt, &reviewedAt); err != nil {
+		return err
+	}
+	if reviewedAt.Valid {
+		c.ReviewedAt = &reviewedAt.Time
+	}
+	return json.Unmarshal(changes, &c.Changes)
+}
+
+// ProposeChange stores a pending change request for an existing menu item,
+// filling in ID and CreatedAt
+func (db *DB) ProposeChange(ctx context.Context, c *models.ChangeRequest) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if !db.schema.hasTable("change_requests") {
+		return ErrChangeRequestsUnavailable
+	}
+	if _, err := db.GetMenuItemByID(ctx, c.EntityID); err != nil {
+		return err
+	}
+	if c.ProposedBy == "" {
+		c.ProposedBy = "staff"
+	}
+	changes, err := json.Marshal(c.Changes)
+	if err != nil {
+		return err
+	}
+	c.Status = models.ChangeStatusPending
+	return db.QueryRowContext(ctx,
+		`INSERT INTO change_requests (entity, entity_id, changes, note, proposed_by) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		c.Entity, c.EntityID, changes, c.Note, c.ProposedBy,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// ListChangeRequests returns one page of the change requests with status,
+// or all of them when status is empty, newest first, and the total count
+func (db *DB) ListChangeRequests(ctx context.Context, status string, page models.Page) ([]models.ChangeRequest, int, error) {
+	if err := models.ValidateChangeStatus(status); err != nil {
+		return nil, 0, err
+	}
+	if !db.schema.hasTable("change_requests") {
+		return nil, 0, ErrChangeRequestsUnavailable
+	}
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM change_requests WHERE $1 = '' OR status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+changeRequestColumns+` FROM change_requests WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		status, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	requests := []models.ChangeRequest{}
+	for rows.Next() {
+		var c models.ChangeRequest
+		if err := scanChangeRequest(rows, &c); err != nil {
+			return nil, 0, err
+		}
+		requests = append(requests, c)
+	}
+	return requests, total, rows.Err()
+}
+
+// ReviewChangeRequest approves or rejects a pending change request on
+// behalf of reviewer. Approving applies the changes to the menu item like
+// UpdateMenuItem, price history included, in the same transaction that
+// closes the request. A request that isn't pending wraps ErrChangeReviewed.
+func (db *DB) ReviewChangeRequest(ctx context.Context, id int, approve bool, reviewer string) (*models.ChangeRequest, error) {
+	if !db.schema.hasTable("change_requests") {
+		return nil, ErrChangeRequestsUnavailable
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var c models.ChangeRequest
+	err = scanChangeRequest(tx.QueryRowContext(ctx, `SELECT `+changeRequestColumns+` FROM change_requests WHERE id = $1 FOR UPDATE`, id), &c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("change request with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != models.ChangeStatusPending {
+		return nil, fmt.Errorf("change request %d is %s by %s: %w", id, c.Status, c.ReviewedBy, ErrChangeReviewed)
+	}
+
+	c.Status = models.ChangeStatusRejected
+	var m models.MenuItem
+	if approve {
+		c.Status = models.ChangeStatusApproved
+		err := scanMenuItem(tx.QueryRowContext(ctx, `SELECT `+menuItemColumns.list()+` FROM menu_items WHERE id = $1 FOR UPDATE`, c.EntityID), &m)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("menu item with ID %d %w", c.EntityID, ErrNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		oldPrice := m.Price
+		c.Changes.ApplyTo(&m)
+		if err := updateMenuItem(ctx, tx, &m, oldPrice, c.ProposedBy+" (approved by "+reviewer+")"); err != nil {
+			return nil, err
+		}
+	}
+
+	var reviewedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE change_requests SET status = $1, reviewed_by = $2, reviewed_at = NOW() WHERE id = $3 RETURNING reviewed_at`,
+		c.Status, reviewer, id,
+	).Scan(&reviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if approve {
+		db.reads.forget(menuReads(m.RestaurantID))
+	}
+	c.ReviewedBy, c.ReviewedAt = reviewer, &reviewedAt
+	return &c, nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import "strings"
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface{ Scan(...interface{}) error }
+
+// column is one column a model is read from: its name in the table, the
+// expression that selects it and the field it scans into. Keeping the three
+// together means a SELECT list and its Scan call can't drift apart.
+type column[T any] struct {
+	name  string
+	read  string // SELECT expression, e.g. to map NULL to ""; name when empty
+	field func(*T) interface{}
+}
+
+// columnList is the registry of the columns a model is read from, in SELECT
+// order. Adding a column to a model is one entry in its list; the storage
+// tests check every list against the migrated schema.
+type columnList[T any] []column[T]
+
+// list is the SELECT list of the read expressions. It names optional
+// columns bare, so schema.read can replace them.
+func (l columnList[T]) list() string {
+	exprs := make([]string, len(l))
+	for i, c := range l {
+		exprs[i] = c.read
+		if exprs[i] == "" {
+			exprs[i] = c.name
+		}
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// names are the table's column names, in SELECT order
+func (l columnList[T]) names() []string {
+	names := make([]string, len(l))
+	for i, c := range l {
+		names[i] = c.name
+	}
+	return names
+}
+
+// dest returns the Scan destinations in v for a row selected with list, to
+// combine with other columns of a join
+func (l columnList[T]) dest(v *T) []interface{} {
+	dest := make([]interface{}, len(l))
+	for i, c := range l {
+		dest[i] = c.field(v)
+	}
+	return dest
+}
+
+// scan reads a row selected with list into v
+func (l columnList[T]) scan(row rowScanner, v *T) error {
+	return row.Scan(l.dest(v)...)
+}
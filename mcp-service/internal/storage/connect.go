@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ConnectRetry controls how long startup waits for Postgres, which in
+// docker-compose or Kubernetes often comes up a few seconds after the service
+type ConnectRetry struct {
+	Attempts       int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration // bounds the whole wait, including pings
+}
+
+// DefaultConnectRetry is used for any setting not given in the environment
+func DefaultConnectRetry() ConnectRetry {
+	return ConnectRetry{
+		Attempts:       10,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Timeout:        60 * time.Second,
+	}
+}
+
+// ConnectRetryFromEnv reads DB_CONNECT_ATTEMPTS, DB_CONNECT_MAX_BACKOFF_SECONDS
+// and DB_CONNECT_TIMEOUT (seconds) over the defaults
+func ConnectRetryFromEnv() (ConnectRetry, error) {
+	cfg := DefaultConnectRetry()
+	if v := os.Getenv("DB_CONNECT_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return ConnectRetry{}, fmt.Errorf("invalid DB_CONNECT_ATTEMPTS: %q", v)
+		}
+		cfg.Attempts = n
+	}
+	for _, s := range []struct {
+		name string
+		dest *time.Duration
+	}{
+		{"DB_CONNECT_MAX_BACKOFF_SECONDS", &cfg.MaxBackoff},
+		{"DB_CONNECT_TIMEOUT", &cfg.Timeout},
+	} {
+		if v := os.Getenv(s.name); v != "" {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				return ConnectRetry{}, fmt.Errorf("invalid %s: %q", s.name, v)
+			}
+			*s.dest = time.Duration(seconds) * time.Second
+		}
+	}
+	return cfg, nil
+}
+
+// backoff returns the wait after the given failed attempt: doubling from
+// InitialBackoff, capped at MaxBackoff
+func (c ConnectRetry) backoff(attempt int) time.Duration {
+	wait := c.InitialBackoff
+	for i := 1; i < attempt && wait < c.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, c.MaxBackoff)
+}
+
+// Ping pings conn until it answers, logging each failed attempt. It gives up
+// after Attempts tries or once Timeout has passed, whichever comes first.
+func (c ConnectRetry) Ping(conn *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	var err error
+	for attempt := 1; attempt <= c.Attempts; attempt++ {
+		if err = conn.PingContext(ctx); err == nil {
+			if attempt > 1 {
+				log.Printf("Database reachable after %d attempts", attempt)
+			}
+			return nil
+		}
+		if attempt == c.Attempts {
+			break
+		}
+		wait := c.backoff(attempt)
+		log.Printf("Database not reachable (attempt %d/%d), retrying in %s: %v", attempt, c.Attempts, wait, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts in %s: %v", attempt, c.Timeout, err)
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %v", c.Attempts, err)
+}
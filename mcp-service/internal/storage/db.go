@@ -0,0 +1,1608 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrNotFound is wrapped by the errors returned for rows that don't exist
+var ErrNotFound = errors.New("not found")
+
+// ErrRestaurantInactive is returned by CreateOrder for a deactivated
+// restaurant
+var ErrRestaurantInactive = errors.New("is inactive and takes no orders")
+
+// ErrOrderNotEditable is returned by UpdateOrderItems once the kitchen has
+// started on an order
+var ErrOrderNotEditable = errors.New("items can only be changed while an order is pending or confirmed")
+
+// ErrOrderNotTippable is returned by SetOrderTip for a cancelled or paid
+// order
+var ErrOrderNotTippable = errors.New("a tip can only be set before an order is paid or cancelled")
+
+// ErrNotDeliverable is returned by AssignDelivery for an order that isn't a
+// delivery order ready to leave the kitchen
+var ErrNotDeliverable = errors.New("only ready delivery orders can be assigned to a rider")
+
+// ForeignItemsError is returned by CreateOrder when some of the order's menu
+// items belong to another restaurant
+type ForeignItemsError struct {
+	RestaurantID int
+	Items        []ForeignItem
+}
+
+// ForeignItem is a menu item ordered from the wrong restaurant
+type ForeignItem struct {
+	MenuItemID   int `json:"menu_item_id"`
+	RestaurantID int `json:"restaurant_id"` // the restaurant it is on
+}
+
+func (e *ForeignItemsError) Error() string {
+	parts := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		parts[i] = fmt.Sprintf("%d (restaurant %d)", item.MenuItemID, item.RestaurantID)
+	}
+	return fmt.Sprintf("menu items not on restaurant %d's menu: %s", e.RestaurantID, strings.Join(parts, ", "))
+}
+
+// InsufficientStockError is returned when an order wants more of some menu
+// items than they have left in stock
+type InsufficientStockError struct {
+	Items []StockShortage
+}
+
+// StockShortage is a menu item ordered beyond its stock
+type StockShortage struct {
+	MenuItemID int    `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Wanted     int    `json:"wanted"`
+	Remaining  int    `json:"remaining"`
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		parts[i] = fmt.Sprintf("only %d of %s (menu item %d) left, %d wanted", item.Remaining, item.Name, item.MenuItemID, item.Wanted)
+	}
+	return "not enough stock: " + strings.Join(parts, "; ")
+}
+
+// MissingItemsError is returned by CreateOrder when some of the order's menu
+// items don't exist. It matches ErrNotFound.
+type MissingItemsError struct {
+	MenuItemIDs []int
+}
+
+func (e *MissingItemsError) Error() string {
+	ids := make([]string, len(e.MenuItemIDs))
+	for i, id := range e.MenuItemIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf("menu items not found: %s", strings.Join(ids, ", "))
+}
+
+func (e *MissingItemsError) Is(target error) bool { return target == ErrNotFound }
+
+// BatchItemError is returned by batch writes such as CreateMenuItems when an
+// item fails, rolling back the whole batch. Index is the item's position in
+// the batch.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string { return fmt.Sprintf("item %d: %v", e.Index, e.Err) }
+
+func (e *BatchItemError) Unwrap() error { return e.Err }
+
+// RestaurantInUseError is returned by DeleteRestaurant without force while
+// menu items or orders still reference the restaurant
+type RestaurantInUseError struct {
+	RestaurantID int
+	MenuItems    int
+	Orders       int
+	OpenOrders   int // of Orders, those not yet delivered or cancelled
+}
+
+func (e *RestaurantInUseError) Error() string {
+	return fmt.Sprintf("restaurant %d still has %d menu items and %d orders (%d open); delete them first or pass force",
+		e.RestaurantID, e.MenuItems, e.Orders, e.OpenOrders)
+}
+
+// VersionConflictError is returned by an update based on a version of a
+// restaurant or menu item that has since been changed by someone else
+type VersionConflictError struct {
+	Entity   string // "restaurant" or "menu item"
+	ID       int
+	Expected int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s %d was changed since version %d was read; fetch it again and reapply the change to the latest version",
+		e.Entity, e.ID, e.Expected)
+}
+
+// DuplicateRestaurantError is returned when a restaurant would get the name
+// and address of another one, compared ignoring case
+type DuplicateRestaurantError struct {
+	ExistingID int
+}
+
+func (e *DuplicateRestaurantError) Error() string {
+	return fmt.Sprintf("a restaurant with this name and address already exists, id=%d", e.ExistingID)
+}
+
+// DB wraps sql.DB with the restaurant data access methods used by the MCP servers
+type DB struct {
+	*sql.DB
+	outage outage
+	reads  flightGroup // dedups concurrent identical restaurant and menu reads
+	pool   PoolConfig
+	schema *schemaInfo // optional columns missing from the database, see DetectSchema
+}
+
+// NewDB opens a connection pool sized by pool, waits for the database as
+// allowed by retry and applies any pending migrations
+func NewDB(connStr string, pool PoolConfig, retry ConnectRetry) (*DB, error) {
+	conn, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	pool.Apply(conn)
+	log.Printf("Database pool: %s", pool)
+
+	ctx := context.Background()
+	if err := retry.Ping(conn); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	db := &DB{DB: conn, pool: pool}
+	if _, err := db.Migrate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %v", err)
+	}
+	if err := db.DetectSchema(ctx); err != nil {
+		return nil, fmt.Errorf("failed to inspect schema: %v", err)
+	}
+
+	if _, err := db.MigrateEnums(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate enum columns: %v", err)
+	}
+
+	return db, nil
+}
+
+// Wrap returns a DB using a connection opened elsewhere, such as the one a
+// handler is given. It doesn't migrate the schema.
+func Wrap(conn *sql.DB) *DB {
+	return &DB{DB: conn}
+}
+
+// restaurantColumns are the columns a models.Restaurant is read from
+var restaurantColumns = columnList[models.Restaurant]{
+	{"id", "", func(r *models.Restaurant) interface{} { return &r.ID }},
+	{"name", "", func(r *models.Restaurant) interface{} { return &r.Name }},
+	{"address", "", func(r *models.Restaurant) interface{} { return &r.Address }},
+	{"phone_number", "COALESCE(phone_number, '')", func(r *models.Restaurant) interface{} { return &r.PhoneNumber }},
+	{"email", "COALESCE(email, '')", func(r *models.Restaurant) interface{} { return &r.Email }},
+	{"cuisine_type", "COALESCE(cuisine_type, '')", func(r *models.Restaurant) interface{} { return &r.CuisineType }},
+	{"timezone", "", func(r *models.Restaurant) interface{} { return &r.Timezone }},
+	{"notification_preferences", "", func(r *models.Restaurant) interface{} { return &r.NotificationPreferences }},
+	{"public_menu_enabled", "", func(r *models.Restaurant) interface{} { return &r.PublicMenuEnabled }},
+	{"auto_confirm_orders", "", func(r *models.Restaurant) interface{} { return &r.AutoConfirmOrders }},
+	{"tax_rate", "", func(r *models.Restaurant) interface{} { return &r.TaxRate }},
+	{"service_charge_percent", "", func(r *models.Restaurant) interface{} { return &r.ServiceChargePercent }},
+	{"active", "", func(r *models.Restaurant) interface{} { return &r.Active }},
+	{"version", "", func(r *models.Restaurant) interface{} { return &r.Version }},
+	{"created_at", "", func(r *models.Restaurant) interface{} { return &r.CreatedAt }},
+	{"updated_at", "COALESCE(updated_at, created_at)", func(r *models.Restaurant) interface{} { return &r.UpdatedAt }},
+}
+
+func scanRestaurant(row rowScanner, r *models.Restaurant) error {
+	return restaurantColumns.scan(row, r)
+}
+
+// restaurantOrderBy maps each accepted sort to its ORDER BY clause. Only
+// these fixed strings are ever concatenated into the query.
+var restaurantOrderBy = map[string]string{
+	"":                               "id",
+	models.RestaurantSortID:          "id",
+	models.RestaurantSortName:        "name, id",
+	models.RestaurantSortCreatedAt:   "created_at, id",
+	models.RestaurantSortCuisineType: "cuisine_type, name, id",
+}
+
+// restaurantList is the shared result of a GetAllRestaurants query
+type restaurantList struct {
+	restaurants []models.Restaurant
+	total       int
+}
+
+// GetAllRestaurants returns one page of active restaurants in the given sort order
+// (see models.RestaurantSorts; empty means by ID) and the total count.
+// Concurrent calls share one query; ctx only limits how long this caller waits.
+func (db *DB) GetAllRestaurants(ctx context.Context, page models.Page, sort string) ([]models.Restaurant, int, error) {
+	orderBy, ok := restaurantOrderBy[sort]
+	if !ok {
+		return nil, 0, models.ValidateRestaurantSort(sort)
+	}
+	query := db.schema.read(`SELECT `+restaurantColumns.list()+`, COUNT(*) OVER () FROM restaurants WHERE active ORDER BY `+orderBy+` LIMIT $1 OFFSET $2`, "restaurants")
+	v, _, err := db.reads.do(ctx, flightKey(restaurantReads, query, page.Limit, page.Offset), func(ctx context.Context) (interface{}, error) {
+		rows, err := db.QueryContext(ctx, query, page.Limit, page.Offset)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		list := restaurantList{restaurants: []models.Restaurant{}}
+		for rows.Next() {
+			var r models.Restaurant
+			if err := scanRestaurant(rowWithExtra{rows, &list.total}, &r); err != nil {
+				return nil, err
+			}
+			list.restaurants = append(list.restaurants, r)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(list.restaurants) == 0 && page.Offset > 0 {
+			// Past the end the window count is lost, so count separately
+			if err := db.QueryRowContext(ctx, db.schema.read(`SELECT COUNT(*) FROM restaurants WHERE active`, "restaurants")).Scan(&list.total); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	// Callers may modify the result, so each gets its own copy of the shared
+	// restaurants
+	shared := v.(restaurantList)
+	restaurants := make([]models.Restaurant, len(shared.restaurants))
+	for i, r := range shared.restaurants {
+		restaurants[i] = sharedRestaurant(r)
+	}
+	return restaurants, shared.total, nil
+}
+
+// SearchRestaurants returns active restaurants whose name or address contains
+// query, case-insensitively, and whose cuisine type is cuisine, ignoring
+// case, ordered by name. Empty query and cuisine don't filter. At most
+// limit restaurants are returned (see models.RestaurantSearchLimit).
+func (db *DB) SearchRestaurants(ctx context.Context, query, cuisine string, limit int) ([]models.Restaurant, error) {
+	limit, err := models.RestaurantSearchLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	where := []string{"active"}
+	args := []interface{}{limit}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		p := param("%" + likeEscaper.Replace(query) + "%")
+		where = append(where, "(name ILIKE "+p+" OR address ILIKE "+p+")")
+	}
+	if cuisine = strings.TrimSpace(cuisine); cuisine != "" {
+		where = append(where, "LOWER(cuisine_type) = LOWER("+param(cuisine)+")")
+	}
+
+	rows, err := db.QueryContext(ctx, db.schema.read(`
+		SELECT `+restaurantColumns.list()+` FROM restaurants
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name, id LIMIT $1`, "restaurants"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restaurants := []models.Restaurant{}
+	for rows.Next() {
+		var r models.Restaurant
+		if err := scanRestaurant(rows, &r); err != nil {
+			return nil, err
+		}
+		restaurants = append(restaurants, r)
+	}
+	return restaurants, rows.Err()
+}
+
+// rowWithExtra scans a row whose select list has one more column than the
+// scan function it is passed to knows about, into extra
+type rowWithExtra struct {
+	row   interface{ Scan(...interface{}) error }
+	extra interface{}
+}
+
+func (r rowWithExtra) Scan(dest ...interface{}) error {
+	return r.row.Scan(append(dest, r.extra)...)
+}
+
+// GetRestaurantByID returns a single restaurant, active or not, sharing the
+// query with concurrent lookups of the same restaurant
+func (db *DB) GetRestaurantByID(ctx context.Context, id int) (*models.Restaurant, error) {
+	query := db.schema.read(`SELECT `+restaurantColumns.list()+` FROM restaurants WHERE id = $1`, "restaurants")
+	v, _, err := db.reads.do(ctx, flightKey(restaurantReads, query, id), func(ctx context.Context) (interface{}, error) {
+		var r models.Restaurant
+		err := scanRestaurant(db.QueryRowContext(ctx, query, id), &r)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("restaurant with ID %d %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &r, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := sharedRestaurant(*v.(*models.Restaurant))
+	return &r, nil
+}
+
+// CreateRestaurant inserts a restaurant and fills in its ID and creation time
+func (db *DB) CreateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := models.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+	if err := models.ValidateTaxRate(r.TaxRate); err != nil {
+		return err
+	}
+	if err := models.ValidateServiceChargePercent(r.ServiceChargePercent); err != nil {
+		return err
+	}
+	r.Active, r.Version = true, 1 // the column defaults
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO restaurants (name, address, phone_number, email, cuisine_type, notification_preferences, tax_rate, service_charge_percent)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8) RETURNING id, created_at, updated_at`,
+		r.Name, r.Address, r.PhoneNumber, r.Email, r.CuisineType, r.NotificationPreferences, r.TaxRate, r.ServiceChargePercent,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return db.duplicateRestaurant(ctx, err, 0, r.Name, r.Address)
+	}
+	db.reads.forget(restaurantReads)
+	return nil
+}
+
+// UpdateRestaurant overwrites all editable fields of the restaurant with ID
+// r.ID, if it is still at r.Version, and sets r.Version to the new version
+func (db *DB) UpdateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := models.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+	err := db.QueryRowContext(ctx,
+		`UPDATE restaurants SET name = $1, address = $2, phone_number = $3, email = NULLIF($4, ''), cuisine_type = $5, notification_preferences = $6,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 AND version = $8 RETURNING created_at, updated_at, version`,
+		r.Name, r.Address, r.PhoneNumber, r.Email, r.CuisineType, r.NotificationPreferences, r.ID, r.Version,
+	).Scan(&r.CreatedAt, &r.UpdatedAt, &r.Version)
+	if err == sql.ErrNoRows {
+		return db.versionMismatch(ctx, "restaurants", "restaurant", r.ID, r.Version)
+	}
+	if err != nil {
+		return db.duplicateRestaurant(ctx, err, r.ID, r.Name, r.Address)
+	}
+	db.reads.forget(restaurantReads, menuReads(r.ID))
+	return nil
+}
+
+// restaurantNameAddressIndex keeps restaurant names and addresses unique
+const restaurantNameAddressIndex = "restaurants_name_address"
+
+// duplicateRestaurant turns a violation of the unique index on restaurant
+// names and addresses into a *DuplicateRestaurantError naming the other
+// restaurant with name and address, not the one with id. Other errors are
+// returned as they are.
+func (db *DB) duplicateRestaurant(ctx context.Context, err error, id int, name, address string) error {
+	if !isUniqueViolation(err, restaurantNameAddressIndex) {
+		return err
+	}
+	duplicate := &DuplicateRestaurantError{}
+	if lookupErr := db.QueryRowContext(ctx,
+		`SELECT id FROM restaurants WHERE lower(name) = lower($1) AND lower(address) = lower($2) AND id <> $3`,
+		name, address, id).Scan(&duplicate.ExistingID); lookupErr != nil {
+		return err
+	}
+	return duplicate
+}
+
+// versionMismatch explains an update of the row with id in table that
+// matched nothing: the row is gone, or it is no longer at version
+func (db *DB) versionMismatch(ctx context.Context, table, entity string, id, version int) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s with ID %d %w", entity, id, ErrNotFound)
+	}
+	return &VersionConflictError{Entity: entity, ID: id, Expected: version}
+}
+
+// PatchRestaurant changes only the fields set in patch and returns the
+// updated restaurant, so callers don't have to resend name and address.
+// With patch.ExpectedVersion set it fails with a *VersionConflictError
+// unless the restaurant is still at that version.
+func (db *DB) PatchRestaurant(ctx context.Context, id int, patch models.RestaurantPatch) (*models.Restaurant, error) {
+	if err := models.ValidateEmail(patch.Email); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateTaxRate(patch.TaxRate); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateServiceChargePercent(patch.ServiceChargePercent); err != nil {
+		return nil, err
+	}
+	// The given flags are merged into the stored JSONB, keeping the others
+	var prefs sql.NullString
+	if len(patch.NotificationPreferences) > 0 {
+		data, err := json.Marshal(patch.NotificationPreferences)
+		if err != nil {
+			return nil, err
+		}
+		prefs = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var r models.Restaurant
+	err := scanRestaurant(db.QueryRowContext(ctx,
+		`UPDATE restaurants SET
+			name = COALESCE(NULLIF($1, ''), name),
+			address = COALESCE(NULLIF($2, ''), address),
+			phone_number = COALESCE(NULLIF($3, ''), phone_number),
+			email = COALESCE(NULLIF($4, ''), email),
+			cuisine_type = COALESCE(NULLIF($5, ''), cuisine_type),
+			notification_preferences = notification_preferences || COALESCE($6::jsonb, '{}'::jsonb),
+			public_menu_enabled = COALESCE($8, public_menu_enabled),
+			auto_confirm_orders = COALESCE($9, auto_confirm_orders),
+			tax_rate = COALESCE($10, tax_rate),
+			service_charge_percent = COALESCE($11, service_charge_percent),
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 AND ($12::integer IS NULL OR version = $12) RETURNING `+restaurantColumns.list(),
+		patch.Name, patch.Address, patch.PhoneNumber, patch.Email, patch.CuisineType, prefs, id, patch.PublicMenuEnabled, patch.AutoConfirmOrders,
+		patch.TaxRate, patch.ServiceChargePercent, patch.ExpectedVersion,
+	), &r)
+	if err == sql.ErrNoRows && patch.ExpectedVersion != nil {
+		return nil, db.versionMismatch(ctx, "restaurants", "restaurant", id, *patch.ExpectedVersion)
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("restaurant with ID %d %w", id, ErrNotFound)
+	}
+	if isUniqueViolation(err, restaurantNameAddressIndex) {
+		// The name or address left unpatched are the stored ones
+		if current, getErr := db.GetRestaurantByID(ctx, id); getErr == nil {
+			if patch.Name == "" {
+				patch.Name = current.Name
+			}
+			if patch.Address == "" {
+				patch.Address = current.Address
+			}
+		}
+		return nil, db.duplicateRestaurant(ctx, err, id, patch.Name, patch.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.reads.forget(restaurantReads, menuReads(id))
+	return &r, nil
+}
+
+// DeleteRestaurant deactivates a restaurant, or with opts.Hard removes it.
+// While menu items or orders reference it a hard delete fails with a
+// *RestaurantInUseError, unless opts.Force is set: then its orders and menu
+// items are deleted with it in one transaction.
+func (db *DB) DeleteRestaurant(ctx context.Context, id int, opts models.RestaurantDeleteOptions) (models.RestaurantDeletion, error) {
+	d := models.RestaurantDeletion{RestaurantID: id}
+	if !opts.Hard {
+		err := db.setRestaurantActive(ctx, id, false)
+		d.Deactivated = err == nil
+		return d, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return d, err
+	}
+	defer tx.Rollback()
+
+	// The row lock keeps menu items and orders from being added meanwhile
+	err = tx.QueryRowContext(ctx, `SELECT id FROM restaurants WHERE id = $1 FOR UPDATE`, id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return d, fmt.Errorf("restaurant with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return d, err
+	}
+	err = tx.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM menu_items WHERE restaurant_id = $1),
+			(SELECT COUNT(*) FROM orders WHERE restaurant_id = $1),
+			(SELECT COUNT(*) FROM orders WHERE restaurant_id = $1 AND status NOT IN ($2, $3))`,
+		id, models.OrderStatusDelivered, models.OrderStatusCancelled,
+	).Scan(&d.MenuItems, &d.Orders, &d.OpenOrders)
+	if err != nil {
+		return d, err
+	}
+	if !opts.Force && (d.MenuItems > 0 || d.Orders > 0) {
+		return d, &RestaurantInUseError{RestaurantID: id, MenuItems: d.MenuItems, Orders: d.Orders, OpenOrders: d.OpenOrders}
+	}
+
+	// Orders first: their items reference the menu items
+	for _, q := range []string{
+		`DELETE FROM orders WHERE restaurant_id = $1`,
+		`DELETE FROM menu_items WHERE restaurant_id = $1`,
+		`DELETE FROM restaurants WHERE id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return d, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return d, err
+	}
+	db.reads.forget(restaurantReads, menuReads(id))
+	return d, nil
+}
+
+// RestoreRestaurant reactivates a restaurant deleted without Hard
+func (db *DB) RestoreRestaurant(ctx context.Context, id int) (*models.Restaurant, error) {
+	if err := db.setRestaurantActive(ctx, id, true); err != nil {
+		return nil, err
+	}
+	return db.GetRestaurantByID(ctx, id)
+}
+
+func (db *DB) setRestaurantActive(ctx context.Context, id int, active bool) error {
+	res, err := db.ExecContext(ctx, `UPDATE restaurants SET active = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id, active)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(res, "restaurant", id); err != nil {
+		return err
+	}
+	// RestoreRestaurant reads the restaurant straight back
+	db.reads.forget(restaurantReads, menuReads(id))
+	return nil
+}
+
+// menuItemColumns are the columns a models.MenuItem is read from
+var menuItemColumns = columnList[models.MenuItem]{
+	{"id", "", func(m *models.MenuItem) interface{} { return &m.ID }},
+	{"restaurant_id", "", func(m *models.MenuItem) interface{} { return &m.RestaurantID }},
+	{"name", "", func(m *models.MenuItem) interface{} { return &m.Name }},
+	{"description", "COALESCE(description, '')", func(m *models.MenuItem) interface{} { return &m.Description }},
+	{"price", "", func(m *models.MenuItem) interface{} { return &m.Price }},
+	{"category", "COALESCE(category, '')", func(m *models.MenuItem) interface{} { return &m.Category }},
+	{"dietary_type", "COALESCE(dietary_type, '')", func(m *models.MenuItem) interface{} { return &m.DietaryType }},
+	{"spice_level", "COALESCE(spice_level, '')", func(m *models.MenuItem) interface{} { return &m.SpiceLevel }},
+	{"available", "COALESCE(available, FALSE)", func(m *models.MenuItem) interface{} { return &m.Available }},
+	{"effective_from", "COALESCE(to_char(effective_from, 'YYYY-MM-DD'), '')", func(m *models.MenuItem) interface{} { return &m.EffectiveFrom }},
+	{"effective_to", "COALESCE(to_char(effective_to, 'YYYY-MM-DD'), '')", func(m *models.MenuItem) interface{} { return &m.EffectiveTo }},
+	{"version", "", func(m *models.MenuItem) interface{} { return &m.Version }},
+	{"created_at", "", func(m *models.MenuItem) interface{} { return &m.CreatedAt }},
+	{"updated_at", "COALESCE(updated_at, created_at)", func(m *models.MenuItem) interface{} { return &m.UpdatedAt }},
+	{"stock_quantity", "", func(m *models.MenuItem) interface{} { return &m.StockQuantity }},
+}
+
+func scanMenuItem(row rowScanner, m *models.MenuItem) error {
+	return menuItemColumns.scan(row, m)
+}
+
+// GetMenuByRestaurantID returns the menu items of a restaurant that are
+// available and effective today in the restaurant's timezone
+func (db *DB) GetMenuByRestaurantID(ctx context.Context, restaurantID int, includeUnavailable bool) ([]models.MenuItem, error) {
+	return db.GetMenuAsOf(ctx, restaurantID, "", includeUnavailable)
+}
+
+// GetMenuAsOf returns the available menu items of a restaurant that are
+// effective on asOf (YYYY-MM-DD), so a seasonal menu can be previewed before
+// it goes live. An empty asOf means today in the restaurant's timezone.
+// includeUnavailable also returns items switched off, for managers.
+// Concurrent reads of the same menu share one query.
+func (db *DB) GetMenuAsOf(ctx context.Context, restaurantID int, asOf string, includeUnavailable bool) ([]models.MenuItem, error) {
+	if asOf != "" {
+		if _, err := time.Parse(models.DateLayout, asOf); err != nil {
+			return nil, fmt.Errorf("invalid as_of %q: expected YYYY-MM-DD", asOf)
+		}
+	}
+	query := db.schema.read(`
+		SELECT `+menuItemColumns.list()+` FROM menu_items
+		CROSS JOIN (
+			SELECT COALESCE(NULLIF($2, '')::date,
+				(NOW() AT TIME ZONE COALESCE((SELECT timezone FROM restaurants WHERE id = $1), 'Asia/Kolkata'))::date) AS day
+		) d
+		WHERE restaurant_id = $1 AND (available = true OR $3)
+			AND (effective_from IS NULL OR effective_from <= d.day)
+			AND (effective_to IS NULL OR effective_to >= d.day)
+		ORDER BY category, name`, "menu_items", "restaurants")
+	v, _, err := db.reads.do(ctx, flightKey(menuReads(restaurantID), query, asOf, includeUnavailable), func(ctx context.Context) (interface{}, error) {
+		rows, err := db.QueryContext(ctx, query, restaurantID, asOf, includeUnavailable)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		items := []models.MenuItem{}
+		for rows.Next() {
+			var m models.MenuItem
+			if err := scanMenuItem(rows, &m); err != nil {
+				return nil, err
+			}
+			items = append(items, m)
+		}
+		return items, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	shared := v.([]models.MenuItem)
+	items := make([]models.MenuItem, len(shared))
+	for i, m := range shared {
+		items[i] = sharedMenuItem(m)
+	}
+	return items, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term so it matches
+// literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchMenuItems returns the items of a restaurant's current menu (as
+// GetMenuByRestaurantID) that match every filter of q
+func (db *DB) SearchMenuItems(ctx context.Context, q models.MenuSearch) ([]models.MenuItem, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	where := []string{
+		"restaurant_id = $1",
+		"available = true",
+		"(effective_from IS NULL OR effective_from <= d.day)",
+		"(effective_to IS NULL OR effective_to >= d.day)",
+	}
+	args := []interface{}{q.RestaurantID}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.Query != "" {
+		p := param("%" + likeEscaper.Replace(q.Query) + "%")
+		where = append(where, "(name ILIKE "+p+" OR COALESCE(description, '') ILIKE "+p+")")
+	}
+	if q.Category != "" {
+		where = append(where, "LOWER(category) = LOWER("+param(q.Category)+")")
+	}
+	if q.DietaryType != "" {
+		where = append(where, "dietary_type = "+param(q.DietaryType))
+	}
+	if q.SpiceLevel != "" {
+		where = append(where, "spice_level = "+param(q.SpiceLevel))
+	}
+	if q.MinPrice > 0 {
+		where = append(where, "price >= "+param(q.MinPrice))
+	}
+	if q.MaxPrice > 0 {
+		where = append(where, "price <= "+param(q.MaxPrice))
+	}
+
+	rows, err := db.QueryContext(ctx, db.schema.read(`
+		SELECT `+menuItemColumns.list()+` FROM menu_items
+		CROSS JOIN (
+			SELECT (NOW() AT TIME ZONE COALESCE((SELECT timezone FROM restaurants WHERE id = $1), 'Asia/Kolkata'))::date AS day
+		) d
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY category, name`, "menu_items", "restaurants"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.MenuItem{}
+	for rows.Next() {
+		var m models.MenuItem
+		if err := scanMenuItem(rows, &m); err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+// GetMenuItemByID returns a single menu item
+func (db *DB) GetMenuItemByID(ctx context.Context, id int) (*models.MenuItem, error) {
+	var m models.MenuItem
+	err := scanMenuItem(db.QueryRowContext(ctx, db.schema.read(`SELECT `+menuItemColumns.list()+` FROM menu_items WHERE id = $1`, "menu_items"), id), &m)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("menu item with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMenuItemsByIDs returns the menu items with the given IDs keyed by ID.
+// IDs without a menu item are left out of the map rather than failing the
+// call, so the caller can tell which ones are missing.
+func (db *DB) GetMenuItemsByIDs(ctx context.Context, ids []int) (map[int]models.MenuItem, error) {
+	return db.menuItemsByIDs(ctx, db.DB, ids, "")
+}
+
+// menuItemsByIDs reads the menu items with ids through q, adding lock (e.g.
+// FOR SHARE) to the query
+func (db *DB) menuItemsByIDs(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, ids []int, lock string) (map[int]models.MenuItem, error) {
+	items := make(map[int]models.MenuItem, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+	rows, err := q.QueryContext(ctx, db.schema.read(`SELECT `+menuItemColumns.list()+` FROM menu_items WHERE id = ANY($1) `+lock, "menu_items"), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.MenuItem
+		if err := scanMenuItem(rows, &m); err != nil {
+			return nil, err
+		}
+		items[m.ID] = m
+	}
+	return items, rows.Err()
+}
+
+// CreateMenuItem inserts a menu item and fills in its ID and timestamps
+func (db *DB) CreateMenuItem(ctx context.Context, m *models.MenuItem) error {
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return err
+	}
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO menu_items (restaurant_id, name, description, price, category, dietary_type, spice_level, available, effective_from, effective_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::date, NULLIF($10, '')::date) RETURNING id, version, created_at, updated_at`,
+		m.RestaurantID, m.Name, m.Description, m.Price, m.Category, m.DietaryType, m.SpiceLevel, m.Available, m.EffectiveFrom, m.EffectiveTo,
+	).Scan(&m.ID, &m.Version, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	db.reads.forget(menuReads(m.RestaurantID))
+	return nil
+}
+
+// CreateMenuItems inserts menu items in one transaction and fills in their
+// IDs and timestamps. Every item is validated first; if any item is
+// invalid or fails to insert, nothing is created and the error is a
+// *BatchItemError naming the first such item.
+func (db *DB) CreateMenuItems(ctx context.Context, items []*models.MenuItem) error {
+	for i, m := range items {
+		if err := m.Validate(); err != nil {
+			return &BatchItemError{Index: i, Err: err}
+		}
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO menu_items (restaurant_id, name, description, price, category, dietary_type, spice_level, available, effective_from, effective_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::date, NULLIF($10, '')::date) RETURNING id, version, created_at, updated_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, m := range items {
+		err := stmt.QueryRowContext(ctx,
+			m.RestaurantID, m.Name, m.Description, m.Price, m.Category, m.DietaryType, m.SpiceLevel, m.Available, m.EffectiveFrom, m.EffectiveTo,
+		).Scan(&m.ID, &m.Version, &m.CreatedAt, &m.UpdatedAt)
+		if isForeignKeyViolation(err) {
+			err = fmt.Errorf("restaurant with ID %d %w", m.RestaurantID, ErrNotFound)
+		}
+		if err != nil {
+			return &BatchItemError{Index: i, Err: err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, m := range items {
+		db.reads.forget(menuReads(m.RestaurantID))
+	}
+	return nil
+}
+
+// UpdateMenuItem overwrites all editable fields of a menu item if it is
+// still at m.Version, failing with a *VersionConflictError otherwise, and
+// sets m.Version to the new version. A price change is recorded in the
+// price history, attributed to changedBy, in the same transaction.
+func (db *DB) UpdateMenuItem(ctx context.Context, m *models.MenuItem, changedBy string) error {
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldPrice models.Money
+	err = tx.QueryRowContext(ctx, `SELECT price, restaurant_id FROM menu_items WHERE id = $1 FOR UPDATE`, m.ID).Scan(&oldPrice, &m.RestaurantID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("menu item with ID %d %w", m.ID, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if err := updateMenuItem(ctx, tx, m, oldPrice, changedBy); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.reads.forget(menuReads(m.RestaurantID))
+	return nil
+}
+
+// updateMenuItem writes m over the row locked in tx, if it is still at
+// m.Version, and records the change from oldPrice
+func updateMenuItem(ctx context.Context, tx *sql.Tx, m *models.MenuItem, oldPrice models.Money, changedBy string) error {
+	err := tx.QueryRowContext(ctx,
+		`UPDATE menu_items SET name = $1, description = $2, price = $3, category = $4, dietary_type = $5, spice_level = $6, available = $7,
+			effective_from = NULLIF($8, '')::date, effective_to = NULLIF($9, '')::date, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND version = $11 RETURNING version, updated_at`,
+		m.Name, m.Description, m.Price, m.Category, m.DietaryType, m.SpiceLevel, m.Available, m.EffectiveFrom, m.EffectiveTo, m.ID, m.Version,
+	).Scan(&m.Version, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &VersionConflictError{Entity: "menu item", ID: m.ID, Expected: m.Version}
+	}
+	if err != nil {
+		return err
+	}
+	return RecordPriceChange(ctx, tx, m.ID, oldPrice, m.Price, changedBy)
+}
+
+// DeleteMenuItem removes a menu item. Items that appear on past orders can't
+// be deleted since order_items references them; mark those unavailable.
+func (db *DB) DeleteMenuItem(ctx context.Context, id int) error {
+	var restaurantID int
+	err := db.QueryRowContext(ctx, `DELETE FROM menu_items WHERE id = $1 RETURNING restaurant_id`, id).Scan(&restaurantID)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("menu item with ID %d is referenced by existing orders; set it unavailable instead of deleting it", id)
+	}
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("menu item with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	db.reads.forget(menuReads(restaurantID))
+	return nil
+}
+
+// requireRow turns an UPDATE or DELETE that matched nothing into the same
+// not-found error the Get methods return
+func requireRow(result sql.Result, entity string, id int) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s with ID %d %w", entity, id, ErrNotFound)
+	}
+	return nil
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign_key_violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation of
+// the named constraint or index
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// orderColumns are the columns a models.Order is read from, without its
+// items
+var orderColumns = columnList[models.Order]{
+	{"id", "", func(o *models.Order) interface{} { return &o.ID }},
+	{"restaurant_id", "", func(o *models.Order) interface{} { return &o.RestaurantID }},
+	{"customer_name", "", func(o *models.Order) interface{} { return &o.CustomerName }},
+	{"customer_phone", "COALESCE(customer_phone, '')", func(o *models.Order) interface{} { return &o.CustomerPhone }},
+	{"order_type", "", func(o *models.Order) interface{} { return &o.OrderType }},
+	{"status", "COALESCE(status, 'pending')", func(o *models.Order) interface{} { return &o.Status }},
+	{"total_amount", "", func(o *models.Order) interface{} { return &o.TotalAmount }},
+	{"tax_amount", "", func(o *models.Order) interface{} { return &o.TaxAmount }},
+	{"discount", "", func(o *models.Order) interface{} { return &o.Discount }},
+	{"discount_percent", "", func(o *models.Order) interface{} { return &o.DiscountPercent }},
+	{"service_charge", "", func(o *models.Order) interface{} { return &o.ServiceCharge }},
+	{"tip", "", func(o *models.Order) interface{} { return &o.Tip }},
+	{"final_amount", "", func(o *models.Order) interface{} { return &o.FinalAmount }},
+	{"payment_status", "COALESCE(payment_status, 'pending')", func(o *models.Order) interface{} { return &o.PaymentStatus }},
+	{"payment_method", "COALESCE(payment_method, '')", func(o *models.Order) interface{} { return &o.PaymentMethod }},
+	{"billing_address", "COALESCE(billing_address, '')", func(o *models.Order) interface{} { return &o.BillingAddress }},
+	{"delivery_person_name", "COALESCE(delivery_person_name, '')", func(o *models.Order) interface{} { return &o.DeliveryPersonName }},
+	{"delivery_person_phone", "COALESCE(delivery_person_phone, '')", func(o *models.Order) interface{} { return &o.DeliveryPersonPhone }},
+	{"dispatched_at", "", func(o *models.Order) interface{} { return &o.DispatchedAt }},
+	{"delivered_at", "", func(o *models.Order) interface{} { return &o.DeliveredAt }},
+	{"cancellation_reason", "COALESCE(cancellation_reason, '')", func(o *models.Order) interface{} { return &o.CancellationReason }},
+	{"cancellation_note", "COALESCE(cancellation_note, '')", func(o *models.Order) interface{} { return &o.CancellationNote }},
+	{"cancelled_by", "COALESCE(cancelled_by, '')", func(o *models.Order) interface{} { return &o.CancelledBy }},
+	{"cancelled_at", "", func(o *models.Order) interface{} { return &o.CancelledAt }},
+	{"split_from", "", func(o *models.Order) interface{} { return &o.SplitFrom }},
+	{"merged_from", "", func(o *models.Order) interface{} { return pq.Array(&o.MergedFrom) }},
+	{"created_at", "", func(o *models.Order) interface{} { return &o.CreatedAt }},
+	{"updated_at", "COALESCE(updated_at, created_at)", func(o *models.Order) interface{} { return &o.UpdatedAt }},
+}
+
+// scanOrder reads an order, normalizing legacy status values
+func scanOrder(row rowScanner, o *models.Order) error {
+	if err := orderColumns.scan(row, o); err != nil {
+		return err
+	}
+	o.Status = readEnum("orders", "status", o.Status)
+	o.PaymentStatus = readEnum("orders", "payment_status", o.PaymentStatus)
+	return nil
+}
+
+// GetAllOrders returns one page of orders, newest first, with their items,
+// and the total number of orders
+func (db *DB) GetAllOrders(ctx context.Context, page models.Page) ([]models.Order, int, error) {
+	return db.GetOrders(ctx, models.OrderFilter{}, page)
+}
+
+// GetOrdersByRestaurantID returns one page of a restaurant's orders, newest
+// first, with their items, and the restaurant's total number of orders
+func (db *DB) GetOrdersByRestaurantID(ctx context.Context, restaurantID int, page models.Page) ([]models.Order, int, error) {
+	return db.GetOrders(ctx, models.OrderFilter{RestaurantID: restaurantID}, page)
+}
+
+// GetOrdersByStatus returns one page of the orders in any of the given
+// statuses, newest first, with their items, and how many there are
+func (db *DB) GetOrdersByStatus(ctx context.Context, statuses []string, page models.Page) ([]models.Order, int, error) {
+	return db.GetOrders(ctx, models.OrderFilter{Statuses: statuses}, page)
+}
+
+// GetOrders returns one page of the orders matching f, newest first, with
+// their items, and how many match in total. Filtering on a restaurant that
+// doesn't exist returns ErrNotFound rather than an empty page.
+func (db *DB) GetOrders(ctx context.Context, f models.OrderFilter, page models.Page) ([]models.Order, int, error) {
+	if f.RestaurantID != 0 {
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM restaurants WHERE id = $1)`, f.RestaurantID).Scan(&exists); err != nil {
+			return nil, 0, err
+		}
+		if !exists {
+			return nil, 0, fmt.Errorf("restaurant with ID %d %w", f.RestaurantID, ErrNotFound)
+		}
+	}
+
+	const where = `WHERE ($1 = 0 OR restaurant_id = $1) AND (COALESCE(cardinality($2::text[]), 0) = 0 OR status = ANY($2::text[]))
+		AND ($3::timestamptz IS NULL OR created_at >= $3) AND ($4::timestamptz IS NULL OR created_at < $4)`
+	args := []interface{}{f.RestaurantID, pq.Array(f.Statuses),
+		sql.NullTime{Time: f.CreatedFrom, Valid: !f.CreatedFrom.IsZero()},
+		sql.NullTime{Time: f.CreatedBefore, Valid: !f.CreatedBefore.IsZero()}}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orders, err := db.queryOrders(ctx, `SELECT `+orderColumns.list()+` FROM orders `+where+` ORDER BY created_at DESC, id DESC LIMIT $5 OFFSET $6`,
+		append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// GetOrdersByCustomerPhone returns one page of the orders placed with a
+// phone number, across restaurants and newest first, with their items, and
+// a summary of all of them. Numbers are compared after NormalizePhone.
+func (db *DB) GetOrdersByCustomerPhone(ctx context.Context, phone string, page models.Page) ([]models.Order, models.CustomerSummary, error) {
+	var summary models.CustomerSummary
+	// Mirrors models.NormalizePhone
+	const where = `WHERE regexp_replace(regexp_replace(COALESCE(customer_phone, ''), '\D', '', 'g'), '^(91|0)(\d{10})$', '\2') = $1`
+	if phone = models.NormalizePhone(phone); phone == "" {
+		return nil, summary, fmt.Errorf("customer phone must contain digits")
+	}
+
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(final_amount) FILTER (WHERE status <> 'cancelled'), 0) FROM orders `+where,
+		phone).Scan(&summary.Orders, &summary.Spend)
+	if err != nil {
+		return nil, summary, err
+	}
+
+	orders, err := db.queryOrders(ctx, `SELECT `+orderColumns.list()+` FROM orders `+where+` ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		phone, page.Limit, page.Offset)
+	if err != nil {
+		return nil, summary, err
+	}
+	return orders, summary, nil
+}
+
+// queryOrders runs a query selecting orderColumns from orders and fetches
+// the items of all returned orders in one more query
+func (db *DB) queryOrders(ctx context.Context, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := db.QueryContext(ctx, db.schema.read(query, "orders"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	ids := make([]int64, len(orders))
+	byID := make(map[int]*models.Order, len(orders))
+	for i := range orders {
+		ids[i] = int64(orders[i].ID)
+		orders[i].OrderItems = []models.OrderItem{}
+		byID[orders[i].ID] = &orders[i]
+	}
+	items, err := db.queryOrderItems(ctx, `oi.order_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		o := byID[item.OrderID]
+		o.OrderItems = append(o.OrderItems, item)
+	}
+	return orders, nil
+}
+
+// GetOrderByID returns a single order with its items
+func (db *DB) GetOrderByID(ctx context.Context, id int) (*models.Order, error) {
+	var o models.Order
+	err := scanOrder(db.QueryRowContext(ctx, db.schema.read(`SELECT `+orderColumns.list()+` FROM orders WHERE id = $1`, "orders"), id), &o)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := db.GetOrderItemsByOrderID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	o.OrderItems = items
+	return &o, nil
+}
+
+// GetOrderItemsByOrderID returns the items of an order joined with their menu items
+func (db *DB) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]models.OrderItem, error) {
+	return db.queryOrderItems(ctx, `oi.order_id = $1`, orderID)
+}
+
+// orderItemsIn is GetOrderItemsByOrderID reading through tx. A transaction
+// holding the order's row lock must read the items this way: on the pool
+// the read waits for a second connection, which a pool of one never has.
+func (db *DB) orderItemsIn(ctx context.Context, tx *sql.Tx, orderID int) ([]models.OrderItem, error) {
+	return db.queryOrderItemsFrom(ctx, tx, "order_items", `oi.order_id = $1`, orderID)
+}
+
+// queryOrderItems returns the order items matching where, joined with their
+// menu items, in order and then insertion order
+func (db *DB) queryOrderItems(ctx context.Context, where string, args ...interface{}) ([]models.OrderItem, error) {
+	return db.queryOrderItemsFrom(ctx, db, "order_items", where, args...)
+}
+
+// queryOrderItemsFrom is queryOrderItems reading table, which has the
+// columns of order_items, through q
+func (db *DB) queryOrderItemsFrom(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, table, where string, args ...interface{}) ([]models.OrderItem, error) {
+	rows, err := q.QueryContext(ctx, db.schema.read(`
+		SELECT oi.id, oi.order_id, oi.menu_item_id, mi.*, oi.quantity, oi.price, COALESCE(oi.notes, ''), oi.subtotal, pricing_rule_id, from_stock
+		FROM `+table+` oi
+		JOIN LATERAL (SELECT `+menuItemColumns.list()+` FROM menu_items WHERE menu_items.id = oi.menu_item_id) mi ON true
+		WHERE `+where+`
+		ORDER BY oi.order_id, oi.id`, table, "menu_items"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.OrderItem{}
+	for rows.Next() {
+		var oi models.OrderItem
+		var m models.MenuItem
+		dest := append([]interface{}{&oi.ID, &oi.OrderID, &oi.MenuItemID}, menuItemColumns.dest(&m)...)
+		dest = append(dest, &oi.Quantity, &oi.Price, &oi.Notes, &oi.Subtotal, &oi.PricingRuleID, &oi.FromStock)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		oi.MenuItem = &m
+		items = append(items, oi)
+	}
+	return items, rows.Err()
+}
+
+// CreateOrder inserts an order and its items in a single transaction. Each
+// item is priced from the menu, must belong to the order's restaurant, and
+// the order totals are computed from those prices. Items whose stock is
+// tracked are taken from it, failing with an *InsufficientStockError when
+// there isn't enough. A pending order for a restaurant with
+// auto_confirm_orders is created confirmed; an inactive restaurant takes no
+// orders. When the restaurant already has an order with
+// o's IdempotencyKey, o is set to that order and nothing is created, even
+// if the two creates race.
+func (db *DB) CreateOrder(ctx context.Context, o *models.Order) error {
+	if found, err := db.idempotentOrder(ctx, o); found || err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if o.OrderType == "" {
+		o.OrderType = models.OrderTypeDineIn
+	}
+
+	if err := db.moveStock(ctx, tx, nil, o.OrderItems); err != nil {
+		return err
+	}
+	if err := db.priceItems(ctx, tx, o.RestaurantID, o.OrderItems); err != nil {
+		return err
+	}
+
+	// Read in the same transaction, so an order is never seen pending by a
+	// restaurant that auto-confirms
+	r := models.Restaurant{Active: true}
+	err = tx.QueryRowContext(ctx, db.schema.read(`SELECT auto_confirm_orders, active, tax_rate, service_charge_percent FROM restaurants WHERE id = $1`, "restaurants"),
+		o.RestaurantID).Scan(&r.AutoConfirmOrders, &r.Active, &r.TaxRate, &r.ServiceChargePercent)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if !r.Active {
+		return fmt.Errorf("restaurant with ID %d %w", o.RestaurantID, ErrRestaurantInactive)
+	}
+	o.ComputeTotals(r.OrderCharges())
+	o.AutoConfirm(r)
+
+	err = db.insertOrder(ctx, tx, o)
+	if err == sql.ErrNoRows && o.IdempotencyKey != "" {
+		// A concurrent create with the same key committed first
+		tx.Rollback()
+		found, err := db.idempotentOrder(ctx, o)
+		if err == nil && !found {
+			err = fmt.Errorf("order with idempotency key %q conflicted but can't be found", o.IdempotencyKey)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.insertOrderItems(ctx, tx, o); err != nil {
+		return err
+	}
+	if err := db.recordStatus(ctx, tx, o.ID, "", o.Status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Taking stock can change the menu's availability
+	db.reads.forget(menuReads(o.RestaurantID))
+	return nil
+}
+
+// idempotentOrder replaces o with the order its restaurant already created
+// with o's idempotency key, reporting whether there is one. An archived
+// order keeps its key, so a late retry doesn't place it again.
+func (db *DB) idempotentOrder(ctx context.Context, o *models.Order) (bool, error) {
+	if o.IdempotencyKey == "" {
+		return false, nil
+	}
+	var id int
+	get := db.GetOrderByID
+	err := db.QueryRowContext(ctx, `SELECT id FROM orders WHERE restaurant_id = $1 AND idempotency_key = $2`,
+		o.RestaurantID, o.IdempotencyKey).Scan(&id)
+	if err == sql.ErrNoRows && db.schema.hasTable("orders_archive") {
+		get = db.GetArchivedOrder
+		err = db.QueryRowContext(ctx, `SELECT id FROM orders_archive WHERE restaurant_id = $1 AND idempotency_key = $2`,
+			o.RestaurantID, o.IdempotencyKey).Scan(&id)
+	}
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	existing, err := get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	existing.IdempotencyKey = o.IdempotencyKey
+	*o = *existing
+	return true, nil
+}
+
+// insertOrder adds the row of a new order in tx, without its items, and
+// sets its ID and timestamps. A zero CreatedAt means now. It returns
+// sql.ErrNoRows when the restaurant has an order with o's idempotency key.
+func (db *DB) insertOrder(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	var createdAt *time.Time
+	if !o.CreatedAt.IsZero() {
+		createdAt = &o.CreatedAt
+	}
+	var mergedFrom interface{}
+	if len(o.MergedFrom) > 0 {
+		mergedFrom = pq.Array(o.MergedFrom)
+	}
+	return tx.QueryRowContext(ctx,
+		`INSERT INTO orders (restaurant_id, customer_name, customer_phone, order_type, status, total_amount, tax_amount, discount, final_amount,
+			payment_status, payment_method, billing_address, split_from, merged_from, created_at, discount_percent, service_charge, tip, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE($15, CURRENT_TIMESTAMP), $16, $17, $18, NULLIF($19, ''))
+		ON CONFLICT (restaurant_id, idempotency_key) DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		o.RestaurantID, o.CustomerName, o.CustomerPhone, o.OrderType, o.Status, o.TotalAmount, o.TaxAmount,
+		o.Discount, o.FinalAmount, o.PaymentStatus, o.PaymentMethod, o.BillingAddress, o.SplitFrom, mergedFrom, createdAt, o.DiscountPercent,
+		o.ServiceCharge, o.Tip, o.IdempotencyKey,
+	).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// priceItems sets each item's price from the menu, never from the caller,
+// less the best pricing rule running now, checking every item exists and
+// is on the restaurant's menu. FOR SHARE keeps a concurrent
+// update_menu_item from changing the prices until tx commits.
+func (db *DB) priceItems(ctx context.Context, tx *sql.Tx, restaurantID int, items []models.OrderItem) error {
+	rules, local, err := db.activePricing(ctx, tx, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.MenuItemID
+	}
+	menu, err := db.menuItemsByIDs(ctx, tx, ids, "FOR SHARE")
+	if err != nil {
+		return err
+	}
+	missing := &MissingItemsError{}
+	foreign := &ForeignItemsError{RestaurantID: restaurantID}
+	for i := range items {
+		item := &items[i]
+		m, ok := menu[item.MenuItemID]
+		if !ok {
+			missing.MenuItemIDs = append(missing.MenuItemIDs, item.MenuItemID)
+			continue
+		}
+		if m.RestaurantID != restaurantID {
+			foreign.Items = append(foreign.Items, ForeignItem{item.MenuItemID, m.RestaurantID})
+		}
+		item.Price, item.PricingRuleID = m.Price, nil
+		if r := models.BestPricingRule(rules, m, local); r != nil {
+			item.Price, item.PricingRuleID = r.Apply(m.Price), &r.ID
+		}
+	}
+	if len(missing.MenuItemIDs) > 0 {
+		return missing
+	}
+	if len(foreign.Items) > 0 {
+		return foreign
+	}
+	return nil
+}
+
+// insertOrderItems inserts the items of o, setting their IDs and subtotals
+func (db *DB) insertOrderItems(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	insertItem := `INSERT INTO order_items (order_id, menu_item_id, quantity, price, notes, from_stock) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, subtotal`
+	if db.schema.hasTable("pricing_rules") {
+		insertItem = `INSERT INTO order_items (order_id, menu_item_id, quantity, price, notes, from_stock, pricing_rule_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, subtotal`
+	}
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.OrderID = o.ID
+		args := []interface{}{item.OrderID, item.MenuItemID, item.Quantity, item.Price, item.Notes, item.FromStock}
+		if db.schema.hasTable("pricing_rules") {
+			args = append(args, item.PricingRuleID)
+		}
+		err := tx.QueryRowContext(ctx, insertItem, args...).Scan(&item.ID, &item.Subtotal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderCharges returns the OrderCharges of a restaurant, read in tx
+func (db *DB) orderCharges(ctx context.Context, tx *sql.Tx, restaurantID int) (models.Charges, error) {
+	var r models.Restaurant
+	err := tx.QueryRowContext(ctx, db.schema.read(`SELECT tax_rate, service_charge_percent FROM restaurants WHERE id = $1`, "restaurants"), restaurantID).
+		Scan(&r.TaxRate, &r.ServiceChargePercent)
+	if err != nil && err != sql.ErrNoRows {
+		return models.Charges{}, err
+	}
+	return r.OrderCharges(), nil
+}
+
+// updateOrderTotals saves the amounts ComputeTotals set on o, in tx
+func updateOrderTotals(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE orders SET total_amount = $1, tax_amount = $2, discount = $3, service_charge = $4, tip = $5, final_amount = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7`,
+		o.TotalAmount, o.TaxAmount, o.Discount, o.ServiceCharge, o.Tip, o.FinalAmount, o.ID)
+	return err
+}
+
+// UpdateOrderItems replaces the items of a pending or confirmed order in a
+// single transaction, repricing them from the menu and taking them from
+// stock as CreateOrder does, after putting back the stock the old items
+// took, and recomputing the order totals. It returns the updated order.
+func (db *DB) UpdateOrderItems(ctx context.Context, orderID int, items []models.OrderItem) (*models.Order, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("order %d needs at least one item; cancel it instead", orderID)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o := models.Order{ID: orderID, OrderItems: append([]models.OrderItem(nil), items...)}
+	err = tx.QueryRowContext(ctx, db.schema.read(`SELECT restaurant_id, order_type, COALESCE(status, 'pending'), discount, discount_percent, tip FROM orders WHERE id = $1 FOR UPDATE`, "orders"), orderID).
+		Scan(&o.RestaurantID, &o.OrderType, &o.Status, &o.Discount, &o.DiscountPercent, &o.Tip)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order with ID %d %w", orderID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Status = readEnum("orders", "status", o.Status); !o.ItemsEditable() {
+		return nil, fmt.Errorf("order %d is %s; %w", orderID, o.Status, ErrOrderNotEditable)
+	}
+
+	old, err := db.orderItemsIn(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.moveStock(ctx, tx, old, o.OrderItems); err != nil {
+		return nil, err
+	}
+	if err := db.priceItems(ctx, tx, o.RestaurantID, o.OrderItems); err != nil {
+		return nil, err
+	}
+	charges, err := db.orderCharges(ctx, tx, o.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	o.ComputeTotals(charges)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, orderID); err != nil {
+		return nil, err
+	}
+	if err := db.insertOrderItems(ctx, tx, &o); err != nil {
+		return nil, err
+	}
+	if err := updateOrderTotals(ctx, tx, &o); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.reads.forget(menuReads(o.RestaurantID))
+	return db.GetOrderByID(ctx, orderID)
+}
+
+// SetOrderTip sets the tip on an order that isn't cancelled or paid and
+// recomputes its totals, keeping the items at the prices they were
+// ordered at. It returns the updated order.
+func (db *DB) SetOrderTip(ctx context.Context, orderID int, tip models.Money) (*models.Order, error) {
+	if tip < 0 {
+		return nil, fmt.Errorf("tip must be an amount of at least 0")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	orders, err := db.lockOrders(ctx, tx, []int{orderID})
+	if err != nil {
+		return nil, err
+	}
+	o := orders[0]
+	if !o.Tippable() {
+		return nil, fmt.Errorf("order %d is %s with payment %s; %w", orderID, o.Status, o.PaymentStatus, ErrOrderNotTippable)
+	}
+	charges, err := db.orderCharges(ctx, tx, o.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	o.Tip = tip
+	o.ComputeTotals(charges)
+	if err := updateOrderTotals(ctx, tx, &o); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetOrderByID(ctx, orderID)
+}
+
+// UpdateOrder saves the status and payment fields of an order. A status
+// change must follow models.OrderStatusTransitions, is checked against the
+// locked row and is added to the order's status history. Moving an order
+// to delivered stamps delivered_at.
+func (db *DB) UpdateOrder(ctx context.Context, o *models.Order) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockOrderStatus(ctx, tx, o.ID)
+	if err != nil {
+		return err
+	}
+	if err := current.CheckTransition(o.Status); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`UPDATE orders SET status = $1, payment_status = $2, payment_method = $3, billing_address = $4, updated_at = CURRENT_TIMESTAMP,
+			delivered_at = CASE WHEN $1 = 'delivered' THEN COALESCE(delivered_at, CURRENT_TIMESTAMP) ELSE delivered_at END
+		WHERE id = $5 RETURNING updated_at, delivered_at`,
+		o.Status, o.PaymentStatus, o.PaymentMethod, o.BillingAddress, o.ID,
+	).Scan(&o.UpdatedAt, &o.DeliveredAt)
+	if err != nil {
+		return err
+	}
+	if err := db.recordStatus(ctx, tx, o.ID, current.Status, o.Status); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockOrderStatus reads the status and restaurant of an order in tx,
+// locking the row until tx ends so it can't change under a status update
+func lockOrderStatus(ctx context.Context, tx *sql.Tx, orderID int) (*models.Order, error) {
+	o := models.Order{ID: orderID}
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(status, 'pending'), restaurant_id FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&o.Status, &o.RestaurantID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order with ID %d %w", orderID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.Status = readEnum("orders", "status", o.Status)
+	return &o, nil
+}
+
+// AssignDelivery hands a ready delivery order to a rider and marks it
+// out_for_delivery. The order row is locked for the checks, so two
+// concurrent assignments can't both succeed.
+func (db *DB) AssignDelivery(ctx context.Context, orderID int, riderName, riderPhone string) (*models.Order, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var order models.Order
+	err = scanOrder(tx.QueryRowContext(ctx, db.schema.read(`SELECT `+orderColumns.list()+` FROM orders WHERE id = $1 FOR UPDATE`, "orders"), orderID), &order)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order with ID %d %w", orderID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if order.OrderType != models.OrderTypeDelivery {
+		return nil, fmt.Errorf("order %d is a %s order; %w", orderID, order.OrderType, ErrNotDeliverable)
+	}
+	if !models.CanTransition(order.Status, models.OrderStatusOutForDelivery) {
+		return nil, fmt.Errorf("order %d is %s; %w", orderID, order.Status, ErrNotDeliverable)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`UPDATE orders SET status = $1, delivery_person_name = $2, delivery_person_phone = $3,
+			dispatched_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING dispatched_at, updated_at`,
+		models.OrderStatusOutForDelivery, riderName, riderPhone, orderID,
+	).Scan(&order.DispatchedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.recordStatus(ctx, tx, orderID, order.Status, models.OrderStatusOutForDelivery); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if order.OrderItems, err = db.GetOrderItemsByOrderID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	order.Status = models.OrderStatusOutForDelivery
+	order.DeliveryPersonName = riderName
+	order.DeliveryPersonPhone = riderPhone
+	return &order, nil
+}
+
+// CancelOrder cancels an order with a reason. An order past preparing
+// can't be cancelled and gets a *models.TransitionError; a completed
+// payment is marked refunded in the same update and the stock its items
+// took is put back. The order row is locked for the check, and the change
+// is added to the order's status history.
+func (db *DB) CancelOrder(ctx context.Context, orderID int, reason, note, cancelledBy string) (*models.Order, error) {
+	if err := models.ValidateCancelReason(reason, note); err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockOrderStatus(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == models.OrderStatusCancelled || !models.CanTransition(current.Status, models.OrderStatusCancelled) {
+		return nil, &models.TransitionError{OrderID: orderID, From: current.Status, To: models.OrderStatusCancelled}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET status = $1, cancellation_reason = $2, cancellation_note = NULLIF($3, ''), cancelled_by = $4,
+			cancelled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+			payment_status = CASE WHEN payment_status = 'completed' THEN 'refunded' ELSE payment_status END
+		WHERE id = $5`,
+		models.OrderStatusCancelled, reason, note, cancelledBy, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.recordStatus(ctx, tx, orderID, current.Status, models.OrderStatusCancelled); err != nil {
+		return nil, err
+	}
+	items, err := db.orderItemsIn(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.moveStock(ctx, tx, items, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.reads.forget(menuReads(current.RestaurantID))
+	return db.GetOrderByID(ctx, orderID)
+}
+
+// DeleteOrder removes an order; its items are removed by the cascade
+func (db *DB) DeleteOrder(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, id)
+	return err
+}
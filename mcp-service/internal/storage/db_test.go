@@ -0,0 +1,616 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+func TestMain(m *testing.M) { storagetest.Main(m) }
+
+func TestUpdateMenuItemNotFound(t *testing.T) {
+	db := storagetest.DB(t)
+
+	err := db.UpdateMenuItem(t.Context(), &models.MenuItem{ID: 999, Name: "Ghost Dish", Price: models.Rupees(1)}, "")
+	if err == nil || err.Error() != "menu item with ID 999 not found" {
+		t.Fatalf("UpdateMenuItem(999) = %v, want the not-found error", err)
+	}
+}
+
+func TestStaleUpdatesConflict(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	ctx := t.Context()
+
+	first, second := r.Menu[0], r.Menu[0]
+	first.Price++
+	if err := db.UpdateMenuItem(ctx, &first, ""); err != nil || first.Version != 2 {
+		t.Fatalf("first update: %v, version %d", err, first.Version)
+	}
+	var conflict *storage.VersionConflictError
+	if err := db.UpdateMenuItem(ctx, &second, ""); !errors.As(err, &conflict) {
+		t.Errorf("update from version 1 = %v, want a conflict", err)
+	}
+
+	restaurant := *r.Restaurant
+	if err := db.UpdateRestaurant(ctx, &restaurant); err != nil || restaurant.Version != 2 {
+		t.Fatalf("UpdateRestaurant: %v, version %d", err, restaurant.Version)
+	}
+	stale := 1
+	if _, err := db.PatchRestaurant(ctx, restaurant.ID, models.RestaurantPatch{Name: "Renamed", ExpectedVersion: &stale}); !errors.As(err, &conflict) {
+		t.Errorf("patch from version 1 = %v, want a conflict", err)
+	}
+	if _, err := db.PatchRestaurant(ctx, 999, models.RestaurantPatch{Name: "Ghost", ExpectedVersion: &stale}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("patch of a missing restaurant = %v, want not found", err)
+	}
+}
+
+func TestUpdateMenuItemSetsUpdatedAt(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	m := r.Menu[0]
+	if m.UpdatedAt.IsZero() || !m.UpdatedAt.Equal(m.CreatedAt) {
+		t.Errorf("new item updated_at %v, want its created_at %v", m.UpdatedAt, m.CreatedAt)
+	}
+
+	created := m.UpdatedAt
+	m.Price++
+	if err := db.UpdateMenuItem(t.Context(), &m, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetMenuItemByID(t.Context(), m.ID)
+	if err != nil || !got.UpdatedAt.After(created) || !got.UpdatedAt.Equal(m.UpdatedAt) {
+		t.Errorf("updated_at after a price change = %+v, %v; want %v, after %v", got, err, m.UpdatedAt, created)
+	}
+}
+
+func TestDeleteMenuItem(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).Build(t, db)
+
+	if err := db.DeleteMenuItem(t.Context(), 999); err == nil || err.Error() != "menu item with ID 999 not found" {
+		t.Errorf("DeleteMenuItem(999) = %v, want the not-found error", err)
+	}
+
+	ordered := r.Menu[0].ID
+	err := db.DeleteMenuItem(t.Context(), ordered)
+	if err == nil || !strings.Contains(err.Error(), "referenced by existing orders") {
+		t.Errorf("DeleteMenuItem of an ordered item = %v, want the referenced error", err)
+	}
+	if _, err := db.GetMenuItemByID(t.Context(), ordered); err != nil {
+		t.Errorf("ordered item after the refused delete: %v", err)
+	}
+
+	if err := db.DeleteMenuItem(t.Context(), r.Menu[1].ID); err != nil {
+		t.Fatalf("DeleteMenuItem of an unordered item: %v", err)
+	}
+	if _, err := db.GetMenuItemByID(t.Context(), r.Menu[1].ID); err == nil {
+		t.Error("deleted item is still there")
+	}
+}
+
+// TestCanceledContextAbortsQuery holds a menu item's row lock in another
+// transaction, so UpdateMenuItem blocks until its context ends
+func TestCanceledContextAbortsQuery(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	item := r.Menu[0]
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT id FROM menu_items WHERE id = $1 FOR UPDATE`, item.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	item.Price++
+	err = db.UpdateMenuItem(ctx, &item, "")
+	if !errors.Is(err, context.DeadlineExceeded) && (err == nil || !strings.Contains(err.Error(), "canceling statement")) {
+		t.Fatalf("UpdateMenuItem on a locked row = %v, want it canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("UpdateMenuItem returned after %v, long after its deadline", elapsed)
+	}
+}
+
+// TestOrderChangesWorkOnAPoolOfOne edits, tips and cancels an order with a
+// single pooled connection: a read on the pool while the order's
+// transaction holds that connection would wait forever
+func TestOrderChangesWorkOnAPoolOfOne(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	o := storagetest.NewOrder(r).WithItem(0, 1).Build(t, db)
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := db.UpdateOrderItems(ctx, o.ID, []models.OrderItem{{MenuItemID: r.Menu[1].ID, Quantity: 2}}); err != nil {
+		t.Fatalf("UpdateOrderItems: %v", err)
+	}
+	if _, err := db.SetOrderTip(ctx, o.ID, models.Rupees(20)); err != nil {
+		t.Fatalf("SetOrderTip: %v", err)
+	}
+	cancelled, err := db.CancelOrder(ctx, o.ID, models.CancelReasonCustomerRequest, "", "test")
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if cancelled.Status != models.OrderStatusCancelled {
+		t.Errorf("order %s after CancelOrder", cancelled.Status)
+	}
+}
+
+func TestCreateOrderPricesItemsFromTheMenu(t *testing.T) {
+	db := storagetest.DB(t)
+	ctx := t.Context()
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	other := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	deleted := r.Menu[1]
+	if err := db.DeleteMenuItem(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	m := r.Menu[0]
+	o := &models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", OrderType: models.OrderTypeDineIn,
+		OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2, Price: models.Rupees(1)}}}
+	if err := db.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.OrderItems[0].Price != m.Price || o.TotalAmount != 2*m.Price {
+		t.Errorf("order priced at %v each, %v total; want the menu's %v", o.OrderItems[0].Price, o.TotalAmount, m.Price)
+	}
+
+	var foreignErr *storage.ForeignItemsError
+	o = &models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", OrderType: models.OrderTypeDineIn,
+		OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}, {MenuItemID: other.Menu[0].ID, Quantity: 1}}}
+	if err := db.CreateOrder(ctx, o); !errors.As(err, &foreignErr) || foreignErr.Items[0].MenuItemID != other.Menu[0].ID {
+		t.Errorf("CreateOrder with another restaurant's item = %v, want a ForeignItemsError", err)
+	}
+	o = &models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", OrderType: models.OrderTypeDineIn,
+		OrderItems: []models.OrderItem{{MenuItemID: deleted.ID, Quantity: 1}}}
+	if err := db.CreateOrder(ctx, o); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("CreateOrder with a deleted item = %v, want ErrNotFound", err)
+	}
+	if _, total, err := db.GetAllOrders(ctx, models.Page{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("%d orders stored, %v; want only the first", total, err)
+	}
+}
+
+func TestGetMenuItemsByIDsLeavesOutMissingItems(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+
+	items, err := db.GetMenuItemsByIDs(t.Context(), []int{r.Menu[1].ID, 999, r.Menu[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[r.Menu[0].ID].Name != r.Menu[0].Name || items[r.Menu[1].ID].Price != r.Menu[1].Price {
+		t.Errorf("GetMenuItemsByIDs = %+v, want both menu items", items)
+	}
+	if _, ok := items[999]; ok {
+		t.Error("missing item 999 is in the map")
+	}
+
+	var missing *storage.MissingItemsError
+	o := &models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", OrderType: models.OrderTypeDineIn,
+		OrderItems: []models.OrderItem{{MenuItemID: 998, Quantity: 1}, {MenuItemID: r.Menu[0].ID, Quantity: 1}, {MenuItemID: 999, Quantity: 1}}}
+	if err := db.CreateOrder(t.Context(), o); !errors.As(err, &missing) || err.Error() != "menu items not found: 998, 999" {
+		t.Errorf("CreateOrder with missing items = %v, want both reported", err)
+	}
+}
+
+func TestCreateMenuItemsRollsBackOnAFailedItem(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().Build(t, db)
+
+	items := []*models.MenuItem{
+		{RestaurantID: r.Restaurant.ID, Name: "Idli Sambar", Price: models.Rupees(70), Category: "Breakfast", Available: true},
+		{RestaurantID: 999999, Name: "Medu Vada", Price: models.Rupees(60), Category: "Breakfast", Available: true},
+	}
+	var itemErr *storage.BatchItemError
+	if err := db.CreateMenuItems(t.Context(), items); !errors.As(err, &itemErr) || itemErr.Index != 1 || !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("CreateMenuItems with a missing restaurant = %v, want item 1 not found", err)
+	}
+	if menu, err := db.GetMenuByRestaurantID(t.Context(), r.Restaurant.ID, true); err != nil || len(menu) != 0 {
+		t.Errorf("menu after a rolled back batch = %+v, %v; want empty", menu, err)
+	}
+
+	items[1].RestaurantID = r.Restaurant.ID
+	if err := db.CreateMenuItems(t.Context(), items); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID == 0 || items[1].ID <= items[0].ID {
+		t.Errorf("IDs %d and %d, want both filled in order", items[0].ID, items[1].ID)
+	}
+}
+
+func TestCreateOrderAutoConfirms(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	on := true
+	if _, err := db.PatchRestaurant(t.Context(), r.Restaurant.ID, models.RestaurantPatch{AutoConfirmOrders: &on}); err != nil {
+		t.Fatal(err)
+	}
+
+	o := storagetest.NewOrder(r).WithItem(0, 1).Build(t, db)
+	got, err := db.GetOrderByID(t.Context(), o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderStatusConfirmed {
+		t.Errorf("status %s, want confirmed", got.Status)
+	}
+	stats, err := db.GetRestaurantStats(t.Context(), r.Restaurant.ID)
+	if err != nil || stats.PendingOrders != 0 {
+		t.Errorf("stats %+v, %v; want no orders pending confirmation", stats, err)
+	}
+}
+
+func TestCreateOrderWithAnIdempotencyKeyCreatesItOnce(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+
+	ids := make([]int, 8)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o := models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", IdempotencyKey: "retry-1",
+				OrderItems: []models.OrderItem{{MenuItemID: r.Menu[0].ID, Quantity: 2}}}
+			if err := db.CreateOrder(t.Context(), &o); err != nil {
+				t.Error(err)
+			}
+			ids[i] = o.ID
+		}()
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("concurrent creates with one key made orders %v, want one", ids)
+		}
+	}
+	got, err := db.GetOrderByID(t.Context(), ids[0])
+	if err != nil || len(got.OrderItems) != 1 || got.OrderItems[0].Quantity != 2 {
+		t.Errorf("order %+v, %v; want its one item", got, err)
+	}
+	if _, total, err := db.GetOrders(t.Context(), models.OrderFilter{RestaurantID: r.Restaurant.ID}, models.Page{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("%d orders, %v; want 1", total, err)
+	}
+}
+
+func TestCreateOrderRetriedAfterArchivingReturnsTheArchivedOrder(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	create := func() models.Order {
+		o := models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", Status: models.OrderStatusDelivered, IdempotencyKey: "retry-1",
+			CreatedAt: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), OrderItems: []models.OrderItem{{MenuItemID: r.Menu[0].ID, Quantity: 2}}}
+		if err := db.CreateOrder(t.Context(), &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	first := create()
+	if n, err := db.ArchiveOrders(t.Context(), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)); err != nil || n != 1 {
+		t.Fatalf("ArchiveOrders = %d, %v; want the order", n, err)
+	}
+
+	retry := create()
+	if retry.ID != first.ID || retry.ArchivedAt == nil || len(retry.OrderItems) != 1 {
+		t.Errorf("retry after archiving = %+v, want archived order %d", retry, first.ID)
+	}
+	if _, total, err := db.GetOrders(t.Context(), models.OrderFilter{RestaurantID: r.Restaurant.ID}, models.Page{Limit: 10}); err != nil || total != 0 {
+		t.Errorf("%d live orders, %v; want the retry to create none", total, err)
+	}
+}
+
+func TestConcurrentOrdersNeverOversellStock(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	item := r.Menu[0].ID
+	five := 5
+	if _, err := db.SetStock(t.Context(), item, &five); err != nil {
+		t.Fatal(err)
+	}
+
+	var placed, refused atomic.Int32
+	var firstID atomic.Int64
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o := models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending,
+				OrderItems: []models.OrderItem{{MenuItemID: item, Quantity: 1}}}
+			var short *storage.InsufficientStockError
+			switch err := db.CreateOrder(t.Context(), &o); {
+			case err == nil:
+				placed.Add(1)
+				firstID.CompareAndSwap(0, int64(o.ID))
+			case errors.As(err, &short):
+				refused.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	m, err := db.GetMenuItemByID(t.Context(), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.Load() != 5 || refused.Load() != 5 || *m.StockQuantity != 0 || m.Available {
+		t.Errorf("%d placed, %d refused, item %+v; want 5 each and the item sold out", placed.Load(), refused.Load(), m)
+	}
+
+	if _, err := db.CancelOrder(t.Context(), int(firstID.Load()), models.CancelReasonCustomerRequest, "", "staff"); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = db.GetMenuItemByID(t.Context(), item); err != nil || *m.StockQuantity != 1 || !m.Available {
+		t.Errorf("after a cancellation: %+v, %v; want 1 left and available again", m, err)
+	}
+}
+
+func TestRestaurantsCannotShareANameAndAddress(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithName("Taj Mahal Restaurant").WithAddress("Connaught Place").Build(t, db)
+
+	var duplicate *storage.DuplicateRestaurantError
+	again := models.Restaurant{Name: "taj mahal restaurant", Address: "CONNAUGHT PLACE", NotificationPreferences: models.DefaultNotificationPreferences()}
+	if err := db.CreateRestaurant(t.Context(), &again); !errors.As(err, &duplicate) || duplicate.ExistingID != r.Restaurant.ID {
+		t.Fatalf("CreateRestaurant of a duplicate = %v, want one naming %d", err, r.Restaurant.ID)
+	}
+
+	other := storagetest.NewRestaurant().WithName("Taj Mahal Restaurant").Build(t, db)
+	if _, err := db.PatchRestaurant(t.Context(), other.Restaurant.ID, models.RestaurantPatch{Address: "Connaught Place"}); !errors.As(err, &duplicate) || duplicate.ExistingID != r.Restaurant.ID {
+		t.Errorf("PatchRestaurant to a duplicate address = %v, want one naming %d", err, r.Restaurant.ID)
+	}
+}
+
+func TestHardDeleteRestaurantWithForce(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).Build(t, db)
+	storagetest.NewOrder(r).WithStatus(models.OrderStatusDelivered).WithItem(1, 2).Build(t, db)
+
+	var inUse *storage.RestaurantInUseError
+	if _, err := db.DeleteRestaurant(t.Context(), r.Restaurant.ID, models.RestaurantDeleteOptions{Hard: true}); !errors.As(err, &inUse) || inUse.MenuItems != 2 || inUse.Orders != 2 || inUse.OpenOrders != 1 {
+		t.Fatalf("DeleteRestaurant without force = %v, want 2 menu items and 2 orders, 1 open", err)
+	}
+	deleted, err := db.DeleteRestaurant(t.Context(), r.Restaurant.ID, models.RestaurantDeleteOptions{Hard: true, Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.RestaurantDeletion{RestaurantID: r.Restaurant.ID, MenuItems: 2, Orders: 2, OpenOrders: 1}); deleted != want {
+		t.Errorf("deleted %+v, want %+v", deleted, want)
+	}
+	if _, err := db.GetRestaurantByID(t.Context(), r.Restaurant.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetRestaurantByID after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRestaurantDeactivates(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithName("Closed Kitchen").WithMenu(1).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).Build(t, db)
+
+	if deleted, err := db.DeleteRestaurant(t.Context(), r.Restaurant.ID, models.RestaurantDeleteOptions{}); err != nil || !deleted.Deactivated {
+		t.Fatalf("DeleteRestaurant = %+v, %v; want it deactivated", deleted, err)
+	}
+	if list, total, err := db.GetAllRestaurants(t.Context(), models.Page{Limit: 10}, ""); err != nil || total != 0 || len(list) != 0 {
+		t.Errorf("GetAllRestaurants = %d of %d, %v; want the inactive restaurant left out", len(list), total, err)
+	}
+	if found, err := db.SearchRestaurants(t.Context(), "Closed", "", 10); err != nil || len(found) != 0 {
+		t.Errorf("SearchRestaurants = %+v, %v; want the inactive restaurant left out", found, err)
+	}
+	if got, err := db.GetRestaurantByID(t.Context(), r.Restaurant.ID); err != nil || got.Active {
+		t.Errorf("GetRestaurantByID = %+v, %v; want it marked inactive", got, err)
+	}
+	o := models.Order{RestaurantID: r.Restaurant.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: r.Menu[0].ID, Quantity: 1}}}
+	if err := db.CreateOrder(t.Context(), &o); !errors.Is(err, storage.ErrRestaurantInactive) {
+		t.Errorf("CreateOrder for an inactive restaurant = %v, want ErrRestaurantInactive", err)
+	}
+
+	if restored, err := db.RestoreRestaurant(t.Context(), r.Restaurant.ID); err != nil || !restored.Active {
+		t.Fatalf("RestoreRestaurant = %+v, %v", restored, err)
+	}
+	if err := db.CreateOrder(t.Context(), &o); err != nil {
+		t.Errorf("CreateOrder after restoring = %v", err)
+	}
+}
+
+func TestSearchRestaurants(t *testing.T) {
+	db := storagetest.DB(t)
+	storagetest.NewRestaurant().WithName("Hyderabad House").WithAddress("Road No. 1, Banjara Hills, Hyderabad").WithCuisine("Hyderabadi").Build(t, db)
+	storagetest.NewRestaurant().WithName("Banjara Biryani").WithAddress("Jubilee Hills, Hyderabad").WithCuisine("Hyderabadi").Build(t, db)
+	storagetest.NewRestaurant().WithName("Surya Mahal").WithAddress("Linking Road, Mumbai").WithCuisine("South Indian").Build(t, db)
+	storagetest.NewRestaurant().WithName("100% Dosa").WithAddress("MG Road, Pune").WithCuisine("South Indian").Build(t, db)
+
+	names := func(query, cuisine string, limit int) string {
+		t.Helper()
+		found, err := db.SearchRestaurants(t.Context(), query, cuisine, limit)
+		if err != nil {
+			t.Fatalf("SearchRestaurants(%q, %q): %v", query, cuisine, err)
+		}
+		var names []string
+		for _, r := range found {
+			names = append(names, r.Name)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	for _, tc := range []struct {
+		query, cuisine string
+		limit          int
+		want           string
+	}{
+		{"banjara", "", 0, "Banjara Biryani, Hyderabad House"},
+		{"banjara hills", "", 0, "Hyderabad House"},
+		{"", "south indian", 0, "100% Dosa, Surya Mahal"},
+		{"road", "South Indian", 0, "100% Dosa, Surya Mahal"},
+		{"road", "Hyderabadi", 0, "Hyderabad House"},
+		{"100%", "", 0, "100% Dosa"},
+		{"%", "", 0, "100% Dosa"},
+		{"", "", 2, "100% Dosa, Banjara Biryani"},
+		{"", "Indian", 0, ""},
+	} {
+		if got := names(tc.query, tc.cuisine, tc.limit); got != tc.want {
+			t.Errorf("SearchRestaurants(%q, %q, %d) = %q, want %q", tc.query, tc.cuisine, tc.limit, got, tc.want)
+		}
+	}
+	if _, err := db.SearchRestaurants(t.Context(), "", "", -1); err == nil {
+		t.Error("SearchRestaurants with a negative limit succeeded")
+	}
+}
+
+// TestNullColumnsRead clears every nullable column a read scans into a
+// plain field, as a row inserted by hand in psql may leave them
+func TestNullColumnsRead(t *testing.T) {
+	ctx := t.Context()
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithEmail("desk@example.com").WithMenu(1).Build(t, db)
+	o := storagetest.NewOrder(r).WithItem(0, 2).Build(t, db)
+
+	for _, q := range []string{
+		`UPDATE restaurants SET phone_number = NULL, email = NULL, cuisine_type = NULL, updated_at = NULL`,
+		`UPDATE menu_items SET description = NULL, category = NULL, dietary_type = NULL, spice_level = NULL, available = NULL`,
+		`UPDATE orders SET customer_phone = NULL, status = NULL, payment_status = NULL, payment_method = NULL, billing_address = NULL, updated_at = NULL`,
+		`UPDATE order_items SET notes = NULL`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	list, total, err := db.GetAllRestaurants(ctx, models.Page{Limit: 10}, "")
+	if err != nil || total != 1 || len(list) != 1 {
+		t.Fatalf("GetAllRestaurants = %d of %d, %v; want the restaurant", len(list), total, err)
+	}
+	if list[0].PhoneNumber != "" || list[0].UpdatedAt.IsZero() {
+		t.Errorf("restaurant %+v, want no phone and updated_at falling back to created_at", list[0])
+	}
+	menu, err := db.GetMenuByRestaurantID(ctx, r.Restaurant.ID, true)
+	if err != nil || len(menu) != 1 || menu[0].Available {
+		t.Errorf("GetMenuByRestaurantID = %+v, %v; want the item, unavailable", menu, err)
+	}
+	got, err := db.GetOrderByID(ctx, o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderStatusPending || got.PaymentStatus != "pending" || got.CustomerPhone != "" || len(got.OrderItems) != 1 {
+		t.Errorf("order %+v, want pending with no phone and its item", got)
+	}
+	if _, total, err := db.GetAllOrders(ctx, models.Page{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("GetAllOrders = %d, %v", total, err)
+	}
+}
+
+func TestAuditLogFilters(t *testing.T) {
+	db := storagetest.DB(t)
+	ctx := t.Context()
+	order := 7
+	for _, e := range []models.AuditEntry{
+		{Actor: "priya@example.com", Tool: "create_order", EntityType: models.AuditOrder, EntityID: &order, Arguments: map[string]interface{}{"customer_name": "Asha Rao"}},
+		{Actor: "priya@example.com", Tool: "cancel_order", EntityType: models.AuditOrder, EntityID: &order, Arguments: map[string]interface{}{"reason": "out_of_stock"}},
+		{Actor: "mcp", Tool: "bulk_create_menu_items", EntityType: models.AuditMenuItem, Arguments: map[string]interface{}{}},
+	} {
+		if err := db.RecordAudit(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, total, err := db.GetAuditLog(ctx, models.AuditQuery{EntityType: models.AuditOrder, EntityID: order}, models.Page{Limit: 1})
+	if err != nil || total != 2 || len(entries) != 1 {
+		t.Fatalf("GetAuditLog = %d of %d, %v; want 1 of 2", len(entries), total, err)
+	}
+	if entries[0].Tool != "cancel_order" || entries[0].Arguments["reason"] != "out_of_stock" {
+		t.Errorf("newest entry %+v, want the cancel", entries[0])
+	}
+	today := time.Now().UTC().Format(models.DateLayout)
+	if _, total, err := db.GetAuditLog(ctx, models.AuditQuery{From: today, To: today}, models.Page{Limit: 10}); err != nil || total != 3 {
+		t.Errorf("entries of today: %d, %v; want 3", total, err)
+	}
+}
+
+func TestExportOrdersStreamsLineItemsInRange(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	march := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	first := storagetest.NewOrder(r).WithCustomer(`Rao, Asha "Ashu"`, "").WithItem(0, 2).WithItem(1, 1).WithCreatedAt(march).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(march.AddDate(0, 1, 0)).Build(t, db)
+
+	from, to := models.OrderExportQuery{RestaurantID: r.Restaurant.ID, From: "2026-03-01", To: "2026-03-31"}.Range(r.Restaurant.Timezone)
+	var rows []models.OrderExportRow
+	err := db.ExportOrders(t.Context(), r.Restaurant.ID, from, to, func(row models.OrderExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("ExportOrders = %d rows, %v; want the 2 items of the March order", len(rows), err)
+	}
+	if rows[0].Order.ID != first.ID || rows[0].Order.CustomerName != `Rao, Asha "Ashu"` || rows[0].ItemName != r.Menu[0].Name || rows[0].Item.Quantity != 2 {
+		t.Errorf("first row %+v", rows[0])
+	}
+	if rows[1].Item.MenuItemID != r.Menu[1].ID || rows[1].Item.Subtotal != r.Menu[1].Price {
+		t.Errorf("second row %+v", rows[1])
+	}
+
+	stop := errors.New("stop")
+	if err := db.ExportOrders(t.Context(), r.Restaurant.ID, from, to, func(models.OrderExportRow) error { return stop }); err != stop {
+		t.Errorf("ExportOrders with a failing row = %v, want its error", err)
+	}
+}
+
+func TestArchiveOrdersMovesFinishedOrdersWithTheirRows(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	january := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	delivered := storagetest.NewOrder(r).WithStatus(models.OrderStatusDelivered).WithItem(0, 2).WithItem(1, 1).WithCreatedAt(january).Build(t, db)
+	pending := storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(january).Build(t, db)
+	recent := storagetest.NewOrder(r).WithStatus(models.OrderStatusCancelled).WithItem(0, 1).WithCreatedAt(january.AddDate(0, 2, 0)).Build(t, db)
+	if err := db.RecordOrderFeedback(t.Context(), &models.OrderFeedback{OrderID: delivered.ID, Score: 4, RecordedBy: "staff"}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := db.ArchiveOrders(t.Context(), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || n != 1 {
+		t.Fatalf("ArchiveOrders = %d, %v; want only the delivered January order", n, err)
+	}
+	if _, err := db.GetOrderByID(t.Context(), delivered.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetOrderByID(archived) = %v, want ErrNotFound", err)
+	}
+	for _, o := range []*models.Order{pending, recent} {
+		if _, err := db.GetOrderByID(t.Context(), o.ID); err != nil {
+			t.Errorf("order %d was archived: %v", o.ID, err)
+		}
+	}
+
+	archived, err := db.GetArchivedOrder(t.Context(), delivered.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived.ArchivedAt == nil || archived.Status != models.OrderStatusDelivered || archived.FinalAmount != delivered.FinalAmount ||
+		len(archived.OrderItems) != 2 || archived.OrderItems[0].Subtotal != delivered.OrderItems[0].Subtotal || archived.OrderItems[1].MenuItem.Name != r.Menu[1].Name {
+		t.Errorf("archived order %+v, want the delivered order with its 2 items", archived)
+	}
+	var feedback int
+	if err := db.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM order_feedback_archive WHERE order_id = $1`, delivered.ID).Scan(&feedback); err != nil || feedback != 1 {
+		t.Errorf("archived feedback rows = %d, %v; want 1", feedback, err)
+	}
+	if _, err := db.GetArchivedOrder(t.Context(), pending.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetArchivedOrder(live) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,18 @@
+-- Code generated by go generate ./internal/storage; DO NOT EDIT.
+-- Allowed values come from the constants in internal/models.
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_valid;
+ALTER TABLE orders ADD CONSTRAINT orders_status_valid
+	CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'out_for_delivery', 'delivered', 'cancelled'));
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_payment_status_valid;
+ALTER TABLE orders ADD CONSTRAINT orders_payment_status_valid
+	CHECK (payment_status IN ('pending', 'completed', 'failed', 'refunded'));
+
+ALTER TABLE menu_items DROP CONSTRAINT IF EXISTS menu_items_dietary_type_valid;
+ALTER TABLE menu_items ADD CONSTRAINT menu_items_dietary_type_valid
+	CHECK (dietary_type IN ('', 'vegetarian', 'non_vegetarian', 'vegan', 'jain_friendly'));
+
+ALTER TABLE menu_items DROP CONSTRAINT IF EXISTS menu_items_spice_level_valid;
+ALTER TABLE menu_items ADD CONSTRAINT menu_items_spice_level_valid
+	CHECK (spice_level IN ('', 'mild', 'medium', 'hot', 'extra_hot'));
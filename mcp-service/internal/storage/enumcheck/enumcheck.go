@@ -0,0 +1,139 @@
+// Package enumcheck describes the enum-valued columns whose allowed values are
+// defined by Go constants in internal/models, and renders the CHECK
+// constraints that hold the database to the same values. The SQL is written
+// to internal/storage/enum_checks.sql by go generate, so a change to the
+// constants shows up as a reviewable migration diff.
+package enumcheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// Column is a text column restricted to a fixed set of values
+type Column struct {
+	Table  string
+	Column string
+	Values []string
+	// Optional columns also accept the empty string, which the write paths
+	// store for "not specified". NULL always passes a CHECK constraint.
+	Optional bool
+	// Fallback replaces existing values that can't be mapped to a valid one.
+	// Empty means NULL.
+	Fallback string
+	// Aliases maps spellings seen in old data, after Normalize's case and
+	// separator folding, to their valid value
+	Aliases map[string]string
+}
+
+// Columns lists every enum column, in migration order
+var Columns = []Column{
+	{
+		Table:    "orders",
+		Column:   "status",
+		Values:   models.OrderStatuses,
+		Fallback: models.OrderStatusPending,
+		Aliases: map[string]string{
+			"canceled":   models.OrderStatusCancelled,
+			"cooking":    models.OrderStatusPreparing,
+			"in_kitchen": models.OrderStatusPreparing,
+			"dispatched": models.OrderStatusOutForDelivery,
+			"completed":  models.OrderStatusDelivered,
+		},
+	},
+	{
+		Table:    "orders",
+		Column:   "payment_status",
+		Values:   models.PaymentStatuses,
+		Fallback: models.PaymentStatusPending,
+		Aliases: map[string]string{
+			"paid":     models.PaymentStatusCompleted,
+			"complete": models.PaymentStatusCompleted,
+			"refund":   models.PaymentStatusRefunded,
+		},
+	},
+	{
+		Table:    "menu_items",
+		Column:   "dietary_type",
+		Values:   models.DietaryTypes,
+		Optional: true,
+		Aliases: map[string]string{
+			"veg":     models.DietaryVegetarian,
+			"nonveg":  models.DietaryNonVegetarian,
+			"non_veg": models.DietaryNonVegetarian,
+			"jain":    models.DietaryJainFriendly,
+		},
+	},
+	{
+		Table:    "menu_items",
+		Column:   "spice_level",
+		Values:   models.SpiceLevels,
+		Optional: true,
+		Aliases: map[string]string{
+			"low":      models.SpiceMild,
+			"spicy":    models.SpiceHot,
+			"high":     models.SpiceHot,
+			"very_hot": models.SpiceExtraHot,
+		},
+	},
+}
+
+// Find returns the enum column table.column
+func Find(table, column string) (Column, bool) {
+	for _, c := range Columns {
+		if c.Table == table && c.Column == column {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Constraint is the name of the column's CHECK constraint
+func (c Column) Constraint() string {
+	return c.Table + "_" + c.Column + "_valid"
+}
+
+// Allowed returns every value the constraint accepts
+func (c Column) Allowed() []string {
+	if c.Optional {
+		return append([]string{""}, c.Values...)
+	}
+	return c.Values
+}
+
+// Normalize maps an out-of-range value to a valid one, folding case,
+// surrounding space and "-" or " " separators before trying the aliases.
+// ok is false when nothing matches and Fallback has to be used.
+func (c Column) Normalize(value string) (normalized string, ok bool) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	v = strings.NewReplacer("-", "_", " ", "_").Replace(v)
+	for _, allowed := range c.Allowed() {
+		if v == allowed {
+			return v, true
+		}
+	}
+	if alias, found := c.Aliases[v]; found {
+		return alias, true
+	}
+	return "", false
+}
+
+// SQL renders the migration that (re)creates every CHECK constraint.
+// Constraints are dropped first so a changed value list replaces the old one.
+func SQL() string {
+	var b strings.Builder
+	b.WriteString("-- Code generated by go generate ./internal/storage; DO NOT EDIT.\n")
+	b.WriteString("-- Allowed values come from the constants in internal/models.\n")
+	for _, c := range Columns {
+		quoted := make([]string, len(c.Allowed()))
+		for i, v := range c.Allowed() {
+			quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+		fmt.Fprintf(&b, "\nALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;\n", c.Table, c.Constraint())
+		fmt.Fprintf(&b, "ALTER TABLE %s ADD CONSTRAINT %s\n\tCHECK (%s IN (%s));\n",
+			c.Table, c.Constraint(), c.Column, strings.Join(quoted, ", "))
+	}
+	return b.String()
+}
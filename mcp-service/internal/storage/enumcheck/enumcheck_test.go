@@ -0,0 +1,30 @@
+package enumcheck
+
+import "testing"
+
+func TestNormalizeMapsLegacyValues(t *testing.T) {
+	tests := []struct {
+		table, column, value string
+		want                 string
+		ok                   bool
+	}{
+		{"orders", "status", "cooking", "preparing", true},
+		{"orders", "status", "Out for delivery", "out_for_delivery", true},
+		{"orders", "status", "preparing", "preparing", true},
+		{"orders", "status", "lost", "", false},
+		{"orders", "payment_status", "paid", "completed", true},
+		{"menu_items", "spice_level", "", "", true},
+	}
+	for _, tc := range tests {
+		c, found := Find(tc.table, tc.column)
+		if !found {
+			t.Fatalf("no enum column %s.%s", tc.table, tc.column)
+		}
+		if got, ok := c.Normalize(tc.value); got != tc.want || ok != tc.ok {
+			t.Errorf("%s.%s %q: got %q, %v, want %q, %v", tc.table, tc.column, tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+	if _, found := Find("orders", "customer_name"); found {
+		t.Error("found a column that isn't an enum")
+	}
+}
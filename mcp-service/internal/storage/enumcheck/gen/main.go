@@ -0,0 +1,20 @@
+// Command gen writes the enum CHECK constraint migration rendered by
+// enumcheck.SQL. It is run by go generate in internal/storage.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/enumcheck"
+)
+
+func main() {
+	out := flag.String("o", "enum_checks.sql", "output file")
+	flag.Parse()
+
+	if err := os.WriteFile(*out, []byte(enumcheck.SQL()), 0o644); err != nil {
+		log.Fatalf("enumcheck: %v", err)
+	}
+}
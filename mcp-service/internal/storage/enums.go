@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/enumcheck"
+)
+
+//go:generate go run ./enumcheck/gen -o enum_checks.sql
+
+// enumChecksSQL is the CHECK constraint migration generated from the Go
+// constants; see package enumcheck
+//
+//go:embed enum_checks.sql
+var enumChecksSQL string
+
+// EnumFix records existing rows rewritten by MigrateEnums because their
+// value wasn't valid. An empty To means the value was set to NULL.
+type EnumFix struct {
+	Table  string
+	Column string
+	From   string
+	To     string
+	Rows   int64
+	Mapped bool // false when To is the column's fallback rather than a match
+}
+
+func (f EnumFix) String() string {
+	to := fmt.Sprintf("%q", f.To)
+	if f.To == "" {
+		to = "NULL"
+	}
+	how := "mapped"
+	if !f.Mapped {
+		how = "no match, fallback"
+	}
+	return fmt.Sprintf("%s.%s: %q -> %s (%s, %d rows)", f.Table, f.Column, f.From, to, how, f.Rows)
+}
+
+// MigrateEnums normalizes existing enum column values and then adds the
+// generated CHECK constraints, in one transaction. Each rewrite is logged and
+// returned so operators can see what changed. It refuses to run when
+// enum_checks.sql is older than the constants.
+func (db *DB) MigrateEnums(ctx context.Context) ([]EnumFix, error) {
+	if enumChecksSQL != enumcheck.SQL() {
+		return nil, fmt.Errorf("enum_checks.sql is out of date with internal/models; run go generate ./internal/storage")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var fixes []EnumFix
+	for _, c := range enumcheck.Columns {
+		// Table and column names come from enumcheck.Columns, never from input
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+			`SELECT %[2]s, COUNT(*) FROM %[1]s WHERE %[2]s IS NOT NULL AND NOT (%[2]s = ANY($1)) GROUP BY %[2]s ORDER BY %[2]s`,
+			c.Table, c.Column), pq.Array(c.Allowed()))
+		if err != nil {
+			return nil, err
+		}
+		var found []EnumFix
+		for rows.Next() {
+			f := EnumFix{Table: c.Table, Column: c.Column}
+			if err := rows.Scan(&f.From, &f.Rows); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if f.To, f.Mapped = c.Normalize(f.From); !f.Mapped {
+				f.To = c.Fallback
+			}
+			found = append(found, f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, f := range found {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %[1]s SET %[2]s = NULLIF($1, '') WHERE %[2]s = $2`, c.Table, c.Column), f.To, f.From)
+			if err != nil {
+				return nil, fmt.Errorf("normalizing %s.%s: %v", c.Table, c.Column, err)
+			}
+			log.Printf("Enum migration: %s", f)
+		}
+		fixes = append(fixes, found...)
+	}
+
+	if _, err := tx.ExecContext(ctx, enumChecksSQL); err != nil {
+		return nil, fmt.Errorf("adding enum constraints: %v", err)
+	}
+	return fixes, tx.Commit()
+}
+
+// readEnum maps a value read from an enum column to its current spelling,
+// for rows written with the legacy vocabulary (cooking, paid) that
+// MigrateEnums hasn't rewritten yet. A value it can't map is logged and
+// returned as is, so reads never fail on old data.
+func readEnum(table, column, value string) string {
+	c, ok := enumcheck.Find(table, column)
+	if !ok {
+		return value
+	}
+	normalized, ok := c.Normalize(value)
+	if !ok {
+		log.Printf("Enum read: %s.%s has unmapped value %q", table, column, value)
+		return value
+	}
+	return normalized
+}
@@ -0,0 +1,8 @@
+package storage
+
+// ReadColumns are the column registries by table, for the schema tests
+var ReadColumns = map[string][]string{
+	"restaurants": restaurantColumns.names(),
+	"menu_items":  menuItemColumns.names(),
+	"orders":      orderColumns.names(),
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// sharedReadTimeout bounds a deduplicated read. The leader runs on a context
+// detached from any caller, so this is the only thing that can stop it.
+const sharedReadTimeout = 10 * time.Second
+
+// flightGroup collapses concurrent identical reads into one database round
+// trip, in the spirit of golang.org/x/sync/singleflight. Unlike that package
+// the shared call runs on its own goroutine and context, so a caller whose
+// request is cancelled stops waiting without failing the other waiters, and
+// writers can forget the calls their change makes stale.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	dups int // callers that joined the call, guarded by the group's mu
+}
+
+// do runs fn once for all concurrent callers with the same key. shared is
+// true when the caller joined a call another caller started.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (val interface{}, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, shared := g.calls[key]
+	if shared {
+		c.dups++
+	} else {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	if shared {
+		metrics.IncCounter("db_read_shared_total", "Reads served by joining an identical in-flight query")
+	} else {
+		metrics.IncCounter("db_read_leader_total", "Reads that executed a query shared with concurrent identical reads")
+	}
+
+	select {
+	case <-c.done:
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		return nil, shared, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(key string, c *flightCall, fn func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedReadTimeout)
+	defer cancel()
+	c.val, c.err = fn(ctx)
+
+	g.mu.Lock()
+	// A writer may have forgotten this call and a new one taken the key
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// forget drops the calls in flight under scopes, so a read that starts
+// after a write never joins a query that began before it. Callers already
+// waiting keep the result they joined for. Writers call it once their
+// change is committed, before returning.
+func (g *flightGroup) forget(scopes ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key := range g.calls {
+		for _, scope := range scopes {
+			if strings.HasPrefix(key, scope+"\x00") {
+				delete(g.calls, key)
+				break
+			}
+		}
+	}
+}
+
+// cloned returns a copy of the value p points to, or nil
+func cloned[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// sharedRestaurant copies a restaurant of a shared read for one caller,
+// pointer fields included, so no caller sees another's changes
+func sharedRestaurant(r models.Restaurant) models.Restaurant {
+	r.TaxRate = cloned(r.TaxRate)
+	r.ServiceChargePercent = cloned(r.ServiceChargePercent)
+	return r
+}
+
+// sharedMenuItem is sharedRestaurant for a menu item
+func sharedMenuItem(m models.MenuItem) models.MenuItem {
+	m.StockQuantity = cloned(m.StockQuantity)
+	m.LastPriceChangeAt = cloned(m.LastPriceChangeAt)
+	return m
+}
+
+// restaurantReads is the scope of the shared restaurant reads, single
+// restaurants and pages alike
+const restaurantReads = "restaurants"
+
+// menuReads is the scope of the shared reads of one restaurant's menu
+func menuReads(restaurantID int) string {
+	return fmt.Sprintf("menu:%d", restaurantID)
+}
+
+// flightKey normalizes a query and its arguments into a dedup key under
+// scope, so the same read issued with different whitespace still shares one
+// call and a writer can forget it
+func flightKey(scope, query string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(scope)
+	b.WriteByte(0)
+	b.WriteString(strings.Join(strings.Fields(query), " "))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "\x00%T:%v", arg, arg)
+	}
+	return b.String()
+}
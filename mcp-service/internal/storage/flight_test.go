@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// leaderRead starts a read of key that returns val once release is closed,
+// and waits until it is running. Only use it for keys with no call in
+// flight, or it waits forever.
+func leaderRead(g *flightGroup, key, val string, release chan struct{}) <-chan interface{} {
+	started := make(chan struct{})
+	result := make(chan interface{}, 1)
+	go func() {
+		v, _, _ := g.do(context.Background(), key, func(context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return val, nil
+		})
+		result <- v
+	}()
+	<-started
+	return result
+}
+
+// TestForgottenReadIsNotJoined is a write landing while a read of the menu
+// is in flight: reads after it must query again rather than take the result
+// of the read that began before it
+func TestForgottenReadIsNotJoined(t *testing.T) {
+	var g flightGroup
+	key, otherMenu := flightKey(menuReads(1), "SELECT menu"), flightKey(menuReads(10), "SELECT menu")
+	releaseStale, releaseOther := make(chan struct{}), make(chan struct{})
+	defer close(releaseOther)
+	stale := leaderRead(&g, key, "old price", releaseStale)
+	leaderRead(&g, otherMenu, "other menu", releaseOther)
+
+	g.forget(menuReads(1))
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	v, shared, err := g.do(ctx, key, func(context.Context) (interface{}, error) { return "new price", nil })
+	if v != "new price" || shared || err != nil {
+		t.Fatalf("read after forget = %v, shared %v, %v; want its own query", v, shared, err)
+	}
+
+	// The forgotten read still answers its own callers, and finishing
+	// mustn't drop a newer read of the same key
+	newer := make(chan struct{})
+	fresh := leaderRead(&g, key, "newer price", newer)
+	close(releaseStale)
+	if v := <-stale; v != "old price" {
+		t.Errorf("forgotten read returned %v to its caller, want old price", v)
+	}
+	g.mu.Lock()
+	_, newerInFlight := g.calls[key]
+	_, otherInFlight := g.calls[otherMenu]
+	g.mu.Unlock()
+	close(newer)
+	<-fresh
+	if !newerInFlight {
+		t.Error("the forgotten read finishing dropped the newer read of its key")
+	}
+	if !otherInFlight {
+		t.Error("forgetting menu 1 dropped the read of menu 10")
+	}
+}
+
+// joined waits until n callers have joined the call in flight for key
+func joined(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		dups := g.calls[key].dups
+		g.mu.Unlock()
+		if dups >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers joined the read, want %d", dups, n)
+		}
+	}
+}
+
+// TestCancelledFollowerLeavesTheReadRunning cancels one of two callers that
+// joined a read: it stops waiting, and the read still answers the leader and
+// the other follower
+func TestCancelledFollowerLeavesTheReadRunning(t *testing.T) {
+	var g flightGroup
+	key := flightKey(menuReads(1), "SELECT menu")
+	release := make(chan struct{})
+	leader := leaderRead(&g, key, "menu", release)
+	ownQuery := func(context.Context) (interface{}, error) { return "own query", nil }
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(ctx, key, ownQuery)
+		cancelled <- err
+	}()
+	other := make(chan interface{}, 1)
+	go func() {
+		v, _, _ := g.do(t.Context(), key, ownQuery)
+		other <- v
+	}()
+	joined(t, &g, key, 2)
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled follower got %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if v := <-leader; v != "menu" {
+		t.Errorf("leader got %v after a follower was cancelled, want menu", v)
+	}
+	if v := <-other; v != "menu" {
+		t.Errorf("other follower got %v after a follower was cancelled, want menu", v)
+	}
+}
+
+func TestSharedMenuItemCopiesPointerFields(t *testing.T) {
+	stock, changed := 5, time.Now()
+	shared := models.MenuItem{ID: 1, StockQuantity: &stock, LastPriceChangeAt: &changed}
+	m := sharedMenuItem(shared)
+	*m.StockQuantity = 4
+	*m.LastPriceChangeAt = time.Time{}
+	if stock != 5 || changed.IsZero() {
+		t.Errorf("changing a caller's copy changed the shared item: stock %d, last price change %v", stock, changed)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/metrics"
+)
+
+// Reconnect tuning for EnsureConnected
+const (
+	pingTimeout       = 2 * time.Second
+	reconnectAttempts = 3
+	reconnectBackoff  = 500 * time.Millisecond
+)
+
+// outage tracks when the database became unreachable so the outage window
+// can be logged once it recovers
+type outage struct {
+	mu    sync.Mutex
+	since time.Time
+}
+
+// ErrUnavailable is returned by EnsureConnected when the database could not
+// be reached after all reconnect attempts
+type ErrUnavailable struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ErrUnavailable) Error() string {
+	return fmt.Sprintf("database temporarily unavailable, retried %d times: %v", e.Attempts, e.Err)
+}
+
+func (e *ErrUnavailable) Unwrap() error { return e.Err }
+
+// EnsureConnected pings the database and, if that fails, retries with a
+// linear backoff. database/sql discards broken pooled connections on a failed
+// ping, so a successful retry means the next query gets a fresh connection.
+// Retrying stops early once ctx is done.
+func (db *DB) EnsureConnected(ctx context.Context) error {
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			db.markUp()
+			return nil
+		}
+		db.markDown(err)
+		if attempt < reconnectAttempts {
+			select {
+			case <-ctx.Done():
+				return &ErrUnavailable{Attempts: attempt, Err: err}
+			case <-time.After(time.Duration(attempt) * reconnectBackoff):
+			}
+		}
+	}
+	return &ErrUnavailable{Attempts: reconnectAttempts, Err: err}
+}
+
+func (db *DB) markDown(err error) {
+	db.outage.mu.Lock()
+	defer db.outage.mu.Unlock()
+	if db.outage.since.IsZero() {
+		db.outage.since = time.Now()
+		log.Printf("Database unreachable, outage started: %v", err)
+	}
+	metrics.SetGauge("db_up", "1 if the last database health check succeeded", 0)
+}
+
+func (db *DB) markUp() {
+	db.outage.mu.Lock()
+	defer db.outage.mu.Unlock()
+	if !db.outage.since.IsZero() {
+		window := time.Since(db.outage.since)
+		log.Printf("Database reachable again after %s outage (%s - %s)",
+			window.Round(time.Millisecond), db.outage.since.Format(time.RFC3339), time.Now().Format(time.RFC3339))
+		metrics.SetGauge("db_last_outage_seconds", "Duration of the most recent database outage", window.Seconds())
+		db.outage.since = time.Time{}
+	}
+	metrics.SetGauge("db_up", "1 if the last database health check succeeded", 1)
+}
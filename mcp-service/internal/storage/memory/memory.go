@@ -0,0 +1,1840 @@
+// Package memory is an in-memory storage.Store for tests of the MCP servers
+// and handlers. It mirrors the Postgres implementation's validation, error
+// messages and order lifecycle checks, but not its concurrency guarantees
+// beyond a single lock.
+//
+//	store := memory.New()
+//	store.CreateRestaurant(ctx, &models.Restaurant{Name: "Test", Address: "Pune"})
+//	server := NewMCPServer(store, links)
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// Store holds restaurants, menu items and orders in maps keyed by ID
+type Store struct {
+	mu          sync.Mutex
+	restaurants map[int]models.Restaurant
+	menuItems   map[int]models.MenuItem
+	orders      map[int]models.Order
+	prices      []models.PriceChange
+	feedback    map[int]models.OrderFeedback       // by order ID
+	history     map[int][]models.OrderStatusChange // by order ID, oldest first
+	changes     []models.ChangeRequest             // in ID order
+	rules       []models.PricingRule               // in ID order
+	shadowed    []models.ShadowedAction            // in ID order
+	audit       []models.AuditEntry                // in ID order
+	archived    map[int]models.Order               // moved out of orders by ArchiveOrders
+	idempotent  map[idempotencyKey]int             // order IDs
+	nextID      map[string]int
+
+	// Err, when set, is returned by every method, to exercise error paths
+	Err error
+}
+
+var _ storage.Store = (*Store)(nil)
+
+// New returns an empty Store
+func New() *Store {
+	return &Store{
+		restaurants: map[int]models.Restaurant{},
+		menuItems:   map[int]models.MenuItem{},
+		orders:      map[int]models.Order{},
+		feedback:    map[int]models.OrderFeedback{},
+		history:     map[int][]models.OrderStatusChange{},
+		archived:    map[int]models.Order{},
+		idempotent:  map[idempotencyKey]int{},
+		nextID:      map[string]int{},
+	}
+}
+
+// idempotencyKey is unique among the orders of a restaurant
+type idempotencyKey struct {
+	restaurantID int
+	key          string
+}
+
+func (s *Store) id(table string) int {
+	s.nextID[table]++
+	return s.nextID[table]
+}
+
+func notFound(entity string, id int) error {
+	return fmt.Errorf("%s with ID %d %w", entity, id, storage.ErrNotFound)
+}
+
+// recordStatus adds a step to an order's status timeline
+func (s *Store) recordStatus(ctx context.Context, orderID int, from, to string) {
+	s.recordStep(ctx, orderID, from, to, "")
+}
+
+// recordStep is recordStatus with a note explaining the step, which is
+// recorded even if the status stays the same
+func (s *Store) recordStep(ctx context.Context, orderID int, from, to, note string) {
+	if from == to && note == "" {
+		return
+	}
+	s.history[orderID] = append(s.history[orderID], models.OrderStatusChange{
+		OrderID: orderID, FromStatus: from, ToStatus: to, ChangedAt: time.Now(), ChangedBy: storage.Actor(ctx), Note: note,
+	})
+}
+
+// EnsureConnected returns Err
+func (s *Store) EnsureConnected(ctx context.Context) error {
+	return s.Err
+}
+
+// GetAllRestaurants returns one page of active restaurants in the given sort order
+func (s *Store) GetAllRestaurants(ctx context.Context, page models.Page, sortBy string) ([]models.Restaurant, int, error) {
+	if err := models.ValidateRestaurantSort(sortBy); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+
+	all := make([]models.Restaurant, 0, len(s.restaurants))
+	for _, r := range s.restaurants {
+		if r.Active {
+			all = append(all, r)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		switch sortBy {
+		case models.RestaurantSortName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		case models.RestaurantSortCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case models.RestaurantSortCuisineType:
+			if a.CuisineType != b.CuisineType {
+				return a.CuisineType < b.CuisineType
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		}
+		return a.ID < b.ID
+	})
+	return pageOf(all, page), len(all), nil
+}
+
+// SearchRestaurants returns up to limit active restaurants matching query
+// and cuisine, ordered by name
+func (s *Store) SearchRestaurants(ctx context.Context, query, cuisine string, limit int) ([]models.Restaurant, error) {
+	limit, err := models.RestaurantSearchLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	found := []models.Restaurant{}
+	for _, r := range s.restaurants {
+		if r.Active && models.MatchesRestaurantSearch(r, strings.TrimSpace(query), strings.TrimSpace(cuisine)) {
+			found = append(found, r)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Name != found[j].Name {
+			return found[i].Name < found[j].Name
+		}
+		return found[i].ID < found[j].ID
+	})
+	return pageOf(found, models.Page{Limit: limit}), nil
+}
+
+// GetRestaurantByID returns a single restaurant
+func (s *Store) GetRestaurantByID(ctx context.Context, id int) (*models.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	r, ok := s.restaurants[id]
+	if !ok {
+		return nil, notFound("restaurant", id)
+	}
+	return &r, nil
+}
+
+// CreateRestaurant stores a restaurant and fills in its ID and timestamps
+func (s *Store) CreateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := models.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+	if err := models.ValidateTaxRate(r.TaxRate); err != nil {
+		return err
+	}
+	if err := models.ValidateServiceChargePercent(r.ServiceChargePercent); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if err := s.checkDuplicate(0, r.Name, r.Address); err != nil {
+		return err
+	}
+	r.ID = s.id("restaurants")
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	r.Active, r.Version = true, 1
+	if r.Timezone == "" {
+		r.Timezone = "Asia/Kolkata"
+	}
+	s.restaurants[r.ID] = *r
+	return nil
+}
+
+// UpdateRestaurant overwrites all editable fields of the restaurant with ID r.ID
+func (s *Store) UpdateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := models.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	stored, ok := s.restaurants[r.ID]
+	if !ok {
+		return notFound("restaurant", r.ID)
+	}
+	if stored.Version != r.Version {
+		return &storage.VersionConflictError{Entity: "restaurant", ID: r.ID, Expected: r.Version}
+	}
+	if err := s.checkDuplicate(r.ID, r.Name, r.Address); err != nil {
+		return err
+	}
+	stored.Version++
+	stored.Name, stored.Address, stored.PhoneNumber = r.Name, r.Address, r.PhoneNumber
+	stored.Email, stored.CuisineType = r.Email, r.CuisineType
+	stored.NotificationPreferences = r.NotificationPreferences
+	stored.UpdatedAt = time.Now()
+	s.restaurants[r.ID] = stored
+	r.CreatedAt, r.UpdatedAt, r.Version = stored.CreatedAt, stored.UpdatedAt, stored.Version
+	return nil
+}
+
+// PatchRestaurant changes only the fields set in patch
+func (s *Store) PatchRestaurant(ctx context.Context, id int, patch models.RestaurantPatch) (*models.Restaurant, error) {
+	if err := models.ValidateEmail(patch.Email); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateTaxRate(patch.TaxRate); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateServiceChargePercent(patch.ServiceChargePercent); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	r, ok := s.restaurants[id]
+	if !ok {
+		return nil, notFound("restaurant", id)
+	}
+	if patch.ExpectedVersion != nil && *patch.ExpectedVersion != r.Version {
+		return nil, &storage.VersionConflictError{Entity: "restaurant", ID: id, Expected: *patch.ExpectedVersion}
+	}
+	r.Version++
+	setIf(&r.Name, patch.Name)
+	setIf(&r.Address, patch.Address)
+	if err := s.checkDuplicate(id, r.Name, r.Address); err != nil {
+		return nil, err
+	}
+	setIf(&r.PhoneNumber, patch.PhoneNumber)
+	setIf(&r.Email, patch.Email)
+	setIf(&r.CuisineType, patch.CuisineType)
+	r.NotificationPreferences.Apply(patch.NotificationPreferences)
+	if patch.PublicMenuEnabled != nil {
+		r.PublicMenuEnabled = *patch.PublicMenuEnabled
+	}
+	if patch.AutoConfirmOrders != nil {
+		r.AutoConfirmOrders = *patch.AutoConfirmOrders
+	}
+	if patch.TaxRate != nil {
+		rate := *patch.TaxRate
+		r.TaxRate = &rate
+	}
+	if patch.ServiceChargePercent != nil {
+		percent := *patch.ServiceChargePercent
+		r.ServiceChargePercent = &percent
+	}
+	r.UpdatedAt = time.Now()
+	s.restaurants[id] = r
+	return &r, nil
+}
+
+// checkDuplicate refuses a name and address another restaurant than the one
+// with id has, ignoring case, like the unique index in Postgres
+func (s *Store) checkDuplicate(id int, name, address string) error {
+	for _, other := range s.restaurants {
+		if other.ID != id && strings.EqualFold(other.Name, name) && strings.EqualFold(other.Address, address) {
+			return &storage.DuplicateRestaurantError{ExistingID: other.ID}
+		}
+	}
+	return nil
+}
+
+func setIf(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// DeleteRestaurant deactivates a restaurant, or with opts.Hard removes it.
+// Like the foreign keys in Postgres, a hard delete refuses while menu items
+// or orders still reference it, unless opts.Force is set: then they are
+// deleted along with it.
+func (s *Store) DeleteRestaurant(ctx context.Context, id int, opts models.RestaurantDeleteOptions) (models.RestaurantDeletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := models.RestaurantDeletion{RestaurantID: id}
+	if s.Err != nil {
+		return d, s.Err
+	}
+	r, ok := s.restaurants[id]
+	if !ok {
+		return d, notFound("restaurant", id)
+	}
+	if !opts.Hard {
+		r.Active, r.UpdatedAt = false, time.Now()
+		s.restaurants[id] = r
+		d.Deactivated = true
+		return d, nil
+	}
+	for _, m := range s.menuItems {
+		if m.RestaurantID == id {
+			d.MenuItems++
+		}
+	}
+	for _, o := range s.orders {
+		if o.RestaurantID == id {
+			d.Orders++
+			if o.Status != models.OrderStatusDelivered && o.Status != models.OrderStatusCancelled {
+				d.OpenOrders++
+			}
+		}
+	}
+	if !opts.Force && (d.MenuItems > 0 || d.Orders > 0) {
+		return d, &storage.RestaurantInUseError{RestaurantID: id, MenuItems: d.MenuItems, Orders: d.Orders, OpenOrders: d.OpenOrders}
+	}
+
+	for orderID, o := range s.orders {
+		if o.RestaurantID == id {
+			delete(s.orders, orderID)
+			delete(s.feedback, orderID)
+			delete(s.history, orderID)
+		}
+	}
+	for itemID, m := range s.menuItems {
+		if m.RestaurantID == id {
+			delete(s.menuItems, itemID)
+		}
+	}
+	s.prices = slices.DeleteFunc(s.prices, func(c models.PriceChange) bool { _, ok := s.menuItems[c.ItemID]; return !ok })
+	s.rules = slices.DeleteFunc(s.rules, func(r models.PricingRule) bool { return r.RestaurantID == id })
+	delete(s.restaurants, id)
+	return d, nil
+}
+
+// RestoreRestaurant reactivates a restaurant deleted without Hard
+func (s *Store) RestoreRestaurant(ctx context.Context, id int) (*models.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	r, ok := s.restaurants[id]
+	if !ok {
+		return nil, notFound("restaurant", id)
+	}
+	r.Active, r.UpdatedAt = true, time.Now()
+	s.restaurants[id] = r
+	return &r, nil
+}
+
+// GetMenuByRestaurantID returns the menu effective today
+func (s *Store) GetMenuByRestaurantID(ctx context.Context, restaurantID int, includeUnavailable bool) ([]models.MenuItem, error) {
+	return s.GetMenuAsOf(ctx, restaurantID, "", includeUnavailable)
+}
+
+// GetMenuAsOf returns the menu items effective on asOf (YYYY-MM-DD), or
+// today in the restaurant's timezone when asOf is empty. Unavailable items
+// are left out unless includeUnavailable.
+func (s *Store) GetMenuAsOf(ctx context.Context, restaurantID int, asOf string, includeUnavailable bool) ([]models.MenuItem, error) {
+	if asOf != "" {
+		if _, err := time.Parse(models.DateLayout, asOf); err != nil {
+			return nil, fmt.Errorf("invalid as_of %q: expected YYYY-MM-DD", asOf)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	day := asOf
+	if day == "" {
+		tz := "Asia/Kolkata"
+		if r, ok := s.restaurants[restaurantID]; ok && r.Timezone != "" {
+			tz = r.Timezone
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			loc = time.UTC
+		}
+		day = time.Now().In(loc).Format(models.DateLayout)
+	}
+
+	items := []models.MenuItem{}
+	for _, m := range s.menuItems {
+		if m.RestaurantID != restaurantID || (!m.Available && !includeUnavailable) {
+			continue
+		}
+		// YYYY-MM-DD strings compare in date order
+		if (m.EffectiveFrom != "" && m.EffectiveFrom > day) || (m.EffectiveTo != "" && m.EffectiveTo < day) {
+			continue
+		}
+		items = append(items, m)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Category != items[j].Category {
+			return items[i].Category < items[j].Category
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
+
+// SearchMenuItems returns the items of a restaurant's current menu that
+// match every filter of q
+func (s *Store) SearchMenuItems(ctx context.Context, q models.MenuSearch) ([]models.MenuItem, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	menu, err := s.GetMenuAsOf(ctx, q.RestaurantID, "", false)
+	if err != nil {
+		return nil, err
+	}
+	items := []models.MenuItem{}
+	for _, m := range menu {
+		if q.Matches(m) {
+			items = append(items, m)
+		}
+	}
+	return items, nil
+}
+
+// GetMenuItemByID returns a single menu item
+func (s *Store) GetMenuItemByID(ctx context.Context, id int) (*models.MenuItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	m, ok := s.menuItems[id]
+	if !ok {
+		return nil, notFound("menu item", id)
+	}
+	return &m, nil
+}
+
+// GetMenuItemsByIDs returns the menu items with the given IDs keyed by ID,
+// leaving out IDs without one
+func (s *Store) GetMenuItemsByIDs(ctx context.Context, ids []int) (map[int]models.MenuItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	items := make(map[int]models.MenuItem, len(ids))
+	for _, id := range ids {
+		if m, ok := s.menuItems[id]; ok {
+			items[id] = m
+		}
+	}
+	return items, nil
+}
+
+// CreateMenuItem stores a menu item and fills in its ID and creation time
+func (s *Store) CreateMenuItem(ctx context.Context, m *models.MenuItem) error {
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if _, ok := s.restaurants[m.RestaurantID]; !ok {
+		return notFound("restaurant", m.RestaurantID)
+	}
+	m.ID, m.Version = s.id("menu_items"), 1
+	m.CreatedAt = time.Now()
+	m.UpdatedAt = m.CreatedAt
+	s.menuItems[m.ID] = *m
+	return nil
+}
+
+// CreateMenuItems stores menu items and fills in their IDs and timestamps,
+// or none of them if any item is invalid
+func (s *Store) CreateMenuItems(ctx context.Context, items []*models.MenuItem) error {
+	for i, m := range items {
+		if err := m.Validate(); err != nil {
+			return &storage.BatchItemError{Index: i, Err: err}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	for i, m := range items {
+		if _, ok := s.restaurants[m.RestaurantID]; !ok {
+			return &storage.BatchItemError{Index: i, Err: notFound("restaurant", m.RestaurantID)}
+		}
+	}
+	for _, m := range items {
+		m.ID, m.Version = s.id("menu_items"), 1
+		m.CreatedAt = time.Now()
+		m.UpdatedAt = m.CreatedAt
+		s.menuItems[m.ID] = *m
+	}
+	return nil
+}
+
+// UpdateMenuItem overwrites all editable fields of a menu item and records
+// a price change
+func (s *Store) UpdateMenuItem(ctx context.Context, m *models.MenuItem, changedBy string) error {
+	if err := models.ValidateEffectiveDates(m.EffectiveFrom, m.EffectiveTo); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	return s.updateMenuItem(m, changedBy)
+}
+
+// updateMenuItem is UpdateMenuItem with s.mu held
+func (s *Store) updateMenuItem(m *models.MenuItem, changedBy string) error {
+	stored, ok := s.menuItems[m.ID]
+	if !ok {
+		return notFound("menu item", m.ID)
+	}
+	if stored.Version != m.Version {
+		return &storage.VersionConflictError{Entity: "menu item", ID: m.ID, Expected: m.Version}
+	}
+	m.Version++
+	if stored.Price != m.Price {
+		if changedBy == "" {
+			changedBy = "staff"
+		}
+		s.prices = append(s.prices, models.PriceChange{
+			ID: s.id("menu_item_price_history"), ItemID: m.ID, ItemName: m.Name, RestaurantID: stored.RestaurantID,
+			OldPrice: stored.Price, NewPrice: m.Price, ChangedBy: changedBy, ChangedAt: time.Now(),
+		})
+	}
+	m.RestaurantID, m.CreatedAt, m.UpdatedAt = stored.RestaurantID, stored.CreatedAt, time.Now()
+	m.StockQuantity = stored.StockQuantity
+	s.menuItems[m.ID] = *m
+	return nil
+}
+
+// GetPriceHistory returns one page of the price changes selected by q,
+// newest first. Dates are compared in UTC.
+func (s *Store) GetPriceHistory(ctx context.Context, q models.PriceHistoryQuery, page models.Page) ([]models.PriceChange, int, error) {
+	if err := q.Validate(); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	matched := []models.PriceChange{}
+	for i := len(s.prices) - 1; i >= 0; i-- {
+		c := s.prices[i]
+		day := c.ChangedAt.UTC().Format(models.DateLayout)
+		if (q.ItemID > 0 && c.ItemID != q.ItemID) || (q.RestaurantID > 0 && c.RestaurantID != q.RestaurantID) ||
+			(q.From != "" && day < q.From) || (q.To != "" && day > q.To) {
+			continue
+		}
+		if m, ok := s.menuItems[c.ItemID]; ok {
+			c.ItemName = m.Name
+		}
+		matched = append(matched, c)
+	}
+	return pageOf(matched, page), len(matched), nil
+}
+
+// LastPriceChanges returns when each item of a restaurant last changed price
+func (s *Store) LastPriceChanges(ctx context.Context, restaurantID int) (map[int]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	last := map[int]time.Time{}
+	for _, c := range s.prices {
+		if c.RestaurantID == restaurantID && c.ChangedAt.After(last[c.ItemID]) {
+			last[c.ItemID] = c.ChangedAt
+		}
+	}
+	return last, nil
+}
+
+// SetStock sets the portions left of a menu item, nil to stop tracking
+// them, and returns the item
+func (s *Store) SetStock(ctx context.Context, menuItemID int, quantity *int) (*models.MenuItem, error) {
+	if quantity != nil && *quantity < 0 {
+		return nil, fmt.Errorf("quantity must be a whole number of at least 0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	m, ok := s.menuItems[menuItemID]
+	if !ok {
+		return nil, notFound("menu item", menuItemID)
+	}
+	if quantity != nil {
+		q := *quantity // not shared with the caller
+		quantity = &q
+	}
+	s.writeStock(m, quantity)
+	m = s.menuItems[menuItemID]
+	return &m, nil
+}
+
+// moveStock checks that the stock of the menu items covers taken once the
+// stock released took is put back, failing with an
+// *storage.InsufficientStockError, and returns a func making the change
+// and setting the FromStock of taken. Nothing changes until it is called.
+func (s *Store) moveStock(released, taken []models.OrderItem) (func(), error) {
+	need := models.StockTaken(released, taken)
+	ids := make([]int, 0, len(need))
+	for id := range need {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	short := &storage.InsufficientStockError{}
+	for _, id := range ids {
+		if m, ok := s.menuItems[id]; ok && m.StockQuantity != nil && need[id] > *m.StockQuantity {
+			short.Items = append(short.Items, storage.StockShortage{MenuItemID: id, Name: m.Name, Wanted: need[id], Remaining: *m.StockQuantity})
+		}
+	}
+	if len(short.Items) > 0 {
+		return nil, short
+	}
+	return func() {
+		for _, id := range ids {
+			if m, ok := s.menuItems[id]; ok && m.StockQuantity != nil && need[id] != 0 {
+				left := *m.StockQuantity - need[id]
+				s.writeStock(m, &left)
+			}
+		}
+		for i := range taken {
+			m := s.menuItems[taken[i].MenuItemID]
+			taken[i].FromStock = m.StockQuantity != nil
+		}
+	}, nil
+}
+
+// writeStock stores m with its stock set to quantity, raising its version
+// when its availability changes
+func (s *Store) writeStock(m models.MenuItem, quantity *int) {
+	if m.SetStock(quantity) {
+		m.Version++
+	}
+	m.UpdatedAt = time.Now()
+	s.menuItems[m.ID] = m
+}
+
+// DeleteMenuItem removes a menu item that no order references
+func (s *Store) DeleteMenuItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if _, ok := s.menuItems[id]; !ok {
+		return notFound("menu item", id)
+	}
+	for _, o := range s.orders {
+		for _, item := range o.OrderItems {
+			if item.MenuItemID == id {
+				return fmt.Errorf("menu item with ID %d is referenced by existing orders; set it unavailable instead of deleting it", id)
+			}
+		}
+	}
+	delete(s.menuItems, id)
+	kept := s.prices[:0]
+	for _, c := range s.prices {
+		if c.ItemID != id {
+			kept = append(kept, c)
+		}
+	}
+	s.prices = kept
+	return nil
+}
+
+// GetAllOrders returns one page of orders, newest first, with their items
+func (s *Store) GetAllOrders(ctx context.Context, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{}, page)
+}
+
+// GetOrdersByRestaurantID returns one page of a restaurant's orders, newest
+// first, with their items
+func (s *Store) GetOrdersByRestaurantID(ctx context.Context, restaurantID int, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{RestaurantID: restaurantID}, page)
+}
+
+// GetOrdersByStatus returns one page of the orders in any of the given
+// statuses, newest first, with their items
+func (s *Store) GetOrdersByStatus(ctx context.Context, statuses []string, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{Statuses: statuses}, page)
+}
+
+// GetOrders returns one page of the orders matching f, newest first, with
+// their items
+func (s *Store) GetOrders(ctx context.Context, f models.OrderFilter, page models.Page) ([]models.Order, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	if _, ok := s.restaurants[f.RestaurantID]; f.RestaurantID != 0 && !ok {
+		return nil, 0, notFound("restaurant", f.RestaurantID)
+	}
+	all := s.ordersWhere(func(o models.Order) bool {
+		return (f.RestaurantID == 0 || o.RestaurantID == f.RestaurantID) &&
+			(len(f.Statuses) == 0 || slices.Contains(f.Statuses, o.Status)) &&
+			(f.CreatedFrom.IsZero() || !o.CreatedAt.Before(f.CreatedFrom)) &&
+			(f.CreatedBefore.IsZero() || o.CreatedAt.Before(f.CreatedBefore))
+	})
+	return pageOf(all, page), len(all), nil
+}
+
+// GetOrdersByCustomerPhone returns one page of the orders placed with a
+// phone number, newest first, and a summary of all of them
+func (s *Store) GetOrdersByCustomerPhone(ctx context.Context, phone string, page models.Page) ([]models.Order, models.CustomerSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var summary models.CustomerSummary
+	if s.Err != nil {
+		return nil, summary, s.Err
+	}
+	if phone = models.NormalizePhone(phone); phone == "" {
+		return nil, summary, fmt.Errorf("customer phone must contain digits")
+	}
+	all := s.ordersWhere(func(o models.Order) bool { return models.NormalizePhone(o.CustomerPhone) == phone })
+	for _, o := range all {
+		summary.Orders++
+		if o.Status != models.OrderStatusCancelled {
+			summary.Spend += o.FinalAmount
+		}
+	}
+	return pageOf(all, page), summary, nil
+}
+
+// GetSalesReport totals a restaurant's orders per day, in the restaurant's
+// timezone, between q.From and q.To; cancelled orders are counted by reason
+// instead
+func (s *Store) GetSalesReport(ctx context.Context, q models.SalesQuery) (*models.SalesReport, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	r, ok := s.restaurants[q.RestaurantID]
+	if !ok {
+		return nil, notFound("restaurant", q.RestaurantID)
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SalesReport{RestaurantID: r.ID, Timezone: r.Timezone, From: q.From, To: q.To,
+		Days: []models.SalesDay{}, Cancelled: []models.SalesCancellations{}}
+	byDay := map[string]*models.SalesDay{}
+	for _, o := range s.orders {
+		day := o.CreatedAt.In(loc).Format(models.DateLayout)
+		if o.RestaurantID != r.ID || day < q.From || day > q.To {
+			continue
+		}
+		if o.Status == models.OrderStatusCancelled {
+			report.AddCancelled(o)
+			continue
+		}
+		if byDay[day] == nil {
+			byDay[day] = &models.SalesDay{Date: day}
+		}
+		byDay[day].Add(o)
+	}
+	for _, d := range byDay {
+		report.Days = append(report.Days, *d)
+	}
+	slices.SortFunc(report.Days, func(a, b models.SalesDay) int { return strings.Compare(a.Date, b.Date) })
+	return report, nil
+}
+
+// GetTopSellingItems ranks a restaurant's menu items by units sold, then by
+// revenue, leaving out cancelled orders
+func (s *Store) GetTopSellingItems(ctx context.Context, q models.TopItemsQuery) ([]models.ItemSales, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if _, ok := s.restaurants[q.RestaurantID]; !ok {
+		return nil, notFound("restaurant", q.RestaurantID)
+	}
+
+	byItem := map[int]*models.ItemSales{}
+	for _, o := range s.orders {
+		if o.RestaurantID != q.RestaurantID || o.Status == models.OrderStatusCancelled || o.CreatedAt.Before(q.Since) {
+			continue
+		}
+		for _, oi := range o.OrderItems {
+			m, ok := s.menuItems[oi.MenuItemID]
+			if !ok || (q.Category != "" && !strings.EqualFold(m.Category, q.Category)) {
+				continue
+			}
+			if byItem[m.ID] == nil {
+				byItem[m.ID] = &models.ItemSales{MenuItemID: m.ID, Name: m.Name, Category: m.Category}
+			}
+			byItem[m.ID].Units += oi.Quantity
+			byItem[m.ID].Revenue += oi.Subtotal
+		}
+	}
+	items := []models.ItemSales{}
+	for _, item := range byItem {
+		items = append(items, *item)
+	}
+	slices.SortFunc(items, func(a, b models.ItemSales) int {
+		if a.Units != b.Units {
+			return b.Units - a.Units
+		}
+		if a.Revenue != b.Revenue {
+			return cmp.Compare(b.Revenue, a.Revenue)
+		}
+		return a.MenuItemID - b.MenuItemID
+	})
+	return items[:min(len(items), q.Limit)], nil
+}
+
+// GetOrderDigest gathers the numbers for a summary of one day of a
+// restaurant's orders in the restaurant's timezone
+func (s *Store) GetOrderDigest(ctx context.Context, q models.DigestQuery) (*models.OrderDigest, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	r, ok := s.restaurants[q.RestaurantID]
+	if !ok {
+		return nil, notFound("restaurant", q.RestaurantID)
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	day, previous := q.Day(loc, time.Now())
+	d := models.NewOrderDigest(r, day)
+
+	var orders []models.Order
+	hours := map[int]int{}
+	soldOut := map[string]bool{}
+	for _, o := range s.orders {
+		if o.RestaurantID != r.ID {
+			continue
+		}
+		cancelled := o.Status == models.OrderStatusCancelled
+		switch o.CreatedAt.In(loc).Format(models.DateLayout) {
+		case previous:
+			if !cancelled {
+				d.PreviousRevenue += o.FinalAmount
+			}
+			continue
+		case day:
+		default:
+			continue
+		}
+		d.Orders++
+		d.ByStatus[o.Status]++
+		hours[o.CreatedAt.In(loc).Hour()]++
+		if o.PaymentStatus == models.PaymentStatusRefunded {
+			d.Refunds++
+			d.RefundedAmount += o.FinalAmount
+		}
+		if cancelled {
+			continue
+		}
+		d.Revenue += o.FinalAmount
+		orders = append(orders, o)
+		for _, oi := range o.OrderItems {
+			if m, ok := s.menuItems[oi.MenuItemID]; ok && !m.Available {
+				soldOut[m.Name] = true
+			}
+		}
+	}
+	for hour, n := range hours {
+		if n > d.BusiestHourOrders || n == d.BusiestHourOrders && hour < d.BusiestHour {
+			d.BusiestHour, d.BusiestHourOrders = hour, n
+		}
+	}
+	if len(orders) > 0 {
+		average := models.Rupees(d.Revenue.Rupees() / float64(len(orders)))
+		slices.SortFunc(orders, func(a, b models.Order) int {
+			if a.FinalAmount != b.FinalAmount {
+				return cmp.Compare(b.FinalAmount, a.FinalAmount)
+			}
+			return a.ID - b.ID
+		})
+		for _, o := range orders {
+			if len(d.LargeOrders) == models.MaxLargeOrders || !models.IsLarge(o.FinalAmount, average) {
+				break
+			}
+			d.LargeOrders = append(d.LargeOrders, models.DigestOrder{ID: o.ID, CustomerName: o.CustomerName, FinalAmount: o.FinalAmount})
+		}
+	}
+	for name := range soldOut {
+		d.SoldOut = append(d.SoldOut, name)
+	}
+	slices.Sort(d.SoldOut)
+	return d, nil
+}
+
+// GetRestaurantStats counts a restaurant's orders and available menu items
+// and totals its revenue
+func (s *Store) GetRestaurantStats(ctx context.Context, restaurantID int) (*models.RestaurantStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if _, ok := s.restaurants[restaurantID]; !ok {
+		return nil, notFound("restaurant", restaurantID)
+	}
+
+	stats := &models.RestaurantStats{RestaurantID: restaurantID}
+	for _, o := range s.orders {
+		if o.RestaurantID != restaurantID {
+			continue
+		}
+		stats.Orders++
+		if o.Status == models.OrderStatusPending {
+			stats.PendingOrders++
+		}
+		if o.Status == models.OrderStatusCancelled {
+			stats.CancelledOrders++
+		} else {
+			stats.Revenue += o.FinalAmount
+		}
+		if stats.LastOrderAt == nil || o.CreatedAt.After(*stats.LastOrderAt) {
+			createdAt := o.CreatedAt
+			stats.LastOrderAt = &createdAt
+		}
+	}
+	stats.SetAverage()
+	for _, m := range s.menuItems {
+		if m.RestaurantID == restaurantID && m.Available {
+			stats.AvailableMenuItems++
+		}
+	}
+	return stats, nil
+}
+
+// ordersWhere returns the orders matching keep, newest first, with their items
+func (s *Store) ordersWhere(keep func(models.Order) bool) []models.Order {
+	all := []models.Order{}
+	for _, o := range s.orders {
+		if keep(o) {
+			all = append(all, s.withItems(o))
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.After(all[j].CreatedAt)
+		}
+		return all[i].ID > all[j].ID
+	})
+	return all
+}
+
+// GetOrderByID returns a single order with its items
+func (s *Store) GetOrderByID(ctx context.Context, id int) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, notFound("order", id)
+	}
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// GetOrderItemsByOrderID returns the items of an order with their menu items
+func (s *Store) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]models.OrderItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.withItems(s.orders[orderID]).OrderItems, nil
+}
+
+// withItems returns a copy of o whose items carry their current menu item
+func (s *Store) withItems(o models.Order) models.Order {
+	items := make([]models.OrderItem, len(o.OrderItems))
+	for i, item := range o.OrderItems {
+		if m, ok := s.menuItems[item.MenuItemID]; ok {
+			item.MenuItem = &m
+		}
+		items[i] = item
+	}
+	o.OrderItems = items
+	return o
+}
+
+// CreateOrder stores an order and its items, filling in their IDs and
+// taking them from stock
+func (s *Store) CreateOrder(ctx context.Context, o *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if _, ok := s.restaurants[o.RestaurantID]; !ok {
+		return notFound("restaurant", o.RestaurantID)
+	}
+	key := idempotencyKey{o.RestaurantID, o.IdempotencyKey}
+	if existing, ok := s.idempotentOrder(key); ok {
+		*o = s.withItems(existing)
+		return nil
+	}
+	if !s.restaurants[o.RestaurantID].Active {
+		return fmt.Errorf("restaurant with ID %d %w", o.RestaurantID, storage.ErrRestaurantInactive)
+	}
+	takeStock, err := s.moveStock(nil, o.OrderItems)
+	if err != nil {
+		return err
+	}
+	if err := s.priceItems(o.RestaurantID, o.OrderItems); err != nil {
+		return err
+	}
+	takeStock()
+	o.ComputeTotals(s.restaurants[o.RestaurantID].OrderCharges())
+	o.AutoConfirm(s.restaurants[o.RestaurantID])
+
+	if o.OrderType == "" {
+		o.OrderType = models.OrderTypeDineIn
+	}
+	o.ID = s.id("orders")
+	o.CreatedAt = time.Now()
+	o.UpdatedAt = o.CreatedAt
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.ID = s.id("order_items")
+		item.OrderID = o.ID
+	}
+	stored := *o
+	stored.OrderItems = append([]models.OrderItem(nil), o.OrderItems...)
+	s.orders[o.ID] = stored
+	if key.key != "" {
+		s.idempotent[key] = o.ID
+	}
+	s.recordStatus(ctx, o.ID, "", o.Status)
+	return nil
+}
+
+// priceItems sets each item's price from the menu less the best pricing
+// rule running now, checking every item is on the restaurant's menu
+func (s *Store) priceItems(restaurantID int, items []models.OrderItem) error {
+	local := models.LocalTime(s.restaurants[restaurantID].Timezone, time.Now())
+	missing := &storage.MissingItemsError{}
+	foreign := &storage.ForeignItemsError{RestaurantID: restaurantID}
+	for i := range items {
+		item := &items[i]
+		m, ok := s.menuItems[item.MenuItemID]
+		if !ok {
+			missing.MenuItemIDs = append(missing.MenuItemIDs, item.MenuItemID)
+			continue
+		}
+		if m.RestaurantID != restaurantID {
+			foreign.Items = append(foreign.Items, storage.ForeignItem{MenuItemID: item.MenuItemID, RestaurantID: m.RestaurantID})
+		}
+		item.Price, item.PricingRuleID = m.Price, nil
+		if r := models.BestPricingRule(s.rules, m, local); r != nil {
+			id := r.ID
+			item.Price, item.PricingRuleID = r.Apply(m.Price), &id
+		}
+	}
+	if len(missing.MenuItemIDs) > 0 {
+		return missing
+	}
+	if len(foreign.Items) > 0 {
+		return foreign
+	}
+	return nil
+}
+
+// UpdateOrderItems replaces the items of a pending or confirmed order,
+// repricing them from the menu, moving the stock they take and recomputing
+// the totals
+func (s *Store) UpdateOrderItems(ctx context.Context, orderID int, items []models.OrderItem) (*models.Order, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("order %d needs at least one item; cancel it instead", orderID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.orders[orderID]
+	if !ok {
+		return nil, notFound("order", orderID)
+	}
+	if !o.ItemsEditable() {
+		return nil, fmt.Errorf("order %d is %s; %w", orderID, o.Status, storage.ErrOrderNotEditable)
+	}
+
+	old := o.OrderItems
+	o.OrderItems = append([]models.OrderItem(nil), items...)
+	takeStock, err := s.moveStock(old, o.OrderItems)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.priceItems(o.RestaurantID, o.OrderItems); err != nil {
+		return nil, err
+	}
+	takeStock()
+	o.ComputeTotals(s.restaurants[o.RestaurantID].OrderCharges())
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.ID = s.id("order_items")
+		item.OrderID = o.ID
+		item.MenuItem = nil
+	}
+	o.UpdatedAt = time.Now()
+	s.orders[orderID] = o
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// SetOrderTip sets the tip on an order that isn't cancelled or paid and
+// recomputes its totals
+func (s *Store) SetOrderTip(ctx context.Context, orderID int, tip models.Money) (*models.Order, error) {
+	if tip < 0 {
+		return nil, fmt.Errorf("tip must be an amount of at least 0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.orders[orderID]
+	if !ok {
+		return nil, notFound("order", orderID)
+	}
+	if !o.Tippable() {
+		return nil, fmt.Errorf("order %d is %s with payment %s; %w", orderID, o.Status, o.PaymentStatus, storage.ErrOrderNotTippable)
+	}
+	o.Tip = tip
+	o.ComputeTotals(s.restaurants[o.RestaurantID].OrderCharges())
+	o.UpdatedAt = time.Now()
+	s.orders[orderID] = o
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// UpdateOrder saves the status and payment fields of an order. Moving an
+// order to delivered stamps DeliveredAt.
+func (s *Store) UpdateOrder(ctx context.Context, o *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	stored, ok := s.orders[o.ID]
+	if !ok {
+		return notFound("order", o.ID)
+	}
+	if err := stored.CheckTransition(o.Status); err != nil {
+		return err
+	}
+	s.recordStatus(ctx, o.ID, stored.Status, o.Status)
+	stored.Status, stored.PaymentStatus = o.Status, o.PaymentStatus
+	stored.PaymentMethod, stored.BillingAddress = o.PaymentMethod, o.BillingAddress
+	stored.UpdatedAt = time.Now()
+	if o.Status == models.OrderStatusDelivered && stored.DeliveredAt == nil {
+		now := stored.UpdatedAt
+		stored.DeliveredAt = &now
+	}
+	s.orders[o.ID] = stored
+	o.UpdatedAt, o.DeliveredAt = stored.UpdatedAt, stored.DeliveredAt
+	return nil
+}
+
+// AssignDelivery hands a ready delivery order to a rider
+func (s *Store) AssignDelivery(ctx context.Context, orderID int, riderName, riderPhone string) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.orders[orderID]
+	if !ok {
+		return nil, notFound("order", orderID)
+	}
+	if o.OrderType != models.OrderTypeDelivery {
+		return nil, fmt.Errorf("order %d is a %s order; %w", orderID, o.OrderType, storage.ErrNotDeliverable)
+	}
+	if !models.CanTransition(o.Status, models.OrderStatusOutForDelivery) {
+		return nil, fmt.Errorf("order %d is %s; %w", orderID, o.Status, storage.ErrNotDeliverable)
+	}
+	now := time.Now()
+	s.recordStatus(ctx, orderID, o.Status, models.OrderStatusOutForDelivery)
+	o.Status = models.OrderStatusOutForDelivery
+	o.DeliveryPersonName, o.DeliveryPersonPhone = riderName, riderPhone
+	o.DispatchedAt, o.UpdatedAt = &now, now
+	s.orders[orderID] = o
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// CancelOrder cancels an order with a reason, refunding a completed payment
+// and putting back the stock its items took
+func (s *Store) CancelOrder(ctx context.Context, orderID int, reason, note, cancelledBy string) (*models.Order, error) {
+	if err := models.ValidateCancelReason(reason, note); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.orders[orderID]
+	if !ok {
+		return nil, notFound("order", orderID)
+	}
+	if o.Status == models.OrderStatusCancelled || !models.CanTransition(o.Status, models.OrderStatusCancelled) {
+		return nil, &models.TransitionError{OrderID: orderID, From: o.Status, To: models.OrderStatusCancelled}
+	}
+	putBack, err := s.moveStock(o.OrderItems, nil)
+	if err != nil {
+		return nil, err
+	}
+	putBack()
+	now := time.Now()
+	s.recordStatus(ctx, orderID, o.Status, models.OrderStatusCancelled)
+	o.Status = models.OrderStatusCancelled
+	o.CancellationReason, o.CancellationNote, o.CancelledBy = reason, strings.TrimSpace(note), cancelledBy
+	o.CancelledAt, o.UpdatedAt = &now, now
+	if o.PaymentStatus == models.PaymentStatusCompleted {
+		o.PaymentStatus = models.PaymentStatusRefunded
+	}
+	s.orders[orderID] = o
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// storeOrderItems gives the items of o new IDs and stores o
+func (s *Store) storeOrderItems(o models.Order) {
+	o.OrderItems = append([]models.OrderItem(nil), o.OrderItems...)
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.ID = s.id("order_items")
+		item.OrderID = o.ID
+		item.MenuItem = nil
+	}
+	s.orders[o.ID] = o
+}
+
+// SplitOrder moves the given quantities of menu items off a pending or
+// confirmed, unpaid order onto a new order and recomputes the totals of
+// both, returning the order split and the new one
+func (s *Store) SplitOrder(ctx context.Context, orderID int, moves []models.OrderItem) (*models.Order, *models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, nil, s.Err
+	}
+	source, ok := s.orders[orderID]
+	if !ok {
+		return nil, nil, notFound("order", orderID)
+	}
+	split, err := source.Split(moves)
+	if err != nil {
+		return nil, nil, err
+	}
+	charges := s.restaurants[source.RestaurantID].OrderCharges()
+	source.ComputeTotals(charges)
+	split.ComputeTotals(charges)
+
+	now := time.Now()
+	source.UpdatedAt = now
+	split.ID = s.id("orders")
+	split.CreatedAt, split.UpdatedAt = now, now
+	s.storeOrderItems(source)
+	s.storeOrderItems(split)
+	s.recordStep(ctx, orderID, source.Status, source.Status, fmt.Sprintf("items moved to split order %d", split.ID))
+	s.recordStep(ctx, split.ID, "", split.Status, fmt.Sprintf("split from order %d", orderID))
+
+	source, split = s.withItems(s.orders[orderID]), s.withItems(s.orders[split.ID])
+	return &source, &split, nil
+}
+
+// MergeOrders combines pending or confirmed, unpaid dine-in orders of one
+// restaurant into a new order, cancelling the orders merged with reason
+// models.CancelReasonMerged
+func (s *Store) MergeOrders(ctx context.Context, orderIDs []int) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	orders := make([]models.Order, len(orderIDs))
+	for i, id := range orderIDs {
+		o, ok := s.orders[id]
+		if !ok {
+			return nil, notFound("order", id)
+		}
+		orders[i] = o
+	}
+	merged, err := models.MergeOrders(orders)
+	if err != nil {
+		return nil, err
+	}
+	merged.ComputeTotals(s.restaurants[merged.RestaurantID].OrderCharges())
+
+	now := time.Now()
+	merged.ID = s.id("orders")
+	merged.UpdatedAt = now
+	s.storeOrderItems(merged)
+	note := fmt.Sprintf("merged into order %d", merged.ID)
+	for _, o := range orders {
+		s.recordStep(ctx, o.ID, o.Status, models.OrderStatusCancelled, note)
+		o.Status = models.OrderStatusCancelled
+		o.CancellationReason, o.CancellationNote, o.CancelledBy = models.CancelReasonMerged, note, storage.Actor(ctx)
+		o.CancelledAt, o.UpdatedAt = &now, now
+		s.orders[o.ID] = o
+	}
+	s.recordStep(ctx, merged.ID, "", merged.Status, "merged from "+merged.MergedFromText())
+
+	merged = s.withItems(s.orders[merged.ID])
+	return &merged, nil
+}
+
+// DeleteOrder removes an order and its items
+func (s *Store) DeleteOrder(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	delete(s.orders, id)
+	delete(s.feedback, id)
+	delete(s.history, id)
+	return nil
+}
+
+// GetOrderHistory returns the status changes of an order, oldest first
+func (s *Store) GetOrderHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if _, ok := s.orders[orderID]; !ok {
+		return nil, notFound("order", orderID)
+	}
+	return append([]models.OrderStatusChange(nil), s.history[orderID]...), nil
+}
+
+// ExportOrders calls row for each line item of the orders a restaurant took
+// from from up to to, oldest order first. The rows are collected under the
+// lock and handed to row after it is released.
+func (s *Store) ExportOrders(ctx context.Context, restaurantID int, from, to time.Time, row func(models.OrderExportRow) error) error {
+	s.mu.Lock()
+	if s.Err != nil {
+		s.mu.Unlock()
+		return s.Err
+	}
+	if _, ok := s.restaurants[restaurantID]; !ok {
+		s.mu.Unlock()
+		return notFound("restaurant", restaurantID)
+	}
+	orders := s.ordersWhere(func(o models.Order) bool {
+		return o.RestaurantID == restaurantID && !o.CreatedAt.Before(from) && o.CreatedAt.Before(to)
+	})
+	s.mu.Unlock()
+
+	slices.Reverse(orders)
+	for _, o := range orders {
+		items := o.OrderItems
+		o.OrderItems = nil
+		if len(items) == 0 {
+			items = []models.OrderItem{{}}
+		}
+		for _, item := range items {
+			r := models.OrderExportRow{Order: o, Item: item}
+			if item.MenuItem != nil {
+				r.ItemName = item.MenuItem.Name
+			}
+			r.Item.MenuItem = nil
+			if err := row(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ArchiveOrders moves the delivered and cancelled orders created before
+// the cutoff to the archive and returns how many it moved. Their feedback
+// and status history are dropped, as they can't be read from the archive.
+func (s *Store) ArchiveOrders(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	now := time.Now()
+	moved := 0
+	for id, o := range s.orders {
+		if !slices.Contains(storage.ArchivedStatuses, o.Status) || !o.CreatedAt.Before(before) {
+			continue
+		}
+		o.ArchivedAt = &now
+		s.archived[id] = o
+		delete(s.orders, id)
+		delete(s.feedback, id)
+		delete(s.history, id)
+		for _, other := range s.orders {
+			if other.SplitFrom != nil && *other.SplitFrom == id {
+				other.SplitFrom = nil
+				s.orders[other.ID] = other
+			}
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// idempotentOrder returns the order created with key, live or archived
+func (s *Store) idempotentOrder(key idempotencyKey) (models.Order, bool) {
+	id, ok := s.idempotent[key]
+	if !ok || key.key == "" {
+		return models.Order{}, false
+	}
+	if o, ok := s.orders[id]; ok {
+		return o, true
+	}
+	o, ok := s.archived[id]
+	return o, ok
+}
+
+// GetArchivedOrder returns an order moved to the archive by ArchiveOrders,
+// with its items
+func (s *Store) GetArchivedOrder(ctx context.Context, id int) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	o, ok := s.archived[id]
+	if !ok {
+		return nil, notFound("archived order", id)
+	}
+	o = s.withItems(o)
+	return &o, nil
+}
+
+// RecordOrderFeedback stores the feedback for a delivered order, refusing
+// undelivered orders and a second recording
+func (s *Store) RecordOrderFeedback(ctx context.Context, f *models.OrderFeedback) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	o, ok := s.orders[f.OrderID]
+	if !ok {
+		return notFound("order", f.OrderID)
+	}
+	if o.Status != models.OrderStatusDelivered {
+		return fmt.Errorf("order %d is %s: %w", f.OrderID, o.Status, storage.ErrOrderNotDelivered)
+	}
+	if existing, ok := s.feedback[f.OrderID]; ok {
+		return fmt.Errorf("order %d: %w (by %s on %s)", f.OrderID, storage.ErrFeedbackRecorded, existing.RecordedBy, existing.RecordedAt.Format(models.DateLayout))
+	}
+	if f.RecordedBy == "" {
+		f.RecordedBy = "staff"
+	}
+	f.RecordedAt = time.Now()
+	s.feedback[f.OrderID] = *f
+	return nil
+}
+
+// GetOrderFeedback returns the feedback recorded for an order, or nil
+func (s *Store) GetOrderFeedback(ctx context.Context, orderID int) (*models.OrderFeedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	f, ok := s.feedback[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// ProposeChange stores a pending change request for an existing menu item
+func (s *Store) ProposeChange(ctx context.Context, c *models.ChangeRequest) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if _, ok := s.menuItems[c.EntityID]; !ok {
+		return notFound("menu item", c.EntityID)
+	}
+	if c.ProposedBy == "" {
+		c.ProposedBy = "staff"
+	}
+	c.ID, c.Status, c.CreatedAt = s.id("change_requests"), models.ChangeStatusPending, time.Now()
+	s.changes = append(s.changes, *c)
+	return nil
+}
+
+// ListChangeRequests returns one page of the change requests with status,
+// or all of them, newest first
+func (s *Store) ListChangeRequests(ctx context.Context, status string, page models.Page) ([]models.ChangeRequest, int, error) {
+	if err := models.ValidateChangeStatus(status); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	matched := []models.ChangeRequest{}
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		if status == "" || s.changes[i].Status == status {
+			matched = append(matched, s.changes[i])
+		}
+	}
+	return pageOf(matched, page), len(matched), nil
+}
+
+// ReviewChangeRequest approves or rejects a pending change request,
+// applying the changes to the menu item when approved
+func (s *Store) ReviewChangeRequest(ctx context.Context, id int, approve bool, reviewer string) (*models.ChangeRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	i := id - 1
+	if i < 0 || i >= len(s.changes) {
+		return nil, notFound("change request", id)
+	}
+	c := s.changes[i]
+	if c.Status != models.ChangeStatusPending {
+		return nil, fmt.Errorf("change request %d is %s by %s: %w", id, c.Status, c.ReviewedBy, storage.ErrChangeReviewed)
+	}
+	c.Status = models.ChangeStatusRejected
+	if approve {
+		c.Status = models.ChangeStatusApproved
+		m, ok := s.menuItems[c.EntityID]
+		if !ok {
+			return nil, notFound("menu item", c.EntityID)
+		}
+		c.Changes.ApplyTo(&m)
+		if err := s.updateMenuItem(&m, c.ProposedBy+" (approved by "+reviewer+")"); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	c.ReviewedBy, c.ReviewedAt = reviewer, &now
+	s.changes[i] = c
+	return &c, nil
+}
+
+// RecordShadowedAction stores a pending shadowed action
+func (s *Store) RecordShadowedAction(ctx context.Context, a *models.ShadowedAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	a.ID, a.Status, a.CreatedAt = s.id("shadowed_actions"), models.ShadowStatusPending, time.Now()
+	s.shadowed = append(s.shadowed, *a)
+	return nil
+}
+
+// ListShadowedActions returns one page of the shadowed actions with status,
+// or all of them, newest first
+func (s *Store) ListShadowedActions(ctx context.Context, status string, page models.Page) ([]models.ShadowedAction, int, error) {
+	if err := models.ValidateShadowStatus(status); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	matched := []models.ShadowedAction{}
+	for i := len(s.shadowed) - 1; i >= 0; i-- {
+		if status == "" || s.shadowed[i].Status == status {
+			matched = append(matched, s.shadowed[i])
+		}
+	}
+	return pageOf(matched, page), len(matched), nil
+}
+
+// ClaimShadowedAction marks a pending or failed shadowed action as being
+// replayed
+func (s *Store) ClaimShadowedAction(ctx context.Context, id int, replayer string) (*models.ShadowedAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	i := id - 1
+	if i < 0 || i >= len(s.shadowed) {
+		return nil, notFound("shadowed action", id)
+	}
+	a := s.shadowed[i]
+	if a.Status != models.ShadowStatusPending && a.Status != models.ShadowStatusFailed {
+		return nil, fmt.Errorf("shadowed action %d is %s: %w", id, a.Status, storage.ErrShadowReplayed)
+	}
+	now := time.Now()
+	a.Status, a.ReplayedBy, a.ReplayedAt = models.ShadowStatusReplaying, replayer, &now
+	s.shadowed[i] = a
+	return &a, nil
+}
+
+// FinishShadowedAction records the result of replaying a claimed action
+func (s *Store) FinishShadowedAction(ctx context.Context, id int, failed bool, result string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	i := id - 1
+	if i < 0 || i >= len(s.shadowed) {
+		return notFound("shadowed action", id)
+	}
+	s.shadowed[i].Status, s.shadowed[i].ReplayResult = models.ShadowStatusReplayed, result
+	if failed {
+		s.shadowed[i].Status = models.ShadowStatusFailed
+	}
+	return nil
+}
+
+// RecordAudit stores an audit log entry, filling in ID and CreatedAt
+func (s *Store) RecordAudit(ctx context.Context, e *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	e.ID = s.id("audit_log")
+	e.CreatedAt = time.Now()
+	s.audit = append(s.audit, *e)
+	return nil
+}
+
+// GetAuditLog returns one page of the audit log entries selected by q,
+// newest first, and the total count
+func (s *Store) GetAuditLog(ctx context.Context, q models.AuditQuery, page models.Page) ([]models.AuditEntry, int, error) {
+	if err := q.Validate(); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	matched := []models.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if q.Matches(s.audit[i]) {
+			matched = append(matched, s.audit[i])
+		}
+	}
+	return pageOf(matched, page), len(matched), nil
+}
+
+// pageOf returns the slice of all selected by page
+func pageOf[T any](all []T, page models.Page) []T {
+	if page.Offset >= len(all) {
+		return []T{}
+	}
+	end := page.Offset + page.Limit
+	if page.Limit == 0 || end > len(all) {
+		end = len(all)
+	}
+	return all[page.Offset:end]
+}
+
+// checkPricingRuleTarget checks the restaurant of r exists and its menu
+// item, if any, is on that restaurant's menu
+func (s *Store) checkPricingRuleTarget(r *models.PricingRule) error {
+	if _, ok := s.restaurants[r.RestaurantID]; !ok {
+		return notFound("restaurant", r.RestaurantID)
+	}
+	if r.MenuItemID == 0 {
+		return nil
+	}
+	m, ok := s.menuItems[r.MenuItemID]
+	if !ok {
+		return notFound("menu item", r.MenuItemID)
+	}
+	if m.RestaurantID != r.RestaurantID {
+		return fmt.Errorf("menu item %d is on the menu of restaurant %d, not %d", m.ID, m.RestaurantID, r.RestaurantID)
+	}
+	return nil
+}
+
+// CreatePricingRule stores a rule, filling in ID and CreatedAt
+func (s *Store) CreatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if err := s.checkPricingRuleTarget(r); err != nil {
+		return err
+	}
+	if r.DaysOfWeek == nil {
+		r.DaysOfWeek = []string{}
+	}
+	r.ID, r.CreatedAt = s.id("pricing_rules"), time.Now()
+	s.rules = append(s.rules, *r)
+	return nil
+}
+
+// ListPricingRules returns every rule of a restaurant, oldest first
+func (s *Store) ListPricingRules(ctx context.Context, restaurantID int) ([]models.PricingRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	rules := []models.PricingRule{}
+	for _, r := range s.rules {
+		if r.RestaurantID == restaurantID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// GetPricingRule returns one rule
+func (s *Store) GetPricingRule(ctx context.Context, id int) (*models.PricingRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	for _, r := range s.rules {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, notFound("pricing rule", id)
+}
+
+// UpdatePricingRule saves every field of r but its restaurant
+func (s *Store) UpdatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if err := s.checkPricingRuleTarget(r); err != nil {
+		return err
+	}
+	for i := range s.rules {
+		if s.rules[i].ID == r.ID {
+			r.RestaurantID, r.CreatedAt = s.rules[i].RestaurantID, s.rules[i].CreatedAt
+			s.rules[i] = *r
+			return nil
+		}
+	}
+	return notFound("pricing rule", r.ID)
+}
+
+// DeletePricingRule removes a rule
+func (s *Store) DeletePricingRule(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	for i := range s.rules {
+		if s.rules[i].ID == id {
+			s.rules = slices.Delete(s.rules, i, i+1)
+			return nil
+		}
+	}
+	return notFound("pricing rule", id)
+}
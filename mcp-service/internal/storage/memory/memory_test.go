@@ -0,0 +1,814 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// newRestaurant stores a restaurant with one dish on its menu
+func newRestaurant(t *testing.T, s *Store) (models.Restaurant, models.MenuItem) {
+	t.Helper()
+	ctx := context.Background()
+	r := models.Restaurant{Name: "Test Kitchen", Address: "Pune"}
+	if err := s.CreateRestaurant(ctx, &r); err != nil {
+		t.Fatalf("CreateRestaurant: %v", err)
+	}
+	m := models.MenuItem{RestaurantID: r.ID, Name: "Masala Dosa", Price: models.Rupees(90), Available: true}
+	if err := s.CreateMenuItem(ctx, &m); err != nil {
+		t.Fatalf("CreateMenuItem: %v", err)
+	}
+	return r, m
+}
+
+func TestCreateOrderPricesItemsFromTheMenu(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	other := models.Restaurant{Name: "Other Kitchen", Address: "Mumbai"}
+	if err := s.CreateRestaurant(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+	foreign := models.MenuItem{RestaurantID: other.ID, Name: "Vada Pav", Price: models.Rupees(30), Available: true}
+	if err := s.CreateMenuItem(ctx, &foreign); err != nil {
+		t.Fatal(err)
+	}
+	deleted := models.MenuItem{RestaurantID: r.ID, Name: "Old Special", Price: models.Rupees(200), Available: true}
+	if err := s.CreateMenuItem(ctx, &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteMenuItem(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2, Price: models.Rupees(1)}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.OrderItems[0].Price != m.Price || o.TotalAmount != 2*m.Price {
+		t.Errorf("order priced at %v each, %v total; want the menu's %v", o.OrderItems[0].Price, o.TotalAmount, m.Price)
+	}
+
+	var foreignErr *storage.ForeignItemsError
+	o = &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}, {MenuItemID: foreign.ID, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, o); !errors.As(err, &foreignErr) || foreignErr.Items[0].MenuItemID != foreign.ID {
+		t.Errorf("CreateOrder with another restaurant's item = %v, want a ForeignItemsError", err)
+	}
+	o = &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: deleted.ID, Quantity: 1}, {MenuItemID: m.ID, Quantity: 1}, {MenuItemID: 999, Quantity: 1}}}
+	var missing *storage.MissingItemsError
+	if err := s.CreateOrder(ctx, o); !errors.Is(err, storage.ErrNotFound) || !errors.As(err, &missing) || len(missing.MenuItemIDs) != 2 {
+		t.Errorf("CreateOrder with a deleted and an unknown item = %v, want both reported as not found", err)
+	}
+	if _, total, _ := s.GetAllOrders(ctx, models.Page{Limit: 10}); total != 1 {
+		t.Errorf("%d orders stored, want only the first", total)
+	}
+}
+
+func TestMenuIncludesUnavailableItemsOnlyWhenAsked(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	off := models.MenuItem{RestaurantID: r.ID, Name: "Mango Lassi", Price: models.Rupees(120), Available: false}
+	if err := s.CreateMenuItem(ctx, &off); err != nil {
+		t.Fatal(err)
+	}
+
+	if menu, err := s.GetMenuByRestaurantID(ctx, r.ID, false); err != nil || len(menu) != 1 || menu[0].ID != m.ID {
+		t.Errorf("menu = %+v, %v; want only the available item", menu, err)
+	}
+	if menu, err := s.GetMenuByRestaurantID(ctx, r.ID, true); err != nil || len(menu) != 2 {
+		t.Errorf("menu with unavailable items = %+v, %v; want both items", menu, err)
+	}
+}
+
+func TestCreateOrderAppliesRunningPricingRule(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	// A window around now in the restaurant's timezone, so the test doesn't
+	// depend on the time it runs at
+	now := models.LocalTime(r.Timezone, time.Now())
+	running := models.PricingRule{RestaurantID: r.ID, Name: "Happy hour", PercentOff: 20,
+		StartTime: now.Add(-time.Hour).Format(models.ClockLayout), EndTime: now.Add(time.Hour).Format(models.ClockLayout), Active: true}
+	if err := s.CreatePricingRule(ctx, &running); err != nil {
+		t.Fatal(err)
+	}
+	later := models.PricingRule{RestaurantID: r.ID, Name: "Tomorrow", PercentOff: 50, MenuItemID: m.ID,
+		StartTime: now.Add(2 * time.Hour).Format(models.ClockLayout), EndTime: now.Add(3 * time.Hour).Format(models.ClockLayout), Active: true}
+	if err := s.CreatePricingRule(ctx, &later); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	item := o.OrderItems[0]
+	if item.Price != models.Rupees(72) || item.PricingRuleID == nil || *item.PricingRuleID != running.ID {
+		t.Errorf("order item = price %v, rule %v; want 72 from rule %d", item.Price, item.PricingRuleID, running.ID)
+	}
+	if o.TotalAmount != models.Rupees(144) {
+		t.Errorf("total = %v, want 144", o.TotalAmount)
+	}
+
+	running.Active = false
+	if err := s.UpdatePricingRule(ctx, &running); err != nil {
+		t.Fatal(err)
+	}
+	o = &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if item := o.OrderItems[0]; item.Price != m.Price || item.PricingRuleID != nil {
+		t.Errorf("order item after pausing the rule = price %v, rule %v; want the menu price", item.Price, item.PricingRuleID)
+	}
+}
+
+func TestSalesReportSplitsDaysAtLocalMidnight(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	place := func(day, hour, min, quantity int, status string) int {
+		t.Helper()
+		o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: quantity}}}
+		if err := s.CreateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		stored := s.orders[o.ID]
+		stored.CreatedAt = time.Date(2026, 3, day, hour, min, 0, 0, ist)
+		if status != "" {
+			stored.Status = status
+		}
+		s.orders[o.ID] = stored
+		return o.ID
+	}
+	place(8, 23, 59, 1, "") // before the range
+	place(9, 0, 0, 1, "")
+	place(9, 23, 50, 2, "")
+	place(10, 0, 10, 1, "") // the same UTC day as 23:50 on the 9th
+	place(10, 23, 59, 1, "")
+	place(10, 12, 0, 5, models.OrderStatusCancelled)
+	place(11, 0, 0, 1, "") // after the range
+	cancelled, err := s.CancelOrder(ctx, place(9, 13, 0, 3, models.OrderStatusPending), models.CancelReasonOutOfStock, "", "staff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.GetSalesReport(ctx, models.SalesQuery{RestaurantID: r.ID, From: "2026-03-09", To: "2026-03-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Days) != 2 ||
+		report.Days[0].Date != "2026-03-09" || report.Days[0].Orders != 2 || report.Days[0].Gross != 3*m.Price ||
+		report.Days[1].Date != "2026-03-10" || report.Days[1].Orders != 2 || report.Days[1].Gross != 2*m.Price {
+		t.Errorf("days = %+v, want 2 orders on each of the 9th and 10th", report.Days)
+	}
+	if c := report.Cancelled; len(c) != 2 ||
+		c[0] != (models.SalesCancellations{Reason: models.CancelReasonOutOfStock, Orders: 1, Amount: cancelled.FinalAmount}) ||
+		c[1].Reason != "unknown" || c[1].Orders != 1 {
+		t.Errorf("cancelled = %+v, want one out_of_stock and one without a reason", c)
+	}
+}
+
+func TestShadowedActionReplaysOnce(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	a := models.ShadowedAction{Tool: "delete_restaurant", Arguments: map[string]interface{}{"id": 1.0}, Effect: "delete restaurant 1"}
+	if err := s.RecordShadowedAction(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ClaimShadowedAction(ctx, a.ID, "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ClaimShadowedAction(ctx, a.ID, "admin@example.com"); !errors.Is(err, storage.ErrShadowReplayed) {
+		t.Errorf("claiming a replaying action: %v, want ErrShadowReplayed", err)
+	}
+	if err := s.FinishShadowedAction(ctx, a.ID, true, "database unavailable"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ClaimShadowedAction(ctx, a.ID, "admin@example.com"); err != nil {
+		t.Errorf("claiming a failed action again: %v", err)
+	}
+	if err := s.FinishShadowedAction(ctx, a.ID, false, "Restaurant 1 deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ClaimShadowedAction(ctx, a.ID, "admin@example.com"); !errors.Is(err, storage.ErrShadowReplayed) {
+		t.Errorf("claiming a replayed action: %v, want ErrShadowReplayed", err)
+	}
+	if _, err := s.ClaimShadowedAction(ctx, a.ID+1, "admin@example.com"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("claiming a missing action: %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateMenuItemsNamesTheFirstInvalidItem(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, _ := newRestaurant(t, s)
+
+	items := []*models.MenuItem{
+		{RestaurantID: r.ID, Name: "Idli Sambar", Price: models.Rupees(70)},
+		{RestaurantID: r.ID, Name: "Medu Vada", Price: models.Rupees(60), SpiceLevel: "volcanic"},
+		{RestaurantID: r.ID, Name: "Kesari Bath"},
+	}
+	var itemErr *storage.BatchItemError
+	if err := s.CreateMenuItems(ctx, items); !errors.As(err, &itemErr) || itemErr.Index != 1 {
+		t.Fatalf("CreateMenuItems = %v, want item 1 invalid", err)
+	}
+	if menu, _ := s.GetMenuByRestaurantID(ctx, r.ID, true); len(menu) != 1 {
+		t.Errorf("menu has %d items after a rejected batch, want only the first dish", len(menu))
+	}
+
+	items[1].SpiceLevel, items[2].Price = models.SpiceMild, 50
+	if err := s.CreateMenuItems(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+	if menu, _ := s.GetMenuByRestaurantID(ctx, r.ID, true); len(menu) != 4 {
+		t.Errorf("menu has %d items, want 4", len(menu))
+	}
+}
+
+func TestOrderDigestFollowsTheRestaurantsDay(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	ist, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	place := func(day, hour, quantity int, status, payment string) {
+		t.Helper()
+		o := &models.Order{RestaurantID: r.ID, CustomerName: fmt.Sprintf("Guest %d", quantity), OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: quantity}}}
+		if err := s.CreateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		stored := s.orders[o.ID]
+		stored.CreatedAt = time.Date(2026, 3, day, hour, 15, 0, 0, ist)
+		stored.Status, stored.PaymentStatus = status, payment
+		s.orders[o.ID] = stored
+	}
+	place(8, 13, 2, models.OrderStatusDelivered, models.PaymentStatusCompleted)
+	place(9, 0, 1, models.OrderStatusDelivered, models.PaymentStatusCompleted) // 18:45 UTC on the 8th
+	place(9, 13, 1, models.OrderStatusDelivered, models.PaymentStatusCompleted)
+	place(9, 13, 1, models.OrderStatusPreparing, models.PaymentStatusPending)
+	place(9, 13, 10, models.OrderStatusDelivered, models.PaymentStatusCompleted)
+	place(9, 20, 3, models.OrderStatusCancelled, models.PaymentStatusRefunded)
+	m.Available = false
+	if err := s.UpdateMenuItem(ctx, &m, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := s.GetOrderDigest(ctx, models.DigestQuery{RestaurantID: r.ID, Date: "2026-03-09"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := s.orders[2].FinalAmount
+	if d.Orders != 5 || d.ByStatus[models.OrderStatusDelivered] != 3 || d.ByStatus[models.OrderStatusCancelled] != 1 {
+		t.Errorf("orders %d by status %v, want 5 with 3 delivered and 1 cancelled", d.Orders, d.ByStatus)
+	}
+	if d.PreviousRevenue != s.orders[1].FinalAmount || d.Revenue != s.orders[2].FinalAmount+s.orders[3].FinalAmount+s.orders[4].FinalAmount+s.orders[5].FinalAmount {
+		t.Errorf("revenue %v after %v, want the cancelled order left out", d.Revenue, d.PreviousRevenue)
+	}
+	if d.BusiestHour != 13 || d.BusiestHourOrders != 3 {
+		t.Errorf("busiest hour %d with %d orders, want 13 with 3", d.BusiestHour, d.BusiestHourOrders)
+	}
+	if len(d.LargeOrders) != 1 || d.LargeOrders[0].ID != 5 || d.LargeOrders[0].FinalAmount <= 2*one {
+		t.Errorf("large orders %+v, want order 5", d.LargeOrders)
+	}
+	if len(d.SoldOut) != 1 || d.SoldOut[0] != m.Name || d.Refunds != 1 {
+		t.Errorf("sold out %v and %d refunds, want %s and 1", d.SoldOut, d.Refunds, m.Name)
+	}
+}
+
+func TestCreateOrderAutoConfirmsOnlyWhenTheRestaurantOptsIn(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	place := func(status string) models.Order {
+		t.Helper()
+		o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: status, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+		if err := s.CreateOrder(ctx, &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	if o := place(models.OrderStatusPending); o.Status != models.OrderStatusPending {
+		t.Errorf("without auto_confirm_orders: status %s, want pending", o.Status)
+	}
+	on := true
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{AutoConfirmOrders: &on}); err != nil {
+		t.Fatal(err)
+	}
+	if o := place(models.OrderStatusPending); o.Status != models.OrderStatusConfirmed || s.orders[o.ID].Status != models.OrderStatusConfirmed {
+		t.Errorf("with auto_confirm_orders: status %s, stored %s; want confirmed", o.Status, s.orders[o.ID].Status)
+	}
+	if o := place(models.OrderStatusDelivered); o.Status != models.OrderStatusDelivered {
+		t.Errorf("order created delivered: status %s, want it left alone", o.Status)
+	}
+
+	stats, err := s.GetRestaurantStats(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Orders != 3 || stats.PendingOrders != 1 {
+		t.Errorf("stats %+v, want 3 orders with 1 pending confirmation", stats)
+	}
+}
+
+func TestHardDeleteRestaurantNeedsForceWhileInUse(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	for _, status := range []string{models.OrderStatusPending, models.OrderStatusDelivered} {
+		o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: status, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+		if err := s.CreateOrder(ctx, &o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var inUse *storage.RestaurantInUseError
+	_, err := s.DeleteRestaurant(ctx, r.ID, models.RestaurantDeleteOptions{Hard: true})
+	if !errors.As(err, &inUse) || inUse.MenuItems != 1 || inUse.Orders != 2 || inUse.OpenOrders != 1 {
+		t.Fatalf("delete without force: %v, want a RestaurantInUseError counting 1 menu item and 2 orders, 1 open", err)
+	}
+	if _, ok := s.restaurants[r.ID]; !ok {
+		t.Fatal("restaurant deleted without force")
+	}
+
+	deleted, err := s.DeleteRestaurant(ctx, r.ID, models.RestaurantDeleteOptions{Hard: true, Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.RestaurantDeletion{RestaurantID: r.ID, MenuItems: 1, Orders: 2, OpenOrders: 1}
+	if deleted != want {
+		t.Errorf("deleted %+v, want %+v", deleted, want)
+	}
+	if len(s.restaurants) != 0 || len(s.menuItems) != 0 || len(s.orders) != 0 {
+		t.Errorf("left %d restaurants, %d menu items and %d orders", len(s.restaurants), len(s.menuItems), len(s.orders))
+	}
+	if _, err := s.DeleteRestaurant(ctx, r.ID, models.RestaurantDeleteOptions{Hard: true, Force: true}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("deleting again: %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRestaurantDeactivatesByDefault(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	order := func() error {
+		o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+		return s.CreateOrder(ctx, &o)
+	}
+	if err := order(); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := s.DeleteRestaurant(ctx, r.ID, models.RestaurantDeleteOptions{})
+	if err != nil || !deleted.Deactivated {
+		t.Fatalf("DeleteRestaurant = %+v, %v; want it deactivated", deleted, err)
+	}
+	if list, total, err := s.GetAllRestaurants(ctx, models.Page{Limit: 10}, ""); err != nil || total != 0 || len(list) != 0 {
+		t.Errorf("GetAllRestaurants = %d of %d, %v; want the inactive restaurant left out", len(list), total, err)
+	}
+	if found, err := s.SearchRestaurants(ctx, r.Name, "", 10); err != nil || len(found) != 0 {
+		t.Errorf("SearchRestaurants = %+v, %v; want the inactive restaurant left out", found, err)
+	}
+	if got, err := s.GetRestaurantByID(ctx, r.ID); err != nil || got.Active {
+		t.Errorf("GetRestaurantByID = %+v, %v; want it marked inactive", got, err)
+	}
+	if len(s.orders) != 1 || len(s.menuItems) != 1 {
+		t.Errorf("kept %d orders and %d menu items, want the history kept", len(s.orders), len(s.menuItems))
+	}
+	if err := order(); !errors.Is(err, storage.ErrRestaurantInactive) {
+		t.Errorf("CreateOrder for an inactive restaurant = %v, want ErrRestaurantInactive", err)
+	}
+
+	if restored, err := s.RestoreRestaurant(ctx, r.ID); err != nil || !restored.Active {
+		t.Fatalf("RestoreRestaurant = %+v, %v", restored, err)
+	}
+	if err := order(); err != nil {
+		t.Errorf("CreateOrder after restoring = %v", err)
+	}
+	if _, err := s.RestoreRestaurant(ctx, 999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("RestoreRestaurant of a missing restaurant = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateOrderItemsRepricesOnlyEditableOrders(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.UpdateOrderItems(ctx, o.ID, []models.OrderItem{{MenuItemID: m.ID, Quantity: 3, Price: models.Rupees(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.OrderItems) != 1 || updated.OrderItems[0].Price != m.Price || updated.TotalAmount != 3*m.Price {
+		t.Errorf("updated order %+v, want 3 items at the menu's %v", updated, m.Price)
+	}
+	if _, err := s.UpdateOrderItems(ctx, o.ID, nil); err == nil {
+		t.Error("emptied an order")
+	}
+
+	for _, status := range []string{models.OrderStatusConfirmed, models.OrderStatusPreparing} {
+		o.Status = status
+		if err := s.UpdateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.UpdateOrderItems(ctx, o.ID, []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}); !errors.Is(err, storage.ErrOrderNotEditable) {
+		t.Errorf("editing a preparing order = %v, want ErrOrderNotEditable", err)
+	}
+}
+
+func TestOrderHistoryRecordsEachStatusChangeAndWhoMadeIt(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	o.Status = models.OrderStatusConfirmed
+	if err := s.UpdateOrder(storage.WithActor(ctx, "priya@example.com"), o); err != nil {
+		t.Fatal(err)
+	}
+	o.Status = models.OrderStatusDelivered
+	var transition *models.TransitionError
+	if err := s.UpdateOrder(ctx, o); !errors.As(err, &transition) {
+		t.Errorf("UpdateOrder confirmed -> delivered = %v, want a TransitionError", err)
+	}
+	if _, err := s.CancelOrder(ctx, o.ID, "out_of_stock", "", "staff"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := s.GetOrderHistory(ctx, o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, fmt.Sprintf("%s>%s@%s", c.FromStatus, c.ToStatus, c.ChangedBy))
+	}
+	want := []string{">pending@mcp", "pending>confirmed@priya@example.com", "confirmed>cancelled@mcp"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+	if _, err := s.GetOrderHistory(ctx, 999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetOrderHistory of a missing order = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateOrderChargesTheRestaurantsTaxRate(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	place := func() models.Order {
+		t.Helper()
+		o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}}
+		if err := s.CreateOrder(ctx, &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	if o := place(); o.TaxAmount != m.Price.Times(2).MulRate(models.DefaultTaxRate) {
+		t.Errorf("tax %v without a tax_rate, want the default rate", o.TaxAmount)
+	}
+	rate := 0.18
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{TaxRate: &rate}); err != nil {
+		t.Fatal(err)
+	}
+	if o := place(); o.TaxAmount != m.Price.Times(2).MulRate(rate) {
+		t.Errorf("tax %v with tax_rate %v, want %v", o.TaxAmount, rate, m.Price.Times(2).MulRate(rate))
+	}
+	bad := 18.0
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{TaxRate: &bad}); err == nil {
+		t.Error("accepted a tax_rate of 18, a percentage")
+	}
+}
+
+func TestSetOrderTipAddsToTheBillUntilItIsPaid(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	percent := 10.0
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{ServiceChargePercent: &percent}); err != nil {
+		t.Fatal(err)
+	}
+	o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending, PaymentStatus: models.PaymentStatusPending,
+		OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if want := m.Price.Times(2).Percent(percent); o.ServiceCharge != want {
+		t.Errorf("dine-in service charge %v, want %v", o.ServiceCharge, want)
+	}
+
+	tipped, err := s.SetOrderTip(ctx, o.ID, models.Rupees(25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tipped.Tip != models.Rupees(25) || tipped.FinalAmount != o.FinalAmount+models.Rupees(25) {
+		t.Errorf("tipped order: tip %v, final %v; want 25 on top of %v", tipped.Tip, tipped.FinalAmount, o.FinalAmount)
+	}
+
+	o.PaymentStatus = models.PaymentStatusCompleted
+	if err := s.UpdateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetOrderTip(ctx, o.ID, models.Rupees(50)); !errors.Is(err, storage.ErrOrderNotTippable) {
+		t.Errorf("tipping a paid order = %v, want ErrOrderNotTippable", err)
+	}
+}
+
+func TestStaleUpdatesConflict(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+
+	// Two sessions read version 1; the second write loses
+	first, second := m, m
+	first.Price = models.Rupees(100)
+	if err := s.UpdateMenuItem(ctx, &first, "a"); err != nil || first.Version != 2 {
+		t.Fatalf("first update: %v, version %d", err, first.Version)
+	}
+	second.Available = false
+	var conflict *storage.VersionConflictError
+	if err := s.UpdateMenuItem(ctx, &second, "b"); !errors.As(err, &conflict) || conflict.Expected != 1 {
+		t.Errorf("stale update = %v, want a conflict on version 1", err)
+	}
+	if got, _ := s.GetMenuItemByID(ctx, m.ID); got.Price != models.Rupees(100) || !got.Available {
+		t.Errorf("item after the conflict: %+v", got)
+	}
+
+	stale := r.Version
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{Name: "Renamed", ExpectedVersion: &stale}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{Name: "Again", ExpectedVersion: &stale}); !errors.As(err, &conflict) {
+		t.Errorf("stale patch = %v, want a conflict", err)
+	}
+	if patched, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{Address: "Nashik"}); err != nil || patched.Version != stale+2 {
+		t.Errorf("patch without expected_version: %v, version %v", err, patched)
+	}
+}
+
+func TestSplitAndMergeOrdersRecordTheirOrigins(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	place := func(quantity int) *models.Order {
+		t.Helper()
+		o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: quantity}}}
+		if err := s.CreateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	table := place(3)
+
+	source, split, err := s.SplitOrder(ctx, table.ID, []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.TotalAmount != 2*m.Price || split.TotalAmount != m.Price || split.SplitFrom == nil || *split.SplitFrom != table.ID {
+		t.Errorf("split %v + %v from %v, want %v + %v from %d", source.TotalAmount, split.TotalAmount, split.SplitFrom, 2*m.Price, m.Price, table.ID)
+	}
+	var combine *models.CombineError
+	if _, _, err := s.SplitOrder(ctx, table.ID, []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}); !errors.As(err, &combine) {
+		t.Errorf("splitting off every item = %v, want a CombineError", err)
+	}
+
+	other := place(1)
+	merged, err := s.MergeOrders(ctx, []int{other.ID, table.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.TotalAmount != 3*m.Price || merged.MergedFromText() != fmt.Sprintf("orders %d, %d", table.ID, other.ID) {
+		t.Errorf("merged %v from %s, want %v from both orders", merged.TotalAmount, merged.MergedFromText(), 3*m.Price)
+	}
+	cancelled, err := s.GetOrderByID(ctx, table.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != models.OrderStatusCancelled || cancelled.CancellationReason != models.CancelReasonMerged {
+		t.Errorf("merged order is %s (%s), want cancelled as merged", cancelled.Status, cancelled.CancellationReason)
+	}
+	if _, err := s.MergeOrders(ctx, []int{table.ID, split.ID}); !errors.As(err, &combine) {
+		t.Errorf("merging a cancelled order = %v, want a CombineError", err)
+	}
+
+	changes, err := s.GetOrderHistory(ctx, table.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notes []string
+	for _, c := range changes {
+		notes = append(notes, c.Note)
+	}
+	want := []string{"", fmt.Sprintf("items moved to split order %d", split.ID), fmt.Sprintf("merged into order %d", merged.ID)}
+	if fmt.Sprint(notes) != fmt.Sprint(want) {
+		t.Errorf("history notes = %q, want %q", notes, want)
+	}
+}
+
+func TestCreateOrderWithAnIdempotencyKeyCreatesItOnce(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	other := models.Restaurant{Name: "Other Kitchen", Address: "Mumbai"}
+	if err := s.CreateRestaurant(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+	dish := models.MenuItem{RestaurantID: other.ID, Name: "Vada Pav", Price: models.Rupees(30), Available: true}
+	if err := s.CreateMenuItem(ctx, &dish); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", IdempotencyKey: "retry-1", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}}
+			if err := s.CreateOrder(ctx, &o); err != nil {
+				t.Error(err)
+			}
+			ids[i] = o.ID
+		}()
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("concurrent creates with one key made orders %v, want one", ids)
+		}
+	}
+	if _, total, err := s.GetOrders(ctx, models.OrderFilter{RestaurantID: r.ID}, models.Page{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("%d orders, %v; want 1", total, err)
+	}
+
+	// Keys are per restaurant
+	o := models.Order{RestaurantID: other.ID, CustomerName: "Ravi Kumar", IdempotencyKey: "retry-1", OrderItems: []models.OrderItem{{MenuItemID: dish.ID, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, &o); err != nil || o.ID == ids[0] {
+		t.Errorf("same key at another restaurant: order %d, %v; want a new order", o.ID, err)
+	}
+}
+
+func TestRestaurantsCannotShareANameAndAddress(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, _ := newRestaurant(t, s)
+
+	var duplicate *storage.DuplicateRestaurantError
+	again := models.Restaurant{Name: "TEST KITCHEN", Address: "pune"}
+	err := s.CreateRestaurant(ctx, &again)
+	if !errors.As(err, &duplicate) || duplicate.ExistingID != r.ID {
+		t.Fatalf("second Test Kitchen, Pune = %v, want a duplicate of %d", err, r.ID)
+	}
+	if err.Error() != fmt.Sprintf("a restaurant with this name and address already exists, id=%d", r.ID) {
+		t.Errorf("message %q", err)
+	}
+
+	other := models.Restaurant{Name: "Test Kitchen", Address: "Nashik"}
+	if err := s.CreateRestaurant(ctx, &other); err != nil {
+		t.Fatalf("same name at another address: %v", err)
+	}
+	if _, err := s.PatchRestaurant(ctx, other.ID, models.RestaurantPatch{Address: "Pune"}); !errors.As(err, &duplicate) {
+		t.Errorf("moving it to Pune = %v, want a duplicate", err)
+	}
+	if _, err := s.PatchRestaurant(ctx, r.ID, models.RestaurantPatch{Name: "Test Kitchen", Address: "Pune"}); err != nil {
+		t.Errorf("patching a restaurant to its own name and address: %v", err)
+	}
+}
+
+func TestOrdersTakeStockAndCancellingPutsItBack(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	three := 3
+	if _, err := s.SetStock(ctx, m.ID, &three); err != nil {
+		t.Fatal(err)
+	}
+	order := func(quantity int) (*models.Order, error) {
+		o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusPending, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: quantity}}}
+		return o, s.CreateOrder(ctx, o)
+	}
+	stock := func() (int, bool) {
+		item, _ := s.GetMenuItemByID(ctx, m.ID)
+		return *item.StockQuantity, item.Available
+	}
+
+	first, err := order(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var short *storage.InsufficientStockError
+	if _, err := order(2); !errors.As(err, &short) || short.Items[0].Remaining != 1 || !strings.Contains(err.Error(), "only 1 of Masala Dosa") {
+		t.Errorf("ordering 2 of the last 1 = %v, want an InsufficientStockError", err)
+	}
+	if _, err := order(1); err != nil {
+		t.Fatal(err)
+	}
+	if left, available := stock(); left != 0 || available {
+		t.Errorf("after selling out: %d left, available %v; want 0, unavailable", left, available)
+	}
+
+	if _, err := s.CancelOrder(ctx, first.ID, models.CancelReasonCustomerRequest, "", "staff"); err != nil {
+		t.Fatal(err)
+	}
+	if left, available := stock(); left != 2 || !available {
+		t.Errorf("after cancelling an order of 2: %d left, available %v; want 2, available", left, available)
+	}
+
+	// Growing an order takes only the portions added
+	second, err := order(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateOrderItems(ctx, second.ID, []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := stock(); left != 0 {
+		t.Errorf("after growing an order from 1 to 2: %d left, want 0", left)
+	}
+	if _, err := s.SetStock(ctx, m.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := order(50); err != nil {
+		t.Errorf("ordering an untracked item: %v", err)
+	}
+}
+
+func TestCreateOrderRetriedAfterArchivingReturnsTheArchivedOrder(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	create := func() models.Order {
+		o := models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: models.OrderStatusDelivered, IdempotencyKey: "retry-1", OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 2}}}
+		if err := s.CreateOrder(ctx, &o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	first := create()
+	if n, err := s.ArchiveOrders(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("ArchiveOrders = %d, %v; want the order", n, err)
+	}
+
+	retry := create()
+	if retry.ID != first.ID || retry.ArchivedAt == nil || len(retry.OrderItems) != 1 {
+		t.Errorf("retry after archiving = %+v, want archived order %d", retry, first.ID)
+	}
+	if _, total, err := s.GetOrders(ctx, models.OrderFilter{RestaurantID: r.ID}, models.Page{Limit: 10}); err != nil || total != 0 {
+		t.Errorf("%d live orders, %v; want the retry to create none", total, err)
+	}
+}
+
+func TestArchiveOrdersMovesOnlyFinishedOrders(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	r, m := newRestaurant(t, s)
+	order := func(status string) *models.Order {
+		o := &models.Order{RestaurantID: r.ID, CustomerName: "Asha Rao", Status: status, OrderItems: []models.OrderItem{{MenuItemID: m.ID, Quantity: 1}}}
+		if err := s.CreateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	delivered, pending := order(models.OrderStatusDelivered), order(models.OrderStatusPending)
+
+	if n, err := s.ArchiveOrders(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("ArchiveOrders before the orders were created = %d, %v; want 0", n, err)
+	}
+	if n, err := s.ArchiveOrders(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("ArchiveOrders = %d, %v; want the delivered order", n, err)
+	}
+	if _, err := s.GetOrderByID(ctx, delivered.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetOrderByID(archived) = %v, want ErrNotFound", err)
+	}
+	archived, err := s.GetArchivedOrder(ctx, delivered.ID)
+	if err != nil || archived.ArchivedAt == nil || len(archived.OrderItems) != 1 || archived.OrderItems[0].MenuItem == nil {
+		t.Errorf("GetArchivedOrder = %+v, %v; want the delivered order with its item", archived, err)
+	}
+	if _, err := s.GetArchivedOrder(ctx, pending.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetArchivedOrder(live) = %v, want ErrNotFound", err)
+	}
+}
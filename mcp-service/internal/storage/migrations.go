@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles holds the schema steps, named NNNN_description.sql. Add a
+// new file for every schema change; never edit one that has shipped.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock key that keeps replicas starting
+// together from applying the same migration twice
+const migrationLockID = 7270510
+
+// Migration is one versioned schema step
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded migrations in version order. Versions must
+// run 1, 2, 3... without gaps so a missing file can't go unnoticed.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var all []Migration
+	for _, e := range entries {
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_description.sql", e.Name())
+		}
+		body, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, Migration{Version: version, Name: name, SQL: string(body)})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s: expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+	return all, nil
+}
+
+// Migrate applies the migrations not yet recorded in schema_migrations, in
+// one transaction, and returns their versions. Running it again is a no-op.
+func (db *DB) Migrate(ctx context.Context) ([]int, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("locking schema_migrations: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return nil, err
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return nil, err
+	}
+	if current > len(all) {
+		// A newer build migrated first, as happens mid rolling deploy. Explicit
+		// column lists keep this build working against the extra columns.
+		log.Printf("Warning: database schema is at version %d, newer than this build's %d", current, len(all))
+		return nil, tx.Commit()
+	}
+
+	var applied []int
+	for _, m := range all[current:] {
+		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %v", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return nil, err
+		}
+		applied = append(applied, m.Version)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range applied {
+		log.Printf("Applied migration %04d_%s", v, all[v-1].Name)
+	}
+	return applied, nil
+}
@@ -1,5 +1,6 @@
--- Complete Database Schema for MCP Service with OAuth
--- This includes OAuth tables and Restaurant tables
+-- Version 1: the schema as it stood before versioned migrations.
+-- Existing deployments already have most of it, so every statement must be
+-- a no-op when its object exists.
 
 -- ============================================
 -- OAuth Tables
@@ -19,7 +20,7 @@ CREATE TABLE IF NOT EXISTS user_profiles (
     created_at TIMESTAMPTZ DEFAULT NOW(),
     last_login_at TIMESTAMPTZ,
     updated_at TIMESTAMPTZ DEFAULT NOW(),
-    
+
     CONSTRAINT unique_provider_user UNIQUE(provider, provider_user_id)
 );
 
@@ -54,10 +55,15 @@ CREATE TABLE IF NOT EXISTS oauth_tokens (
     expires_at TIMESTAMPTZ NOT NULL,
     created_at TIMESTAMPTZ DEFAULT NOW(),
     active BOOLEAN DEFAULT true,
-    
+
     FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE
 );
 
+-- token_id holds the SHA-256 hex of the JWT token_id claim. Rows written
+-- before hashing hold the raw UUID and are still matched until they expire.
+-- created_by_ip is kept for forensics only.
+ALTER TABLE oauth_tokens ADD COLUMN IF NOT EXISTS created_by_ip TEXT;
+
 -- ============================================
 -- Restaurant Tables
 -- ============================================
@@ -72,6 +78,18 @@ CREATE TABLE IF NOT EXISTS restaurants (
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- Contact email (not unique: outlets of a chain may share one) and
+-- notification flags read by the notification sender
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS email TEXT;
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS notification_preferences JSONB
+    NOT NULL DEFAULT '{"notify_on_order": true, "notify_on_low_stock": true}'::jsonb;
+
+-- IANA timezone used to decide which menu items are effective "today"
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Asia/Kolkata';
+
+-- Last time any field of the restaurant was changed
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+
 -- Menu Items
 CREATE TABLE IF NOT EXISTS menu_items (
     id SERIAL PRIMARY KEY,
@@ -86,6 +104,14 @@ CREATE TABLE IF NOT EXISTS menu_items (
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- Seasonal menus: an item is listed only between these dates (inclusive,
+-- NULL = open-ended). available stays the manual override on top.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS effective_from DATE;
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS effective_to DATE;
+ALTER TABLE menu_items DROP CONSTRAINT IF EXISTS menu_items_effective_range;
+ALTER TABLE menu_items ADD CONSTRAINT menu_items_effective_range
+    CHECK (effective_from IS NULL OR effective_to IS NULL OR effective_from <= effective_to);
+
 -- Orders
 CREATE TABLE IF NOT EXISTS orders (
     id SERIAL PRIMARY KEY,
@@ -104,6 +130,20 @@ CREATE TABLE IF NOT EXISTS orders (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- Order type and delivery rider tracking; delivered_at is set when the
+-- order status becomes delivered
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_type TEXT NOT NULL DEFAULT 'dine_in';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_person_name TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_person_phone TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ;
+
+-- Cancellation details recorded by cancel_order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_note TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_by TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
 -- Order Items
 CREATE TABLE IF NOT EXISTS order_items (
     id SERIAL PRIMARY KEY,
@@ -175,7 +215,7 @@ CREATE TRIGGER update_orders_updated_at
 -- ============================================
 
 -- Insert default admin (will be skipped if already exists due to unique constraint)
-INSERT INTO user_profiles (user_id, email, name, status, role, created_at) 
+INSERT INTO user_profiles (user_id, email, name, status, role, created_at)
 VALUES (
     'admin-default-vishal',
     'vishalkapadi17@hotmail.com',
@@ -184,16 +224,3 @@ VALUES (
     'admin',
     NOW()
 ) ON CONFLICT (email) DO NOTHING;
-
--- ============================================
--- Sample Restaurant Data (Optional)
--- ============================================
-
--- Insert sample restaurants
-INSERT INTO restaurants (name, address, phone_number, cuisine_type) VALUES
-    ('Taj Mahal Restaurant', 'Connaught Place, New Delhi', '+91-11-12345678', 'Indian'),
-    ('Surya Mahal', 'Linking Road, Mumbai', '+91-22-87654321', 'Indian'),
-    ('Hyderabad House', 'Banjara Hills, Hyderabad', '+91-40-23456789', 'Indian')
-ON CONFLICT DO NOTHING;
-
--- Note: Menu items will be inserted via application seed logic
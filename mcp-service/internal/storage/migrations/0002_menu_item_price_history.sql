@@ -0,0 +1,12 @@
+-- Every price change of a menu item, written in the same transaction as the
+-- update. Rows go with the item when it is deleted.
+CREATE TABLE IF NOT EXISTS menu_item_price_history (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    old_price DECIMAL(10, 2) NOT NULL,
+    new_price DECIMAL(10, 2) NOT NULL,
+    changed_by TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_item_price_history_item ON menu_item_price_history(item_id, changed_at);
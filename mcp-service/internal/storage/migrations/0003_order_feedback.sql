@@ -0,0 +1,10 @@
+-- Staff-recorded satisfaction for a delivered order, at most one per order.
+-- Kept apart from public reviews; rows go with the order when it is deleted.
+CREATE TABLE IF NOT EXISTS order_feedback (
+    order_id INTEGER PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    contacted BOOLEAN NOT NULL DEFAULT false,
+    recorded_by TEXT NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+-- Whether GET /public/restaurants/{id}/menu serves this restaurant's menu
+-- without authentication. Off until the restaurant opts in.
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS public_menu_enabled BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,16 @@
+-- Changes staff propose for an owner or admin to approve. changes holds the
+-- proposed fields only; approving applies them to the entity.
+CREATE TABLE IF NOT EXISTS change_requests (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    changes JSONB NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    proposed_by TEXT NOT NULL,
+    reviewed_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_requests_status ON change_requests(status, created_at);
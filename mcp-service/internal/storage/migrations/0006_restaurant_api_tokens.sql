@@ -0,0 +1,15 @@
+-- API tokens a restaurant's POS uses to push orders without an OAuth login.
+-- Only the SHA-256 of the secret is kept; the secret is shown once at creation.
+CREATE TABLE IF NOT EXISTS restaurant_api_tokens (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_restaurant_api_tokens_restaurant ON restaurant_api_tokens(restaurant_id);
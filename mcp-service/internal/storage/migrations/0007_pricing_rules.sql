@@ -0,0 +1,21 @@
+-- Time-boxed promotions such as happy hours. A rule takes percent_off off
+-- the matching items while the restaurant's local time is inside the
+-- window; an end_time before start_time runs past midnight. Order items
+-- record the rule that priced them.
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    category VARCHAR(100),
+    menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    percent_off NUMERIC(5, 2) NOT NULL CHECK (percent_off > 0 AND percent_off <= 100),
+    days_of_week TEXT[] NOT NULL DEFAULT '{}',
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL CHECK (end_time <> start_time),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_restaurant ON pricing_rules(restaurant_id) WHERE active;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS pricing_rule_id INTEGER REFERENCES pricing_rules(id) ON DELETE SET NULL;
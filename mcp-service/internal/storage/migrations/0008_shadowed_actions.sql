@@ -0,0 +1,16 @@
+-- Destructive tool calls recorded instead of run while SHADOW_MODE covers
+-- the tool. arguments are the call's arguments as sent; replaying runs them.
+CREATE TABLE IF NOT EXISTS shadowed_actions (
+    id SERIAL PRIMARY KEY,
+    tool VARCHAR(100) NOT NULL,
+    arguments JSONB NOT NULL,
+    effect TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'replaying', 'replayed', 'failed')),
+    requested_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    replayed_by TEXT,
+    replayed_at TIMESTAMPTZ,
+    replay_result TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_shadowed_actions_status ON shadowed_actions(status, created_at);
@@ -0,0 +1,3 @@
+-- Whether new orders skip the manual confirm step and are created
+-- confirmed. Off until the restaurant opts in.
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS auto_confirm_orders BOOLEAN NOT NULL DEFAULT false;
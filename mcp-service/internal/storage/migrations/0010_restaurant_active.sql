@@ -0,0 +1,4 @@
+-- Deleting a restaurant deactivates it by default, keeping its order
+-- history for accounting. Inactive restaurants are left out of listings
+-- and search and take no new orders.
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,3 @@
+-- GST charged on the restaurant's orders, as a fraction. NULL charges the
+-- server-wide default (DEFAULT_TAX_RATE, 0.05 unless set).
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5, 4) CHECK (tax_rate >= 0 AND tax_rate < 1);
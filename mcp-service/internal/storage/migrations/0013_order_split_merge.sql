@@ -0,0 +1,7 @@
+-- Where an order split off another one, or merged from several, came from
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS split_from INTEGER REFERENCES orders(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS merged_from INTEGER[];
+
+-- Explains history entries that are more than a status change, such as
+-- items moved to a split order
+ALTER TABLE order_status_history ADD COLUMN IF NOT EXISTS note TEXT;
@@ -0,0 +1,3 @@
+-- A discount given as a percentage of the item total. discount still holds
+-- the amount taken off, recomputed from this when the items change.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_percent NUMERIC(5, 2) CHECK (discount_percent >= 0 AND discount_percent <= 100);
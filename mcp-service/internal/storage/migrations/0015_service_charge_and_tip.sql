@@ -0,0 +1,7 @@
+-- A service charge added to dine-in orders as a percent of the item total
+-- less discount; NULL charges none
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS service_charge_percent NUMERIC(5, 2) CHECK (service_charge_percent >= 0 AND service_charge_percent <= 100);
+
+-- The service charge and tip included in an order's final_amount
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS service_charge DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tip DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (tip >= 0);
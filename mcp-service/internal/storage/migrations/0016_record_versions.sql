@@ -0,0 +1,4 @@
+-- Raised by every update of a restaurant or menu item, so an update based
+-- on a stale read is refused instead of overwriting a newer change
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
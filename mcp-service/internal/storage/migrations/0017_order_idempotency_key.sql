@@ -0,0 +1,5 @@
+-- Set by a client that may retry create_order, so a retry returns the order
+-- the first attempt created instead of placing a duplicate. Keys are only
+-- unique within a restaurant; NULL keys never conflict.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS orders_idempotency_key ON orders (restaurant_id, idempotency_key);
@@ -0,0 +1,10 @@
+-- No two restaurants may share a name and address, ignoring case. Existing
+-- duplicates keep their menus and orders; all but the oldest get their ID
+-- appended to their name so the index can be built.
+UPDATE restaurants r
+SET name = r.name || ' (#' || r.id || ')', version = r.version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE EXISTS (
+    SELECT 1 FROM restaurants o
+    WHERE lower(o.name) = lower(r.name) AND lower(o.address) = lower(r.address) AND o.id < r.id
+);
+CREATE UNIQUE INDEX IF NOT EXISTS restaurants_name_address ON restaurants (lower(name), lower(address));
@@ -0,0 +1,8 @@
+-- Last time any field of the menu item was changed, e.g. to tell when its
+-- price last changed. Existing items start at their last recorded price
+-- change, or at their creation.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE menu_items m
+SET updated_at = GREATEST(m.created_at, (SELECT max(h.changed_at) FROM menu_item_price_history h WHERE h.item_id = m.id))
+WHERE updated_at IS NULL;
+ALTER TABLE menu_items ALTER COLUMN updated_at SET DEFAULT NOW();
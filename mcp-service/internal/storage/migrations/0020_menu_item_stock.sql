@@ -0,0 +1,5 @@
+-- Portions of a menu item left in stock; NULL when stock isn't tracked.
+-- Orders take from it and cancellations put back what their items took,
+-- as recorded by order_items.from_stock.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS stock_quantity INTEGER CHECK (stock_quantity >= 0);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS from_stock BOOLEAN NOT NULL DEFAULT FALSE;
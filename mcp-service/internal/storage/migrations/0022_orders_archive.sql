@@ -0,0 +1,35 @@
+-- Delivered and cancelled orders moved out of the live tables by
+-- ArchiveOrders, with their items, status history and feedback. Each table
+-- starts with the columns of its live table and rows are copied by column
+-- name: a migration adding a column to orders, order_items,
+-- order_status_history or order_feedback must add it here too, or archiving
+-- drops it. order_items_archive.subtotal is a plain column holding the
+-- copied value.
+CREATE TABLE IF NOT EXISTS orders_archive (
+    LIKE orders,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS order_items_archive (
+    LIKE order_items,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS order_status_history_archive (
+    LIKE order_status_history,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS order_feedback_archive (
+    LIKE order_feedback,
+    PRIMARY KEY (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_archive_restaurant ON orders_archive(restaurant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_archive_order ON order_items_archive(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_archive_order ON order_status_history_archive(order_id, changed_at);
+-- CreateOrder looks idempotency keys up here too, so a late retry of an
+-- archived order returns it. Not unique: the live orders' index already
+-- rules out a second use.
+CREATE INDEX IF NOT EXISTS idx_orders_archive_idempotency_key ON orders_archive(restaurant_id, idempotency_key);
@@ -0,0 +1,4 @@
+-- Why a call recorded in the audit log made no change: 'denied' when the
+-- restaurant was outside the caller's scope, 'shadowed' when shadow mode
+-- recorded it instead of running it. NULL for a change that was made.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS outcome VARCHAR(20);
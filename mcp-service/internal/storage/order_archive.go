@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrArchiveUnavailable is returned while the database predates the
+// orders_archive migration
+var ErrArchiveUnavailable = errors.New("the order archive is not available until the database is migrated")
+
+// ArchivedStatuses are the statuses an order must be in to be archived: no
+// further change is expected of it
+var ArchivedStatuses = []string{models.OrderStatusDelivered, models.OrderStatusCancelled}
+
+// archivedTables are the tables whose rows ArchiveOrders moves, each to the
+// table of the same name with an _archive suffix, by the column holding
+// the order ID. Rows of the others go with their order when it is deleted.
+var archivedTables = []struct{ table, orderID string }{
+	{"orders", "id"},
+	{"order_items", "order_id"},
+	{"order_status_history", "order_id"},
+	{"order_feedback", "order_id"},
+}
+
+// ArchiveOrders moves the delivered and cancelled orders created before
+// the cutoff, with their items, status history and feedback, into the
+// archive tables in a single transaction, and returns how many it moved.
+// Orders split off an archived order lose their split_from.
+func (db *DB) ArchiveOrders(ctx context.Context, before time.Time) (int, error) {
+	if !db.schema.hasTable("orders_archive") {
+		return 0, ErrArchiveUnavailable
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id FROM orders WHERE status = ANY($1) AND created_at < $2 ORDER BY id FOR UPDATE`,
+		pq.Array(ArchivedStatuses), before)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Rows are copied by column name, so the archive tables needn't keep
+	// their live tables' column order; archived_at is ignored by all but
+	// orders_archive
+	now := time.Now()
+	for _, t := range archivedTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s_archive
+			SELECT a.* FROM %[1]s t, jsonb_populate_record(NULL::%[1]s_archive, to_jsonb(t) || jsonb_build_object('archived_at', $2::timestamptz)) a
+			WHERE t.%[2]s = ANY($1)`, t.table, t.orderID),
+			pq.Array(ids), now); err != nil {
+			return 0, fmt.Errorf("archiving %s: %w", t.table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// GetArchivedOrder returns an order moved to the archive by ArchiveOrders,
+// with its items
+func (db *DB) GetArchivedOrder(ctx context.Context, id int) (*models.Order, error) {
+	if !db.schema.hasTable("orders_archive") {
+		return nil, ErrArchiveUnavailable
+	}
+	var o models.Order
+	var archivedAt time.Time
+	row := db.QueryRowContext(ctx, `SELECT `+orderColumns.list()+`, archived_at FROM orders_archive WHERE id = $1`, id)
+	err := scanOrder(rowWithExtra{row, &archivedAt}, &o)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("archived order with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.ArchivedAt = &archivedAt
+
+	if o.OrderItems, err = db.queryOrderItemsFrom(ctx, db, "order_items_archive", `oi.order_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// lockOrders reads the orders with the given IDs, with their items, locking
+// the rows in ID order so two merges of the same orders can't deadlock
+func (db *DB) lockOrders(ctx context.Context, tx *sql.Tx, ids []int) ([]models.Order, error) {
+	rows, err := tx.QueryContext(ctx, db.schema.read(`SELECT `+orderColumns.list()+` FROM orders WHERE id = ANY($1) ORDER BY id FOR UPDATE`, "orders"), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byID := map[int]models.Order{}
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, err
+		}
+		byID[o.ID] = o
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	orders := make([]models.Order, len(ids))
+	for i, id := range ids {
+		o, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("order with ID %d %w", id, ErrNotFound)
+		}
+		// Every change to an order's items locks the order row first
+		if o.OrderItems, err = db.orderItemsIn(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		orders[i] = o
+	}
+	return orders, nil
+}
+
+// SplitOrder moves the given quantities of menu items off a pending or
+// confirmed, unpaid order onto a new order, in a single transaction, and
+// recomputes the totals of both. It returns the order split and the new
+// one. A *models.CombineError says why the split isn't possible.
+func (db *DB) SplitOrder(ctx context.Context, orderID int, moves []models.OrderItem) (*models.Order, *models.Order, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	orders, err := db.lockOrders(ctx, tx, []int{orderID})
+	if err != nil {
+		return nil, nil, err
+	}
+	source := orders[0]
+	split, err := source.Split(moves)
+	if err != nil {
+		return nil, nil, err
+	}
+	charges, err := db.orderCharges(ctx, tx, source.RestaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	source.ComputeTotals(charges)
+	split.ComputeTotals(charges)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, orderID); err != nil {
+		return nil, nil, err
+	}
+	if err := db.insertOrderItems(ctx, tx, &source); err != nil {
+		return nil, nil, err
+	}
+	if err := updateOrderTotals(ctx, tx, &source); err != nil {
+		return nil, nil, err
+	}
+	if err := db.insertOrder(ctx, tx, &split); err != nil {
+		return nil, nil, err
+	}
+	if err := db.insertOrderItems(ctx, tx, &split); err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.recordStep(ctx, tx, orderID, source.Status, source.Status, fmt.Sprintf("items moved to split order %d", split.ID)); err != nil {
+		return nil, nil, err
+	}
+	if err := db.recordStep(ctx, tx, split.ID, "", split.Status, fmt.Sprintf("split from order %d", orderID)); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	updated, err := db.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	created, err := db.GetOrderByID(ctx, split.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, created, nil
+}
+
+// MergeOrders combines pending or confirmed, unpaid dine-in orders of one
+// restaurant into a new order, as models.MergeOrders describes, in a
+// single transaction. The orders merged are cancelled with reason
+// models.CancelReasonMerged. A *models.CombineError says why the merge
+// isn't possible.
+func (db *DB) MergeOrders(ctx context.Context, orderIDs []int) (*models.Order, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	orders, err := db.lockOrders(ctx, tx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := models.MergeOrders(orders)
+	if err != nil {
+		return nil, err
+	}
+	charges, err := db.orderCharges(ctx, tx, merged.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	merged.ComputeTotals(charges)
+
+	if err := db.insertOrder(ctx, tx, &merged); err != nil {
+		return nil, err
+	}
+	if err := db.insertOrderItems(ctx, tx, &merged); err != nil {
+		return nil, err
+	}
+
+	note := fmt.Sprintf("merged into order %d", merged.ID)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET status = $1, cancellation_reason = $2, cancellation_note = $3, cancelled_by = $4,
+			cancelled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($5)`,
+		models.OrderStatusCancelled, models.CancelReasonMerged, note, Actor(ctx), pq.Array(merged.MergedFrom))
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		if err := db.recordStep(ctx, tx, o.ID, o.Status, models.OrderStatusCancelled, note); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.recordStep(ctx, tx, merged.ID, "", merged.Status, "merged from "+merged.MergedFromText()); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetOrderByID(ctx, merged.ID)
+}
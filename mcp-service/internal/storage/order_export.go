@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ExportOrders calls row for each line item of the orders a restaurant took
+// from from up to to, oldest order first, as the rows are read rather than
+// after loading them all, so a year of orders streams in constant memory.
+// An order without items is one row. An error from row stops the export
+// and is returned.
+func (db *DB) ExportOrders(ctx context.Context, restaurantID int, from, to time.Time, row func(models.OrderExportRow) error) error {
+	if _, err := db.GetRestaurantByID(ctx, restaurantID); err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, db.schema.read(`
+		SELECT o.*, COALESCE(i.menu_item_id, 0), COALESCE(i.name, ''), COALESCE(i.quantity, 0), i.price, i.subtotal, COALESCE(i.notes, '')
+		FROM (SELECT `+orderColumns.list()+` FROM orders WHERE restaurant_id = $1 AND created_at >= $2 AND created_at < $3) o
+		LEFT JOIN LATERAL (
+			SELECT oi.id, oi.menu_item_id, menu_items.name, oi.quantity, oi.price, oi.subtotal, oi.notes
+			FROM order_items oi LEFT JOIN menu_items ON menu_items.id = oi.menu_item_id
+			WHERE oi.order_id = o.id
+		) i ON true
+		ORDER BY o.created_at, o.id, i.id`, "orders"), restaurantID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.OrderExportRow
+		dest := append(orderColumns.dest(&r.Order), &r.Item.MenuItemID, &r.ItemName, &r.Item.Quantity, &r.Item.Price, &r.Item.Subtotal, &r.Item.Notes)
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		r.Order.Status = readEnum("orders", "status", r.Order.Status)
+		r.Order.PaymentStatus = readEnum("orders", "payment_status", r.Order.PaymentStatus)
+		r.Item.OrderID = r.Order.ID
+		if err := row(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrFeedbackUnavailable is returned while the database predates the
+// order_feedback migration
+var ErrFeedbackUnavailable = errors.New("order feedback is not available until the database is migrated")
+
+// Rules RecordOrderFeedback enforces, wrapped by its errors so callers can
+// tell a refused recording from a database failure
+var (
+	ErrOrderNotDelivered = errors.New("feedback can only be recorded for delivered orders")
+	ErrFeedbackRecorded  = errors.New("feedback was already recorded for this order")
+)
+
+// RecordOrderFeedback stores the feedback for a delivered order, filling in
+// RecordedAt. An order that isn't delivered yet or already has feedback is
+// refused with ErrOrderNotDelivered or ErrFeedbackRecorded.
+func (db *DB) RecordOrderFeedback(ctx context.Context, f *models.OrderFeedback) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if !db.schema.hasTable("order_feedback") {
+		return ErrFeedbackUnavailable
+	}
+	if f.RecordedBy == "" {
+		f.RecordedBy = "staff"
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// FOR SHARE keeps the order from being deleted or changing status
+	// between the check and the insert
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(status, 'pending') FROM orders WHERE id = $1 FOR SHARE`, f.OrderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order with ID %d %w", f.OrderID, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OrderStatusDelivered {
+		return fmt.Errorf("order %d is %s: %w", f.OrderID, status, ErrOrderNotDelivered)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO order_feedback (order_id, score, comment, contacted, recorded_by) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id) DO NOTHING RETURNING recorded_at`,
+		f.OrderID, f.Score, f.Comment, f.Contacted, f.RecordedBy,
+	).Scan(&f.RecordedAt)
+	if err == sql.ErrNoRows {
+		existing, err := getOrderFeedback(ctx, tx, f.OrderID)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("order %d: %w (by %s on %s)", f.OrderID, ErrFeedbackRecorded, existing.RecordedBy, existing.RecordedAt.Format(models.DateLayout))
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOrderFeedback returns the feedback recorded for an order, or nil if
+// there is none
+func (db *DB) GetOrderFeedback(ctx context.Context, orderID int) (*models.OrderFeedback, error) {
+	if !db.schema.hasTable("order_feedback") {
+		return nil, ErrFeedbackUnavailable
+	}
+	f, err := getOrderFeedback(ctx, db, orderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return f, err
+}
+
+func getOrderFeedback(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}, orderID int) (*models.OrderFeedback, error) {
+	var f models.OrderFeedback
+	err := q.QueryRowContext(ctx,
+		`SELECT order_id, score, comment, contacted, recorded_by, recorded_at FROM order_feedback WHERE order_id = $1`, orderID,
+	).Scan(&f.OrderID, &f.Score, &f.Comment, &f.Contacted, &f.RecordedBy, &f.RecordedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// DefaultActor is who status changes are recorded as made by when the
+// context names no one, as for the stdio server
+const DefaultActor = "mcp"
+
+// ErrHistoryUnavailable is returned while the database predates the
+// order_status_history migration
+var ErrHistoryUnavailable = errors.New("order status history is not available until the database is migrated")
+
+type actorKey struct{}
+
+// WithActor returns ctx naming who the writes made with it are made by,
+// such as the authenticated user of a tool call
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns who ctx says writes are made by, DefaultActor if no one
+func Actor(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
+
+// recordStatus adds a step to an order's status timeline in tx. It does
+// nothing while the table is missing, so status changes keep working
+// mid-deploy.
+func (db *DB) recordStatus(ctx context.Context, tx *sql.Tx, orderID int, from, to string) error {
+	return db.recordStep(ctx, tx, orderID, from, to, "")
+}
+
+// recordStep is recordStatus with a note explaining the step, which is
+// recorded even if the status stays the same
+func (db *DB) recordStep(ctx context.Context, tx *sql.Tx, orderID int, from, to, note string) error {
+	if (from == to && note == "") || !db.schema.hasTable("order_status_history") {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note) VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''))`,
+		orderID, from, to, Actor(ctx), note)
+	return err
+}
+
+// GetOrderHistory returns the status changes of an order, oldest first
+func (db *DB) GetOrderHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error) {
+	if !db.schema.hasTable("order_status_history") {
+		return nil, ErrHistoryUnavailable
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("order with ID %d %w", orderID, ErrNotFound)
+	}
+
+	rows, err := db.QueryContext(ctx, db.schema.read(
+		`SELECT order_id, COALESCE(from_status, ''), to_status, changed_at, changed_by, COALESCE(note, '')
+		FROM order_status_history WHERE order_id = $1 ORDER BY changed_at, id`, "order_status_history"), orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []models.OrderStatusChange
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.OrderID, &c.FromStatus, &c.ToStatus, &c.ChangedAt, &c.ChangedBy, &c.Note); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
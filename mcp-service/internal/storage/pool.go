@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// PoolConfig sizes the connection pool. The database/sql defaults allow
+// unlimited open connections but keep only two idle ones, so concurrent tool
+// calls either exhaust Postgres or keep reconnecting.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultPoolConfig is used for any setting not given in the environment
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+	}
+}
+
+// PoolConfigFromEnv reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and
+// DB_CONN_MAX_LIFETIME_MINUTES over the defaults
+func PoolConfigFromEnv() (PoolConfig, error) {
+	cfg := DefaultPoolConfig()
+	settings := []struct {
+		name string
+		dest *int
+	}{
+		{"DB_MAX_OPEN_CONNS", &cfg.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &cfg.MaxIdleConns},
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return PoolConfig{}, fmt.Errorf("invalid %s: %q", s.name, v)
+			}
+			*s.dest = n
+		}
+	}
+	if v := os.Getenv("DB_CONN_MAX_LIFETIME_MINUTES"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			return PoolConfig{}, fmt.Errorf("invalid DB_CONN_MAX_LIFETIME_MINUTES: %q", v)
+		}
+		cfg.ConnMaxLifetime = time.Duration(minutes) * time.Minute
+	}
+	if cfg.MaxIdleConns > cfg.MaxOpenConns {
+		return PoolConfig{}, fmt.Errorf("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", cfg.MaxIdleConns, cfg.MaxOpenConns)
+	}
+	return cfg, nil
+}
+
+// Apply sets the pool limits on conn
+func (c PoolConfig) Apply(conn *sql.DB) {
+	conn.SetMaxOpenConns(c.MaxOpenConns)
+	conn.SetMaxIdleConns(c.MaxIdleConns)
+	conn.SetConnMaxLifetime(c.ConnMaxLifetime)
+}
+
+func (c PoolConfig) String() string {
+	return fmt.Sprintf("max_open=%d max_idle=%d max_lifetime=%s", c.MaxOpenConns, c.MaxIdleConns, c.ConnMaxLifetime)
+}
+
+// PoolStatus is the pool configuration and current usage reported on /health
+type PoolStatus struct {
+	MaxOpenConns    int    `json:"max_open_conns"`
+	MaxIdleConns    int    `json:"max_idle_conns"`
+	ConnMaxLifetime string `json:"conn_max_lifetime"`
+	OpenConns       int    `json:"open_conns"`
+	InUse           int    `json:"in_use"`
+	Idle            int    `json:"idle"`
+	WaitCount       int64  `json:"wait_count"`
+}
+
+// Status reports c together with conn's live statistics. The max open count
+// comes from the pool itself, so a setting that didn't take effect shows up.
+func (c PoolConfig) Status(conn *sql.DB) PoolStatus {
+	stats := conn.Stats()
+	return PoolStatus{
+		MaxOpenConns:    stats.MaxOpenConnections,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime.String(),
+		OpenConns:       stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+		WaitCount:       stats.WaitCount,
+	}
+}
+
+// PoolStatus reports the pool of a DB opened with NewDB
+func (db *DB) PoolStatus() PoolStatus {
+	return db.pool.Status(db.DB)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrPriceHistoryUnavailable is returned by GetPriceHistory while the
+// database predates the menu_item_price_history migration
+var ErrPriceHistoryUnavailable = errors.New("price history is not available until the database is migrated")
+
+// RecordPriceChange adds a menu_item_price_history row when newPrice differs
+// from oldPrice. Call it inside the transaction that updates the item so the
+// history can't miss or invent a change. Against a database without the
+// table it logs a warning and records nothing.
+func RecordPriceChange(ctx context.Context, tx *sql.Tx, itemID int, oldPrice, newPrice models.Money, changedBy string) error {
+	if oldPrice == newPrice {
+		return nil
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT to_regclass('menu_item_price_history') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		log.Printf("Warning: not recording price change of menu item %d, menu_item_price_history does not exist", itemID)
+		return nil
+	}
+	if changedBy == "" {
+		changedBy = "staff"
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO menu_item_price_history (item_id, old_price, new_price, changed_by) VALUES ($1, $2, $3, $4)`,
+		itemID, oldPrice, newPrice, changedBy)
+	return err
+}
+
+// GetPriceHistory returns one page of the price changes selected by q,
+// newest first, and the total count
+func (db *DB) GetPriceHistory(ctx context.Context, q models.PriceHistoryQuery, page models.Page) ([]models.PriceChange, int, error) {
+	if err := q.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if !db.schema.hasTable("menu_item_price_history") {
+		return nil, 0, ErrPriceHistoryUnavailable
+	}
+	// Dates are compared in the restaurant's timezone, like menu effective dates
+	const where = `
+		FROM menu_item_price_history h
+		JOIN menu_items m ON m.id = h.item_id
+		JOIN restaurants r ON r.id = m.restaurant_id
+		WHERE ($1 = 0 OR h.item_id = $1) AND ($2 = 0 OR m.restaurant_id = $2)
+			AND ($3 = '' OR (h.changed_at AT TIME ZONE r.timezone)::date >= NULLIF($3, '')::date)
+			AND ($4 = '' OR (h.changed_at AT TIME ZONE r.timezone)::date <= NULLIF($4, '')::date)`
+	args := []interface{}{q.ItemID, q.RestaurantID, q.From, q.To}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT h.id, h.item_id, m.name, m.restaurant_id, h.old_price, h.new_price, h.changed_by, h.changed_at`+where+`
+		ORDER BY h.changed_at DESC, h.id DESC LIMIT $5 OFFSET $6`,
+		append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	changes := []models.PriceChange{}
+	for rows.Next() {
+		var c models.PriceChange
+		if err := rows.Scan(&c.ID, &c.ItemID, &c.ItemName, &c.RestaurantID, &c.OldPrice, &c.NewPrice, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, 0, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, total, rows.Err()
+}
+
+// LastPriceChanges returns when each item of a restaurant last changed price.
+// Items whose price never changed are absent, as are all items while the
+// price history table doesn't exist.
+func (db *DB) LastPriceChanges(ctx context.Context, restaurantID int) (map[int]time.Time, error) {
+	if !db.schema.hasTable("menu_item_price_history") {
+		return map[int]time.Time{}, nil
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT h.item_id, MAX(h.changed_at)
+		FROM menu_item_price_history h
+		JOIN menu_items m ON m.id = h.item_id
+		WHERE m.restaurant_id = $1
+		GROUP BY h.item_id`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	last := map[int]time.Time{}
+	for rows.Next() {
+		var itemID int
+		var at time.Time
+		if err := rows.Scan(&itemID, &at); err != nil {
+			return nil, err
+		}
+		last[itemID] = at
+	}
+	return last, rows.Err()
+}
+
+// WithLastPriceChanges sets LastPriceChangeAt on the items found in last
+func WithLastPriceChanges(items []models.MenuItem, last map[int]time.Time) {
+	for i := range items {
+		if at, ok := last[items[i].ID]; ok {
+			items[i].LastPriceChangeAt = &at
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrPricingRulesUnavailable is returned while the database predates the
+// pricing_rules migration
+var ErrPricingRulesUnavailable = errors.New("pricing rules are not available until the database is migrated")
+
+const pricingRuleColumns = `id, restaurant_id, name, COALESCE(category, ''), COALESCE(menu_item_id, 0), percent_off, days_of_week,
+	to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), active, created_at`
+
+func scanPricingRule(row interface{ Scan(...interface{}) error }, r *models.PricingRule) error {
+	r.DaysOfWeek = []string{}
+	return row.Scan(&r.ID, &r.RestaurantID, &r.Name, &r.Category, &r.MenuItemID, &r.PercentOff, pq.Array(&r.DaysOfWeek),
+		&r.StartTime, &r.EndTime, &r.Active, &r.CreatedAt)
+}
+
+// checkPricingRuleTarget checks the restaurant of r exists and its menu item,
+// if any, is on that restaurant's menu
+func (db *DB) checkPricingRuleTarget(ctx context.Context, r *models.PricingRule) error {
+	if _, err := db.GetRestaurantByID(ctx, r.RestaurantID); err != nil {
+		return err
+	}
+	if r.MenuItemID == 0 {
+		return nil
+	}
+	m, err := db.GetMenuItemByID(ctx, r.MenuItemID)
+	if err != nil {
+		return err
+	}
+	if m.RestaurantID != r.RestaurantID {
+		return fmt.Errorf("menu item %d is on the menu of restaurant %d, not %d", m.ID, m.RestaurantID, r.RestaurantID)
+	}
+	return nil
+}
+
+// CreatePricingRule stores a rule, filling in ID and CreatedAt
+func (db *DB) CreatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if !db.schema.hasTable("pricing_rules") {
+		return ErrPricingRulesUnavailable
+	}
+	if err := db.checkPricingRuleTarget(ctx, r); err != nil {
+		return err
+	}
+	return db.QueryRowContext(ctx,
+		`INSERT INTO pricing_rules (restaurant_id, name, category, menu_item_id, percent_off, days_of_week, start_time, end_time, active)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0), $5, $6, $7, $8, $9) RETURNING id, created_at`,
+		r.RestaurantID, r.Name, r.Category, r.MenuItemID, r.PercentOff, pq.Array(r.DaysOfWeek), r.StartTime, r.EndTime, r.Active,
+	).Scan(&r.ID, &r.CreatedAt)
+}
+
+// ListPricingRules returns every rule of a restaurant, inactive ones
+// included, oldest first
+func (db *DB) ListPricingRules(ctx context.Context, restaurantID int) ([]models.PricingRule, error) {
+	if !db.schema.hasTable("pricing_rules") {
+		return nil, ErrPricingRulesUnavailable
+	}
+	return queryPricingRules(ctx, db.DB, `restaurant_id = $1`, restaurantID)
+}
+
+// queryPricingRules returns the rules matching where, oldest first
+func queryPricingRules(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, where string, args ...interface{}) ([]models.PricingRule, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+pricingRuleColumns+` FROM pricing_rules WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.PricingRule{}
+	for rows.Next() {
+		var r models.PricingRule
+		if err := scanPricingRule(rows, &r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetPricingRule returns one rule
+func (db *DB) GetPricingRule(ctx context.Context, id int) (*models.PricingRule, error) {
+	if !db.schema.hasTable("pricing_rules") {
+		return nil, ErrPricingRulesUnavailable
+	}
+	var r models.PricingRule
+	err := scanPricingRule(db.QueryRowContext(ctx, `SELECT `+pricingRuleColumns+` FROM pricing_rules WHERE id = $1`, id), &r)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pricing rule with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// UpdatePricingRule saves every field of r but its restaurant
+func (db *DB) UpdatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if !db.schema.hasTable("pricing_rules") {
+		return ErrPricingRulesUnavailable
+	}
+	if err := db.checkPricingRuleTarget(ctx, r); err != nil {
+		return err
+	}
+	result, err := db.ExecContext(ctx,
+		`UPDATE pricing_rules SET name = $1, category = NULLIF($2, ''), menu_item_id = NULLIF($3, 0), percent_off = $4,
+			days_of_week = $5, start_time = $6, end_time = $7, active = $8
+		WHERE id = $9`,
+		r.Name, r.Category, r.MenuItemID, r.PercentOff, pq.Array(r.DaysOfWeek), r.StartTime, r.EndTime, r.Active, r.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("pricing rule with ID %d %w", r.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// DeletePricingRule removes a rule. Order items it priced keep their price
+// and lose the reference.
+func (db *DB) DeletePricingRule(ctx context.Context, id int) error {
+	if !db.schema.hasTable("pricing_rules") {
+		return ErrPricingRulesUnavailable
+	}
+	result, err := db.ExecContext(ctx, `DELETE FROM pricing_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("pricing rule with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// activePricing returns the active rules of a restaurant and the current
+// time there, for pricing an order inside tx. Without the pricing_rules
+// table there are no rules.
+func (db *DB) activePricing(ctx context.Context, tx *sql.Tx, restaurantID int) ([]models.PricingRule, time.Time, error) {
+	var tz string
+	err := tx.QueryRowContext(ctx, db.schema.read(`SELECT COALESCE(timezone, '') FROM restaurants WHERE id = $1`, "restaurants"), restaurantID).Scan(&tz)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, time.Time{}, err
+	}
+	local := models.LocalTime(tz, time.Now())
+	if !db.schema.hasTable("pricing_rules") {
+		return nil, local, nil
+	}
+	rules, err := queryPricingRules(ctx, tx, `restaurant_id = $1 AND active`, restaurantID)
+	return rules, local, err
+}
+
+// PriceMenu prices a restaurant's menu items with its pricing rules running
+// now, for get_menu's include_promotions. Without the pricing_rules table no
+// promotions run.
+func PriceMenu(ctx context.Context, s Store, restaurantID int, items []models.MenuItem) ([]models.PricedMenuItem, []string, error) {
+	rules, err := s.ListPricingRules(ctx, restaurantID)
+	if errors.Is(err, ErrPricingRulesUnavailable) {
+		rules, err = nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var tz string
+	if r, err := s.GetRestaurantByID(ctx, restaurantID); err == nil {
+		tz = r.Timezone
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+	priced, notes := models.PriceMenu(items, rules, models.LocalTime(tz, time.Now()))
+	return priced, notes, nil
+}
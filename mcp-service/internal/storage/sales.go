@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// GetSalesReport totals a restaurant's orders per day between q.From and
+// q.To, both inclusive. Days follow the restaurant's timezone, so an order
+// placed at 00:30 IST counts towards that IST day even though it is the
+// previous day in UTC. Cancelled orders are left out of the days and
+// counted by cancellation reason instead.
+func (db *DB) GetSalesReport(ctx context.Context, q models.SalesQuery) (*models.SalesReport, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r, err := db.GetRestaurantByID(ctx, q.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.SalesReport{RestaurantID: r.ID, Timezone: r.Timezone, From: q.From, To: q.To,
+		Days: []models.SalesDay{}, Cancelled: []models.SalesCancellations{}}
+
+	// The range is turned into instants in SQL so the index on created_at
+	// still applies; only the grouping converts each row
+	rows, err := db.QueryContext(ctx, db.schema.read(`
+		SELECT to_char(created_at AT TIME ZONE $2::text, 'YYYY-MM-DD') AS day, COUNT(*),
+			COALESCE(SUM(total_amount), 0), COALESCE(SUM(tax_amount), 0), COALESCE(SUM(discount), 0),
+			COALESCE(SUM(service_charge), 0), COALESCE(SUM(tip), 0), COALESCE(SUM(final_amount), 0)
+		FROM orders
+		WHERE restaurant_id = $1 AND status <> 'cancelled'
+			AND created_at >= $3::date::timestamp AT TIME ZONE $2::text
+			AND created_at < ($4::date + 1)::timestamp AT TIME ZONE $2::text
+		GROUP BY day
+		ORDER BY day
+	`, "orders"), r.ID, r.Timezone, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.SalesDay
+		if err := rows.Scan(&d.Date, &d.Orders, &d.Gross, &d.Tax, &d.Discount, &d.ServiceCharge, &d.Tips, &d.Net); err != nil {
+			return nil, err
+		}
+		report.Days = append(report.Days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, db.schema.read(`
+		SELECT COALESCE(NULLIF(cancellation_reason, ''), 'unknown') AS reason, COUNT(*), COALESCE(SUM(final_amount), 0)
+		FROM orders
+		WHERE restaurant_id = $1 AND status = 'cancelled'
+			AND created_at >= $3::date::timestamp AT TIME ZONE $2::text
+			AND created_at < ($4::date + 1)::timestamp AT TIME ZONE $2::text
+		GROUP BY reason
+		ORDER BY reason
+	`, "orders"), r.ID, r.Timezone, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.SalesCancellations
+		if err := rows.Scan(&c.Reason, &c.Orders, &c.Amount); err != nil {
+			return nil, err
+		}
+		report.Cancelled = append(report.Cancelled, c)
+	}
+	return report, rows.Err()
+}
+
+// GetTopSellingItems ranks a restaurant's menu items by units sold, then by
+// revenue. Cancelled orders don't count.
+func (db *DB) GetTopSellingItems(ctx context.Context, q models.TopItemsQuery) ([]models.ItemSales, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := db.GetRestaurantByID(ctx, q.RestaurantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT mi.id, mi.name, COALESCE(mi.category, ''), SUM(oi.quantity), COALESCE(SUM(oi.subtotal), 0)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN menu_items mi ON mi.id = oi.menu_item_id
+		WHERE o.restaurant_id = $1 AND o.status <> 'cancelled'
+			AND ($2::timestamptz IS NULL OR o.created_at >= $2)
+			AND ($3 = '' OR lower(mi.category) = lower($3))
+		GROUP BY mi.id, mi.name, mi.category
+		ORDER BY SUM(oi.quantity) DESC, SUM(oi.subtotal) DESC, mi.id
+		LIMIT $4
+	`, q.RestaurantID, sql.NullTime{Time: q.Since, Valid: !q.Since.IsZero()}, q.Category, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ItemSales{}
+	for rows.Next() {
+		var item models.ItemSales
+		if err := rows.Scan(&item.MenuItemID, &item.Name, &item.Category, &item.Units, &item.Revenue); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetRestaurantStats counts a restaurant's orders and available menu items
+// and totals its revenue
+func (db *DB) GetRestaurantStats(ctx context.Context, restaurantID int) (*models.RestaurantStats, error) {
+	if _, err := db.GetRestaurantByID(ctx, restaurantID); err != nil {
+		return nil, err
+	}
+
+	stats := &models.RestaurantStats{RestaurantID: restaurantID}
+	var lastOrderAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'cancelled'), COUNT(*) FILTER (WHERE status = 'pending'),
+			COALESCE(SUM(final_amount) FILTER (WHERE status <> 'cancelled'), 0), MAX(created_at)
+		FROM orders WHERE restaurant_id = $1
+	`, restaurantID).Scan(&stats.Orders, &stats.CancelledOrders, &stats.PendingOrders, &stats.Revenue, &lastOrderAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastOrderAt.Valid {
+		stats.LastOrderAt = &lastOrderAt.Time
+	}
+	stats.SetAverage()
+
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM menu_items WHERE restaurant_id = $1 AND available`,
+		restaurantID).Scan(&stats.AvailableMenuItems)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetOrderDigest gathers the numbers for a summary of one day of a
+// restaurant's orders, the day following the restaurant's timezone
+func (db *DB) GetOrderDigest(ctx context.Context, q models.DigestQuery) (*models.OrderDigest, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r, err := db.GetRestaurantByID(ctx, q.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	day, previous := q.Day(loc, time.Now())
+	d := models.NewOrderDigest(*r, day)
+
+	// Each query takes restaurant, timezone and date as $1 to $3 so they
+	// share this filter on the restaurant's day
+	const onDay = `o.restaurant_id = $1
+		AND o.created_at >= $3::date::timestamp AT TIME ZONE $2::text
+		AND o.created_at < ($3::date + 1)::timestamp AT TIME ZONE $2::text`
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(o.status, 'pending'), COUNT(*), COALESCE(SUM(o.final_amount), 0),
+			COUNT(*) FILTER (WHERE o.payment_status = 'refunded'), COALESCE(SUM(o.final_amount) FILTER (WHERE o.payment_status = 'refunded'), 0)
+		FROM orders o
+		WHERE `+onDay+`
+		GROUP BY 1`, r.ID, r.Timezone, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count, refunds int
+		var amount, refunded models.Money
+		if err := rows.Scan(&status, &count, &amount, &refunds, &refunded); err != nil {
+			return nil, err
+		}
+		d.ByStatus[status] = count
+		d.Orders += count
+		if status != models.OrderStatusCancelled {
+			d.Revenue += amount
+		}
+		d.Refunds += refunds
+		d.RefundedAmount += refunded
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(SUM(o.final_amount), 0) FROM orders o WHERE `+onDay+` AND o.status <> 'cancelled'`,
+		r.ID, r.Timezone, previous).Scan(&d.PreviousRevenue)
+	if err != nil {
+		return nil, err
+	}
+	if d.Orders == 0 {
+		return d, nil
+	}
+
+	err = db.QueryRowContext(ctx, `
+		SELECT EXTRACT(HOUR FROM o.created_at AT TIME ZONE $2::text)::int AS hour, COUNT(*)
+		FROM orders o
+		WHERE `+onDay+`
+		GROUP BY hour
+		ORDER BY COUNT(*) DESC, hour
+		LIMIT 1`, r.ID, r.Timezone, day).Scan(&d.BusiestHour, &d.BusiestHourOrders)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		WITH day AS (SELECT o.id, o.customer_name, o.final_amount FROM orders o WHERE `+onDay+` AND o.status <> 'cancelled')
+		SELECT id, customer_name, final_amount FROM day
+		WHERE final_amount >= $4 * (SELECT AVG(final_amount) FROM day)
+		ORDER BY final_amount DESC, id
+		LIMIT $5`, r.ID, r.Timezone, day, models.LargeOrderFactor, models.MaxLargeOrders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o models.DigestOrder
+		if err := rows.Scan(&o.ID, &o.CustomerName, &o.FinalAmount); err != nil {
+			return nil, err
+		}
+		d.LargeOrders = append(d.LargeOrders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT DISTINCT mi.name
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN menu_items mi ON mi.id = oi.menu_item_id
+		WHERE `+onDay+` AND o.status <> 'cancelled' AND NOT mi.available
+		ORDER BY mi.name`, r.ID, r.Timezone, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		d.SoldOut = append(d.SoldOut, name)
+	}
+	return d, rows.Err()
+}
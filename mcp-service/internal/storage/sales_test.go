@@ -0,0 +1,61 @@
+package storage_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// TestSalesReportSplitsDaysAtLocalMidnight places orders either side of
+// midnight IST; 23:50 on the 9th and 00:10 on the 10th are the same UTC day
+func TestSalesReportSplitsDaysAtLocalMidnight(t *testing.T) {
+	db := storagetest.DB(t)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min, sec int) time.Time { return time.Date(2026, 3, day, hour, min, sec, 0, ist) }
+
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(at(8, 23, 59, 59)).Build(t, db) // before the range
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(at(9, 0, 0, 0)).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 2).WithCreatedAt(at(9, 23, 50, 0)).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(at(10, 0, 10, 0)).Build(t, db)
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(at(10, 23, 59, 59)).Build(t, db)
+	unknown := storagetest.NewOrder(r).WithItem(0, 5).WithStatus(models.OrderStatusCancelled).WithCreatedAt(at(10, 12, 0, 0)).Build(t, db)
+	outOfStock := storagetest.NewOrder(r).WithItem(0, 3).WithCreatedAt(at(9, 13, 0, 0)).Build(t, db)
+	if _, err := db.CancelOrder(t.Context(), outOfStock.ID, models.CancelReasonOutOfStock, "", "staff"); err != nil {
+		t.Fatal(err)
+	}
+	storagetest.NewOrder(r).WithItem(0, 1).WithCreatedAt(at(11, 0, 0, 0)).Build(t, db) // after the range
+
+	report, err := db.GetSalesReport(t.Context(), models.SalesQuery{RestaurantID: r.Restaurant.ID, From: "2026-03-09", To: "2026-03-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	price := r.Menu[0].Price
+	want := []struct {
+		date   string
+		orders int
+		gross  models.Money
+	}{{"2026-03-09", 2, 3 * price}, {"2026-03-10", 2, 2 * price}}
+	if len(report.Days) != len(want) {
+		t.Fatalf("days = %+v, want %d", report.Days, len(want))
+	}
+	for i, w := range want {
+		d := report.Days[i]
+		if d.Date != w.date || d.Orders != w.orders || d.Gross != w.gross {
+			t.Errorf("day %d = %+v, want %s with %d orders, gross %v", i, d, w.date, w.orders, w.gross)
+		}
+	}
+	wantCancelled := []models.SalesCancellations{
+		{Reason: models.CancelReasonOutOfStock, Orders: 1, Amount: outOfStock.FinalAmount},
+		{Reason: "unknown", Orders: 1, Amount: unknown.FinalAmount},
+	}
+	if !reflect.DeepEqual(report.Cancelled, wantCancelled) {
+		t.Errorf("cancelled = %+v, want %+v", report.Cancelled, wantCancelled)
+	}
+}
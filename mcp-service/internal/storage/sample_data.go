@@ -0,0 +1,73 @@
+package storage
+
+import "github.com/vishalk17/mcp-service-restaurant/internal/models"
+
+// SampleRestaurant is a demo restaurant and its menu
+type SampleRestaurant struct {
+	Restaurant models.Restaurant
+	Menu       []models.MenuItem
+}
+
+// SampleData are the demo restaurants a database without restaurants is
+// seeded with, by SeedSampleData and by the SQLite backend alike
+var SampleData = []SampleRestaurant{
+	{
+		Restaurant: models.Restaurant{Name: "Taj Mahal Restaurant", Address: "Connaught Place, New Delhi", PhoneNumber: "+91-11-12345678", CuisineType: "North Indian"},
+		Menu: []models.MenuItem{
+			{Name: "Butter Chicken", Description: "Tender chicken in a creamy tomato and butter sauce", Price: 350, Category: "Main Course", DietaryType: "non_vegetarian", SpiceLevel: "medium"},
+			{Name: "Paneer Tikka Masala", Description: "Grilled paneer in rich, spiced tomato gravy", Price: 300, Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "medium"},
+			{Name: "Dal Makhani", Description: "Slow-cooked black lentils with butter and cream", Price: 250, Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "mild"},
+			{Name: "Gulab Jamun", Description: "Milk dumplings soaked in rose-flavoured syrup", Price: 120, Category: "Desserts", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		},
+	},
+	{
+		Restaurant: models.Restaurant{Name: "Surya Mahal", Address: "Linking Road, Mumbai", PhoneNumber: "+91-22-87654321", CuisineType: "South Indian"},
+		Menu: []models.MenuItem{
+			{Name: "Masala Dosa", Description: "Crispy fermented crepe filled with spiced potatoes", Price: 150, Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "mild"},
+			{Name: "Idli Sambar", Description: "Steamed rice cakes served with lentil curry", Price: 100, Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "mild"},
+			{Name: "Vada Pav", Description: "Spiced potato fritter in a soft bun", Price: 60, Category: "Street Food", DietaryType: "vegetarian", SpiceLevel: "hot"},
+			{Name: "Filter Coffee", Description: "Traditional South Indian coffee with chicory", Price: 50, Category: "Beverages", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		},
+	},
+	{
+		Restaurant: models.Restaurant{Name: "Hyderabad House", Address: "Banjara Hills, Hyderabad", PhoneNumber: "+91-40-23456789", CuisineType: "Hyderabadi"},
+		Menu: []models.MenuItem{
+			{Name: "Hyderabadi Chicken Biryani", Description: "Fragrant basmati rice with marinated chicken", Price: 400, Category: "Main Course", DietaryType: "non_vegetarian", SpiceLevel: "hot"},
+			{Name: "Mirchi Ka Salan", Description: "Green chilies in peanut and sesame gravy", Price: 200, Category: "Main Course", DietaryType: "vegetarian", SpiceLevel: "hot"},
+			{Name: "Double Ka Meetha", Description: "Hyderabadi bread pudding", Price: 150, Category: "Desserts", DietaryType: "vegetarian", SpiceLevel: "mild"},
+		},
+	},
+}
+
+// SampleOrderItem is a quantity of a sample menu item, named
+type SampleOrderItem struct {
+	Name     string
+	Quantity int
+}
+
+// SampleOrder is a demo order. It names its restaurant and menu items, as
+// their IDs are only known once they are seeded.
+type SampleOrder struct {
+	Restaurant    string
+	CustomerName  string
+	CustomerPhone string
+	OrderType     string
+	Items         []SampleOrderItem
+}
+
+// SampleOrders are the demo orders a database without orders is seeded
+// with, so list_orders has something to show
+var SampleOrders = []SampleOrder{
+	{
+		Restaurant: "Taj Mahal Restaurant", CustomerName: "Priya Sharma", CustomerPhone: "+91 98100 11223", OrderType: models.OrderTypeDineIn,
+		Items: []SampleOrderItem{{"Butter Chicken", 1}, {"Dal Makhani", 1}, {"Gulab Jamun", 2}},
+	},
+	{
+		Restaurant: "Surya Mahal", CustomerName: "Arjun Iyer", CustomerPhone: "+91 98200 44556", OrderType: models.OrderTypeTakeaway,
+		Items: []SampleOrderItem{{"Masala Dosa", 2}, {"Filter Coffee", 2}},
+	},
+	{
+		Restaurant: "Hyderabad House", CustomerName: "Fatima Khan", CustomerPhone: "+91 98490 77889", OrderType: models.OrderTypeDineIn,
+		Items: []SampleOrderItem{{"Hyderabadi Chicken Biryani", 2}, {"Mirchi Ka Salan", 1}},
+	},
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"regexp"
+)
+
+// optionalColumn is a column added after the original schema. During a
+// rolling deploy a binary can meet a database that doesn't have it yet;
+// reads then use Fallback in its place instead of failing the whole query.
+type optionalColumn struct {
+	Table    string
+	Column   string
+	Fallback string // SQL expression of the column's type
+}
+
+var optionalColumns = []optionalColumn{
+	{"restaurants", "email", "NULL::text"},
+	{"restaurants", "notification_preferences", `'{"notify_on_order": true, "notify_on_low_stock": true}'::jsonb`},
+	{"restaurants", "timezone", "'Asia/Kolkata'::text"},
+	{"restaurants", "updated_at", "NULL::timestamptz"},
+	{"restaurants", "public_menu_enabled", "false"},
+	{"restaurants", "auto_confirm_orders", "false"},
+	{"restaurants", "tax_rate", "NULL::numeric"},
+	{"restaurants", "service_charge_percent", "NULL::numeric"},
+	{"restaurants", "active", "true"},
+	{"restaurants", "version", "1"},
+	{"menu_items", "effective_from", "NULL::date"},
+	{"menu_items", "effective_to", "NULL::date"},
+	{"menu_items", "version", "1"},
+	{"menu_items", "updated_at", "NULL::timestamptz"},
+	{"menu_items", "stock_quantity", "NULL::integer"},
+	{"orders", "order_type", "'dine_in'::text"},
+	{"orders", "delivery_person_name", "NULL::text"},
+	{"orders", "delivery_person_phone", "NULL::text"},
+	{"orders", "dispatched_at", "NULL::timestamptz"},
+	{"orders", "delivered_at", "NULL::timestamptz"},
+	{"orders", "cancellation_reason", "NULL::text"},
+	{"orders", "cancellation_note", "NULL::text"},
+	{"orders", "cancelled_by", "NULL::text"},
+	{"orders", "cancelled_at", "NULL::timestamptz"},
+	{"orders", "discount_percent", "NULL::numeric"},
+	{"orders", "service_charge", "0::numeric"},
+	{"orders", "tip", "0::numeric"},
+	{"orders", "split_from", "NULL::integer"},
+	{"orders", "merged_from", "NULL::integer[]"},
+	{"order_status_history", "note", "NULL::text"},
+	{"order_items", "pricing_rule_id", "NULL::integer"},
+	{"order_items", "from_stock", "false"},
+	{"audit_log", "outcome", "NULL::text"},
+}
+
+// optionalTables back whole features that are skipped when the table is
+// missing
+var optionalTables = []string{"menu_item_price_history", "order_feedback", "change_requests", "pricing_rules", "shadowed_actions", "order_status_history", "audit_log", "orders_archive"}
+
+// schemaInfo records which optional columns and tables the database lacks.
+// A nil *schemaInfo, as on a DB from Wrap, assumes the schema is current.
+type schemaInfo struct {
+	missing map[string][]missingColumn // by table
+	tables  map[string]bool            // optional tables present
+}
+
+type missingColumn struct {
+	optionalColumn
+	pattern *regexp.Regexp // the column name as a whole word
+}
+
+// DetectSchema looks up which optional columns and tables exist and logs a
+// warning for each one missing. NewDB calls it after migrating.
+func (db *DB) DetectSchema(ctx context.Context) error {
+	present := map[string]bool{}
+	rows, err := db.QueryContext(ctx,
+		`SELECT table_name || '.' || column_name FROM information_schema.columns WHERE table_schema = current_schema()`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		present[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	info := &schemaInfo{missing: map[string][]missingColumn{}, tables: map[string]bool{}}
+	for _, c := range optionalColumns {
+		if !present[c.Table+"."+c.Column] {
+			info.missing[c.Table] = append(info.missing[c.Table], missingColumn{c, regexp.MustCompile(`\b` + c.Column + `\b`)})
+			log.Printf("Warning: schema has no %s.%s; reading it as %s until migrations run", c.Table, c.Column, c.Fallback)
+		}
+	}
+	for _, table := range optionalTables {
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+			return err
+		}
+		info.tables[table] = exists
+		if !exists {
+			log.Printf("Warning: schema has no %s table; features using it are disabled until migrations run", table)
+		}
+	}
+	db.schema = info
+	return nil
+}
+
+// hasTable reports whether an optional table exists
+func (s *schemaInfo) hasTable(table string) bool {
+	return s == nil || s.tables[table]
+}
+
+// hasColumn reports whether an optional column exists
+func (s *schemaInfo) hasColumn(table, column string) bool {
+	if s == nil {
+		return true
+	}
+	for _, c := range s.missing[table] {
+		if c.Column == column {
+			return false
+		}
+	}
+	return true
+}
+
+// read rewrites a read-only query over the given tables so missing optional
+// columns are replaced by their fallbacks. Never use it on writes: a SET or
+// INSERT column list can't take an expression.
+func (s *schemaInfo) read(query string, tables ...string) string {
+	if s == nil {
+		return query
+	}
+	for _, table := range tables {
+		for _, c := range s.missing[table] {
+			query = c.pattern.ReplaceAllLiteralString(query, c.Fallback)
+		}
+	}
+	return query
+}
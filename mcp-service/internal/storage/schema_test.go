@@ -0,0 +1,126 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// migratedTo returns a DB over a schema migrated only up to version, as a
+// new binary meets it mid rolling deploy
+func migratedTo(t *testing.T, version int) *storage.DB {
+	t.Helper()
+	all, err := storage.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sql.Open("postgres", storagetest.ConnString(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for _, m := range all[:version] {
+		if _, err := conn.Exec(m.SQL); err != nil {
+			t.Fatalf("migration %04d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	db := storage.Wrap(conn)
+	if err := db.DetectSchema(t.Context()); err != nil {
+		t.Fatalf("DetectSchema at version %d: %v", version, err)
+	}
+	return db
+}
+
+func TestRunsAgainstThePreviousMigration(t *testing.T) {
+	all, err := storage.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := migratedTo(t, len(all)-1)
+	ctx := t.Context()
+
+	r := storagetest.NewRestaurant().WithMenu(2).Build(t, db)
+	o := storagetest.NewOrder(r).WithItem(0, 2).WithItem(1, 1).Build(t, db)
+	got, err := db.GetOrderByID(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if len(got.OrderItems) != 2 || got.TotalAmount != 2*r.Menu[0].Price+r.Menu[1].Price {
+		t.Errorf("order = %+v, want both items", got)
+	}
+	if _, total, err := db.GetAllRestaurants(ctx, models.Page{Limit: 10}, ""); err != nil || total != 1 {
+		t.Errorf("GetAllRestaurants = %d, %v", total, err)
+	}
+
+	// The last migration added price history, which is off until it runs
+	item := r.Menu[0]
+	item.Price++
+	if err := db.UpdateMenuItem(ctx, &item, ""); err != nil {
+		t.Errorf("UpdateMenuItem without price history: %v", err)
+	}
+	if _, _, err := db.GetPriceHistory(ctx, models.PriceHistoryQuery{ItemID: item.ID}, models.Page{Limit: 10}); !errors.Is(err, storage.ErrPriceHistoryUnavailable) {
+		t.Errorf("GetPriceHistory = %v, want ErrPriceHistoryUnavailable", err)
+	}
+}
+
+func TestReadsFallBackForMissingColumns(t *testing.T) {
+	db := migratedTo(t, 1)
+	r := storagetest.NewRestaurant().WithMenu(1).Build(t, db)
+
+	// As on a database from before restaurants.timezone was added
+	if _, err := db.Exec(`ALTER TABLE restaurants DROP COLUMN timezone CASCADE`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DetectSchema(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetRestaurantByID(t.Context(), r.Restaurant.ID)
+	if err != nil {
+		t.Fatalf("GetRestaurantByID without timezone: %v", err)
+	}
+	if got.Timezone != "Asia/Kolkata" {
+		t.Errorf("Timezone = %q, want the Asia/Kolkata fallback", got.Timezone)
+	}
+	menu, err := db.GetMenuByRestaurantID(t.Context(), r.Restaurant.ID, false)
+	if err != nil || len(menu) != 1 {
+		t.Errorf("GetMenuByRestaurantID = %+v, %v", menu, err)
+	}
+}
+
+// TestColumnListsMatchTheSchema checks each column registry names exactly the
+// columns of its migrated table, so a migration adding a column fails here
+// until the model reads it
+func TestColumnListsMatchTheSchema(t *testing.T) {
+	db := storagetest.DB(t)
+	for table, columns := range storage.ReadColumns {
+		rows, err := db.QueryContext(t.Context(),
+			`SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1`, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			schema = append(schema, name)
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		registry := slices.Clone(columns)
+		slices.Sort(registry)
+		slices.Sort(schema)
+		if !slices.Equal(registry, schema) {
+			t.Errorf("%s: registry reads %v, table has %v", table, registry, schema)
+		}
+	}
+}
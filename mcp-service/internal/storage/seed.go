@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// SeedFromEnv reads SEED_SAMPLE_DATA, whether a server seeds the sample data
+// on startup, falling back to byDefault when it is unset
+func SeedFromEnv(byDefault bool) (bool, error) {
+	v := os.Getenv("SEED_SAMPLE_DATA")
+	if v == "" {
+		return byDefault, nil
+	}
+	seed, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid SEED_SAMPLE_DATA: %q", v)
+	}
+	return seed, nil
+}
+
+// SeedSampleData adds SampleData to a database without restaurants and
+// SampleOrders to one without orders. Each table is checked on its own, so
+// running it again adds nothing, and a database with restaurants of its own
+// only gets sample orders for the sample restaurants it has.
+func (db *DB) SeedSampleData(ctx context.Context) error {
+	if err := db.seedSampleRestaurants(ctx); err != nil {
+		return err
+	}
+	return SeedSampleOrders(ctx, db)
+}
+
+// seedLockID is the advisory lock key that serializes seeding across
+// replicas starting against the same empty database
+const seedLockID = 7270511
+
+// seedSampleRestaurants adds SampleData to a database without restaurants.
+// It runs in one transaction under an advisory lock and re-checks emptiness
+// after taking it, so replicas seeding together seed once; inserts are
+// also skipped per restaurant name in case the lock is ever bypassed.
+func (db *DB) seedSampleRestaurants(ctx context.Context) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, seedLockID); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM restaurants").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, r := range SampleData {
+		restaurant := r.Restaurant
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO restaurants (name, address, phone_number, cuisine_type, notification_preferences)
+			SELECT $1::text, $2::text, $3::text, $4::text, $5::jsonb
+			WHERE NOT EXISTS (SELECT 1 FROM restaurants WHERE name = $1)
+			RETURNING id`,
+			restaurant.Name, restaurant.Address, restaurant.PhoneNumber, restaurant.CuisineType, models.DefaultNotificationPreferences(),
+		).Scan(&restaurant.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range r.Menu {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO menu_items (restaurant_id, name, description, price, category, dietary_type, spice_level, available)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, true)`,
+				restaurant.ID, item.Name, item.Description, item.Price, item.Category, item.DietaryType, item.SpiceLevel)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Println("Sample restaurants seeded successfully")
+	return nil
+}
+
+// SeedSampleOrders places SampleOrders through s when it has no orders,
+// skipping those whose restaurant or menu items it doesn't have. Each order
+// carries an idempotency key, so stores seeded at once place it once.
+func SeedSampleOrders(ctx context.Context, s Store) error {
+	_, total, err := s.GetOrders(ctx, models.OrderFilter{}, models.Page{Limit: 1})
+	if err != nil || total > 0 {
+		return err
+	}
+	restaurants, _, err := s.GetAllRestaurants(ctx, models.Page{Limit: models.MaxPageSize}, models.RestaurantSortID)
+	if err != nil {
+		return err
+	}
+	restaurantIDs := map[string]int{}
+	for _, r := range restaurants {
+		if _, ok := restaurantIDs[r.Name]; !ok {
+			restaurantIDs[r.Name] = r.ID
+		}
+	}
+
+	placed := 0
+	for i, sample := range SampleOrders {
+		order, err := sampleOrder(ctx, s, restaurantIDs, sample)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			continue
+		}
+		order.IdempotencyKey = fmt.Sprintf("sample-order-%d", i+1)
+		if err := s.CreateOrder(ctx, order); err != nil {
+			return fmt.Errorf("sample order %d: %w", i+1, err)
+		}
+		placed++
+	}
+	if placed > 0 {
+		log.Printf("Seeded %d sample orders", placed)
+	}
+	return nil
+}
+
+// sampleOrder resolves the names in sample to an order, nil when a name is
+// missing from s
+func sampleOrder(ctx context.Context, s Store, restaurantIDs map[string]int, sample SampleOrder) (*models.Order, error) {
+	restaurantID, ok := restaurantIDs[sample.Restaurant]
+	if !ok {
+		return nil, nil
+	}
+	menu, err := s.GetMenuByRestaurantID(ctx, restaurantID, false)
+	if err != nil {
+		return nil, err
+	}
+	itemIDs := map[string]int{}
+	for _, m := range menu {
+		itemIDs[m.Name] = m.ID
+	}
+	order := &models.Order{RestaurantID: restaurantID, CustomerName: sample.CustomerName, CustomerPhone: sample.CustomerPhone, OrderType: sample.OrderType}
+	for _, item := range sample.Items {
+		id, ok := itemIDs[item.Name]
+		if !ok {
+			return nil, nil
+		}
+		order.OrderItems = append(order.OrderItems, models.OrderItem{MenuItemID: id, Quantity: item.Quantity})
+	}
+	return order, nil
+}
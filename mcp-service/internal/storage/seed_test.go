@@ -0,0 +1,74 @@
+package storage_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage/storagetest"
+)
+
+// TestConcurrentStartupSeedsOnce starts replicas against one empty database
+// at once, each migrating and seeding it as the servers do with
+// SEED_SAMPLE_DATA set
+func TestConcurrentStartupSeedsOnce(t *testing.T) {
+	connStr := storagetest.ConnString(t)
+
+	const replicas = 4
+	dbs := make([]*storage.DB, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dbs[i], errs[i] = storage.NewDB(connStr, storage.DefaultPoolConfig(), storage.DefaultConnectRetry())
+			if errs[i] == nil {
+				errs[i] = dbs[i].SeedSampleData(t.Context())
+			}
+		}()
+	}
+	wg.Wait()
+	t.Cleanup(func() {
+		for _, db := range dbs {
+			if db != nil {
+				db.Close()
+			}
+		}
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("replica %d: %v", i, err)
+		}
+	}
+
+	restaurants, total, err := dbs[0].GetAllRestaurants(t.Context(), models.Page{Limit: 100}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Errorf("%d restaurants after concurrent seeding, want the 3 demo restaurants: %+v", total, restaurants)
+	}
+	var items int
+	if err := dbs[0].QueryRow(`SELECT COUNT(*) FROM menu_items`).Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+	if items != 11 {
+		t.Errorf("%d menu items after concurrent seeding, want the 11 demo items", items)
+	}
+	if _, orders, err := dbs[0].GetOrders(t.Context(), models.OrderFilter{}, models.Page{Limit: 1}); err != nil || orders != len(storage.SampleOrders) {
+		t.Errorf("%d orders after concurrent seeding, %v; want the %d sample orders", orders, err, len(storage.SampleOrders))
+	}
+}
+
+func TestNewDBDoesNotSeed(t *testing.T) {
+	db, err := storage.NewDB(storagetest.ConnString(t), storage.DefaultPoolConfig(), storage.DefaultConnectRetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, total, err := db.GetAllRestaurants(t.Context(), models.Page{Limit: 1}, ""); err != nil || total != 0 {
+		t.Errorf("%d restaurants after NewDB, %v; want none without SeedSampleData", total, err)
+	}
+}
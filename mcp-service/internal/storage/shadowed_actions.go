@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrShadowedActionsUnavailable is returned while the database predates the
+// shadowed_actions migration
+var ErrShadowedActionsUnavailable = errors.New("shadow mode is not available until the database is migrated")
+
+// ErrShadowReplayed is wrapped by ClaimShadowedAction when the action was
+// already replayed or is being replayed
+var ErrShadowReplayed = errors.New("shadowed action was already replayed")
+
+const shadowedActionColumns = `id, tool, arguments, effect, status, requested_by, created_at, COALESCE(replayed_by, ''), replayed_at, COALESCE(replay_result, '')`
+
+func scanShadowedAction(row interface{ Scan(...interface{}) error }, a *models.ShadowedAction) error {
+	var arguments []byte
+	var replayedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.Tool, &arguments, &a.Effect, &a.Status, &a.RequestedBy, &a.CreatedAt, &a.ReplayedBy, &replayedAt, &a.ReplayResult); err != nil {
+		return err
+	}
+	if replayedAt.Valid {
+		a.ReplayedAt = &replayedAt.Time
+	}
+	return json.Unmarshal(arguments, &a.Arguments)
+}
+
+// RecordShadowedAction stores a pending shadowed action, filling in ID,
+// Status and CreatedAt
+func (db *DB) RecordShadowedAction(ctx context.Context, a *models.ShadowedAction) error {
+	if !db.schema.hasTable("shadowed_actions") {
+		return ErrShadowedActionsUnavailable
+	}
+	arguments, err := json.Marshal(a.Arguments)
+	if err != nil {
+		return err
+	}
+	a.Status = models.ShadowStatusPending
+	return db.QueryRowContext(ctx,
+		`INSERT INTO shadowed_actions (tool, arguments, effect, requested_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		a.Tool, arguments, a.Effect, a.RequestedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+// ListShadowedActions returns one page of the shadowed actions with status,
+// or all of them when status is empty, newest first, and the total count
+func (db *DB) ListShadowedActions(ctx context.Context, status string, page models.Page) ([]models.ShadowedAction, int, error) {
+	if err := models.ValidateShadowStatus(status); err != nil {
+		return nil, 0, err
+	}
+	if !db.schema.hasTable("shadowed_actions") {
+		return nil, 0, ErrShadowedActionsUnavailable
+	}
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM shadowed_actions WHERE $1 = '' OR status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+shadowedActionColumns+` FROM shadowed_actions WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		status, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	actions := []models.ShadowedAction{}
+	for rows.Next() {
+		var a models.ShadowedAction
+		if err := scanShadowedAction(rows, &a); err != nil {
+			return nil, 0, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, total, rows.Err()
+}
+
+// ClaimShadowedAction marks a pending or failed shadowed action as being
+// replayed by replayer and returns it, so two admins can't replay it at
+// once. Any other status wraps ErrShadowReplayed.
+func (db *DB) ClaimShadowedAction(ctx context.Context, id int, replayer string) (*models.ShadowedAction, error) {
+	if !db.schema.hasTable("shadowed_actions") {
+		return nil, ErrShadowedActionsUnavailable
+	}
+	var a models.ShadowedAction
+	err := scanShadowedAction(db.QueryRowContext(ctx,
+		`UPDATE shadowed_actions SET status = $1, replayed_by = $2, replayed_at = NOW()
+		WHERE id = $3 AND status IN ($4, $5)
+		RETURNING `+shadowedActionColumns,
+		models.ShadowStatusReplaying, replayer, id, models.ShadowStatusPending, models.ShadowStatusFailed), &a)
+	if err != sql.ErrNoRows {
+		if err != nil {
+			return nil, err
+		}
+		return &a, nil
+	}
+
+	var status string
+	err = db.QueryRowContext(ctx, `SELECT status FROM shadowed_actions WHERE id = $1`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("shadowed action with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("shadowed action %d is %s: %w", id, status, ErrShadowReplayed)
+}
+
+// FinishShadowedAction records the result of replaying a claimed action.
+// A failed replay leaves it open to another attempt.
+func (db *DB) FinishShadowedAction(ctx context.Context, id int, failed bool, result string) error {
+	if !db.schema.hasTable("shadowed_actions") {
+		return ErrShadowedActionsUnavailable
+	}
+	status := models.ShadowStatusReplayed
+	if failed {
+		status = models.ShadowStatusFailed
+	}
+	_, err := db.ExecContext(ctx, `UPDATE shadowed_actions SET status = $1, replay_result = $2 WHERE id = $3`, status, result, id)
+	return err
+}
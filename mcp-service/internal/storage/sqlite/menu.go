@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+const menuItemColumns = `id, restaurant_id, name, description, price, category, dietary_type, spice_level, available,
+	effective_from, effective_to, version, created_at, updated_at, stock_quantity`
+
+func scanMenuItem(row rowScanner, m *models.MenuItem) error {
+	return row.Scan(&m.ID, &m.RestaurantID, &m.Name, &m.Description, &m.Price, &m.Category, &m.DietaryType, &m.SpiceLevel, &m.Available,
+		&m.EffectiveFrom, &m.EffectiveTo, &m.Version, &m.CreatedAt, &m.UpdatedAt, &m.StockQuantity)
+}
+
+// queryer is what reads need of a *sql.DB or *sql.Tx
+type queryer interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+func queryMenuItems(ctx context.Context, q queryer, query string, args ...interface{}) ([]models.MenuItem, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.MenuItem{}
+	for rows.Next() {
+		var m models.MenuItem
+		if err := scanMenuItem(rows, &m); err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+// GetMenuByRestaurantID returns the menu items of a restaurant that are
+// available and effective today in the restaurant's timezone
+func (s *Store) GetMenuByRestaurantID(ctx context.Context, restaurantID int, includeUnavailable bool) ([]models.MenuItem, error) {
+	return s.GetMenuAsOf(ctx, restaurantID, "", includeUnavailable)
+}
+
+// GetMenuAsOf returns the menu items of a restaurant that are effective on
+// asOf (YYYY-MM-DD), or today in the restaurant's timezone when asOf is
+// empty. Unavailable items are left out unless includeUnavailable.
+func (s *Store) GetMenuAsOf(ctx context.Context, restaurantID int, asOf string, includeUnavailable bool) ([]models.MenuItem, error) {
+	day := asOf
+	if day != "" {
+		if _, err := time.Parse(models.DateLayout, day); err != nil {
+			return nil, fmt.Errorf("invalid as_of %q: expected YYYY-MM-DD", asOf)
+		}
+	} else {
+		var tz string
+		if err := s.db.QueryRowContext(ctx, `SELECT timezone FROM restaurants WHERE id = ?`, restaurantID).Scan(&tz); err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		day = models.LocalTime(tz, time.Now()).Format(models.DateLayout)
+	}
+	// YYYY-MM-DD text compares in date order
+	return queryMenuItems(ctx, s.db, `
+		SELECT `+menuItemColumns+` FROM menu_items
+		WHERE restaurant_id = ? AND (available OR ?)
+			AND (effective_from = '' OR effective_from <= ?)
+			AND (effective_to = '' OR effective_to >= ?)
+		ORDER BY category, name`, restaurantID, includeUnavailable, day, day)
+}
+
+// SearchMenuItems returns the items of a restaurant's current menu that
+// match every filter of q
+func (s *Store) SearchMenuItems(ctx context.Context, q models.MenuSearch) ([]models.MenuItem, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	menu, err := s.GetMenuAsOf(ctx, q.RestaurantID, "", false)
+	if err != nil {
+		return nil, err
+	}
+	items := []models.MenuItem{}
+	for _, m := range menu {
+		if q.Matches(m) {
+			items = append(items, m)
+		}
+	}
+	return items, nil
+}
+
+// GetMenuItemByID returns a single menu item
+func (s *Store) GetMenuItemByID(ctx context.Context, id int) (*models.MenuItem, error) {
+	var m models.MenuItem
+	err := scanMenuItem(s.db.QueryRowContext(ctx, `SELECT `+menuItemColumns+` FROM menu_items WHERE id = ?`, id), &m)
+	if err == sql.ErrNoRows {
+		return nil, notFound("menu item", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMenuItemsByIDs returns the menu items with the given IDs keyed by ID,
+// leaving out IDs without one
+func (s *Store) GetMenuItemsByIDs(ctx context.Context, ids []int) (map[int]models.MenuItem, error) {
+	return menuItemsByIDs(ctx, s.db, ids)
+}
+
+func menuItemsByIDs(ctx context.Context, q queryer, ids []int) (map[int]models.MenuItem, error) {
+	items := make(map[int]models.MenuItem, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+	list, err := queryMenuItems(ctx, q, `SELECT `+menuItemColumns+` FROM menu_items WHERE id IN (`+placeholders(len(ids))+`)`, ints(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		items[m.ID] = m
+	}
+	return items, nil
+}
@@ -0,0 +1,475 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+const orderColumns = `id, restaurant_id, customer_name, customer_phone, order_type, status, total_amount, tax_amount, discount, discount_percent,
+	service_charge, tip, final_amount, payment_status, payment_method, billing_address, delivery_person_name, delivery_person_phone,
+	dispatched_at, delivered_at, cancellation_reason, cancellation_note, cancelled_by, cancelled_at, created_at, updated_at`
+
+func scanOrder(row rowScanner, o *models.Order) error {
+	return row.Scan(&o.ID, &o.RestaurantID, &o.CustomerName, &o.CustomerPhone, &o.OrderType, &o.Status, &o.TotalAmount, &o.TaxAmount, &o.Discount, &o.DiscountPercent,
+		&o.ServiceCharge, &o.Tip, &o.FinalAmount, &o.PaymentStatus, &o.PaymentMethod, &o.BillingAddress, &o.DeliveryPersonName, &o.DeliveryPersonPhone,
+		&o.DispatchedAt, &o.DeliveredAt, &o.CancellationReason, &o.CancellationNote, &o.CancelledBy, &o.CancelledAt, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// newestFirst orders orders as the Store methods return them
+const newestFirst = ` ORDER BY created_at DESC, id DESC`
+
+// queryOrders returns the orders matching where, which may go on to order
+// and limit them, with their items and the items' menu items
+func queryOrders(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.Order, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	orders := []models.Order{}
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	ids := make([]int, len(orders))
+	byID := make(map[int]*models.Order, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+		byID[orders[i].ID] = &orders[i]
+		orders[i].OrderItems = []models.OrderItem{}
+	}
+	items, err := queryOrderItems(ctx, q, `order_id IN (`+placeholders(len(ids))+`)`, ints(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		o := byID[item.OrderID]
+		o.OrderItems = append(o.OrderItems, item)
+	}
+	return orders, nil
+}
+
+// queryOrderItems returns the order items matching where, in insertion
+// order, with their menu items
+func queryOrderItems(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.OrderItem, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, order_id, menu_item_id, quantity, price, notes, subtotal, from_stock FROM order_items WHERE `+where+` ORDER BY order_id, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	items := []models.OrderItem{}
+	for rows.Next() {
+		var oi models.OrderItem
+		if err := rows.Scan(&oi.ID, &oi.OrderID, &oi.MenuItemID, &oi.Quantity, &oi.Price, &oi.Notes, &oi.Subtotal, &oi.FromStock); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, oi)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var menuIDs []int
+	for _, oi := range items {
+		if !slices.Contains(menuIDs, oi.MenuItemID) {
+			menuIDs = append(menuIDs, oi.MenuItemID)
+		}
+	}
+	menu, err := menuItemsByIDs(ctx, q, menuIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if m, ok := menu[items[i].MenuItemID]; ok {
+			items[i].MenuItem = &m
+		}
+	}
+	return items, nil
+}
+
+// GetAllOrders returns one page of orders, newest first, with their items
+func (s *Store) GetAllOrders(ctx context.Context, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{}, page)
+}
+
+// GetOrdersByRestaurantID returns one page of a restaurant's orders, newest
+// first, with their items
+func (s *Store) GetOrdersByRestaurantID(ctx context.Context, restaurantID int, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{RestaurantID: restaurantID}, page)
+}
+
+// GetOrdersByStatus returns one page of the orders in any of the given
+// statuses, newest first, with their items
+func (s *Store) GetOrdersByStatus(ctx context.Context, statuses []string, page models.Page) ([]models.Order, int, error) {
+	return s.GetOrders(ctx, models.OrderFilter{Statuses: statuses}, page)
+}
+
+// GetOrders returns one page of the orders matching f, newest first, with
+// their items, and how many match in all
+func (s *Store) GetOrders(ctx context.Context, f models.OrderFilter, page models.Page) ([]models.Order, int, error) {
+	if f.RestaurantID != 0 {
+		if _, err := s.GetRestaurantByID(ctx, f.RestaurantID); err != nil {
+			return nil, 0, err
+		}
+	}
+	conds := []string{"1 = 1"}
+	var args []interface{}
+	if f.RestaurantID != 0 {
+		conds, args = append(conds, "restaurant_id = ?"), append(args, f.RestaurantID)
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]interface{}, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = status
+		}
+		conds, args = append(conds, "status IN ("+placeholders(len(statuses))+")"), append(args, statuses...)
+	}
+	if !f.CreatedFrom.IsZero() {
+		conds, args = append(conds, "created_at >= ?"), append(args, f.CreatedFrom.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		conds, args = append(conds, "created_at < ?"), append(args, f.CreatedBefore.UTC())
+	}
+	where := strings.Join(conds, " AND ")
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	orders, err := queryOrders(ctx, s.db, where+newestFirst+` LIMIT ? OFFSET ?`, append(args, limit(page), page.Offset)...)
+	return orders, total, err
+}
+
+// GetOrdersByCustomerPhone returns one page of the orders placed with a
+// phone number, compared by digits, newest first, and a summary of all of
+// them
+func (s *Store) GetOrdersByCustomerPhone(ctx context.Context, phone string, page models.Page) ([]models.Order, models.CustomerSummary, error) {
+	var summary models.CustomerSummary
+	if phone = models.NormalizePhone(phone); phone == "" {
+		return nil, summary, fmt.Errorf("customer phone must contain digits")
+	}
+	all, err := queryOrders(ctx, s.db, `customer_phone != ''`+newestFirst)
+	if err != nil {
+		return nil, summary, err
+	}
+	matched := []models.Order{}
+	for _, o := range all {
+		if models.NormalizePhone(o.CustomerPhone) != phone {
+			continue
+		}
+		matched = append(matched, o)
+		summary.Orders++
+		if o.Status != models.OrderStatusCancelled {
+			summary.Spend += o.FinalAmount
+		}
+	}
+	end := len(matched)
+	if page.Limit > 0 {
+		end = min(end, page.Offset+page.Limit)
+	}
+	if page.Offset >= end {
+		return []models.Order{}, summary, nil
+	}
+	return matched[page.Offset:end], summary, nil
+}
+
+// GetOrderByID returns a single order with its items
+func (s *Store) GetOrderByID(ctx context.Context, id int) (*models.Order, error) {
+	return getOrder(ctx, s.db, id)
+}
+
+func getOrder(ctx context.Context, q queryer, id int) (*models.Order, error) {
+	orders, err := queryOrders(ctx, q, `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, notFound("order", id)
+	}
+	return &orders[0], nil
+}
+
+// GetOrderItemsByOrderID returns the items of an order with their menu items
+func (s *Store) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]models.OrderItem, error) {
+	return queryOrderItems(ctx, s.db, `order_id = ?`, orderID)
+}
+
+// CreateOrder stores an order and its items, priced from the menu, and
+// takes the items whose stock is tracked from it, failing with an
+// *storage.InsufficientStockError when there isn't enough. It follows the
+// Postgres store otherwise: an inactive restaurant takes no orders, one with
+// auto_confirm_orders gets them confirmed, and a second order with the same
+// IdempotencyKey sets o to the first instead.
+func (s *Store) CreateOrder(ctx context.Context, o *models.Order) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	r, err := getRestaurant(ctx, tx, o.RestaurantID)
+	if err != nil {
+		return err
+	}
+	if o.IdempotencyKey != "" {
+		var id int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM orders WHERE restaurant_id = ? AND idempotency_key = ?`, o.RestaurantID, o.IdempotencyKey).Scan(&id)
+		if err == nil {
+			existing, err := getOrder(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			*o = *existing
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+	if !r.Active {
+		return fmt.Errorf("restaurant with ID %d %w", o.RestaurantID, storage.ErrRestaurantInactive)
+	}
+
+	if err := moveStock(ctx, tx, nil, o.OrderItems); err != nil {
+		return err
+	}
+	if err := priceItems(ctx, tx, o.RestaurantID, o.OrderItems); err != nil {
+		return err
+	}
+	if o.OrderType == "" {
+		o.OrderType = models.OrderTypeDineIn
+	}
+	if o.Status == "" {
+		o.Status = models.OrderStatusPending
+	}
+	if o.PaymentStatus == "" {
+		o.PaymentStatus = models.PaymentStatusPending
+	}
+	o.ComputeTotals(r.OrderCharges())
+	o.AutoConfirm(*r)
+	o.CreatedAt = now()
+	o.UpdatedAt = o.CreatedAt
+
+	var key interface{}
+	if o.IdempotencyKey != "" {
+		key = o.IdempotencyKey
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO orders (restaurant_id, customer_name, customer_phone, order_type, status, total_amount, tax_amount, discount, discount_percent,
+			service_charge, tip, final_amount, payment_status, payment_method, billing_address, idempotency_key, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		o.RestaurantID, o.CustomerName, o.CustomerPhone, o.OrderType, o.Status, o.TotalAmount, o.TaxAmount, o.Discount, o.DiscountPercent,
+		o.ServiceCharge, o.Tip, o.FinalAmount, o.PaymentStatus, o.PaymentMethod, o.BillingAddress, key, o.CreatedAt, o.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	o.ID = int(id)
+	for i := range o.OrderItems {
+		item := &o.OrderItems[i]
+		item.OrderID = o.ID
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO order_items (order_id, menu_item_id, quantity, price, notes, subtotal, from_stock) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			item.OrderID, item.MenuItemID, item.Quantity, item.Price, item.Notes, item.Subtotal, item.FromStock)
+		if err != nil {
+			return err
+		}
+		itemID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(itemID)
+	}
+	if err := recordStatus(ctx, tx, o.ID, "", o.Status); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// priceItems sets each item's price from the menu, checking every item is
+// on the restaurant's menu. The SQLite backend keeps no pricing rules.
+func priceItems(ctx context.Context, tx *sql.Tx, restaurantID int, items []models.OrderItem) error {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.MenuItemID
+	}
+	menu, err := menuItemsByIDs(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	missing := &storage.MissingItemsError{}
+	foreign := &storage.ForeignItemsError{RestaurantID: restaurantID}
+	for i := range items {
+		item := &items[i]
+		m, ok := menu[item.MenuItemID]
+		if !ok {
+			missing.MenuItemIDs = append(missing.MenuItemIDs, item.MenuItemID)
+			continue
+		}
+		if m.RestaurantID != restaurantID {
+			foreign.Items = append(foreign.Items, storage.ForeignItem{MenuItemID: item.MenuItemID, RestaurantID: m.RestaurantID})
+		}
+		item.Price, item.PricingRuleID = m.Price, nil
+	}
+	if len(missing.MenuItemIDs) > 0 {
+		return missing
+	}
+	if len(foreign.Items) > 0 {
+		return foreign
+	}
+	return nil
+}
+
+// moveStock puts the stock the released items took back and takes the
+// items in taken from the stock of their menu items, in tx, setting their
+// FromStock. Wanting more than is left fails with an
+// *storage.InsufficientStockError; items that aren't on the menu are left
+// for priceItems to report.
+func moveStock(ctx context.Context, tx *sql.Tx, released, taken []models.OrderItem) error {
+	need := models.StockTaken(released, taken)
+	ids := make([]int, 0, len(need))
+	for id := range need {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	menu, err := menuItemsByIDs(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	short := &storage.InsufficientStockError{}
+	for _, id := range ids {
+		if m, ok := menu[id]; ok && m.StockQuantity != nil && need[id] > *m.StockQuantity {
+			short.Items = append(short.Items, storage.StockShortage{MenuItemID: id, Name: m.Name, Wanted: need[id], Remaining: *m.StockQuantity})
+		}
+	}
+	if len(short.Items) > 0 {
+		return short
+	}
+	for _, id := range ids {
+		m, ok := menu[id]
+		if !ok || m.StockQuantity == nil || need[id] == 0 {
+			continue
+		}
+		left := *m.StockQuantity - need[id]
+		raise := 0
+		if m.SetStock(&left) {
+			raise = 1
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE menu_items SET stock_quantity = ?, available = ?, version = version + ?, updated_at = ? WHERE id = ?`,
+			m.StockQuantity, m.Available, raise, now(), m.ID); err != nil {
+			return err
+		}
+	}
+	for i := range taken {
+		m, ok := menu[taken[i].MenuItemID]
+		taken[i].FromStock = ok && m.StockQuantity != nil
+	}
+	return nil
+}
+
+// CancelOrder cancels an order with a reason, putting the stock its items
+// took back, and refunds it when it was paid. Delivered and already
+// cancelled orders are refused with a *models.TransitionError.
+func (s *Store) CancelOrder(ctx context.Context, orderID int, reason, note, cancelledBy string) (*models.Order, error) {
+	if err := models.ValidateCancelReason(reason, note); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := getOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status == models.OrderStatusCancelled || !models.CanTransition(o.Status, models.OrderStatusCancelled) {
+		return nil, &models.TransitionError{OrderID: orderID, From: o.Status, To: models.OrderStatusCancelled}
+	}
+	if err := moveStock(ctx, tx, o.OrderItems, nil); err != nil {
+		return nil, err
+	}
+	if err := recordStatus(ctx, tx, orderID, o.Status, models.OrderStatusCancelled); err != nil {
+		return nil, err
+	}
+	at := now()
+	o.Status = models.OrderStatusCancelled
+	o.CancellationReason, o.CancellationNote, o.CancelledBy = reason, strings.TrimSpace(note), cancelledBy
+	o.CancelledAt, o.UpdatedAt = &at, at
+	if o.PaymentStatus == models.PaymentStatusCompleted {
+		o.PaymentStatus = models.PaymentStatusRefunded
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status = ?, cancellation_reason = ?, cancellation_note = ?, cancelled_by = ?, cancelled_at = ?, payment_status = ?, updated_at = ?
+		WHERE id = ?`,
+		o.Status, o.CancellationReason, o.CancellationNote, o.CancelledBy, o.CancelledAt, o.PaymentStatus, o.UpdatedAt, orderID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// recordStatus adds a step to an order's status timeline in tx, made by
+// storage.Actor(ctx)
+func recordStatus(ctx context.Context, tx *sql.Tx, orderID int, from, to string) error {
+	if from == to {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO order_status_history (order_id, from_status, to_status, changed_at, changed_by) VALUES (?, ?, ?, ?, ?)`,
+		orderID, from, to, now(), storage.Actor(ctx))
+	return err
+}
+
+// GetOrderHistory returns the status changes of an order, oldest first
+func (s *Store) GetOrderHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = ?)`, orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, notFound("order", orderID)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT order_id, from_status, to_status, changed_at, changed_by, note FROM order_status_history WHERE order_id = ? ORDER BY changed_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []models.OrderStatusChange{}
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.OrderID, &c.FromStatus, &c.ToStatus, &c.ChangedAt, &c.ChangedBy, &c.Note); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
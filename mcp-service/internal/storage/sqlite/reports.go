@@ -0,0 +1,243 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// The reports bucket orders by day in the restaurant's timezone, which
+// SQLite can't convert to, so they read a restaurant's orders and total
+// them in Go, as the in-memory store does.
+
+// restaurantOrders returns the restaurant with restaurantID and its orders
+// created at or after since, newest first, with their items
+func (s *Store) restaurantOrders(ctx context.Context, restaurantID int, since time.Time) (*models.Restaurant, []models.Order, error) {
+	r, err := s.GetRestaurantByID(ctx, restaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	orders, err := queryOrders(ctx, s.db, `restaurant_id = ? AND created_at >= ?`+newestFirst, restaurantID, since.UTC())
+	return r, orders, err
+}
+
+// GetSalesReport totals a restaurant's orders per day, in the restaurant's
+// timezone, between q.From and q.To; cancelled orders are counted by reason
+// instead
+func (s *Store) GetSalesReport(ctx context.Context, q models.SalesQuery) (*models.SalesReport, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	from, err := time.Parse(models.DateLayout, q.From)
+	if err != nil {
+		return nil, err
+	}
+	// A day before From in UTC is before its start in any timezone
+	r, orders, err := s.restaurantOrders(ctx, q.RestaurantID, from.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SalesReport{RestaurantID: r.ID, Timezone: r.Timezone, From: q.From, To: q.To,
+		Days: []models.SalesDay{}, Cancelled: []models.SalesCancellations{}}
+	byDay := map[string]*models.SalesDay{}
+	for _, o := range orders {
+		day := o.CreatedAt.In(loc).Format(models.DateLayout)
+		if day < q.From || day > q.To {
+			continue
+		}
+		if o.Status == models.OrderStatusCancelled {
+			report.AddCancelled(o)
+			continue
+		}
+		if byDay[day] == nil {
+			byDay[day] = &models.SalesDay{Date: day}
+		}
+		byDay[day].Add(o)
+	}
+	for _, d := range byDay {
+		report.Days = append(report.Days, *d)
+	}
+	slices.SortFunc(report.Days, func(a, b models.SalesDay) int { return strings.Compare(a.Date, b.Date) })
+	return report, nil
+}
+
+// GetTopSellingItems ranks a restaurant's menu items by units sold, then by
+// revenue, leaving out cancelled orders
+func (s *Store) GetTopSellingItems(ctx context.Context, q models.TopItemsQuery) ([]models.ItemSales, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	_, orders, err := s.restaurantOrders(ctx, q.RestaurantID, q.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	byItem := map[int]*models.ItemSales{}
+	for _, o := range orders {
+		if o.Status == models.OrderStatusCancelled {
+			continue
+		}
+		for _, oi := range o.OrderItems {
+			m := oi.MenuItem
+			if m == nil || (q.Category != "" && !strings.EqualFold(m.Category, q.Category)) {
+				continue
+			}
+			if byItem[m.ID] == nil {
+				byItem[m.ID] = &models.ItemSales{MenuItemID: m.ID, Name: m.Name, Category: m.Category}
+			}
+			byItem[m.ID].Units += oi.Quantity
+			byItem[m.ID].Revenue += oi.Subtotal
+		}
+	}
+	items := []models.ItemSales{}
+	for _, item := range byItem {
+		items = append(items, *item)
+	}
+	slices.SortFunc(items, func(a, b models.ItemSales) int {
+		if a.Units != b.Units {
+			return b.Units - a.Units
+		}
+		if a.Revenue != b.Revenue {
+			return cmp.Compare(b.Revenue, a.Revenue)
+		}
+		return a.MenuItemID - b.MenuItemID
+	})
+	return items[:min(len(items), q.Limit)], nil
+}
+
+// GetOrderDigest gathers the numbers for a summary of one day of a
+// restaurant's orders in the restaurant's timezone
+func (s *Store) GetOrderDigest(ctx context.Context, q models.DigestQuery) (*models.OrderDigest, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r, err := s.GetRestaurantByID(ctx, q.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	day, previous := q.Day(loc, time.Now())
+	start, err := time.Parse(models.DateLayout, previous)
+	if err != nil {
+		return nil, err
+	}
+	_, orders, err := s.restaurantOrders(ctx, r.ID, start.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	d := models.NewOrderDigest(*r, day)
+
+	var kept []models.Order
+	hours := map[int]int{}
+	soldOut := map[string]bool{}
+	for _, o := range orders {
+		cancelled := o.Status == models.OrderStatusCancelled
+		switch o.CreatedAt.In(loc).Format(models.DateLayout) {
+		case previous:
+			if !cancelled {
+				d.PreviousRevenue += o.FinalAmount
+			}
+			continue
+		case day:
+		default:
+			continue
+		}
+		d.Orders++
+		d.ByStatus[o.Status]++
+		hours[o.CreatedAt.In(loc).Hour()]++
+		if o.PaymentStatus == models.PaymentStatusRefunded {
+			d.Refunds++
+			d.RefundedAmount += o.FinalAmount
+		}
+		if cancelled {
+			continue
+		}
+		d.Revenue += o.FinalAmount
+		kept = append(kept, o)
+		for _, oi := range o.OrderItems {
+			if oi.MenuItem != nil && !oi.MenuItem.Available {
+				soldOut[oi.MenuItem.Name] = true
+			}
+		}
+	}
+	for hour, n := range hours {
+		if n > d.BusiestHourOrders || n == d.BusiestHourOrders && hour < d.BusiestHour {
+			d.BusiestHour, d.BusiestHourOrders = hour, n
+		}
+	}
+	if len(kept) > 0 {
+		average := models.Rupees(d.Revenue.Rupees() / float64(len(kept)))
+		slices.SortFunc(kept, func(a, b models.Order) int {
+			if a.FinalAmount != b.FinalAmount {
+				return cmp.Compare(b.FinalAmount, a.FinalAmount)
+			}
+			return a.ID - b.ID
+		})
+		for _, o := range kept {
+			if len(d.LargeOrders) == models.MaxLargeOrders || !models.IsLarge(o.FinalAmount, average) {
+				break
+			}
+			d.LargeOrders = append(d.LargeOrders, models.DigestOrder{ID: o.ID, CustomerName: o.CustomerName, FinalAmount: o.FinalAmount})
+		}
+	}
+	for name := range soldOut {
+		d.SoldOut = append(d.SoldOut, name)
+	}
+	slices.Sort(d.SoldOut)
+	return d, nil
+}
+
+// GetRestaurantStats counts a restaurant's orders and available menu items
+// and totals its revenue
+func (s *Store) GetRestaurantStats(ctx context.Context, restaurantID int) (*models.RestaurantStats, error) {
+	if _, err := s.GetRestaurantByID(ctx, restaurantID); err != nil {
+		return nil, err
+	}
+	stats := &models.RestaurantStats{RestaurantID: restaurantID}
+	var lastOrderAt string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(status = 'pending'), 0), COALESCE(SUM(status = 'cancelled'), 0), COALESCE(MAX(created_at), '')
+		FROM orders WHERE restaurant_id = ?`, restaurantID).Scan(&stats.Orders, &stats.PendingOrders, &stats.CancelledOrders, &lastOrderAt)
+	if err != nil {
+		return nil, err
+	}
+	// Money is summed in Go, as SQLite would sum it as floating point
+	rows, err := s.db.QueryContext(ctx, `SELECT final_amount FROM orders WHERE restaurant_id = ? AND status != 'cancelled'`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var amount models.Money
+		if err := rows.Scan(&amount); err != nil {
+			return nil, err
+		}
+		stats.Revenue += amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if lastOrderAt != "" {
+		// An aggregate loses the column's type, so the time comes back as text
+		at, err := time.Parse(timeFormat, lastOrderAt)
+		if err != nil {
+			return nil, err
+		}
+		stats.LastOrderAt = &at
+	}
+	stats.SetAverage()
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM menu_items WHERE restaurant_id = ? AND available`, restaurantID).Scan(&stats.AvailableMenuItems)
+	return stats, err
+}
@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+const restaurantColumns = `id, name, address, phone_number, email, cuisine_type, timezone, notification_preferences, public_menu_enabled,
+	auto_confirm_orders, tax_rate, service_charge_percent, active, version, created_at, updated_at`
+
+func scanRestaurant(row rowScanner, r *models.Restaurant) error {
+	return row.Scan(&r.ID, &r.Name, &r.Address, &r.PhoneNumber, &r.Email, &r.CuisineType, &r.Timezone, &r.NotificationPreferences, &r.PublicMenuEnabled,
+		&r.AutoConfirmOrders, &r.TaxRate, &r.ServiceChargePercent, &r.Active, &r.Version, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// restaurantOrderBy maps each accepted sort to its ORDER BY clause, as in
+// the Postgres store
+var restaurantOrderBy = map[string]string{
+	"":                               "id",
+	models.RestaurantSortID:          "id",
+	models.RestaurantSortName:        "name, id",
+	models.RestaurantSortCreatedAt:   "created_at, id",
+	models.RestaurantSortCuisineType: "cuisine_type, name, id",
+}
+
+func (s *Store) queryRestaurants(ctx context.Context, query string, args ...interface{}) ([]models.Restaurant, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	restaurants := []models.Restaurant{}
+	for rows.Next() {
+		var r models.Restaurant
+		if err := scanRestaurant(rows, &r); err != nil {
+			return nil, err
+		}
+		restaurants = append(restaurants, r)
+	}
+	return restaurants, rows.Err()
+}
+
+// GetAllRestaurants returns one page of active restaurants in the given
+// sort order and the total count
+func (s *Store) GetAllRestaurants(ctx context.Context, page models.Page, sort string) ([]models.Restaurant, int, error) {
+	orderBy, ok := restaurantOrderBy[sort]
+	if !ok {
+		return nil, 0, models.ValidateRestaurantSort(sort)
+	}
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM restaurants WHERE active`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	restaurants, err := s.queryRestaurants(ctx,
+		`SELECT `+restaurantColumns+` FROM restaurants WHERE active ORDER BY `+orderBy+` LIMIT ? OFFSET ?`, limit(page), page.Offset)
+	return restaurants, total, err
+}
+
+// SearchRestaurants returns up to limit active restaurants matching query
+// and cuisine as models.MatchesRestaurantSearch does, ordered by name
+func (s *Store) SearchRestaurants(ctx context.Context, query, cuisine string, limit int) ([]models.Restaurant, error) {
+	limit, err := models.RestaurantSearchLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.queryRestaurants(ctx, `SELECT `+restaurantColumns+` FROM restaurants WHERE active ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	found := []models.Restaurant{}
+	for _, r := range all {
+		if len(found) < limit && models.MatchesRestaurantSearch(r, strings.TrimSpace(query), strings.TrimSpace(cuisine)) {
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+// GetRestaurantByID returns a single restaurant, active or not
+func (s *Store) GetRestaurantByID(ctx context.Context, id int) (*models.Restaurant, error) {
+	return getRestaurant(ctx, s.db, id)
+}
+
+func getRestaurant(ctx context.Context, q queryer, id int) (*models.Restaurant, error) {
+	var r models.Restaurant
+	err := scanRestaurant(q.QueryRowContext(ctx, `SELECT `+restaurantColumns+` FROM restaurants WHERE id = ?`, id), &r)
+	if err == sql.ErrNoRows {
+		return nil, notFound("restaurant", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRestaurant stores a restaurant and fills in its ID and timestamps,
+// refusing the name and address of another one
+func (s *Store) CreateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	if err := models.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+	if err := models.ValidateTaxRate(r.TaxRate); err != nil {
+		return err
+	}
+	if err := models.ValidateServiceChargePercent(r.ServiceChargePercent); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM restaurants WHERE lower(name) = lower(?) AND lower(address) = lower(?)`, r.Name, r.Address).Scan(&existing)
+	if err == nil {
+		return &storage.DuplicateRestaurantError{ExistingID: existing}
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	r.Active, r.Version = true, 1
+	if r.Timezone == "" {
+		r.Timezone = "Asia/Kolkata"
+	}
+	r.CreatedAt = now()
+	r.UpdatedAt = r.CreatedAt
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO restaurants (name, address, phone_number, email, cuisine_type, timezone, notification_preferences, tax_rate, service_charge_percent, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Name, r.Address, r.PhoneNumber, r.Email, r.CuisineType, r.Timezone, r.NotificationPreferences, r.TaxRate, r.ServiceChargePercent, r.CreatedAt, r.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = int(id)
+	return tx.Commit()
+}
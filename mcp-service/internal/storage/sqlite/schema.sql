@@ -0,0 +1,103 @@
+-- The SQLite schema: the tables of the Postgres migrations the stdio server
+-- reads and writes, in SQLite's types. Money is NUMERIC, times are
+-- TIMESTAMP text in UTC and flags are 0/1. order_items.subtotal is written
+-- by the store rather than GENERATED, and the case-insensitive uniqueness of
+-- a restaurant's name and address is an expression index.
+CREATE TABLE IF NOT EXISTS restaurants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    phone_number TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    cuisine_type TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT 'Asia/Kolkata',
+    notification_preferences TEXT NOT NULL DEFAULT '{"notify_on_order": true, "notify_on_low_stock": true}',
+    public_menu_enabled BOOLEAN NOT NULL DEFAULT 0,
+    auto_confirm_orders BOOLEAN NOT NULL DEFAULT 0,
+    tax_rate REAL,
+    service_charge_percent REAL,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurants_name_address ON restaurants(lower(name), lower(address));
+
+CREATE TABLE IF NOT EXISTS menu_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price NUMERIC NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    dietary_type TEXT NOT NULL DEFAULT '',
+    spice_level TEXT NOT NULL DEFAULT '',
+    available BOOLEAN NOT NULL DEFAULT 1,
+    effective_from TEXT NOT NULL DEFAULT '',
+    effective_to TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    stock_quantity INTEGER CHECK (stock_quantity >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_restaurant ON menu_items(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    customer_name TEXT NOT NULL,
+    customer_phone TEXT NOT NULL DEFAULT '',
+    order_type TEXT NOT NULL DEFAULT 'dine_in',
+    status TEXT NOT NULL DEFAULT 'pending',
+    total_amount NUMERIC NOT NULL DEFAULT 0,
+    tax_amount NUMERIC NOT NULL DEFAULT 0,
+    discount NUMERIC NOT NULL DEFAULT 0,
+    discount_percent REAL,
+    service_charge NUMERIC NOT NULL DEFAULT 0,
+    tip NUMERIC NOT NULL DEFAULT 0,
+    final_amount NUMERIC NOT NULL DEFAULT 0,
+    payment_status TEXT NOT NULL DEFAULT 'pending',
+    payment_method TEXT NOT NULL DEFAULT '',
+    billing_address TEXT NOT NULL DEFAULT '',
+    delivery_person_name TEXT NOT NULL DEFAULT '',
+    delivery_person_phone TEXT NOT NULL DEFAULT '',
+    dispatched_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    cancellation_reason TEXT NOT NULL DEFAULT '',
+    cancellation_note TEXT NOT NULL DEFAULT '',
+    cancelled_by TEXT NOT NULL DEFAULT '',
+    cancelled_at TIMESTAMP,
+    idempotency_key TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant ON orders(restaurant_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_idempotency_key ON orders(restaurant_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    menu_item_id INTEGER NOT NULL REFERENCES menu_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price NUMERIC NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    subtotal NUMERIC NOT NULL,
+    from_stock BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL DEFAULT '',
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    changed_by TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, changed_at);
@@ -0,0 +1,166 @@
+// Package sqlite is a storage.Store on an SQLite database, so the stdio MCP
+// server can run without a Postgres, as for a Claude Desktop demo. It keeps
+// restaurants, menus, orders and their status history, which is all the
+// stdio server's tools use. Methods for the rest, such as pricing rules,
+// change requests and the audit log, return ErrUnsupported.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver, pure Go
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+//go:embed schema.sql
+var schema string
+
+// ErrUnsupported is returned by the methods of features the SQLite backend
+// doesn't keep
+var ErrUnsupported = fmt.Errorf("%w; the SQLite backend only keeps restaurants, menus and orders, use Postgres", storage.ErrUnsupported)
+
+// Store is a storage.Store on an SQLite database
+type Store struct {
+	db *sql.DB
+}
+
+var _ storage.Store = (*Store)(nil)
+
+// IsURL reports whether a DATABASE_URL names an SQLite database rather
+// than a Postgres one
+func IsURL(url string) bool {
+	return strings.HasPrefix(url, "sqlite:")
+}
+
+// Open opens the SQLite database named by url, sqlite:///path/to/file.db or
+// sqlite::memory:, creating its schema if it has none
+func Open(url string) (*Store, error) {
+	if !IsURL(url) {
+		return nil, fmt.Errorf("%q is not an SQLite URL like sqlite:///path/to/file.db or sqlite::memory:", url)
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(url, "sqlite:"), "//")
+	if path == "" {
+		return nil, fmt.Errorf("%q names no database file", url)
+	}
+	conn, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite takes one writer at a time, and every connection to :memory:
+	// would get a database of its own
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.ExecContext(context.Background(), schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create schema: %v", err)
+	}
+	return &Store{db: conn}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SeedSampleData adds storage.SampleData to a database without restaurants
+// and storage.SampleOrders to one without orders, as the Postgres store's
+// SeedSampleData does
+func (s *Store) SeedSampleData(ctx context.Context) error {
+	if err := s.seedSampleRestaurants(ctx); err != nil {
+		return err
+	}
+	return storage.SeedSampleOrders(ctx, s)
+}
+
+func (s *Store) seedSampleRestaurants(ctx context.Context) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM restaurants`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	at := now()
+	for _, sample := range storage.SampleData {
+		r := sample.Restaurant
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO restaurants (name, address, phone_number, cuisine_type, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			r.Name, r.Address, r.PhoneNumber, r.CuisineType, at, at)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, m := range sample.Menu {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO menu_items (restaurant_id, name, description, price, category, dietary_type, spice_level, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, m.Name, m.Description, m.Price, m.Category, m.DietaryType, m.SpiceLevel, at, at); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Println("Sample restaurants seeded successfully")
+	return nil
+}
+
+// EnsureConnected checks the database can be queried
+func (s *Store) EnsureConnected(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// timeFormat is how _time_format=sqlite writes times
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// now is the time written for a change made now. Times are kept in UTC, in
+// the one text format of _time_format=sqlite, so they compare as text.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func notFound(entity string, id int) error {
+	return fmt.Errorf("%s with ID %d %w", entity, id, storage.ErrNotFound)
+}
+
+// limit is page.Limit for a LIMIT clause, where SQLite takes -1 for none
+func limit(page models.Page) int {
+	if page.Limit == 0 {
+		return -1
+	}
+	return page.Limit
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// ints converts ids to query arguments
+func ints(ids []int) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+type rowScanner interface{ Scan(...interface{}) error }
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// open returns an in-memory database with the sample restaurants and no
+// orders
+func open(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.seedSampleRestaurants(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSeedSampleDataOnce(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "restaurants.db")
+	for range 2 {
+		s, err := Open("sqlite://" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.SeedSampleData(ctx)
+		restaurants, total, _ := s.GetAllRestaurants(ctx, models.Page{Limit: 10}, models.RestaurantSortName)
+		_, orders, _ := s.GetOrders(ctx, models.OrderFilter{}, models.Page{Limit: 10})
+		s.Close()
+		if err != nil || total != len(storage.SampleData) || restaurants[0].Name != "Hyderabad House" || orders != len(storage.SampleOrders) {
+			t.Fatalf("after seeding: %d restaurants, %d orders, %v; want the %d sample restaurants and %d sample orders",
+				total, orders, err, len(storage.SampleData), len(storage.SampleOrders))
+		}
+	}
+	if _, err := Open("postgres://localhost/restaurants"); err == nil {
+		t.Error("Open(postgres URL) succeeded")
+	}
+}
+
+func TestRestaurantsAndMenus(t *testing.T) {
+	ctx := context.Background()
+	s := open(t)
+
+	r := &models.Restaurant{Name: "Chai Point", Address: "Park Street, Kolkata", CuisineType: "Cafe"}
+	if err := s.CreateRestaurant(ctx, r); err != nil || r.ID == 0 || r.Timezone != "Asia/Kolkata" {
+		t.Fatalf("CreateRestaurant = %+v, %v", r, err)
+	}
+	var dup *storage.DuplicateRestaurantError
+	if err := s.CreateRestaurant(ctx, &models.Restaurant{Name: "chai point", Address: "PARK STREET, KOLKATA"}); !errors.As(err, &dup) || dup.ExistingID != r.ID {
+		t.Errorf("CreateRestaurant(same name and address) = %v, want a DuplicateRestaurantError", err)
+	}
+	got, err := s.GetRestaurantByID(ctx, r.ID)
+	if err != nil || got.Name != r.Name || !got.CreatedAt.Equal(r.CreatedAt) || !got.Active {
+		t.Errorf("GetRestaurantByID = %+v, %v; want %+v", got, err, r)
+	}
+	if _, err := s.GetRestaurantByID(ctx, 999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetRestaurantByID(999) = %v, want ErrNotFound", err)
+	}
+	if found, err := s.SearchRestaurants(ctx, "mahal", "", 0); err != nil || len(found) != 2 {
+		t.Errorf("SearchRestaurants(mahal) = %d, %v; want 2", len(found), err)
+	}
+
+	menu, err := s.GetMenuByRestaurantID(ctx, 2, false)
+	if err != nil || len(menu) != 4 || menu[0].Name != "Filter Coffee" || menu[0].Price != 50 {
+		t.Fatalf("GetMenuByRestaurantID(2) = %+v, %v; want the 4 sample items by category", menu, err)
+	}
+	found, err := s.SearchMenuItems(ctx, models.MenuSearch{RestaurantID: 2, SpiceLevel: models.SpiceHot})
+	if err != nil || len(found) != 1 || found[0].Name != "Vada Pav" {
+		t.Errorf("SearchMenuItems(hot) = %+v, %v", found, err)
+	}
+}
+
+func TestOrdersAndReports(t *testing.T) {
+	ctx := storage.WithActor(context.Background(), "asha@example.com")
+	s := open(t)
+
+	o := &models.Order{RestaurantID: 2, CustomerName: "Ravi", CustomerPhone: "+91 98450 12345", IdempotencyKey: "k1",
+		OrderItems: []models.OrderItem{{MenuItemID: 5, Quantity: 2}, {MenuItemID: 8, Quantity: 1, Notes: "strong"}}}
+	if err := s.CreateOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != models.OrderStatusPending || o.TotalAmount != 350 || o.OrderItems[0].Subtotal != 300 || o.FinalAmount <= o.TotalAmount {
+		t.Errorf("created order %+v", o)
+	}
+	again := &models.Order{RestaurantID: 2, CustomerName: "Ravi", IdempotencyKey: "k1", OrderItems: []models.OrderItem{{MenuItemID: 6, Quantity: 1}}}
+	if err := s.CreateOrder(ctx, again); err != nil || again.ID != o.ID || len(again.OrderItems) != 2 {
+		t.Errorf("CreateOrder with the same key = %+v, %v; want order %d", again, err, o.ID)
+	}
+	var foreign *storage.ForeignItemsError
+	if err := s.CreateOrder(ctx, &models.Order{RestaurantID: 2, CustomerName: "Ravi", OrderItems: []models.OrderItem{{MenuItemID: 1, Quantity: 1}}}); !errors.As(err, &foreign) {
+		t.Errorf("CreateOrder with another restaurant's item = %v, want a ForeignItemsError", err)
+	}
+
+	got, err := s.GetOrderByID(ctx, o.ID)
+	if err != nil || got.FinalAmount != o.FinalAmount || len(got.OrderItems) != 2 || got.OrderItems[1].Notes != "strong" || got.OrderItems[1].MenuItem.Name != "Filter Coffee" {
+		t.Fatalf("GetOrderByID = %+v, %v", got, err)
+	}
+	orders, total, err := s.GetOrders(ctx, models.OrderFilter{RestaurantID: 2, CreatedFrom: time.Now().Add(-time.Hour)}, models.Page{Limit: 10})
+	if err != nil || total != 1 || orders[0].ID != o.ID {
+		t.Errorf("GetOrders(last hour) = %d of %d, %v; want the order", len(orders), total, err)
+	}
+	if _, summary, err := s.GetOrdersByCustomerPhone(ctx, "9845012345", models.Page{Limit: 10}); err != nil || summary.Orders != 1 || summary.Spend != o.FinalAmount {
+		t.Errorf("GetOrdersByCustomerPhone = %+v, %v", summary, err)
+	}
+
+	today := models.LocalTime("Asia/Kolkata", time.Now()).Format(models.DateLayout)
+	report, err := s.GetSalesReport(ctx, models.SalesQuery{RestaurantID: 2, From: today, To: today})
+	if err != nil || len(report.Days) != 1 || report.Days[0].Orders != 1 || report.Days[0].Gross != o.TotalAmount {
+		t.Errorf("GetSalesReport(today) = %+v, %v", report, err)
+	}
+	top, err := s.GetTopSellingItems(ctx, models.TopItemsQuery{RestaurantID: 2, Limit: 5})
+	if err != nil || len(top) != 2 || top[0].Name != "Masala Dosa" || top[0].Units != 2 {
+		t.Errorf("GetTopSellingItems = %+v, %v", top, err)
+	}
+	stats, err := s.GetRestaurantStats(ctx, 2)
+	if err != nil || stats.Orders != 1 || stats.PendingOrders != 1 || stats.Revenue != o.FinalAmount || stats.LastOrderAt == nil || stats.AvailableMenuItems != 4 {
+		t.Errorf("GetRestaurantStats = %+v, %v", stats, err)
+	}
+
+	cancelled, err := s.CancelOrder(ctx, o.ID, models.CancelReasonCustomerRequest, "", "staff")
+	if err != nil || cancelled.Status != models.OrderStatusCancelled || cancelled.CancelledAt == nil {
+		t.Fatalf("CancelOrder = %+v, %v", cancelled, err)
+	}
+	var transition *models.TransitionError
+	if _, err := s.CancelOrder(ctx, o.ID, models.CancelReasonCustomerRequest, "", "staff"); !errors.As(err, &transition) {
+		t.Errorf("cancelling twice = %v, want a TransitionError", err)
+	}
+	history, err := s.GetOrderHistory(ctx, o.ID)
+	if err != nil || len(history) != 2 || history[1].ToStatus != models.OrderStatusCancelled || history[1].ChangedBy != "asha@example.com" {
+		t.Errorf("GetOrderHistory = %+v, %v", history, err)
+	}
+	digest, err := s.GetOrderDigest(ctx, models.DigestQuery{RestaurantID: 2, Date: today})
+	if err != nil || digest.Orders != 1 || digest.ByStatus[models.OrderStatusCancelled] != 1 || digest.Revenue != 0 {
+		t.Errorf("GetOrderDigest = %+v, %v", digest, err)
+	}
+
+	if err := s.DeleteOrder(ctx, o.ID); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("DeleteOrder = %v, want ErrUnsupported", err)
+	}
+}
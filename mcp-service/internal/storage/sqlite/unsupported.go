@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// The methods below are of features the stdio server has no tools for.
+// They fail with ErrUnsupported, except the reads whose answer without the
+// feature is known: there are no pricing rules and no price changes.
+
+func (s *Store) UpdateRestaurant(ctx context.Context, r *models.Restaurant) error {
+	return ErrUnsupported
+}
+
+func (s *Store) PatchRestaurant(ctx context.Context, id int, patch models.RestaurantPatch) (*models.Restaurant, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) DeleteRestaurant(ctx context.Context, id int, opts models.RestaurantDeleteOptions) (models.RestaurantDeletion, error) {
+	return models.RestaurantDeletion{}, ErrUnsupported
+}
+
+func (s *Store) RestoreRestaurant(ctx context.Context, id int) (*models.Restaurant, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) CreateMenuItem(ctx context.Context, m *models.MenuItem) error {
+	return ErrUnsupported
+}
+
+func (s *Store) CreateMenuItems(ctx context.Context, items []*models.MenuItem) error {
+	return ErrUnsupported
+}
+
+func (s *Store) UpdateMenuItem(ctx context.Context, m *models.MenuItem, changedBy string) error {
+	return ErrUnsupported
+}
+
+func (s *Store) SetStock(ctx context.Context, menuItemID int, quantity *int) (*models.MenuItem, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) DeleteMenuItem(ctx context.Context, id int) error {
+	return ErrUnsupported
+}
+
+func (s *Store) GetPriceHistory(ctx context.Context, q models.PriceHistoryQuery, page models.Page) ([]models.PriceChange, int, error) {
+	return nil, 0, ErrUnsupported
+}
+
+// LastPriceChanges returns no changes, as prices can't be changed
+func (s *Store) LastPriceChanges(ctx context.Context, restaurantID int) (map[int]time.Time, error) {
+	return map[int]time.Time{}, nil
+}
+
+func (s *Store) UpdateOrder(ctx context.Context, o *models.Order) error {
+	return ErrUnsupported
+}
+
+func (s *Store) UpdateOrderItems(ctx context.Context, orderID int, items []models.OrderItem) (*models.Order, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) SetOrderTip(ctx context.Context, orderID int, tip models.Money) (*models.Order, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) SplitOrder(ctx context.Context, orderID int, moves []models.OrderItem) (*models.Order, *models.Order, error) {
+	return nil, nil, ErrUnsupported
+}
+
+func (s *Store) MergeOrders(ctx context.Context, orderIDs []int) (*models.Order, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) AssignDelivery(ctx context.Context, orderID int, riderName, riderPhone string) (*models.Order, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) DeleteOrder(ctx context.Context, id int) error {
+	return ErrUnsupported
+}
+
+func (s *Store) RecordOrderFeedback(ctx context.Context, f *models.OrderFeedback) error {
+	return ErrUnsupported
+}
+
+func (s *Store) GetOrderFeedback(ctx context.Context, orderID int) (*models.OrderFeedback, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) ExportOrders(ctx context.Context, restaurantID int, from, to time.Time, row func(models.OrderExportRow) error) error {
+	return ErrUnsupported
+}
+
+func (s *Store) ArchiveOrders(ctx context.Context, before time.Time) (int, error) {
+	return 0, ErrUnsupported
+}
+
+func (s *Store) GetArchivedOrder(ctx context.Context, id int) (*models.Order, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) ProposeChange(ctx context.Context, c *models.ChangeRequest) error {
+	return ErrUnsupported
+}
+
+func (s *Store) ListChangeRequests(ctx context.Context, status string, page models.Page) ([]models.ChangeRequest, int, error) {
+	return nil, 0, ErrUnsupported
+}
+
+func (s *Store) ReviewChangeRequest(ctx context.Context, id int, approve bool, reviewer string) (*models.ChangeRequest, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) RecordShadowedAction(ctx context.Context, a *models.ShadowedAction) error {
+	return ErrUnsupported
+}
+
+func (s *Store) ListShadowedActions(ctx context.Context, status string, page models.Page) ([]models.ShadowedAction, int, error) {
+	return nil, 0, ErrUnsupported
+}
+
+func (s *Store) ClaimShadowedAction(ctx context.Context, id int, replayer string) (*models.ShadowedAction, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) FinishShadowedAction(ctx context.Context, id int, failed bool, result string) error {
+	return ErrUnsupported
+}
+
+func (s *Store) RecordAudit(ctx context.Context, e *models.AuditEntry) error {
+	return ErrUnsupported
+}
+
+func (s *Store) GetAuditLog(ctx context.Context, q models.AuditQuery, page models.Page) ([]models.AuditEntry, int, error) {
+	return nil, 0, ErrUnsupported
+}
+
+func (s *Store) CreatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	return ErrUnsupported
+}
+
+// ListPricingRules returns no rules, as none can be created
+func (s *Store) ListPricingRules(ctx context.Context, restaurantID int) ([]models.PricingRule, error) {
+	return []models.PricingRule{}, nil
+}
+
+func (s *Store) GetPricingRule(ctx context.Context, id int) (*models.PricingRule, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) UpdatePricingRule(ctx context.Context, r *models.PricingRule) error {
+	return ErrUnsupported
+}
+
+func (s *Store) DeletePricingRule(ctx context.Context, id int) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// SetStock sets the portions left of a menu item, nil to stop tracking
+// them, and returns the item. Availability follows MenuItem.SetStock; a
+// change of it raises the item's version.
+func (db *DB) SetStock(ctx context.Context, menuItemID int, quantity *int) (*models.MenuItem, error) {
+	if quantity != nil && *quantity < 0 {
+		return nil, fmt.Errorf("quantity must be a whole number of at least 0")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	items, err := db.menuItemsByIDs(ctx, tx, []int{menuItemID}, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	m, ok := items[menuItemID]
+	if !ok {
+		return nil, fmt.Errorf("menu item with ID %d %w", menuItemID, ErrNotFound)
+	}
+	if err := writeStock(ctx, tx, &m, m.SetStock(quantity)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.reads.forget(menuReads(m.RestaurantID))
+	return &m, nil
+}
+
+// moveStock puts the stock the released items took back and takes the
+// items in taken from the stock of their menu items, in tx, setting their
+// FromStock. The menu items are locked in ID order, so concurrent orders
+// can't deadlock or oversell; wanting more than is left fails with an
+// *InsufficientStockError. Items that aren't on the menu are left for
+// priceItems to report.
+func (db *DB) moveStock(ctx context.Context, tx *sql.Tx, released, taken []models.OrderItem) error {
+	need := models.StockTaken(released, taken)
+	ids := make([]int, 0, len(need))
+	for id := range need {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	menu, err := db.menuItemsByIDs(ctx, tx, ids, "AND stock_quantity IS NOT NULL ORDER BY id FOR UPDATE")
+	if err != nil {
+		return err
+	}
+
+	short := &InsufficientStockError{}
+	for _, id := range ids {
+		if m, ok := menu[id]; ok && need[id] > *m.StockQuantity {
+			short.Items = append(short.Items, StockShortage{MenuItemID: id, Name: m.Name, Wanted: need[id], Remaining: *m.StockQuantity})
+		}
+	}
+	if len(short.Items) > 0 {
+		return short
+	}
+	for _, id := range ids {
+		m, ok := menu[id]
+		if !ok || need[id] == 0 {
+			continue
+		}
+		left := *m.StockQuantity - need[id]
+		if err := writeStock(ctx, tx, &m, m.SetStock(&left)); err != nil {
+			return err
+		}
+	}
+	for i := range taken {
+		_, taken[i].FromStock = menu[taken[i].MenuItemID]
+	}
+	return nil
+}
+
+// writeStock saves the stock and availability SetStock gave m, in tx,
+// raising its version when availability changed
+func writeStock(ctx context.Context, tx *sql.Tx, m *models.MenuItem, availabilityChanged bool) error {
+	raise := 0
+	if availabilityChanged {
+		raise = 1
+	}
+	return tx.QueryRowContext(ctx,
+		`UPDATE menu_items SET stock_quantity = $1, available = $2, version = version + $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 RETURNING version, updated_at`,
+		m.StockQuantity, m.Available, raise, m.ID,
+	).Scan(&m.Version, &m.UpdatedAt)
+}
@@ -0,0 +1,188 @@
+package storagetest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// RestaurantBuilder builds a restaurant and optionally its menu
+type RestaurantBuilder struct {
+	restaurant models.Restaurant
+	menu       []models.MenuItem
+}
+
+// RestaurantFixture is a persisted restaurant with its menu
+type RestaurantFixture struct {
+	Restaurant *models.Restaurant
+	Menu       []models.MenuItem
+}
+
+// restaurants numbers the default addresses, since no two restaurants may
+// share a name and address
+var restaurants atomic.Int64
+
+// NewRestaurant starts a restaurant with sensible defaults
+func NewRestaurant() *RestaurantBuilder {
+	return &RestaurantBuilder{
+		restaurant: models.Restaurant{
+			Name:                    "Test Restaurant",
+			Address:                 fmt.Sprintf("%d Test Street, Pune", restaurants.Add(1)),
+			PhoneNumber:             "+91-20-00000000",
+			CuisineType:             "Indian",
+			NotificationPreferences: models.DefaultNotificationPreferences(),
+		},
+	}
+}
+
+// WithName sets the restaurant name
+func (b *RestaurantBuilder) WithName(name string) *RestaurantBuilder {
+	b.restaurant.Name = name
+	return b
+}
+
+// WithAddress sets the restaurant address
+func (b *RestaurantBuilder) WithAddress(address string) *RestaurantBuilder {
+	b.restaurant.Address = address
+	return b
+}
+
+// WithEmail sets the restaurant contact email
+func (b *RestaurantBuilder) WithEmail(email string) *RestaurantBuilder {
+	b.restaurant.Email = email
+	return b
+}
+
+// WithCuisine sets the cuisine type
+func (b *RestaurantBuilder) WithCuisine(cuisine string) *RestaurantBuilder {
+	b.restaurant.CuisineType = cuisine
+	return b
+}
+
+// WithMenu adds n generated menu items priced 100, 110, 120...
+func (b *RestaurantBuilder) WithMenu(n int) *RestaurantBuilder {
+	for i := 0; i < n; i++ {
+		b.menu = append(b.menu, models.MenuItem{
+			Name:        fmt.Sprintf("Dish %d", len(b.menu)+1),
+			Description: "Test dish",
+			Price:       models.Rupees(float64(100 + 10*len(b.menu))),
+			Category:    "Main Course",
+			DietaryType: "vegetarian",
+			SpiceLevel:  "medium",
+			Available:   true,
+		})
+	}
+	return b
+}
+
+// WithMenuItem adds a specific menu item; RestaurantID is filled in by Build
+func (b *RestaurantBuilder) WithMenuItem(item models.MenuItem) *RestaurantBuilder {
+	b.menu = append(b.menu, item)
+	return b
+}
+
+// Build persists the restaurant and its menu, failing the test on error
+func (b *RestaurantBuilder) Build(t testing.TB, db *storage.DB) *RestaurantFixture {
+	t.Helper()
+
+	restaurant := b.restaurant
+	if err := db.CreateRestaurant(t.Context(), &restaurant); err != nil {
+		t.Fatalf("storagetest: create restaurant: %v", err)
+	}
+
+	fixture := &RestaurantFixture{Restaurant: &restaurant}
+	for _, item := range b.menu {
+		item.RestaurantID = restaurant.ID
+		if err := db.CreateMenuItem(t.Context(), &item); err != nil {
+			t.Fatalf("storagetest: create menu item: %v", err)
+		}
+		fixture.Menu = append(fixture.Menu, item)
+	}
+	return fixture
+}
+
+// OrderBuilder builds an order against a restaurant fixture
+type OrderBuilder struct {
+	restaurant *RestaurantFixture
+	order      models.Order
+	createdAt  time.Time // backdated after insert when set
+}
+
+// NewOrder starts a pending cash order for the given restaurant
+func NewOrder(restaurant *RestaurantFixture) *OrderBuilder {
+	return &OrderBuilder{
+		restaurant: restaurant,
+		order: models.Order{
+			RestaurantID:  restaurant.Restaurant.ID,
+			CustomerName:  "Test Customer",
+			CustomerPhone: "+91-98000-00000",
+			OrderType:     models.OrderTypeDineIn,
+			Status:        models.OrderStatusPending,
+			PaymentStatus: "pending",
+			PaymentMethod: "cash",
+		},
+	}
+}
+
+// WithCustomer sets the customer name and phone
+func (b *OrderBuilder) WithCustomer(name, phone string) *OrderBuilder {
+	b.order.CustomerName = name
+	b.order.CustomerPhone = phone
+	return b
+}
+
+// WithType sets the order type
+func (b *OrderBuilder) WithType(orderType string) *OrderBuilder {
+	b.order.OrderType = orderType
+	return b
+}
+
+// WithStatus sets the order status
+func (b *OrderBuilder) WithStatus(status string) *OrderBuilder {
+	b.order.Status = status
+	return b
+}
+
+// WithCreatedAt backdates the order to at, e.g. to place it either side of
+// midnight in the restaurant's timezone
+func (b *OrderBuilder) WithCreatedAt(at time.Time) *OrderBuilder {
+	b.createdAt = at
+	return b
+}
+
+// WithItem adds quantity of the restaurant's menu item at index menuIndex
+func (b *OrderBuilder) WithItem(menuIndex, quantity int) *OrderBuilder {
+	item := b.restaurant.Menu[menuIndex]
+	b.order.OrderItems = append(b.order.OrderItems, models.OrderItem{
+		MenuItemID: item.ID,
+		Quantity:   quantity,
+	})
+	return b
+}
+
+// Build persists the order, which prices it from the menu, and returns it
+// as read back from the database
+func (b *OrderBuilder) Build(t testing.TB, db *storage.DB) *models.Order {
+	t.Helper()
+
+	order := b.order
+	order.OrderItems = append([]models.OrderItem(nil), b.order.OrderItems...)
+
+	if err := db.CreateOrder(t.Context(), &order); err != nil {
+		t.Fatalf("storagetest: create order: %v", err)
+	}
+	if !b.createdAt.IsZero() {
+		if _, err := db.ExecContext(t.Context(), `UPDATE orders SET created_at = $1 WHERE id = $2`, b.createdAt, order.ID); err != nil {
+			t.Fatalf("storagetest: backdate order: %v", err)
+		}
+	}
+	saved, err := db.GetOrderByID(t.Context(), order.ID)
+	if err != nil {
+		t.Fatalf("storagetest: reload order: %v", err)
+	}
+	return saved
+}
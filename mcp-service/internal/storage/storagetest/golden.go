@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite golden files in testdata/ with the current output")
+
+// timestampPattern matches RFC 3339 timestamps as produced by encoding/json,
+// including ones escaped inside a JSON string such as an MCP text result
+var timestampPattern = regexp.MustCompile(`(\\?)"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})\\?"`)
+
+// localTimePattern matches the local times text results show, such as an
+// order's status timeline
+var localTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+
+// NormalizeJSON marshals v as indented JSON with every timestamp replaced by
+// a fixed placeholder so output is stable across runs
+func NormalizeJSON(t testing.TB, v interface{}) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("storagetest: marshal: %v", err)
+	}
+	data = timestampPattern.ReplaceAll(data, []byte(`$1"<timestamp>$1"`))
+	return append(localTimePattern.ReplaceAll(data, []byte(`<local time>`)), '\n')
+}
+
+// AssertGolden compares v, normalized with NormalizeJSON, against
+// testdata/<name>.golden. Run tests with -update-golden to rewrite the file.
+func AssertGolden(t testing.TB, name string, v interface{}) {
+	t.Helper()
+
+	got := NormalizeJSON(t, v)
+	path := filepath.Join("testdata", name+".golden")
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("storagetest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("storagetest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("storagetest: %v (run with -update-golden to create it)", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("storagetest: %s does not match golden file\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
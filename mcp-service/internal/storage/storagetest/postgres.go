@@ -0,0 +1,175 @@
+// Package storagetest provides a Postgres harness, fixture builders and
+// golden-file helpers for tests of the storage package and its callers.
+//
+// Typical use:
+//
+//	func TestMain(m *testing.M) { storagetest.Main(m) }
+//
+//	func TestGetMenu(t *testing.T) {
+//		db := storagetest.DB(t)
+//		r := storagetest.NewRestaurant().WithMenu(5).Build(t, db)
+//		...
+//	}
+package storagetest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/storage"
+)
+
+// postgresImage is the image started when TEST_DATABASE_URL is not set
+const postgresImage = "postgres:16-alpine"
+
+var (
+	startOnce   sync.Once
+	baseConnStr string
+	containerID string
+	startErr    error
+)
+
+// Main runs the package's tests and stops the Postgres container, if one was
+// started, afterwards. Call it from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	if containerID != "" {
+		exec.Command("docker", "rm", "-f", containerID).Run()
+	}
+	os.Exit(code)
+}
+
+// DB returns a storage.DB isolated to the calling test. Each test gets its own
+// Postgres schema with migrations applied; it is dropped when the test ends.
+//
+// Isolation uses a schema rather than a rolled-back transaction because
+// storage methods such as CreateOrder open and commit their own transactions.
+//
+// Postgres comes from TEST_DATABASE_URL when set, otherwise a container is
+// started once per test binary. The test is skipped if neither is available.
+func DB(t testing.TB) *storage.DB {
+	t.Helper()
+
+	db, err := storage.NewDB(ConnString(t), storage.DefaultPoolConfig(), storage.DefaultConnectRetry())
+	if err != nil {
+		t.Fatalf("storagetest: connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// ConnString returns a connection string to a new, empty Postgres schema,
+// dropped when the test ends, for tests that run NewDB or migrations
+// themselves. Close connections to it in a Cleanup registered afterwards,
+// so they are closed before the schema is dropped.
+func ConnString(t testing.TB) string {
+	t.Helper()
+
+	startOnce.Do(start)
+	if startErr != nil {
+		t.Skipf("storagetest: postgres unavailable: %v", startErr)
+	}
+
+	schema := "test_" + randomSuffix()
+	admin, err := sql.Open("postgres", baseConnStr)
+	if err != nil {
+		t.Fatalf("storagetest: open: %v", err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("storagetest: create schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		admin, err := sql.Open("postgres", baseConnStr)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+	// lib/pq passes unknown keys through as run-time parameters, so every
+	// pooled connection gets the test schema on its search_path
+	return withSearchPath(baseConnStr, schema)
+}
+
+func start() {
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		baseConnStr = url
+		startErr = waitForPostgres(baseConnStr, 10*time.Second)
+		return
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		startErr = fmt.Errorf("TEST_DATABASE_URL not set and docker not found")
+		return
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=postgres",
+		"-e", "POSTGRES_DB=mcp_restaurant_test",
+		"-p", "127.0.0.1::5432",
+		postgresImage).Output()
+	if err != nil {
+		startErr = fmt.Errorf("docker run: %v", err)
+		return
+	}
+	containerID = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", containerID, "5432/tcp").Output()
+	if err != nil {
+		startErr = fmt.Errorf("docker port: %v", err)
+		return
+	}
+	// "127.0.0.1:49153", possibly followed by an IPv6 mapping on the next line
+	hostPort := strings.Fields(string(out))[0]
+	port := hostPort[strings.LastIndex(hostPort, ":")+1:]
+
+	baseConnStr = fmt.Sprintf("host=127.0.0.1 port=%s user=postgres password=postgres dbname=mcp_restaurant_test sslmode=disable", port)
+	startErr = waitForPostgres(baseConnStr, 30*time.Second)
+}
+
+// withSearchPath adds a search_path parameter to either connection string form
+func withSearchPath(connStr, schema string) string {
+	if strings.HasPrefix(connStr, "postgres://") || strings.HasPrefix(connStr, "postgresql://") {
+		sep := "?"
+		if strings.Contains(connStr, "?") {
+			sep = "&"
+		}
+		return connStr + sep + "search_path=" + schema
+	}
+	return connStr + " search_path=" + schema
+}
+
+func waitForPostgres(connStr string, timeout time.Duration) error {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = db.Ping()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
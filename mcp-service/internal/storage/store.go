@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+// ErrUnsupported is wrapped by the errors of Store methods whose feature a
+// backend doesn't keep, such as pricing rules on SQLite
+var ErrUnsupported = errors.New("not supported by this storage backend")
+
+// Store is the restaurant data access the MCP servers and handlers depend
+// on. *DB implements it against Postgres; memory.Store is an in-memory fake
+// for tests. Implementations return errors wrapping ErrNotFound for missing
+// rows.
+type Store interface {
+	// EnsureConnected checks the backing store is reachable before a tool call
+	EnsureConnected(ctx context.Context) error
+
+	GetAllRestaurants(ctx context.Context, page models.Page, sort string) ([]models.Restaurant, int, error)
+	SearchRestaurants(ctx context.Context, query, cuisine string, limit int) ([]models.Restaurant, error)
+	GetRestaurantByID(ctx context.Context, id int) (*models.Restaurant, error)
+	CreateRestaurant(ctx context.Context, r *models.Restaurant) error
+	UpdateRestaurant(ctx context.Context, r *models.Restaurant) error
+	PatchRestaurant(ctx context.Context, id int, patch models.RestaurantPatch) (*models.Restaurant, error)
+	DeleteRestaurant(ctx context.Context, id int, opts models.RestaurantDeleteOptions) (models.RestaurantDeletion, error)
+	RestoreRestaurant(ctx context.Context, id int) (*models.Restaurant, error)
+
+	GetMenuByRestaurantID(ctx context.Context, restaurantID int, includeUnavailable bool) ([]models.MenuItem, error)
+	GetMenuAsOf(ctx context.Context, restaurantID int, asOf string, includeUnavailable bool) ([]models.MenuItem, error)
+	SearchMenuItems(ctx context.Context, q models.MenuSearch) ([]models.MenuItem, error)
+	GetMenuItemByID(ctx context.Context, id int) (*models.MenuItem, error)
+	GetMenuItemsByIDs(ctx context.Context, ids []int) (map[int]models.MenuItem, error)
+	CreateMenuItem(ctx context.Context, m *models.MenuItem) error
+	CreateMenuItems(ctx context.Context, items []*models.MenuItem) error
+	UpdateMenuItem(ctx context.Context, m *models.MenuItem, changedBy string) error
+	SetStock(ctx context.Context, menuItemID int, quantity *int) (*models.MenuItem, error)
+	DeleteMenuItem(ctx context.Context, id int) error
+	GetPriceHistory(ctx context.Context, q models.PriceHistoryQuery, page models.Page) ([]models.PriceChange, int, error)
+	LastPriceChanges(ctx context.Context, restaurantID int) (map[int]time.Time, error)
+
+	GetAllOrders(ctx context.Context, page models.Page) ([]models.Order, int, error)
+	GetOrdersByRestaurantID(ctx context.Context, restaurantID int, page models.Page) ([]models.Order, int, error)
+	GetOrdersByStatus(ctx context.Context, statuses []string, page models.Page) ([]models.Order, int, error)
+	GetOrders(ctx context.Context, f models.OrderFilter, page models.Page) ([]models.Order, int, error)
+	GetOrdersByCustomerPhone(ctx context.Context, phone string, page models.Page) ([]models.Order, models.CustomerSummary, error)
+	GetSalesReport(ctx context.Context, q models.SalesQuery) (*models.SalesReport, error)
+	GetTopSellingItems(ctx context.Context, q models.TopItemsQuery) ([]models.ItemSales, error)
+	GetOrderDigest(ctx context.Context, q models.DigestQuery) (*models.OrderDigest, error)
+	GetRestaurantStats(ctx context.Context, restaurantID int) (*models.RestaurantStats, error)
+	GetOrderByID(ctx context.Context, id int) (*models.Order, error)
+	GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]models.OrderItem, error)
+	CreateOrder(ctx context.Context, o *models.Order) error
+	UpdateOrder(ctx context.Context, o *models.Order) error
+	UpdateOrderItems(ctx context.Context, orderID int, items []models.OrderItem) (*models.Order, error)
+	SetOrderTip(ctx context.Context, orderID int, tip models.Money) (*models.Order, error)
+	SplitOrder(ctx context.Context, orderID int, moves []models.OrderItem) (*models.Order, *models.Order, error)
+	MergeOrders(ctx context.Context, orderIDs []int) (*models.Order, error)
+	AssignDelivery(ctx context.Context, orderID int, riderName, riderPhone string) (*models.Order, error)
+	CancelOrder(ctx context.Context, orderID int, reason, note, cancelledBy string) (*models.Order, error)
+	DeleteOrder(ctx context.Context, id int) error
+	RecordOrderFeedback(ctx context.Context, f *models.OrderFeedback) error
+	GetOrderFeedback(ctx context.Context, orderID int) (*models.OrderFeedback, error)
+	GetOrderHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error)
+	ExportOrders(ctx context.Context, restaurantID int, from, to time.Time, row func(models.OrderExportRow) error) error
+	ArchiveOrders(ctx context.Context, before time.Time) (int, error)
+	GetArchivedOrder(ctx context.Context, id int) (*models.Order, error)
+
+	ProposeChange(ctx context.Context, c *models.ChangeRequest) error
+	ListChangeRequests(ctx context.Context, status string, page models.Page) ([]models.ChangeRequest, int, error)
+	ReviewChangeRequest(ctx context.Context, id int, approve bool, reviewer string) (*models.ChangeRequest, error)
+
+	RecordShadowedAction(ctx context.Context, a *models.ShadowedAction) error
+	ListShadowedActions(ctx context.Context, status string, page models.Page) ([]models.ShadowedAction, int, error)
+	ClaimShadowedAction(ctx context.Context, id int, replayer string) (*models.ShadowedAction, error)
+	FinishShadowedAction(ctx context.Context, id int, failed bool, result string) error
+
+	RecordAudit(ctx context.Context, e *models.AuditEntry) error
+	GetAuditLog(ctx context.Context, q models.AuditQuery, page models.Page) ([]models.AuditEntry, int, error)
+
+	CreatePricingRule(ctx context.Context, r *models.PricingRule) error
+	ListPricingRules(ctx context.Context, restaurantID int) ([]models.PricingRule, error)
+	GetPricingRule(ctx context.Context, id int) (*models.PricingRule, error)
+	UpdatePricingRule(ctx context.Context, r *models.PricingRule) error
+	DeletePricingRule(ctx context.Context, id int) error
+}
+
+var _ Store = (*DB)(nil)
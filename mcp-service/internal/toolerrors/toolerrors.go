@@ -0,0 +1,148 @@
+// Package toolerrors keeps the most recent failed tool calls in memory so an
+// admin can ask the server what went wrong without access to its logs.
+package toolerrors
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// BufferSize is how many failures a Ring from NewRing keeps. Override with
+// MCP_ERROR_BUFFER_SIZE.
+var BufferSize = 200
+
+// MaxErrorBytes caps the stored error message
+const MaxErrorBytes = 1024
+
+func init() {
+	if v, err := strconv.Atoi(os.Getenv("MCP_ERROR_BUFFER_SIZE")); err == nil && v > 0 {
+		BufferSize = v
+	}
+}
+
+// Entry is one failed tool call. Error is the message the client was sent,
+// which never includes driver errors, and Arguments have gone through Redact.
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	DurationMs int64                  `json:"duration_ms"`
+	RequestID  string                 `json:"request_id"`
+	ServerID   string                 `json:"server_request_id,omitempty"` // the serverRequestId in the result _meta
+	Session    string                 `json:"session,omitempty"`
+	User       string                 `json:"user,omitempty"`
+	Tool       string                 `json:"tool"`
+	Code       int                    `json:"code,omitempty"` // JSON-RPC error code; 0 for an isError result
+	Error      string                 `json:"error"`
+	Outcome    string                 `json:"outcome,omitempty"` // restaurant access check: "not_found", or "denied" behind a not-found error
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// Ring holds the last few entries, dropping the oldest once full. It is
+// safe for concurrent use.
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int // slot the next entry goes in
+	full    bool
+}
+
+// NewRing returns a ring holding up to size entries
+func NewRing(size int) *Ring {
+	if size <= 0 {
+		size = 1
+	}
+	return &Ring{entries: make([]Entry, size)}
+}
+
+// Add records a failure, redacting its arguments and capping its message
+func (r *Ring) Add(e Entry) {
+	e.Arguments = Redact(e.Arguments)
+	e.Error = truncate(e.Error, MaxErrorBytes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns up to n entries, newest first
+func (r *Ring) Recent(n int) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	n = min(n, count)
+	out := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+// sensitiveKeys are substrings of argument names whose values are personal
+// data or credentials
+var sensitiveKeys = []string{"phone", "address", "email", "password", "secret", "token"}
+
+// Redacted replaces the value of a sensitive argument
+const Redacted = "[redacted]"
+
+// Redact returns a copy of args with the values of sensitive keys, at any
+// depth, replaced by Redacted
+func Redact(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if isSensitive(k) {
+			out[k] = Redacted
+		} else {
+			out[k] = redactValue(v)
+		}
+	}
+	return out
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return Redact(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most max bytes without splitting a UTF-8 sequence
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "…"
+}
@@ -0,0 +1,207 @@
+// Package toolschema validates MCP tool arguments against the JSON schema
+// subset used by our tool definitions (type, properties, required, enum,
+// items) and renders per-tool examples.
+package toolschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Example is a set of arguments for one sample invocation of a tool
+type Example map[string]interface{}
+
+// toMap converts a typed or map-based schema to its generic JSON form. Map
+// literals are round-tripped too, since they may nest typed values such as
+// []string enums.
+func toMap(schema interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Schema is a tool's input schema in its generic JSON form, ready to
+// validate arguments against
+type Schema map[string]interface{}
+
+// Parse converts an input schema, a typed InputSchema struct or a map
+// literal, to a Schema
+func Parse(schema interface{}) (Schema, error) {
+	s, err := toMap(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+// ValidationError lists every way a tool call's arguments break its schema
+type ValidationError struct {
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// ValidateArgs checks args against an object schema. schema may be a typed
+// InputSchema struct or a map literal; both are compared in their JSON form.
+func ValidateArgs(schema interface{}, args map[string]interface{}) error {
+	s, err := Parse(schema)
+	if err != nil {
+		return err
+	}
+	return s.Validate(args)
+}
+
+// Validate checks args against s, returning a *ValidationError with all
+// the violations found
+func (s Schema) Validate(args map[string]interface{}) error {
+	// Round-trip args too so Go ints in examples compare like decoded JSON
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		value = map[string]interface{}{} // no arguments at all
+	}
+	var violations []string
+	validate("", s, value, &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// validate appends the violations of value against schema; a value of the
+// wrong type isn't looked into further
+func validate(path string, schema map[string]interface{}, value interface{}, violations *[]string) {
+	name := path
+	if name == "" {
+		name = "arguments"
+	}
+
+	if t, ok := schema["type"].(string); ok && !hasType(t, value) {
+		*violations = append(*violations, fmt.Sprintf("%s must be of type %s", name, t))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*violations = append(*violations, fmt.Sprintf("%s must be one of %v", name, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				key, _ := r.(string)
+				if _, present := v[key]; !present {
+					*violations = append(*violations, "missing required field "+join(path, key))
+				}
+			}
+		}
+		props, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			// Free-form object
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			propSchema, ok := props[k].(map[string]interface{})
+			if !ok {
+				*violations = append(*violations, "unknown field "+join(path, k))
+				continue
+			}
+			validate(join(path, k), propSchema, v[k], violations)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(fmt.Sprintf("%s[%d]", name, i), items, item, violations)
+			}
+		}
+	}
+}
+
+func hasType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	default:
+		return true
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// RenderDescription appends the first example as a one-line invocation,
+// which models follow noticeably better than prose alone
+func RenderDescription(description string, examples []Example) string {
+	if len(examples) == 0 {
+		return description
+	}
+	data, err := json.Marshal(examples[0])
+	if err != nil {
+		return description
+	}
+	return strings.TrimRight(description, " ") + " Example: " + string(data)
+}
+
+// CheckExamples reports tools that have no example or whose examples don't
+// validate against their own schema, so schema/example drift is caught
+func CheckExamples(tool string, schema interface{}, examples []Example) error {
+	if len(examples) == 0 {
+		return fmt.Errorf("tool %s has no examples", tool)
+	}
+	for i, ex := range examples {
+		if err := ValidateArgs(schema, ex); err != nil {
+			return fmt.Errorf("tool %s example %d: %v", tool, i+1, err)
+		}
+	}
+	return nil
+}
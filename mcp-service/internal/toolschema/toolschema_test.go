@@ -0,0 +1,110 @@
+package toolschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckExamplesCatchesDrift(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "integer"}},
+		"required":   []string{"order_id"},
+	}
+	if err := CheckExamples("get_order", schema, []Example{{"order_id": 1}}); err != nil {
+		t.Errorf("valid example: %v", err)
+	}
+	if err := CheckExamples("get_order", schema, nil); err == nil {
+		t.Error("a tool without examples passed")
+	}
+	if err := CheckExamples("get_order", schema, []Example{{"order_id": 1}, {"id": 1}}); err == nil {
+		t.Error("an example missing a required argument passed")
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	got := RenderDescription("Get an order. ", []Example{{"order_id": 1}, {"order_id": 2}})
+	if want := `Get an order. Example: {"order_id":1}`; got != want {
+		t.Errorf("RenderDescription = %q, want %q", got, want)
+	}
+	if got := RenderDescription("Get an order", nil); got != "Get an order" {
+		t.Errorf("RenderDescription without examples = %q", got)
+	}
+}
+
+func TestValidateArgsNestedObjects(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"items": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"menu_item_id": map[string]interface{}{"type": "integer"},
+						"quantity":     map[string]interface{}{"type": "integer"},
+						"notes":        map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"menu_item_id", "quantity"},
+				},
+			},
+		},
+		"required": []string{"items"},
+	}
+
+	for _, tc := range []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"valid", map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"menu_item_id": 1.0, "quantity": 2.0},
+			map[string]interface{}{"menu_item_id": 4.0, "quantity": 1.0, "notes": "extra syrup"},
+		}}, ""},
+		{"missing items", map[string]interface{}{}, "missing required field items"},
+		{"guessed names", map[string]interface{}{"items": []interface{}{map[string]interface{}{"itemId": 1.0, "qty": 2.0}}}, "missing required field items[0].menu_item_id"},
+		{"extra field", map[string]interface{}{"items": []interface{}{map[string]interface{}{"menu_item_id": 1.0, "quantity": 2.0, "qty": 2.0}}}, "unknown field items[0].qty"},
+		{"item not an object", map[string]interface{}{"items": []interface{}{1.0}}, "items[0] must be of type object"},
+		{"items not an array", map[string]interface{}{"items": map[string]interface{}{"menu_item_id": 1.0}}, "items must be of type array"},
+		{"wrong field types", map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"menu_item_id": 1.0, "quantity": 1.0},
+			map[string]interface{}{"menu_item_id": "Masala Dosa", "quantity": 1.5},
+		}}, "items[1].menu_item_id must be of type integer"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateArgs(schema, tc.args)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateArgs = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ValidateArgs = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateArgsReportsEveryViolation(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"order_id": map[string]interface{}{"type": "integer"},
+			"status":   map[string]interface{}{"type": "string", "enum": []string{"pending", "ready"}},
+		},
+		"required": []string{"order_id"},
+	}
+	err := ValidateArgs(schema, map[string]interface{}{"status": "lost", "note": "x"})
+	invalid, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("ValidateArgs = %v, want a *ValidationError", err)
+	}
+	want := []string{"missing required field order_id", "unknown field note", "status must be one of [pending ready]"}
+	if strings.Join(invalid.Violations, "\n") != strings.Join(want, "\n") {
+		t.Errorf("violations = %q, want %q", invalid.Violations, want)
+	}
+	if err := ValidateArgs(schema, nil); err == nil || !strings.Contains(err.Error(), "order_id") {
+		t.Errorf("ValidateArgs without arguments = %v, want the missing order_id", err)
+	}
+}
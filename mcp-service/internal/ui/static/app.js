@@ -0,0 +1,125 @@
+// Fills the server-rendered pages from the /api endpoints. Requests are
+// same-origin, so the browser sends the session cookie with them.
+(function () {
+  "use strict";
+
+  var app = document.getElementById("app");
+  var rows = document.getElementById("rows");
+
+  function getJSON(url) {
+    return fetch(url, { credentials: "same-origin" }).then(function (res) {
+      if (res.status === 401) {
+        window.location = "/ui/login";
+        throw new Error("Session expired");
+      }
+      if (!res.ok) {
+        return res.text().then(function (text) { throw new Error(text || res.statusText); });
+      }
+      return res.json();
+    });
+  }
+
+  function cell(text, className) {
+    var td = document.createElement("td");
+    td.textContent = text;
+    if (className) td.className = className;
+    return td;
+  }
+
+  function link(text, href) {
+    var td = document.createElement("td");
+    var a = document.createElement("a");
+    a.href = href;
+    a.textContent = text;
+    td.appendChild(a);
+    return td;
+  }
+
+  function fill(items, columns, toRow) {
+    rows.textContent = "";
+    if (items.length === 0) {
+      var tr = document.createElement("tr");
+      var td = cell("Nothing to show");
+      td.colSpan = columns;
+      tr.appendChild(td);
+      rows.appendChild(tr);
+      return;
+    }
+    items.forEach(function (item) {
+      var tr = document.createElement("tr");
+      toRow(item).forEach(function (td) { tr.appendChild(td); });
+      rows.appendChild(tr);
+    });
+  }
+
+  function money(amount) {
+    return Number(amount).toFixed(2);
+  }
+
+  function showError(err) {
+    rows.textContent = "";
+    var el = document.getElementById("error");
+    el.textContent = err.message;
+    el.hidden = false;
+  }
+
+  var pages = {
+    restaurants: function () {
+      return getJSON("/api/restaurants?sort=name&limit=200").then(function (restaurants) {
+        fill(restaurants, 6, function (r) {
+          return [
+            cell(r.id),
+            cell(r.name),
+            cell(r.cuisine_type),
+            cell(r.address),
+            cell(r.phone_number),
+            link("Menu", "/ui/menu?restaurant_id=" + encodeURIComponent(r.id))
+          ];
+        });
+      });
+    },
+
+    menu: function () {
+      var id = encodeURIComponent(app.dataset.restaurantId);
+      getJSON("/api/restaurants/get?id=" + id).then(function (r) {
+        document.getElementById("title").textContent = r.name + " — Menu";
+        document.title = r.name + " · Restaurant Service";
+      }).catch(function () {});
+      return getJSON("/api/restaurants/menu?restaurant_id=" + id).then(function (items) {
+        fill(items, 6, function (m) {
+          return [
+            cell(m.category),
+            cell(m.name),
+            cell(m.description),
+            cell(m.dietary_type),
+            cell(m.spice_level),
+            cell(money(m.price), "num")
+          ];
+        });
+      });
+    },
+
+    orders: function () {
+      var url = "/api/orders";
+      if (app.dataset.status) {
+        url += "?status=" + encodeURIComponent(app.dataset.status);
+      }
+      return getJSON(url).then(function (orders) {
+        fill(orders, 5, function (o) {
+          return [
+            cell(o.id),
+            link(o.restaurant_id, "/ui/menu?restaurant_id=" + encodeURIComponent(o.restaurant_id)),
+            cell(o.customer_name),
+            cell(o.status),
+            cell(money(o.total_amount), "num")
+          ];
+        });
+      });
+    }
+  };
+
+  var load = pages[app.dataset.page];
+  if (load) {
+    load().catch(showError);
+  }
+})();
@@ -0,0 +1,105 @@
+// Package ui serves a small read-only web UI for browsing restaurants, menus
+// and orders. Pages are rendered with html/template from embedded files and
+// fill themselves in from the /api endpoints, authenticated by the session
+// cookie set at /ui/login.
+package ui
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vishalk17/mcp-service-restaurant/internal/models"
+)
+
+//go:embed templates/*.html static/*
+var files embed.FS
+
+// pageData is what every page template receives
+type pageData struct {
+	Title        string
+	Page         string
+	RestaurantID int
+	Status       string
+	Statuses     []string
+}
+
+// Handler serves the UI pages under /ui
+type Handler struct {
+	pages  map[string]*template.Template
+	static http.Handler
+}
+
+// NewHandler parses the embedded templates
+func NewHandler() (*Handler, error) {
+	h := &Handler{pages: map[string]*template.Template{}}
+	for _, page := range []string{"restaurants", "menu", "orders"} {
+		tmpl, err := template.ParseFS(files, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, err
+		}
+		h.pages[page] = tmpl
+	}
+
+	static, err := fs.Sub(files, "static")
+	if err != nil {
+		return nil, err
+	}
+	h.static = http.StripPrefix("/ui/static/", http.FileServer(http.FS(static)))
+	return h, nil
+}
+
+// ServeHTTP routes /ui requests to the page they ask for
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/ui", "/ui/":
+		h.render(w, "restaurants", pageData{Title: "Restaurants"})
+	case "/ui/menu":
+		restaurantID, err := strconv.Atoi(r.URL.Query().Get("restaurant_id"))
+		if err != nil || restaurantID <= 0 {
+			http.Error(w, "Invalid restaurant_id", http.StatusBadRequest)
+			return
+		}
+		h.render(w, "menu", pageData{Title: "Menu", RestaurantID: restaurantID})
+	case "/ui/orders":
+		status := r.URL.Query().Get("status")
+		if status != "" && !isStatus(status) {
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+		h.render(w, "orders", pageData{Title: "Orders", Status: status, Statuses: models.OrderStatuses})
+	default:
+		if strings.HasPrefix(r.URL.Path, "/ui/static/") {
+			h.static.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) render(w http.ResponseWriter, page string, data pageData) {
+	data.Page = page
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.pages[page].ExecuteTemplate(w, "layout", data); err != nil {
+		log.Printf("Error rendering UI page %s: %v", page, err)
+	}
+}
+
+func isStatus(status string) bool {
+	for _, s := range models.OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}